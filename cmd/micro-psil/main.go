@@ -4,9 +4,12 @@ package main
 
 import (
 	"bufio"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/psilLang/psil/pkg/micro"
@@ -16,54 +19,160 @@ func main() {
 	debug := flag.Bool("debug", false, "Enable debug output")
 	disasm := flag.Bool("disasm", false, "Disassemble instead of run")
 	gas := flag.Int("gas", 0, "Gas limit (0 = unlimited)")
+	watchFile := flag.Bool("watch", false, "Re-run the file whenever it changes, showing memory changes between runs")
+	hexIn := flag.String("hex", "", "Run this hex bytecode (e.g. a sandbox genome) instead of a file")
+	emitHex := flag.Bool("emit-hex", false, "Print the bytecode as hex instead of running it")
+	selfModify := flag.Bool("self-modify", false, "Let poke patch the running code")
+	slots := flag.Int("memory", micro.DefaultMemorySlots, "Memory size in 16-bit slots (up to 65536)")
+	gasCosts := flag.String("gas-costs", "", "Per-opcode gas with -gas: flat or weighted, then overrides (e.g. weighted,exec=5)")
+	genVectors := flag.String("gen-vectors", "", "Write the Go/Z80 test vectors (vectors.json, vectors.bin) to this directory")
 	flag.Parse()
 
 	args := flag.Args()
+	cfg := vmConfig{debug: *debug, gas: *gas, selfModify: *selfModify, slots: *slots}
+	if *gasCosts != "" {
+		costs, err := micro.ParseGasCosts(*gasCosts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		cfg.gasCosts = costs
+	}
+
+	if *genVectors != "" {
+		if err := writeVectors(*genVectors); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	if len(args) == 0 {
-		repl(*debug, *gas)
+	if len(args) == 0 && *hexIn == "" {
+		repl(cfg)
 		return
 	}
 
-	// Load and run file
-	data, err := os.ReadFile(args[0])
+	if *watchFile {
+		if len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: -watch needs a file")
+			os.Exit(1)
+		}
+		watch(args[0], cfg)
+		return
+	}
+
+	// Load the genome or file
+	var data []byte
+	var raw bool
+	var err error
+	if *hexIn != "" {
+		data, err = parseHex(*hexIn)
+		raw = true
+	} else {
+		data, err = os.ReadFile(args[0])
+		raw = isBytecode(data)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	source := string(data)
+	if *emitHex {
+		code := data
+		if !raw {
+			if code, _, err = assembleSource(string(data)); err != nil {
+				fmt.Fprintf(os.Stderr, "Assembly error: %v\n", err)
+				os.Exit(1)
+			}
+			if n := len(parseQuotations(string(data))); n > 0 {
+				fmt.Fprintf(os.Stderr, "Note: %d quotation(s) not included, only the main code\n", n)
+			}
+		}
+		fmt.Println(hex.EncodeToString(code))
+		return
+	}
 
-	// Check if it's assembly (text) or raw bytecode
-	if isBytecode(data) {
-		// Raw bytecode
-		if *disasm {
+	if *disasm {
+		if raw {
 			fmt.Print(micro.Disassemble(data))
 			return
 		}
-		runBytecode(data, *debug, *gas)
-	} else {
-		// Assembly text
-		code, quots, err := assembleSource(source)
+		code, quots, err := assembleSource(string(data))
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Assembly error: %v\n", err)
 			os.Exit(1)
 		}
+		fmt.Println("=== Main ===")
+		fmt.Print(micro.Disassemble(code))
+		for name, idx := range quots {
+			fmt.Printf("\n=== [%s] (idx=%d) ===\n", name, idx)
+		}
+		return
+	}
 
-		if *disasm {
-			fmt.Println("=== Main ===")
-			fmt.Print(micro.Disassemble(code))
-			for name, idx := range quots {
-				fmt.Printf("\n=== [%s] (idx=%d) ===\n", name, idx)
-			}
-			return
+	vm, err := runSource(data, raw, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	fmt.Println("Stack:", vm.StackDump())
+}
+
+// writeVectors writes the standard test vectors to dir, creating it if
+// need be
+func writeVectors(dir string) error {
+	files, err := micro.VectorFiles()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			return err
 		}
+	}
+	fmt.Printf("Wrote %d files to %s\n", len(files), dir)
+	return nil
+}
 
-		vm := micro.New()
-		vm.Debug = *debug
-		if *gas > 0 {
-			vm.MaxGas = *gas
-			vm.Gas = *gas
+// vmConfig holds the flags that set up a VM
+type vmConfig struct {
+	debug      bool
+	gas        int // 0 = unlimited
+	selfModify bool
+	slots      int // memory size
+	gasCosts   *micro.GasTable
+}
+
+// newVM returns a fresh VM set up as cfg says
+func (cfg vmConfig) newVM() *micro.VM {
+	vm := micro.New()
+	vm.Debug = cfg.debug
+	vm.SelfModify = cfg.selfModify
+	vm.SetMemorySlots(cfg.slots)
+	vm.GasCosts = cfg.gasCosts
+	if cfg.gas > 0 {
+		vm.MaxGas = cfg.gas
+		vm.Gas = cfg.gas
+	}
+	return vm
+}
+
+// runSource runs a file's contents, raw bytecode or assembly with its
+// quotations, on a fresh VM.
+func runSource(data []byte, raw bool, cfg vmConfig) (*micro.VM, error) {
+	vm := cfg.newVM()
+
+	code := data
+	if !raw {
+		source := string(data)
+		var err error
+		if code, _, err = assembleSource(source); err != nil {
+			return nil, fmt.Errorf("Assembly error: %v", err)
 		}
 
 		// Load quotations
@@ -71,21 +180,28 @@ func main() {
 			asm := micro.NewAssembler()
 			qcode, err := asm.Assemble(q.body)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Quotation %s error: %v\n", q.name, err)
-				os.Exit(1)
+				return nil, fmt.Errorf("Quotation %s error: %v", q.name, err)
 			}
 			vm.DefineQuot(q.idx, qcode)
 		}
+	}
 
-		vm.Load(code)
-		if err := vm.Run(); err != nil {
-			fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
-			os.Exit(1)
-		}
+	vm.Load(code)
+	if err := vm.Run(); err != nil {
+		return vm, fmt.Errorf("Runtime error: %v", err)
+	}
+	return vm, nil
+}
 
-		fmt.Println()
-		fmt.Println("Stack:", vm.StackDump())
+// parseHex decodes a hex genome, ignoring spaces and an optional 0x.
+func parseHex(s string) ([]byte, error) {
+	s = strings.Join(strings.Fields(s), "")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	code, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("bad hex: %v", err)
 	}
+	return code, nil
 }
 
 func isBytecode(data []byte) bool {
@@ -188,35 +304,20 @@ func parseQuotations(source string) []quotDef {
 	return quots
 }
 
-func runBytecode(code []byte, debug bool, gas int) {
-	vm := micro.New()
-	vm.Debug = debug
-	if gas > 0 {
-		vm.MaxGas = gas
-		vm.Gas = gas
-	}
-	vm.Load(code)
-
-	if err := vm.Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "Runtime error: %v\n", err)
-		os.Exit(1)
-	}
-
-	fmt.Println()
-	fmt.Println("Stack:", vm.StackDump())
+// session is the state of a REPL: the VM, and an assembler kept across
+// lines so named quotations resolve to the slots they were defined in.
+type session struct {
+	vm    *micro.VM
+	asm   *micro.Assembler
+	names map[int]string // quotation index -> name
 }
 
-func repl(debug bool, gas int) {
+func repl(cfg vmConfig) {
 	fmt.Println("micro-PSIL VM")
 	fmt.Println("Type 'help' for commands, 'quit' to exit")
 	fmt.Println()
 
-	vm := micro.New()
-	vm.Debug = debug
-	if gas > 0 {
-		vm.MaxGas = gas
-		vm.Gas = gas
-	}
+	s := &session{vm: cfg.newVM(), asm: micro.NewAssembler(), names: make(map[int]string)}
 
 	scanner := bufio.NewScanner(os.Stdin)
 
@@ -230,52 +331,265 @@ func repl(debug bool, gas int) {
 		if line == "" {
 			continue
 		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
 
-		switch line {
+		switch cmd {
 		case "quit", "exit":
 			return
 		case "help":
 			printHelp()
 		case "stack":
-			fmt.Println(vm.StackDump())
+			fmt.Println(s.vm.StackDump())
 		case "clear":
-			vm.Reset()
+			s.vm.Reset()
 			fmt.Println("Cleared")
 		case "debug":
-			vm.Debug = !vm.Debug
-			fmt.Printf("Debug: %v\n", vm.Debug)
+			s.vm.Debug = !s.vm.Debug
+			fmt.Printf("Debug: %v\n", s.vm.Debug)
+		case "quots":
+			s.listQuots()
+		case "mem":
+			s.showMem(arg)
+		case "mem!":
+			s.setMem(arg)
+		case "gas":
+			if s.vm.MaxGas > 0 {
+				fmt.Printf("Gas: %d/%d\n", s.vm.Gas, s.vm.MaxGas)
+			} else {
+				fmt.Println("Gas: unlimited")
+			}
+		case "load":
+			if arg == "" {
+				fmt.Println("Usage: load <file>")
+				continue
+			}
+			if err := s.load(arg); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
+			fmt.Println("->", s.vm.StackDump())
+		case "QUOT":
+			// Multi-line definition: read the body up to ENDQUOT
+			body := []string{line}
+			for {
+				fmt.Print("..  ")
+				if !scanner.Scan() {
+					break
+				}
+				body = append(body, scanner.Text())
+				if strings.HasPrefix(strings.TrimSpace(scanner.Text()), "ENDQUOT") {
+					break
+				}
+			}
+			if err := s.defineQuots(strings.Join(body, "\n"), true); err != nil {
+				fmt.Printf("Error: %v\n", err)
+			}
 		default:
 			// Try to assemble and run
-			asm := micro.NewAssembler()
-			code, err := asm.Assemble(line)
-			if err != nil {
+			if err := s.run(line); err != nil {
 				fmt.Printf("Error: %v\n", err)
-				continue
 			}
+			fmt.Println("->", s.vm.StackDump())
+		}
+	}
+}
 
-			if vm.Debug {
-				fmt.Println("Bytecode:", micro.Disassemble(code))
-			}
+// run assembles source and runs it on the session's VM.
+func (s *session) run(source string) error {
+	code, err := s.asm.Assemble(source)
+	if err != nil {
+		return err
+	}
+	// Assemble reuses its buffer; keep the VM's copy
+	code = append([]byte(nil), code...)
 
-			vm.Load(code)
-			vm.Halted = false
-			if err := vm.Run(); err != nil {
-				fmt.Printf("Error: %v\n", err)
+	if s.vm.Debug {
+		fmt.Println("Bytecode:", micro.Disassemble(code))
+	}
+
+	s.vm.Load(code)
+	s.vm.Halted = false
+	return s.vm.Run()
+}
+
+// defineQuots assembles and defines every QUOT ... ENDQUOT block in source.
+// In the REPL (repl set) a QUOT without an index takes its name's existing
+// slot, or the first free one, rather than its position in source.
+func (s *session) defineQuots(source string, repl bool) error {
+	for _, q := range parseQuotations(source) {
+		if repl && !hasQuotIndex(source, q.name) {
+			idx, err := s.quotSlot(q.name)
+			if err != nil {
+				return err
 			}
+			q.idx = idx
+		}
+		if q.idx < 0 || q.idx > 255 {
+			return fmt.Errorf("quotation %s: index %d out of range", q.name, q.idx)
+		}
+		if old := s.names[q.idx]; old != "" && old != q.name {
+			fmt.Printf("Note: [%s] replaces [%s] in slot %d\n", q.name, old, q.idx)
+		}
+		// Name it first, so the body can call itself by name
+		s.asm.DefineQuotation(q.name, q.idx)
+		s.names[q.idx] = q.name
+		code, err := s.asm.Assemble(q.body)
+		if err != nil {
+			return fmt.Errorf("quotation %s: %v", q.name, err)
+		}
+		s.vm.DefineQuot(q.idx, append([]byte(nil), code...))
+		fmt.Printf("Defined [%s] = %d (%d bytes)\n", q.name, q.idx, len(code))
+	}
+	return nil
+}
 
-			fmt.Println("->", vm.StackDump())
+// hasQuotIndex reports whether source's QUOT header for name gives an index.
+func hasQuotIndex(source, name string) bool {
+	for _, line := range strings.Split(source, "\n") {
+		parts := strings.Fields(line)
+		if len(parts) >= 2 && parts[0] == "QUOT" && parts[1] == name {
+			return len(parts) >= 3
 		}
 	}
+	return false
+}
+
+// quotSlot returns the slot a quotation called name is defined in: the
+// lowest one it already has, or else the first slot that is neither
+// defined nor named.
+func (s *session) quotSlot(name string) (int, error) {
+	for idx := range s.vm.Quotations {
+		if s.names[idx] == name {
+			return idx, nil
+		}
+	}
+	for idx, code := range s.vm.Quotations {
+		if _, named := s.names[idx]; code == nil && !named {
+			return idx, nil
+		}
+	}
+	return 0, fmt.Errorf("quotation %s: all %d slots are taken", name, len(s.vm.Quotations))
+}
+
+// listQuots prints every defined quotation with its disassembly.
+func (s *session) listQuots() {
+	n := 0
+	for idx, code := range s.vm.Quotations {
+		if code == nil {
+			continue
+		}
+		n++
+		name := s.names[idx]
+		if name == "" {
+			name = "?"
+		}
+		fmt.Printf("[%d] %s (%d bytes)\n", idx, name, len(code))
+		for _, line := range strings.Split(strings.TrimRight(micro.Disassemble(code), "\n"), "\n") {
+			fmt.Println("    " + line)
+		}
+	}
+	if n == 0 {
+		fmt.Println("No quotations defined")
+	}
+}
+
+// parseSlot reads a memory slot: a number below the memory size or a
+// symbol such as health or 'health.
+func (s *session) parseSlot(arg string) (int, error) {
+	last := s.vm.MemorySlots() - 1
+	if n, err := strconv.ParseInt(arg, 0, 64); err == nil {
+		if n < 0 || n > int64(last) {
+			return 0, fmt.Errorf("slot %d out of range 0-%d", n, last)
+		}
+		return int(n), nil
+	}
+	code, err := micro.NewAssembler().Assemble(arg)
+	if err != nil || len(code) != 1 || !micro.IsInlineSym(code[0]) {
+		return 0, fmt.Errorf("bad slot %q (want 0-%d or a symbol)", arg, last)
+	}
+	return int(code[0] - micro.SymNil), nil
+}
+
+// showMem prints one memory slot, or with no argument every non-zero one.
+func (s *session) showMem(arg string) {
+	if arg == "" {
+		n := 0
+		for slot := 0; slot < s.vm.MemorySlots(); slot++ {
+			if v := s.vm.MemReadAt(slot); v != 0 {
+				fmt.Printf("  [%3d] = %d\n", slot, v)
+				n++
+			}
+		}
+		if n == 0 {
+			fmt.Println("Memory is all zero")
+		}
+		return
+	}
+	slot, err := s.parseSlot(arg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	fmt.Printf("[%d] = %d\n", slot, s.vm.MemReadAt(slot))
+}
+
+// setMem handles "mem! <slot> <value>".
+func (s *session) setMem(arg string) {
+	parts := strings.Fields(arg)
+	if len(parts) != 2 {
+		fmt.Println("Usage: mem! <slot> <value>")
+		return
+	}
+	slot, err := s.parseSlot(parts[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	v, err := strconv.ParseInt(parts[1], 0, 16)
+	if err != nil {
+		fmt.Printf("Error: bad value %q\n", parts[1])
+		return
+	}
+	s.vm.MemWriteAt(slot, int16(v))
+	fmt.Printf("[%d] = %d\n", slot, v)
+}
+
+// load reads a file into the session: its quotations are defined and its
+// main code, if any, is run. Raw bytecode is just run.
+func (s *session) load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if isBytecode(data) {
+		s.vm.Load(data)
+		s.vm.Halted = false
+		return s.vm.Run()
+	}
+	source := string(data)
+	if err := s.defineQuots(source, false); err != nil {
+		return err
+	}
+	return s.run(extractMain(source))
 }
 
 func printHelp() {
 	fmt.Print(`Commands:
-  quit     - Exit REPL
-  stack    - Show stack
-  clear    - Clear stack and reset
-  debug    - Toggle debug mode
-  help     - Show this help
-
+  quit             - Exit REPL
+  stack            - Show stack
+  clear            - Clear stack and reset
+  debug            - Toggle debug mode
+  quots            - List defined quotations with disassembly
+  mem [slot]       - Show a memory slot (number or symbol), or all non-zero
+  mem! slot value  - Set a memory slot
+  gas              - Show gas left
+  load file        - Define a file's quotations and run its main code
+  help             - Show this help
+
+Multi-line quotations:
+  QUOT name [idx]   ; starts a definition, ended by ENDQUOT
+  ...
+  ENDQUOT
 Instructions:
   Numbers: 0-31 (inline), push.b N (byte), push.w N (word)
   Stack:   dup drop swap over rot dup2 depth clear
@@ -283,13 +597,17 @@ Instructions:
   Compare: = < > (or: eq lt gt)
   Logic:   and or not
   Control: exec ifte loop halt
-  Memory:  @ ! (load store)
+  Memory:  @ ! (load store, slots 0-255)
+           @w !w (load.w store.w, any slot), ld N, st N (slot N)
   I/O:     print . call 0 (newline)
 
 Symbols (prefixed with '):
   'health 'energy 'fear 'anger 'hunger 'enemy 'friend etc.
 
 Quotations: [0] [1] ... [31] for inline, [name] for named
+  idx qdef { body }   ; define quotation idx at run time
+  idx slot len qdef.m ; define it from len bytes of memory at slot
+  byte addr poke      ; patch the running code (needs -self-modify)
 
 Example:
   5 3 + .           ; prints 8
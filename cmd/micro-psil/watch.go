@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+// watchInterval is how often watch checks the file for changes.
+const watchInterval = 300 * time.Millisecond
+
+// watch runs path, then re-assembles and re-runs it every time it changes,
+// printing the stack and the memory slots that differ from the previous
+// run. It runs until interrupted.
+func watch(path string, cfg vmConfig) {
+	var last os.FileInfo
+	prev := cfg.newVM().Memory // memory after the previous run; a fresh VM's at first
+	for run := 1; ; time.Sleep(watchInterval) {
+		info, err := os.Stat(path)
+		if err != nil {
+			if last != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				last = nil
+			}
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		fmt.Printf("=== Run %d: %s (%s) ===\n", run, path, time.Now().Format("15:04:05"))
+		run++
+		vm, err := runSource(data, isBytecode(data), cfg)
+		fmt.Println()
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		if vm == nil {
+			fmt.Println()
+			continue
+		}
+		fmt.Println("Stack:", vm.StackDump())
+		if vm.MaxGas > 0 {
+			fmt.Printf("Gas: %d/%d\n", vm.Gas, vm.MaxGas)
+		}
+		printMemDiff(prev, vm)
+		prev = append([]byte(nil), vm.Memory...)
+		fmt.Println()
+	}
+}
+
+// printMemDiff prints the memory slots of vm that differ from prev.
+func printMemDiff(prev []byte, vm *micro.VM) {
+	old := micro.New()
+	old.Memory = prev
+	n := 0
+	for slot := 0; slot < vm.MemorySlots(); slot++ {
+		was, now := old.MemReadAt(slot), vm.MemReadAt(slot)
+		if was != now {
+			if n == 0 {
+				fmt.Println("Memory:")
+			}
+			fmt.Printf("  [%3d] %d -> %d\n", slot, was, now)
+			n++
+		}
+	}
+	if n == 0 {
+		fmt.Println("Memory: unchanged")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/alecthomas/participle/v2"
+	"github.com/psilLang/psil/pkg/parser"
+	"github.com/psilLang/psil/pkg/vet"
+)
+
+// document is an open file and what the parser makes of it
+type document struct {
+	text   string
+	defs   map[string]definition
+	diags  []Diagnostic
+	tokens []parser.Token
+}
+
+// definition is a DEFINE in a document; offsets are bytes into its text
+type definition struct {
+	name       string
+	nameOffset int
+	start, end int
+	comment    string // the % lines right above it
+}
+
+// analyze parses text. A document that does not parse keeps the
+// definitions before the error, so completion and navigation still work
+// while it is being edited.
+func analyze(text string) *document {
+	doc := &document{text: text, defs: make(map[string]definition), diags: []Diagnostic{}}
+	doc.tokens, _ = parser.Tokenize(text)
+
+	prog, err := parser.Parse(text)
+	if err != nil {
+		doc.diags = append(doc.diags, doc.diagnostic(err))
+	} else if findings, err := vet.Source([]byte(text)); err == nil {
+		for _, f := range findings {
+			doc.diags = append(doc.diags, doc.warning(f))
+		}
+	}
+	if prog == nil {
+		return doc
+	}
+	for _, stmt := range prog.Statements {
+		d := stmt.Definition
+		if d == nil || d.Body == nil || d.EndPos.Line == 0 {
+			continue // not finished
+		}
+		doc.defs[d.Name] = definition{
+			name:       d.Name,
+			nameOffset: d.NamePos().Offset,
+			start:      d.Pos.Offset,
+			end:        d.EndPos.Offset,
+			comment:    commentAbove(text, d.Pos.Offset),
+		}
+	}
+	return doc
+}
+
+// diagnostic reports a parse error at its position
+func (doc *document) diagnostic(err error) Diagnostic {
+	msg, offset := err.Error(), 0
+	var perr participle.Error
+	if errors.As(err, &perr) {
+		msg, offset = perr.Message(), perr.Position().Offset
+	}
+	offset = min(max(offset, 0), len(doc.text))
+	end := offset
+	if t, ok := doc.tokenAt(offset); ok {
+		end = t.Pos.Offset + len(t.Value)
+	} else if offset < len(doc.text) {
+		_, size := utf8.DecodeRuneInString(doc.text[offset:])
+		end = offset + size
+	}
+	return Diagnostic{
+		Range:    Range{doc.position(offset), doc.position(end)},
+		Severity: 1,
+		Source:   "psil",
+		Message:  msg,
+	}
+}
+
+// warning reports a vet finding on the token it points at
+func (doc *document) warning(f vet.Finding) Diagnostic {
+	end := f.Pos.Offset
+	if t, ok := doc.tokenAt(f.Pos.Offset); ok {
+		end = t.Pos.Offset + len(t.Value)
+	}
+	return Diagnostic{
+		Range:    doc.span(f.Pos.Offset, end),
+		Severity: 2,
+		Source:   "psil vet",
+		Message:  f.Message,
+	}
+}
+
+// tokenAt returns the token covering offset, including its end (a cursor
+// just past a word is on the word)
+func (doc *document) tokenAt(offset int) (parser.Token, bool) {
+	for _, t := range doc.tokens {
+		if t.Pos.Offset <= offset && offset <= t.Pos.Offset+len(t.Value) {
+			return t, true
+		}
+	}
+	return parser.Token{}, false
+}
+
+// wordAt returns the word (identifier or operator) at offset
+func (doc *document) wordAt(offset int) (parser.Token, bool) {
+	t, ok := doc.tokenAt(offset)
+	if !ok || (t.Kind != "Ident" && t.Kind != "Operator") {
+		return parser.Token{}, false
+	}
+	return t, true
+}
+
+// commentAbove returns the % comment lines directly above offset's line
+func commentAbove(text string, offset int) string {
+	lines := strings.Split(text[:strings.LastIndex(text[:offset], "\n")+1], "\n")
+	var comment []string
+	for j := len(lines) - 2; j >= 0; j-- {
+		line := strings.TrimSpace(lines[j])
+		if !strings.HasPrefix(line, "%") {
+			break
+		}
+		comment = append([]string{strings.TrimSpace(strings.TrimLeft(line, "%"))}, comment...)
+	}
+	return strings.Join(comment, "\n")
+}
+
+// position converts a byte offset to an LSP position
+func (doc *document) position(offset int) Position {
+	line := strings.Count(doc.text[:offset], "\n")
+	start := strings.LastIndex(doc.text[:offset], "\n") + 1
+	return Position{Line: line, Character: utf16Len(doc.text[start:offset])}
+}
+
+// offset converts an LSP position to a byte offset, clamped to the text
+func (doc *document) offset(p Position) int {
+	start := 0
+	for line := 0; line < p.Line; line++ {
+		next := strings.IndexByte(doc.text[start:], '\n')
+		if next < 0 {
+			return len(doc.text)
+		}
+		start += next + 1
+	}
+	units := 0
+	for j, r := range doc.text[start:] {
+		if r == '\n' || units >= p.Character {
+			return start + j
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(doc.text)
+}
+
+func (doc *document) span(start, end int) Range {
+	return Range{doc.position(start), doc.position(end)}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += len(utf16.Encode([]rune{r}))
+	}
+	return n
+}
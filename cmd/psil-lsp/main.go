@@ -0,0 +1,238 @@
+// psil-lsp is a Language Server Protocol server for PSIL: diagnostics for
+// parse errors and psil vet findings, completion of dictionary words and DEFINEs, hover docs and
+// go-to-definition. Editors start it and talk to it over stdin/stdout.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/interpreter"
+	"github.com/psilLang/psil/pkg/types"
+)
+
+var flagLog = flag.String("log", "", "append a trace of the messages to this file")
+
+// server holds the open documents
+type server struct {
+	conn     *conn
+	docs     map[string]*document
+	builtins []CompletionItem
+	shutdown bool
+}
+
+func main() {
+	flag.Parse()
+	log.SetOutput(io.Discard)
+	if *flagLog != "" {
+		f, err := os.OpenFile(*flagLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "log: %v\n", err)
+			os.Exit(1)
+		}
+		log.SetOutput(f) // unbuffered, so nothing is lost at os.Exit
+	}
+
+	s := &server{conn: newConn(os.Stdin, os.Stdout), docs: make(map[string]*document), builtins: builtinItems()}
+	for {
+		body, err := s.conn.read()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("read: %v", err)
+			}
+			os.Exit(1) // the client went away without exit
+		}
+		log.Printf("<- %s", body)
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			s.conn.replyError(nil, codeParseError, err.Error())
+			continue
+		}
+		if req.Method == "exit" {
+			if s.shutdown {
+				os.Exit(0)
+			}
+			os.Exit(1)
+		}
+		if err := s.handle(req); err != nil {
+			log.Printf("write: %v", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// builtinItems lists the interpreter's dictionary for completion, opt-in
+// words included
+func builtinItems() []CompletionItem {
+	var items []CompletionItem
+	for name, v := range interpreter.New(interpreter.WithCapabilities(interpreter.CapAll)).Dictionary {
+		kind := kindFunction
+		if _, ok := v.(*types.Builtin); !ok {
+			kind = kindConstant
+		}
+		doc, _ := interpreter.WordDoc(name)
+		items = append(items, CompletionItem{Label: name, Kind: kind, Detail: doc})
+	}
+	sort.Slice(items, func(a, b int) bool { return items[a].Label < items[b].Label })
+	return append(items, CompletionItem{Label: "DEFINE", Kind: kindKeyword, Detail: "DEFINE name == [ ... ]."})
+}
+
+// handle answers a request or acts on a notification
+func (s *server) handle(req request) error {
+	var result any
+	var err error
+	switch req.Method {
+	case "initialize":
+		var init InitializeResult
+		init.Capabilities.TextDocumentSync = 1
+		init.Capabilities.HoverProvider = true
+		init.Capabilities.DefinitionProvider = true
+		init.ServerInfo.Name = "psil-lsp"
+		result = init
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		var p DidOpenTextDocumentParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			return s.update(p.TextDocument.URI, p.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		var p DidChangeTextDocumentParams
+		if err = json.Unmarshal(req.Params, &p); err == nil && len(p.ContentChanges) > 0 {
+			return s.update(p.TextDocument.URI, p.ContentChanges[len(p.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		var p DidCloseTextDocumentParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			delete(s.docs, p.TextDocument.URI)
+			return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: p.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		}
+	case "textDocument/completion":
+		var p TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			result = s.completion(p)
+		}
+	case "textDocument/hover":
+		var p TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			result = s.hover(p)
+		}
+	case "textDocument/definition":
+		var p TextDocumentPositionParams
+		if err = json.Unmarshal(req.Params, &p); err == nil {
+			result = s.definition(p)
+		}
+	default:
+		if req.ID == nil {
+			return nil // notifications we do not need, such as initialized
+		}
+		return s.conn.replyError(req.ID, codeMethodNotFound, "unsupported method "+req.Method)
+	}
+
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return s.conn.replyError(req.ID, codeInvalidParams, err.Error())
+	}
+	return s.conn.reply(req.ID, result)
+}
+
+// update re-analyzes a document and publishes its diagnostics
+func (s *server) update(uri, text string) error {
+	doc := analyze(text)
+	s.docs[uri] = doc
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: doc.diags})
+}
+
+// completion offers the document's DEFINEs, then the builtins
+func (s *server) completion(p TextDocumentPositionParams) []CompletionItem {
+	items := []CompletionItem{}
+	if doc := s.docs[p.TextDocument.URI]; doc != nil {
+		var names []string
+		for name := range doc.defs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			def := doc.defs[name]
+			items = append(items, CompletionItem{
+				Label: name, Kind: kindFunction,
+				Detail: firstLine(doc.text[def.start:def.end]), Documentation: def.comment,
+			})
+		}
+	}
+	return append(items, s.builtins...)
+}
+
+// hover shows a DEFINE's source and comment, or a builtin's doc
+func (s *server) hover(p TextDocumentPositionParams) *Hover {
+	doc := s.docs[p.TextDocument.URI]
+	if doc == nil {
+		return nil
+	}
+	word, ok := doc.wordAt(doc.offset(p.Position))
+	if !ok {
+		return nil
+	}
+	span := doc.span(word.Pos.Offset, word.Pos.Offset+len(word.Value))
+	var text string
+	if def, d, ok := s.lookup(doc, word.Value); ok {
+		text = "```psil\n" + d.text[def.start:def.end] + "\n```"
+		if def.comment != "" {
+			text += "\n\n" + def.comment
+		}
+	} else if help, ok := interpreter.WordDoc(word.Value); ok {
+		text = fmt.Sprintf("**%s** `%s`", word.Value, help)
+	} else {
+		return nil
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: text}, Range: &span}
+}
+
+// definition finds where the word at the cursor is DEFINEd
+func (s *server) definition(p TextDocumentPositionParams) []Location {
+	locs := []Location{}
+	doc := s.docs[p.TextDocument.URI]
+	if doc == nil {
+		return locs
+	}
+	word, ok := doc.wordAt(doc.offset(p.Position))
+	if !ok {
+		return locs
+	}
+	if def, ok := doc.defs[word.Value]; ok {
+		return append(locs, Location{URI: p.TextDocument.URI, Range: doc.span(def.nameOffset, def.nameOffset+len(def.name))})
+	}
+	for uri, d := range s.docs {
+		if def, ok := d.defs[word.Value]; ok {
+			locs = append(locs, Location{URI: uri, Range: d.span(def.nameOffset, def.nameOffset+len(def.name))})
+		}
+	}
+	return locs
+}
+
+// lookup finds a DEFINE, in doc first, then in the other open documents
+func (s *server) lookup(doc *document, name string) (definition, *document, bool) {
+	if def, ok := doc.defs[name]; ok {
+		return def, doc, true
+	}
+	for _, d := range s.docs {
+		if def, ok := d.defs[name]; ok {
+			return def, d, true
+		}
+	}
+	return definition{}, nil, false
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
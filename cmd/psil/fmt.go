@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/psilLang/psil/pkg/format"
+)
+
+// runFmt is "psil fmt": it formats the files given, or stdin to stdout,
+// and returns the exit code
+func runFmt(args []string) int {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result back to the files instead of printing it")
+	list := fs.Bool("l", false, "list the files whose formatting differs")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: psil fmt [-w] [-l] [file.psil ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		src, err := io.ReadAll(os.Stdin)
+		if err == nil {
+			src, err = format.Source(src)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: <stdin>: %v\n", err)
+			return 1
+		}
+		os.Stdout.Write(src)
+		return 0
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		if err := fmtFile(path, *write, *list); err != nil {
+			fmt.Fprintf(os.Stderr, "fmt: %s: %v\n", path, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+func fmtFile(path string, write, list bool) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+	changed := !bytes.Equal(src, out)
+	if list && changed {
+		fmt.Println(path)
+	}
+	if write {
+		if changed {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(path, out, info.Mode().Perm())
+		}
+		return nil
+	}
+	if !list {
+		os.Stdout.Write(out)
+	}
+	return nil
+}
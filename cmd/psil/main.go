@@ -7,6 +7,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
 	"strings"
 
 	"github.com/psilLang/psil/pkg/interpreter"
@@ -15,20 +16,46 @@ import (
 )
 
 var (
-	flagDebug = flag.Bool("debug", false, "Enable debug mode (show flags after each command)")
-	flagGas   = flag.Int("gas", 0, "Set gas limit (0 = unlimited)")
-	flagQuiet = flag.Bool("quiet", false, "Quiet mode (no banner)")
+	flagDebug   = flag.Bool("debug", false, "Enable debug mode (show flags after each command)")
+	flagGas     = flag.Int("gas", 0, "Set gas limit (0 = unlimited)")
+	flagQuiet   = flag.Bool("quiet", false, "Quiet mode (no banner)")
+	flagTimeout = flag.Duration("timeout", 0, "Stop each run after this long, e.g. 5s (0 = no limit)")
+	flagExec    = flag.Bool("allow-exec", false, "Let scripts run other programs with exec")
+	flagNetwork = flag.Bool("allow-network", false, "Let scripts open sockets (socket-connect and friends)")
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "vet":
+			os.Exit(runVet(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		}
+	}
+
+	var plugins pluginFlag
+	flag.Var(&plugins, "plugin", "Load a Go plugin of extra words (built with -buildmode=plugin; repeatable)")
 	flag.Parse()
 
 	// Create interpreter
-	interp := interpreter.New()
+	caps := interpreter.CapDefault
+	if *flagExec {
+		caps |= interpreter.CapExec
+	}
+	if *flagNetwork {
+		caps |= interpreter.CapNetwork
+	}
+	interp := interpreter.New(interpreter.WithGas(*flagGas), interpreter.WithTimeout(*flagTimeout),
+		interpreter.WithCapabilities(caps))
 	interp.Debug = *flagDebug
-	if *flagGas > 0 {
-		interp.MaxGas = *flagGas
-		interp.Gas = *flagGas
+	for _, path := range plugins {
+		if err := loadPlugin(interp, path); err != nil {
+			fmt.Fprintf(os.Stderr, "plugin: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	args := flag.Args()
@@ -221,8 +248,21 @@ func executeREPL(interp *interpreter.Interpreter, source string) {
 		fmt.Printf("Defined: %s\n", name)
 	}
 
-	// Execute expressions
-	if err := interp.Run(values); err != nil {
+	// Execute expressions; Ctrl-C stops a runaway program, not the REPL
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-sig:
+			interp.Interrupt()
+		case <-done:
+		}
+	}()
+	err = interp.Run(values)
+	close(done)
+	signal.Stop(sig)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 	}
 
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"plugin"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/interpreter"
+)
+
+// pluginFlag collects the -plugin paths (repeatable, or comma-separated)
+type pluginFlag []string
+
+func (f *pluginFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *pluginFlag) Set(spec string) error {
+	for _, path := range strings.Split(spec, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			*f = append(*f, path)
+		}
+	}
+	return nil
+}
+
+// loadPlugin opens a Go plugin (built with -buildmode=plugin against the
+// same PSIL version) and lets it add its words through its entry point:
+//
+//	func RegisterWords(interp *interpreter.Interpreter) error
+func loadPlugin(interp *interpreter.Interpreter, path string) error {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup("RegisterWords")
+	if err != nil {
+		return fmt.Errorf("%s: no RegisterWords entry point", path)
+	}
+	register, ok := sym.(func(*interpreter.Interpreter) error)
+	if !ok {
+		return fmt.Errorf("%s: RegisterWords is %T, want func(*interpreter.Interpreter) error", path, sym)
+	}
+	if err := register(interp); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/psilLang/psil/pkg/golden"
+)
+
+// runTest is "psil test": it runs the programs under the paths given
+// (examples by default) against their .expected output and returns the
+// exit code
+func runTest(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	update := fs.Bool("update", false, "record the current output in the .expected files")
+	fs.DurationVar(&golden.Timeout, "timeout", golden.Timeout, "fail a program that runs longer than this")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: psil test [-update] [-timeout d] [dir|file.psil ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	roots := fs.Args()
+	if len(roots) == 0 {
+		roots = []string{"examples"}
+	}
+
+	code := 0
+	for _, root := range roots {
+		files, err := golden.Files(root)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "test: %v\n", err)
+			code = 1
+		}
+		for _, path := range files {
+			if err := golden.Check(path, *update); err != nil {
+				fmt.Printf("FAIL %s: %v\n", path, err)
+				code = 1
+				continue
+			}
+			if *update {
+				fmt.Printf("wrote %s\n", golden.ExpectedPath(path))
+			} else {
+				fmt.Printf("ok   %s\n", path)
+			}
+		}
+	}
+	return code
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/psilLang/psil/pkg/vet"
+)
+
+// runVet is "psil vet": it reports likely mistakes in the files given and
+// returns the exit code, 1 if anything was found
+func runVet(args []string) int {
+	fs := flag.NewFlagSet("vet", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: psil vet file.psil ...")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	code := 0
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "vet: %v\n", err)
+			code = 1
+			continue
+		}
+		findings, err := vet.Source(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "vet: %s: %v\n", path, err)
+			code = 1
+			continue
+		}
+		for _, f := range findings {
+			fmt.Printf("%s:%s\n", path, f)
+			code = 1
+		}
+	}
+	return code
+}
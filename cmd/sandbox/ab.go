@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// abScenario is one side of an A/B comparison: the sweep parameters it
+// sets over the base config, as "name=value;name=value" or the path of a
+// scenario file that sets them.
+type abScenario struct {
+	name string
+	spec string
+}
+
+// apply sets the scenario's parameters on cfg.
+func (sc abScenario) apply(cfg *simConfig) error {
+	settings, err := sc.settings()
+	if err != nil {
+		return fmt.Errorf("%s: %w", sc.name, err)
+	}
+	for _, set := range settings {
+		if set.name == "seed" {
+			continue // both sides run -seed's seeds
+		}
+		param, ok := sweepParams[set.name]
+		if !ok {
+			return fmt.Errorf("%s: %w", sc.name, unknownParam(set.name))
+		}
+		if err := param.set(cfg, set.value); err != nil {
+			return fmt.Errorf("%s: %s: bad value %q", sc.name, set.name, set.value)
+		}
+	}
+	return nil
+}
+
+// settings returns the parameters the scenario sets, reading them from its
+// scenario file if its spec names one rather than listing them.
+func (sc abScenario) settings() ([]scenarioSetting, error) {
+	if sc.spec != "" && !strings.Contains(sc.spec, "=") {
+		settings, err := readScenario(sc.spec)
+		for i := range settings {
+			if settings[i].value == "" {
+				settings[i].value = "true" // a bool alone
+			}
+		}
+		return settings, err
+	}
+	var settings []scenarioSetting
+	for _, part := range strings.Split(sc.spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=value", part)
+		}
+		settings = append(settings, scenarioSetting{name: strings.TrimSpace(name), value: strings.TrimSpace(val)})
+	}
+	return settings, nil
+}
+
+// abMetric is a timeline metric the comparison charts.
+type abMetric struct {
+	label string
+	fn    func(timePoint) int
+}
+
+var abMetrics = []abMetric{
+	{"alive", func(tp timePoint) int { return tp.alive }},
+	{"avgFit", func(tp timePoint) int { return tp.avgFit }},
+	{"bestFit", func(tp timePoint) int { return tp.bestFit }},
+	{"genomeAvg", func(tp timePoint) int { return tp.genomeAvg }},
+	{"trades", func(tp timePoint) int { return tp.trades }},
+	{"teaches", func(tp timePoint) int { return tp.teaches }},
+	{"gold", func(tp timePoint) int { return tp.gold }},
+	{"diversity", func(tp timePoint) int { return tp.diversity }},
+}
+
+// abRows is how many sampled ticks the timeline delta table shows.
+const abRows = 8
+
+// runAB runs scenarios a and b over the same seeds (seed..seed+seeds-1),
+// jobs runs at a time, and prints how b differs from a: final metrics,
+// sparkline pairs drawn to a shared scale, and the deltas through the run.
+// With several seeds every figure is the mean over them.
+func runAB(cfg simConfig, a, b abScenario, seeds, jobs int) error {
+	seeds = max(seeds, 1)
+	cfg.verbose = false
+	cfg.snapEvery = 0
+	cfg.record = ""
+	cfg.lineage = ""
+	if cfg.tlEvery = cfg.ticks / 80; cfg.tlEvery < 1 {
+		cfg.tlEvery = 1 // shared, so both timelines sample the same ticks
+	}
+	var cfgs []simConfig
+	for _, sc := range []abScenario{a, b} {
+		for s := 0; s < seeds; s++ {
+			c := cfg
+			c.seed = cfg.seed + int64(s)
+			if err := sc.apply(&c); err != nil {
+				return err
+			}
+			cfgs = append(cfgs, c)
+		}
+	}
+
+	runs := runBatch(cfgs, jobs, func(i, done int) {
+		sc := a
+		if i >= seeds {
+			sc = b
+		}
+		status.event("ab-run", -1, logFields{"done": done, "runs": len(cfgs), "scenario": sc.name, "seed": cfgs[i].seed},
+			fmt.Sprintf("[%d/%d] %s seed=%d", done, len(cfgs), sc.name, cfgs[i].seed))
+	})
+	printAB(cfg, seeds, [2]abScenario{a, b}, [2][]sweepRun{runs[:seeds], runs[seeds:]})
+	return nil
+}
+
+// printAB writes the comparison report to stderr.
+func printAB(cfg simConfig, seeds int, sc [2]abScenario, runs [2][]sweepRun) {
+	seedText := fmt.Sprintf("seed=%d", cfg.seed)
+	if seeds > 1 {
+		seedText = fmt.Sprintf("seeds %d..%d", cfg.seed, cfg.seed+int64(seeds-1))
+	}
+	fmt.Fprintf(os.Stderr, "\n=== A/B Comparison (%s, npcs=%d, ticks=%d) ===\n", seedText, cfg.npcs, cfg.ticks)
+	for i, s := range sc {
+		spec := s.spec
+		if spec == "" {
+			spec = "(base config)"
+		}
+		extinct := 0
+		for _, r := range runs[i] {
+			if r.extinct {
+				extinct++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "%-10s %s", s.name+":", spec)
+		if extinct > 0 {
+			fmt.Fprintf(os.Stderr, " (%d/%d extinct)", extinct, len(runs[i]))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	// Final metrics; the delta column is named for what it subtracts, B-A
+	delta := sc[1].name + "-" + sc[0].name
+	width := max(10, len(delta))
+	fmt.Fprintf(os.Stderr, "\n%-16s %10s %10s %*s %8s\n", "", sc[0].name, sc[1].name, width, delta, "%")
+	finals := []struct {
+		label string
+		fn    func(sweepRun) float64
+	}{
+		{"alive", func(r sweepRun) float64 { return float64(r.result.alive) }},
+		{"avgFit", func(r sweepRun) float64 { return float64(r.result.avgFit) }},
+		{"bestFit", func(r sweepRun) float64 { return float64(r.result.bestFit) }},
+		{"trades", func(r sweepRun) float64 { return float64(r.result.trades) }},
+		{"teaches", func(r sweepRun) float64 { return float64(r.result.teaches) }},
+		{"genomeAvg", func(r sweepRun) float64 { return float64(r.result.genomeAvg) }},
+		{"totalGold", func(r sweepRun) float64 { return float64(r.result.totalGold) }},
+		{"brokenJumps", func(r sweepRun) float64 { return float64(r.result.brokenJumps) }},
+		{"diversity", func(r sweepRun) float64 { return r.metric("diversity") }},
+		{"unique", func(r sweepRun) float64 { return r.metric("unique") }},
+	}
+	for _, f := range finals {
+		var mean [2]float64
+		for i := range runs {
+			for _, r := range runs[i] {
+				mean[i] += f.fn(r) / float64(len(runs[i]))
+			}
+		}
+		diff := mean[1] - mean[0]
+		pct := "-"
+		if mean[0] != 0 {
+			pct = fmt.Sprintf("%+.1f", 100*diff/mean[0])
+		}
+		fmt.Fprintf(os.Stderr, "%-16s %10.2f %10.2f %+*.2f %8s\n", f.label, mean[0], mean[1], width, diff, pct)
+	}
+
+	// Timelines, averaged over the seeds
+	var series [2][][]int // by scenario, then abMetrics
+	for i := range runs {
+		for _, m := range abMetrics {
+			series[i] = append(series[i], meanSeries(runs[i], m.fn))
+		}
+	}
+
+	fmt.Fprintln(os.Stderr)
+	for m, metric := range abMetrics {
+		sa, sb := series[0][m], series[1][m]
+		lo, hi := seriesRange(sa, sb)
+		fmt.Fprintln(os.Stderr, sparklineScaled(metric.label+" ("+sc[0].name[:1]+")", sa, lo, hi))
+		fmt.Fprintln(os.Stderr, sparklineScaled(metric.label+" ("+sc[1].name[:1]+")", sb, lo, hi))
+	}
+
+	n := min(len(series[0][0]), len(series[1][0]))
+	if n == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n=== Delta %s-%s through the run ===\n%-8s", sc[1].name, sc[0].name, "tick")
+	for _, m := range abMetrics {
+		fmt.Fprintf(os.Stderr, " %10s", m.label)
+	}
+	fmt.Fprintln(os.Stderr)
+	for row := 0; row < min(abRows, n); row++ {
+		i := (n - 1) * row / max(min(abRows, n)-1, 1)
+		fmt.Fprintf(os.Stderr, "%-8d", i*cfg.tlEvery)
+		for m := range abMetrics {
+			fmt.Fprintf(os.Stderr, " %+10d", series[1][m][i]-series[0][m][i])
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+}
+
+// meanSeries averages fn over the runs' timelines, sample by sample; a
+// run that went extinct early stops counting.
+func meanSeries(runs []sweepRun, fn func(timePoint) int) []int {
+	var sum, count []int
+	for _, r := range runs {
+		for i, tp := range r.result.timeline {
+			if i == len(sum) {
+				sum, count = append(sum, 0), append(count, 0)
+			}
+			sum[i] += fn(tp)
+			count[i]++
+		}
+	}
+	for i := range sum {
+		sum[i] /= count[i]
+	}
+	return sum
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// assertFailExit is the exit status of a run that fails an -assert check.
+const assertFailExit = 3
+
+// loadAssertions reads the -assert checks, or -assert-file's (one per line)
+// when set.
+func loadAssertions(spec, path string) ([]sandbox.Assertion, error) {
+	if path != "" {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec = string(src)
+	}
+	as, err := sandbox.ParseAssertions(spec)
+	if err != nil {
+		return nil, err
+	}
+	return as.Checks, nil
+}
+
+// installAssertions runs a fresh copy of checks on sched, reporting each
+// failure as it happens. It returns nil when there are none.
+func installAssertions(sched *sandbox.Scheduler, checks []sandbox.Assertion) *sandbox.Assertions {
+	if len(checks) == 0 {
+		return nil
+	}
+	as := &sandbox.Assertions{Checks: append([]sandbox.Assertion(nil), checks...)}
+	as.Install(sched)
+	sched.Subscribe(sandbox.SubscriberFunc(func(ev sandbox.Event) {
+		if e, ok := ev.(sandbox.AssertionFailed); ok {
+			a := as.Checks[e.Check]
+			status.event("assert-failed", e.Tick, logFields{"assertion": a.Text, "metric": a.Metric, "got": a.Got},
+				fmt.Sprintf("Tick %d: assertion failed: %s (%s=%d)", e.Tick, a.Text, a.Metric, a.Got))
+		}
+	}))
+	return as
+}
+
+// printAssertions prints the verdict on every check.
+func printAssertions(as *sandbox.Assertions, failed int) {
+	fmt.Fprintf(os.Stderr, "\n=== Assertions (%d/%d passed) ===\n", len(as.Checks)-failed, len(as.Checks))
+	for _, a := range as.Checks {
+		verdict := "PASS"
+		if !a.Passed {
+			verdict = "FAIL"
+		}
+		fmt.Fprintf(os.Stderr, "  %s  %-40s tick %d, %s=%d\n", verdict, a.Text, a.Tick, a.Metric, a.Got)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// defaultBrains holds the built-in role brains, written in PSIL and
+// compiled to genomes at startup.
+//
+//go:embed brains/*.psil
+var defaultBrains embed.FS
+
+// Seed genomes for each role, from brains/<role>.psil.
+var (
+	traderGenome  = mustBrain("trader")
+	foragerGenome = mustBrain("forager")
+	crafterGenome = mustBrain("crafter")
+	teacherGenome = mustBrain("teacher")
+	farmerGenome  = mustBrain("farmer")
+	fighterGenome = mustBrain("fighter")
+	healerGenome  = mustBrain("healer")
+	hunterGenome  = mustBrain("hunter") // predators (-predators)
+)
+
+// roleGenomes maps a brain file name to the genome it replaces.
+var roleGenomes = map[string]*[]byte{
+	"trader":  &traderGenome,
+	"forager": &foragerGenome,
+	"crafter": &crafterGenome,
+	"teacher": &teacherGenome,
+	"farmer":  &farmerGenome,
+	"fighter": &fighterGenome,
+	"healer":  &healerGenome,
+	"hunter":  &hunterGenome,
+}
+
+func mustBrain(role string) []byte {
+	src, err := defaultBrains.ReadFile("brains/" + role + ".psil")
+	if err != nil {
+		panic(err)
+	}
+	genome, err := sandbox.CompileBrain(string(src))
+	if err != nil {
+		panic(fmt.Sprintf("brains/%s.psil: %v", role, err))
+	}
+	return genome
+}
+
+// loadBrains compiles every <role>.psil in dir over that role's genome.
+// Roles without a file keep their built-in brain.
+func loadBrains(dir string) error {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.psil"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no .psil brains in %s", dir)
+	}
+	for _, path := range paths {
+		role := strings.TrimSuffix(filepath.Base(path), ".psil")
+		genome, ok := roleGenomes[role]
+		if !ok {
+			roles := make([]string, 0, len(roleGenomes))
+			for r := range roleGenomes {
+				roles = append(roles, r)
+			}
+			sort.Strings(roles)
+			return fmt.Errorf("%s: unknown role %q (want one of %s)", path, role, strings.Join(roles, ", "))
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		compiled, err := sandbox.CompileBrain(string(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(compiled) == 0 {
+			return fmt.Errorf("%s: empty brain", path)
+		}
+		*genome = compiled
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// runState is the part of a run kept here rather than in the scheduler,
+// saved as the Extra of its checkpoints.
+type runState struct {
+	Stage       int                        `json:"stage"` // curriculum stages reached
+	Checks      []sandbox.Assertion        `json:"checks,omitempty"`
+	EpochDeaths [][sandbox.DeathCauses]int `json:"epoch_deaths,omitempty"`
+	PredatorRNG []byte                     `json:"predator_rng,omitempty"` // the predators' GA's source (-predators)
+}
+
+// writeCheckpoint saves the run to path, replacing the file only once the
+// new checkpoint is complete.
+func writeCheckpoint(path string, sched *sandbox.Scheduler, src *sandbox.Source, gas []*sandbox.GA, st runState) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = sandbox.SaveCheckpoint(f, sched, src, gas, st)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// resumeCheckpoint restores the run saved at path into sched, src and gas
+// and returns the rest of its state.
+func resumeCheckpoint(path string, sched *sandbox.Scheduler, src *sandbox.Source, gas []*sandbox.GA) (runState, error) {
+	var st runState
+	f, err := os.Open(path)
+	if err != nil {
+		return st, err
+	}
+	defer f.Close()
+	cp, err := sandbox.LoadCheckpoint(f)
+	if err != nil {
+		return st, err
+	}
+	if err := cp.Restore(sched, src, gas); err != nil {
+		return st, err
+	}
+	if len(cp.Extra) > 0 {
+		err = json.Unmarshal(cp.Extra, &st)
+	}
+	return st, err
+}
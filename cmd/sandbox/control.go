@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+const controlHelp = "p=pause r=resume n [N]=step N ticks speed T=ticks/sec (0=max) q=stop help=inspection commands"
+
+const consoleHelp = `  npc ID               show an NPC
+  top [N]              the N fittest NPCs (default 5)
+  genome ID            its genome, disassembled
+  setgenome ID HEX     replace its genome (append freeze to keep it out of evolution)
+  freeze ID            keep its genome out of evolution (thaw ID hands it back)
+  mem ID               its non-zero VM memory slots
+  poke ID SLOT VALUE   write a VM memory slot (0-255)
+  set ID FIELD VALUE   health, energy, gold, stress, fitness or age
+  give ID ITEM         hand it an item (none takes it away)
+  kill ID              it dies at the next tick
+  infect ID            make it ill
+  spawn TILE X Y       food, tool, weapon, treasure, crystal, poison, wall or empty
+  blight               wipe out about half the food`
+
+// spawnTiles are the tiles the console can place.
+var spawnTiles = map[string]byte{
+	"empty": sandbox.TileEmpty, "wall": sandbox.TileWall, "food": sandbox.TileFood,
+	"tool": sandbox.TileTool, "weapon": sandbox.TileWeapon, "treasure": sandbox.TileTreasure,
+	"crystal": sandbox.TileCrystal, "poison": sandbox.TilePoison,
+}
+
+// console runs control and inspection commands against a running
+// simulation, from stdin (-control) or the dashboard (-serve). Commands
+// that touch the world run between ticks through Scheduler.Do.
+type console struct {
+	w     *sandbox.World
+	sched *sandbox.Scheduler
+}
+
+// readControl runs one command per line from in until in closes or a
+// command stops the simulation.
+func readControl(c *console, in io.Reader, out io.Writer) {
+	fmt.Fprintln(out, "control:", controlHelp)
+	sc := bufio.NewScanner(in)
+	for sc.Scan() {
+		if !c.exec(sc.Text(), out) {
+			return
+		}
+	}
+}
+
+// exec runs one command line, writing its output to out. It returns false
+// once the simulation has been stopped.
+func (c *console) exec(line string, out io.Writer) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return true
+	}
+	arg := 0.0
+	switch fields[0] {
+	case "n", "step", "speed":
+		if len(fields) > 1 {
+			v, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil {
+				fmt.Fprintf(out, "control: bad number %q\n", fields[1])
+				return true
+			}
+			arg = v
+		}
+	}
+	switch fields[0] {
+	case "p", "pause":
+		c.sched.Pause()
+		fmt.Fprintln(out, "control: paused")
+	case "r", "resume":
+		c.sched.Resume()
+		fmt.Fprintln(out, "control: running")
+	case "n", "step":
+		c.sched.Step(max(int(arg), 1))
+	case "speed":
+		c.sched.SetSpeed(arg)
+	case "q", "quit":
+		c.sched.Stop()
+		return false
+	case "help", "?":
+		fmt.Fprintf(out, "control: %s\n%s\n", controlHelp, consoleHelp)
+	default:
+		var err error
+		if !c.sched.Do(func() { err = c.inspect(fields, out) }) {
+			err = fmt.Errorf("the simulation has stopped")
+		}
+		if err != nil {
+			fmt.Fprintf(out, "control: %v\n", err)
+		}
+	}
+	return true
+}
+
+// inspect runs a world command. It is called between ticks.
+func (c *console) inspect(fields []string, out io.Writer) error {
+	args := fields[1:]
+	num := func(i int) (int, error) {
+		if i >= len(args) {
+			return 0, fmt.Errorf("%s: missing argument (help lists them)", fields[0])
+		}
+		v, err := strconv.Atoi(args[i])
+		if err != nil {
+			return 0, fmt.Errorf("bad number %q", args[i])
+		}
+		return v, nil
+	}
+
+	switch fields[0] {
+	case "top":
+		n := 5
+		if len(args) > 0 {
+			var err error
+			if n, err = num(0); err != nil {
+				return err
+			}
+		}
+		for _, npc := range fittest(c.w.NPCs, n) {
+			fmt.Fprintf(out, "NPC %-5d fitness=%-6d age=%-5d at %d,%d item=%s\n",
+				npc.ID, npc.Fitness, npc.Age, npc.X, npc.Y, itemName(npc.Item))
+		}
+		return nil
+	case "spawn":
+		if len(args) == 0 {
+			return fmt.Errorf("spawn: missing argument (help lists them)")
+		}
+		tile, ok := spawnTiles[args[0]]
+		if !ok {
+			return fmt.Errorf("spawn: unknown tile %q", args[0])
+		}
+		args = args[1:]
+		x, err := num(0)
+		if err != nil {
+			return err
+		}
+		y, err := num(1)
+		if err != nil {
+			return err
+		}
+		if !c.w.InBounds(x, y) {
+			return fmt.Errorf("spawn: %d,%d is off the map", x, y)
+		}
+		c.w.SetTile(x, y, sandbox.MakeTile(tile))
+		if tile == sandbox.TilePoison {
+			c.w.PoisonTTL[y*c.w.Size+x] = c.w.Tick
+		}
+		fmt.Fprintf(out, "%s at %d,%d\n", fields[1], x, y)
+		return nil
+	case "blight":
+		fmt.Fprintf(out, "blight destroyed %d food\n", c.sched.Blight())
+		return nil
+	}
+
+	// The rest act on one living NPC
+	switch fields[0] {
+	case "npc", "genome", "setgenome", "freeze", "thaw", "mem", "poke", "set", "give", "kill", "infect":
+	default:
+		return fmt.Errorf("unknown command %q (help lists them)", fields[0])
+	}
+	id, err := num(0)
+	if err != nil {
+		return err
+	}
+	npc := c.w.NPCByID(uint16(id))
+	if npc == nil || !npc.Alive() {
+		return fmt.Errorf("no living NPC %d", id)
+	}
+	args = args[1:]
+	switch fields[0] {
+	case "npc":
+		fmt.Fprintf(out, "NPC %d at %d,%d hp=%d energy=%d age=%d fitness=%d\n",
+			npc.ID, npc.X, npc.Y, npc.Health, npc.Energy, npc.Age, npc.Fitness)
+		fmt.Fprintf(out, "  item=%s gold=%d stress=%d clan=%d infection=%d predator=%v frozen=%v genome=%d bytes\n",
+			itemName(npc.Item), npc.Gold, npc.Stress, npc.Clan, npc.Infection, npc.Predator, npc.Frozen, len(npc.Genome))
+		if mods := modLabels(npc.Mods); len(mods) > 0 {
+			fmt.Fprintf(out, "  mods: %s\n", strings.Join(mods, " "))
+		}
+	case "genome":
+		fmt.Fprintf(out, "NPC %d genome (%d bytes) %s\n", npc.ID, len(npc.Genome), hex.EncodeToString(npc.Genome))
+		fmt.Fprint(out, sandbox.ExplainGenome(npc.Genome))
+	case "setgenome":
+		if len(args) == 0 {
+			return fmt.Errorf("setgenome: missing genome")
+		}
+		genome, err := hex.DecodeString(args[0])
+		if err != nil || len(genome) == 0 {
+			return fmt.Errorf("setgenome: bad hex genome %q", args[0])
+		}
+		freeze := len(args) > 1 && args[1] == "freeze"
+		if err := c.sched.SwapGenome(npc.ID, genome, freeze); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "NPC %d genome replaced (%d bytes)%s\n", npc.ID, len(genome), frozenNote(freeze))
+	case "freeze", "thaw":
+		npc.Frozen = fields[0] == "freeze"
+		fmt.Fprintf(out, "NPC %d%s\n", npc.ID, frozenNote(npc.Frozen))
+	case "mem":
+		vm := c.sched.VM(npc)
+		for slot := 0; slot < 256; slot++ {
+			if v := vm.MemRead(byte(slot)); v != 0 {
+				fmt.Fprintf(out, "  %3d %-14s %d\n", slot, sandbox.MemoryName(byte(slot)), v)
+			}
+		}
+	case "poke":
+		slot, err := num(0)
+		if err != nil {
+			return err
+		}
+		v, err := num(1)
+		if err != nil {
+			return err
+		}
+		if slot < 0 || slot > 255 || v < -32768 || v > 32767 {
+			return fmt.Errorf("poke: slot must be 0-255 and the value 16-bit")
+		}
+		c.sched.VM(npc).MemWrite(byte(slot), int16(v))
+		fmt.Fprintf(out, "NPC %d mem[%d] = %d\n", npc.ID, slot, v)
+	case "set":
+		if len(args) == 0 {
+			return fmt.Errorf("set: missing field")
+		}
+		name := args[0]
+		field := map[string]*int{
+			"health": &npc.Health, "energy": &npc.Energy, "gold": &npc.Gold,
+			"stress": &npc.Stress, "fitness": &npc.Fitness, "age": &npc.Age,
+		}[name]
+		if field == nil {
+			return fmt.Errorf("set: unknown field %q", name)
+		}
+		args = args[1:]
+		v, err := num(0)
+		if err != nil {
+			return err
+		}
+		*field = v
+		fmt.Fprintf(out, "NPC %d %s=%d\n", npc.ID, name, v)
+	case "give":
+		if len(args) == 0 {
+			return fmt.Errorf("give: missing item")
+		}
+		item, err := parseItem(args[0])
+		if err != nil {
+			return err
+		}
+		npc.SetItem(item)
+		fmt.Fprintf(out, "NPC %d holds %s\n", npc.ID, itemName(item))
+	case "kill":
+		npc.Health = 0
+		fmt.Fprintf(out, "NPC %d dies at the next tick\n", npc.ID)
+	case "infect":
+		if !c.sched.Infect(npc) {
+			return fmt.Errorf("NPC %d is already ill or immune", npc.ID)
+		}
+		fmt.Fprintf(out, "NPC %d infected\n", npc.ID)
+	}
+	return nil
+}
+
+// frozenNote describes whether an NPC is kept out of evolution.
+func frozenNote(frozen bool) string {
+	if frozen {
+		return ", frozen out of evolution"
+	}
+	return ", evolving"
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// controlRequest is one command on the -control-socket: a JSON object per
+// line. Only the fields of its command matter.
+type controlRequest struct {
+	Cmd    string  `json:"cmd"`
+	N      int     `json:"n,omitempty"`      // step: ticks (default 1); top: NPCs (default 5)
+	TPS    float64 `json:"tps,omitempty"`    // speed: ticks per second, 0 = flat out
+	ID     int     `json:"id,omitempty"`     // npc, swap
+	Genome string  `json:"genome,omitempty"` // inject, swap: hex
+	Freeze bool    `json:"freeze,omitempty"` // swap: keep the NPC out of evolution
+	Count  int     `json:"count,omitempty"`  // inject: NPCs (default 1)
+	X      *int    `json:"x,omitempty"`      // inject: position (default random)
+	Y      *int    `json:"y,omitempty"`
+	Grid   bool    `json:"grid,omitempty"` // snapshot: include the tiles
+	Line   string  `json:"line,omitempty"` // console: a -control command line
+}
+
+// controlReply answers a request, a JSON object per line. On success Tick
+// and Paused are the simulation's state once the command has taken effect.
+type controlReply struct {
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+	Tick   int    `json:"tick"`
+	Paused bool   `json:"paused"`
+	Data   any    `json:"data,omitempty"`
+}
+
+// controlSnapshot is the reply data of snapshot.
+type controlSnapshot struct {
+	Size  int                 `json:"size"`
+	Grid  []byte              `json:"grid,omitempty"` // tile bytes, base64
+	NPCs  []dashNPC           `json:"npcs"`
+	Stats sandbox.RecordStats `json:"stats"`
+}
+
+// controlNPC is the reply data of npc.
+type controlNPC struct {
+	reportBest
+	X      int      `json:"x"`
+	Y      int      `json:"y"`
+	Health int      `json:"health"`
+	Energy int      `json:"energy"`
+	Clan   int      `json:"clan"`
+	Mods   []string `json:"mods"`
+}
+
+// errEnded is the reply to commands that reach the simulation after its
+// run is over.
+var errEnded = errors.New("the simulation has ended")
+
+// controlServer is the -control-socket: it drives the simulation for
+// external programs the way -control does for a person. Commands touching
+// the world run between ticks through Scheduler.Do.
+type controlServer struct {
+	w     *sandbox.World
+	sched *sandbox.Scheduler
+	rng   *rand.Rand // the run's, for inject positions (used between ticks only)
+	ln    net.Listener
+	path  string         // unix socket file, removed on close
+	done  chan struct{}  // closed when the run ends
+	busy  sync.WaitGroup // requests being answered
+}
+
+// listenControl starts the control socket on addr: "unix:PATH", a path
+// containing a slash (a unix socket), or a TCP host:port.
+func listenControl(addr string, w *sandbox.World, sched *sandbox.Scheduler, rng *rand.Rand) (*controlServer, error) {
+	network, path := "tcp", ""
+	if p, ok := strings.CutPrefix(addr, "unix:"); ok || strings.Contains(addr, "/") {
+		network, path = "unix", addr
+		if ok {
+			path = p
+		}
+		// A socket left behind by an earlier run would block the listen
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			os.Remove(path)
+		}
+		addr = path
+	}
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &controlServer{w: w, sched: sched, rng: rng, ln: ln, path: path, done: make(chan struct{})}
+	go c.accept()
+	return c, nil
+}
+
+// addr is where the socket listens, for status lines.
+func (c *controlServer) addr() string {
+	if c.path != "" {
+		return "unix:" + c.path
+	}
+	return c.ln.Addr().String()
+}
+
+// close ends the run for the socket: commands still waiting on the
+// simulation fail, and later ones are refused. It returns once every
+// pending command has been answered.
+func (c *controlServer) close() {
+	c.sched.Stop()
+	c.sched.RunCalls()
+	close(c.done)
+	c.ln.Close()
+	if c.path != "" {
+		os.Remove(c.path)
+	}
+	c.busy.Wait()
+}
+
+func (c *controlServer) accept() {
+	for {
+		conn, err := c.ln.Accept()
+		if err != nil {
+			return
+		}
+		go c.serve(conn)
+	}
+}
+
+// serve answers one connection's requests in order until it closes.
+func (c *controlServer) serve(conn net.Conn) {
+	defer conn.Close()
+	sc := bufio.NewScanner(conn)
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	enc := json.NewEncoder(conn)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var req controlRequest
+		var reply controlReply
+		c.busy.Add(1)
+		if err := json.Unmarshal(line, &req); err != nil {
+			reply.Error = fmt.Sprintf("bad request: %v", err)
+		} else {
+			reply = c.handle(req)
+		}
+		err := enc.Encode(reply)
+		c.busy.Done()
+		if err != nil {
+			return
+		}
+	}
+}
+
+// handle runs one request.
+func (c *controlServer) handle(req controlRequest) controlReply {
+	var data any
+	var err error
+	switch req.Cmd {
+	case "pause":
+		c.sched.Pause()
+	case "resume":
+		c.sched.Resume()
+	case "step":
+		err = c.step(max(req.N, 1))
+	case "speed":
+		c.sched.SetSpeed(req.TPS)
+	case "stop":
+		c.sched.Stop()
+		return controlReply{OK: true}
+	case "snapshot", "npc", "top", "inject", "swap", "console":
+		if !c.sched.Do(func() { data, err = c.inspect(req) }) {
+			err = errEnded
+		}
+	default:
+		err = fmt.Errorf("unknown command %q (want pause, resume, step, speed, snapshot, npc, top, inject, swap, console or stop)", req.Cmd)
+	}
+
+	reply := controlReply{Data: data}
+	if err == nil && !c.sched.Do(func() { reply.Tick = c.w.Tick }) {
+		err = errEnded
+	}
+	if err != nil {
+		return controlReply{Error: err.Error()}
+	}
+	reply.OK, reply.Paused = true, c.sched.Paused()
+	return reply
+}
+
+// step runs n more ticks and returns once they have all run.
+func (c *controlServer) step(n int) error {
+	c.sched.Step(n)
+	for !c.sched.Paused() {
+		select {
+		case <-c.done:
+			return errEnded
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return nil
+}
+
+// inspect runs a command that reads or changes the world. It is called
+// between ticks.
+func (c *controlServer) inspect(req controlRequest) (any, error) {
+	switch req.Cmd {
+	case "snapshot":
+		snap := controlSnapshot{Size: c.w.Size, NPCs: []dashNPC{}, Stats: sandbox.NewRecordStats(c.sched)}
+		if req.Grid {
+			snap.Grid = make([]byte, len(c.w.Grid))
+			for i, t := range c.w.Grid {
+				snap.Grid[i] = byte(t)
+			}
+		}
+		for _, npc := range c.w.NPCs {
+			if npc.Alive() {
+				snap.NPCs = append(snap.NPCs, dashNPC{RecordNPC: sandbox.NewRecordNPC(npc), Predator: npc.Predator})
+			}
+		}
+		return snap, nil
+	case "npc":
+		npc := c.w.NPCByID(uint16(req.ID))
+		if req.ID < 0 || npc == nil || !npc.Alive() {
+			return nil, fmt.Errorf("no living NPC %d", req.ID)
+		}
+		return controlNPC{
+			reportBest: bestStats(npc, c.sched),
+			X:          npc.X, Y: npc.Y, Health: npc.Health, Energy: npc.Energy,
+			Clan: int(npc.Clan), Mods: modLabels(npc.Mods),
+		}, nil
+	case "top":
+		n := req.N
+		if n <= 0 {
+			n = 5
+		}
+		top := []reportGenome{}
+		for _, npc := range fittest(c.w.NPCs, n) {
+			top = append(top, reportGenome{npc.ID, npc.Fitness, hex.EncodeToString(npc.Genome), explainLines(npc.Genome)})
+		}
+		return top, nil
+	case "inject":
+		genome, err := hex.DecodeString(strings.TrimSpace(req.Genome))
+		if err != nil || len(genome) == 0 {
+			return nil, fmt.Errorf("inject: bad hex genome %q", req.Genome)
+		}
+		if (req.X == nil) != (req.Y == nil) || (req.X != nil && !c.w.InBounds(*req.X, *req.Y)) {
+			return nil, fmt.Errorf("inject: give both x and y, on the map")
+		}
+		ids := []uint16{}
+		for i := 0; i < max(req.Count, 1); i++ {
+			npc := sandbox.NewNPC(append([]byte(nil), genome...))
+			if req.X != nil {
+				npc.X, npc.Y = *req.X, *req.Y
+			} else {
+				npc.X, npc.Y = c.rng.Intn(c.w.Size), c.rng.Intn(c.w.Size)
+			}
+			if !c.w.Spawn(npc) {
+				break // no room left
+			}
+			ids = append(ids, npc.ID)
+		}
+		status.event("inject", c.w.Tick, logFields{"count": len(ids), "source": "control-socket"},
+			fmt.Sprintf("Injected %d NPCs over the control socket at tick %d", len(ids), c.w.Tick))
+		return map[string][]uint16{"ids": ids}, nil
+	case "swap":
+		genome, err := hex.DecodeString(strings.TrimSpace(req.Genome))
+		if err != nil || len(genome) == 0 {
+			return nil, fmt.Errorf("swap: bad hex genome %q", req.Genome)
+		}
+		if req.ID <= 0 || req.ID > 0xFFFF {
+			return nil, fmt.Errorf("no living NPC %d", req.ID)
+		}
+		if err := c.sched.SwapGenome(uint16(req.ID), genome, req.Freeze); err != nil {
+			return nil, err
+		}
+		status.event("swap", c.w.Tick, logFields{"npc": req.ID, "frozen": req.Freeze, "source": "control-socket"},
+			fmt.Sprintf("Swapped NPC %d's genome over the control socket at tick %d", req.ID, c.w.Tick))
+		return nil, nil
+	case "console":
+		// An inspection command, run directly: this is already between
+		// ticks
+		var out bytes.Buffer
+		fields := strings.Fields(req.Line)
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("console: empty line")
+		}
+		if err := (&console{w: c.w, sched: c.sched}).inspect(fields, &out); err != nil {
+			return nil, err
+		}
+		return out.String(), nil
+	}
+	return nil, nil
+}
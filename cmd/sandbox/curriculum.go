@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// loadCurriculum reads the -curriculum stages, or -curriculum-file's (one
+// stage per line) when set.
+func loadCurriculum(spec, path string) ([]sandbox.Stage, error) {
+	if path != "" {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec = string(src)
+	}
+	c, err := sandbox.ParseCurriculum(spec)
+	if err != nil {
+		return nil, err
+	}
+	return c.Stages, nil
+}
+
+// installCurriculum runs stages on sched, reporting each stage as the
+// world changes. It returns nil when there are none.
+func installCurriculum(sched *sandbox.Scheduler, stages []sandbox.Stage) *sandbox.Curriculum {
+	if len(stages) == 0 {
+		return nil
+	}
+	c := &sandbox.Curriculum{Stages: stages}
+	c.Install(sched)
+	w := sched.World
+	sched.Subscribe(sandbox.SubscriberFunc(func(ev sandbox.Event) {
+		if e, ok := ev.(sandbox.StageReached); ok {
+			status.event("curriculum-stage", e.Tick, logFields{"stage": e.Stage + 1, "stages": len(stages), "food_rate": w.FoodRate,
+				"max_food": w.MaxFood, "poison_odds": w.PoisonOdds, "night_ticks": w.NightTicks},
+				fmt.Sprintf("Tick %d: curriculum stage %d/%d → food_rate=%.3f max_food=%d poison=1/%d night=%d",
+					e.Tick, e.Stage+1, len(stages), w.FoodRate, w.MaxFood, w.PoisonOdds, w.NightTicks))
+		}
+	}))
+	return c
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// parseSeedList reads a -seeds list: comma-separated seeds and lo..hi
+// ranges, e.g. "1..30" or "1..10,42".
+func parseSeedList(spec string) ([]int64, error) {
+	var seeds []int64
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(part, "..")
+		from, err1 := strconv.ParseInt(strings.TrimSpace(lo), 10, 64)
+		to, err2 := from, error(nil)
+		if isRange {
+			to, err2 = strconv.ParseInt(strings.TrimSpace(hi), 10, 64)
+		}
+		if err1 != nil || err2 != nil || from > to {
+			return nil, fmt.Errorf("bad seeds %q (want N or LO..HI)", part)
+		}
+		for s := from; s <= to; s++ {
+			seeds = append(seeds, s)
+		}
+	}
+	return seeds, nil
+}
+
+// experimentStat summarises one metric over the seeds of an experiment.
+type experimentStat struct {
+	Mean   float64 `json:"mean"`
+	StdDev float64 `json:"stddev"` // sample standard deviation
+	CILow  float64 `json:"ci95_low"`
+	CIHigh float64 `json:"ci95_high"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+}
+
+// experimentSeed is the outcome of one seed.
+type experimentSeed struct {
+	Seed    int64              `json:"seed"`
+	Extinct bool               `json:"extinct"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// experimentResult is the report of an experiment.
+type experimentResult struct {
+	Runs    int                       `json:"runs"`
+	Extinct int                       `json:"extinct"`
+	Stats   map[string]experimentStat `json:"stats"`
+	Seeds   []experimentSeed          `json:"seeds"`
+}
+
+// runExperiment runs cfg once per seed, jobs runs at a time, and reports
+// the mean, standard deviation and 95% confidence interval of each of the
+// sweepMetrics across the seeds: as a table on stdout, or written to out
+// (.json = JSON with every seed's metrics, else CSV).
+func runExperiment(cfg simConfig, seedSpec string, jobs int, out string) error {
+	seeds, err := parseSeedList(seedSpec)
+	if err != nil {
+		return err
+	}
+	if len(seeds) < 2 {
+		return fmt.Errorf("need at least 2 seeds for confidence intervals, got %d", len(seeds))
+	}
+	jobs = max(jobs, 1)
+
+	cfg.verbose = false
+	cfg.snapEvery = 0
+	cfg.record = ""
+	cfg.lineage = ""
+	if cfg.tlEvery = cfg.ticks / 80; cfg.tlEvery < 1 {
+		cfg.tlEvery = 1
+	}
+	cfgs := make([]simConfig, len(seeds))
+	for i, seed := range seeds {
+		cfgs[i] = cfg
+		cfgs[i].seed = seed
+	}
+
+	status.event("experiment", -1, logFields{"seeds": len(seeds), "jobs": jobs},
+		fmt.Sprintf("Running %d seeds on %d jobs...", len(seeds), jobs))
+	runs := runBatch(cfgs, jobs, func(i, done int) {
+		status.event("experiment-run", -1, logFields{"done": done, "runs": len(cfgs), "seed": seeds[i]},
+			fmt.Sprintf("[%d/%d] seed=%d", done, len(cfgs), seeds[i]))
+	})
+	res := summariseExperiment(seeds, runs)
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch {
+	case out == "":
+		printExperiment(w, res)
+		return nil
+	case strings.EqualFold(filepath.Ext(out), ".json"):
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", " ")
+		return enc.Encode(res)
+	}
+	return writeExperimentCSV(w, res)
+}
+
+// summariseExperiment computes the statistics of every metric over runs,
+// one per seed.
+func summariseExperiment(seeds []int64, runs []sweepRun) experimentResult {
+	res := experimentResult{
+		Runs:  len(runs),
+		Stats: make(map[string]experimentStat, len(sweepMetrics)),
+		Seeds: make([]experimentSeed, len(runs)),
+	}
+	for i, r := range runs {
+		res.Seeds[i] = experimentSeed{Seed: seeds[i], Extinct: r.extinct, Metrics: make(map[string]float64, len(sweepMetrics))}
+		for m, name := range sweepMetrics {
+			res.Seeds[i].Metrics[name] = r.metrics[m]
+		}
+		if r.extinct {
+			res.Extinct++
+		}
+	}
+	n := float64(len(runs))
+	for m, name := range sweepMetrics {
+		st := experimentStat{Min: math.Inf(1), Max: math.Inf(-1)}
+		sum, sq := 0.0, 0.0
+		for _, r := range runs {
+			sum += r.metrics[m]
+			st.Min = math.Min(st.Min, r.metrics[m])
+			st.Max = math.Max(st.Max, r.metrics[m])
+		}
+		st.Mean = sum / n
+		for _, r := range runs {
+			sq += (r.metrics[m] - st.Mean) * (r.metrics[m] - st.Mean)
+		}
+		st.StdDev = math.Sqrt(sq / (n - 1))
+		half := tCritical95(len(runs)-1) * st.StdDev / math.Sqrt(n)
+		st.CILow, st.CIHigh = st.Mean-half, st.Mean+half
+		res.Stats[name] = st
+	}
+	return res
+}
+
+// tTable95 holds the two-sided 95% critical values of Student's t for 1 to
+// 30 degrees of freedom.
+var tTable95 = [...]float64{
+	12.706, 4.303, 3.182, 2.776, 2.571, 2.447, 2.365, 2.306, 2.262, 2.228,
+	2.201, 2.179, 2.160, 2.145, 2.131, 2.120, 2.110, 2.101, 2.093, 2.086,
+	2.080, 2.074, 2.069, 2.064, 2.060, 2.056, 2.052, 2.048, 2.045, 2.042,
+}
+
+// tCritical95 returns the two-sided 95% critical value of Student's t for
+// df degrees of freedom, rounding df down to the nearest tabulated value
+// past 30.
+func tCritical95(df int) float64 {
+	switch {
+	case df <= len(tTable95):
+		return tTable95[df-1]
+	case df < 40:
+		return tTable95[len(tTable95)-1] // df 30
+	case df < 60:
+		return 2.021 // df 40
+	case df < 120:
+		return 2.000 // df 60
+	case df < 1000:
+		return 1.980 // df 120
+	}
+	return 1.960
+}
+
+// printExperiment prints the statistics as a table.
+func printExperiment(w io.Writer, res experimentResult) {
+	fmt.Fprintf(w, "=== Experiment: %d seeds, %d extinct ===\n", res.Runs, res.Extinct)
+	fmt.Fprintf(w, "%-12s %12s %12s %27s %12s %12s\n", "metric", "mean", "stddev", "95% CI", "min", "max")
+	for _, name := range sweepMetrics {
+		st := res.Stats[name]
+		ci := fmt.Sprintf("[%.2f, %.2f]", st.CILow, st.CIHigh)
+		fmt.Fprintf(w, "%-12s %12.2f %12.2f %27s %12.2f %12.2f\n", name, st.Mean, st.StdDev, ci, st.Min, st.Max)
+	}
+}
+
+// writeExperimentCSV writes one row per metric with its statistics.
+func writeExperimentCSV(w io.Writer, res experimentResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"metric", "runs", "extinct", "mean", "stddev", "ci95_low", "ci95_high", "min", "max"})
+	for _, name := range sweepMetrics {
+		st := res.Stats[name]
+		row := []string{name, strconv.Itoa(res.Runs), strconv.Itoa(res.Extinct)}
+		for _, v := range []float64{st.Mean, st.StdDev, st.CILow, st.CIHigh, st.Min, st.Max} {
+			row = append(row, strconv.FormatFloat(v, 'f', 2, 64))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
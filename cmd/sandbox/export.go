@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// exportBest writes the k fittest living genomes into dir, each as
+// NN-npcID.hex (one hex line, as -genome-file and -inject read), .bin (raw
+// bytecode), .txt (annotated disassembly) and .json (the NPC's stats), NN
+// being its rank. It returns how many it wrote.
+func exportBest(dir string, k int, w *sandbox.World, sched *sandbox.Scheduler) (int, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	best := fittest(w.NPCs, k)
+	for i, npc := range best {
+		base := filepath.Join(dir, fmt.Sprintf("%02d-npc%d", i+1, npc.ID))
+		stats, err := json.MarshalIndent(bestStats(npc, sched), "", "  ")
+		if err != nil {
+			return i, err
+		}
+		files := []struct {
+			ext  string
+			data []byte
+		}{
+			{".hex", []byte(hex.EncodeToString(npc.Genome) + "\n")},
+			{".bin", npc.Genome},
+			{".txt", []byte(strings.Join(explainLines(npc.Genome), "\n") + "\n")},
+			{".json", append(stats, '\n')},
+		}
+		for _, f := range files {
+			if err := os.WriteFile(base+f.ext, f.data, 0o644); err != nil {
+				return i, err
+			}
+		}
+	}
+	status.event("export-best", w.Tick, logFields{"written": len(best), "dir": dir},
+		fmt.Sprintf("Exported the %d fittest genomes to %s", len(best), dir))
+	return len(best), nil
+}
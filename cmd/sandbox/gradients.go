@@ -0,0 +1,20 @@
+package main
+
+import (
+	"os"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// loadGradients reads the -gradients spec, or -gradients-file's (one
+// gradient per line) when set.
+func loadGradients(spec, path string) (sandbox.Gradients, error) {
+	if path != "" {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return sandbox.Gradients{}, err
+		}
+		spec = string(src)
+	}
+	return sandbox.ParseGradients(spec)
+}
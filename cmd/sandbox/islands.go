@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// runIslands evolves cfg.islands populations side by side (island i runs
+// seed+i), migrating the best genomes between them every cfg.migrateEvery
+// ticks, then prints a per-island comparison. An island that goes extinct
+// stops; the rest carry on.
+func runIslands(cfg simConfig) {
+	cfg.verbose = false
+	cfg.snapEvery = 0
+
+	arch := &sandbox.Archipelago{
+		Topology: cfg.topology,
+		Migrants: cfg.migrants,
+		Rng:      rand.New(sandbox.NewSource(cfg.seed)),
+	}
+	islands := make([]*simulation, cfg.islands)
+	running := make([]bool, cfg.islands)
+	for i := range islands {
+		c := cfg
+		c.seed = cfg.seed + int64(i)
+		islands[i] = newSimulation(c)
+		running[i] = true
+		arch.Islands = append(arch.Islands, islands[i].w)
+	}
+
+	status.event("islands", -1, logFields{"islands": cfg.islands, "seed": cfg.seed, "migrants": cfg.migrants, "migrate_every": cfg.migrateEvery},
+		fmt.Sprintf("Running %d islands (seeds %d-%d), %d migrants every %d ticks...",
+			cfg.islands, cfg.seed, cfg.seed+int64(cfg.islands-1), cfg.migrants, cfg.migrateEvery))
+	migrated := 0
+	for tick := 0; tick < cfg.ticks; tick++ {
+		live := 0
+		for i, s := range islands {
+			if running[i] {
+				running[i] = s.step(tick)
+			}
+			if running[i] {
+				live++
+			}
+		}
+		if live == 0 {
+			break
+		}
+		if cfg.migrateEvery > 0 && tick > 0 && tick%cfg.migrateEvery == 0 {
+			migrated += arch.Migrate()
+		}
+	}
+
+	printIslands(cfg, islands, migrated)
+}
+
+func printIslands(cfg simConfig, islands []*simulation, migrated int) {
+	fmt.Fprintf(os.Stderr, "\n=== Islands (seed=%d, npcs=%d, ticks=%d, migrated=%d) ===\n",
+		cfg.seed, cfg.npcs, cfg.ticks, migrated)
+	fmt.Fprintf(os.Stderr, "%-8s %8s %8s %8s %10s %8s %10s\n", "island", "alive", "avgFit", "bestFit", "genomeAvg", "unique", "diversity")
+
+	var everyone []*sandbox.NPC
+	for i, s := range islands {
+		r := s.result()
+		fmt.Fprintf(os.Stderr, "%-8d %8d %8d %8d %10d %8d %10.2f\n",
+			i, r.alive, r.avgFit, r.bestFit, r.genomeAvg, sandbox.CensusGenomes(s.w.NPCs).Unique, sandbox.GenomeDiversity(s.w.NPCs))
+		everyone = append(everyone, s.w.NPCs...)
+	}
+	fmt.Fprintf(os.Stderr, "%-8s %8d %8s %8s %10s %8d %10.2f\n",
+		"all", len(everyone), "", "", "", sandbox.CensusGenomes(everyone).Unique, sandbox.GenomeDiversity(everyone))
+
+	fmt.Fprintln(os.Stderr)
+	for i, s := range islands {
+		fmt.Fprintln(os.Stderr, sparkline(fmt.Sprintf("avgFit (%d)", i),
+			extractField(s.timeline, func(tp timePoint) int { return tp.avgFit })))
+	}
+	for i, s := range islands {
+		fmt.Fprintln(os.Stderr, sparkline(fmt.Sprintf("diversity (%d)", i),
+			extractField(s.timeline, func(tp timePoint) int { return tp.diversity })))
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// writeLineage exports l to path, as Graphviz DOT for a .dot or .gv file and
+// JSON otherwise. Unless all is set only the lineages of the survivors are
+// written.
+func writeLineage(l *sandbox.Lineage, path string, all bool) error {
+	var keep []bool
+	if !all {
+		keep = l.Ancestry()
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".dot", ".gv":
+		err = l.WriteDOT(f, keep)
+	default:
+		err = l.WriteJSON(f, keep)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	n := len(l.Nodes)
+	if keep != nil {
+		n = 0
+		for _, k := range keep {
+			if k {
+				n++
+			}
+		}
+	}
+	status.event("lineage", -1, logFields{"written": n, "individuals": len(l.Nodes), "path": path},
+		fmt.Sprintf("lineage: %d of %d individuals written to %s", n, len(l.Nodes), path))
+	return nil
+}
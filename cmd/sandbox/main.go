@@ -1,18 +1,20 @@
 package main
 
 import (
-	"bufio"
 	"encoding/csv"
-	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"math"
 	"math/rand"
 	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/psilLang/psil/pkg/micro"
 	"github.com/psilLang/psil/pkg/sandbox"
 )
 
@@ -28,187 +30,61 @@ type timePoint struct {
 	avgFit      int
 	bestFit     int
 	holders     int // NPCs with items
-	crafted     int // shield+compass holders
+	crafted     int // shield+compass+charm+remedy holders
 	crystalNPCs int
 	genomeMin   int
 	genomeMax   int
 	genomeAvg   int
+	genomeP50   int // median genome length
+	genomeP90   int // 90th percentile genome length
 	attacks     int // cumulative
 	kills       int // cumulative
 	heals       int // cumulative
 	harvests    int // cumulative
 	terraforms  int // cumulative
-}
-
-// Trader genome: goal-based navigation
-// If holding item → move toward nearest NPC, trade with them
-// Else → move toward food, eat
-// Bytecode layout:
-//   0-5:   r0@ 15, push 0, >, jnz +8    (check item)
-//   6-13:  forage: r0@ 13(food_dir), r1! 0, push 1, r1! 1, yield
-//   14-24: trade:  r0@ 18(near_dir), r1! 0, push 4, r1! 1, r0@ 12(near_id), r1! 2, yield
-var traderGenome = []byte{
-	0x8A, 0x0F, 0x20, 0x0D, 0x88, 0x08, // r0@ 15, push 0, >, jnz +8
-	// forage: move toward food, eat (bytes 6-13)
-	0x8A, 0x0D, // r0@ 13 (food direction)
-	0x8C, 0x00, // r1! 0 (move)
-	0x21,       // push 1 (eat)
-	0x8C, 0x01, // r1! 1 (action)
-	0xF1,       // yield
-	// trade: move toward nearest NPC, trade (bytes 14-24)
-	0x8A, 0x12, // r0@ 18 (nearest NPC direction)
-	0x8C, 0x00, // r1! 0 (move toward them)
-	0x24,       // push 4 (ActionTrade)
-	0x8C, 0x01, // r1! 1 (action)
-	0x8A, 0x0C, // r0@ 12 (nearest NPC ID)
-	0x8C, 0x02, // r1! 2 (target)
-	0xF1,       // yield
-}
-
-// Forager genome: goal-based — move toward food, eat
-var foragerGenome = []byte{
-	0x8A, 0x0D, // r0@ 13 (food direction)
-	0x8C, 0x00, // r1! 0 (move toward food)
-	0x21,       // push 1 (eat)
-	0x8C, 0x01, // r1! 1 (action=eat)
-	0xF1,       // yield
-}
-
-// Crafter genome: if on forge AND holding item → craft, else forage
-// Bytecode layout:
-//   0-5:   r0@ 23(on_forge), push 0, >, jnz +skip_to_craft
-//   6-13:  forage: r0@ 13(food_dir), r1! 0, push 1, r1! 1, yield
-//   14-19: craft:  r0@ 15(my_item), push 0, >, jnz +do_craft (if holding item)
-//   20-24: do_craft: push 5(ActionCraft), r1! 1, yield
-// Teacher genome: if holding item AND nearest NPC adjacent → teach, else forage
-// Bytecode layout (no unreachable halts — yield ends tick):
-//   0-5:   r0@ 15(my_item), push 0, >, jnz +8
-//   6-13:  forage: r0@ 13(food_dir), r1! 0, push 1, r1! 1, yield
-//   14-19: r0@ 7(near_dist), push 2, <, jnz +8 → teach
-//   20-27: move toward NPC, forage: r0@ 18(near_dir), r1! 0, push 1, r1! 1, yield
-//   28-39: teach: push 6, r1! 1, r0@ 12(near_id), r1! 2, r0@ 13(food_dir), r1! 0, yield
-var teacherGenome = []byte{
-	// Check if holding item (bytes 0-5)
-	0x8A, 0x0F, // r0@ 15 (Ring0MyItem)
-	0x20,       // push 0
-	0x0D,       // >
-	0x88, 0x08, // jnz +8 → teach check (PC=6, 6+8=14)
-	// forage: move toward food, eat (bytes 6-13)
-	0x8A, 0x0D, // r0@ 13 (food direction)
-	0x8C, 0x00, // r1! 0 (move)
-	0x21,       // push 1 (eat)
-	0x8C, 0x01, // r1! 1 (action)
-	0xF1,       // yield (ends tick)
-	// teach check: if nearest NPC dist < 2 → teach (bytes 14-19)
-	0x8A, 0x07, // r0@ 7 (Ring0Near)
-	0x22,       // push 2
-	0x0C,       // < (near_dist < 2 → adjacent)
-	0x88, 0x08, // jnz +8 → teach (PC=20, 20+8=28)
-	// NPC not adjacent: move toward them (bytes 20-27)
-	0x8A, 0x12, // r0@ 18 (nearest NPC direction)
-	0x8C, 0x00, // r1! 0 (move)
-	0x21,       // push 1 (eat)
-	0x8C, 0x01, // r1! 1 (action)
-	0xF1,       // yield (ends tick)
-	// teach: push ActionTeach, target nearest NPC (bytes 28-39)
-	0x26,       // push 6 (ActionTeach)
-	0x8C, 0x01, // r1! 1 (action)
-	0x8A, 0x0C, // r0@ 12 (nearest NPC ID)
-	0x8C, 0x02, // r1! 2 (target)
-	0x8A, 0x0D, // r0@ 13 (food direction — move toward food while teaching)
-	0x8C, 0x00, // r1! 0 (move)
-	0xF1,       // yield
-}
-
-var crafterGenome = []byte{
-	// Check if on forge
-	0x8A, 0x17, // r0@ 23 (Ring0OnForge)
-	0x20,       // push 0
-	0x0D,       // >
-	0x88, 0x08, // jnz +8 → skip to craft check (byte 14)
-	// forage: move toward food, eat (bytes 6-13)
-	0x8A, 0x0D, // r0@ 13 (food direction)
-	0x8C, 0x00, // r1! 0 (move)
-	0x21,       // push 1 (eat)
-	0x8C, 0x01, // r1! 1 (action)
-	0xF1,       // yield
-	0xFF,       // halt (unreachable)
-	// craft check: if holding item → craft (bytes 14-19)
-	0x8A, 0x0F, // r0@ 15 (Ring0MyItem)
-	0x20,       // push 0
-	0x0D,       // >
-	0x88, 0x04, // jnz +4 → do craft (byte 24)
-	// no item: forage instead (bytes 20-23)
-	0x8A, 0x0D, // r0@ 13 (food direction)
-	0x8C, 0x00, // r1! 0 (move)
-	// do craft (bytes 24-28)
-	0x25,       // push 5 (ActionCraft)
-	0x8C, 0x01, // r1! 1 (action)
-	0xF1,       // yield
-}
-
-// Farmer genome (action opcodes): sense food → if scarce, terraform → else eat → yield
-// Uses multi-yield: move toward food, eat, then check if should plant.
-var farmerGenome = []byte{
-	0x93, 0x05, // act.move toward food
-	0x96, 0x00, // act.eat
-	0x8A, 0x02, // r0@ 2 (energy)
-	0x8A, 0x1B, // r0@ 27 (tile type)
-	0x20,       // push 0 (TileEmpty)
-	0x0B,       // = (tile is empty?)
-	0x88, 0x02, // jnz +2 → plant
-	0xF0,       // halt
-	0x98, 0x00, // act.terraform (plant food)
-	0xF0,       // halt
-}
-
-// Fighter genome (action opcodes): if near NPC adjacent → attack, else move toward
-var fighterGenome = []byte{
-	0x8A, 0x07, // r0@ 7 (near dist)
-	0x22,       // push 2
-	0x0C,       // < (dist < 2 → adjacent)
-	0x88, 0x04, // jnz +4 → attack
-	0x93, 0x06, // act.move toward nearest NPC
-	0xF0,       // halt
-	0x94, 0x00, // act.attack
-	0x93, 0x05, // act.move toward food (forage after attack)
-	0x96, 0x00, // act.eat
-	0xF0,       // halt
-}
-
-// Healer genome (action opcodes): if near NPC is kin (similarity > 50) → heal, else forage
-var healerGenome = []byte{
-	0x8A, 0x07, // r0@ 7 (near dist)
-	0x22,       // push 2
-	0x0C,       // < (adjacent?)
-	0x88, 0x0A, // jnz +10 → check kin
-	0x93, 0x05, // act.move toward food
-	0x96, 0x00, // act.eat
-	0xF0,       // halt
-	0x00, 0x00, 0x00, 0x00, // padding to reach offset
-	0x8A, 0x1C, // r0@ 28 (similarity)
-	0x8A, 0x07, // r0@ 7 (near dist — re-check)
-	0x22,       // push 2
-	0x0C,       // < (still adjacent?)
-	0x88, 0x02, // jnz +2 → heal
-	0xF0,       // halt
-	0x95, 0x00, // act.heal
-	0xF0,       // halt
+	deaths      [sandbox.DeathCauses]int // cumulative, by cause
+	diversity   int // mean pairwise genome distance, 0-100
+	mutation    int // effective GA mutation rate, percent
+	unique      int // distinct genomes
+	shannon     int // Shannon index of genome frequencies, ×100
+	simpson     int // Simpson diversity of genomes, 0-100
+	predators   int // alive predators (-predators)
+	predatorFit int // predators' average fitness
+	preyFit     int // prey's average fitness
+	preyKills   int // cumulative
+	gini        int // Gini coefficient of gold across alive NPCs, ×100
+	demo        sandbox.Demographics // age, genome-length and behaviour counts
 }
 
 type simConfig struct {
 	npcs, worldSize, ticks, gas, evolveEvery int
 	seed                                     int64
 	traderFrac                               float64
+	roles                                    []roleSpec // nil = defaultRoles(traderFrac)
 	verbose                                  bool
 	snapEvery, tlEvery                       int
 	crossoverMode                            sandbox.CrossoverMode
+	selection                                sandbox.SelectionMode
+	diversityTarget                          float64
+	elitism                                  int
+	speciesThreshold                         float64
+	clonePenalty                             float64
+	cloneDistance                            float64
+	lengthPenalty                            float64
+	shorterFirst                             bool
+	structural                               bool // jump-aware crossover and mutation
+	tournamentSize                           int
 	classicRate                              float64
 	biomes                                   bool
 	wfcGenome                                bool
 	maxGenome                                int
 	record                                   string
 	recordEvery                              int
+	renderEvery                              int // PNG frame every N ticks (0 = none)
+	renderDir                                string
+	renderScale                              int
+	renderColor                              sandbox.NPCColoring
+	timelapse                                string // animated GIF path
 	inject                                   string
 	injectCount                              int
 	injectAt                                 int
@@ -216,21 +92,133 @@ type simConfig struct {
 	genomeGrowEvery                          int
 	gasGrowDelta                             int
 	gasGrowEvery                             int
+	recipeMemes                              bool
+	shootRange                               int
+	giftFitness                              int
+	fitness                                  sandbox.FitnessWeights
+	actions                                  sandbox.ActionCosts
+	gasCosts                                 *micro.GasTable // nil = 1 per instruction
+	curriculum                               []sandbox.Stage // -curriculum stages
+	gradients                                sandbox.Gradients // -gradients spawn richness
+	sensorNoise                              *sandbox.SensorNoise // -sensor-noise rules (seeded per run)
+	assertions                               []sandbox.Assertion // -assert checks
+	clanShare                                float64
+	reproduction                             string
+	contagion                                int
+	visionCone                               bool
+	selfModify                               bool
+	biographies                              int
+	player                                   bool
+	brainAddr                                string
+	brainCount                               int
+	workers                                  int
+	sensorFields                             bool
+	control                                  bool
+	tui                                      bool
+	serve                                    string // -serve dashboard address
+	serveToken                               string // -serve-token
+	controlSocket                            string // -control-socket address
+	speed                                    float64
+	progress                                 bool // progress bar on a terminal
+	hashes                                   bool // record World.Hash after every tick
+	lineage                                  string
+	lineageAll                               bool
+	eventLog                                 string // -event-log JSONL path
+	traceNPC                                 int    // -trace-npc ID (0 = none)
+	traceFile                                string // where its trace goes
+	sqlite                                   string // -sqlite stats database
+	reportJSON                               string // -report-json path
+	exportBest                               int    // -export-best: fittest genomes to export
+	exportDir                                string
+	checkpointEvery                          int    // save a checkpoint every N ticks (0 = never)
+	checkpointFile                           string
+	resumeFrom                               string // -resume-from checkpoint
+	seeds                                    []seedGenome // -genome-file/-genome-hex
+	predators                                int          // predator NPCs with their own GA
+	markets                                  int          // market tiles (-markets)
+	posts                                    int          // trading-post tiles (-posts)
+	schools                                  int          // school tiles (-schools)
+	economy                                  sandbox.Economy // -tax, -tax-redistribute, -gold-faucet, -wealth-cap
+	contractEvery                            int             // ticks between contract postings (-contracts)
+	islands                                  int
+	migrateEvery                             int
+	migrants                                 int
+	topology                                 sandbox.Topology
 }
 
 type simResult struct {
-	timeline  []timePoint
-	alive     int
-	avgFit    int
-	bestFit   int
-	trades    int
-	teaches   int
-	genomeAvg int
-	totalGold int
+	timeline    []timePoint
+	alive       int
+	avgFit      int
+	bestFit     int
+	trades      int
+	teaches     int
+	genomeAvg   int
+	totalGold   int
+	brokenJumps int      // jumps landing mid-instruction, summed over the survivors
+	hashes      []uint64 // per-tick World.Hash (cfg.hashes)
 }
 
 func runSimulation(cfg simConfig) simResult {
-	rng := rand.New(rand.NewSource(cfg.seed))
+	s := newSimulation(cfg)
+	for tick := 0; tick < cfg.ticks; tick++ {
+		if !s.step(tick) {
+			break
+		}
+	}
+	return s.result()
+}
+
+// simulation is one quiet run of cfg, advanced a tick at a time by step.
+type simulation struct {
+	cfg            simConfig
+	rng            *rand.Rand
+	ws             int
+	w              *sandbox.World
+	sched          *sandbox.Scheduler
+	ga             *sandbox.GA
+	predGA         *sandbox.GA // predators' GA (-predators)
+	reportInterval int
+	tlEvery        int
+	timeline       []timePoint
+	hashes         []uint64
+}
+
+// newGA returns a GA set up as cfg asks, drawing from rng.
+func newGA(cfg simConfig, rng *rand.Rand) *sandbox.GA {
+	ga := sandbox.NewGA(rng)
+	ga.Mode = cfg.crossoverMode
+	ga.Selection = cfg.selection
+	ga.DiversityTarget = cfg.diversityTarget
+	ga.Elitism = cfg.elitism
+	ga.SpeciesThreshold = cfg.speciesThreshold
+	ga.ClonePenalty = cfg.clonePenalty
+	ga.CloneDistance = cfg.cloneDistance
+	ga.LengthPenalty = cfg.lengthPenalty
+	ga.ShorterFirst = cfg.shorterFirst
+	ga.Structural = cfg.structural
+	ga.SelfModify = cfg.selfModify
+	ga.TournamentSize = cfg.tournamentSize
+	ga.ClassicRate = cfg.classicRate
+	ga.MaxGenomeSize = cfg.maxGenome
+	if cfg.wfcGenome {
+		ga.WFCEnabled = true
+		ga.Archetypes = [][]byte{
+			traderGenome, foragerGenome, crafterGenome, teacherGenome,
+			farmerGenome, fighterGenome, healerGenome,
+		}
+	}
+	return ga
+}
+
+// predatorSource returns the random source of the predators' GA, seeded
+// from the run seed but apart from the prey's stream.
+func predatorSource(seed int64) *sandbox.Source {
+	return sandbox.NewSource(^seed)
+}
+
+func newSimulation(cfg simConfig) *simulation {
+	rng := rand.New(sandbox.NewSource(cfg.seed))
 
 	// Auto-scale world size
 	ws := cfg.worldSize
@@ -251,59 +239,52 @@ func runSimulation(cfg simConfig) simResult {
 		maxItems = 4
 	}
 	w.MaxItems = maxItems
-	ga := sandbox.NewGA(rng)
-	ga.Mode = cfg.crossoverMode
-	ga.ClassicRate = cfg.classicRate
-	ga.MaxGenomeSize = cfg.maxGenome
-	if cfg.wfcGenome {
-		ga.WFCEnabled = true
-		ga.Archetypes = [][]byte{
-			traderGenome, foragerGenome, crafterGenome, teacherGenome,
-			farmerGenome, fighterGenome, healerGenome,
-		}
-	}
+	w.PlaceMarkets(cfg.markets)
+	w.PlaceTradingPosts(cfg.posts)
+	w.PlaceSchools(cfg.schools)
+	w.Gradients = cfg.gradients
+	ga := newGA(cfg, rng)
 
 	sched := sandbox.NewScheduler(w, cfg.gas, io.Discard)
-
-	numTraders := int(float64(cfg.npcs) * cfg.traderFrac)
-	numForagers := cfg.npcs / 4
-	numCrafters := cfg.npcs / 10
-	numTeachers := cfg.npcs / 20
-	if numTeachers < 1 {
-		numTeachers = 1
-	}
-
-	for i := 0; i < cfg.npcs; i++ {
-		var genome []byte
-		if i < numTraders {
-			genome = make([]byte, len(traderGenome))
-			copy(genome, traderGenome)
-		} else if i < numTraders+numForagers {
-			genome = make([]byte, len(foragerGenome))
-			copy(genome, foragerGenome)
-		} else if i < numTraders+numForagers+numCrafters {
-			genome = make([]byte, len(crafterGenome))
-			copy(genome, crafterGenome)
-		} else if i < numTraders+numForagers+numCrafters+numTeachers {
-			genome = make([]byte, len(teacherGenome))
-			copy(genome, teacherGenome)
-		} else {
-			genome = ga.RandomGenome(24 + rng.Intn(16))
-		}
-		npc := sandbox.NewNPC(genome)
-		npc.X = rng.Intn(ws)
-		npc.Y = rng.Intn(ws)
-		if i < numTraders {
-			npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
-		}
-		if i >= numTraders+numForagers && i < numTraders+numForagers+numCrafters {
-			npc.Item = sandbox.ItemTool
-		}
-		if i >= numTraders+numForagers+numCrafters && i < numTraders+numForagers+numCrafters+numTeachers {
-			npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
-		}
-		w.Spawn(npc)
-	}
+	sched.RecipeMemes = cfg.recipeMemes
+	sched.ShootRange = cfg.shootRange
+	sched.GiftFitness = cfg.giftFitness
+	sched.Fitness = cfg.fitness
+	sched.Actions = cfg.actions
+	sched.GasCosts = cfg.gasCosts
+	installCurriculum(sched, cfg.curriculum)
+	sched.ClanShare = cfg.clanShare
+	sched.Contagion = cfg.contagion
+	sched.VisionCone = cfg.visionCone
+	sched.SelfModify = cfg.selfModify
+	sched.Workers = cfg.workers
+	sched.SensorFields = cfg.sensorFields
+	if cfg.sensorNoise != nil {
+		noise := *cfg.sensorNoise
+		noise.Seed = cfg.seed
+		sched.SensorNoise = &noise
+	}
+	sched.Economy = cfg.economy
+	sched.ContractEvery = cfg.contractEvery
+	sched.KeepGraveyard = cfg.biographies > 0
+	if cfg.reproduction != "epoch" {
+		sched.Mating = ga
+		sched.MaxPopulation = cfg.npcs * 2
+	}
+	var predGA *sandbox.GA
+	if cfg.predators > 0 {
+		predGA = newGA(cfg, rand.New(predatorSource(cfg.seed)))
+		sched.PredatorMating = predGA
+	}
+
+	// Seed genomes first; the role population fills the rest
+	roles := cfg.npcs - spawnSeeds(w, cfg.seeds, rng, ws)
+	mix := cfg.roles
+	if len(mix) == 0 {
+		mix = defaultRoles(cfg.traderFrac)
+	}
+	spawnRoles(w, mix, roles, ga, cfg.recipeMemes, rng, ws)
+	spawnPredators(w, cfg.predators, rng, ws)
 
 	seedFood := ws
 	if seedFood < cfg.npcs {
@@ -329,78 +310,107 @@ func runSimulation(cfg simConfig) simResult {
 			tlEvery = 1
 		}
 	}
-	var timeline []timePoint
+	return &simulation{
+		cfg:            cfg,
+		rng:            rng,
+		ws:             ws,
+		w:              w,
+		sched:          sched,
+		ga:             ga,
+		predGA:         predGA,
+		reportInterval: reportInterval,
+		tlEvery:        tlEvery,
+	}
+}
 
-	for tick := 0; tick < cfg.ticks; tick++ {
-		sched.Tick()
+// step runs tick and its epoch bookkeeping. It returns false once the
+// population is extinct.
+func (s *simulation) step(tick int) bool {
+	cfg, w, sched, ga, rng, ws := s.cfg, s.w, s.sched, s.ga, s.rng, s.ws
 
-		// Dynamic brain growth
-		if cfg.genomeGrowDelta > 0 && cfg.genomeGrowEvery > 0 && tick > 0 && tick%cfg.genomeGrowEvery == 0 {
-			ga.MaxGenomeSize += cfg.genomeGrowDelta
-			fmt.Fprintf(os.Stderr, "Tick %d: max genome size → %d\n", tick, ga.MaxGenomeSize)
-		}
+	sched.Tick()
+	if cfg.hashes {
+		s.hashes = append(s.hashes, w.Hash())
+	}
 
-		// Dynamic gas scaling
-		if cfg.gasGrowDelta > 0 && cfg.gasGrowEvery > 0 && tick > 0 && tick%cfg.gasGrowEvery == 0 {
-			sched.Gas += cfg.gasGrowDelta
-			fmt.Fprintf(os.Stderr, "Tick %d: base gas → %d\n", tick, sched.Gas)
-		}
+	// Dynamic brain growth
+	if cfg.genomeGrowDelta > 0 && cfg.genomeGrowEvery > 0 && tick > 0 && tick%cfg.genomeGrowEvery == 0 {
+		ga.MaxGenomeSize += cfg.genomeGrowDelta
+		fmt.Fprintf(os.Stderr, "Tick %d: max genome size → %d\n", tick, ga.MaxGenomeSize)
+	}
 
-		if tick%tlEvery == 0 {
-			timeline = append(timeline, sampleStats(w, sched, tick))
-		}
+	// Dynamic gas scaling
+	if cfg.gasGrowDelta > 0 && cfg.gasGrowEvery > 0 && tick > 0 && tick%cfg.gasGrowEvery == 0 {
+		sched.Gas += cfg.gasGrowDelta
+		fmt.Fprintf(os.Stderr, "Tick %d: base gas → %d\n", tick, sched.Gas)
+	}
 
-		if tick > 0 && tick%cfg.evolveEvery == 0 {
-			w.NPCs = ga.Evolve(w.NPCs)
+	if tick%s.tlEvery == 0 {
+		s.timeline = append(s.timeline, sampleStats(w, sched, ga, tick))
+	}
 
-			refillIdx := 0
-			for len(w.NPCs) < cfg.npcs/2 {
-				var genome []byte
-				if cfg.wfcGenome && refillIdx%5 < 3 {
-					genome = ga.WFCGenome(24 + rng.Intn(16))
-				} else {
-					archetypes := [][]byte{
-						traderGenome, foragerGenome, crafterGenome, teacherGenome,
-						farmerGenome, fighterGenome, healerGenome,
-					}
-					src := archetypes[refillIdx%len(archetypes)]
-					genome = make([]byte, len(src))
-					copy(genome, src)
-				}
-				npc := sandbox.NewNPC(genome)
-				npc.X = rng.Intn(ws)
-				npc.Y = rng.Intn(ws)
-				if refillIdx%5 == 0 {
-					npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
-				}
-				if refillIdx%5 == 1 {
-					npc.Item = sandbox.ItemTool
+	if tick > 0 && tick%cfg.evolveEvery == 0 {
+		sched.SettleEconomy()
+		if cfg.reproduction != "mate" {
+			evolve(sched, ga, s.predGA, w.NPCs)
+		}
+
+		refillIdx := 0
+		predators := countPredators(w.NPCs)
+		for len(w.NPCs)-predators < cfg.npcs/2 {
+			var genome []byte
+			if cfg.wfcGenome && refillIdx%5 < 3 {
+				genome = ga.WFCGenome(24 + rng.Intn(16))
+			} else {
+				archetypes := [][]byte{
+					traderGenome, foragerGenome, crafterGenome, teacherGenome,
+					farmerGenome, fighterGenome, healerGenome,
 				}
-				w.Spawn(npc)
-				refillIdx++
+				src := archetypes[refillIdx%len(archetypes)]
+				genome = make([]byte, len(src))
+				copy(genome, src)
 			}
+			npc := sandbox.NewNPC(genome)
+			npc.X = rng.Intn(ws)
+			npc.Y = rng.Intn(ws)
+			if refillIdx%5 == 0 {
+				npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
+			}
+			if refillIdx%5 == 1 {
+				npc.Item = sandbox.ItemTool
+			}
+			w.Spawn(npc)
+			refillIdx++
 		}
-
-		if cfg.verbose && tick%reportInterval == 0 {
-			printStatus(w, sched, tick)
+		if s.predGA != nil {
+			refillPredators(w, cfg.predators, rng, ws)
 		}
+	}
 
-		if cfg.snapEvery > 0 && tick > 0 && tick%cfg.snapEvery == 0 {
-			printSnapshot(w, sched, tick)
-		}
+	if cfg.verbose && tick%s.reportInterval == 0 {
+		printStatus(w, sched, tick)
+	}
 
-		if len(w.NPCs) == 0 {
-			fmt.Fprintf(os.Stderr, "Population extinct at tick %d\n", tick)
-			break
-		}
+	if cfg.snapEvery > 0 && tick > 0 && tick%cfg.snapEvery == 0 {
+		printSnapshot(w, sched, tick)
 	}
 
-	// Collect final stats
+	if len(w.NPCs) == 0 {
+		fmt.Fprintf(os.Stderr, "Population extinct at tick %d\n", tick)
+		return false
+	}
+	return true
+}
+
+// result collects the final stats.
+func (s *simulation) result() simResult {
+	w, sched := s.w, s.sched
 	res := simResult{
-		timeline: timeline,
+		timeline: s.timeline,
 		alive:    len(w.NPCs),
 		trades:   sched.TradeCount,
 		teaches:  sched.TeachCount,
+		hashes:   s.hashes,
 	}
 	totalFit := 0
 	totalGenome := 0
@@ -411,6 +421,7 @@ func runSimulation(cfg simConfig) simResult {
 		}
 		res.totalGold += npc.Gold
 		totalGenome += len(npc.Genome)
+		res.brokenJumps += sandbox.BrokenJumps(npc.Genome)
 	}
 	if res.alive > 0 {
 		res.avgFit = totalFit / res.alive
@@ -420,7 +431,7 @@ func runSimulation(cfg simConfig) simResult {
 	return res
 }
 
-func printFinalReport(cfg simConfig, w *sandbox.World, sched *sandbox.Scheduler) {
+func printFinalReport(cfg simConfig, w *sandbox.World, sched *sandbox.Scheduler, epochDeaths [][sandbox.DeathCauses]int) {
 	fmt.Fprintf(os.Stderr, "\n=== Final Stats (tick %d) ===\n", w.Tick)
 	fmt.Fprintf(os.Stderr, "alive=%d food_on_map=%d items_on_map=%d total_food_spawned=%d trades=%d teaches=%d\n",
 		len(w.NPCs), w.FoodCount(), w.ItemCount(), w.FoodSpawned, sched.TradeCount, sched.TeachCount)
@@ -447,15 +458,110 @@ func printFinalReport(cfg simConfig, w *sandbox.World, sched *sandbox.Scheduler)
 		if npc.ModSum(sandbox.ModGas) > 0 {
 			crystalNPCs++
 		}
-		if npc.Item == sandbox.ItemShield || npc.Item == sandbox.ItemCompass {
+		if npc.Item == sandbox.ItemShield || npc.Item == sandbox.ItemCompass || npc.Item == sandbox.ItemCharm || npc.Item == sandbox.ItemRemedy {
 			craftedItems++
 		}
 	}
 
 	fmt.Fprintf(os.Stderr, "total_gold=%d crystal_npcs=%d crafted_items=%d total_crafts=%d avg_stress=%d taught=%d teach_count=%d\n",
 		totalGold, crystalNPCs, craftedItems, totalCrafts, totalStress/max(len(w.NPCs), 1), totalTaught, totalTeachCount)
-	fmt.Fprintf(os.Stderr, "attacks=%d kills=%d heals=%d harvests=%d terraforms=%d food_rate=%.4f\n",
-		sched.AttackCount, sched.KillCount, sched.HealCount, sched.HarvestCount, sched.TerraformCount, w.FoodRate)
+	fmt.Fprintf(os.Stderr, "attacks=%d kills=%d heals=%d harvests=%d terraforms=%d builds=%d food_rate=%.4f\n",
+		sched.AttackCount, sched.KillCount, sched.HealCount, sched.HarvestCount, sched.TerraformCount, sched.BuildCount, w.FoodRate)
+	if clans := sandbox.ClanStats(w.NPCs); len(clans) > 0 {
+		fmt.Fprintf(os.Stderr, "clans=%d clan_joins=%d\n", len(clans), sched.ClanJoins)
+		for _, c := range clans {
+			fmt.Fprintf(os.Stderr, "  clan %-10s members=%-3d avg_fit=%-6d gold=%-5d items=%d\n",
+				c.Name, c.Members, c.AvgFitness, c.Gold, c.Items)
+		}
+	}
+	printDeaths(cfg, sched, epochDeaths, w.Tick)
+	if len(sched.Controllers) > 0 {
+		var ctlAlive, ctlFit, genAlive, genFit int
+		for _, npc := range w.NPCs {
+			if sched.Controllers[npc.ID] != nil {
+				ctlAlive++
+				ctlFit += npc.Fitness
+			} else {
+				genAlive++
+				genFit += npc.Fitness
+			}
+		}
+		fmt.Fprintf(os.Stderr, "controlled: alive=%d/%d avg_fit=%d | genomes: avg_fit=%d\n",
+			ctlAlive, len(sched.Controllers), ctlFit/max(ctlAlive, 1), genFit/max(genAlive, 1))
+	}
+	if sched.PredatorMating != nil {
+		var predAlive, predFit, preyAlive, preyFit int
+		for _, npc := range w.NPCs {
+			if npc.Predator {
+				predAlive++
+				predFit += npc.Fitness
+			} else {
+				preyAlive++
+				preyFit += npc.Fitness
+			}
+		}
+		fmt.Fprintf(os.Stderr, "predators: alive=%d avg_fit=%d prey_kills=%d | prey: alive=%d avg_fit=%d\n",
+			predAlive, predFit/max(predAlive, 1), sched.PreyKills, preyAlive, preyFit/max(preyAlive, 1))
+	}
+	if sched.SleepTicks > 0 {
+		fmt.Fprintf(os.Stderr, "sleep_ticks=%d\n", sched.SleepTicks)
+	}
+	if sched.TaxCollected > 0 || sched.GoldMinted > 0 {
+		fmt.Fprintf(os.Stderr, "economy: taxed=%d minted=%d burned=%d treasury=%d gold_gini=%.2f\n",
+			sched.TaxCollected, sched.GoldMinted, sched.GoldBurned, sched.Treasury, sandbox.GoldGini(w.NPCs))
+	}
+	if sched.ContractEvery > 0 {
+		fmt.Fprintf(os.Stderr, "contracts: fulfilled=%d gold_paid=%d open=%d\n",
+			sched.ContractsDone, sched.ContractGold, len(sched.Contracts))
+	}
+	if w.Markets() > 0 {
+		fmt.Fprintf(os.Stderr, "markets=%d buys=%d sells=%d gold_spent=%d\n",
+			w.Markets(), sched.MarketBuys, sched.MarketSells, sched.GoldSpent)
+	}
+	if cfg.posts > 0 {
+		fmt.Fprintf(os.Stderr, "trading posts: offers=%d trades=%d open=%d\n",
+			sched.PostOffers, sched.PostTrades, len(sched.Offers))
+	}
+	if cfg.schools > 0 {
+		x, y, busiest := sched.BusiestSchool()
+		fmt.Fprintf(os.Stderr, "schools: lessons=%d of %d teaches, busiest=(%d,%d) with %d\n",
+			sched.SchoolLessons(), sched.TeachCount, x, y, busiest)
+	}
+	if sched.BirthCount > 0 {
+		fmt.Fprintf(os.Stderr, "births=%d care_energy=%d\n", sched.BirthCount, sched.CareEnergy)
+	}
+	if sched.Infections > 0 {
+		sick := 0
+		for _, npc := range w.NPCs {
+			if npc.Infection > 0 {
+				sick++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "infections=%d cures=%d infected_now=%d\n", sched.Infections, sched.Cures, sick)
+	}
+	if sched.GiftCount+sched.GoldGifted > 0 {
+		fmt.Fprintf(os.Stderr, "gifts=%d gold_gifted=%d\n", sched.GiftCount, sched.GoldGifted)
+	}
+	if sched.ShotCount > 0 {
+		fmt.Fprintf(os.Stderr, "shots=%d shot_hits=%d\n", sched.ShotCount, sched.ShotHits)
+	}
+	if sched.BuildCount > 0 {
+		stored := 0
+		for _, c := range w.Chests {
+			stored += len(c.Items)
+		}
+		fmt.Fprintf(os.Stderr, "chests=%d chest_items=%d deposits=%d raids=%d\n",
+			len(w.Chests), stored, sched.DepositCount, sched.RaidCount)
+	}
+	if cfg.recipeMemes {
+		knowers := 0
+		for _, npc := range w.NPCs {
+			if npc.Recipes != 0 {
+				knowers++
+			}
+		}
+		fmt.Fprintf(os.Stderr, "recipes_learned=%d recipe_knowers=%d\n", sched.RecipesLearned, knowers)
+	}
 
 	itemCounts := make(map[byte]int)
 	for _, npc := range w.NPCs {
@@ -466,6 +572,7 @@ func printFinalReport(cfg simConfig, w *sandbox.World, sched *sandbox.Scheduler)
 	itemNames := map[byte]string{
 		sandbox.ItemTool: "tool", sandbox.ItemWeapon: "weapon", sandbox.ItemTreasure: "treasure",
 		sandbox.ItemCrystal: "crystal", sandbox.ItemShield: "shield", sandbox.ItemCompass: "compass",
+		sandbox.ItemCharm: "charm", sandbox.ItemRemedy: "remedy",
 	}
 	fmt.Fprintf(os.Stderr, "item_distribution:")
 	for item, count := range itemCounts {
@@ -511,17 +618,52 @@ func printFinalReport(cfg simConfig, w *sandbox.World, sched *sandbox.Scheduler)
 		fmt.Fprintf(os.Stderr, "best: fitness=%d age=%d food=%d gold=%d item=%d stress=%d gas_bonus=%d\n",
 			bestNPC.Fitness, bestNPC.Age, bestNPC.FoodEaten, bestNPC.Gold, bestNPC.Item,
 			bestNPC.Stress, bestNPC.ModSum(sandbox.ModGas))
+		fmt.Fprintf(os.Stderr, "best fitness terms:")
+		for _, term := range sched.Fitness.Breakdown(bestNPC) {
+			if term.Value != 0 {
+				fmt.Fprintf(os.Stderr, " %s=%d", term.Name, term.Value)
+			}
+		}
+		if gifts := bestNPC.GiftCount * sched.GiftFitness; gifts != 0 {
+			fmt.Fprintf(os.Stderr, " gifts=%d", gifts)
+		}
+		fmt.Fprintln(os.Stderr)
 		fmt.Fprintf(os.Stderr, "Best genome: ")
 		for _, b := range bestNPC.Genome {
 			fmt.Fprintf(os.Stderr, "%02x", b)
 		}
 		fmt.Fprintln(os.Stderr)
+		printGenome(bestNPC.Genome)
+	}
+}
+
+// topGenomes is how many of the fittest genomes snapshots and -report-json
+// disassemble.
+const topGenomes = 3
+
+// fittest returns the n fittest living NPCs, fittest first.
+func fittest(npcs []*sandbox.NPC, n int) []*sandbox.NPC {
+	alive := make([]*sandbox.NPC, 0, len(npcs))
+	for _, npc := range npcs {
+		if npc.Alive() {
+			alive = append(alive, npc)
+		}
+	}
+	sort.SliceStable(alive, func(i, j int) bool { return alive[i].Fitness > alive[j].Fitness })
+	return alive[:min(n, len(alive))]
+}
+
+// printGenome writes genome's annotated disassembly to stderr, indented.
+func printGenome(genome []byte) {
+	for _, line := range explainLines(genome) {
+		fmt.Fprintf(os.Stderr, "  %s\n", line)
 	}
 }
 
 // runFullSimulation runs a simulation and prints all output (for non-AB mode).
-func runFullSimulation(cfg simConfig, csvOut bool) {
-	rng := rand.New(rand.NewSource(cfg.seed))
+func runFullSimulation(cfg simConfig, csvOut bool) (failed int) {
+	src := sandbox.NewSource(cfg.seed) // saved in checkpoints
+	rng := rand.New(src)
 
 	ws := cfg.worldSize
 	if ws == 0 {
@@ -541,58 +683,97 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 		maxItems = 4
 	}
 	w.MaxItems = maxItems
-	ga := sandbox.NewGA(rng)
-	ga.Mode = cfg.crossoverMode
-	ga.ClassicRate = cfg.classicRate
-	ga.MaxGenomeSize = cfg.maxGenome
-	if cfg.wfcGenome {
-		ga.WFCEnabled = true
-		ga.Archetypes = [][]byte{
-			traderGenome, foragerGenome, crafterGenome, teacherGenome,
-			farmerGenome, fighterGenome, healerGenome,
-		}
-	}
+	w.PlaceMarkets(cfg.markets)
+	w.PlaceTradingPosts(cfg.posts)
+	w.PlaceSchools(cfg.schools)
+	w.Gradients = cfg.gradients
+	ga := newGA(cfg, rng)
 
 	sched := sandbox.NewScheduler(w, cfg.gas, io.Discard)
-
-	numTraders := int(float64(cfg.npcs) * cfg.traderFrac)
-	numForagers := cfg.npcs / 4
-	numCrafters := cfg.npcs / 10
-	numTeachers := cfg.npcs / 20
-	if numTeachers < 1 {
-		numTeachers = 1
-	}
-
-	for i := 0; i < cfg.npcs; i++ {
-		var genome []byte
-		if i < numTraders {
-			genome = make([]byte, len(traderGenome))
-			copy(genome, traderGenome)
-		} else if i < numTraders+numForagers {
-			genome = make([]byte, len(foragerGenome))
-			copy(genome, foragerGenome)
-		} else if i < numTraders+numForagers+numCrafters {
-			genome = make([]byte, len(crafterGenome))
-			copy(genome, crafterGenome)
-		} else if i < numTraders+numForagers+numCrafters+numTeachers {
-			genome = make([]byte, len(teacherGenome))
-			copy(genome, teacherGenome)
-		} else {
-			genome = ga.RandomGenome(24 + rng.Intn(16))
-		}
-		npc := sandbox.NewNPC(genome)
-		npc.X = rng.Intn(ws)
-		npc.Y = rng.Intn(ws)
-		if i < numTraders {
-			npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
-		}
-		if i >= numTraders+numForagers && i < numTraders+numForagers+numCrafters {
-			npc.Item = sandbox.ItemTool
-		}
-		if i >= numTraders+numForagers+numCrafters && i < numTraders+numForagers+numCrafters+numTeachers {
-			npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
-		}
-		w.Spawn(npc)
+	sched.RecipeMemes = cfg.recipeMemes
+	sched.ShootRange = cfg.shootRange
+	sched.GiftFitness = cfg.giftFitness
+	sched.Fitness = cfg.fitness
+	sched.Actions = cfg.actions
+	sched.GasCosts = cfg.gasCosts
+	curriculum := installCurriculum(sched, cfg.curriculum)
+	checks := installAssertions(sched, cfg.assertions)
+	sched.ClanShare = cfg.clanShare
+	sched.Contagion = cfg.contagion
+	sched.VisionCone = cfg.visionCone
+	sched.SelfModify = cfg.selfModify
+	sched.Workers = cfg.workers
+	sched.SensorFields = cfg.sensorFields
+	if cfg.sensorNoise != nil {
+		noise := *cfg.sensorNoise
+		noise.Seed = cfg.seed
+		sched.SensorNoise = &noise
+	}
+	sched.Economy = cfg.economy
+	sched.ContractEvery = cfg.contractEvery
+	sched.KeepGraveyard = cfg.biographies > 0
+	if cfg.reproduction != "epoch" {
+		sched.Mating = ga
+		sched.MaxPopulation = cfg.npcs * 2
+	}
+	var predGA *sandbox.GA
+	var predSrc *sandbox.Source // saved in checkpoints too
+	if cfg.predators > 0 {
+		predSrc = predatorSource(cfg.seed)
+		predGA = newGA(cfg, rand.New(predSrc))
+		sched.PredatorMating = predGA
+	}
+
+	// Seed genomes first; the role population fills the rest
+	roles := cfg.npcs - spawnSeeds(w, cfg.seeds, rng, ws)
+	mix := cfg.roles
+	if len(mix) == 0 {
+		mix = defaultRoles(cfg.traderFrac)
+	}
+	spawnRoles(w, mix, roles, ga, cfg.recipeMemes, rng, ws)
+	spawnPredators(w, cfg.predators, rng, ws)
+
+	// Human player: one NPC driven from stdin instead of a genome
+	var you *sandbox.NPC
+	var human *player
+	if cfg.player {
+		you = sandbox.NewNPC([]byte{0xF0}) // halt: the controller decides
+		you.X = rng.Intn(ws)
+		you.Y = rng.Intn(ws)
+		w.Spawn(you)
+		human = newPlayer(w, os.Stdin, os.Stderr)
+		sched.Controllers = map[uint16]sandbox.Controller{you.ID: human}
+	}
+
+	// Keyboard control of the tick loop
+	sched.SetSpeed(cfg.speed)
+	if cfg.control {
+		go readControl(&console{w: w, sched: sched}, os.Stdin, os.Stderr)
+	}
+
+	// External brains: NPCs driven over TCP (JSON Ring0 out, Ring1 back)
+	if cfg.brainAddr != "" {
+		brain, err := sandbox.DialBrain(cfg.brainAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "brain: %v\n", err)
+			exit(1)
+		}
+		defer brain.Close()
+		if sched.Controllers == nil {
+			sched.Controllers = make(map[uint16]sandbox.Controller)
+		}
+		for i := 0; i < cfg.brainCount; i++ {
+			npc := sandbox.NewNPC([]byte{0xF0}) // halt: the brain decides
+			npc.X = rng.Intn(ws)
+			npc.Y = rng.Intn(ws)
+			w.Spawn(npc)
+			sched.Controllers[npc.ID] = brain
+		}
+		defer func() {
+			if brain.Err != nil {
+				fmt.Fprintf(os.Stderr, "brain: %v\n", brain.Err)
+			}
+		}()
 	}
 
 	seedFood := ws
@@ -620,6 +801,37 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 		}
 	}
 	var timeline []timePoint
+	var epochDeaths [][sandbox.DeathCauses]int // cumulative deaths at each evolve tick
+
+	// Resume: the checkpoint replaces the world set up above
+	gas := []*sandbox.GA{ga}
+	if predGA != nil {
+		gas = append(gas, predGA)
+	}
+	start := 0
+	if cfg.resumeFrom != "" {
+		st, err := resumeCheckpoint(cfg.resumeFrom, sched, src, gas)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "resume-from: %v\n", err)
+			exit(1)
+		}
+		if curriculum != nil {
+			curriculum.Reached = min(st.Stage, len(curriculum.Stages))
+		}
+		if checks != nil && len(st.Checks) == len(checks.Checks) {
+			checks.Checks = st.Checks
+		}
+		if predSrc != nil {
+			if err := predSrc.UnmarshalBinary(st.PredatorRNG); err != nil {
+				fmt.Fprintf(os.Stderr, "resume-from: predators: %v\n", err)
+				exit(1)
+			}
+		}
+		epochDeaths = st.EpochDeaths
+		start = w.Tick
+		status.event("resume", start, logFields{"file": cfg.resumeFrom},
+			fmt.Sprintf("Resumed from %s at tick %d", cfg.resumeFrom, start))
+	}
 
 	// Set up recorder if requested
 	var rec *sandbox.Recorder
@@ -628,7 +840,7 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 		rec, err = sandbox.NewRecorder(cfg.record, cfg.recordEvery)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "record: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
 		defer rec.Close()
 		var biomeGrid []byte
@@ -646,39 +858,125 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 		})
 	}
 
+	render, err := newRenderer(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "render: %v\n", err)
+		exit(1)
+	}
+
+	var lineage *sandbox.Lineage
+	if cfg.lineage != "" {
+		lineage = sandbox.NewLineage(w.NPCs, w.Tick)
+		sched.Subscribe(lineage)
+	}
+
+	var events *sandbox.EventLog
+	if cfg.eventLog != "" {
+		f, err := os.Create(cfg.eventLog)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "event-log: %v\n", err)
+			exit(1)
+		}
+		defer f.Close()
+		events = sandbox.NewEventLog(f)
+		events.Attach(sched)
+	}
+
+	var trace *sandbox.BrainTrace
+	if cfg.traceNPC > 0 {
+		f, err := os.Create(cfg.traceFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "trace-npc: %v\n", err)
+			exit(1)
+		}
+		defer f.Close()
+		trace = sandbox.NewBrainTrace(uint16(cfg.traceNPC), f)
+		sched.Trace = trace
+	}
+
+	var stats *statsDB
+	if cfg.sqlite != "" {
+		var err error
+		if stats, err = openStatsDB(cfg.sqlite, cfg, ws); err != nil {
+			fmt.Fprintf(os.Stderr, "sqlite: %v\n", err)
+			exit(1)
+		}
+		sched.Subscribe(sandbox.SubscriberFunc(stats.onEvent))
+	}
+
 	// Load injected genome if requested
 	var injectedGenome []byte
 	if cfg.inject != "" {
-		gf, err := os.Open(cfg.inject)
+		var err error
+		injectedGenome, err = readGenomeFile(cfg.inject)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "inject: %v\n", err)
-			os.Exit(1)
-		}
-		sc := bufio.NewScanner(gf)
-		for sc.Scan() {
-			line := strings.TrimSpace(sc.Text())
-			if line != "" {
-				injectedGenome, err = hex.DecodeString(line)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "inject: bad hex: %v\n", err)
-					os.Exit(1)
-				}
-				break
-			}
+			exit(1)
 		}
-		gf.Close()
-		if len(injectedGenome) == 0 {
-			fmt.Fprintf(os.Stderr, "inject: no genome found in %s\n", cfg.inject)
-			os.Exit(1)
+	}
+
+	// Live terminal viewer: it paces the loop in place of sched.Wait
+	wait := sched.Wait
+	var ui *tui
+	if cfg.tui {
+		var err error
+		ui, err = newTUI(w, sched, cfg.speed)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "tui: %v\n", err)
+			exit(1)
 		}
+		defer ui.close()
+		wait = ui.wait
 	}
 
-	for tick := 0; tick < cfg.ticks; tick++ {
+	// Web dashboard: publishes a frame before each tick
+	if cfg.serve != "" {
+		dash, err := serveDashboard(cfg.serve, cfg.serveToken, w, sched)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "serve: %v\n", err)
+			exit(1)
+		}
+		status.event("serve", -1, logFields{"url": dash.url}, "Dashboard at "+dash.url)
+		wait = dash.wrap(wait)
+	}
+
+	// Control socket: external programs drive the run, which waits for
+	// them to resume or step it
+	var ctl *controlServer
+	if cfg.controlSocket != "" {
+		var err error
+		ctl, err = listenControl(cfg.controlSocket, w, sched, rng)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "control-socket: %v\n", err)
+			exit(1)
+		}
+		sched.Pause()
+		status.event("control-socket", -1, logFields{"addr": ctl.addr()}, fmt.Sprintf("Control socket on %s (paused)", ctl.addr()))
+	}
+
+	// Progress bar, unless something else is using the terminal
+	status.startProgress(cfg.ticks, cfg.progress && !cfg.tui && !cfg.control && !cfg.player && isTerminal(os.Stderr))
+	for tick := start; tick < cfg.ticks; tick++ {
+		if !wait() {
+			break
+		}
 		sched.Tick()
+		status.progress(tick)
+
+		if human != nil && (human.quit || !you.Alive()) {
+			if !you.Alive() {
+				status.event("player-died", tick, logFields{"age": you.Age, "fitness": you.Fitness},
+					fmt.Sprintf("You died at tick %d (age %d, fitness %d)", tick, you.Age, you.Fitness))
+			}
+			break
+		}
 
 		if rec != nil {
 			rec.RecordTick(tick, w, sched)
 		}
+		if render != nil {
+			render.frame(w, tick)
+		}
 
 		// Inject custom genome at specified tick
 		if injectedGenome != nil && tick == cfg.injectAt {
@@ -690,31 +988,57 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 				npc.Y = rng.Intn(ws)
 				w.Spawn(npc)
 			}
-			fmt.Fprintf(os.Stderr, "Injected %d NPCs with genome from %s at tick %d\n",
-				cfg.injectCount, cfg.inject, tick)
+			status.event("inject", tick, logFields{"count": cfg.injectCount, "genome_file": cfg.inject},
+				fmt.Sprintf("Injected %d NPCs with genome from %s at tick %d", cfg.injectCount, cfg.inject, tick))
 		}
 
 		// Dynamic brain growth
 		if cfg.genomeGrowDelta > 0 && cfg.genomeGrowEvery > 0 && tick > 0 && tick%cfg.genomeGrowEvery == 0 {
 			ga.MaxGenomeSize += cfg.genomeGrowDelta
-			fmt.Fprintf(os.Stderr, "Tick %d: max genome size → %d\n", tick, ga.MaxGenomeSize)
+			status.event("genome-grow", tick, logFields{"max_genome": ga.MaxGenomeSize},
+				fmt.Sprintf("Tick %d: max genome size → %d", tick, ga.MaxGenomeSize))
 		}
 
 		// Dynamic gas scaling
 		if cfg.gasGrowDelta > 0 && cfg.gasGrowEvery > 0 && tick > 0 && tick%cfg.gasGrowEvery == 0 {
 			sched.Gas += cfg.gasGrowDelta
-			fmt.Fprintf(os.Stderr, "Tick %d: base gas → %d\n", tick, sched.Gas)
+			status.event("gas-grow", tick, logFields{"gas": sched.Gas}, fmt.Sprintf("Tick %d: base gas → %d", tick, sched.Gas))
 		}
 
 		if tick%tlEvery == 0 {
-			timeline = append(timeline, sampleStats(w, sched, tick))
+			timeline = append(timeline, sampleStats(w, sched, ga, tick))
+			if stats != nil {
+				stats.addSample(timeline[len(timeline)-1])
+			}
 		}
 
 		if tick > 0 && tick%cfg.evolveEvery == 0 {
-			w.NPCs = ga.Evolve(w.NPCs)
+			sched.SettleEconomy()
+			epochDeaths = append(epochDeaths, sched.Deaths)
+			if stats != nil {
+				deaths := 0
+				for _, n := range sched.Deaths {
+					deaths += n
+				}
+				stats.addEpoch(len(epochDeaths), sampleStats(w, sched, ga, tick), w, deaths)
+			}
+			if cfg.reproduction != "mate" {
+				// Player and externally driven NPCs are never culled or rebred
+				pop := w.NPCs
+				if len(sched.Controllers) > 0 {
+					pop = make([]*sandbox.NPC, 0, len(w.NPCs))
+					for _, npc := range w.NPCs {
+						if sched.Controllers[npc.ID] == nil {
+							pop = append(pop, npc)
+						}
+					}
+				}
+				evolve(sched, ga, predGA, pop)
+			}
 
 			refillIdx := 0
-			for len(w.NPCs) < cfg.npcs/2 {
+			predators := countPredators(w.NPCs)
+			for len(w.NPCs)-predators < cfg.npcs/2 {
 				var genome []byte
 				if cfg.wfcGenome && refillIdx%5 < 3 {
 					genome = ga.WFCGenome(24 + rng.Intn(16))
@@ -739,6 +1063,9 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 				w.Spawn(npc)
 				refillIdx++
 			}
+			if predGA != nil {
+				refillPredators(w, cfg.predators, rng, ws)
+			}
 		}
 
 		if cfg.verbose && tick%reportInterval == 0 {
@@ -746,78 +1073,118 @@ func runFullSimulation(cfg simConfig, csvOut bool) {
 		}
 
 		if cfg.snapEvery > 0 && tick > 0 && tick%cfg.snapEvery == 0 {
-			printSnapshot(w, sched, tick)
+			logSnapshot(w, sched, tick)
 		}
 
 		if len(w.NPCs) == 0 {
-			fmt.Fprintf(os.Stderr, "Population extinct at tick %d\n", tick)
+			status.event("extinct", tick, nil, fmt.Sprintf("Population extinct at tick %d", tick))
 			break
 		}
-	}
-
-	printFinalReport(cfg, w, sched)
 
-	if csvOut {
-		printCSV(timeline, os.Stdout)
+		if cfg.checkpointEvery > 0 && (tick+1)%cfg.checkpointEvery == 0 {
+			st := runState{EpochDeaths: epochDeaths}
+			if curriculum != nil {
+				st.Stage = curriculum.Reached
+			}
+			if checks != nil {
+				st.Checks = checks.Checks
+			}
+			if predSrc != nil {
+				st.PredatorRNG, _ = predSrc.MarshalBinary() // a PCG state always marshals
+			}
+			if err := writeCheckpoint(cfg.checkpointFile, sched, src, gas, st); err != nil {
+				fmt.Fprintf(os.Stderr, "checkpoint: %v\n", err)
+			} else {
+				status.event("checkpoint", tick, logFields{"file": cfg.checkpointFile},
+					fmt.Sprintf("Tick %d: checkpoint saved to %s", tick, cfg.checkpointFile))
+			}
+		}
+	}
+	status.endProgress()
+	if ctl != nil {
+		ctl.close()
+	}
+	if ui != nil {
+		ui.close()
 	}
-	if len(timeline) > 1 {
-		printTimeline(timeline, tlEvery)
+	if checks != nil {
+		failed = checks.Finish(sched)
 	}
 
-	printSnapshot(w, sched, w.Tick)
-}
+	if render != nil {
+		if err := render.finish(); err != nil {
+			fmt.Fprintf(os.Stderr, "timelapse: %v\n", err)
+		}
+	}
 
-func printABComparison(cfg simConfig, growth, classic simResult) {
-	fmt.Fprintf(os.Stderr, "\n=== A/B Comparison (seed=%d, npcs=%d, ticks=%d) ===\n",
-		cfg.seed, cfg.npcs, cfg.ticks)
-	fmt.Fprintf(os.Stderr, "%-16s %10s %10s %10s\n", "", "Growth", "Classic", "Delta")
+	if stats != nil {
+		if err := stats.close(w, len(epochDeaths)); err != nil {
+			fmt.Fprintf(os.Stderr, "sqlite: %v\n", err)
+		}
+	}
 
-	type row struct {
-		label   string
-		g, c    int
+	if events != nil {
+		if err := events.Finish(); err != nil {
+			fmt.Fprintf(os.Stderr, "event-log: %v\n", err)
+		}
 	}
-	rows := []row{
-		{"alive", growth.alive, classic.alive},
-		{"avgFit", growth.avgFit, classic.avgFit},
-		{"bestFit", growth.bestFit, classic.bestFit},
-		{"trades", growth.trades, classic.trades},
-		{"teaches", growth.teaches, classic.teaches},
-		{"genomeAvg", growth.genomeAvg, classic.genomeAvg},
-		{"totalGold", growth.totalGold, classic.totalGold},
+
+	if trace != nil {
+		if err := trace.Finish(); err != nil {
+			fmt.Fprintf(os.Stderr, "trace-npc: %v\n", err)
+		}
 	}
 
-	for _, r := range rows {
-		delta := r.g - r.c
-		sign := "+"
-		if delta < 0 {
-			sign = ""
+	if lineage != nil {
+		lineage.Finish(w.NPCs, w.Tick)
+		if err := writeLineage(lineage, cfg.lineage, cfg.lineageAll); err != nil {
+			fmt.Fprintf(os.Stderr, "lineage: %v\n", err)
 		}
-		fmt.Fprintf(os.Stderr, "%-16s %10d %10d %10s%d\n", r.label, r.g, r.c, sign, delta)
 	}
 
-	// Paired sparklines
-	type pairedMetric struct {
-		label string
-		fn    func(timePoint) int
+	printFinalReport(cfg, w, sched, append(epochDeaths, sched.Deaths))
+	if cfg.reportJSON != "" {
+		if err := writeReport(cfg.reportJSON, buildReport(cfg, w, sched, append(epochDeaths, sched.Deaths), timeline, checks)); err != nil {
+			fmt.Fprintf(os.Stderr, "report-json: %v\n", err)
+		}
 	}
-	paired := []pairedMetric{
-		{"avgFit", func(tp timePoint) int { return tp.avgFit }},
-		{"bestFit", func(tp timePoint) int { return tp.bestFit }},
-		{"genomeAvg", func(tp timePoint) int { return tp.genomeAvg }},
-		{"alive", func(tp timePoint) int { return tp.alive }},
-		{"trades", func(tp timePoint) int { return tp.trades }},
+	if cfg.exportBest > 0 {
+		if _, err := exportBest(cfg.exportDir, cfg.exportBest, w, sched); err != nil {
+			fmt.Fprintf(os.Stderr, "export-best: %v\n", err)
+		}
 	}
 
-	fmt.Fprintln(os.Stderr)
-	for _, m := range paired {
-		gVals := extractField(growth.timeline, m.fn)
-		cVals := extractField(classic.timeline, m.fn)
-		fmt.Fprintln(os.Stderr, sparkline(m.label+" (G)", gVals))
-		fmt.Fprintln(os.Stderr, sparkline(m.label+" (C)", cVals))
+	if csvOut {
+		printCSV(timeline, os.Stdout)
 	}
+	if !status.quiet {
+		if len(timeline) > 1 {
+			printTimeline(timeline, tlEvery)
+		}
+		printSnapshot(w, sched, w.Tick)
+	}
+	if checks != nil {
+		printAssertions(checks, failed)
+	}
+	return failed
+}
+
+// exit stops the CPU profile, if one is running, so it is written out
+// before the process exits with code.
+func exit(code int) {
+	pprof.StopCPUProfile()
+	os.Exit(code)
 }
 
 func main() {
+	// "sandbox sweep|experiment [flags]": the usual flags set the base config
+	var subcommand string
+	if len(os.Args) > 1 && (os.Args[1] == "sweep" || os.Args[1] == "experiment") {
+		subcommand = os.Args[1]
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+	sweepMode, experimentMode := subcommand == "sweep", subcommand == "experiment"
+
 	npcs := flag.Int("npcs", 20, "number of NPCs")
 	worldSize := flag.Int("world", 0, "world size (NxN), 0=auto")
 	ticks := flag.Int("ticks", 10000, "number of ticks to simulate")
@@ -825,33 +1192,257 @@ func main() {
 	evolveEvery := flag.Int("evolve-every", 100, "ticks between evolution rounds")
 	seed := flag.Int64("seed", 42, "random seed")
 	verbose := flag.Bool("verbose", false, "verbose output")
+	quiet := flag.Bool("quiet", false, "print only the final report (and -csv): no status lines, progress, timeline or snapshots")
+	logFormat := flag.String("log-format", "text", "status line format: text or json (one JSON object per line)")
+	progress := flag.Bool("progress", true, "show a progress bar with ETA while running, when stderr is a terminal")
 	traderFrac := flag.Float64("traders", 0.25, "fraction of initial population seeded with trader genome")
+	rolesSpec := flag.String("roles", "", "initial role mix replacing the default (and -traders), e.g. trader:0.25,forager:0.25,crafter:0.1,teacher:0.05:1,random:rest; entries are name:share[:min], name=genome-file:share for a custom role")
+	rolesFile := flag.String("roles-file", "", "read -roles entries from this file, one per line (# comments)")
 	snapEvery := flag.Int("snap-every", 0, "print spatial snapshot every N ticks (0=off)")
 	timelineEvery := flag.Int("timeline", 0, "sample stats every N ticks for sparkline chart (0=auto ~80 cols)")
 	csvOut := flag.Bool("csv", false, "output timeline as CSV to stdout")
-	crossover := flag.String("crossover", "growth", "crossover mode: growth or classic")
+	crossover := flag.String("crossover", "growth", "crossover mode: growth, classic, twopoint or uniform")
+	selectionName := flag.String("selection", "tournament", "parent selection: tournament, roulette, rank or truncation")
+	tournamentSize := flag.Int("tournament-size", 3, "candidates per tournament (-selection tournament)")
+	diversityTarget := flag.Float64("diversity-target", 0, "raise the mutation rate (up to 3x) while genome diversity (0-1) is below this; 0 = fixed rate")
+	elitism := flag.Int("elitism", 0, "keep the top N genomes unchanged each evolve round (aged-out elites are cloned)")
+	speciesThreshold := flag.Float64("species-threshold", 0, "genome distance (0-1) that splits species for fitness sharing; 0 = no speciation")
+	clonePenalty := flag.Float64("clone-penalty", 0, "fitness fraction (0-1) each extra copy of a genome loses when ranked for breeding; 0 = off")
+	cloneDistance := flag.Float64("clone-distance", 0, "genome distance (0-1) under which near-identical genomes count as copies for -clone-penalty; 0 = exact copies only")
+	lengthPenalty := flag.Float64("length-penalty", 0, "parsimony pressure: fitness deducted per genome byte when ranking for breeding (0 = off)")
+	structural := flag.Bool("structural", false, "jump-aware operators: crossover at basic-block boundaries, mutate whole instructions, re-fix jump offsets")
+	shorterFirst := flag.Bool("shorter-first", false, "parsimony pressure: among equal fitness, rank the shorter genome higher")
 	classicRate := flag.Float64("classic-rate", 0.20, "classic crossover fraction (0-1)")
 	biomes := flag.Bool("biomes", false, "enable WFC biome generation")
 	wfcGenome := flag.Bool("wfc-genome", false, "use WFC to generate structurally valid genomes")
 	maxGenome := flag.Int("max-genome", 128, "maximum genome size in bytes (default 128)")
 	record := flag.String("record", "", "record simulation to JSONL file")
 	recordEvery := flag.Int("record-every", 100, "record a frame every N ticks")
+	renderEvery := flag.Int("render-every", 0, "render the world to a PNG in -render-dir every N ticks (0=off)")
+	renderDir := flag.String("render-dir", "frames", "directory for -render-every PNG frames")
+	renderScale := flag.Int("render-scale", 4, "pixels per tile in rendered frames")
+	renderColor := flag.String("render-color", "role", "colour rendered NPCs by role (predators, clans) or fitness")
+	timelapse := flag.String("timelapse", "", "write an animated GIF of rendered frames (every -render-every ticks, else ~100 over the run)")
 	inject := flag.String("inject", "", "hex genome file to inject (first line = hex bytes)")
 	injectCount := flag.Int("inject-count", 1, "number of copies to spawn from injected genome")
 	injectAt := flag.Int("inject-at", 0, "tick at which to inject genome")
 	genomeGrowDelta := flag.Int("genome-grow", 64, "increase max genome size by this amount each period (0=off)")
 	genomeGrowEvery := flag.Int("genome-grow-every", 50000, "ticks between genome size increases")
 	gasGrowDelta := flag.Int("gas-grow", 10, "increase gas by this amount each period (0=off)")
+	gasCostsSpec := flag.String("gas-costs", "", "per-opcode gas instead of 1 per instruction: flat or weighted, then overrides, e.g. weighted,exec=5,*=2 (names as in -disasm)")
 	gasGrowEvery := flag.Int("gas-grow-every", 70000, "ticks between gas increases")
 	ab := flag.Bool("ab", false, "run both growth and classic modes, print comparison")
+	abStructural := flag.Bool("ab-structural", false, "run naive and jump-aware (-structural) operators, print comparison")
+	abA := flag.String("ab-a", "", "A/B mode: scenario A's overrides of the base config, e.g. actions=attack.energy=15;elitism=4 (sweep parameter names), or a scenario file setting them")
+	abB := flag.String("ab-b", "", "A/B mode: scenario B's overrides, compared against A")
+	abSeeds := flag.Int("ab-seeds", 1, "A/B modes: run both scenarios on seeds seed..seed+N-1 and compare the means")
+	recipeMemes := flag.Bool("recipe-memes", false, "forge recipes must be learned (teaching, forge discovery, inheritance)")
+	shootRange := flag.Int("shoot-range", 4, "max tiles a weapon shot travels (0=no ranged attacks)")
+	giftFitness := flag.Int("gift-fitness", 0, "fitness reward per gift given (0=altruism unrewarded)")
+	fitnessSpec := flag.String("fitness", "", "fitness weight overrides, e.g. gold=0,kill=40 (keys: age food health gold craft teach trade kill stress explore coop econ)")
+	curriculumSpec := flag.String("curriculum", "", "staged difficulty, e.g. at=2000,food=0.1;fit=500,poison=4,night=96 (keys: at fit food maxfood poison night)")
+	curriculumFile := flag.String("curriculum-file", "", "read -curriculum stages from this file, one per line (# comments)")
+	gradientsSpec := flag.String("gradients", "", "spawn richness across the map, e.g. food=x:1,0.2;items=x:0.2,1;poison=x:0,4,0 (resources: food items poison; weights spread edge to edge along x or y)")
+	gradientsFile := flag.String("gradients-file", "", "read -gradients from this file, one per line (# comments)")
+	sensorNoiseSpec := flag.String("sensor-noise", "", "imperfect senses, e.g. food-dist=gauss:2;near-dist=uniform:3,drop:0.1;*=drop:0.02 (brain sensor names, * = the rest)")
+	sensorNoiseFile := flag.String("sensor-noise-file", "", "read -sensor-noise rules from this file, one per line (# comments)")
+	assertSpec := flag.String("assert", "", "checks on the run, e.g. 'trades >= 100 by tick 10000; population never below 5'; a failure exits with status 3")
+	assertFile := flag.String("assert-file", "", "read -assert checks from this file, one per line (# comments)")
+	actionsSpec := flag.String("actions", "", "action balance overrides, e.g. attack.energy=15,shoot.cooldown=3 (fields: energy cooldown range)")
+	scenarioPath := flag.String("scenario", "", "read flags from this scenario file: one per line as 'name value', lists such as -actions as [actions] sections; the command line overrides it")
+	clanShare := flag.Float64("clan-share", 0, "fraction of fitness taken from the clan average (0-1)")
+	contagion := flag.Int("contagion", 0, "% chance per tick an infected NPC infects each neighbour (0=no disease)")
+	visionCone := flag.Bool("vision-cone", false, "NPCs only sense the 90° cone they face (plus a behind sensor)")
+	selfModify := flag.Bool("self-modify", false, "let genomes patch their own running code with poke (verified; patches last one turn)")
+	biographies := flag.Int("biographies", 0, "print life stories of the N longest-lived dead NPCs (0=off)")
+	control := flag.Bool("control", false, "read pause/resume/step/speed and inspection commands (help lists them) from stdin while running")
+	tuiMode := flag.Bool("tui", false, "live terminal viewer: colour map, event feed, NPC inspector and pause/step/speed keys")
+	controlSocket := flag.String("control-socket", "", "accept JSON commands, one object per line, on this unix socket (unix:PATH or a path) or TCP address: pause, resume, step, speed, snapshot, npc, top, inject, swap, console, stop; the run starts paused")
+	serve := flag.String("serve", "", "serve a live web dashboard on this address (e.g. :8080, which listens on 127.0.0.1 only): streamed map, event feed, NPC inspector, pause/step/speed, genome download, Prometheus /metrics")
+	serveToken := flag.String("serve-token", "", "token the -serve dashboard requires to pause, step or steer the run and to use its console (default: a random one, shown in the dashboard URL)")
+	speed := flag.Float64("speed", 0, "cap the simulation at this many ticks per second (0 = as fast as possible)")
+	verifyDet := flag.Bool("verify-determinism", false, "run the seed twice and report the first tick whose world hash differs; with -workers N>1 the rerun plans on 1 worker, otherwise it repeats the same settings")
+	cpuProfile := flag.String("cpuprofile", "", "write a CPU profile to this file")
+	lineage := flag.String("lineage", "", "write the family tree of the survivors to this file at the end (.dot/.gv = Graphviz, else JSON)")
+	lineageAll := flag.Bool("lineage-all", false, "with -lineage, include extinct lineages too")
+	reportJSON := flag.String("report-json", "", "write the final report (population, items, gurus, best genome with disassembly, timeline) to this file as JSON")
+	exportBestN := flag.Int("export-best", 0, "at the end, export the K fittest genomes (hex, .bin, disassembly, stats JSON) into -export-dir")
+	exportDir := flag.String("export-dir", "best", "where -export-best writes")
+	sqlitePath := flag.String("sqlite", "", "append this run's epochs, timeline samples, trades and genomes to this SQLite database")
+	traceNPC := flag.Int("trace-npc", 0, "record every genome run of the NPC with this ID (sensors, instructions, outputs) as JSON lines")
+	traceFile := flag.String("trace-file", "", "where -trace-npc writes (default npc-ID.trace.jsonl)")
+	eventLog := flag.String("event-log", "", "write NPC events (birth, per-epoch moves, trade, teach, craft, death) to this file, one JSON object per line")
+	checkpointEvery := flag.Int("checkpoint-every", 0, "save the full run state (world, scheduler, GA, RNG) to -checkpoint-file every N ticks; 0 = never")
+	checkpointFile := flag.String("checkpoint-file", "checkpoint.json", "where -checkpoint-every saves, replacing the last checkpoint")
+	resumeFrom := flag.String("resume-from", "", "continue a run exactly from this checkpoint; give the same flags (the timeline and output files cover only the resumed ticks)")
+	var seeds []seedGenome
+	flag.Var(seedFlag{seeds: &seeds, file: true}, "genome-file", "seed the population from a genome file (hex line, or .psil brain); append ,count=N and ,item=NAME (repeatable)")
+	flag.Var(seedFlag{seeds: &seeds}, "genome-hex", "seed the population with a hex genome; append ,count=N and ,item=NAME (repeatable)")
+	predators := flag.Int("predators", 0, "add N predators: they feed by attacking prey instead of eating food, and evolve under their own GA")
+	markets := flag.Int("markets", 0, "place N market tiles where NPCs buy meals and items for gold and sell items back (0=no markets)")
+	schools := flag.Int("schools", 0, "place N school tiles where teaching copies longer fragments, succeeds more often and costs half the energy (0=no schools)")
+	posts := flag.Int("posts", 0, "place N trading-post tiles where NPCs leave items on offer for others to trade against later (0=no posts)")
+	contractEvery := flag.Int("contracts", 0, "post a contract (deliver an item to a tile, or kill a wanted NPC) for gold every N ticks (0=no contracts)")
+	taxRate := flag.Float64("tax", 0, "fraction of gold earned by trading withheld as tax (0-1)")
+	taxRedistribute := flag.Bool("tax-redistribute", false, "share taxed gold equally among the living each epoch instead of destroying it")
+	goldFaucet := flag.Int("gold-faucet", 0, "gold minted for every living NPC each epoch (inflation)")
+	wealthCap := flag.Int("wealth-cap", 0, "gold above this is seized into the treasury each epoch (0=no cap)")
+	islands := flag.Int("islands", 0, "evolve N separate populations (seeds seed..seed+N-1) with migration between them; 0 = one world")
+	migrateEvery := flag.Int("migrate-every", 500, "ticks between island migrations (-islands)")
+	migrants := flag.Int("migrants", 2, "genomes each island sends per migration (-islands)")
+	topologyName := flag.String("topology", "ring", "island migration topology: ring or random")
+	playerMode := flag.Bool("player", false, "spawn one NPC controlled from stdin (WASD + action keys, one command line per tick)")
+	brainAddr := flag.String("brain-addr", "", "TCP address of an external brain driving some NPCs (newline-delimited JSON)")
+	brainCount := flag.Int("brain-count", 1, "number of NPCs driven by the external brain")
+	reproduction := flag.String("reproduction", "epoch", "reproduction: epoch (global GA), mate (in-world act.mate only), or both")
+	workers := flag.Int("workers", 0, "plan NPC brains on N goroutines against the start-of-tick world, then act in NPC-ID order (the same run for any N>0; 0=classic interleaved ticks)")
+	sensorFields := flag.Bool("sensor-fields", false, "read nearest food/item/poison/NPC sensors from per-tick BFS fields instead of per-NPC scans (ignored with -vision-cone)")
+	brainsDir := flag.String("brains-dir", "", "directory of <role>.psil brains compiled over the built-in role genomes")
+	sweepSpec := flag.String("sweep-params", "", "sweep mode: parameters to vary, e.g. elitism=0,2,4;species-threshold=0,0.3 (lo:hi ranges with -sweep-samples)")
+	sweepSamples := flag.Int("sweep-samples", 0, "sweep mode: try N random points instead of the full grid")
+	sweepSeeds := flag.Int("sweep-seeds", 3, "sweep mode: runs per point (seeds seed..seed+N-1)")
+	sweepJobs := flag.Int("sweep-jobs", runtime.NumCPU(), "sweep mode: simulations run in parallel")
+	sweepOut := flag.String("sweep-out", "", "sweep mode: write the ranked report here (.json = JSON, else CSV; default CSV to stdout)")
+	sweepRank := flag.String("sweep-rank", "avg_fit", "sweep mode: metric to rank points by, best (highest mean) first")
+	experimentSeeds := flag.String("seeds", "1..10", "experiment mode: seeds to run, e.g. 1..30 or 1,5,9")
+	parallel := flag.Int("parallel", runtime.NumCPU(), "experiment and A/B modes: simulations run in parallel")
+	experimentOut := flag.String("experiment-out", "", "experiment mode: write the statistics here (.json = JSON with per-seed metrics, else CSV; default a table on stdout)")
 	flag.Parse()
+	if *scenarioPath != "" {
+		if err := applyScenario(*scenarioPath); err != nil {
+			fmt.Fprintf(os.Stderr, "scenario: %v\n", err)
+			exit(1)
+		}
+	}
 
-	var mode sandbox.CrossoverMode
-	switch strings.ToLower(*crossover) {
-	case "classic":
-		mode = sandbox.CrossoverClassic
+	switch *logFormat {
+	case "text":
+	case "json":
+		status.json = true
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -log-format %q (want text or json)\n", *logFormat)
+		exit(1)
+	}
+	status.quiet = *quiet
+
+	if *control && *playerMode {
+		fmt.Fprintln(os.Stderr, "-control and -player both read stdin; pick one")
+		exit(1)
+	}
+	if *tuiMode && (*control || *playerMode) {
+		fmt.Fprintln(os.Stderr, "-tui takes over the terminal; it cannot be combined with -control or -player")
+		exit(1)
+	}
+	abMode := *ab || *abStructural || *abA != "" || *abB != ""
+	if (*assertSpec != "" || *assertFile != "") && (sweepMode || experimentMode || *verifyDet || *islands > 1 || abMode) {
+		fmt.Fprintln(os.Stderr, "-assert checks a single run; it cannot be combined with sweep, experiment, -verify-determinism, -islands or -ab")
+		exit(1)
+	}
+	if (*checkpointEvery > 0 || *resumeFrom != "") && (sweepMode || experimentMode || *verifyDet || *islands > 1 || abMode) {
+		fmt.Fprintln(os.Stderr, "-checkpoint-every and -resume-from save a single run; they cannot be combined with sweep, experiment, -verify-determinism, -islands or -ab")
+		exit(1)
+	}
+	if *resumeFrom != "" && (*playerMode || *brainAddr != "") {
+		fmt.Fprintln(os.Stderr, "-resume-from cannot bring back -player or -brain-addr NPCs")
+		exit(1)
+	}
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+		defer pprof.StopCPUProfile()
+	}
+
+	fitness, err := sandbox.ParseFitness(*fitnessSpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	actions, err := sandbox.ParseActionCosts(*actionsSpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	var gasCosts *micro.GasTable
+	if *gasCostsSpec != "" {
+		if gasCosts, err = micro.ParseGasCosts(*gasCostsSpec); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(1)
+		}
+	}
+	curriculum, err := loadCurriculum(*curriculumSpec, *curriculumFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	gradients, err := loadGradients(*gradientsSpec, *gradientsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	sensorNoise, err := loadSensorNoise(*sensorNoiseSpec, *sensorNoiseFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	assertions, err := loadAssertions(*assertSpec, *assertFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	roles, err := loadRoles(*rolesSpec, *rolesFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(1)
+	}
+	if *taxRate < 0 || *taxRate > 1 {
+		fmt.Fprintf(os.Stderr, "-tax: %g is not a fraction (want 0-1)\n", *taxRate)
+		exit(1)
+	}
+	if *traceNPC < 0 || *traceNPC > math.MaxUint16 {
+		fmt.Fprintf(os.Stderr, "-trace-npc: no NPC has ID %d\n", *traceNPC)
+		exit(1)
+	}
+	if *traceFile == "" {
+		*traceFile = fmt.Sprintf("npc-%d.trace.jsonl", *traceNPC)
+	}
+
+	if *brainsDir != "" {
+		if err := loadBrains(*brainsDir); err != nil {
+			fmt.Fprintf(os.Stderr, "brains: %v\n", err)
+			exit(1)
+		}
+	}
+
+	mode := parseCrossover(*crossover)
+
+	var topology sandbox.Topology
+	switch strings.ToLower(*topologyName) {
+	case "ring":
+		topology = sandbox.TopologyRing
+	case "random":
+		topology = sandbox.TopologyRandom
 	default:
-		mode = sandbox.CrossoverGrowth
+		fmt.Fprintf(os.Stderr, "unknown -topology %q (want ring or random)\n", *topologyName)
+		exit(1)
+	}
+
+	selection, err := parseSelection(*selectionName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-selection: %v\n", err)
+		exit(1)
+	}
+	renderColoring, err := parseColoring(*renderColor)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-render-color: %v\n", err)
+		exit(1)
 	}
 
 	tlEvery := *timelineEvery
@@ -870,16 +1461,32 @@ func main() {
 		evolveEvery:   *evolveEvery,
 		seed:          *seed,
 		traderFrac:    *traderFrac,
+		roles:         roles,
 		verbose:       *verbose,
 		snapEvery:     *snapEvery,
 		tlEvery:       tlEvery,
 		crossoverMode: mode,
+		selection:     selection,
+		tournamentSize: *tournamentSize,
+		diversityTarget: *diversityTarget,
+		elitism:       *elitism,
+		speciesThreshold: *speciesThreshold,
+		clonePenalty:     *clonePenalty,
+		cloneDistance:    *cloneDistance,
+		lengthPenalty:    *lengthPenalty,
+		shorterFirst:     *shorterFirst,
+		structural:       *structural,
 		classicRate:   *classicRate,
 		biomes:        *biomes,
 		wfcGenome:     *wfcGenome,
 		maxGenome:     *maxGenome,
 		record:        *record,
 		recordEvery:   *recordEvery,
+		renderEvery:   *renderEvery,
+		renderDir:     *renderDir,
+		renderScale:   *renderScale,
+		renderColor:   renderColoring,
+		timelapse:     *timelapse,
 		inject:          *inject,
 		injectCount:     *injectCount,
 		injectAt:        *injectAt,
@@ -887,25 +1494,141 @@ func main() {
 		genomeGrowEvery: *genomeGrowEvery,
 		gasGrowDelta:    *gasGrowDelta,
 		gasGrowEvery:    *gasGrowEvery,
+		recipeMemes:     *recipeMemes,
+		shootRange:      *shootRange,
+		giftFitness:     *giftFitness,
+		fitness:         fitness,
+		actions:         actions,
+		gasCosts:        gasCosts,
+		curriculum:      curriculum,
+		gradients:       gradients,
+		sensorNoise:     sensorNoise,
+		assertions:      assertions,
+		clanShare:       *clanShare,
+		reproduction:    strings.ToLower(*reproduction),
+		contagion:       *contagion,
+		visionCone:      *visionCone,
+		selfModify:      *selfModify,
+		biographies:     *biographies,
+		player:          *playerMode,
+		brainAddr:       *brainAddr,
+		brainCount:      *brainCount,
+		workers:         *workers,
+		sensorFields:    *sensorFields,
+		control:         *control,
+		tui:             *tuiMode,
+		serve:           *serve,
+		serveToken:      *serveToken,
+		controlSocket:   *controlSocket,
+		speed:           *speed,
+		progress:        *progress,
+		lineage:         *lineage,
+		lineageAll:      *lineageAll,
+		eventLog:        *eventLog,
+		traceNPC:        *traceNPC,
+		traceFile:       *traceFile,
+		sqlite:          *sqlitePath,
+		reportJSON:      *reportJSON,
+		exportBest:      *exportBestN,
+		exportDir:       *exportDir,
+		checkpointEvery: *checkpointEvery,
+		checkpointFile:  *checkpointFile,
+		resumeFrom:      *resumeFrom,
+		seeds:           seeds,
+		predators:       *predators,
+		markets:         *markets,
+		posts:           *posts,
+		schools:         *schools,
+		contractEvery:   *contractEvery,
+		economy: sandbox.Economy{
+			TaxRate:      *taxRate,
+			Redistribute: *taxRedistribute,
+			Faucet:       *goldFaucet,
+			WealthCap:    *wealthCap,
+		},
+		islands:         *islands,
+		migrateEvery:    *migrateEvery,
+		migrants:        *migrants,
+		topology:        topology,
+	}
+
+	if sweepMode {
+		if err := runSweep(cfg, *sweepSpec, *sweepSamples, *sweepSeeds, *sweepJobs, *sweepRank, *sweepOut); err != nil {
+			fmt.Fprintf(os.Stderr, "sweep: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if experimentMode {
+		if err := runExperiment(cfg, *experimentSeeds, *parallel, *experimentOut); err != nil {
+			fmt.Fprintf(os.Stderr, "experiment: %v\n", err)
+			exit(1)
+		}
+		return
+	}
+
+	if *verifyDet {
+		if !verifyDeterminism(cfg) {
+			exit(1)
+		}
+		return
+	}
+
+	if cfg.islands > 1 {
+		runIslands(cfg)
+		return
+	}
+
+	if abMode {
+		// A/B mode: two scenarios over the same seeds; -ab and
+		// -ab-structural are preset pairs
+		a, b := abScenario{"A", *abA}, abScenario{"B", *abB}
+		switch {
+		case *ab:
+			a, b = abScenario{"Growth", "crossover=growth"}, abScenario{"Classic", "crossover=classic"}
+		case *abStructural:
+			a, b = abScenario{"Naive", "structural=false"}, abScenario{"Structural", "structural=true"}
+		}
+		if err := runAB(cfg, a, b, *abSeeds, *parallel); err != nil {
+			fmt.Fprintf(os.Stderr, "ab: %v\n", err)
+			exit(1)
+		}
+	} else {
+		if runFullSimulation(cfg, *csvOut) > 0 {
+			exit(assertFailExit)
+		}
 	}
+}
 
-	if *ab {
-		// A/B mode: run both, suppress snapshots/verbose, print comparison
-		abCfg := cfg
-		abCfg.verbose = false
-		abCfg.snapEvery = 0
-
-		abCfg.crossoverMode = sandbox.CrossoverGrowth
-		fmt.Fprintf(os.Stderr, "Running growth mode...\n")
-		growthResult := runSimulation(abCfg)
-
-		abCfg.crossoverMode = sandbox.CrossoverClassic
-		fmt.Fprintf(os.Stderr, "Running classic mode...\n")
-		classicResult := runSimulation(abCfg)
+// parseCrossover maps a -crossover name to its mode; unknown names mean
+// growth.
+func parseCrossover(name string) sandbox.CrossoverMode {
+	switch strings.ToLower(name) {
+	case "classic":
+		return sandbox.CrossoverClassic
+	case "twopoint", "two-point":
+		return sandbox.CrossoverTwoPoint
+	case "uniform":
+		return sandbox.CrossoverUniform
+	default:
+		return sandbox.CrossoverGrowth
+	}
+}
 
-		printABComparison(cfg, growthResult, classicResult)
-	} else {
-		runFullSimulation(cfg, *csvOut)
+// parseSelection maps a -selection name to its mode.
+func parseSelection(name string) (sandbox.SelectionMode, error) {
+	switch strings.ToLower(name) {
+	case "tournament":
+		return sandbox.SelectTournament, nil
+	case "roulette":
+		return sandbox.SelectRoulette, nil
+	case "rank":
+		return sandbox.SelectRank, nil
+	case "truncation":
+		return sandbox.SelectTruncation, nil
+	default:
+		return 0, fmt.Errorf("unknown selection %q (want tournament, roulette, rank or truncation)", name)
 	}
 }
 
@@ -932,8 +1655,10 @@ func printStatus(w *sandbox.World, sched *sandbox.Scheduler, tick int) {
 	if alive > 0 {
 		avgFit = totalFit / alive
 	}
-	fmt.Fprintf(os.Stderr, "tick=%d alive=%d food=%d items=%d trades=%d teaches=%d gold=%d holders=%d avg_fit=%d best_fit=%d\n",
-		tick, alive, w.FoodCount(), w.ItemCount(), sched.TradeCount, sched.TeachCount, totalGold, holders, avgFit, bestFit)
+	status.event("status", tick, logFields{"alive": alive, "food": w.FoodCount(), "items": w.ItemCount(), "trades": sched.TradeCount,
+		"teaches": sched.TeachCount, "gold": totalGold, "holders": holders, "avg_fit": avgFit, "best_fit": bestFit},
+		fmt.Sprintf("tick=%d alive=%d food=%d items=%d trades=%d teaches=%d gold=%d holders=%d avg_fit=%d best_fit=%d",
+			tick, alive, w.FoodCount(), w.ItemCount(), sched.TradeCount, sched.TeachCount, totalGold, holders, avgFit, bestFit))
 }
 
 func printSnapshot(w *sandbox.World, sched *sandbox.Scheduler, tick int) {
@@ -950,13 +1675,13 @@ func printSnapshot(w *sandbox.World, sched *sandbox.Scheduler, tick int) {
 	fmt.Fprintf(os.Stderr, "%-6s %-5s %-5s %-6s %-6s %-5s %-5s %-6s %-7s\n",
 		"ID", "X,Y", "HP", "Energy", "Item", "Gold", "Age", "Stress", "Fitness")
 	for _, npc := range alive {
-		itemNames := []string{"none", "food", "tool", "weapon", "treasure", "crystal", "shield", "compass"}
-		itemName := "?"
-		if int(npc.Item) < len(itemNames) {
-			itemName = itemNames[npc.Item]
-		}
 		fmt.Fprintf(os.Stderr, "%-6d %2d,%-2d %-5d %-6d %-6s %-5d %-5d %-6d %-7d\n",
-			npc.ID, npc.X, npc.Y, npc.Health, npc.Energy, itemName, npc.Gold, npc.Age, npc.Stress, npc.Fitness)
+			npc.ID, npc.X, npc.Y, npc.Health, npc.Energy, itemName(npc.Item), npc.Gold, npc.Age, npc.Stress, npc.Fitness)
+	}
+
+	for i, npc := range fittest(alive, topGenomes) {
+		fmt.Fprintf(os.Stderr, "\nGenome #%d: NPC %d fitness=%d (%d bytes)\n", i+1, npc.ID, npc.Fitness, len(npc.Genome))
+		printGenome(npc.Genome)
 	}
 
 	// Cluster analysis — skip at high population to avoid O(n^2)
@@ -1003,38 +1728,65 @@ func printSnapshot(w *sandbox.World, sched *sandbox.Scheduler, tick int) {
 				if occ != 0 {
 					// Find the NPC to check item
 					npc := w.NPCByID(occ)
+					glyph := "@" // NPC
 					if npc != nil && npc.Item != sandbox.ItemNone {
-						fmt.Fprint(os.Stderr, "T") // trader (has item)
-					} else {
-						fmt.Fprint(os.Stderr, "@") // NPC
+						glyph = "T" // trader (has item)
 					}
-				} else {
-					switch typ {
-					case sandbox.TileFood:
-						fmt.Fprint(os.Stderr, "f")
-					case sandbox.TileTool:
-						fmt.Fprint(os.Stderr, "t")
-					case sandbox.TileWeapon:
-						fmt.Fprint(os.Stderr, "w")
-					case sandbox.TileTreasure:
-						fmt.Fprint(os.Stderr, "$")
-					case sandbox.TileCrystal:
-						fmt.Fprint(os.Stderr, "*")
-					case sandbox.TileForge:
-						fmt.Fprint(os.Stderr, "F")
-					case sandbox.TilePoison:
-						fmt.Fprint(os.Stderr, "!")
-					default:
-						fmt.Fprint(os.Stderr, "·")
+					if npc != nil && npc.Clan != 0 {
+						glyph = clanColor(npc.Clan) + glyph + "\033[0m"
 					}
+					fmt.Fprint(os.Stderr, glyph)
+				} else {
+					fmt.Fprint(os.Stderr, tileGlyph(typ))
 				}
 			}
 			fmt.Fprintln(os.Stderr)
 		}
-		fmt.Fprintf(os.Stderr, "Legend: @=NPC T=NPC+item f=food t=tool w=weapon $=treasure *=crystal F=forge !=poison ·=empty\n")
+		fmt.Fprintf(os.Stderr, "Legend: @=NPC T=NPC+item f=food t=tool w=weapon $=treasure *=crystal F=forge !=poison #=wall h=shelter C=chest M=market P=post S=school ·=empty\n")
 	}
 }
 
+// tileGlyph returns the map character for a tile type.
+func tileGlyph(typ byte) string {
+	switch typ {
+	case sandbox.TileFood:
+		return "f"
+	case sandbox.TileTool:
+		return "t"
+	case sandbox.TileWeapon:
+		return "w"
+	case sandbox.TileTreasure:
+		return "$"
+	case sandbox.TileCrystal:
+		return "*"
+	case sandbox.TileForge:
+		return "F"
+	case sandbox.TilePoison:
+		return "!"
+	case sandbox.TileWall:
+		return "#"
+	case sandbox.TileShelter:
+		return "h"
+	case sandbox.TileChest:
+		return "C"
+	case sandbox.TileMarket:
+		return "M"
+	case sandbox.TilePost:
+		return "P"
+	case sandbox.TileSchool:
+		return "S"
+	}
+	return "·"
+}
+
+// clanPalette holds the ANSI 256-colour indexes clans are drawn in.
+var clanPalette = []int{39, 208, 129, 46, 201, 51, 172, 99, 118, 205, 33, 214}
+
+// clanColor returns an ANSI 256-colour foreground for a clan.
+func clanColor(clan byte) string {
+	return fmt.Sprintf("\033[38;5;%dm", clanPalette[int(clan)%len(clanPalette)])
+}
+
 // findClusters groups NPCs by Manhattan proximity using union-find.
 func findClusters(npcs []*sandbox.NPC, maxDist int) [][]*sandbox.NPC {
 	if len(npcs) == 0 {
@@ -1090,7 +1842,87 @@ func centroid(npcs []*sandbox.NPC) (int, int) {
 	return sx / len(npcs), sy / len(npcs)
 }
 
-func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, tick int) timePoint {
+// maxEpochRows caps the per-epoch death table; longer runs are merged into
+// equal spans of epochs.
+const maxEpochRows = 20
+
+// printDeaths reports causes of death overall and per epoch (from the
+// cumulative counts taken at each evolve tick), plus the longest lives when
+// biographies were kept.
+func printDeaths(cfg simConfig, sched *sandbox.Scheduler, epochDeaths [][sandbox.DeathCauses]int, lastTick int) {
+	total := 0
+	for _, n := range sched.Deaths {
+		total += n
+	}
+	if total == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "deaths=%d", total)
+	for cause, n := range sched.Deaths {
+		if n > 0 {
+			fmt.Fprintf(os.Stderr, " %s=%d", sandbox.DeathCauseNames[cause], n)
+		}
+	}
+	fmt.Fprintln(os.Stderr)
+
+	if n := len(epochDeaths); n > 1 {
+		span := (n + maxEpochRows - 1) / maxEpochRows
+		fmt.Fprintf(os.Stderr, "  %-15s %6s %6s %6s %6s\n", "ticks", "starve", "combat", "poison", "age")
+		var prev [sandbox.DeathCauses]int
+		for start := 0; start < n; start += span {
+			end := min(start+span, n) - 1
+			cur := epochDeaths[end]
+			to := min((end+1)*cfg.evolveEvery, lastTick)
+			fmt.Fprintf(os.Stderr, "  %-15s %6d %6d %6d %6d\n",
+				fmt.Sprintf("%d-%d", start*cfg.evolveEvery, to),
+				cur[sandbox.DeathStarvation]-prev[sandbox.DeathStarvation],
+				cur[sandbox.DeathCombat]-prev[sandbox.DeathCombat],
+				cur[sandbox.DeathPoison]-prev[sandbox.DeathPoison],
+				cur[sandbox.DeathAge]-prev[sandbox.DeathAge])
+			prev = cur
+		}
+	}
+
+	if cfg.biographies > 0 && len(sched.Graveyard) > 0 {
+		bios := append([]sandbox.Biography(nil), sched.Graveyard...)
+		sort.Slice(bios, func(i, j int) bool {
+			return bios[i].DeathTick-bios[i].BirthTick > bios[j].DeathTick-bios[j].BirthTick
+		})
+		fmt.Fprintf(os.Stderr, "=== Longest lives ===\n")
+		for _, b := range bios[:min(cfg.biographies, len(bios))] {
+			fmt.Fprintf(os.Stderr, "  #%-5d born=%-6d died=%-6d age=%-5d parents=%d,%d trades=%-4d kills=%-3d items=%s fit=%d cause=%s\n",
+				b.ID, b.BirthTick, b.DeathTick, b.DeathTick-b.BirthTick, b.Parents[0], b.Parents[1],
+				b.Trades, b.Kills, heldItemNames(b.ItemsHeld), b.Fitness, sandbox.DeathCauseNames[b.Cause])
+		}
+	}
+}
+
+// itemNames maps an item type to its report label.
+var itemNames = []string{"none", "food", "tool", "weapon", "treasure", "crystal", "shield", "compass", "charm", "remedy"}
+
+// itemName returns the label of an item type.
+func itemName(item byte) string {
+	if int(item) < len(itemNames) {
+		return itemNames[item]
+	}
+	return "?"
+}
+
+// heldItemNames lists the item types set in an ItemsHeld mask.
+func heldItemNames(mask uint16) string {
+	var held []string
+	for item := 1; item < len(itemNames); item++ {
+		if mask&(1<<item) != 0 {
+			held = append(held, itemNames[item])
+		}
+	}
+	if len(held) == 0 {
+		return "-"
+	}
+	return strings.Join(held, ",")
+}
+
+func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, ga *sandbox.GA, tick int) timePoint {
 	tp := timePoint{
 		tick:      tick,
 		trades:    sched.TradeCount,
@@ -1102,6 +1934,7 @@ func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, tick int) timePoint
 	totalFit := 0
 	totalStress := 0
 	totalGenome := 0
+	var lengths []int
 	for _, npc := range w.NPCs {
 		if !npc.Alive() {
 			continue
@@ -1112,6 +1945,7 @@ func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, tick int) timePoint
 		totalStress += npc.Stress
 		gl := len(npc.Genome)
 		totalGenome += gl
+		lengths = append(lengths, gl)
 		if gl < tp.genomeMin {
 			tp.genomeMin = gl
 		}
@@ -1124,7 +1958,7 @@ func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, tick int) timePoint
 		if npc.Item != sandbox.ItemNone {
 			tp.holders++
 		}
-		if npc.Item == sandbox.ItemShield || npc.Item == sandbox.ItemCompass {
+		if npc.Item == sandbox.ItemShield || npc.Item == sandbox.ItemCompass || npc.Item == sandbox.ItemCharm || npc.Item == sandbox.ItemRemedy {
 			tp.crafted++
 		}
 		if npc.ModSum(sandbox.ModGas) > 0 {
@@ -1135,6 +1969,9 @@ func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, tick int) timePoint
 		tp.avgFit = totalFit / tp.alive
 		tp.avgStress = totalStress / tp.alive
 		tp.genomeAvg = totalGenome / tp.alive
+		sort.Ints(lengths)
+		tp.genomeP50 = lengths[len(lengths)/2]
+		tp.genomeP90 = lengths[len(lengths)*9/10]
 	}
 	if tp.genomeMin == math.MaxInt {
 		tp.genomeMin = 0
@@ -1144,25 +1981,46 @@ func sampleStats(w *sandbox.World, sched *sandbox.Scheduler, tick int) timePoint
 	tp.heals = sched.HealCount
 	tp.harvests = sched.HarvestCount
 	tp.terraforms = sched.TerraformCount
+	tp.deaths = sched.Deaths
+	tp.diversity = int(sandbox.GenomeDiversity(w.NPCs)*100 + 0.5)
+	tp.mutation = int(ga.EffectiveMutation()*100 + 0.5)
+	census := sandbox.CensusGenomes(w.NPCs)
+	tp.unique = census.Unique
+	tp.shannon = int(census.Shannon*100 + 0.5)
+	tp.simpson = int(census.Simpson*100 + 0.5)
+	tp.gini = int(sandbox.GoldGini(w.NPCs)*100 + 0.5)
+	tp.demo = sandbox.CensusDemographics(w.NPCs)
+	tp.preyKills = sched.PreyKills
+	predFit, preyFit := 0, 0
+	for _, npc := range w.NPCs {
+		if !npc.Alive() {
+			continue
+		}
+		if npc.Predator {
+			tp.predators++
+			predFit += npc.Fitness
+		} else {
+			preyFit += npc.Fitness
+		}
+	}
+	tp.predatorFit = predFit / max(tp.predators, 1)
+	tp.preyFit = preyFit / max(tp.alive-tp.predators, 1)
 	return tp
 }
 
 func sparkline(label string, values []int) string {
-	blocks := []rune("▁▂▃▄▅▆▇█")
+	lo, hi := seriesRange(values)
+	return sparklineScaled(label, values, lo, hi)
+}
+
+// sparklineScaled is sparkline drawn from lo to hi, so several lines can
+// share a scale.
+func sparklineScaled(label string, values []int, lo, hi int) string {
 	n := len(values)
 	if n == 0 {
 		return ""
 	}
-
-	lo, hi := values[0], values[0]
-	for _, v := range values {
-		if v < lo {
-			lo = v
-		}
-		if v > hi {
-			hi = v
-		}
-	}
+	blocks := []rune("▁▂▃▄▅▆▇█")
 
 	var sb strings.Builder
 	fmt.Fprintf(&sb, "%-11s [%d→%d]\t", label, values[0], values[n-1])
@@ -1178,6 +2036,23 @@ func sparkline(label string, values []int) string {
 	return sb.String()
 }
 
+// seriesRange returns the lowest and highest value across every series.
+func seriesRange(series ...[]int) (lo, hi int) {
+	first := true
+	for _, s := range series {
+		for _, v := range s {
+			if first || v < lo {
+				lo = v
+			}
+			if first || v > hi {
+				hi = v
+			}
+			first = false
+		}
+	}
+	return lo, hi
+}
+
 func deltas(values []int) []int {
 	if len(values) < 2 {
 		return nil
@@ -1214,6 +2089,7 @@ func printTimeline(timeline []timePoint, interval int) {
 		{"trades", func(tp timePoint) int { return tp.trades }, true},
 		{"teaches", func(tp timePoint) int { return tp.teaches }, true},
 		{"gold", func(tp timePoint) int { return tp.gold }, false},
+		{"goldGini", func(tp timePoint) int { return tp.gini }, false},
 		{"stress", func(tp timePoint) int { return tp.avgStress }, false},
 		{"food", func(tp timePoint) int { return tp.food }, false},
 		{"items", func(tp timePoint) int { return tp.items }, false},
@@ -1225,12 +2101,29 @@ func printTimeline(timeline []timePoint, interval int) {
 		{"genomeMin", func(tp timePoint) int { return tp.genomeMin }, false},
 		{"genomeMax", func(tp timePoint) int { return tp.genomeMax }, false},
 		{"genomeAvg", func(tp timePoint) int { return tp.genomeAvg }, false},
+		{"genomeP50", func(tp timePoint) int { return tp.genomeP50 }, false},
+		{"genomeP90", func(tp timePoint) int { return tp.genomeP90 }, false},
+		{"diversity", func(tp timePoint) int { return tp.diversity }, false},
+		{"mutation%", func(tp timePoint) int { return tp.mutation }, false},
+		{"unique", func(tp timePoint) int { return tp.unique }, false},
+		{"simpson", func(tp timePoint) int { return tp.simpson }, false},
 		{"attacks", func(tp timePoint) int { return tp.attacks }, true},
 		{"kills", func(tp timePoint) int { return tp.kills }, false},
 		{"heals", func(tp timePoint) int { return tp.heals }, false},
 		{"harvests", func(tp timePoint) int { return tp.harvests }, true},
 		{"terraforms", func(tp timePoint) int { return tp.terraforms }, false},
 	}
+	for _, tp := range timeline {
+		if tp.predators > 0 {
+			metrics = append(metrics,
+				metric{"predators", func(tp timePoint) int { return tp.predators }, false},
+				metric{"predatorFit", func(tp timePoint) int { return tp.predatorFit }, false},
+				metric{"preyFit", func(tp timePoint) int { return tp.preyFit }, false},
+				metric{"preyKills", func(tp timePoint) int { return tp.preyKills }, true},
+			)
+			break
+		}
+	}
 
 	for _, m := range metrics {
 		vals := extractField(timeline, m.fn)
@@ -1242,34 +2135,84 @@ func printTimeline(timeline []timePoint, interval int) {
 			}
 		}
 	}
+
+	// Demographics: each group (ages, genome lengths, behaviours) shares a
+	// scale, so the bands can be compared line by line
+	var demo sandbox.Demographics
+	labels := demo.Labels()
+	groups := []struct {
+		name string
+		n    int
+	}{{"age pyramid", len(demo.Ages)}, {"genome lengths", len(demo.Genomes)}, {"roles", len(demo.Behaviors)}}
+	series := make([][]int, len(labels))
+	for i := range series {
+		series[i] = extractField(timeline, func(tp timePoint) int { return tp.demo.Values()[i] })
+	}
+	first := 0
+	for _, g := range groups {
+		group := series[first : first+g.n]
+		lo, hi := seriesRange(group...)
+		fmt.Fprintf(os.Stderr, "-- %s --\n", g.name)
+		for i, vals := range group {
+			fmt.Fprintln(os.Stderr, sparklineScaled(labels[first+i], vals, lo, hi))
+		}
+		first += g.n
+	}
+}
+
+// timelineColumns names the timeline's values, in timePoint.values order:
+// the -csv header and the -sqlite timeline columns.
+var timelineColumns = append([]string{
+	"tick", "alive", "trades", "teaches", "gold", "avg_stress",
+	"food", "items", "avg_fit", "best_fit", "holders", "crafted", "crystal_npcs",
+	"genome_min", "genome_max", "genome_avg",
+	"deaths_unknown", "deaths_starvation", "deaths_combat", "deaths_poison", "deaths_age",
+	"diversity", "mutation_rate", "unique_genomes", "shannon", "simpson",
+	"genome_p50", "genome_p90",
+	"predators", "predator_fit", "prey_fit", "prey_kills",
+	"gold_gini",
+}, demographicColumns()...)
+
+// demographicColumns names the Demographics bands as columns, like
+// "age_lt_1000", "genome128_plus" and "role_trader".
+func demographicColumns() []string {
+	var demo sandbox.Demographics
+	labels := demo.Labels()
+	roles := len(labels) - len(demo.Behaviors)
+	columns := make([]string, len(labels))
+	for i, label := range labels {
+		if i >= roles {
+			label = "role_" + label
+		}
+		columns[i] = strings.NewReplacer("<", "_lt_", "+", "_plus").Replace(label)
+	}
+	return columns
+}
+
+// values lists tp in timelineColumns order.
+func (tp timePoint) values() []int {
+	return append([]int{
+		tp.tick, tp.alive, tp.trades, tp.teaches, tp.gold, tp.avgStress,
+		tp.food, tp.items, tp.avgFit, tp.bestFit, tp.holders, tp.crafted, tp.crystalNPCs,
+		tp.genomeMin, tp.genomeMax, tp.genomeAvg,
+		tp.deaths[sandbox.DeathUnknown], tp.deaths[sandbox.DeathStarvation], tp.deaths[sandbox.DeathCombat],
+		tp.deaths[sandbox.DeathPoison], tp.deaths[sandbox.DeathAge],
+		tp.diversity, tp.mutation, tp.unique, tp.shannon, tp.simpson,
+		tp.genomeP50, tp.genomeP90,
+		tp.predators, tp.predatorFit, tp.preyFit, tp.preyKills,
+		tp.gini,
+	}, tp.demo.Values()...)
 }
 
 func printCSV(timeline []timePoint, w io.Writer) {
 	cw := csv.NewWriter(w)
-	cw.Write([]string{
-		"tick", "alive", "trades", "teaches", "gold", "avg_stress",
-		"food", "items", "avg_fit", "best_fit", "holders", "crafted", "crystal_npcs",
-		"genome_min", "genome_max", "genome_avg",
-	})
+	cw.Write(timelineColumns)
 	for _, tp := range timeline {
-		cw.Write([]string{
-			strconv.Itoa(tp.tick),
-			strconv.Itoa(tp.alive),
-			strconv.Itoa(tp.trades),
-			strconv.Itoa(tp.teaches),
-			strconv.Itoa(tp.gold),
-			strconv.Itoa(tp.avgStress),
-			strconv.Itoa(tp.food),
-			strconv.Itoa(tp.items),
-			strconv.Itoa(tp.avgFit),
-			strconv.Itoa(tp.bestFit),
-			strconv.Itoa(tp.holders),
-			strconv.Itoa(tp.crafted),
-			strconv.Itoa(tp.crystalNPCs),
-			strconv.Itoa(tp.genomeMin),
-			strconv.Itoa(tp.genomeMax),
-			strconv.Itoa(tp.genomeAvg),
-		})
+		row := make([]string, 0, len(timelineColumns))
+		for _, v := range tp.values() {
+			row = append(row, strconv.Itoa(v))
+		}
+		cw.Write(row)
 	}
 	cw.Flush()
 }
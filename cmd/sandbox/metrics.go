@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// simMetrics is what /metrics reports, refreshed after every tick.
+type simMetrics struct {
+	tick, epoch, alive, predators int
+	avgFit, bestFit               int
+	trades, teaches, kills        int
+	births                        int
+	deaths                        [sandbox.DeathCauses]int
+	gas                           int64
+	ticks                         int64   // ticks measured
+	tickSeconds                   float64 // their total duration
+	lastTick                      float64 // duration of the latest
+}
+
+// startTick and endTick are tick hooks timing each tick and refreshing
+// the metrics at its end.
+func (d *dashboard) startTick(s *sandbox.Scheduler) {
+	d.tickStart = time.Now()
+}
+
+func (d *dashboard) endTick(s *sandbox.Scheduler) {
+	took := time.Since(d.tickStart).Seconds()
+	m := simMetrics{tick: s.World.Tick, epoch: s.Epoch, trades: s.TradeCount, teaches: s.TeachCount,
+		kills: s.KillCount, births: s.BirthCount, deaths: s.Deaths, gas: s.GasUsed, lastTick: took}
+	total := 0
+	for _, npc := range s.World.NPCs {
+		if !npc.Alive() {
+			continue
+		}
+		m.alive++
+		total += npc.Fitness
+		m.bestFit = max(m.bestFit, npc.Fitness)
+		if npc.Predator {
+			m.predators++
+		}
+	}
+	m.avgFit = total / max(m.alive, 1)
+
+	d.mu.Lock()
+	m.ticks, m.tickSeconds = d.metrics.ticks+1, d.metrics.tickSeconds+took
+	d.metrics = m
+	d.mu.Unlock()
+}
+
+// handleMetrics serves the metrics in the Prometheus text format. Rates
+// (trades per second and so on) are left to rate() over the counters.
+func (d *dashboard) handleMetrics(rw http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	m := d.metrics
+	d.mu.Unlock()
+
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	metric := func(name, typ, help string, value any) {
+		fmt.Fprintf(rw, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, typ, name, value)
+	}
+	metric("psil_tick", "gauge", "Current simulation tick.", m.tick)
+	metric("psil_epoch", "gauge", "GA generations run.", m.epoch)
+	metric("psil_alive", "gauge", "Living NPCs.", m.alive)
+	metric("psil_predators", "gauge", "Living predators.", m.predators)
+	metric("psil_fitness_avg", "gauge", "Average fitness of the living.", m.avgFit)
+	metric("psil_fitness_best", "gauge", "Best fitness among the living.", m.bestFit)
+	metric("psil_trades_total", "counter", "Bilateral trades completed.", m.trades)
+	metric("psil_teaches_total", "counter", "Successful teach events.", m.teaches)
+	metric("psil_kills_total", "counter", "NPCs killed by attacks.", m.kills)
+	metric("psil_births_total", "counter", "Children born by in-world mating.", m.births)
+	metric("psil_gas_used_total", "counter", "VM gas spent running genomes.", m.gas)
+	metric("psil_ticks_total", "counter", "Ticks run since the dashboard started.", m.ticks)
+	metric("psil_tick_seconds_total", "counter", "Time spent running those ticks.", m.tickSeconds)
+	metric("psil_tick_duration_seconds", "gauge", "Duration of the latest tick.", m.lastTick)
+	writeLabelled(rw, "psil_deaths_total", "counter", "NPC deaths by cause.", "cause", sandbox.DeathCauseNames[:], m.deaths[:])
+}
+
+// writeLabelled writes a metric with one sample per label value.
+func writeLabelled(out io.Writer, name, typ, help, label string, values []string, counts []int) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	for i, v := range values {
+		fmt.Fprintf(out, "%s{%s=%q} %d\n", name, label, v, counts[i])
+	}
+}
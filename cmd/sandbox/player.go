@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// playerView is the radius of the local map shown to a human player.
+const playerView = 7
+
+// playerKeys maps a command key to the Ring1 action it issues.
+var playerKeys = map[byte]int{
+	'e': sandbox.ActionEat,
+	'f': sandbox.ActionAttack,
+	't': sandbox.ActionTrade,
+	'c': sandbox.ActionCraft,
+	'h': sandbox.ActionHeal,
+	'x': sandbox.ActionHarvest,
+	'g': sandbox.ActionGive,
+	'b': sandbox.ActionBuild, // wall
+	'j': sandbox.ActionJoin,
+	'z': sandbox.ActionSleep,
+	'm': sandbox.ActionBuy, // a meal
+	'v': sandbox.ActionSell,
+	'p': sandbox.ActionPost, // for anything
+}
+
+// playerMoves maps WASD to move directions.
+var playerMoves = map[byte]int{
+	'w': sandbox.DirNorth,
+	'd': sandbox.DirEast,
+	's': sandbox.DirSouth,
+	'a': sandbox.DirWest,
+}
+
+// player is a sandbox.Controller that reads one command line per tick.
+type player struct {
+	w    *sandbox.World
+	in   *bufio.Reader
+	out  io.Writer
+	quit bool
+}
+
+func newPlayer(w *sandbox.World, in io.Reader, out io.Writer) *player {
+	return &player{w: w, in: bufio.NewReader(in), out: out}
+}
+
+// Decide renders the player's surroundings and reads the next command. A
+// line may combine one move key with one action key ("wf" = step north and
+// attack); an empty line waits. Actions target the nearest NPC.
+func (p *player) Decide(npc *sandbox.NPC, ring0 []int16) (move, action, target int) {
+	if p.quit {
+		return 0, 0, 0
+	}
+	p.render(npc)
+	fmt.Fprint(p.out, "> ")
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		p.quit = true
+		return 0, 0, 0
+	}
+	for _, k := range []byte(strings.ToLower(strings.TrimSpace(line))) {
+		if k == 'q' {
+			p.quit = true
+			return 0, 0, 0
+		}
+		if d, ok := playerMoves[k]; ok {
+			move = d
+		}
+		if a, ok := playerKeys[k]; ok {
+			action = a
+		}
+	}
+	if action != sandbox.ActionBuild && action != sandbox.ActionBuy && action != sandbox.ActionPost {
+		target = int(ring0[sandbox.Ring0NearID])
+	}
+	return move, action, target
+}
+
+// render draws the local map and the player's status.
+func (p *player) render(npc *sandbox.NPC) {
+	w := p.w
+	fmt.Fprintf(p.out, "\n--- tick %d  HP=%d energy=%d item=%s gold=%d stress=%d ---\n",
+		w.Tick, npc.Health, npc.Energy, heldItemNames(1<<npc.Item), npc.Gold, npc.Stress)
+	for y := npc.Y - playerView; y <= npc.Y+playerView; y++ {
+		var sb strings.Builder
+		for x := npc.X - playerView; x <= npc.X+playerView; x++ {
+			switch occ := w.OccAt(x, y); {
+			case !w.InBounds(x, y):
+				sb.WriteString(" ")
+			case occ == npc.ID:
+				sb.WriteString("\033[1;92m@\033[0m")
+			case occ != 0:
+				sb.WriteString("n")
+			default:
+				sb.WriteString(tileGlyph(w.TileAt(x, y).Type()))
+			}
+		}
+		fmt.Fprintln(p.out, sb.String())
+	}
+	fmt.Fprintln(p.out, "wasd=move e=eat f=attack t=trade c=craft h=heal x=harvest g=give b=wall j=join z=sleep m=buy meal v=sell p=post q=quit")
+}
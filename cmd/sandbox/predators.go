@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math/rand"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// spawnPredators adds n predators running the hunter brain at random
+// positions.
+func spawnPredators(w *sandbox.World, n int, rng *rand.Rand, ws int) {
+	for i := 0; i < n; i++ {
+		npc := sandbox.NewNPC(append([]byte(nil), hunterGenome...))
+		npc.Predator = true
+		npc.X = rng.Intn(ws)
+		npc.Y = rng.Intn(ws)
+		w.Spawn(npc)
+	}
+}
+
+// countPredators returns how many of npcs are predators.
+func countPredators(npcs []*sandbox.NPC) int {
+	n := 0
+	for _, npc := range npcs {
+		if npc.Predator {
+			n++
+		}
+	}
+	return n
+}
+
+// evolve runs one GA epoch over pop. With predators (predGA set) prey and
+// predators evolve apart, each under its own GA. Offspring are reborn in
+// place, so pop keeps its members and order.
+func evolve(sched *sandbox.Scheduler, ga, predGA *sandbox.GA, pop []*sandbox.NPC) {
+	if predGA == nil {
+		sched.Evolve(ga, pop)
+		return
+	}
+	var prey, predators []*sandbox.NPC
+	for _, npc := range pop {
+		if npc.Predator {
+			predators = append(predators, npc)
+		} else {
+			prey = append(prey, npc)
+		}
+	}
+	sched.Evolve(ga, prey)
+	sched.Evolve(predGA, predators)
+}
+
+// refillPredators tops the predators back up to half their starting
+// number.
+func refillPredators(w *sandbox.World, start int, rng *rand.Rand, ws int) {
+	if n := countPredators(w.NPCs); n < start/2 {
+		spawnPredators(w, start/2-n, rng, ws)
+	}
+}
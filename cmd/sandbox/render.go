@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// timelapseDelay is how long each timelapse frame shows, in 1/100 s.
+const timelapseDelay = 10
+
+// renderer draws the world every few ticks: to PNG files (-render-every)
+// and/or into an animated GIF (-timelapse).
+type renderer struct {
+	every    int
+	dir      string // PNG frames go here ("" = none)
+	gifPath  string // "" = no timelapse
+	scale    int
+	coloring sandbox.NPCColoring
+	frames   []*image.Paletted
+}
+
+// parseColoring maps a -render-color name to its NPC coloring.
+func parseColoring(name string) (sandbox.NPCColoring, error) {
+	switch name {
+	case "role":
+		return sandbox.ColorByRole, nil
+	case "fitness":
+		return sandbox.ColorByFitness, nil
+	}
+	return 0, fmt.Errorf("unknown colouring %q (want role or fitness)", name)
+}
+
+// newRenderer returns the renderer cfg asks for, or nil. Without
+// -render-every, a timelapse gets about 100 frames over the run.
+func newRenderer(cfg simConfig) (*renderer, error) {
+	if cfg.renderEvery <= 0 && cfg.timelapse == "" {
+		return nil, nil
+	}
+	r := &renderer{every: cfg.renderEvery, gifPath: cfg.timelapse, scale: cfg.renderScale, coloring: cfg.renderColor}
+	if r.every > 0 {
+		r.dir = cfg.renderDir
+		if err := os.MkdirAll(r.dir, 0o755); err != nil {
+			return nil, err
+		}
+	} else if r.every = cfg.ticks / 100; r.every < 1 {
+		r.every = 1
+	}
+	return r, nil
+}
+
+// frame renders tick if it is due. A PNG that cannot be written is
+// reported once and PNG output stops; the timelapse carries on.
+func (r *renderer) frame(w *sandbox.World, tick int) {
+	if tick%r.every != 0 {
+		return
+	}
+	img := sandbox.Render(w, r.scale, r.coloring)
+	if r.gifPath != "" {
+		r.frames = append(r.frames, img)
+	}
+	if r.dir == "" {
+		return
+	}
+	err := writePNG(filepath.Join(r.dir, fmt.Sprintf("frame-%06d.png", tick)), img)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "render: %v (no more PNG frames)\n", err)
+		r.dir = ""
+	}
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// finish writes the timelapse, if any.
+func (r *renderer) finish() error {
+	if r.gifPath == "" || len(r.frames) == 0 {
+		return nil
+	}
+	f, err := os.Create(r.gifPath)
+	if err != nil {
+		return err
+	}
+	if err := sandbox.EncodeTimelapse(f, r.frames, timelapseDelay); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	status.event("timelapse", -1, logFields{"frames": len(r.frames), "path": r.gifPath},
+		fmt.Sprintf("Timelapse: %d frames → %s", len(r.frames), r.gifPath))
+	return nil
+}
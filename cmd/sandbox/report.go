@@ -0,0 +1,236 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// finalReport is the -report-json file: the final stats printed to stderr,
+// plus the timeline, in a form scripts can read.
+type finalReport struct {
+	Tick        int              `json:"tick"`
+	Seed        int64            `json:"seed"`
+	Population  reportPopulation `json:"population"`
+	Counters    map[string]int   `json:"counters"`
+	Deaths      map[string]int   `json:"deaths"`
+	EpochDeaths []map[string]int `json:"epoch_deaths"` // deaths in each epoch, by cause
+	Items       map[string]int   `json:"items"`        // held items by type
+	Clans       []reportClan     `json:"clans"`
+	Gurus       []reportGuru     `json:"gurus"` // top teachers
+	Best        *reportBest      `json:"best"`  // nil if nobody scored
+	Top         []reportGenome   `json:"top_genomes"`
+	Timeline    []map[string]int `json:"timeline"`
+	Assertions  []reportCheck    `json:"assertions,omitempty"` // -assert verdicts
+}
+
+type reportPopulation struct {
+	Alive        int     `json:"alive"`
+	Predators    int     `json:"predators"`
+	AvgFitness   int     `json:"avg_fitness"`
+	AvgStress    int     `json:"avg_stress"`
+	Gold         int     `json:"gold"`
+	CrystalNPCs  int     `json:"crystal_npcs"`
+	CraftedItems int     `json:"crafted_items"`
+	FoodOnMap    int     `json:"food_on_map"`
+	ItemsOnMap   int     `json:"items_on_map"`
+	FoodSpawned  int     `json:"food_spawned"`
+	FoodRate     float64 `json:"food_rate"`
+}
+
+type reportClan struct {
+	ID         byte   `json:"id"`
+	Name       string `json:"name"`
+	Members    int    `json:"members"`
+	AvgFitness int    `json:"avg_fitness"`
+	Gold       int    `json:"gold"`
+	Items      int    `json:"items"`
+}
+
+type reportGuru struct {
+	ID         uint16 `json:"id"`
+	TeachCount int    `json:"teach_count"`
+	Age        int    `json:"age"`
+	Fitness    int    `json:"fitness"`
+}
+
+type reportGenome struct {
+	ID      uint16   `json:"id"`
+	Fitness int      `json:"fitness"`
+	Genome  string   `json:"genome"` // hex
+	Disasm  []string `json:"disasm"` // annotated, see sandbox.ExplainGenome
+}
+
+type reportCheck struct {
+	Text   string `json:"text"`
+	Passed bool   `json:"passed"`
+	Tick   int    `json:"tick"` // when it was decided
+	Got    int    `json:"got"`  // the metric then
+}
+
+type reportBest struct {
+	ID       uint16         `json:"id"`
+	Fitness  int            `json:"fitness"`
+	Terms    map[string]int `json:"fitness_terms"`
+	Age      int            `json:"age"`
+	Food     int            `json:"food"`
+	Gold     int            `json:"gold"`
+	Item     string         `json:"item"`
+	Stress   int            `json:"stress"`
+	GasBonus int            `json:"gas_bonus"`
+	Genome   string         `json:"genome"` // hex
+	Disasm   []string       `json:"disasm"`
+}
+
+// maxGurus is how many top teachers the report lists.
+const maxGurus = 5
+
+// buildReport collects the final report of a run.
+func buildReport(cfg simConfig, w *sandbox.World, sched *sandbox.Scheduler, epochDeaths [][sandbox.DeathCauses]int, timeline []timePoint, checks *sandbox.Assertions) finalReport {
+	r := finalReport{
+		Tick: w.Tick,
+		Seed: cfg.seed,
+		Population: reportPopulation{
+			FoodOnMap:   w.FoodCount(),
+			ItemsOnMap:  w.ItemCount(),
+			FoodSpawned: w.FoodSpawned,
+			FoodRate:    w.FoodRate,
+		},
+		Counters: map[string]int{
+			"trades": sched.TradeCount, "teaches": sched.TeachCount, "attacks": sched.AttackCount,
+			"kills": sched.KillCount, "heals": sched.HealCount, "harvests": sched.HarvestCount,
+			"terraforms": sched.TerraformCount, "builds": sched.BuildCount, "deposits": sched.DepositCount,
+			"raids": sched.RaidCount, "shots": sched.ShotCount, "shot_hits": sched.ShotHits,
+			"gifts": sched.GiftCount, "gold_gifted": sched.GoldGifted, "clan_joins": sched.ClanJoins,
+			"sleep_ticks": sched.SleepTicks, "births": sched.BirthCount, "care_energy": sched.CareEnergy,
+			"infections": sched.Infections, "cures": sched.Cures, "recipes_learned": sched.RecipesLearned,
+			"prey_kills": sched.PreyKills, "gas_used": int(sched.GasUsed), "epochs": sched.Epoch,
+			"market_buys": sched.MarketBuys, "market_sells": sched.MarketSells, "gold_spent": sched.GoldSpent,
+			"gold_taxed": sched.TaxCollected, "gold_minted": sched.GoldMinted, "gold_burned": sched.GoldBurned,
+			"contracts_done": sched.ContractsDone, "contract_gold": sched.ContractGold,
+			"post_offers": sched.PostOffers, "post_trades": sched.PostTrades, "school_lessons": sched.SchoolLessons(),
+		},
+		Deaths: deathsByCause(sched.Deaths),
+		Items:  make(map[string]int),
+		Clans:  []reportClan{},
+		Gurus:  []reportGuru{},
+	}
+
+	p := &r.Population
+	var best *sandbox.NPC
+	totalFit, totalStress := 0, 0
+	for _, npc := range w.NPCs {
+		if npc.Fitness > 0 && (best == nil || npc.Fitness > best.Fitness) {
+			best = npc
+		}
+		p.Alive++
+		totalFit += npc.Fitness
+		totalStress += npc.Stress
+		p.Gold += npc.Gold
+		if npc.Predator {
+			p.Predators++
+		}
+		if npc.ModSum(sandbox.ModGas) > 0 {
+			p.CrystalNPCs++
+		}
+		switch npc.Item {
+		case sandbox.ItemNone:
+		case sandbox.ItemShield, sandbox.ItemCompass, sandbox.ItemCharm, sandbox.ItemRemedy:
+			p.CraftedItems++
+			fallthrough
+		default:
+			r.Items[itemName(npc.Item)]++
+		}
+		r.Counters["crafts"] += npc.CraftCount
+		r.Counters["taught"] += npc.Taught
+		if npc.TeachCount > 0 {
+			r.Gurus = append(r.Gurus, reportGuru{npc.ID, npc.TeachCount, npc.Age, npc.Fitness})
+		}
+	}
+	p.AvgFitness = totalFit / max(p.Alive, 1)
+	p.AvgStress = totalStress / max(p.Alive, 1)
+
+	var prev [sandbox.DeathCauses]int
+	for _, cur := range epochDeaths {
+		var d [sandbox.DeathCauses]int
+		for cause := range d {
+			d[cause] = cur[cause] - prev[cause]
+		}
+		r.EpochDeaths = append(r.EpochDeaths, deathsByCause(d))
+		prev = cur
+	}
+	for _, c := range sandbox.ClanStats(w.NPCs) {
+		r.Clans = append(r.Clans, reportClan(c))
+	}
+	sort.SliceStable(r.Gurus, func(i, j int) bool { return r.Gurus[i].TeachCount > r.Gurus[j].TeachCount })
+	r.Gurus = r.Gurus[:min(len(r.Gurus), maxGurus)]
+
+	if best != nil {
+		b := bestStats(best, sched)
+		r.Best = &b
+	}
+
+	r.Top = []reportGenome{}
+	for _, npc := range fittest(w.NPCs, topGenomes) {
+		r.Top = append(r.Top, reportGenome{npc.ID, npc.Fitness, hex.EncodeToString(npc.Genome), explainLines(npc.Genome)})
+	}
+
+	for _, tp := range timeline {
+		sample := make(map[string]int, len(timelineColumns))
+		for i, v := range tp.values() {
+			sample[timelineColumns[i]] = v
+		}
+		r.Timeline = append(r.Timeline, sample)
+	}
+	if checks != nil {
+		for _, a := range checks.Checks {
+			r.Assertions = append(r.Assertions, reportCheck{a.Text, a.Passed, a.Tick, a.Got})
+		}
+	}
+	return r
+}
+
+// bestStats describes npc, with its fitness broken down into terms.
+func bestStats(npc *sandbox.NPC, sched *sandbox.Scheduler) reportBest {
+	b := reportBest{
+		ID: npc.ID, Fitness: npc.Fitness, Terms: make(map[string]int),
+		Age: npc.Age, Food: npc.FoodEaten, Gold: npc.Gold, Item: itemName(npc.Item),
+		Stress: npc.Stress, GasBonus: npc.ModSum(sandbox.ModGas),
+		Genome: hex.EncodeToString(npc.Genome),
+		Disasm: explainLines(npc.Genome),
+	}
+	for _, term := range sched.Fitness.Breakdown(npc) {
+		b.Terms[term.Name] = term.Value
+	}
+	if gifts := npc.GiftCount * sched.GiftFitness; gifts != 0 {
+		b.Terms["gifts"] = gifts
+	}
+	return b
+}
+
+// explainLines is genome's annotated disassembly, a line per instruction.
+func explainLines(genome []byte) []string {
+	return strings.Split(strings.TrimRight(sandbox.ExplainGenome(genome), "\n"), "\n")
+}
+
+// deathsByCause labels death counts with their causes.
+func deathsByCause(deaths [sandbox.DeathCauses]int) map[string]int {
+	m := make(map[string]int, len(deaths))
+	for cause, n := range deaths {
+		m[sandbox.DeathCauseNames[cause]] = n
+	}
+	return m
+}
+
+// writeReport writes r to path as indented JSON.
+func writeReport(path string, r finalReport) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
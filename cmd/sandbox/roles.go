@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// roleSpec is one entry of the initial role mix: a share of the role
+// population seeded with a role's genome.
+type roleSpec struct {
+	name   string
+	genome *[]byte // nil = a fresh random genome each
+	frac   float64
+	rest   bool // takes whatever the other roles leave
+	min    int  // at least this many, room permitting
+}
+
+// defaultRoles is the mix used without -roles: the -traders share of
+// traders, a quarter foragers, a tenth crafters, a twentieth teachers (at
+// least one) and random genomes for the rest.
+func defaultRoles(traderFrac float64) []roleSpec {
+	return []roleSpec{
+		{name: "trader", genome: &traderGenome, frac: traderFrac},
+		{name: "forager", genome: &foragerGenome, frac: 0.25},
+		{name: "crafter", genome: &crafterGenome, frac: 0.1},
+		{name: "teacher", genome: &teacherGenome, frac: 0.05, min: 1},
+		{name: "random", rest: true},
+	}
+}
+
+// loadRoles reads a role mix from spec, or from the file at path (one
+// entry per line, # comments), and nil when neither is given.
+func loadRoles(spec, path string) ([]roleSpec, error) {
+	if path != "" {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec = string(src)
+	}
+	return parseRoles(spec)
+}
+
+// parseRoles reads a role mix: comma- or newline-separated entries
+// "name:share[:min]", where share is a fraction of the role population or
+// "rest". name is a built-in brain (trader, forager, crafter, teacher,
+// farmer, fighter, healer, hunter) or random; "name=file:share" seeds the
+// role from a genome file instead (hex, or a .psil brain). Traders,
+// crafters and teachers get their usual starting items under any genome.
+// Without a rest entry the population left over is random.
+func parseRoles(spec string) ([]roleSpec, error) {
+	var mix []roleSpec
+	rest := false
+	for _, line := range strings.Split(spec, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		for _, entry := range strings.Split(line, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+			r, err := parseRole(entry)
+			if err != nil {
+				return nil, err
+			}
+			if r.rest {
+				if rest {
+					return nil, fmt.Errorf("roles: only one entry can take the rest")
+				}
+				rest = true
+			}
+			mix = append(mix, r)
+		}
+	}
+	if len(mix) > 0 && !rest {
+		mix = append(mix, roleSpec{name: "random", rest: true})
+	}
+	return mix, nil
+}
+
+func parseRole(entry string) (roleSpec, error) {
+	parts := strings.Split(entry, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return roleSpec{}, fmt.Errorf("roles: %q is not name:share[:min]", entry)
+	}
+	name, file, hasFile := strings.Cut(strings.TrimSpace(parts[0]), "=")
+	r := roleSpec{name: strings.ToLower(strings.TrimSpace(name))}
+	switch {
+	case hasFile:
+		genome, err := readGenomeFile(strings.TrimSpace(file))
+		if err != nil {
+			return roleSpec{}, fmt.Errorf("roles: %s: %v", r.name, err)
+		}
+		r.genome = &genome
+	case r.name == "random":
+	default:
+		g, ok := roleGenomes[r.name]
+		if !ok {
+			names := []string{"random"}
+			for n := range roleGenomes {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return roleSpec{}, fmt.Errorf("roles: unknown role %q (want one of %s, or name=file)", r.name, strings.Join(names, ", "))
+		}
+		r.genome = g
+	}
+
+	share := strings.TrimSpace(parts[1])
+	if strings.EqualFold(share, "rest") {
+		r.rest = true
+	} else {
+		f, err := strconv.ParseFloat(share, 64)
+		if err != nil || f < 0 || f > 1 {
+			return roleSpec{}, fmt.Errorf("roles: %s: bad share %q (want 0-1 or rest)", r.name, share)
+		}
+		r.frac = f
+	}
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+		if err != nil || n < 0 {
+			return roleSpec{}, fmt.Errorf("roles: %s: bad minimum %q", r.name, parts[2])
+		}
+		r.min = n
+	}
+	return r, nil
+}
+
+// roleCounts splits n NPCs over the mix. Each share is rounded down and
+// raised to its minimum, in order, until the NPCs run out; the rest entry
+// gets what is left.
+func roleCounts(mix []roleSpec, n int) []int {
+	counts := make([]int, len(mix))
+	left := n
+	for i, r := range mix {
+		if r.rest {
+			continue
+		}
+		counts[i] = min(max(int(float64(n)*r.frac), r.min), left)
+		left -= counts[i]
+	}
+	for i, r := range mix {
+		if r.rest {
+			counts[i] = left
+		}
+	}
+	return counts
+}
+
+// spawnRoles spawns n NPCs in the role mix at random positions, with each
+// role's starting items (and recipes, with recipe memes).
+func spawnRoles(w *sandbox.World, mix []roleSpec, n int, ga *sandbox.GA, recipeMemes bool, rng *rand.Rand, ws int) {
+	for i, count := range roleCounts(mix, n) {
+		r := mix[i]
+		for j := 0; j < count; j++ {
+			var genome []byte
+			if r.genome != nil {
+				genome = append([]byte(nil), *r.genome...)
+			} else {
+				genome = ga.RandomGenome(24 + rng.Intn(16))
+			}
+			npc := sandbox.NewNPC(genome)
+			npc.X = rng.Intn(ws)
+			npc.Y = rng.Intn(ws)
+			switch r.name {
+			case "trader", "teacher":
+				npc.Item = byte(sandbox.ItemTool + rng.Intn(3))
+			case "crafter":
+				npc.Item = sandbox.ItemTool
+			}
+			// Recipe memes: seeded crafters and teachers carry the initial recipe culture
+			if recipeMemes && (r.name == "crafter" || r.name == "teacher") {
+				npc.Recipes = sandbox.RecipeCompass | sandbox.RecipeShield | sandbox.RecipeRemedy
+			}
+			w.Spawn(npc)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// A scenario file (-scenario) sets flags, one per line, as "name value" or
+// "name=value", without the dash; a bool flag alone means true. Blank
+// lines and # comments are skipped. The list flags can also be written as
+// a [section] naming the flag, one entry per line:
+//
+//	npcs 60
+//	ticks 20000
+//
+//	[actions]
+//	attack.energy=15
+//	shoot.cooldown=3
+//
+//	[curriculum]
+//	at=2000,food=0.1
+//	fit=500,poison=4,night=96
+//
+//	[assert]
+//	trades >= 100 by tick 10000
+//	population never below 5
+//
+//	[sensor-noise]
+//	food-dist=gauss:2
+//	*=drop:0.02
+//
+// A flag given on the command line overrides the file's.
+
+// scenarioSetting is one flag a scenario file sets.
+type scenarioSetting struct {
+	name, value string
+	line        int // where it is set (a section's first entry)
+}
+
+// scenarioSections are the flags a scenario file may write as a section,
+// and what joins the section's lines into the flag's value.
+var scenarioSections = map[string]string{
+	"actions":      ",",
+	"curriculum":   ";", // one stage per line
+	"assert":       ";", // one check per line
+	"sensor-noise": ";", // one sensor per line
+}
+
+// readScenario reads the settings in the scenario file at path, in order.
+func readScenario(path string) ([]scenarioSetting, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var settings []scenarioSetting
+	section := -1 // index in settings of the open section's flag
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "["):
+			name, ok := strings.CutSuffix(line[1:], "]")
+			name = strings.TrimSpace(name)
+			if _, known := scenarioSections[name]; !ok || !known {
+				return nil, fmt.Errorf("%s:%d: unknown section %s", path, n, line)
+			}
+			settings = append(settings, scenarioSetting{name: name, line: n})
+			section = len(settings) - 1
+		case section >= 0:
+			s := &settings[section]
+			if s.value != "" {
+				s.value += scenarioSections[s.name]
+			}
+			s.value += line
+		default:
+			name, value, ok := strings.Cut(line, "=")
+			if i := strings.IndexAny(line, " \t"); i >= 0 && (!ok || i < len(name)) {
+				name, value = line[:i], line[i+1:]
+			}
+			settings = append(settings, scenarioSetting{strings.TrimSpace(name), strings.TrimSpace(value), n})
+		}
+	}
+	return settings, sc.Err()
+}
+
+// applyScenario sets the flags the scenario file at path sets, but for
+// those given on the command line. Call it after flag.Parse.
+func applyScenario(path string) error {
+	settings, err := readScenario(path)
+	if err != nil {
+		return err
+	}
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, s := range settings {
+		f := flag.Lookup(s.name)
+		if f == nil || s.name == "scenario" {
+			return fmt.Errorf("%s:%d: unknown flag %q", path, s.line, s.name)
+		}
+		if given[s.name] {
+			continue
+		}
+		value := s.value
+		if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() && value == "" {
+			value = "true"
+		}
+		if err := f.Value.Set(value); err != nil {
+			return fmt.Errorf("%s:%d: -%s: %v", path, s.line, s.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// seedGenome is one -genome-file or -genome-hex entry: count NPCs spawned
+// with genome (and item) before the role population.
+type seedGenome struct {
+	genome []byte
+	count  int
+	item   byte
+}
+
+// seedFlag collects repeatable seed genome flags. A spec is the genome
+// (a file path, or hex bytes) followed by optional ",count=N" and
+// ",item=NAME" settings.
+type seedFlag struct {
+	seeds *[]seedGenome
+	file  bool // the spec names a genome file rather than hex bytes
+}
+
+func (f seedFlag) String() string { return "" }
+
+func (f seedFlag) Set(spec string) error {
+	parts := strings.Split(spec, ",")
+	seed := seedGenome{count: 1}
+	var err error
+	if f.file {
+		seed.genome, err = readGenomeFile(parts[0])
+	} else {
+		seed.genome, err = hex.DecodeString(strings.TrimSpace(parts[0]))
+		if err == nil && len(seed.genome) == 0 {
+			err = fmt.Errorf("empty genome")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, kv := range parts[1:] {
+		key, val, ok := strings.Cut(strings.TrimSpace(kv), "=")
+		switch {
+		case ok && key == "count":
+			seed.count, err = strconv.Atoi(val)
+			if err != nil || seed.count < 1 {
+				return fmt.Errorf("bad count %q", val)
+			}
+		case ok && key == "item":
+			seed.item, err = parseItem(val)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown setting %q (want count=N or item=NAME)", kv)
+		}
+	}
+	*f.seeds = append(*f.seeds, seed)
+	return nil
+}
+
+// readGenomeFile loads a genome: a .psil file is compiled as a brain,
+// anything else holds hex bytes on its first non-empty line.
+func readGenomeFile(path string) ([]byte, error) {
+	if strings.EqualFold(filepath.Ext(path), ".psil") {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		genome, err := sandbox.CompileBrain(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		return genome, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		genome, err := hex.DecodeString(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad hex: %v", path, err)
+		}
+		return genome, nil
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no genome found in %s", path)
+}
+
+// parseItem maps an item name to its item type.
+func parseItem(name string) (byte, error) {
+	names := []string{"none", "food", "tool", "weapon", "treasure", "crystal", "shield", "compass", "charm", "remedy"}
+	for i, n := range names {
+		if strings.EqualFold(name, n) {
+			return byte(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown item %q (want one of %s)", name, strings.Join(names, ", "))
+}
+
+// spawnSeeds spawns every seed genome at random positions and returns how
+// many NPCs it added.
+func spawnSeeds(w *sandbox.World, seeds []seedGenome, rng *rand.Rand, ws int) int {
+	n := 0
+	for _, seed := range seeds {
+		for i := 0; i < seed.count; i++ {
+			npc := sandbox.NewNPC(append([]byte(nil), seed.genome...))
+			npc.X = rng.Intn(ws)
+			npc.Y = rng.Intn(ws)
+			npc.Item = seed.item
+			w.Spawn(npc)
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"os"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// loadSensorNoise reads the -sensor-noise rules, or -sensor-noise-file's
+// (one rule per line) when set. It returns nil for exact senses; each run
+// seeds its own copy.
+func loadSensorNoise(spec, path string) (*sandbox.SensorNoise, error) {
+	if path != "" {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		spec = string(src)
+	}
+	return sandbox.ParseSensorNoise(spec, 0)
+}
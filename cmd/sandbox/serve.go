@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/psilLang/psil/pkg/micro"
+	"github.com/psilLang/psil/pkg/sandbox"
+	"golang.org/x/net/websocket"
+)
+
+// dashboardFiles is the bundled canvas viewer served at /.
+//
+//go:embed web
+var dashboardFiles embed.FS
+
+const (
+	dashboardFPS    = 10  // frames streamed per second while running
+	dashboardEvents = 200 // events kept per frame; the rest are dropped
+)
+
+// dashNPC is one NPC as the dashboard sees it: the recorder's frame state,
+// plus what the inspector needs (not streamed).
+type dashNPC struct {
+	sandbox.RecordNPC
+	Predator bool `json:"pred,omitempty"`
+
+	genome []byte
+	mods   [4]sandbox.Modifier
+}
+
+// dashFrame is a message on the /ws stream. The first is "full", with the
+// whole grid; each later "diff" lists only the tiles that changed, as
+// index, tile pairs. NPCs are always sent whole.
+type dashFrame struct {
+	Type   string              `json:"type"` // "full" or "diff"
+	Tick   int                 `json:"t"`
+	Size   int                 `json:"size,omitempty"`
+	Grid   []byte              `json:"grid,omitempty"` // full: tile bytes, base64
+	Tiles  []int               `json:"tiles,omitempty"`
+	NPCs   []dashNPC           `json:"npcs"`
+	Stats  sandbox.RecordStats `json:"s"`
+	Events []string            `json:"events,omitempty"`
+	Paused bool                `json:"paused"`
+}
+
+// dashboard is the -serve web viewer. The tick loop publishes a snapshot
+// of the world from wait; HTTP handlers only ever read snapshots, so they
+// never race the simulation, and steer it through the TickControl.
+type dashboard struct {
+	w     *sandbox.World
+	sched *sandbox.Scheduler
+	size  int
+	token string // required by the requests that change the run
+	url   string // where to open the dashboard, token included
+
+	pending   []string // events since the last frame (tick loop only)
+	last      time.Time
+	tickStart time.Time
+
+	mu      sync.Mutex
+	tick    int
+	grid    []byte
+	npcs    []dashNPC
+	stats   sandbox.RecordStats
+	clients map[chan []byte]bool
+	metrics simMetrics
+}
+
+// serveDashboard starts the dashboard on addr, with Prometheus metrics at
+// /metrics. An address without a host (":8080") listens on 127.0.0.1
+// only. Anyone who can reach it may watch; pausing, stepping, changing
+// the speed and the console (which can rewrite NPCs) need token, and an
+// empty token is replaced by a random one, which the dashboard's url
+// carries.
+func serveDashboard(addr, token string, w *sandbox.World, sched *sandbox.Scheduler) (*dashboard, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	if token == "" {
+		var b [16]byte
+		rand.Read(b[:])
+		token = hex.EncodeToString(b[:])
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	d := &dashboard{w: w, sched: sched, size: w.Size, token: token, clients: make(map[chan []byte]bool)}
+	d.url = "http://" + ln.Addr().String() + "/?token=" + token
+	sched.Subscribe(sandbox.SubscriberFunc(d.onEvent))
+	sched.PreTick(d.startTick)
+	sched.PostTick(d.endTick)
+	d.publish()
+
+	static, _ := fs.Sub(dashboardFiles, "web")
+	mux := http.NewServeMux()
+	mux.Handle("GET /", http.FileServer(http.FS(static)))
+	mux.Handle("GET /ws", websocket.Handler(d.stream))
+	mux.HandleFunc("GET /api/state", d.handleState)
+	mux.HandleFunc("GET /api/npc/{id}", d.handleNPC)
+	mux.HandleFunc("GET /api/npc/{id}/genome", d.handleGenome)
+	mux.HandleFunc("POST /api/{cmd}", d.authorized(d.handleControl))
+	mux.HandleFunc("POST /api/console", d.authorized(d.handleConsole))
+	mux.HandleFunc("GET /metrics", d.handleMetrics)
+	go http.Serve(ln, mux)
+	return d, nil
+}
+
+// wrap returns a tick-loop wait that publishes a frame first: at most
+// dashboardFPS times a second while running, after every tick while
+// stepping.
+func (d *dashboard) wrap(wait func() bool) func() bool {
+	return func() bool {
+		if d.sched.Paused() || time.Since(d.last) >= time.Second/dashboardFPS {
+			d.publish()
+		}
+		return wait()
+	}
+}
+
+func (d *dashboard) onEvent(ev sandbox.Event) {
+	if line := describeEvent(ev); line != "" && len(d.pending) < dashboardEvents {
+		d.pending = append(d.pending, fmt.Sprintf("%6d %s", ev.EventTick(), line))
+	}
+}
+
+// publish snapshots the world and streams the diff to every client. A
+// client too slow to keep up is dropped; its page reconnects.
+func (d *dashboard) publish() {
+	grid := make([]byte, len(d.w.Grid))
+	for i, t := range d.w.Grid {
+		grid[i] = byte(t)
+	}
+	npcs := make([]dashNPC, 0, len(d.w.NPCs))
+	for _, npc := range d.w.NPCs {
+		if npc.Alive() {
+			npcs = append(npcs, dashNPC{
+				RecordNPC: sandbox.NewRecordNPC(npc),
+				Predator:  npc.Predator,
+				genome:    append([]byte(nil), npc.Genome...),
+				mods:      npc.Mods,
+			})
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	diff := dashFrame{Type: "diff", Tick: d.w.Tick, NPCs: npcs, Stats: sandbox.NewRecordStats(d.sched),
+		Events: d.pending, Paused: d.sched.Paused()}
+	for i := range grid {
+		if i < len(d.grid) && grid[i] != d.grid[i] {
+			diff.Tiles = append(diff.Tiles, i, int(grid[i]))
+		}
+	}
+	d.tick, d.grid, d.npcs, d.stats = diff.Tick, grid, npcs, diff.Stats
+	d.pending, d.last = nil, time.Now()
+
+	if len(d.clients) == 0 {
+		return
+	}
+	msg, _ := json.Marshal(diff)
+	for c := range d.clients {
+		select {
+		case c <- msg:
+		default:
+			delete(d.clients, c)
+			close(c)
+		}
+	}
+}
+
+// full returns the latest snapshot as a "full" frame. Called with mu held.
+func (d *dashboard) full() dashFrame {
+	return dashFrame{Type: "full", Tick: d.tick, Size: d.size, Grid: d.grid, NPCs: d.npcs,
+		Stats: d.stats, Paused: d.sched.Paused()}
+}
+
+// stream sends a client the full snapshot, then every diff.
+func (d *dashboard) stream(ws *websocket.Conn) {
+	defer ws.Close()
+	c := make(chan []byte, 16)
+	d.mu.Lock()
+	first, _ := json.Marshal(d.full())
+	d.clients[c] = true
+	d.mu.Unlock()
+	defer func() {
+		d.mu.Lock()
+		if d.clients[c] {
+			delete(d.clients, c)
+			close(c)
+		}
+		d.mu.Unlock()
+	}()
+
+	// The client never speaks; a failed read means it went away
+	gone := make(chan struct{})
+	go func() {
+		var discard string
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+		close(gone)
+	}()
+
+	if websocket.Message.Send(ws, string(first)) != nil {
+		return
+	}
+	for {
+		select {
+		case msg, ok := <-c:
+			if !ok || websocket.Message.Send(ws, string(msg)) != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+func (d *dashboard) handleState(rw http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	frame := d.full()
+	d.mu.Unlock()
+	writeJSON(rw, frame)
+}
+
+// lookup finds NPC {id} in the latest snapshot, or replies 404.
+func (d *dashboard) lookup(rw http.ResponseWriter, r *http.Request) (dashNPC, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, npc := range d.npcs {
+		if err == nil && int(npc.ID) == id {
+			return npc, true
+		}
+	}
+	http.Error(rw, "no living NPC "+r.PathValue("id"), http.StatusNotFound)
+	return dashNPC{}, false
+}
+
+// handleNPC describes one NPC for the inspector, genome disassembled.
+func (d *dashboard) handleNPC(rw http.ResponseWriter, r *http.Request) {
+	npc, ok := d.lookup(rw, r)
+	if !ok {
+		return
+	}
+	writeJSON(rw, struct {
+		dashNPC
+		Item   string   `json:"item"`
+		Mods   []string `json:"mods"`
+		Genome string   `json:"genome"` // hex
+		Disasm []string `json:"disasm"`
+	}{
+		dashNPC: npc,
+		Item:    itemName(npc.Item),
+		Mods:    modLabels(npc.mods),
+		Genome:  hex.EncodeToString(npc.genome),
+		Disasm:  strings.Split(strings.TrimRight(micro.Disassemble(npc.genome), "\n"), "\n"),
+	})
+}
+
+// handleGenome downloads an NPC's genome as a hex genome file, ready for
+// -genome-file or -inject.
+func (d *dashboard) handleGenome(rw http.ResponseWriter, r *http.Request) {
+	npc, ok := d.lookup(rw, r)
+	if !ok {
+		return
+	}
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="npc-%d.hex"`, npc.ID))
+	fmt.Fprintln(rw, hex.EncodeToString(npc.genome))
+}
+
+// authorized lets a request through to h only if it carries the
+// dashboard's token, in an X-Dashboard-Token header or a token parameter.
+func (d *dashboard) authorized(h http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		got := r.Header.Get("X-Dashboard-Token")
+		if got == "" {
+			got = r.URL.Query().Get("token")
+		}
+		if subtle.ConstantTimeCompare([]byte(got), []byte(d.token)) != 1 {
+			http.Error(rw, "missing or wrong dashboard token", http.StatusForbidden)
+			return
+		}
+		h(rw, r)
+	}
+}
+
+// handleControl serves POST /api/pause, resume, step?n=N and speed?tps=T.
+func (d *dashboard) handleControl(rw http.ResponseWriter, r *http.Request) {
+	arg := func(name string, def float64) float64 {
+		if v, err := strconv.ParseFloat(r.URL.Query().Get(name), 64); err == nil {
+			return v
+		}
+		return def
+	}
+	switch r.PathValue("cmd") {
+	case "pause":
+		// Stop after one more tick rather than at once, so the loop
+		// publishes the tick it stops on even when it was waiting out a
+		// speed cap
+		if !d.sched.Paused() {
+			d.sched.Step(1)
+		}
+	case "resume":
+		d.sched.Resume()
+	case "step":
+		d.sched.Step(max(int(arg("n", 1)), 1))
+	case "speed":
+		d.sched.SetSpeed(arg("tps", 0))
+	default:
+		http.NotFound(rw, r)
+		return
+	}
+	writeJSON(rw, map[string]bool{"paused": d.sched.Paused()})
+}
+
+// handleConsole runs the request body as a -control console command line
+// and replies with its output.
+func (d *dashboard) handleConsole(rw http.ResponseWriter, r *http.Request) {
+	line, err := io.ReadAll(http.MaxBytesReader(rw, r.Body, 4096))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	var out bytes.Buffer
+	c := console{w: d.w, sched: d.sched}
+	c.exec(string(line), &out)
+	rw.Header().Set("Content-Type", "text/plain")
+	rw.Write(out.Bytes())
+}
+
+func writeJSON(rw http.ResponseWriter, v any) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(v)
+}
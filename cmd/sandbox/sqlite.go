@@ -0,0 +1,260 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+	_ "modernc.org/sqlite"
+)
+
+// statsSchema is the -sqlite database. Every run appends to it under a new
+// runs.id, so runs can be compared with plain SQL.
+const statsSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id         INTEGER PRIMARY KEY,
+	started    TEXT,
+	args       TEXT,
+	seed       INTEGER,
+	npcs       INTEGER,
+	world_size INTEGER,
+	ticks      INTEGER,
+	ended_tick INTEGER
+);
+CREATE TABLE IF NOT EXISTS epochs (
+	run            INTEGER REFERENCES runs(id),
+	epoch          INTEGER,
+	tick           INTEGER,
+	alive          INTEGER,
+	avg_fit        INTEGER,
+	best_fit       INTEGER,
+	genome_avg     INTEGER,
+	diversity      INTEGER,
+	unique_genomes INTEGER,
+	trades         INTEGER,
+	teaches        INTEGER,
+	deaths         INTEGER,
+	PRIMARY KEY (run, epoch)
+);
+CREATE TABLE IF NOT EXISTS trades (
+	run  INTEGER REFERENCES runs(id),
+	tick INTEGER,
+	a    INTEGER,
+	b    INTEGER
+);
+CREATE TABLE IF NOT EXISTS genomes (
+	run     INTEGER REFERENCES runs(id),
+	kind    TEXT,
+	epoch   INTEGER,
+	tick    INTEGER,
+	npc     INTEGER,
+	fitness INTEGER,
+	length  INTEGER,
+	genome  BLOB
+);
+`
+
+// statsDB is the -sqlite sink: run metadata, timeline samples, one row per
+// epoch with its fittest genome ("best"), every trade, and the survivors'
+// genomes at the end ("final"). Rows are committed with each timeline
+// sample and by close, so a run never holds more than a sample interval in
+// one transaction. A run that dies halfway keeps what it had sampled, but
+// its runs row has no ended_tick.
+type statsDB struct {
+	db  *sql.DB
+	tx  *sql.Tx
+	run int64
+	err error // first failed insert
+
+	sample, epoch, trade, genome *sql.Stmt // prepared in tx
+	deaths                       int       // at the last epoch
+}
+
+// openStatsDB opens (creating if need be) the database at path and starts a
+// run in it on a size×size world.
+func openStatsDB(path string, cfg simConfig, size int) (*statsDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	s := &statsDB{db: db}
+	if err := s.init(cfg, size); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *statsDB) init(cfg simConfig, size int) error {
+	// The timeline follows the -csv columns, so it is built from them
+	cols := make([]string, len(timelineColumns))
+	for i, c := range timelineColumns {
+		cols[i] = c + " INTEGER"
+	}
+	schema := statsSchema + "CREATE TABLE IF NOT EXISTS timeline (run INTEGER REFERENCES runs(id), " +
+		strings.Join(cols, ", ") + ");"
+	if _, err := s.db.Exec(schema); err != nil {
+		return err
+	}
+	if err := s.migrate(); err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`INSERT INTO runs (started, args, seed, npcs, world_size, ticks) VALUES (?, ?, ?, ?, ?, ?)`,
+		time.Now().UTC().Format(time.RFC3339), strings.Join(os.Args[1:], " "), cfg.seed, cfg.npcs, size, cfg.ticks)
+	if err != nil {
+		return err
+	}
+	if s.run, err = res.LastInsertId(); err != nil {
+		return err
+	}
+	return s.begin()
+}
+
+// timelineAdditions are the timeline columns added since the first -sqlite
+// schema, in the order they were added. A database made before one of them
+// gets it as a column of NULLs for its older runs.
+var timelineAdditions = append([]string{"gold_gini"}, demographicColumns()...)
+
+// migrate brings a database made by an older build up to the current
+// schema, so its runs and this one share the tables.
+func (s *statsDB) migrate() error {
+	rows, err := s.db.Query(`SELECT name FROM pragma_table_info('timeline')`)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		have[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, c := range timelineAdditions {
+		if !have[c] {
+			if _, err := s.db.Exec(`ALTER TABLE timeline ADD COLUMN ` + c + ` INTEGER`); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// begin starts the next transaction and prepares the inserts in it.
+func (s *statsDB) begin() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	prepare := func(query string) *sql.Stmt {
+		stmt, perr := tx.Prepare(query)
+		if err == nil {
+			err = perr
+		}
+		return stmt
+	}
+	// Columns by name: a migrated timeline has them in the order added
+	s.sample = prepare(`INSERT INTO timeline (run, ` + strings.Join(timelineColumns, ", ") + `) VALUES (?` +
+		strings.Repeat(", ?", len(timelineColumns)) + `)`)
+	s.epoch = prepare(`INSERT INTO epochs VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	s.trade = prepare(`INSERT INTO trades VALUES (?, ?, ?, ?)`)
+	s.genome = prepare(`INSERT INTO genomes VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	s.tx = tx
+	return nil
+}
+
+// commit commits the rows written since the last commit, keeping the first
+// error for close.
+func (s *statsDB) commit() {
+	if s.err != nil {
+		return
+	}
+	s.err = s.tx.Commit()
+	s.tx = nil
+}
+
+// exec runs stmt, keeping the first error for close.
+func (s *statsDB) exec(stmt *sql.Stmt, args ...any) {
+	if s.err != nil {
+		return
+	}
+	_, s.err = stmt.Exec(append([]any{s.run}, args...)...)
+}
+
+func (s *statsDB) onEvent(ev sandbox.Event) {
+	if e, ok := ev.(sandbox.TradeCompleted); ok {
+		s.exec(s.trade, e.Tick, e.A, e.B)
+	}
+}
+
+// addSample records a timeline sample.
+func (s *statsDB) addSample(tp timePoint) {
+	args := make([]any, 0, len(timelineColumns))
+	for _, v := range tp.values() {
+		args = append(args, v)
+	}
+	s.exec(s.sample, args...)
+	s.commit()
+	if s.err == nil {
+		s.err = s.begin()
+	}
+}
+
+// addEpoch records the epoch ending now, tp sampled at its end, and the
+// fittest genome of the population about to be evolved.
+func (s *statsDB) addEpoch(epoch int, tp timePoint, w *sandbox.World, deaths int) {
+	s.exec(s.epoch, epoch, tp.tick, tp.alive, tp.avgFit, tp.bestFit, tp.genomeAvg,
+		tp.diversity, tp.unique, tp.trades, tp.teaches, deaths-s.deaths)
+	s.deaths = deaths
+	var best *sandbox.NPC
+	for _, npc := range w.NPCs {
+		if npc.Alive() && (best == nil || npc.Fitness > best.Fitness) {
+			best = npc
+		}
+	}
+	if best != nil {
+		s.addGenome("best", epoch, tp.tick, best)
+	}
+}
+
+func (s *statsDB) addGenome(kind string, epoch, tick int, npc *sandbox.NPC) {
+	s.exec(s.genome, kind, epoch, tick, npc.ID, npc.Fitness, len(npc.Genome), npc.Genome)
+}
+
+// close records the survivors' genomes and the tick the run ended on, and
+// commits the last of the run.
+func (s *statsDB) close(w *sandbox.World, epoch int) error {
+	for _, npc := range w.NPCs {
+		if npc.Alive() {
+			s.addGenome("final", epoch, w.Tick, npc)
+		}
+	}
+	if s.err == nil {
+		_, s.err = s.tx.Exec(`UPDATE runs SET ended_tick = ? WHERE id = ?`, w.Tick, s.run)
+	}
+	if s.err == nil {
+		s.commit()
+	} else if s.tx != nil {
+		s.tx.Rollback()
+	}
+	if err := s.db.Close(); s.err == nil {
+		s.err = err
+	}
+	if s.err != nil {
+		return s.err
+	}
+	status.event("sqlite", -1, logFields{"run": s.run}, fmt.Sprintf("sqlite: run %d written", s.run))
+	return nil
+}
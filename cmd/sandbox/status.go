@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// logFields are the data of a status line, as JSON sees it.
+type logFields map[string]any
+
+// statusLog carries the status lines of a run (what it prints on stderr
+// before the final report) as text, as JSON lines (-log-format json), or
+// not at all (-quiet). Errors bypass it. Sweep jobs share it, hence the
+// lock.
+type statusLog struct {
+	mu    sync.Mutex
+	json  bool
+	quiet bool
+
+	// Progress through the run, once startProgress is called
+	total int
+	start time.Time
+	bar   *progressBar // nil when not shown
+}
+
+// status is the status log, set up from the flags in main.
+var status statusLog
+
+// event writes a status line: msg as text, or in JSON an object with the
+// time, event, tick (when not negative), msg and f.
+func (l *statusLog) event(event string, tick int, f logFields, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.write(event, tick, f, msg)
+}
+
+func (l *statusLog) write(event string, tick int, f logFields, msg string) {
+	if l.quiet {
+		return
+	}
+	l.bar.clear()
+	if !l.json {
+		fmt.Fprintln(os.Stderr, msg)
+		return
+	}
+	line := logFields{"time": time.Now().UTC().Format(time.RFC3339Nano), "event": event, "msg": msg}
+	if tick >= 0 {
+		line["tick"] = tick
+	}
+	for k, v := range f {
+		line[k] = v
+	}
+	data, _ := json.Marshal(line)
+	fmt.Fprintf(os.Stderr, "%s\n", data)
+}
+
+// text reports whether status lines come out as text, so multi-line
+// reports (snapshots) may be printed as they are.
+func (l *statusLog) text() bool {
+	return !l.quiet && !l.json
+}
+
+// startProgress begins reporting progress through a run of total ticks: a
+// progress bar with an ETA when bar is set (stderr is a terminal), or in
+// JSON a progress line every 10% of the run.
+func (l *statusLog) startProgress(total int, bar bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.total, l.start = total, time.Now()
+	if bar && l.text() && total > 0 {
+		l.bar = &progressBar{}
+	}
+}
+
+// progress notes that tick has run.
+func (l *statusLog) progress(tick int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	done := tick + 1
+	switch {
+	case l.quiet || l.total == 0:
+	case l.json:
+		if step := l.total / 10; step > 0 && done%step == 0 {
+			eta := l.eta(done)
+			l.write("progress", tick, logFields{"total": l.total, "percent": done * 100 / l.total, "eta_seconds": eta.Seconds()},
+				fmt.Sprintf("Tick %d/%d (%d%%), ETA %s", done, l.total, done*100/l.total, eta))
+		}
+	case l.bar != nil && time.Since(l.bar.drawn) >= progressEvery:
+		l.bar.draw(done, l.total, l.eta(done))
+	}
+}
+
+// eta estimates the time left once done ticks have run.
+func (l *statusLog) eta(done int) time.Duration {
+	return (time.Since(l.start) * time.Duration(l.total-done) / time.Duration(max(done, 1))).Round(time.Second)
+}
+
+// endProgress takes the bar down.
+func (l *statusLog) endProgress() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.bar.clear()
+	l.bar, l.total = nil, 0
+}
+
+// progressBar is the progress bar drawn on a terminal.
+type progressBar struct {
+	drawn   time.Time
+	visible bool
+}
+
+const (
+	progressEvery = 200 * time.Millisecond // between redraws
+	progressWidth = 30
+)
+
+// draw shows the bar at done ticks of total.
+func (p *progressBar) draw(done, total int, eta time.Duration) {
+	p.drawn = time.Now()
+	filled := progressWidth * done / total
+	fmt.Fprintf(os.Stderr, "\r\033[K[%s%s] %d/%d %3d%% ETA %s",
+		strings.Repeat("#", filled), strings.Repeat("-", progressWidth-filled), done, total, done*100/total, eta)
+	p.visible = true
+}
+
+// clear erases the bar, if it is showing, so a line can be printed in its
+// place; the next progress call puts it back.
+func (p *progressBar) clear() {
+	if p != nil && p.visible {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.visible = false
+		p.drawn = time.Time{}
+	}
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// logSnapshot reports a -snap-every snapshot: printed in full as text, as
+// the NPC table in JSON.
+func logSnapshot(w *sandbox.World, sched *sandbox.Scheduler, tick int) {
+	switch {
+	case status.quiet:
+		return
+	case status.text():
+		status.mu.Lock()
+		status.bar.clear()
+		status.mu.Unlock()
+		printSnapshot(w, sched, tick)
+		return
+	}
+	var npcs []logFields
+	for _, npc := range w.NPCs {
+		if npc.Alive() {
+			npcs = append(npcs, logFields{"id": npc.ID, "x": npc.X, "y": npc.Y, "health": npc.Health, "energy": npc.Energy,
+				"item": itemName(npc.Item), "gold": npc.Gold, "age": npc.Age, "stress": npc.Stress, "fitness": npc.Fitness})
+		}
+	}
+	status.event("snapshot", tick, logFields{"npcs": npcs}, fmt.Sprintf("Snapshot at tick %d: %d NPCs", tick, len(npcs)))
+}
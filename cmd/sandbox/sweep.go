@@ -0,0 +1,431 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// sweepParam sets one swept parameter, named after its flag, on a config.
+type sweepParam struct {
+	set     func(cfg *simConfig, v string) error
+	numeric bool // accepts lo:hi ranges in random search
+	integer bool // ranges sample whole numbers
+}
+
+func intParam(field func(*simConfig) *int) sweepParam {
+	return sweepParam{numeric: true, integer: true, set: func(cfg *simConfig, v string) error {
+		n, err := strconv.Atoi(v)
+		*field(cfg) = n
+		return err
+	}}
+}
+
+func floatParam(field func(*simConfig) *float64) sweepParam {
+	return sweepParam{numeric: true, set: func(cfg *simConfig, v string) error {
+		f, err := strconv.ParseFloat(v, 64)
+		*field(cfg) = f
+		return err
+	}}
+}
+
+func boolParam(field func(*simConfig) *bool) sweepParam {
+	return sweepParam{set: func(cfg *simConfig, v string) error {
+		b, err := strconv.ParseBool(v)
+		*field(cfg) = b
+		return err
+	}}
+}
+
+// sweepParams are the GA and world parameters a sweep can vary.
+var sweepParams = map[string]sweepParam{
+	"npcs":              intParam(func(c *simConfig) *int { return &c.npcs }),
+	"world":             intParam(func(c *simConfig) *int { return &c.worldSize }),
+	"ticks":             intParam(func(c *simConfig) *int { return &c.ticks }),
+	"gas":               intParam(func(c *simConfig) *int { return &c.gas }),
+	"evolve-every":      intParam(func(c *simConfig) *int { return &c.evolveEvery }),
+	"elitism":           intParam(func(c *simConfig) *int { return &c.elitism }),
+	"tournament-size":   intParam(func(c *simConfig) *int { return &c.tournamentSize }),
+	"max-genome":        intParam(func(c *simConfig) *int { return &c.maxGenome }),
+	"predators":         intParam(func(c *simConfig) *int { return &c.predators }),
+	"contagion":         intParam(func(c *simConfig) *int { return &c.contagion }),
+	"shoot-range":       intParam(func(c *simConfig) *int { return &c.shootRange }),
+	"gift-fitness":      intParam(func(c *simConfig) *int { return &c.giftFitness }),
+	"traders":           floatParam(func(c *simConfig) *float64 { return &c.traderFrac }),
+	"classic-rate":      floatParam(func(c *simConfig) *float64 { return &c.classicRate }),
+	"diversity-target":  floatParam(func(c *simConfig) *float64 { return &c.diversityTarget }),
+	"species-threshold": floatParam(func(c *simConfig) *float64 { return &c.speciesThreshold }),
+	"clone-penalty":     floatParam(func(c *simConfig) *float64 { return &c.clonePenalty }),
+	"clone-distance":    floatParam(func(c *simConfig) *float64 { return &c.cloneDistance }),
+	"length-penalty":    floatParam(func(c *simConfig) *float64 { return &c.lengthPenalty }),
+	"clan-share":        floatParam(func(c *simConfig) *float64 { return &c.clanShare }),
+	"structural":        boolParam(func(c *simConfig) *bool { return &c.structural }),
+	"shorter-first":     boolParam(func(c *simConfig) *bool { return &c.shorterFirst }),
+	"biomes":            boolParam(func(c *simConfig) *bool { return &c.biomes }),
+	"recipe-memes":      boolParam(func(c *simConfig) *bool { return &c.recipeMemes }),
+	"crossover": {set: func(c *simConfig, v string) error {
+		c.crossoverMode = parseCrossover(v)
+		return nil
+	}},
+	"selection": {set: func(c *simConfig, v string) error {
+		var err error
+		c.selection, err = parseSelection(v)
+		return err
+	}},
+	"reproduction": {set: func(c *simConfig, v string) error {
+		c.reproduction = strings.ToLower(v)
+		return nil
+	}},
+	"actions": {set: func(c *simConfig, v string) error {
+		var err error
+		c.actions, err = sandbox.ParseActionCosts(v)
+		return err
+	}},
+	"fitness": {set: func(c *simConfig, v string) error {
+		var err error
+		c.fitness, err = sandbox.ParseFitness(v)
+		return err
+	}},
+	"roles": {set: func(c *simConfig, v string) error {
+		var err error
+		c.roles, err = parseRoles(v)
+		return err
+	}},
+}
+
+// unknownParam is the error for a parameter missing from sweepParams.
+func unknownParam(name string) error {
+	names := make([]string, 0, len(sweepParams))
+	for n := range sweepParams {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("unknown parameter %q (want %s)", name, strings.Join(names, ", "))
+}
+
+// sweepAxis is one swept parameter: its values (grid and random search)
+// or a lo:hi range (random search only).
+type sweepAxis struct {
+	name   string
+	param  sweepParam
+	values []string
+	lo, hi float64 // range, when values is empty
+}
+
+// parseSweep reads "name=v1,v2,...;name=lo:hi" into axes.
+func parseSweep(spec string) ([]sweepAxis, error) {
+	var axes []sweepAxis
+	for _, part := range strings.Split(spec, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, vals, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%q is not name=values", part)
+		}
+		name = strings.TrimSpace(name)
+		param, ok := sweepParams[name]
+		if !ok {
+			return nil, unknownParam(name)
+		}
+		axis := sweepAxis{name: name, param: param}
+		if lo, hi, isRange := strings.Cut(vals, ":"); isRange && param.numeric {
+			var err1, err2 error
+			axis.lo, err1 = strconv.ParseFloat(strings.TrimSpace(lo), 64)
+			axis.hi, err2 = strconv.ParseFloat(strings.TrimSpace(hi), 64)
+			if err1 != nil || err2 != nil || axis.lo > axis.hi {
+				return nil, fmt.Errorf("%s: bad range %q", name, vals)
+			}
+		} else {
+			for _, v := range strings.Split(vals, ",") {
+				v = strings.TrimSpace(v)
+				var probe simConfig
+				if err := param.set(&probe, v); err != nil {
+					return nil, fmt.Errorf("%s: bad value %q", name, v)
+				}
+				axis.values = append(axis.values, v)
+			}
+		}
+		axes = append(axes, axis)
+	}
+	if len(axes) == 0 {
+		return nil, fmt.Errorf("nothing to sweep")
+	}
+	return axes, nil
+}
+
+// sweepPoints returns the parameter settings to try: the full grid, or
+// samples random points when samples > 0.
+func sweepPoints(axes []sweepAxis, samples int, rng *rand.Rand) ([][]string, error) {
+	if samples > 0 {
+		points := make([][]string, samples)
+		for i := range points {
+			for _, a := range axes {
+				var v string
+				switch {
+				case len(a.values) > 0:
+					v = a.values[rng.Intn(len(a.values))]
+				case a.param.integer:
+					v = strconv.Itoa(int(a.lo) + rng.Intn(int(a.hi)-int(a.lo)+1))
+				default:
+					v = strconv.FormatFloat(a.lo+rng.Float64()*(a.hi-a.lo), 'f', 3, 64)
+				}
+				points[i] = append(points[i], v)
+			}
+		}
+		return points, nil
+	}
+	points := [][]string{nil}
+	for _, a := range axes {
+		if len(a.values) == 0 {
+			return nil, fmt.Errorf("%s: ranges need random search (-sweep-samples)", a.name)
+		}
+		var next [][]string
+		for _, p := range points {
+			for _, v := range a.values {
+				next = append(next, append(p[:len(p):len(p)], v))
+			}
+		}
+		points = next
+	}
+	return points, nil
+}
+
+// sweepMetrics are the final metrics aggregated per point, in report order.
+var sweepMetrics = []string{"alive", "avg_fit", "best_fit", "genome_avg", "trades", "teaches", "gold", "diversity", "unique"}
+
+// sweepRun is the outcome of one seed at one point.
+type sweepRun struct {
+	metrics []float64 // by sweepMetrics
+	extinct bool
+	result  simResult
+}
+
+// metric returns the run's value of the named sweepMetrics entry.
+func (r sweepRun) metric(name string) float64 {
+	for i, m := range sweepMetrics {
+		if m == name {
+			return r.metrics[i]
+		}
+	}
+	return 0
+}
+
+// sweepResult aggregates every seed run at one point.
+type sweepResult struct {
+	Params  map[string]string  `json:"params"`
+	Runs    int                `json:"runs"`
+	Extinct int                `json:"extinct"`
+	Mean    map[string]float64 `json:"mean"`
+	StdDev  map[string]float64 `json:"stddev"`
+	values  []string
+}
+
+// runSweep runs every point of the sweep for seeds seeds each (seed,
+// seed+1, ...), jobs runs at a time, and writes the points ranked by the
+// mean of rank (best first) as CSV, or as JSON when out ends in .json.
+func runSweep(cfg simConfig, spec string, samples, seeds, jobs int, rank, out string) error {
+	axes, err := parseSweep(spec)
+	if err != nil {
+		return err
+	}
+	rankIdx := -1
+	for i, m := range sweepMetrics {
+		if m == rank {
+			rankIdx = i
+		}
+	}
+	if rankIdx < 0 {
+		return fmt.Errorf("unknown rank metric %q (want %s)", rank, strings.Join(sweepMetrics, ", "))
+	}
+	points, err := sweepPoints(axes, samples, rand.New(sandbox.NewSource(cfg.seed)))
+	if err != nil {
+		return err
+	}
+	seeds = max(seeds, 1)
+	jobs = max(jobs, 1)
+
+	cfg.verbose = false
+	cfg.snapEvery = 0
+	cfg.record = ""
+	cfg.lineage = ""
+	cfgs := make([]simConfig, 0, len(points)*seeds)
+	for _, p := range points {
+		for s := 0; s < seeds; s++ {
+			c := cfg
+			c.seed = cfg.seed + int64(s)
+			for i, a := range axes {
+				a.param.set(&c, p[i])
+			}
+			if c.tlEvery = c.ticks / 80; c.tlEvery < 1 {
+				c.tlEvery = 1
+			}
+			cfgs = append(cfgs, c)
+		}
+	}
+
+	status.event("sweep", -1, logFields{"points": len(points), "seeds": seeds, "runs": len(cfgs), "jobs": jobs},
+		fmt.Sprintf("Sweeping %d points × %d seeds = %d runs on %d jobs...", len(points), seeds, len(cfgs), jobs))
+	runs := runBatch(cfgs, jobs, func(i, done int) {
+		status.event("sweep-run", -1, logFields{"done": done, "runs": len(cfgs), "point": formatPoint(axes, points[i/seeds]), "seed": cfgs[i].seed},
+			fmt.Sprintf("[%d/%d] %s seed=%d", done, len(cfgs), formatPoint(axes, points[i/seeds]), cfgs[i].seed))
+	})
+
+	results := make([]sweepResult, len(points))
+	for i, p := range points {
+		results[i] = aggregateSweep(axes, p, runs[i*seeds:(i+1)*seeds])
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Mean[rank] > results[j].Mean[rank]
+	})
+	fmt.Fprintf(os.Stderr, "best: %s %s=%.1f\n", formatPoint(axes, results[0].values), rank, results[0].Mean[rank])
+
+	w := io.Writer(os.Stdout)
+	if out != "" {
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if strings.EqualFold(filepath.Ext(out), ".json") {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", " ")
+		return enc.Encode(results)
+	}
+	return writeSweepCSV(w, axes, results)
+}
+
+// runBatch runs every config with sweepOnce, jobs at a time, and returns
+// the runs in order. finished is called, one run at a time, with the index
+// of each run as it ends and how many have ended so far.
+func runBatch(cfgs []simConfig, jobs int, finished func(i, done int)) []sweepRun {
+	runs := make([]sweepRun, len(cfgs))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	next, done := 0, 0
+	for j := 0; j < max(jobs, 1); j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				i := next
+				next++
+				mu.Unlock()
+				if i >= len(cfgs) {
+					return
+				}
+				runs[i] = sweepOnce(cfgs[i])
+				mu.Lock()
+				done++
+				finished(i, done)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return runs
+}
+
+// sweepOnce runs one simulation to the end and measures it.
+func sweepOnce(cfg simConfig) sweepRun {
+	s := newSimulation(cfg)
+	for tick := 0; tick < cfg.ticks; tick++ {
+		if !s.step(tick) {
+			break
+		}
+	}
+	r := s.result()
+	return sweepRun{
+		result:  r,
+		extinct: r.alive == 0,
+		metrics: []float64{
+			float64(r.alive), float64(r.avgFit), float64(r.bestFit), float64(r.genomeAvg),
+			float64(r.trades), float64(r.teaches), float64(r.totalGold),
+			sandbox.GenomeDiversity(s.w.NPCs), float64(sandbox.CensusGenomes(s.w.NPCs).Unique),
+		},
+	}
+}
+
+// aggregateSweep averages the runs at one point.
+func aggregateSweep(axes []sweepAxis, point []string, runs []sweepRun) sweepResult {
+	res := sweepResult{
+		Params: make(map[string]string, len(axes)),
+		Runs:   len(runs),
+		Mean:   make(map[string]float64, len(sweepMetrics)),
+		StdDev: make(map[string]float64, len(sweepMetrics)),
+		values: point,
+	}
+	for i, a := range axes {
+		res.Params[a.name] = point[i]
+	}
+	for _, r := range runs {
+		if r.extinct {
+			res.Extinct++
+		}
+	}
+	n := float64(len(runs))
+	for m, name := range sweepMetrics {
+		sum, sq := 0.0, 0.0
+		for _, r := range runs {
+			sum += r.metrics[m]
+		}
+		mean := sum / n
+		for _, r := range runs {
+			sq += (r.metrics[m] - mean) * (r.metrics[m] - mean)
+		}
+		res.Mean[name] = mean
+		res.StdDev[name] = math.Sqrt(sq / n)
+	}
+	return res
+}
+
+// writeSweepCSV writes one row per point, best first: its rank, parameter
+// values, runs, extinctions, then the mean and standard deviation of each
+// metric.
+func writeSweepCSV(w io.Writer, axes []sweepAxis, results []sweepResult) error {
+	cw := csv.NewWriter(w)
+	header := []string{"rank"}
+	for _, a := range axes {
+		header = append(header, a.name)
+	}
+	header = append(header, "runs", "extinct")
+	for _, m := range sweepMetrics {
+		header = append(header, m, m+"_sd")
+	}
+	cw.Write(header)
+	for i, r := range results {
+		row := []string{strconv.Itoa(i + 1)}
+		row = append(row, r.values...)
+		row = append(row, strconv.Itoa(r.Runs), strconv.Itoa(r.Extinct))
+		for _, m := range sweepMetrics {
+			row = append(row, strconv.FormatFloat(r.Mean[m], 'f', 2, 64), strconv.FormatFloat(r.StdDev[m], 'f', 2, 64))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatPoint renders a point as "name=value ...".
+func formatPoint(axes []sweepAxis, point []string) string {
+	parts := make([]string, len(axes))
+	for i, a := range axes {
+		parts[i] = a.name + "=" + point[i]
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/psilLang/psil/pkg/micro"
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+const (
+	tuiPanel = 34 // inspector width
+	tuiFeed  = 8  // event feed height
+	tuiFPS   = 30 // redraws per second while running
+)
+
+const tuiHelp = "space=pause n=step +/-=speed tab/shift-tab=select f=fittest q=quit"
+
+// modNames maps a modifier kind to its inspector label.
+var modNames = []string{"none", "gas", "forage", "attack", "defense", "energy", "health", "stealth", "trade", "stress", "resist-poison"}
+
+// tileStyles colours the map tiles; anything missing is drawn as empty.
+var tileStyles = map[byte]tcell.Style{
+	sandbox.TileFood:     tcell.StyleDefault.Foreground(tcell.ColorGreen),
+	sandbox.TileTool:     tcell.StyleDefault.Foreground(tcell.ColorSilver),
+	sandbox.TileWeapon:   tcell.StyleDefault.Foreground(tcell.ColorSilver),
+	sandbox.TileTreasure: tcell.StyleDefault.Foreground(tcell.ColorYellow),
+	sandbox.TileCrystal:  tcell.StyleDefault.Foreground(tcell.ColorAqua),
+	sandbox.TileForge:    tcell.StyleDefault.Foreground(tcell.ColorRed),
+	sandbox.TilePoison:   tcell.StyleDefault.Foreground(tcell.ColorFuchsia),
+	sandbox.TileWall:     tcell.StyleDefault.Foreground(tcell.ColorGray),
+	sandbox.TileShelter:  tcell.StyleDefault.Foreground(tcell.ColorOlive),
+	sandbox.TileChest:    tcell.StyleDefault.Foreground(tcell.ColorOlive),
+	sandbox.TileMarket:   tcell.StyleDefault.Foreground(tcell.ColorYellow),
+	sandbox.TilePost:     tcell.StyleDefault.Foreground(tcell.ColorTeal),
+	sandbox.TileSchool:   tcell.StyleDefault.Foreground(tcell.ColorFuchsia),
+}
+
+// tui is the -tui live viewer: the map, an inspector for one selected NPC
+// and a scrolling feed of scheduler events and log lines. A key goroutine
+// drives the TickControl; everything that reads the world runs on the
+// tick loop, from wait.
+type tui struct {
+	screen tcell.Screen
+	w      *sandbox.World
+	sched  *sandbox.Scheduler
+	events chan tcell.Event // every terminal event, for the tick loop
+
+	mu      sync.Mutex
+	feed    []string // newest last
+	speed   float64  // ticks/sec (0 = flat out)
+	stopped bool
+
+	selected uint16 // inspected NPC (0 = none)
+	drawn    time.Time
+	stderr   *os.File // the real stderr, while log lines go to the feed
+	logW     *os.File
+}
+
+// newTUI takes over the terminal and starts reading keys. Log lines written
+// to os.Stderr go to the feed until close.
+func newTUI(w *sandbox.World, sched *sandbox.Scheduler, speed float64) (*tui, error) {
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return nil, err
+	}
+	if err := screen.Init(); err != nil {
+		return nil, err
+	}
+	u := &tui{screen: screen, w: w, sched: sched, speed: speed, events: make(chan tcell.Event, 64)}
+	sched.Subscribe(sandbox.SubscriberFunc(u.onEvent))
+
+	if r, lw, err := os.Pipe(); err == nil {
+		u.stderr, u.logW = os.Stderr, lw
+		os.Stderr = lw
+		go func() {
+			sc := bufio.NewScanner(r)
+			for sc.Scan() {
+				u.log(sc.Text())
+			}
+		}()
+	}
+	go u.readKeys(screen)
+	return u, nil
+}
+
+// close restores the terminal and stderr. It is safe to call twice.
+func (u *tui) close() {
+	if u.screen == nil {
+		return
+	}
+	u.screen.Fini()
+	u.screen = nil
+	if u.logW != nil {
+		os.Stderr = u.stderr
+		u.logW.Close()
+	}
+}
+
+// readKeys applies the control keys to the scheduler and passes every
+// event on to the tick loop, until the screen is closed.
+func (u *tui) readKeys(screen tcell.Screen) {
+	for {
+		ev := screen.PollEvent()
+		if ev == nil {
+			return
+		}
+		if key, ok := ev.(*tcell.EventKey); ok {
+			u.control(key)
+		}
+		select {
+		case u.events <- ev:
+		default: // the loop is busy ticking; it redraws soon anyway
+		}
+	}
+}
+
+// control handles pause, step, speed and quit.
+func (u *tui) control(key *tcell.EventKey) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	switch {
+	case key.Key() == tcell.KeyEscape || key.Key() == tcell.KeyCtrlC || key.Rune() == 'q':
+		u.stopped = true
+		u.sched.Stop()
+	case key.Rune() == ' ' || key.Rune() == 'p':
+		// Pause after one more tick, so the loop draws the tick it stops on
+		// even when it was waiting out a speed cap
+		if u.sched.Paused() {
+			u.sched.Resume()
+		} else {
+			u.sched.Step(1)
+		}
+	case key.Rune() == 'n':
+		u.sched.Step(1)
+	case key.Rune() == '+':
+		// Double the speed; past 200 ticks/sec run flat out
+		if u.speed *= 2; u.speed > 200 {
+			u.speed = 0
+		}
+		u.sched.SetSpeed(u.speed)
+	case key.Rune() == '-':
+		switch {
+		case u.speed == 0:
+			u.speed = 200
+		case u.speed > 1:
+			u.speed /= 2
+		}
+		u.sched.SetSpeed(u.speed)
+	}
+}
+
+// wait stands in for Scheduler.Wait in the tick loop. It redraws at most
+// tuiFPS times a second and, while paused, keeps serving the inspector
+// and Scheduler.Do calls (the -serve console) until the simulation resumes,
+// steps or stops.
+func (u *tui) wait() bool {
+	for drained := false; !drained; {
+		select {
+		case ev := <-u.events:
+			u.handle(ev)
+		default:
+			drained = true
+		}
+	}
+	if time.Since(u.drawn) >= time.Second/tuiFPS {
+		u.draw()
+	}
+	for u.sched.Paused() && !u.isStopped() {
+		u.draw()
+		select {
+		case ev := <-u.events:
+			u.handle(ev)
+		case <-time.After(time.Second / tuiFPS):
+			u.sched.RunCalls()
+		}
+	}
+	return u.sched.Wait()
+}
+
+func (u *tui) isStopped() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.stopped
+}
+
+// handle applies the view keys: NPC selection and resizing.
+func (u *tui) handle(ev tcell.Event) {
+	switch ev := ev.(type) {
+	case *tcell.EventResize:
+		u.screen.Sync()
+	case *tcell.EventKey:
+		switch {
+		case ev.Key() == tcell.KeyTab:
+			u.cycle(1)
+		case ev.Key() == tcell.KeyBacktab:
+			u.cycle(-1)
+		case ev.Rune() == 'f':
+			best := -1 << 31
+			for _, npc := range u.w.NPCs {
+				if npc.Alive() && npc.Fitness > best {
+					best, u.selected = npc.Fitness, npc.ID
+				}
+			}
+		}
+	}
+}
+
+// cycle selects the next (dir 1) or previous (dir -1) living NPC by ID.
+func (u *tui) cycle(dir int) {
+	var ids []int
+	for _, npc := range u.w.NPCs {
+		if npc.Alive() {
+			ids = append(ids, int(npc.ID))
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Ints(ids)
+	i := sort.SearchInts(ids, int(u.selected))
+	switch {
+	case dir < 0:
+		i = (i - 1 + len(ids)) % len(ids)
+	case i < len(ids) && ids[i] == int(u.selected):
+		i = (i + 1) % len(ids)
+	default:
+		i %= len(ids)
+	}
+	u.selected = uint16(ids[i])
+}
+
+// onEvent adds a scheduler event to the feed.
+func (u *tui) onEvent(ev sandbox.Event) {
+	if line := describeEvent(ev); line != "" {
+		u.log(fmt.Sprintf("%6d %s", ev.EventTick(), line))
+	}
+}
+
+// describeEvent returns a feed line for ev, or "" for events not shown
+// (curriculum stages are logged already).
+func describeEvent(ev sandbox.Event) string {
+	switch e := ev.(type) {
+	case sandbox.TradeCompleted:
+		return fmt.Sprintf("#%d traded with #%d", e.A, e.B)
+	case sandbox.TeachSucceeded:
+		return fmt.Sprintf("#%d taught #%d", e.Teacher, e.Student)
+	case sandbox.NPCBorn:
+		if e.Clone {
+			return fmt.Sprintf("#%d reborn as a clone", e.ID)
+		}
+		return fmt.Sprintf("#%d born to #%d and #%d", e.ID, e.Parents[0], e.Parents[1])
+	case sandbox.NPCDied:
+		return fmt.Sprintf("#%d died of %s at age %d (fitness %d)", e.ID, sandbox.DeathCauseNames[e.Cause], e.Age, e.Fitness)
+	case sandbox.ItemCrafted:
+		return fmt.Sprintf("#%d crafted %s into %s", e.NPC, itemName(e.Input), itemName(e.Output))
+	case sandbox.Blight:
+		return fmt.Sprintf("blight destroyed %d food", e.Destroyed)
+	case sandbox.ContractPosted:
+		return "contract posted: " + describeContract(e.Contract)
+	case sandbox.ContractFulfilled:
+		return fmt.Sprintf("#%d fulfilled contract: %s", e.NPC, describeContract(e.Contract))
+	case sandbox.GenomeSwapped:
+		if e.Frozen {
+			return fmt.Sprintf("#%d got a new genome, frozen out of evolution", e.NPC)
+		}
+		return fmt.Sprintf("#%d got a new genome", e.NPC)
+	}
+	return ""
+}
+
+// describeContract names a contract's job and reward.
+func describeContract(c sandbox.Contract) string {
+	if c.Kind == sandbox.ContractEliminate {
+		return fmt.Sprintf("kill #%d for %d gold", c.Target, c.Reward)
+	}
+	return fmt.Sprintf("deliver %s to (%d,%d) for %d gold", itemName(c.Item), c.X, c.Y, c.Reward)
+}
+
+// log appends a line to the feed, keeping a screenful.
+func (u *tui) log(line string) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.feed = append(u.feed, line)
+	if len(u.feed) > 4*tuiFeed {
+		u.feed = append(u.feed[:0], u.feed[len(u.feed)-tuiFeed:]...)
+	}
+}
+
+// draw renders the whole screen.
+func (u *tui) draw() {
+	s := u.screen
+	s.Clear()
+	width, height := s.Size()
+	mapW, mapH := max(width-tuiPanel-1, 1), max(height-tuiFeed-2, 1)
+
+	// Status line
+	alive := 0
+	for _, npc := range u.w.NPCs {
+		if npc.Alive() {
+			alive++
+		}
+	}
+	u.mu.Lock()
+	speed := "max"
+	if u.speed > 0 {
+		speed = fmt.Sprintf("%g/s", u.speed)
+	}
+	state := "running"
+	if u.sched.Paused() {
+		state = "paused"
+	}
+	u.print(0, 0, width, tcell.StyleDefault.Bold(true),
+		fmt.Sprintf("tick %d  alive %d  %s  speed %s   %s", u.w.Tick, alive, state, speed, tuiHelp))
+	feed := u.feed[max(len(u.feed)-tuiFeed, 0):]
+	for i, line := range feed {
+		u.print(0, height-len(feed)+i, width, tcell.StyleDefault, line)
+	}
+	u.mu.Unlock()
+
+	// Map, scrolled to keep the selected NPC in view
+	sel := u.w.NPCByID(u.selected)
+	if sel != nil && !sel.Alive() {
+		sel = nil
+	}
+	ox, oy := 0, 0
+	if sel != nil {
+		ox = min(max(sel.X-mapW/2, 0), max(u.w.Size-mapW, 0))
+		oy = min(max(sel.Y-mapH/2, 0), max(u.w.Size-mapH, 0))
+	}
+	empty := tcell.StyleDefault.Foreground(tcell.ColorDimGray)
+	for y := 0; y < mapH && oy+y < u.w.Size; y++ {
+		for x := 0; x < mapW && ox+x < u.w.Size; x++ {
+			wx, wy := ox+x, oy+y
+			glyph, style := []rune(tileGlyph(u.w.TileAt(wx, wy).Type()))[0], empty
+			if st, ok := tileStyles[u.w.TileAt(wx, wy).Type()]; ok {
+				style = st
+			}
+			if npc := u.w.NPCByID(u.w.OccAt(wx, wy)); npc != nil {
+				glyph, style = '@', tcell.StyleDefault.Foreground(tcell.ColorWhite).Bold(true)
+				if npc.Item != sandbox.ItemNone {
+					glyph = 'T'
+				}
+				if npc.Clan != 0 {
+					style = style.Foreground(tcell.PaletteColor(clanPalette[int(npc.Clan)%len(clanPalette)]))
+				}
+				if npc.Predator {
+					glyph, style = 'P', style.Foreground(tcell.ColorRed)
+				}
+				if npc == sel {
+					style = style.Reverse(true)
+				}
+			}
+			s.SetContent(x, y+1, glyph, nil, style)
+		}
+	}
+
+	u.inspect(width-tuiPanel, 1, height-tuiFeed-2, sel)
+	s.Show()
+	u.drawn = time.Now()
+}
+
+// inspect renders the selected NPC's stats, modifiers and disassembled
+// genome into the panel at column x, rows y..y+rows-1.
+func (u *tui) inspect(x, y, rows int, npc *sandbox.NPC) {
+	if npc == nil {
+		u.print(x, y, tuiPanel, tcell.StyleDefault, "tab: select an NPC")
+		return
+	}
+	role := "prey"
+	if npc.Predator {
+		role = "predator"
+	}
+	if npc.Clan != 0 {
+		role += ", clan " + sandbox.ClanName(npc.Clan)
+	}
+	lines := []string{
+		fmt.Sprintf("NPC #%d at %d,%d (%s)", npc.ID, npc.X, npc.Y, role),
+		fmt.Sprintf("hp %d  energy %d  age %d", npc.Health, npc.Energy, npc.Age),
+		fmt.Sprintf("hunger %d  stress %d  gold %d", npc.Hunger, npc.Stress, npc.Gold),
+		fmt.Sprintf("item %s  fitness %d", itemName(npc.Item), npc.Fitness),
+		fmt.Sprintf("trades %d  kills %d  taught %d", npc.Trades, npc.Kills, npc.TeachCount),
+	}
+	if npc.Infection > 0 {
+		lines = append(lines, fmt.Sprintf("infected (%d ticks)", npc.Infection))
+	}
+	mods := "-"
+	if m := modLabels(npc.Mods); len(m) > 0 {
+		mods = strings.Join(m, " ")
+	}
+	lines = append(lines, "mods "+mods, "", fmt.Sprintf("genome (%d bytes):", len(npc.Genome)))
+	lines = append(lines, strings.Split(strings.TrimRight(micro.Disassemble(npc.Genome), "\n"), "\n")...)
+	for i, line := range lines {
+		if i >= rows {
+			break
+		}
+		u.print(x, y+i, tuiPanel, tcell.StyleDefault, line)
+	}
+}
+
+// modLabels lists the active modifiers, e.g. "attack+10" or "energy+5/20t"
+// for one with 20 ticks left.
+func modLabels(mods [4]sandbox.Modifier) []string {
+	var labels []string
+	for _, m := range mods {
+		if m.Duration == 0 || int(m.Kind) >= len(modNames) {
+			continue
+		}
+		label := fmt.Sprintf("%s%+d", modNames[m.Kind], m.Mag)
+		if m.Duration > 0 {
+			label += fmt.Sprintf("/%dt", m.Duration)
+		}
+		labels = append(labels, label)
+	}
+	return labels
+}
+
+// print writes text at x,y, cut off after width cells.
+func (u *tui) print(x, y, width int, style tcell.Style, text string) {
+	for i, r := range []rune(text) {
+		if i >= width {
+			return
+		}
+		u.screen.SetContent(x+i, y, r, nil, style)
+	}
+}
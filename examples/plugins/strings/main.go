@@ -0,0 +1,40 @@
+// Package main is an example PSIL plugin adding string words. Build and
+// load it with:
+//
+//	go build -buildmode=plugin -o strings.so ./examples/plugins/strings
+//	./psil -plugin strings.so
+package main
+
+import (
+	"strings"
+
+	"github.com/psilLang/psil/pkg/interpreter"
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// RegisterWords is the entry point cmd/psil calls after loading the plugin
+func RegisterWords(interp *interpreter.Interpreter) error {
+	interp.RegisterWord("upper", interpreter.CapNone, stringWord(strings.ToUpper))
+	interp.RegisterWord("lower", interpreter.CapNone, stringWord(strings.ToLower))
+	interp.RegisterWord("trim", interpreter.CapNone, stringWord(strings.TrimSpace))
+	interp.RegisterWord("strlen", interpreter.CapNone, func(i *interpreter.Interpreter) error {
+		if s, ok := i.PopString(); ok {
+			i.Push(types.Number(len([]rune(string(s)))))
+		}
+		return nil
+	})
+	return nil
+}
+
+// stringWord makes a word mapping the string on top of the stack
+func stringWord(f func(string) string) func(*interpreter.Interpreter) error {
+	return func(i *interpreter.Interpreter) error {
+		if s, ok := i.PopString(); ok {
+			i.Push(types.String(f(string(s))))
+		}
+		return nil
+	}
+}
+
+// main is unused: a plugin only runs RegisterWords
+func main() {}
@@ -0,0 +1,285 @@
+// Package format re-prints PSIL source in canonical form: quotations
+// indented by depth, single spaces between words, trailing comments and
+// runs of one-line DEFINEs aligned. Line breaks and comments are kept as
+// written, so formatting changes layout only.
+package format
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/parser"
+)
+
+// Indent is one level of quotation nesting
+const Indent = "    "
+
+// node is the lossless parse tree: a token (words and comments alike), or
+// a quotation with its contents and closing bracket
+type node struct {
+	tok   parser.Token
+	body  []*node // quotation contents
+	close *parser.Token
+	term  bool // the "." ending a DEFINE
+}
+
+// Source formats PSIL source. Source that does not parse is an error.
+func Source(src []byte) ([]byte, error) {
+	text := string(src)
+	if _, err := parser.Parse(text); err != nil {
+		return nil, err
+	}
+	tokens, err := parser.Tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	tree, _ := build(tokens)
+	markTerminators(tree)
+
+	var atoms []atom
+	for _, n := range tree {
+		atoms = flatten(n, atoms)
+	}
+	out := render(assemble(atoms))
+
+	if err := sameProgram(text, out); err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// build reads nodes up to the "]" closing the current quotation (or the
+// end), returning them and the tokens left after that bracket
+func build(tokens []parser.Token) ([]*node, []parser.Token) {
+	var nodes []*node
+	for len(tokens) > 0 {
+		t := tokens[0]
+		tokens = tokens[1:]
+		switch {
+		case t.Kind == "Punct" && t.Value == "]":
+			return nodes, append([]parser.Token{t}, tokens...)
+		case t.Kind == "Punct" && t.Value == "[":
+			q := &node{tok: t}
+			q.body, tokens = build(tokens)
+			if len(tokens) > 0 {
+				q.close = &tokens[0]
+				tokens = tokens[1:]
+			}
+			nodes = append(nodes, q)
+		default:
+			nodes = append(nodes, &node{tok: t})
+		}
+	}
+	return nodes, nil
+}
+
+// markTerminators finds the "." after each top-level DEFINE's body
+func markTerminators(nodes []*node) {
+	inDefine, bodyDone := false, false
+	for _, n := range nodes {
+		switch {
+		case n.tok.Kind == "Comment":
+		case n.tok.Kind == "DEFINE":
+			inDefine, bodyDone = true, false
+		case inDefine && n.close != nil:
+			bodyDone = true
+		case inDefine && bodyDone:
+			n.term = n.tok.Value == "."
+			inDefine = false
+		}
+	}
+}
+
+// atom is one token on its way to the output
+type atom struct {
+	text       string
+	line, last int // first and last source line
+	depth      int // +1 opens a quotation, -1 closes one
+	glue       bool
+	comment    bool
+	term       bool
+}
+
+func flatten(n *node, atoms []atom) []atom {
+	a := atom{
+		text:    strings.TrimRight(n.tok.Value, " \t\r"),
+		line:    n.tok.Pos.Line,
+		last:    n.tok.Pos.Line + strings.Count(n.tok.Value, "\n"),
+		comment: n.tok.Kind == "Comment",
+		term:    n.term,
+		glue:    n.term,
+	}
+	if len(atoms) > 0 {
+		prev := atoms[len(atoms)-1]
+		// Right after "[", and 'symbol
+		a.glue = a.glue || prev.depth == 1 || (prev.text == "'" && !prev.comment)
+	}
+	if n.close != nil || n.tok.Kind == "Punct" && n.tok.Value == "[" {
+		a.depth = 1
+		atoms = append(atoms, a)
+		for _, c := range n.body {
+			atoms = flatten(c, atoms)
+		}
+		if n.close != nil {
+			atoms = append(atoms, atom{text: "]", line: n.close.Pos.Line, last: n.close.Pos.Line, depth: -1, glue: true})
+		}
+		return atoms
+	}
+	return append(atoms, a)
+}
+
+// line is one output line: code, then maybe a comment
+type line struct {
+	indent  int
+	atoms   []atom
+	comment string
+	blank   bool // preceded by an empty line
+}
+
+// assemble groups atoms into lines as they were in the source
+func assemble(atoms []atom) []*line {
+	var lines []*line
+	var cur *line
+	depth, prevLast := 0, 0
+	for _, a := range atoms {
+		if cur == nil || a.line > prevLast {
+			cur = &line{blank: cur != nil && a.line > prevLast+1}
+			lines = append(lines, cur)
+			cur.indent = depth
+		}
+		if a.depth < 0 && allClosers(cur.atoms) {
+			cur.indent-- // a line starting with "]" sits at the outer level
+		}
+		if a.comment {
+			cur.comment = a.text
+		} else {
+			cur.atoms = append(cur.atoms, a)
+		}
+		depth += a.depth
+		prevLast = a.last
+	}
+	return lines
+}
+
+func allClosers(atoms []atom) bool {
+	for _, a := range atoms {
+		if a.depth >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// code joins a line's atoms with single spaces
+func (l *line) code() string {
+	var b strings.Builder
+	for j, a := range l.atoms {
+		if j > 0 && !a.glue {
+			b.WriteByte(' ')
+		}
+		b.WriteString(a.text)
+	}
+	return b.String()
+}
+
+// oneLineDefine reports whether the line is a whole DEFINE, and its name
+func (l *line) oneLineDefine() (string, bool) {
+	if len(l.atoms) < 4 || l.atoms[0].text != "DEFINE" || !l.atoms[len(l.atoms)-1].term {
+		return "", false
+	}
+	return l.atoms[1].text, true
+}
+
+// render prints lines, aligning the "==" of consecutive one-line DEFINEs
+// and consecutive trailing comments
+func render(lines []*line) string {
+	codes := make([]string, len(lines))
+	for j := 0; j < len(lines); {
+		k, width := j, 0
+		for ; k < len(lines) && (k == j || !lines[k].blank); k++ {
+			name, ok := lines[k].oneLineDefine()
+			if !ok || lines[k].indent != lines[j].indent {
+				break
+			}
+			width = max(width, len(name))
+		}
+		if k == j {
+			codes[j] = lines[j].code()
+			j++
+			continue
+		}
+		for ; j < k; j++ {
+			name, _ := lines[j].oneLineDefine()
+			rest := lines[j].atoms[2:]
+			tail := (&line{atoms: rest}).code()
+			codes[j] = "DEFINE " + name + strings.Repeat(" ", width-len(name)) + " " + tail
+		}
+	}
+
+	var b strings.Builder
+	for j := 0; j < len(lines); {
+		// A run of lines with code and a trailing comment shares a column
+		k, width := j, 0
+		for ; k < len(lines) && (k == j || !lines[k].blank); k++ {
+			l := lines[k]
+			if l.comment == "" || len(l.atoms) == 0 || strings.Contains(codes[k], "\n") {
+				break
+			}
+			width = max(width, len(Indent)*max(l.indent, 0)+len(codes[k]))
+		}
+		if k == j {
+			k = j + 1
+		}
+		for ; j < k; j++ {
+			l := lines[j]
+			if l.blank {
+				b.WriteByte('\n')
+			}
+			s := strings.Repeat(Indent, max(l.indent, 0)) + codes[j]
+			if l.comment != "" {
+				if len(l.atoms) > 0 {
+					s += strings.Repeat(" ", max(width-len(s), 0)+2)
+				}
+				s += l.comment
+			}
+			b.WriteString(strings.TrimRight(s, " ") + "\n")
+		}
+	}
+	return b.String()
+}
+
+// sameProgram checks that formatting kept the program as it was
+func sameProgram(before, after string) error {
+	a, err := program(before)
+	if err != nil {
+		return err
+	}
+	b, err := program(after)
+	if err != nil || a != b {
+		return fmt.Errorf("format: internal error: formatting changed the program")
+	}
+	return nil
+}
+
+// program prints what a source parses to
+func program(source string) (string, error) {
+	prog, err := parser.Parse(source)
+	if err != nil {
+		return "", err
+	}
+	values, defs := prog.ToValues()
+	var b strings.Builder
+	for _, v := range values {
+		fmt.Fprintf(&b, "%s %T\n", v, v)
+	}
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s == %s\n", name, defs[name])
+	}
+	return b.String(), nil
+}
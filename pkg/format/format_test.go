@@ -0,0 +1,63 @@
+package format
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"spacing", "1   2+ [ dup  * ] 'foo\n", "1 2 + [dup *] 'foo\n"},
+		{"blank lines", "\n\n1 .\n\n\n\n2 .", "1 .\n\n2 .\n"},
+		{"aligned defines",
+			"DEFINE sq==[dup *].\nDEFINE cube == [dup sq *].\n",
+			"DEFINE sq   == [dup *].\nDEFINE cube == [dup sq *].\n"},
+		{"indent and comments",
+			"DEFINE f == [\n[0 =] % p\n      [dup 1 -]   % r\n% inner\nlinrec\n  ] .\n",
+			"DEFINE f == [\n    [0 =]      % p\n    [dup 1 -]  % r\n    % inner\n    linrec\n].\n"},
+		{"nested", "[\n[\n1\n]\n]", "[\n    [\n        1\n    ]\n]\n"},
+		{"strings kept", "\"a  b\"   .", "\"a  b\" .\n"},
+	}
+	for _, tt := range tests {
+		got, err := Source([]byte(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\ngot:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	if _, err := Source([]byte("DEFINE f == [1")); err == nil {
+		t.Error("Expected a parse error")
+	}
+}
+
+// TestExamplesIdempotent formats every example twice
+func TestExamplesIdempotent(t *testing.T) {
+	files, _ := filepath.Glob("../../examples/*.psil")
+	if len(files) == 0 {
+		t.Skip("no examples")
+	}
+	for _, path := range files {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		once, err := Source(src)
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+			continue
+		}
+		twice, err := Source(once)
+		if err != nil || string(twice) != string(once) {
+			t.Errorf("%s: formatting is not stable (%v)", path, err)
+		}
+	}
+}
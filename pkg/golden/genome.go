@@ -0,0 +1,178 @@
+package golden
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// The small world a genome is run in: worldNPCs copies of it, every other
+// one holding a tool, on a worldSize×worldSize map grown from worldSeed,
+// for worldTicks ticks. Every
+// run of the genome, there or on a sensor vector, gets gas.
+const (
+	worldSize  = 16
+	worldNPCs  = 8
+	worldTicks = 200
+	worldSeed  = 1
+	gas        = 200
+)
+
+// GenomeFiles lists the .hex genomes under root (a directory or a single
+// file) in lexical order
+func GenomeFiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".hex" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// ReadSensors reads the sensor vectors a genome is run on, one per line
+// as for sandbox.ParseSensors; blank lines and # comments are skipped
+func ReadSensors(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if _, err := sandbox.ParseSensors(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, sc.Err()
+}
+
+// RunGenome reports what the genome at path (hex on its first non-empty
+// line) does: the Ring1 outputs of each yield for every sensor vector,
+// then how a small world of its copies ends up.
+func RunGenome(path string, sensors []string) (string, error) {
+	genome, err := readHex(path)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	for _, line := range sensors {
+		vector, err := sandbox.ParseSensors(line)
+		if err != nil {
+			return "", err
+		}
+		var yields []string
+		for _, r1 := range sandbox.RunBrain(genome, vector, gas) {
+			yields = append(yields, ring1(r1))
+		}
+		fmt.Fprintf(&out, "%s: %s\n", line, strings.Join(yields, " | "))
+	}
+	writeWorld(&out, genome)
+	return out.String(), nil
+}
+
+// ring1 formats one set of Ring1 outputs, naming the slots that are set
+func ring1(r1 [sandbox.Ring1Count]int16) string {
+	var set []string
+	for i, v := range r1 {
+		if v != 0 {
+			name := strings.TrimPrefix(sandbox.MemoryName(byte(64+i)), "set-")
+			set = append(set, fmt.Sprintf("%s=%d", name, v))
+		}
+	}
+	if len(set) == 0 {
+		return "-"
+	}
+	return strings.Join(set, " ")
+}
+
+// writeWorld runs genome in the small world and writes what its NPCs
+// achieved, dead or alive, how many died of what, and the world's state
+// hash so any other difference shows too
+func writeWorld(out io.Writer, genome []byte) {
+	rng := rand.New(rand.NewSource(worldSeed))
+	w := sandbox.NewWorld(worldSize, rng)
+	s := sandbox.NewScheduler(w, gas, io.Discard)
+	var npcs []*sandbox.NPC
+	for i := 0; i < worldNPCs; i++ {
+		npc := sandbox.NewNPC(genome)
+		npc.X, npc.Y = rng.Intn(worldSize), rng.Intn(worldSize)
+		if i%2 == 0 {
+			npc.Item = sandbox.ItemTool // something to trade, craft and teach with
+		}
+		w.Spawn(npc)
+		npcs = append(npcs, npc)
+	}
+	for i := 0; i < worldTicks; i++ {
+		s.Tick()
+	}
+	var food, trades, crafts, taught, gold, kills int
+	for _, n := range npcs {
+		food += n.FoodEaten
+		trades += n.Trades
+		crafts += n.CraftCount
+		taught += n.TeachCount
+		gold += n.Gold
+		kills += n.Kills
+	}
+	fmt.Fprintf(out, "after %d ticks: %d alive, %d food eaten, %d trades, %d crafts, %d taught, %d gold, %d kills\n",
+		worldTicks, len(w.NPCs), food, trades, crafts, taught, gold, kills)
+	var deaths []string
+	for cause, n := range s.Deaths {
+		if n > 0 {
+			deaths = append(deaths, fmt.Sprintf("%d %s", n, sandbox.DeathCauseNames[cause]))
+		}
+	}
+	if len(deaths) > 0 {
+		fmt.Fprintf(out, "deaths: %s\n", strings.Join(deaths, ", "))
+	}
+	fmt.Fprintf(out, "state %016x\n", w.StateHash())
+}
+
+// readHex reads the hex genome on the first non-empty line of path
+func readHex(path string) ([]byte, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(string(src), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			genome, err := hex.DecodeString(line)
+			if err != nil {
+				return nil, fmt.Errorf("%s: bad hex: %v", path, err)
+			}
+			return genome, nil
+		}
+	}
+	return nil, fmt.Errorf("%s: no genome", path)
+}
+
+// CheckGenome runs the genome at path and compares what it does with the
+// record in its .expected file. With update it records it instead.
+func CheckGenome(path string, sensors []string, update bool) error {
+	got, err := RunGenome(path, sensors)
+	if err != nil {
+		return err
+	}
+	return compare(path, got, update)
+}
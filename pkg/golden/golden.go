@@ -0,0 +1,130 @@
+// Package golden runs PSIL programs and compares what they print with the
+// output recorded in a .expected file next to each, so the examples keep
+// working as the language changes. It does the same for sandbox genomes,
+// recording what each does on fixed sensor vectors and in a small world,
+// so their behaviour stays put as the engine changes.
+package golden
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/psilLang/psil/pkg/interpreter"
+)
+
+// Ext replaces .psil (or a genome's .hex) in the name of the recorded
+// output
+const Ext = ".expected"
+
+// Timeout bounds each run. A program that takes longer fails, as its
+// output would depend on the machine.
+var Timeout = 30 * time.Second
+
+// ExpectedPath is where the output of the program (or genome) at path is
+// recorded
+func ExpectedPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + Ext
+}
+
+// Files lists the .psil programs under root (a directory or a single file)
+// in lexical order
+func Files(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".psil" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	sort.Strings(files)
+	return files, err
+}
+
+// Run executes the program at path and returns what it prints. A run that
+// fails ends with an "Error: ..." line, like the psil command prints.
+// Files the program saves go to a temporary directory, which has an
+// output/ directory for the examples' images.
+func Run(path string) (string, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp("", "psil-golden")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Mkdir(filepath.Join(dir, "output"), 0o755); err != nil {
+		return "", err
+	}
+
+	var out bytes.Buffer
+	interp := interpreter.New(
+		interpreter.WithOutput(&out),
+		interpreter.WithDir(dir),
+		interpreter.WithTimeout(Timeout),
+	)
+	err = interp.RunString(context.Background(), string(src))
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("timed out after %v", Timeout)
+	}
+	if err != nil {
+		fmt.Fprintf(&out, "Error: %v\n", err)
+	}
+	return out.String(), nil
+}
+
+// Check runs the program at path and compares its output with the
+// recorded one. With update it records the output instead.
+func Check(path string, update bool) error {
+	got, err := Run(path)
+	if err != nil {
+		return err
+	}
+	return compare(path, got, update)
+}
+
+// compare checks got against the output recorded for path, or records it
+func compare(path, got string, update bool) error {
+	expected := ExpectedPath(path)
+	if update {
+		return os.WriteFile(expected, []byte(got), 0o644)
+	}
+	want, err := os.ReadFile(expected)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no %s; record it with -update", filepath.Base(expected))
+	}
+	if err != nil {
+		return err
+	}
+	if got != string(want) {
+		return fmt.Errorf("output differs from %s: %s", filepath.Base(expected), firstDiff(got, string(want)))
+	}
+	return nil
+}
+
+// firstDiff describes the first line where got and want differ
+func firstDiff(got, want string) string {
+	g, w := strings.Split(got, "\n"), strings.Split(want, "\n")
+	for n := 0; ; n++ {
+		switch {
+		case n >= len(g):
+			return fmt.Sprintf("line %d: missing %q", n+1, w[n])
+		case n >= len(w):
+			return fmt.Sprintf("line %d: unexpected %q", n+1, g[n])
+		case g[n] != w[n]:
+			return fmt.Sprintf("line %d: got %q, want %q", n+1, g[n], w[n])
+		}
+	}
+}
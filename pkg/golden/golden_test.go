@@ -0,0 +1,127 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+var update = flag.Bool("update", false, "record the current output in the .expected files")
+
+// TestExamples runs every example against its recorded output. After an
+// intended change: go test ./pkg/golden -update
+func TestExamples(t *testing.T) {
+	files, err := Files("../../examples")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skip("no examples")
+	}
+	for _, path := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".psil"), func(t *testing.T) {
+			if err := Check(path, *update); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestGenomes runs every reference genome against its recorded behaviour.
+// After an intended change: go test ./pkg/golden -run Genomes -update
+func TestGenomes(t *testing.T) {
+	sensors, err := ReadSensors("../../testdata/genomes/sensors.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := GenomeFiles("../../testdata/genomes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".hex"), func(t *testing.T) {
+			if err := CheckGenome(path, sensors, *update); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestVectors runs the Go/Z80 test vectors on the Go VM, both as written
+// in pkg/micro and as checked in to z80/vectors, and checks the files are
+// what micro-psil -gen-vectors writes. After changing the vectors:
+// go test ./pkg/golden -run Vectors -update
+func TestVectors(t *testing.T) {
+	const dir = "../../z80/vectors"
+	vectors, err := micro.Vectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range vectors {
+		if err := v.Check(); err != nil {
+			t.Error(err)
+		}
+	}
+	files, err := micro.VectorFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		for name, data := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	text, err := os.ReadFile(filepath.Join(dir, "vectors.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var checkedIn []micro.Vector
+	if err := json.Unmarshal(text, &checkedIn); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range checkedIn {
+		if err := v.Check(); err != nil {
+			t.Error(err)
+		}
+	}
+	for name, data := range files {
+		if got, _ := os.ReadFile(filepath.Join(dir, name)); !bytes.Equal(got, data) {
+			t.Errorf("%s is out of date; regenerate it with -update", name)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "p.psil")
+	if err := os.WriteFile(path, []byte(`"one" . "two" . 1 0 /`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(path, false); err == nil || !strings.Contains(err.Error(), "-update") {
+		t.Errorf("Expected a missing .expected file, got %v", err)
+	}
+	if err := Check(path, true); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	want := "one\ntwo\nError: error flag set: division by zero (code 3)\n"
+	if got, _ := os.ReadFile(ExpectedPath(path)); string(got) != want {
+		t.Errorf("Recorded %q, want %q", got, want)
+	}
+	if err := Check(path, false); err != nil {
+		t.Errorf("Expected a match, got %v", err)
+	}
+
+	os.WriteFile(ExpectedPath(path), []byte("one\n2\n"), 0o644)
+	err := Check(path, false)
+	if err == nil || !strings.Contains(err.Error(), `line 2: got "two", want "2"`) {
+		t.Errorf("Expected a difference on line 2, got %v", err)
+	}
+}
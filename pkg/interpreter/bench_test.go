@@ -0,0 +1,56 @@
+package interpreter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/psilLang/psil/pkg/parser"
+)
+
+// benchPSIL runs code b.N times, parsing it once
+func benchPSIL(b *testing.B, code string) {
+	b.Helper()
+	prog, err := parser.Parse(code)
+	if err != nil {
+		b.Fatal(err)
+	}
+	values, _ := prog.ToValues()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		interp := New()
+		if err := interp.Run(values); err != nil || interp.HasError() {
+			b.Fatalf("%v (A=%d)", err, interp.ARegister)
+		}
+	}
+}
+
+func BenchmarkMap(b *testing.B) {
+	benchPSIL(b, "1000000 iota [1 +] map drop")
+}
+
+func BenchmarkFold(b *testing.B) {
+	benchPSIL(b, "0 1000000 iota [+] fold drop")
+}
+
+// BenchmarkCons and BenchmarkConcat build a list an element at a time,
+// which is quadratic if every step copies the list so far
+func BenchmarkCons(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			benchPSIL(b, fmt.Sprintf("[] %d [0 swap cons] times drop", n))
+		})
+	}
+}
+
+func BenchmarkConcat(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			benchPSIL(b, fmt.Sprintf("[] %d [[0] concat] times drop", n))
+		})
+	}
+}
+
+func BenchmarkStringBuffer(b *testing.B) {
+	benchPSIL(b, `sbuf-new 100000 ["line " sbuf-add 7 sbuf-add sbuf-newline] times sbuf-string drop`)
+}
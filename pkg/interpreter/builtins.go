@@ -6,6 +6,8 @@ import (
 	"image/png"
 	"math"
 	"os"
+	"path/filepath"
+	"sort"
 
 	"github.com/psilLang/psil/pkg/types"
 )
@@ -101,6 +103,18 @@ func (i *Interpreter) RegisterBuiltins() {
 	i.registerBuiltin("index", builtinIndex)
 	i.registerBuiltin("sort", builtinSort)
 	i.registerBuiltin("last", builtinLast)
+	i.registerBuiltin("sort-asc", builtinSortAsc)   // numbers only, no comparator
+	i.registerBuiltin("sort-desc", builtinSortDesc) // numbers only, no comparator
+	i.registerBuiltin("sum", builtinSum)
+	i.registerBuiltin("product", builtinProduct)
+	i.registerBuiltin("lmin", builtinListMin) // list-min, not min of two
+	i.registerBuiltin("lmax", builtinListMax) // list-max, not max of two
+
+	// Lazy sequences (streams)
+	i.registerBuiltin("lazy-range", builtinLazyRange)
+	i.registerBuiltin("lazy-map", builtinLazyMap)
+	i.registerBuiltin("lazy-filter", builtinLazyFilter)
+	i.registerBuiltin("force", builtinForce)
 
 	// I/O
 	i.registerBuiltin(".", builtinPrint)
@@ -108,6 +122,12 @@ func (i *Interpreter) RegisterBuiltins() {
 	i.registerBuiltin("newline", builtinNewline)
 	i.registerBuiltin("stack", builtinShowStack)
 
+	// String buffers (building text without quadratic copying)
+	i.registerBuiltin("sbuf-new", builtinSbufNew)
+	i.registerBuiltin("sbuf-add", builtinSbufAdd)
+	i.registerBuiltin("sbuf-newline", builtinSbufNewline)
+	i.registerBuiltin("sbuf-string", builtinSbufString)
+
 	// Error handling
 	i.registerBuiltin("err?", builtinErrQ)
 	i.registerBuiltin("errcode", builtinErrCode)
@@ -154,6 +174,21 @@ func (i *Interpreter) RegisterBuiltins() {
 	i.Define("e", types.Number(math.E))
 	i.Define("tau", types.Number(math.Pi*2))
 
+	// Vectors and matrices (native, for shaders)
+	i.registerBuiltin("vec2", builtinVec2)
+	i.registerBuiltin("vec3", builtinVec3)
+	i.registerBuiltin("mat3", builtinMat3)
+	i.registerBuiltin("unvec", builtinUnvec)
+	i.registerBuiltin("vec?", builtinIsVector)
+	i.registerBuiltin("vec-add", builtinVecAdd)
+	i.registerBuiltin("vec-sub", builtinVecSub)
+	i.registerBuiltin("vec-scale", builtinVecScale)
+	i.registerBuiltin("vec-dot", builtinVecDot)
+	i.registerBuiltin("vec-cross", builtinVecCross)
+	i.registerBuiltin("vec-length", builtinVecLength)
+	i.registerBuiltin("vec-normalize", builtinVecNormalize)
+	i.registerBuiltin("mat-mul", builtinMatMul)
+
 	// Graphics operations
 	i.registerBuiltin("img-new", builtinImgNew)
 	i.registerBuiltin("img-setpixel", builtinImgSetPixel)
@@ -185,9 +220,32 @@ func (i *Interpreter) RegisterBuiltins() {
 	i.registerBuiltin("setheading", builtinSetHeading) // turtle angle -> turtle
 	i.registerBuiltin("turtle-img", builtinTurtleImg) // turtle -> image (get canvas)
 	i.registerBuiltin("turtle?", builtinIsTurtle)    // value -> bool
+
+	// Sockets (line-based network clients)
+	i.registerBuiltin("socket-connect", builtinSocketConnect) // address -> socket
+	i.registerBuiltin("socket-send", builtinSocketSend)       // socket string -> socket
+	i.registerBuiltin("socket-recv", builtinSocketRecv)       // socket -> socket string
+	i.registerBuiltin("socket-close", builtinSocketClose)     // socket ->
+
+	// Subprocesses
+	i.registerBuiltin("exec", builtinExec) // command [args] -> code stdout stderr
 }
 
 func (i *Interpreter) registerBuiltin(name string, fn func(*Interpreter) error) {
+	i.RegisterWord(name, builtinCapability[name], fn)
+}
+
+// RegisterWord adds a builtin word, the way extensions (such as cmd/psil
+// plugins) add their own. A word needing a capability (CapNone for none) is
+// left out when it is disabled, and checks it again on every call, against
+// the interpreter it runs in.
+func (i *Interpreter) RegisterWord(name string, need Capability, fn func(*Interpreter) error) {
+	if need != 0 {
+		if !i.Allowed(need) {
+			return
+		}
+		fn = guarded(name, need, fn)
+	}
 	i.Dictionary[name] = &types.Builtin{
 		Name: name,
 		Fn: func(interp interface{}) error {
@@ -716,10 +774,7 @@ func builtinConcat(i *Interpreter) error {
 	if !ok {
 		return nil
 	}
-	items := make([]types.Value, 0, len(a.Items)+len(b.Items))
-	items = append(items, a.Items...)
-	items = append(items, b.Items...)
-	i.Push(&types.Quotation{Items: items})
+	i.Push(types.Concat(a, b))
 	return nil
 }
 
@@ -733,10 +788,7 @@ func builtinCons(i *Interpreter) error {
 	if v == nil {
 		return nil
 	}
-	items := make([]types.Value, 0, len(q.Items)+1)
-	items = append(items, v)
-	items = append(items, q.Items...)
-	i.Push(&types.Quotation{Items: items})
+	i.Push(types.Cons(v, q))
 	return nil
 }
 
@@ -829,11 +881,9 @@ func builtinPrint(i *Interpreter) error {
 	}
 	// Print strings without quotes
 	if s, ok := v.(types.String); ok {
-		fmt.Fprintln(i.Output, string(s))
-	} else {
-		fmt.Fprintln(i.Output, v.String())
+		return i.write(string(s) + "\n")
 	}
-	return nil
+	return i.write(v.String() + "\n")
 }
 
 func builtinPrintNoNL(i *Interpreter) error {
@@ -842,21 +892,17 @@ func builtinPrintNoNL(i *Interpreter) error {
 		return nil
 	}
 	if s, ok := v.(types.String); ok {
-		fmt.Fprint(i.Output, string(s))
-	} else {
-		fmt.Fprint(i.Output, v.String())
+		return i.write(string(s))
 	}
-	return nil
+	return i.write(v.String())
 }
 
 func builtinNewline(i *Interpreter) error {
-	fmt.Fprintln(i.Output)
-	return nil
+	return i.write("\n")
 }
 
 func builtinShowStack(i *Interpreter) error {
-	fmt.Fprintln(i.Output, i.StackString())
-	return nil
+	return i.write(i.StackString() + "\n")
 }
 
 // === Error handling ===
@@ -974,11 +1020,17 @@ func builtinNth(i *Interpreter) error {
 }
 
 // take - take first n elements: [list] n take
+// Taken from a stream, they stay a stream: nothing runs until force
 func builtinTake(i *Interpreter) error {
 	n, ok := i.PopNumber()
 	if !ok {
 		return nil
 	}
+	if s, ok := i.Peek().(*types.Stream); ok {
+		i.Pop()
+		takeStream(i, s, int(n))
+		return nil
+	}
 	q, ok := i.PopQuotation()
 	if !ok {
 		return nil
@@ -1353,6 +1405,109 @@ func builtinLast(i *Interpreter) error {
 	return nil
 }
 
+// popNumbers pops a list whose elements must all be numbers
+func popNumbers(i *Interpreter) ([]float64, bool) {
+	q, ok := i.PopQuotation()
+	if !ok {
+		return nil, false
+	}
+	xs := make([]float64, len(q.Items))
+	for n, item := range q.Items {
+		x, ok := item.(types.Number)
+		if !ok {
+			i.SetError(types.ErrTypeMismatch)
+			return nil, false
+		}
+		xs[n] = float64(x)
+	}
+	return xs, true
+}
+
+func pushNumbers(i *Interpreter, xs []float64) {
+	items := make([]types.Value, len(xs))
+	for n, x := range xs {
+		items[n] = types.Number(x)
+	}
+	i.Push(&types.Quotation{Items: items})
+}
+
+// sort-asc - sort a list of numbers, smallest first, natively
+func builtinSortAsc(i *Interpreter) error {
+	xs, ok := popNumbers(i)
+	if !ok {
+		return nil
+	}
+	sort.Float64s(xs)
+	pushNumbers(i, xs)
+	return nil
+}
+
+// sort-desc - sort a list of numbers, largest first, natively
+func builtinSortDesc(i *Interpreter) error {
+	xs, ok := popNumbers(i)
+	if !ok {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(xs)))
+	pushNumbers(i, xs)
+	return nil
+}
+
+// sum - add up a list of numbers (0 for an empty list)
+func builtinSum(i *Interpreter) error {
+	xs, ok := popNumbers(i)
+	if !ok {
+		return nil
+	}
+	total := 0.0
+	for _, x := range xs {
+		total += x
+	}
+	i.Push(types.Number(total))
+	return nil
+}
+
+// product - multiply a list of numbers (1 for an empty list)
+func builtinProduct(i *Interpreter) error {
+	xs, ok := popNumbers(i)
+	if !ok {
+		return nil
+	}
+	total := 1.0
+	for _, x := range xs {
+		total *= x
+	}
+	i.Push(types.Number(total))
+	return nil
+}
+
+// lmin - smallest number in a list
+func builtinListMin(i *Interpreter) error {
+	return listExtreme(i, math.Min)
+}
+
+// lmax - largest number in a list
+func builtinListMax(i *Interpreter) error {
+	return listExtreme(i, math.Max)
+}
+
+func listExtreme(i *Interpreter, pick func(a, b float64) float64) error {
+	xs, ok := popNumbers(i)
+	if !ok {
+		return nil
+	}
+	if len(xs) == 0 {
+		i.SetError(types.ErrInvalidQuotation)
+		return nil
+	}
+	best := xs[0]
+	for _, x := range xs[1:] {
+		best = pick(best, x)
+	}
+	i.Push(types.Number(best))
+	return nil
+}
+
 // === Math functions ===
 
 func builtinSin(i *Interpreter) error {
@@ -1687,7 +1842,7 @@ func builtinImgSave(i *Interpreter) error {
 	if !ok {
 		return nil
 	}
-	file, err := os.Create(string(filename))
+	file, err := os.Create(i.path(string(filename)))
 	if err != nil {
 		i.SetError(types.ErrFileError)
 		return nil
@@ -1697,8 +1852,15 @@ func builtinImgSave(i *Interpreter) error {
 		i.SetError(types.ErrImageError)
 		return nil
 	}
-	fmt.Fprintf(i.Output, "Saved: %s\n", filename)
-	return nil
+	return i.write(fmt.Sprintf("Saved: %s\n", filename))
+}
+
+// path resolves a file name a program uses against the WithDir directory
+func (i *Interpreter) path(name string) string {
+	if i.dir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(i.dir, name)
 }
 
 // img-width: image -> width
@@ -2041,6 +2203,11 @@ func builtinImgRender(i *Interpreter) error {
 	height := img.Height
 
 	for py := 0; py < height; py++ {
+		// Stop between rows once the run is canceled, even if the shader
+		// never reaches Execute
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
 		for px := 0; px < width; px++ {
 			// Push x, y, width, height for the shader
 			i.Push(types.Number(px))
@@ -0,0 +1,76 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// ErrInterrupted ends a run stopped by Interrupt
+var ErrInterrupted = errors.New("interrupted")
+
+// RunContext is Run that stops once ctx is done: the run ends with
+// ctx.Err() and the error flag set to ErrCanceled. Cancelation is checked
+// before every step and every turn of a looping combinator, so runaway
+// loops stop too.
+func (i *Interpreter) RunContext(ctx context.Context, values []types.Value) error {
+	defer i.bind(ctx)()
+	return i.Run(values)
+}
+
+// Interrupt stops the run in progress, from any goroutine: it ends with
+// ErrInterrupted and the error flag set to ErrCanceled. It has no effect
+// on later runs.
+func (i *Interpreter) Interrupt() {
+	i.interrupt.Store(true)
+}
+
+// bind makes ctx the context of the current run and returns the function
+// restoring the previous one
+func (i *Interpreter) bind(ctx context.Context) func() {
+	saved := i.ctx
+	i.ctx = ctx
+	return func() { i.ctx = saved }
+}
+
+// begin starts a run and returns the function ending it. The outermost
+// run clears any earlier Interrupt and applies the WithTimeout deadline.
+func (i *Interpreter) begin() func() {
+	i.depth++
+	if i.depth > 1 {
+		return func() { i.depth-- }
+	}
+	i.interrupt.Store(false)
+	saved, cancel := i.ctx, context.CancelFunc(func() {})
+	if i.timeout > 0 {
+		parent := i.ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+		i.ctx, cancel = context.WithTimeout(parent, i.timeout)
+	}
+	return func() {
+		cancel()
+		i.ctx = saved
+		i.depth--
+		i.interrupt.Store(false)
+	}
+}
+
+// checkCanceled sets the error flag and returns the reason once the run
+// is interrupted or its context is done
+func (i *Interpreter) checkCanceled() error {
+	if i.interrupt.Load() {
+		i.SetError(types.ErrCanceled)
+		return ErrInterrupted
+	}
+	if i.ctx == nil {
+		return nil
+	}
+	if err := i.ctx.Err(); err != nil {
+		i.SetError(types.ErrCanceled)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// Capability is a group of builtins that reach outside the interpreter.
+// The core language (stack, arithmetic, quotations, combinators, math) is
+// always available.
+type Capability uint
+
+const (
+	// CapIO is console output: . print newline stack
+	CapIO Capability = 1 << iota
+	// CapGraphics is in-memory images and turtles
+	CapGraphics
+	// CapFiles is file access: img-save
+	CapFiles
+	// CapNetwork is network access: socket-connect socket-send socket-recv
+	// socket-close. Builtins an embedder adds can check it with Allowed.
+	// Off unless asked for with WithCapabilities.
+	CapNetwork
+	// CapExec is running other programs: exec. Off unless asked for with
+	// WithCapabilities.
+	CapExec
+
+	// CapNone enables only the core language
+	CapNone Capability = 0
+	// CapAll enables every group
+	CapAll = CapIO | CapGraphics | CapFiles | CapNetwork | CapExec
+	// CapDefault is what New enables without WithCapabilities
+	CapDefault = CapAll &^ capOptIn
+
+	// capOptIn are the groups only WithCapabilities turns on
+	capOptIn = CapNetwork | CapExec
+)
+
+// capabilityWords lists the builtins each capability enables
+var capabilityWords = map[Capability][]string{
+	CapIO: {".", "print", "newline", "stack"},
+	CapGraphics: {
+		"img-new", "img-setpixel", "img-getpixel", "img-width", "img-height",
+		"img-fill", "image?", "img-render",
+		"turtle", "fd", "forward", "bk", "back", "lt", "left", "rt", "right",
+		"pu", "penup", "pd", "pendown", "pencolor", "home", "setxy",
+		"setheading", "turtle-img", "turtle?",
+	},
+	CapFiles:   {"img-save"},
+	CapNetwork: {"socket-connect", "socket-send", "socket-recv", "socket-close"},
+	CapExec:    {"exec"},
+}
+
+// builtinCapability maps each guarded builtin to the capability it needs
+var builtinCapability = map[string]Capability{}
+
+func init() {
+	for c, words := range capabilityWords {
+		for _, name := range words {
+			builtinCapability[name] = c
+		}
+	}
+}
+
+// String names the groups in c, e.g. "files|network"
+func (c Capability) String() string {
+	var names []string
+	for _, g := range []struct {
+		c    Capability
+		name string
+	}{{CapIO, "io"}, {CapGraphics, "graphics"}, {CapFiles, "files"}, {CapNetwork, "network"}, {CapExec, "exec"}} {
+		if c&g.c != 0 {
+			names = append(names, g.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// Sandbox restricts what a script can reach. Together with WithGas and a
+// RunContext deadline it makes user-submitted PSIL safe to evaluate.
+type Sandbox struct {
+	NoFilesystem   bool // no file access (img-save)
+	NoNetwork      bool // no network access
+	NoImages       bool // no images or turtles
+	MaxOutputBytes int  // console output until Reset (0 = unlimited)
+}
+
+// WithSandbox applies the restrictions of s on top of any other options.
+// A sandboxed script never runs other programs.
+func WithSandbox(s Sandbox) Option {
+	return func(i *Interpreter) {
+		i.denied |= CapExec
+		if s.NoFilesystem {
+			i.denied |= CapFiles
+		}
+		if s.NoNetwork {
+			i.denied |= CapNetwork
+		}
+		if s.NoImages {
+			i.denied |= CapGraphics
+		}
+		i.maxOutput = s.MaxOutputBytes
+	}
+}
+
+// Allowed reports whether every group in c is enabled
+func (i *Interpreter) Allowed(c Capability) bool {
+	return i.denied&c == 0 && i.granted&c&capOptIn == c&capOptIn
+}
+
+// guarded wraps a builtin with a call-time check of its capability, which
+// also covers a builtin value carried over from a less restricted
+// interpreter
+func guarded(name string, need Capability, fn func(*Interpreter) error) func(*Interpreter) error {
+	return func(i *Interpreter) error {
+		if !i.Allowed(need) {
+			i.SetError(types.ErrDenied)
+			return fmt.Errorf("%s: %v capability disabled", name, need)
+		}
+		return fn(i)
+	}
+}
+
+// write prints s to Output within the output budget. Output past the
+// budget is dropped and ends the run with ErrOutputLimit.
+func (i *Interpreter) write(s string) error {
+	if i.maxOutput > 0 {
+		if i.outputUsed+len(s) > i.maxOutput {
+			i.SetError(types.ErrOutputLimit)
+			return fmt.Errorf("output limit of %d bytes exceeded", i.maxOutput)
+		}
+		i.outputUsed += len(s)
+	}
+	io.WriteString(i.Output, s)
+	return nil
+}
@@ -183,6 +183,9 @@ func linrecHelper(i *Interpreter, p, t, r1, r2 *types.Quotation) error {
 	if !i.ConsumeGas(1) {
 		return nil
 	}
+	if err := i.checkCanceled(); err != nil {
+		return err
+	}
 
 	// Save stack for condition
 	savedStack := make([]types.Value, len(i.Stack))
@@ -246,6 +249,9 @@ func binrecHelper(i *Interpreter, p, t, r1, r2 *types.Quotation) error {
 	if !i.ConsumeGas(1) {
 		return nil
 	}
+	if err := i.checkCanceled(); err != nil {
+		return err
+	}
 
 	// Save stack
 	savedStack := make([]types.Value, len(i.Stack))
@@ -322,6 +328,9 @@ func genrecHelper(i *Interpreter, p, t, r1, r2 *types.Quotation) error {
 	if !i.ConsumeGas(1) {
 		return nil
 	}
+	if err := i.checkCanceled(); err != nil {
+		return err
+	}
 
 	// Save stack
 	savedStack := make([]types.Value, len(i.Stack))
@@ -413,6 +422,9 @@ func builtinTailrec(i *Interpreter) error {
 		if !i.ConsumeGas(1) {
 			return nil
 		}
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
 
 		// Save stack
 		savedStack := make([]types.Value, len(i.Stack))
@@ -459,6 +471,9 @@ func builtinTimes(i *Interpreter) error {
 		if !i.ConsumeGas(1) {
 			return nil
 		}
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
 		if err := i.ExecuteQuotation(q); err != nil {
 			return err
 		}
@@ -484,6 +499,9 @@ func builtinWhile(i *Interpreter) error {
 		if !i.ConsumeGas(1) {
 			return nil
 		}
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
 
 		// Check condition
 		if err := i.ExecuteQuotation(cond); err != nil {
@@ -524,6 +542,9 @@ func builtinLoop(i *Interpreter) error {
 		if !i.ConsumeGas(1) {
 			return nil
 		}
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
 		if err := i.ExecuteQuotation(body); err != nil {
 			return err
 		}
@@ -0,0 +1,293 @@
+package interpreter
+
+import "strings"
+
+// WordDoc returns the stack effect and a short description of a builtin
+// word, for editors and help screens
+func WordDoc(name string) (string, bool) {
+	doc, ok := wordDocs[name]
+	return doc, ok
+}
+
+// WordEffect returns how many values a builtin takes from the stack and how
+// many it leaves, as its doc states them. Words whose effect depends on
+// their arguments, such as the combinators, report false.
+func WordEffect(name string) (in, out int, ok bool) {
+	doc, ok := wordDocs[name]
+	if !ok || !strings.HasPrefix(doc, "(") {
+		return 0, 0, false
+	}
+	depth := 0
+	end := strings.IndexFunc(doc, func(r rune) bool {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		return depth == 0
+	})
+	if end < 0 {
+		return 0, 0, false
+	}
+	before, after, found := strings.Cut(doc[1:end], " -- ")
+	if !found {
+		return 0, 0, false
+	}
+	in, okIn := countItems(before)
+	out, okOut := countItems(after)
+	return in, out, okIn && okOut
+}
+
+// countItems counts the values in one side of a stack effect; a bracketed
+// list is one value, and "..." or an alternative "|" makes it unknown
+func countItems(side string) (int, bool) {
+	n, depth := 0, 0
+	for _, field := range strings.Fields(side) {
+		if depth == 0 {
+			if field == "..." || field == "|" {
+				return 0, false
+			}
+			n++
+		}
+		depth += strings.Count(field, "[") - strings.Count(field, "]")
+	}
+	return n, true
+}
+
+// wordDocs documents every builtin: "( before -- after ) description"
+var wordDocs = map[string]string{
+	// Stack manipulation
+	"dup":    "( a -- a a ) duplicate the top value",
+	"drop":   "( a -- ) discard the top value",
+	"pop":    "( a -- ) discard the top value (alias of drop)",
+	"swap":   "( a b -- b a ) swap the top two values",
+	"over":   "( a b -- a b a ) copy the second value to the top",
+	"rot":    "( a b c -- b c a ) rotate the third value to the top",
+	"nip":    "( a b -- b ) discard the second value",
+	"tuck":   "( a b -- b a b ) copy the top value below the second",
+	"dup2":   "( a b -- a b a b ) duplicate the top two values",
+	"drop2":  "( a b -- ) discard the top two values",
+	"clear":  "( ... -- ) empty the stack",
+	"depth":  "( -- n ) push the number of values on the stack",
+	"roll":   "( ... n -- ... ) rotate the top n values, bringing the nth to the top",
+	"unroll": "( ... n -- ... ) rotate the top n values the other way, putting the top at the nth place",
+	"pick":   "( ... n -- ... x ) copy the nth value (0 = top) to the top",
+
+	// Arithmetic
+	"+":   "( a b -- a+b ) add",
+	"add": "( a b -- a+b ) add",
+	"-":   "( a b -- a-b ) subtract",
+	"sub": "( a b -- a-b ) subtract",
+	"*":   "( a b -- a*b ) multiply",
+	"mul": "( a b -- a*b ) multiply",
+	"/":   "( a b -- a/b ) divide; division by zero sets error 3",
+	"div": "( a b -- a/b ) divide; division by zero sets error 3",
+	"mod": "( a b -- a%b ) remainder",
+	"%":   "( a b -- a%b ) remainder",
+	"neg": "( a -- -a ) negate",
+	"abs": "( a -- |a| ) absolute value",
+	"inc": "( a -- a+1 ) increment",
+	"dec": "( a -- a-1 ) decrement",
+
+	// Comparison and logic (set the Z flag too)
+	"<":   "( a b -- bool ) less than; sets Z",
+	">":   "( a b -- bool ) greater than; sets Z",
+	"<=":  "( a b -- bool ) less or equal; sets Z",
+	">=":  "( a b -- bool ) greater or equal; sets Z",
+	"=":   "( a b -- bool ) equal; sets Z",
+	"!=":  "( a b -- bool ) not equal; sets Z",
+	"eq":  "( a b -- bool ) equal; sets Z",
+	"neq": "( a b -- bool ) not equal; sets Z",
+	"and": "( a b -- bool ) logical and; sets Z",
+	"or":  "( a b -- bool ) logical or; sets Z",
+	"not": "( a -- bool ) logical not; sets Z",
+
+	// Type predicates
+	"number?":    "( x -- bool ) is x a number",
+	"string?":    "( x -- bool ) is x a string",
+	"boolean?":   "( x -- bool ) is x a boolean",
+	"quotation?": "( x -- bool ) is x a quotation",
+	"symbol?":    "( x -- bool ) is x a symbol",
+	"image?":     "( x -- bool ) is x an image",
+	"turtle?":    "( x -- bool ) is x a turtle",
+
+	// Quotations
+	"i":       "( [Q] -- ... ) execute a quotation",
+	"call":    "( [Q] -- ... ) execute a quotation (alias of i)",
+	"x":       "( [Q] -- [Q] ... ) execute a quotation, keeping it below",
+	"dip":     "( a [Q] -- ... a ) execute Q with a set aside",
+	"concat":  "( [A] [B] -- [A B] ) join two quotations",
+	"cons":    "( a [Q] -- [a Q] ) prepend a value to a quotation",
+	"uncons":  "( [a Q] -- a [Q] ) split off the first element",
+	"first":   "( [a Q] -- a ) first element",
+	"rest":    "( [a Q] -- [Q] ) all but the first element",
+	"size":    "( [Q] -- n ) number of elements",
+	"length":  "( [Q] -- n ) number of elements (alias of size)",
+	"null?":   "( [Q] -- bool ) is the quotation empty",
+	"empty?":  "( [Q] -- bool ) is the quotation empty (alias of null?)",
+	"quote":   "( a -- [a] ) wrap a value in a quotation",
+	"unit":    "( a -- [a] ) wrap a value in a quotation (alias of quote)",
+	"reverse": "( [Q] -- [Q'] ) reverse a list",
+	"nth":     "( [Q] n -- x ) element n (from 0)",
+	"take":    "( [Q] n -- [Q'] ) the first n elements (of a stream, lazily)",
+	"ldrop":   "( [Q] n -- [Q'] ) all but the first n elements",
+	"split":   "( [Q] n -- [first-n] [rest] ) split a list at n",
+	"zip":     "( [A] [B] -- [[a1 b1] ...] ) pair up two lists",
+	"zipwith": "( [A] [B] [Q] -- [Q'] ) combine two lists element by element with Q",
+	"range":   "( start end -- [start ... end-1] ) list of numbers",
+	"iota":    "( n -- [0 ... n-1] ) list of numbers",
+	"flatten": "( [Q] -- [Q'] ) flatten nested lists one level",
+	"any":     "( [Q] [P] -- bool ) does any element satisfy P",
+	"all":     "( [Q] [P] -- bool ) do all elements satisfy P",
+	"find":    "( [Q] [P] -- x true | false ) first element satisfying P",
+	"index":   "( [Q] [P] -- n ) index of the first element satisfying P, or -1",
+	"sort":    "( [Q] [C] -- [Q'] ) sort with C: a b C -- bool, true if a < b",
+	"last":    "( [Q] -- x ) last element",
+
+	// Lists of numbers (native, no quotation calls)
+	"sort-asc":  "( [Q] -- [Q'] ) sort numbers, smallest first",
+	"sort-desc": "( [Q] -- [Q'] ) sort numbers, largest first",
+	"sum":       "( [Q] -- n ) sum of the numbers (0 if empty)",
+	"product":   "( [Q] -- n ) product of the numbers (1 if empty)",
+	"lmin":      "( [Q] -- n ) smallest number in a non-empty list",
+	"lmax":      "( [Q] -- n ) largest number in a non-empty list",
+
+	// Lazy sequences
+	"lazy-range":  "( start -- stream ) start, start+1, ... without end",
+	"lazy-map":    "( stream [Q] -- stream ) apply Q to each element as it is pulled",
+	"lazy-filter": "( stream [P] -- stream ) keep the elements satisfying P, as they are pulled",
+	"force":       "( stream -- [L] ) run a stream to its end and collect its elements",
+
+	// Combinators
+	"ifte":    "( [C] [T] [E] -- ... ) run T if C yields true (or sets Z), else E",
+	"ifelse":  "( [C] [T] [E] -- ... ) if-then-else (alias of ifte)",
+	"branch":  "( [C] [T] [E] -- ... ) if-then-else (alias of ifte)",
+	"if":      "( [C] [T] -- ... ) run T if C yields true (or sets Z)",
+	"choice":  "( a b bool -- a|b ) a if true, else b",
+	"linrec":  "( [P] [T] [R1] [R2] -- ... ) linear recursion: T if P, else R1, recurse, R2",
+	"binrec":  "( [P] [T] [R1] [R2] -- ... ) binary recursion: T if P, else R1 splits in two, recurse on each, R2 combines",
+	"genrec":  "( [P] [T] [R1] [R2] -- ... ) general recursion: T if P, else R1, then R2 with the recursion as a quotation",
+	"primrec": "( n [B] [C] -- ... ) primitive recursion: B for 0, else recurse on n-1 and C with n",
+	"tailrec": "( [P] [T] [R] -- ... ) tail recursion: T if P, else R and repeat",
+	"times":   "( n [Q] -- ... ) run Q n times",
+	"while":   "( [C] [Q] -- ... ) run Q while C yields true",
+	"loop":    "( [Q] -- ... ) run Q until an error, gas or cancelation stops it",
+	"map":     "( [L] [Q] -- [L'] ) apply Q to each element",
+	"fold":    "( init [L] [Q] -- acc ) fold with Q: acc x -- acc",
+	"filter":  "( [L] [P] -- [L'] ) keep the elements satisfying P",
+	"each":    "( [L] [Q] -- ... ) run Q on each element",
+	"step":    "( [L] [Q] -- ... ) run Q on each element (alias of each)",
+	"infra":   "( [L] [Q] -- [L'] ) run Q with the list as the stack",
+	"cleave":  "( x [[Q1] [Q2] ...] -- x r1 r2 ... ) apply each quotation to x",
+	"spread":  "( a b ... [[Q1] [Q2] ...] -- ... ) apply each quotation to its own value",
+	"apply":   "( [args] [Q] -- ... ) push the arguments and run Q",
+	"onerr":   "( [H] -- ... ) if the error flag is set, clear it and run H with the code",
+	"try":     "( [B] [H] -- ... ) run B; on error clear it and run H with the code",
+
+	// I/O
+	".":       "( x -- ) print a value and a newline",
+	"print":   "( x -- ) print a value",
+	"newline": "( -- ) print a newline",
+	"stack":   "( -- ) print the stack",
+
+	// String buffers
+	"sbuf-new":     "( -- sbuf ) an empty string buffer",
+	"sbuf-add":     "( sbuf x -- sbuf ) append x as print would write it",
+	"sbuf-newline": "( sbuf -- sbuf ) append a newline",
+	"sbuf-string":  "( sbuf -- s ) the text so far",
+
+	// Flags and errors
+	"err?":     "( -- bool ) is the error flag (C) set",
+	"errcode":  "( -- n ) the error code (A register)",
+	"clearerr": "( -- ) clear the error flag",
+	"z?":       "( -- bool ) is the Z flag set",
+	"setz":     "( -- ) set the Z flag",
+	"clrz":     "( -- ) clear the Z flag",
+	"true":     "( -- true ) the boolean true",
+	"false":    "( -- false ) the boolean false",
+
+	// Definitions
+	"define":   "( [Q] name -- ) define name as Q",
+	"undefine": "( name -- ) remove a definition",
+
+	// Math
+	"sin":        "( a -- sin(a) ) sine (radians)",
+	"cos":        "( a -- cos(a) ) cosine (radians)",
+	"tan":        "( a -- tan(a) ) tangent (radians)",
+	"asin":       "( a -- asin(a) ) arc sine",
+	"acos":       "( a -- acos(a) ) arc cosine",
+	"atan":       "( a -- atan(a) ) arc tangent",
+	"atan2":      "( y x -- angle ) arc tangent of y/x",
+	"sqrt":       "( a -- sqrt(a) ) square root",
+	"pow":        "( base exp -- base^exp ) power",
+	"exp":        "( a -- e^a ) exponential",
+	"log":        "( a -- ln(a) ) natural logarithm",
+	"floor":      "( a -- n ) round down",
+	"ceil":       "( a -- n ) round up",
+	"round":      "( a -- n ) round to nearest",
+	"min":        "( a b -- min ) smaller of two",
+	"max":        "( a b -- max ) larger of two",
+	"clamp":      "( x lo hi -- x' ) limit x to lo..hi",
+	"lerp":       "( a b t -- a+(b-a)t ) linear interpolation",
+	"sign":       "( a -- -1|0|1 ) sign",
+	"fract":      "( a -- a-floor(a) ) fractional part",
+	"smoothstep": "( e0 e1 x -- y ) smooth Hermite interpolation between edges",
+	"pi":         "( -- 3.14159... ) pi",
+	"e":          "( -- 2.71828... ) Euler's number",
+	"tau":        "( -- 6.28318... ) two pi",
+
+	// Vectors and matrices
+	"vec2":          "( x y -- v ) a 2D vector",
+	"vec3":          "( x y z -- v ) a 3D vector",
+	"mat3":          "( r0 r1 r2 -- m ) a 3x3 matrix from three vec3 rows",
+	"unvec":         "( v -- x y ... ) a vector's elements, or a matrix's rows",
+	"vec?":          "( x -- bool ) is x a vector or matrix",
+	"vec-add":       "( a b -- a+b ) add vectors (or matrices) of the same shape",
+	"vec-sub":       "( a b -- a-b ) subtract vectors (or matrices) of the same shape",
+	"vec-scale":     "( v s -- v*s ) multiply each element by s",
+	"vec-dot":       "( a b -- n ) dot product",
+	"vec-cross":     "( a b -- axb ) cross product of two vec3s",
+	"vec-length":    "( v -- n ) length",
+	"vec-normalize": "( v -- v' ) unit vector in the same direction (zero stays zero)",
+	"mat-mul":       "( m x -- mx ) multiply a matrix by a matrix or vector",
+
+	// Images
+	"img-new":      "( w h -- image ) a black image",
+	"img-setpixel": "( image x y r g b -- image ) set a pixel",
+	"img-getpixel": "( image x y -- r g b ) read a pixel",
+	"img-save":     "( image filename -- ) write a PNG",
+	"img-width":    "( image -- w ) width",
+	"img-height":   "( image -- h ) height",
+	"img-fill":     "( image r g b -- image ) fill with a color",
+	"img-render":   "( image [S] -- image ) color each pixel with S: x y w h -- r g b",
+
+	// Turtles
+	"turtle":     "( image -- turtle ) a turtle at the center of an image",
+	"fd":         "( turtle n -- turtle ) move forward",
+	"forward":    "( turtle n -- turtle ) move forward",
+	"bk":         "( turtle n -- turtle ) move back",
+	"back":       "( turtle n -- turtle ) move back",
+	"lt":         "( turtle deg -- turtle ) turn left",
+	"left":       "( turtle deg -- turtle ) turn left",
+	"rt":         "( turtle deg -- turtle ) turn right",
+	"right":      "( turtle deg -- turtle ) turn right",
+	"pu":         "( turtle -- turtle ) pen up",
+	"penup":      "( turtle -- turtle ) pen up",
+	"pd":         "( turtle -- turtle ) pen down",
+	"pendown":    "( turtle -- turtle ) pen down",
+	"pencolor":   "( turtle r g b -- turtle ) set the pen color",
+	"home":       "( turtle -- turtle ) back to the center, facing up",
+	"setxy":      "( turtle x y -- turtle ) move without drawing",
+	"setheading": "( turtle deg -- turtle ) face a direction",
+	"turtle-img": "( turtle -- image ) the turtle's canvas",
+
+	// Sockets
+	"socket-connect": "( address -- socket ) connect to host:port, or unix:PATH",
+	"socket-send":    "( socket s -- socket ) send s and a newline",
+	"socket-recv":    "( socket -- socket s ) read the next line",
+	"socket-close":   "( socket -- ) close the connection",
+
+	// Subprocesses
+	"exec": "( command [args] -- code stdout stderr ) run a program and wait for it",
+}
@@ -3,9 +3,12 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
+	"time"
 
 	"github.com/psilLang/psil/pkg/types"
 )
@@ -37,18 +40,35 @@ type Interpreter struct {
 
 	// Debug mode shows extra info
 	Debug bool
+
+	denied     Capability      // disabled builtin groups
+	granted    Capability      // opt-in groups asked for with WithCapabilities
+	maxOutput  int             // output budget in bytes, refilled by Reset (0 = unlimited)
+	outputUsed int             // output written since the last Reset
+	prelude    string          // WithPrelude source not yet run
+	ctx        context.Context // the current RunContext/RunString, if any
+	timeout    time.Duration   // WithTimeout deadline for each run
+	dir        string          // WithDir base for relative file names
+	depth      int             // nested runs in progress
+	interrupt  atomic.Bool     // set by Interrupt until the run ends
 }
 
-// New creates a new Interpreter with builtins registered
-func New() *Interpreter {
+// New creates a new Interpreter with builtins registered, configured by
+// opts. Without options it has every builtin, unlimited gas and prints to
+// os.Stdout.
+func New(opts ...Option) *Interpreter {
 	interp := &Interpreter{
 		Stack:      make([]types.Value, 0, 64),
 		Dictionary: make(map[string]types.Value),
 		Output:     os.Stdout,
 		Gas:        0, // unlimited by default
 	}
+	for _, opt := range opts {
+		opt(interp)
+	}
 
-	// Register all builtins and combinators
+	// Register all builtins and combinators (those of disabled
+	// capabilities are left out)
 	interp.RegisterBuiltins()
 	interp.RegisterCombinators()
 
@@ -64,6 +84,7 @@ func (i *Interpreter) Reset() {
 	if i.MaxGas > 0 {
 		i.Gas = i.MaxGas
 	}
+	i.outputUsed = 0
 }
 
 // SetError sets the error flag and code
@@ -216,6 +237,48 @@ func (i *Interpreter) PopTurtle() (*types.Turtle, bool) {
 	return t, true
 }
 
+// PopSocket pops a socket, sets error if not a socket
+func (i *Interpreter) PopSocket() (*types.Socket, bool) {
+	v := i.Pop()
+	if v == nil {
+		return nil, false
+	}
+	s, ok := v.(*types.Socket)
+	if !ok {
+		i.SetError(types.ErrTypeMismatch)
+		return nil, false
+	}
+	return s, true
+}
+
+// PopVector pops a vector or matrix, sets error if not one
+func (i *Interpreter) PopVector() (*types.Vector, bool) {
+	v := i.Pop()
+	if v == nil {
+		return nil, false
+	}
+	vec, ok := v.(*types.Vector)
+	if !ok {
+		i.SetError(types.ErrTypeMismatch)
+		return nil, false
+	}
+	return vec, true
+}
+
+// PopStringBuffer pops a string buffer, sets error if not one
+func (i *Interpreter) PopStringBuffer() (*types.StringBuffer, bool) {
+	v := i.Pop()
+	if v == nil {
+		return nil, false
+	}
+	b, ok := v.(*types.StringBuffer)
+	if !ok {
+		i.SetError(types.ErrTypeMismatch)
+		return nil, false
+	}
+	return b, true
+}
+
 // Define adds a definition to the dictionary
 func (i *Interpreter) Define(name string, value types.Value) {
 	i.Dictionary[name] = value
@@ -238,6 +301,9 @@ func (i *Interpreter) Execute(v types.Value) error {
 	if !i.ConsumeGas(1) {
 		return fmt.Errorf("gas exhausted")
 	}
+	if err := i.checkCanceled(); err != nil {
+		return err
+	}
 
 	switch val := v.(type) {
 	case types.Number:
@@ -265,6 +331,18 @@ func (i *Interpreter) Execute(v types.Value) error {
 		// Turtles are pushed like other values
 		i.Push(val)
 
+	case *types.Vector:
+		// Vectors are pushed like other values
+		i.Push(val)
+
+	case *types.Stream:
+		// Streams are pushed like other values; only forcing runs them
+		i.Push(val)
+
+	case *types.StringBuffer:
+		// String buffers are pushed like other values
+		i.Push(val)
+
 	case types.Symbol:
 		// Look up and execute
 		if def, ok := i.Dictionary[string(val)]; ok {
@@ -304,8 +382,13 @@ func (i *Interpreter) ExecuteQuotation(q *types.Quotation) error {
 	return nil
 }
 
-// Run executes a slice of values (the main program)
+// Run executes a slice of values (the main program), after the
+// WithPrelude source on the first run
 func (i *Interpreter) Run(values []types.Value) error {
+	defer i.begin()()
+	if err := i.loadPrelude(); err != nil {
+		return err
+	}
 	for _, v := range values {
 		if err := i.Execute(v); err != nil {
 			return err
@@ -2,18 +2,27 @@ package interpreter
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/psilLang/psil/pkg/parser"
 	"github.com/psilLang/psil/pkg/types"
 )
 
 // Helper to run PSIL code and get results
-func runPSIL(t *testing.T, code string) *Interpreter {
+func runPSIL(t *testing.T, code string, opts ...Option) *Interpreter {
 	t.Helper()
-	interp := New()
+	interp := New(opts...)
 	prog, err := parser.Parse(code)
 	if err != nil {
 		t.Fatalf("Parse error: %v", err)
@@ -376,6 +385,72 @@ func TestFold(t *testing.T) {
 	}
 }
 
+func TestNumericLists(t *testing.T) {
+	tests := []struct{ code, want string }{
+		{"[3 -1 2.5 0 2] sort-asc", "[ -1 0 2 2.5 3 ]"},
+		{"[3 -1 2.5 0 2] sort-desc", "[ 3 2.5 2 0 -1 ]"},
+		{"[3 -1 2.5 0 2] [<] sort", "[ -1 0 2 2.5 3 ]"}, // same as the slow path
+		{"[] sort-asc", "[  ]"},
+		{"[1 2 3 4 5] sum", "15"},
+		{"[] sum", "0"},
+		{"[1 2 3 4 5] product", "120"},
+		{"[] product", "1"},
+		{"[4 -7 9 0] lmin", "-7"},
+		{"[4 -7 9 0] lmax", "9"},
+	}
+	for _, tt := range tests {
+		interp := runPSIL(t, tt.code)
+		if interp.HasError() || len(interp.Stack) != 1 || interp.Stack[0].String() != tt.want {
+			t.Errorf("%s: got %v (A=%d), want %s", tt.code, interp.Stack, interp.ARegister, tt.want)
+		}
+	}
+
+	errs := []struct {
+		code string
+		want int
+	}{
+		{`[1 "a" 2] sum`, types.ErrTypeMismatch},
+		{"[1 [2] 3] sort-asc", types.ErrTypeMismatch},
+		{"[] lmin", types.ErrInvalidQuotation},
+	}
+	for _, tt := range errs {
+		interp := runPSIL(t, tt.code)
+		if interp.ARegister != tt.want {
+			t.Errorf("%s: expected error %d, got A=%d", tt.code, tt.want, interp.ARegister)
+		}
+	}
+}
+
+func TestStreams(t *testing.T) {
+	tests := []struct{ code, want string }{
+		{"0 lazy-range [dup *] lazy-map [2 mod 0 =] lazy-filter 5 take force", "[ 0 4 16 36 64 ]"},
+		{"[1 2 3 4] [10 *] lazy-map force", "[ 10 20 30 40 ]"},
+		{"[1 2 3 4] [2 >] lazy-filter force", "[ 3 4 ]"},
+		{"1 lazy-range 3 take dup force swap force concat", "[ 1 2 3 1 2 3 ]"}, // each use starts over
+		{"5 lazy-range 0 take force", "[  ]"},
+		{"[1 2 3] 2 take", "[ 1 2 ]"},
+	}
+	for _, tt := range tests {
+		interp := runPSIL(t, tt.code)
+		if interp.HasError() || len(interp.Stack) != 1 || interp.Stack[0].String() != tt.want {
+			t.Errorf("%s: got %v (A=%d), want %s", tt.code, interp.Stack, interp.ARegister, tt.want)
+		}
+	}
+
+	// Nothing runs until force
+	_, out := runPSILWithOutput(t, `[1 2 3] [dup .] lazy-map 2 take "built" . force drop`)
+	if out != "built\n1\n2\n" {
+		t.Errorf("Expected the map to run only when forced, got %q", out)
+	}
+
+	// An endless stream forced runs until gas stops it
+	interp := New(WithGas(500))
+	interp.RunString(context.Background(), "0 lazy-range force")
+	if interp.ARegister != types.ErrGasExhausted {
+		t.Errorf("Expected gas exhaustion, got A=%d", interp.ARegister)
+	}
+}
+
 func TestRange(t *testing.T) {
 	interp := runPSIL(t, "1 6 range")
 	if len(interp.Stack) != 1 {
@@ -481,6 +556,46 @@ func TestComplexProgram(t *testing.T) {
 	}
 }
 
+func TestSharedLists(t *testing.T) {
+	// Lists built by cons and concat share items, but stay values:
+	// growing one never changes another built from the same list
+	tests := []struct{ code, want string }{
+		{"[1 2] dup 3 swap cons swap 4 swap cons", "[ 3 1 2 ] [ 4 1 2 ]"},
+		{"[] 0 swap cons dup 1 swap cons swap 2 swap cons", "[ 1 0 ] [ 2 0 ]"},
+		{"[1] [2] concat dup [3] concat swap [4] concat", "[ 1 2 3 ] [ 1 2 4 ]"},
+		{"[1] [2] concat dup 0 swap cons swap [3] concat", "[ 0 1 2 ] [ 1 2 3 ]"},
+		{"[1 2 3] rest 9 swap cons", "[ 9 2 3 ]"},
+	}
+	for _, tt := range tests {
+		interp := runPSIL(t, tt.code)
+		var got []string
+		for _, v := range interp.Stack {
+			got = append(got, v.String())
+		}
+		if strings.Join(got, " ") != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.code, strings.Join(got, " "), tt.want)
+		}
+	}
+
+	// Only one of many conses onto the same list may take its free slot
+	base := types.Cons(types.Number(0), &types.Quotation{})
+	lists := make([]*types.Quotation, 8)
+	var wg sync.WaitGroup
+	for n := range lists {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lists[n] = types.Cons(types.Number(n+1), base)
+		}()
+	}
+	wg.Wait()
+	for n, q := range lists {
+		if want := fmt.Sprintf("[ %d 0 ]", n+1); q.String() != want {
+			t.Errorf("cons %d: got %s, want %s", n, q, want)
+		}
+	}
+}
+
 func TestQuotationComposition(t *testing.T) {
 	interp := runPSIL(t, "[1 +] [2 *] concat 5 swap i")
 	if len(interp.Stack) != 1 {
@@ -515,3 +630,363 @@ func TestPrintStrings(t *testing.T) {
 		t.Errorf("Expected 'test', got '%s'", output)
 	}
 }
+
+func TestStringBuffer(t *testing.T) {
+	interp := runPSIL(t, `sbuf-new "total: " sbuf-add 3 sbuf-add sbuf-newline [1 2] sbuf-add sbuf-string`)
+	if got := interp.Peek(); len(interp.Stack) != 1 || got == nil || !got.Equal(types.String("total: 3\n[ 1 2 ]")) {
+		t.Errorf("Expected the built string, got %v", interp.Stack)
+	}
+
+	// Copies share the buffer, as they share an image
+	interp = runPSIL(t, `sbuf-new dup "a" sbuf-add drop "b" sbuf-add sbuf-string`)
+	if got := interp.Peek(); got == nil || !got.Equal(types.String("ab")) {
+		t.Errorf("Expected a shared buffer, got %v", interp.Stack)
+	}
+
+	interp = runPSIL(t, `"a" "b" sbuf-add`)
+	if interp.ARegister != types.ErrTypeMismatch {
+		t.Errorf("Expected a type mismatch, got A=%d", interp.ARegister)
+	}
+}
+
+// === Embedding ===
+
+func TestOptions(t *testing.T) {
+	var buf bytes.Buffer
+	interp := New(WithOutput(&buf), WithPrelude("DEFINE sq == [dup *]."))
+	if err := interp.RunString(context.Background(), "7 sq ."); err != nil {
+		t.Fatalf("Runtime error: %v", err)
+	}
+	if buf.String() != "49\n" {
+		t.Errorf("Expected '49\\n', got %q", buf.String())
+	}
+}
+
+func TestPreludeError(t *testing.T) {
+	interp := New(WithPrelude("1 0 /"))
+	err := interp.RunString(context.Background(), "1")
+	if err == nil || !strings.Contains(err.Error(), "prelude") {
+		t.Errorf("Expected a prelude error, got %v", err)
+	}
+}
+
+func TestGasOption(t *testing.T) {
+	interp := New(WithGas(100))
+	err := interp.RunString(context.Background(), "[1 drop] loop")
+	if err == nil || interp.ARegister != types.ErrGasExhausted {
+		t.Errorf("Expected gas exhausted, got %v (A=%d)", err, interp.ARegister)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	interp := New(WithCapabilities(CapIO|CapGraphics), WithOutput(nil))
+	if err := interp.RunString(context.Background(), `10 10 img-new img-width .`); err != nil {
+		t.Errorf("Expected graphics to be enabled, got %v", err)
+	}
+	err := interp.RunString(context.Background(), `10 10 img-new "x.png" img-save`)
+	if err == nil || interp.ARegister != types.ErrUndefinedSymbol {
+		t.Errorf("Expected img-save to be undefined, got %v", err)
+	}
+}
+
+func TestDirOption(t *testing.T) {
+	dir := t.TempDir()
+	interp := New(WithDir(dir), WithOutput(nil))
+	if err := interp.RunString(context.Background(), `2 2 img-new "x.png" img-save`); err != nil {
+		t.Fatalf("RunString: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "x.png")); err != nil {
+		t.Errorf("Expected x.png in the WithDir directory: %v", err)
+	}
+}
+
+func TestRunContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	interp := New()
+	prog, err := parser.Parse("[1 drop] loop")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	values, _ := prog.ToValues()
+	err = interp.RunContext(ctx, values)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", err)
+	}
+	if interp.ARegister != types.ErrCanceled {
+		t.Errorf("Expected error code %d, got %d", types.ErrCanceled, interp.ARegister)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	interp := New(WithSandbox(Sandbox{NoFilesystem: true, NoImages: true}), WithOutput(nil))
+	for _, word := range []string{"img-save", "img-new", "turtle"} {
+		if _, ok := interp.Lookup(word); ok {
+			t.Errorf("Expected %s to be undefined", word)
+		}
+	}
+	if !interp.Allowed(CapIO) || interp.Allowed(CapFiles) {
+		t.Errorf("Expected only files and graphics denied, got %v", CapAll&^interp.denied)
+	}
+
+	// A builtin from an unrestricted interpreter is refused at call time
+	save, _ := New().Lookup("img-save")
+	interp.Define("save", save)
+	err := interp.RunString(context.Background(), `"x.png" "x.png" save`)
+	if err == nil || interp.ARegister != types.ErrDenied {
+		t.Errorf("Expected capability denied, got %v (A=%d)", err, interp.ARegister)
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	var buf bytes.Buffer
+	interp := New(WithSandbox(Sandbox{MaxOutputBytes: 10}), WithOutput(&buf))
+	err := interp.RunString(context.Background(), `[1234 .] loop`)
+	if err == nil || interp.ARegister != types.ErrOutputLimit {
+		t.Errorf("Expected output limit exceeded, got %v (A=%d)", err, interp.ARegister)
+	}
+	if buf.String() != "1234\n1234\n" {
+		t.Errorf("Expected two lines of output, got %q", buf.String())
+	}
+
+	interp.Reset()
+	if err := interp.RunString(context.Background(), `"again" .`); err != nil {
+		t.Errorf("Expected Reset to refill the budget, got %v", err)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	interp := New()
+	time.AfterFunc(20*time.Millisecond, interp.Interrupt)
+	// An empty body never reaches Execute; the loop itself must notice
+	err := interp.RunString(context.Background(), "[[] loop] [drop] try")
+	if !errors.Is(err, ErrInterrupted) || interp.ARegister != types.ErrCanceled {
+		t.Errorf("Expected interrupted, got %v (A=%d)", err, interp.ARegister)
+	}
+
+	// The interrupt does not outlive its run
+	interp.Reset()
+	if err := interp.RunString(context.Background(), "1 2 +"); err != nil {
+		t.Errorf("Expected a clean run after the interrupt, got %v", err)
+	}
+}
+
+func TestTimeoutOption(t *testing.T) {
+	interp := New(WithTimeout(20 * time.Millisecond))
+	prog, err := parser.Parse("0 [inc] loop")
+	if err != nil {
+		t.Fatalf("Parse error: %v", err)
+	}
+	values, _ := prog.ToValues()
+	err = interp.Run(values)
+	if !errors.Is(err, context.DeadlineExceeded) || interp.ARegister != types.ErrCanceled {
+		t.Errorf("Expected deadline exceeded, got %v (A=%d)", err, interp.ARegister)
+	}
+}
+
+func TestRegisterWord(t *testing.T) {
+	ping := func(i *Interpreter) error {
+		i.Push(types.String("pong"))
+		return nil
+	}
+	interp := New(WithCapabilities(CapAll))
+	interp.RegisterWord("ping", CapNetwork, ping)
+	if err := interp.RunString(context.Background(), "ping"); err != nil || len(interp.Stack) != 1 {
+		t.Fatalf("Expected ping to run, got %v with stack %s", err, interp.StackString())
+	}
+
+	for _, sandboxed := range []*Interpreter{New(), New(WithSandbox(Sandbox{NoNetwork: true}))} {
+		sandboxed.RegisterWord("ping", CapNetwork, ping)
+		if _, ok := sandboxed.Lookup("ping"); ok {
+			t.Errorf("Expected ping to be left out without network access")
+		}
+	}
+}
+
+func TestWordDocs(t *testing.T) {
+	interp := New(WithCapabilities(CapAll))
+	for name := range interp.Dictionary {
+		if _, ok := WordDoc(name); !ok {
+			t.Errorf("Builtin %q has no WordDoc", name)
+		}
+	}
+	for name := range wordDocs {
+		if _, ok := interp.Lookup(name); !ok {
+			t.Errorf("WordDoc for %q, which is not a builtin", name)
+		}
+	}
+}
+
+func TestWordEffect(t *testing.T) {
+	tests := []struct {
+		name    string
+		in, out int
+		ok      bool
+	}{
+		{"dup", 1, 2, true},
+		{"img-setpixel", 6, 1, true},
+		{"sqrt", 1, 1, true},
+		{"lerp", 3, 1, true},
+		{"vec3", 3, 1, true},
+		{"unvec", 0, 0, false},
+		{"concat", 2, 1, true},
+		{"range", 2, 1, true},
+		{"pi", 0, 1, true},
+		{"ifte", 0, 0, false},
+		{"find", 0, 0, false},
+		{"nosuchword", 0, 0, false},
+	}
+	for _, tt := range tests {
+		in, out, ok := WordEffect(tt.name)
+		if ok != tt.ok || ok && (in != tt.in || out != tt.out) {
+			t.Errorf("WordEffect(%q) = %d, %d, %v; want %d, %d, %v", tt.name, in, out, ok, tt.in, tt.out, tt.ok)
+		}
+	}
+}
+
+func TestVectors(t *testing.T) {
+	tests := []struct {
+		code string
+		want types.Value
+	}{
+		{"1 2 vec2 3 4 vec2 vec-add", types.NewVector(4, 6)},
+		{"5 5 5 vec3 1 2 3 vec3 vec-sub", types.NewVector(4, 3, 2)},
+		{"1 2 3 vec3 2 vec-scale", types.NewVector(2, 4, 6)},
+		{"1 2 3 vec3 4 5 6 vec3 vec-dot", types.Number(32)},
+		{"1 0 0 vec3 0 1 0 vec3 vec-cross", types.NewVector(0, 0, 1)},
+		{"3 4 vec2 vec-length", types.Number(5)},
+		{"3 4 vec2 vec-normalize", types.NewVector(0.6, 0.8)},
+		{"0 0 vec2 vec-normalize", types.NewVector(0, 0)},
+		{"1 2 3 vec3 unvec + +", types.Number(6)},
+		// A quarter turn about z, applied to a vector and to itself
+		{"0 -1 0 vec3 1 0 0 vec3 0 0 1 vec3 mat3 1 2 3 vec3 mat-mul", types.NewVector(-2, 1, 3)},
+		{"0 -1 0 vec3 1 0 0 vec3 0 0 1 vec3 mat3 dup mat-mul unvec drop drop", types.NewVector(-1, 0, 0)},
+	}
+	for _, tt := range tests {
+		interp := runPSIL(t, tt.code)
+		if interp.HasError() || len(interp.Stack) != 1 || !interp.Stack[0].Equal(tt.want) {
+			t.Errorf("%s: got %v (A=%d), want %v", tt.code, interp.Stack, interp.ARegister, tt.want)
+		}
+	}
+
+	for _, code := range []string{
+		"1 2 vec2 1 2 3 vec3 vec-add",
+		"1 2 vec2 3 4 vec2 vec-cross",
+		"1 2 vec2 1 2 3 vec3 mat-mul",
+		"1 2 vec2 3 vec-dot",
+	} {
+		interp := runPSIL(t, code)
+		if interp.ARegister != types.ErrTypeMismatch {
+			t.Errorf("%s: expected a type mismatch, got A=%d", code, interp.ARegister)
+		}
+	}
+}
+
+// echoServer answers each line it reads with the same line
+func echoServer(t *testing.T, network, addr string) net.Listener {
+	t.Helper()
+	ln, err := net.Listen(network, addr)
+	if err != nil {
+		t.Skipf("listen %s: %v", network, err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return ln
+}
+
+func TestSockets(t *testing.T) {
+	tcp := echoServer(t, "tcp", "127.0.0.1:0")
+	unix := echoServer(t, "unix", filepath.Join(t.TempDir(), "echo.sock"))
+	for _, addr := range []string{tcp.Addr().String(), "unix:" + unix.Addr().String()} {
+		var out bytes.Buffer
+		interp := New(WithOutput(&out), WithCapabilities(CapAll))
+		src := fmt.Sprintf(`"%s" socket-connect "ping" socket-send "pong" socket-send socket-recv . socket-recv . socket-close`, addr)
+		if err := interp.RunString(context.Background(), src); err != nil {
+			t.Errorf("%s: %v", addr, err)
+		}
+		if out.String() != "ping\npong\n" {
+			t.Errorf("%s: got %q", addr, out.String())
+		}
+	}
+
+	interp := New(WithOutput(nil), WithCapabilities(CapAll))
+	err := interp.RunString(context.Background(), `"127.0.0.1:1" socket-connect`)
+	if err == nil || interp.ARegister != types.ErrNetwork {
+		t.Errorf("Expected a network error, got %v (A=%d)", err, interp.ARegister)
+	}
+
+	for _, opts := range [][]Option{nil, {WithSandbox(Sandbox{NoNetwork: true}), WithCapabilities(CapAll)}} {
+		if _, ok := New(opts...).Lookup("socket-connect"); ok {
+			t.Errorf("Expected socket-connect to be undefined with %d options", len(opts))
+		}
+	}
+}
+
+func TestSocketRecvCanceled(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("listen: %v", err)
+	}
+	defer ln.Close() // accepts, then never answers
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	interp := New(WithCapabilities(CapAll))
+	err = interp.RunString(ctx, fmt.Sprintf(`"%s" socket-connect socket-recv`, ln.Addr()))
+	if !errors.Is(err, context.DeadlineExceeded) || interp.ARegister != types.ErrCanceled {
+		t.Errorf("Expected the wait to be canceled, got %v (A=%d)", err, interp.ARegister)
+	}
+}
+
+func TestExec(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh")
+	}
+	interp := runPSIL(t, `"sh" ["-c" "echo out; echo err >&2; exit 3"] exec`, WithCapabilities(CapAll))
+	want := []types.Value{types.Number(3), types.String("out\n"), types.String("err\n")}
+	if len(interp.Stack) != 3 {
+		t.Fatalf("Expected code, stdout and stderr, got %v", interp.Stack)
+	}
+	for n, v := range want {
+		if !interp.Stack[n].Equal(v) {
+			t.Errorf("Stack[%d] = %v, want %v", n, interp.Stack[n], v)
+		}
+	}
+
+	interp = runPSIL(t, `"no-such-program-psil" [] exec`, WithCapabilities(CapAll))
+	if interp.ARegister != types.ErrExec {
+		t.Errorf("Expected an exec error, got A=%d", interp.ARegister)
+	}
+
+	for _, opts := range [][]Option{nil, {WithSandbox(Sandbox{})}, {WithCapabilities(CapAll &^ CapExec)},
+		{WithSandbox(Sandbox{}), WithCapabilities(CapAll)}} {
+		if _, ok := New(opts...).Lookup("exec"); ok {
+			t.Errorf("Expected exec to be undefined with %d options", len(opts))
+		}
+	}
+}
+
+func TestExecCanceled(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("no sleep")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	interp := New(WithCapabilities(CapAll))
+	err := interp.RunString(ctx, `"sleep" [10] exec`)
+	if !errors.Is(err, context.DeadlineExceeded) || time.Since(start) > 5*time.Second {
+		t.Errorf("Expected sleep to be killed at the deadline, got %v after %v", err, time.Since(start))
+	}
+}
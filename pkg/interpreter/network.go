@@ -0,0 +1,103 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// pollInterval is how often a socket-recv waiting for a line checks for
+// cancelation
+const pollInterval = 100 * time.Millisecond
+
+// socket-connect: address -> socket
+// "host:port" is TCP; "unix:PATH" or a path with a slash is a unix socket,
+// as for the sandbox's -control-socket
+func builtinSocketConnect(i *Interpreter) error {
+	addr, ok := i.PopString()
+	if !ok {
+		return nil
+	}
+	network, address := "tcp", string(addr)
+	if p, ok := strings.CutPrefix(address, "unix:"); ok {
+		network, address = "unix", p
+	} else if strings.Contains(address, "/") {
+		network = "unix"
+	}
+	ctx := i.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	conn, err := new(net.Dialer).DialContext(ctx, network, address)
+	if err != nil {
+		if ctx.Err() != nil {
+			return i.checkCanceled()
+		}
+		i.SetError(types.ErrNetwork)
+		return nil
+	}
+	i.Push(types.NewSocket(conn, string(addr)))
+	return nil
+}
+
+// socket-send: socket string -> socket (sends the string and a newline)
+func builtinSocketSend(i *Interpreter) error {
+	s, ok := i.PopString()
+	if !ok {
+		return nil
+	}
+	sock, ok := i.PopSocket()
+	if !ok {
+		return nil
+	}
+	if _, err := io.WriteString(sock.Conn, string(s)+"\n"); err != nil {
+		i.SetError(types.ErrNetwork)
+		return nil
+	}
+	i.Push(sock)
+	return nil
+}
+
+// socket-recv: socket -> socket string (the next line, without its
+// newline). It waits for the line until the run is canceled; the end of
+// the connection is a network error.
+func builtinSocketRecv(i *Interpreter) error {
+	sock, ok := i.PopSocket()
+	if !ok {
+		return nil
+	}
+	var line strings.Builder
+	for {
+		sock.Conn.SetReadDeadline(time.Now().Add(pollInterval))
+		part, err := sock.Reader.ReadString('\n')
+		line.WriteString(part)
+		switch {
+		case err == nil, errors.Is(err, io.EOF) && line.Len() > 0:
+			i.Push(sock)
+			i.Push(types.String(strings.TrimSuffix(strings.TrimSuffix(line.String(), "\n"), "\r")))
+			return nil
+		case !errors.Is(err, os.ErrDeadlineExceeded):
+			i.SetError(types.ErrNetwork)
+			return nil
+		}
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
+	}
+}
+
+// socket-close: socket ->
+func builtinSocketClose(i *Interpreter) error {
+	sock, ok := i.PopSocket()
+	if !ok {
+		return nil
+	}
+	sock.Conn.Close()
+	return nil
+}
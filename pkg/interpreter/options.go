@@ -0,0 +1,115 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/psilLang/psil/pkg/parser"
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// Option configures an Interpreter made by New
+type Option func(*Interpreter)
+
+// WithOutput sends console output to w instead of os.Stdout (nil discards it)
+func WithOutput(w io.Writer) Option {
+	return func(i *Interpreter) {
+		if w == nil {
+			w = io.Discard
+		}
+		i.Output = w
+	}
+}
+
+// WithGas sets the gas budget, refilled by Reset (0 = unlimited)
+func WithGas(gas int) Option {
+	return func(i *Interpreter) {
+		i.Gas = gas
+		i.MaxGas = gas
+	}
+}
+
+// WithPrelude loads PSIL source (definitions and code) before the first
+// program: it is run by the first Run, RunContext or RunString, which
+// returns its error.
+func WithPrelude(source string) Option {
+	return func(i *Interpreter) {
+		i.prelude = source
+	}
+}
+
+// WithTimeout limits every run (Run, RunContext, RunString) to d, after
+// which it is canceled like a RunContext whose deadline passed
+func WithTimeout(d time.Duration) Option {
+	return func(i *Interpreter) {
+		i.timeout = d
+	}
+}
+
+// WithDir resolves the relative file names a program uses (img-save)
+// against dir instead of the working directory
+func WithDir(dir string) Option {
+	return func(i *Interpreter) {
+		i.dir = dir
+	}
+}
+
+// WithCapabilities enables only the given builtin groups; the words of the
+// others are left undefined. It is also the only way to turn on the groups
+// CapDefault leaves out, CapNetwork and CapExec. A group denied by WithSandbox or
+// another WithCapabilities stays denied, whatever the order of the options.
+func WithCapabilities(caps Capability) Option {
+	return func(i *Interpreter) {
+		i.granted |= caps
+		i.denied |= CapAll &^ caps
+	}
+}
+
+// RunString parses source, adds its definitions to the dictionary and runs
+// it under ctx. Unlike Run, an error flag left set by the program is
+// returned as an error.
+func (i *Interpreter) RunString(ctx context.Context, source string) error {
+	defer i.bind(ctx)()
+	defer i.begin()()
+	if err := i.loadPrelude(); err != nil {
+		return err
+	}
+	if err := i.runSource(source); err != nil {
+		return err
+	}
+	if i.CFlag {
+		return fmt.Errorf("error flag set: %s (code %d)", types.ErrorMessage(i.ARegister), i.ARegister)
+	}
+	return nil
+}
+
+// runSource parses and runs source
+func (i *Interpreter) runSource(source string) error {
+	prog, err := parser.Parse(source)
+	if err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	values, definitions := prog.ToValues()
+	for name, q := range definitions {
+		i.Define(name, q)
+	}
+	return i.Run(values)
+}
+
+// loadPrelude runs the WithPrelude source, once
+func (i *Interpreter) loadPrelude() error {
+	if i.prelude == "" {
+		return nil
+	}
+	source := i.prelude
+	i.prelude = ""
+	if err := i.runSource(source); err != nil {
+		return fmt.Errorf("prelude: %w", err)
+	}
+	if i.CFlag {
+		return fmt.Errorf("prelude: error flag set: %s (code %d)", types.ErrorMessage(i.ARegister), i.ARegister)
+	}
+	return nil
+}
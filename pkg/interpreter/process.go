@@ -0,0 +1,73 @@
+package interpreter
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"time"
+
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// exec: command [args] -> code stdout stderr
+// Runs command with args (strings, numbers or symbols) in the WithDir
+// directory and waits for it. A program that exits non-zero is not an
+// error; one that cannot be started is. Canceling the run kills it.
+func builtinExec(i *Interpreter) error {
+	argq, ok := i.PopQuotation()
+	if !ok {
+		return nil
+	}
+	name, ok := i.PopString()
+	if !ok {
+		return nil
+	}
+	args := make([]string, 0, len(argq.Items))
+	for _, item := range argq.Items {
+		switch v := item.(type) {
+		case types.String:
+			args = append(args, string(v))
+		case types.Number, types.Symbol:
+			args = append(args, v.String())
+		case *types.QuotedSymbol:
+			args = append(args, v.Name)
+		default:
+			i.SetError(types.ErrTypeMismatch)
+			return nil
+		}
+	}
+
+	cmd := exec.Command(string(name), args...)
+	cmd.Dir = i.dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Start(); err != nil {
+		i.SetError(types.ErrExec)
+		return nil
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case err := <-done:
+			var exit *exec.ExitError
+			if err != nil && !errors.As(err, &exit) {
+				i.SetError(types.ErrExec)
+				return nil
+			}
+			i.Push(types.Number(cmd.ProcessState.ExitCode()))
+			i.Push(types.String(stdout.String()))
+			i.Push(types.String(stderr.String()))
+			return nil
+		case <-ticker.C:
+			if err := i.checkCanceled(); err != nil {
+				cmd.Process.Kill()
+				<-done
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package interpreter
+
+import "github.com/psilLang/psil/pkg/types"
+
+// sbuf-new: -> sbuf
+func builtinSbufNew(i *Interpreter) error {
+	i.Push(&types.StringBuffer{})
+	return nil
+}
+
+// sbuf-add: sbuf x -> sbuf
+// Appends x as print would write it: strings without quotes
+func builtinSbufAdd(i *Interpreter) error {
+	v := i.Pop()
+	if v == nil {
+		return nil
+	}
+	b, ok := i.PopStringBuffer()
+	if !ok {
+		return nil
+	}
+	if s, ok := v.(types.String); ok {
+		b.Text.WriteString(string(s))
+	} else {
+		b.Text.WriteString(v.String())
+	}
+	i.Push(b)
+	return nil
+}
+
+// sbuf-newline: sbuf -> sbuf
+// String literals have no escapes, so this is how a line ends
+func builtinSbufNewline(i *Interpreter) error {
+	b, ok := i.PopStringBuffer()
+	if !ok {
+		return nil
+	}
+	b.Text.WriteByte('\n')
+	i.Push(b)
+	return nil
+}
+
+// sbuf-string: sbuf -> string
+func builtinSbufString(i *Interpreter) error {
+	b, ok := i.PopStringBuffer()
+	if !ok {
+		return nil
+	}
+	i.Push(types.String(b.Text.String()))
+	return nil
+}
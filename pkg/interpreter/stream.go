@@ -0,0 +1,197 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// streamNext is a types.StreamNext with the interpreter already asserted
+type streamNext func(i *Interpreter) (types.Value, bool, error)
+
+// newStream makes a stream whose every use calls open for a fresh start
+func newStream(desc string, open func() streamNext) *types.Stream {
+	return &types.Stream{
+		Desc: desc,
+		Open: func() types.StreamNext {
+			next := open()
+			return func(interp interface{}) (types.Value, bool, error) {
+				return next(interp.(*Interpreter))
+			}
+		},
+	}
+}
+
+// popSequence pops a stream, or a list to read as one
+func popSequence(i *Interpreter) (*types.Stream, bool) {
+	v := i.Pop()
+	if v == nil {
+		return nil, false
+	}
+	switch s := v.(type) {
+	case *types.Stream:
+		return s, true
+	case *types.Quotation:
+		return newStream(s.String(), func() streamNext {
+			n := 0
+			return func(i *Interpreter) (types.Value, bool, error) {
+				if n >= len(s.Items) {
+					return nil, false, nil
+				}
+				n++
+				return s.Items[n-1], true, nil
+			}
+		}), true
+	}
+	i.SetError(types.ErrTypeMismatch)
+	return nil, false
+}
+
+// lazy-range: start -> stream
+// Counts start, start+1, ... without end; take cuts it short
+func builtinLazyRange(i *Interpreter) error {
+	start, ok := i.PopNumber()
+	if !ok {
+		return nil
+	}
+	i.Push(newStream(start.String()+" lazy-range", func() streamNext {
+		n := start
+		return func(i *Interpreter) (types.Value, bool, error) {
+			n++
+			return n - 1, true, nil
+		}
+	}))
+	return nil
+}
+
+// lazy-map: stream [Q] -> stream
+// Q runs on each element as it is pulled, as for map
+func builtinLazyMap(i *Interpreter) error {
+	q, ok := i.PopQuotation()
+	if !ok {
+		return nil
+	}
+	s, ok := popSequence(i)
+	if !ok {
+		return nil
+	}
+	i.Push(newStream(s.Desc+" "+q.String()+" lazy-map", func() streamNext {
+		next := s.Open()
+		return func(i *Interpreter) (types.Value, bool, error) {
+			for {
+				item, ok, err := next(i)
+				if !ok || err != nil {
+					return nil, false, err
+				}
+				if !i.ConsumeGas(1) {
+					return nil, false, nil
+				}
+				i.Push(item)
+				if err := i.ExecuteQuotation(q); err != nil {
+					return nil, false, err
+				}
+				if i.CFlag {
+					return nil, false, nil
+				}
+				if len(i.Stack) > 0 {
+					return i.Pop(), true, nil
+				}
+			}
+		}
+	}))
+	return nil
+}
+
+// lazy-filter: stream [P] -> stream
+// Keeps the elements P accepts, as for filter, testing them as they are
+// pulled
+func builtinLazyFilter(i *Interpreter) error {
+	pred, ok := i.PopQuotation()
+	if !ok {
+		return nil
+	}
+	s, ok := popSequence(i)
+	if !ok {
+		return nil
+	}
+	i.Push(newStream(s.Desc+" "+pred.String()+" lazy-filter", func() streamNext {
+		next := s.Open()
+		return func(i *Interpreter) (types.Value, bool, error) {
+			for {
+				item, ok, err := next(i)
+				if !ok || err != nil {
+					return nil, false, err
+				}
+				if !i.ConsumeGas(1) {
+					return nil, false, nil
+				}
+				savedLen := len(i.Stack)
+				i.Push(item)
+				if err := i.ExecuteQuotation(pred); err != nil {
+					return nil, false, err
+				}
+				if i.CFlag {
+					return nil, false, nil
+				}
+				keep := i.ZFlag
+				if len(i.Stack) > savedLen {
+					if b, ok := i.Stack[len(i.Stack)-1].(types.Boolean); ok {
+						keep = bool(b)
+						i.Stack = i.Stack[:len(i.Stack)-1]
+					}
+				}
+				if keep {
+					return item, true, nil
+				}
+			}
+		}
+	}))
+	return nil
+}
+
+// takeStream is take for a stream: the first n elements, still lazy
+func takeStream(i *Interpreter, s *types.Stream, n int) {
+	i.Push(newStream(fmt.Sprintf("%s %d take", s.Desc, n), func() streamNext {
+		next, left := s.Open(), n
+		return func(i *Interpreter) (types.Value, bool, error) {
+			if left <= 0 {
+				return nil, false, nil
+			}
+			left--
+			return next(i)
+		}
+	}))
+}
+
+// force: stream -> [list]
+// Runs a stream to its end. An endless one runs until gas or cancelation
+// stops it, as loop does.
+func builtinForce(i *Interpreter) error {
+	s, ok := popSequence(i)
+	if !ok {
+		return nil
+	}
+	next := s.Open()
+	items := make([]types.Value, 0)
+	for {
+		if err := i.checkCanceled(); err != nil {
+			return err
+		}
+		if !i.ConsumeGas(1) {
+			return nil
+		}
+		item, ok, err := next(i)
+		if err != nil {
+			return err
+		}
+		if i.CFlag {
+			return nil
+		}
+		if !ok {
+			break
+		}
+		items = append(items, item)
+	}
+	i.Push(&types.Quotation{Items: items})
+	return nil
+}
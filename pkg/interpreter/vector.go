@@ -0,0 +1,250 @@
+package interpreter
+
+import (
+	"math"
+
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// vec2: x y -> vec2
+func builtinVec2(i *Interpreter) error {
+	return pushVector(i, 2)
+}
+
+// vec3: x y z -> vec3
+func builtinVec3(i *Interpreter) error {
+	return pushVector(i, 3)
+}
+
+// pushVector pops n numbers, the last on top, and pushes them as a vector
+func pushVector(i *Interpreter, n int) error {
+	elems := make([]float64, n)
+	for k := n - 1; k >= 0; k-- {
+		x, ok := i.PopNumber()
+		if !ok {
+			return nil
+		}
+		elems[k] = float64(x)
+	}
+	i.Push(types.NewVector(elems...))
+	return nil
+}
+
+// mat3: row0 row1 row2 -> mat3
+// Each row is a vec3
+func builtinMat3(i *Interpreter) error {
+	elems := make([]float64, 9)
+	for row := 2; row >= 0; row-- {
+		v, ok := popVectorLen(i, 3)
+		if !ok {
+			return nil
+		}
+		copy(elems[row*3:], v.Elems)
+	}
+	i.Push(&types.Vector{Elems: elems, Side: 3})
+	return nil
+}
+
+// unvec: vector -> x y ...
+// Pushes a vector's elements, or a matrix's rows as vectors
+func builtinUnvec(i *Interpreter) error {
+	v, ok := i.PopVector()
+	if !ok {
+		return nil
+	}
+	if v.Side > 0 {
+		for row := 0; row < len(v.Elems); row += v.Side {
+			i.Push(types.NewVector(v.Elems[row : row+v.Side]...))
+		}
+		return nil
+	}
+	for _, x := range v.Elems {
+		i.Push(types.Number(x))
+	}
+	return nil
+}
+
+// vec?: value -> bool
+func builtinIsVector(i *Interpreter) error {
+	v := i.Peek()
+	if v == nil {
+		return nil
+	}
+	_, ok := v.(*types.Vector)
+	i.ZFlag = ok
+	i.Push(types.Boolean(ok))
+	return nil
+}
+
+// vec-add: a b -> a+b
+func builtinVecAdd(i *Interpreter) error {
+	return elementwise(i, func(a, b float64) float64 { return a + b })
+}
+
+// vec-sub: a b -> a-b
+func builtinVecSub(i *Interpreter) error {
+	return elementwise(i, func(a, b float64) float64 { return a - b })
+}
+
+// elementwise combines two vectors, or two matrices, of the same shape
+func elementwise(i *Interpreter, op func(a, b float64) float64) error {
+	b, ok := i.PopVector()
+	if !ok {
+		return nil
+	}
+	a, ok := i.PopVector()
+	if !ok {
+		return nil
+	}
+	if a.Side != b.Side || len(a.Elems) != len(b.Elems) {
+		i.SetError(types.ErrTypeMismatch)
+		return nil
+	}
+	elems := make([]float64, len(a.Elems))
+	for n := range elems {
+		elems[n] = op(a.Elems[n], b.Elems[n])
+	}
+	i.Push(&types.Vector{Elems: elems, Side: a.Side})
+	return nil
+}
+
+// vec-scale: vector s -> vector*s
+func builtinVecScale(i *Interpreter) error {
+	s, ok := i.PopNumber()
+	if !ok {
+		return nil
+	}
+	v, ok := i.PopVector()
+	if !ok {
+		return nil
+	}
+	i.Push(scaled(v, float64(s)))
+	return nil
+}
+
+func scaled(v *types.Vector, s float64) *types.Vector {
+	elems := make([]float64, len(v.Elems))
+	for n, x := range v.Elems {
+		elems[n] = x * s
+	}
+	return &types.Vector{Elems: elems, Side: v.Side}
+}
+
+// vec-dot: a b -> a.b
+func builtinVecDot(i *Interpreter) error {
+	b, ok := popVectorLen(i, -1)
+	if !ok {
+		return nil
+	}
+	a, ok := popVectorLen(i, len(b.Elems))
+	if !ok {
+		return nil
+	}
+	i.Push(types.Number(dot(a.Elems, b.Elems)))
+	return nil
+}
+
+func dot(a, b []float64) float64 {
+	sum := 0.0
+	for n, x := range a {
+		sum += x * b[n]
+	}
+	return sum
+}
+
+// vec-cross: a b -> a×b (vec3 only)
+func builtinVecCross(i *Interpreter) error {
+	b, ok := popVectorLen(i, 3)
+	if !ok {
+		return nil
+	}
+	a, ok := popVectorLen(i, 3)
+	if !ok {
+		return nil
+	}
+	x, y := a.Elems, b.Elems
+	i.Push(types.NewVector(
+		x[1]*y[2]-x[2]*y[1],
+		x[2]*y[0]-x[0]*y[2],
+		x[0]*y[1]-x[1]*y[0],
+	))
+	return nil
+}
+
+// vec-length: vector -> |vector|
+func builtinVecLength(i *Interpreter) error {
+	v, ok := popVectorLen(i, -1)
+	if !ok {
+		return nil
+	}
+	i.Push(types.Number(math.Sqrt(dot(v.Elems, v.Elems))))
+	return nil
+}
+
+// vec-normalize: vector -> unit vector
+// The zero vector stays zero, so a shader never sees NaN
+func builtinVecNormalize(i *Interpreter) error {
+	v, ok := popVectorLen(i, -1)
+	if !ok {
+		return nil
+	}
+	length := math.Sqrt(dot(v.Elems, v.Elems))
+	if length == 0 {
+		i.Push(v)
+		return nil
+	}
+	elems := make([]float64, len(v.Elems))
+	for n, x := range v.Elems {
+		elems[n] = x / length
+	}
+	i.Push(types.NewVector(elems...))
+	return nil
+}
+
+// mat-mul: m x -> m*x
+// x is a matrix or a vector of the same size as m
+func builtinMatMul(i *Interpreter) error {
+	x, ok := i.PopVector()
+	if !ok {
+		return nil
+	}
+	m, ok := i.PopVector()
+	if !ok {
+		return nil
+	}
+	side := m.Side
+	if side == 0 || x.Side != side && (x.Side != 0 || len(x.Elems) != side) {
+		i.SetError(types.ErrTypeMismatch)
+		return nil
+	}
+	cols := 1 // a vector is a single column
+	if x.Side > 0 {
+		cols = side
+	}
+	elems := make([]float64, side*cols)
+	for r := 0; r < side; r++ {
+		for c := 0; c < cols; c++ {
+			sum := 0.0
+			for k := 0; k < side; k++ {
+				sum += m.Elems[r*side+k] * x.Elems[k*cols+c]
+			}
+			elems[r*cols+c] = sum
+		}
+	}
+	i.Push(&types.Vector{Elems: elems, Side: x.Side})
+	return nil
+}
+
+// popVectorLen pops a vector (not a matrix) of n elements, or of any
+// length for n < 0, and sets the error flag for anything else
+func popVectorLen(i *Interpreter, n int) (*types.Vector, bool) {
+	v, ok := i.PopVector()
+	if !ok {
+		return nil, false
+	}
+	if v.Side > 0 || n >= 0 && len(v.Elems) != n {
+		i.SetError(types.ErrTypeMismatch)
+		return nil, false
+	}
+	return v, true
+}
@@ -1,6 +1,7 @@
 package micro
 
 import (
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"strings"
@@ -78,6 +79,7 @@ var mnemonics = map[string]byte{
 	"1-":     OpDec,
 	"dup2":   OpDup2,
 	"2dup":   OpDup2,
+	"pick":   OpPick,
 	"depth":  OpDepth,
 	"clear":  OpClear,
 
@@ -85,10 +87,19 @@ var mnemonics = map[string]byte{
 	"halt":   OpHalt,
 	"yield":  OpYield,
 	"break":  OpBreak,
+	"debug":  OpDebug,
 	"end":    OpEnd,
 	"error":  OpError,
 	"clrerr": OpClearE,
 	"err?":   OpCheckE,
+	"qdef.m": OpQuotDefM,
+	"poke":   OpPoke,
+
+	// 16-bit addressed memory
+	"@w":      OpLoadW,
+	"load.w":  OpLoadW,
+	"!w":      OpStoreW,
+	"store.w": OpStoreW,
 }
 
 // symbols maps names to inline symbol opcodes
@@ -222,8 +233,8 @@ func tokenize(line string) []string {
 
 func (a *Assembler) assembleTokens(tokens []string, lineNum int) error {
 	for i := 0; i < len(tokens); i++ {
-		tok := tokens[i]
-		tok = strings.ToLower(tok)
+		raw := tokens[i]
+		tok := strings.ToLower(raw)
 
 		// Check for mnemonic
 		if op, ok := mnemonics[tok]; ok {
@@ -314,6 +325,24 @@ func (a *Assembler) assembleTokens(tokens []string, lineNum int) error {
 			continue
 		}
 
+		// Load/store at a 16-bit address
+		if tok == "ld" || tok == "st" {
+			if i+1 >= len(tokens) {
+				return fmt.Errorf("%s requires address", tok)
+			}
+			i++
+			n, err := strconv.ParseUint(tokens[i], 0, 16)
+			if err != nil {
+				return fmt.Errorf("invalid address: %s", tokens[i])
+			}
+			op := byte(OpLoad16)
+			if tok == "st" {
+				op = OpStore16
+			}
+			a.code = append(a.code, op, byte(n>>8), byte(n&0xFF))
+			continue
+		}
+
 		// Jump instructions
 		if tok == "jmp" || tok == "jump" {
 			if i+1 >= len(tokens) {
@@ -322,8 +351,8 @@ func (a *Assembler) assembleTokens(tokens []string, lineNum int) error {
 			i++
 			target := tokens[i]
 			if n, err := strconv.Atoi(target); err == nil {
-				// Numeric offset
-				if n >= 0 {
+				// Numeric offset ("-0" still jumps back)
+				if !strings.HasPrefix(target, "-") {
 					a.code = append(a.code, OpJump, byte(n))
 				} else {
 					a.code = append(a.code, OpJumpBack, byte(-n))
@@ -463,28 +492,185 @@ func (a *Assembler) assembleTokens(tokens []string, lineNum int) error {
 			continue
 		}
 
+		// Quotation definition: idx qdef { body }
+		if tok == "qdef" {
+			if i+1 >= len(tokens) || tokens[i+1] != "{" {
+				return fmt.Errorf("qdef requires { body }")
+			}
+			j, depth := i+1, 0
+			for ; j < len(tokens); j++ {
+				switch tokens[j] {
+				case "{":
+					depth++
+				case "}":
+					depth--
+				}
+				if depth == 0 {
+					break
+				}
+			}
+			if j == len(tokens) {
+				return fmt.Errorf("qdef body missing }")
+			}
+			body, err := a.assembleBody(tokens[i+2:j], lineNum)
+			if err != nil {
+				return err
+			}
+			if len(body) > 255 {
+				return fmt.Errorf("qdef body is %d bytes, max 255", len(body))
+			}
+			a.code = append(a.code, OpQuotDef, byte(len(body)))
+			a.code = append(a.code, body...)
+			i = j
+			continue
+		}
+
 		// String literal
-		if strings.HasPrefix(tok, "\"") && strings.HasSuffix(tok, "\"") {
-			str := tok[1 : len(tok)-1]
+		if len(raw) >= 2 && strings.HasPrefix(raw, "\"") && strings.HasSuffix(raw, "\"") {
+			str := raw[1 : len(raw)-1]
 			a.code = append(a.code, OpStringVar, byte(len(str)))
 			a.code = append(a.code, []byte(str)...)
 			continue
 		}
 
+		// Other 2-byte ops by the name Disassemble gives them
+		if op, ok := twoByteOps[tok]; ok {
+			if i+1 >= len(tokens) {
+				return fmt.Errorf("%s requires argument", tok)
+			}
+			i++
+			n, err := strconv.ParseUint(tokens[i], 0, 8)
+			if err != nil {
+				return fmt.Errorf("invalid %s argument: %s", tok, tokens[i])
+			}
+			a.code = append(a.code, op, byte(n))
+			continue
+		}
+
+		// Opcodes written raw, as Disassemble writes those it has no
+		// better name for
+		if n, err := a.assembleRaw(tok, tokens[i+1:]); err != nil {
+			return err
+		} else if n >= 0 {
+			i += n
+			continue
+		}
+
 		return fmt.Errorf("unknown token: %s", tok)
 	}
 
 	return nil
 }
 
+// twoByteOps maps the OpName of each named 2-byte op to it
+var twoByteOps = func() map[string]byte {
+	m := make(map[string]byte)
+	for op := 0x80; op <= 0xBF; op++ {
+		if name := OpName(byte(op)); name != "2op" {
+			m[name] = byte(op)
+		}
+	}
+	return m
+}()
+
+// assembleRaw assembles tok if it is a raw opcode: ?XX (one byte),
+// sym.XX (inline symbol XX), 2op.XX ARG, 3op.XX VALUE or var.XX LEN [HEX],
+// with XX in hex. It returns how many of the args it used, or -1 if tok is
+// not raw.
+func (a *Assembler) assembleRaw(tok string, args []string) (int, error) {
+	prefix, hexOp, ok := strings.Cut(tok, ".")
+	if !ok && strings.HasPrefix(tok, "?") && len(tok) > 1 {
+		prefix, hexOp = "?", tok[1:]
+	} else if !ok {
+		return -1, nil
+	}
+	op64, err := strconv.ParseUint(hexOp, 16, 8)
+	if err != nil {
+		return -1, nil
+	}
+	op := byte(op64)
+	arg := func(i, bits int) (int64, error) {
+		if i >= len(args) {
+			return 0, fmt.Errorf("%s requires argument", tok)
+		}
+		n, err := strconv.ParseInt(args[i], 0, bits)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s argument: %s", tok, args[i])
+		}
+		return n, nil
+	}
+	switch prefix {
+	case "?":
+		a.code = append(a.code, op)
+		return 0, nil
+	case "sym":
+		if op >= 32 {
+			return 0, fmt.Errorf("%s: inline symbols go up to 1F", tok)
+		}
+		a.code = append(a.code, 0x40+op)
+		return 0, nil
+	case "2op":
+		n, err := arg(0, 16)
+		if err != nil || !Is2ByteOp(op) || n < 0 || n > 255 {
+			return 0, fmt.Errorf("invalid %s %v", tok, args)
+		}
+		a.code = append(a.code, op, byte(n))
+		return 1, nil
+	case "3op":
+		n, err := arg(0, 16)
+		if err != nil || !Is3ByteOp(op) {
+			return 0, fmt.Errorf("invalid %s %v", tok, args)
+		}
+		a.code = append(a.code, op, byte(n>>8), byte(n))
+		return 1, nil
+	case "var":
+		n, err := arg(0, 16)
+		if err != nil || !IsVarLenOp(op) || n < 0 || n > 255 {
+			return 0, fmt.Errorf("invalid %s %v", tok, args)
+		}
+		a.code = append(a.code, op, byte(n))
+		if n == 0 {
+			return 1, nil
+		}
+		if len(args) < 2 {
+			return 0, fmt.Errorf("%s %d requires its bytes in hex", tok, n)
+		}
+		data, err := hex.DecodeString(args[1])
+		if err != nil || len(data) != int(n) {
+			return 0, fmt.Errorf("%s %d: bad bytes %s", tok, n, args[1])
+		}
+		a.code = append(a.code, data...)
+		return 2, nil
+	}
+	return -1, nil
+}
+
+// assembleBody assembles tokens on their own, for a qdef body
+func (a *Assembler) assembleBody(tokens []string, lineNum int) ([]byte, error) {
+	code, fixups := a.code, len(a.fixups)
+	a.code = nil
+	err := a.assembleTokens(tokens, lineNum)
+	body := a.code
+	a.code = code
+	if err == nil && len(a.fixups) != fixups {
+		err = fmt.Errorf("qdef body cannot jump to a label")
+	}
+	a.fixups = a.fixups[:fixups]
+	return body, err
+}
+
 func (a *Assembler) emitNumber(n int) {
+	a.code = appendNumber(a.code, n)
+}
+
+// appendNumber appends the shortest push encoding of n.
+func appendNumber(code []byte, n int) []byte {
 	if n >= 0 && n <= 31 {
-		a.code = append(a.code, SmallNumOp(n))
+		return append(code, SmallNumOp(n))
 	} else if n >= 0 && n <= 255 {
-		a.code = append(a.code, OpPushByte, byte(n))
-	} else {
-		a.code = append(a.code, OpPushWord, byte(n>>8), byte(n&0xFF))
+		return append(code, OpPushByte, byte(n))
 	}
+	return append(code, OpPushWord, byte(n>>8), byte(n&0xFF))
 }
 
 // GetQuotations returns the quotation name to index mapping
@@ -492,6 +678,15 @@ func (a *Assembler) GetQuotations() map[string]int {
 	return a.quotations
 }
 
+// DefineQuotation names quotation idx, so later [name] references
+// assemble to it
+func (a *Assembler) DefineQuotation(name string, idx int) {
+	a.quotations[strings.ToLower(name)] = idx
+	if idx >= a.nextQuot {
+		a.nextQuot = idx + 1
+	}
+}
+
 // Disassemble converts bytecode back to text
 func Disassemble(code []byte) string {
 	var sb strings.Builder
@@ -542,7 +737,11 @@ func Disassemble(code []byte) string {
 			case OpSymbol:
 				sb.WriteString(fmt.Sprintf("sym.x %d", arg))
 			case OpQuotation:
-				sb.WriteString(fmt.Sprintf("[%d]", arg))
+				if arg < 32 {
+					sb.WriteString(fmt.Sprintf("quot.x %d", arg)) // [n] would assemble inline
+				} else {
+					sb.WriteString(fmt.Sprintf("[%d]", arg))
+				}
 			case OpJump:
 				sb.WriteString(fmt.Sprintf("jmp +%d", arg))
 			case OpJumpBack:
@@ -551,14 +750,20 @@ func Disassemble(code []byte) string {
 				sb.WriteString(fmt.Sprintf("jz +%d", arg))
 			case OpJumpNZ:
 				sb.WriteString(fmt.Sprintf("jnz +%d", arg))
-			case OpLocal:
-				sb.WriteString(fmt.Sprintf("local %d", arg))
-			case OpSetLocal:
-				sb.WriteString(fmt.Sprintf("local! %d", arg))
+			case OpLocal, OpSetLocal:
+				if arg < 16 {
+					sb.WriteString(fmt.Sprintf("%s %d", OpName(op), arg))
+				} else {
+					sb.WriteString(fmt.Sprintf("2op.%02X %d", op, arg)) // no such local
+				}
 			case OpCall:
 				sb.WriteString(fmt.Sprintf("call %d", arg))
 			default:
-				sb.WriteString(fmt.Sprintf("%s %d", OpName(op), arg))
+				if name := OpName(op); name != "2op" {
+					sb.WriteString(fmt.Sprintf("%s %d", name, arg))
+				} else {
+					sb.WriteString(fmt.Sprintf("2op.%02X %d", op, arg))
+				}
 			}
 			pc += 2
 
@@ -574,6 +779,8 @@ func Disassemble(code []byte) string {
 			switch op {
 			case OpPushWord:
 				sb.WriteString(fmt.Sprintf("push.w %d", val))
+			case OpLoad16, OpStore16:
+				sb.WriteString(fmt.Sprintf("%s %d", OpName(op), uint16(val)))
 			default:
 				sb.WriteString(fmt.Sprintf("3op.%02X %d", op, val))
 			}
@@ -592,11 +799,22 @@ func Disassemble(code []byte) string {
 				break
 			}
 			data := code[pc+2 : pc+2+length]
-			switch op {
-			case OpStringVar:
+			switch {
+			case op == OpStringVar && plainString(data):
 				sb.WriteString(fmt.Sprintf("\"%s\"", string(data)))
+			case op == OpQuotDef && Verify(data) == nil:
+				sb.WriteString("qdef { ")
+				for _, line := range strings.Split(strings.TrimSuffix(Disassemble(data), "\n"), "\n") {
+					if _, text, ok := strings.Cut(line, ": "); ok {
+						sb.WriteString(text + " ")
+					}
+				}
+				sb.WriteString("}")
 			default:
-				sb.WriteString(fmt.Sprintf("var.%02X [%d bytes]", op, length))
+				sb.WriteString(fmt.Sprintf("var.%02X %d", op, length))
+				if length > 0 {
+					sb.WriteString(fmt.Sprintf(" %x", data))
+				}
 			}
 			pc += 2 + length
 
@@ -609,6 +827,15 @@ func Disassemble(code []byte) string {
 		case op == OpEnd:
 			sb.WriteString("end")
 			pc++
+		case op == OpQuotDefM:
+			sb.WriteString("qdef.m")
+			pc++
+		case op == OpPoke, op == OpLoadW, op == OpStoreW:
+			sb.WriteString(OpName(op))
+			pc++
+		case op == OpBreak, op == OpDebug, op == OpError, op == OpClearE, op == OpCheckE:
+			sb.WriteString(specialNames[op])
+			pc++
 		default:
 			sb.WriteString(fmt.Sprintf("?%02X", op))
 			pc++
@@ -620,6 +847,24 @@ func Disassemble(code []byte) string {
 	return sb.String()
 }
 
+// specialNames names the special opcodes that have a mnemonic but no
+// OpName
+var specialNames = map[byte]string{
+	OpBreak: "break", OpDebug: "debug", OpError: "error", OpClearE: "clrerr", OpCheckE: "err?",
+}
+
+// plainString reports whether data can be written as a "..." literal that
+// assembles back to it: printable ASCII, and nothing the assembler reads
+// as a quote or comment
+func plainString(data []byte) bool {
+	for _, b := range data {
+		if b < 0x20 || b > 0x7E || b == '"' || b == ';' || b == '%' {
+			return false
+		}
+	}
+	return true
+}
+
 // AssembleQuotation assembles a quotation body
 func (a *Assembler) AssembleQuotation(source string) ([]byte, error) {
 	return a.Assemble(source)
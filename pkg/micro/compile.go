@@ -0,0 +1,158 @@
+package micro
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/psilLang/psil/pkg/parser"
+	"github.com/psilLang/psil/pkg/types"
+)
+
+// Compiler lowers high-level PSIL to flat micro bytecode.
+//
+// The supported subset is what an NPC brain needs: integer literals,
+// true/false, stack and arithmetic words, DEFINE'd words (inlined at each
+// use), and the [cond] [then] if / [cond] [then] [else] ifte combinators.
+// Quotations are compiled to forward jumps rather than a quotation table,
+// so the output can be loaded as a genome directly. The condition leaves a
+// flag that the branch consumes; unlike the interpreter, the stack is not
+// restored after it.
+type Compiler struct {
+	// Words extends the vocabulary: each name expands to the given bytes.
+	// Host environments use it for sensors, outputs and constants.
+	Words map[string][]byte
+}
+
+// compileOps are the assembler mnemonics that make sense without a
+// quotation table. Ops that take an operand byte are left out: the
+// compiler emits a word as its opcode alone, and the VM would read the
+// next instruction as the operand.
+var compileOps = func() map[string]byte {
+	m := make(map[string]byte, len(mnemonics))
+	for name, op := range mnemonics {
+		switch {
+		case op == OpExec, op == OpIfte, op == OpDip, op == OpLoop:
+			continue
+		case Is2ByteOp(op), Is3ByteOp(op), IsVarLenOp(op):
+			continue
+		}
+		m[name] = op
+	}
+	return m
+}()
+
+// Compile parses PSIL source and returns the bytecode of its top-level
+// expressions.
+func (c *Compiler) Compile(source string) ([]byte, error) {
+	prog, err := parser.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	values, defs := prog.ToValues()
+	code, err := c.body(values, defs, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// body compiles a sequence of values. active holds the definitions being
+// expanded, to reject recursion (which has no flat encoding).
+func (c *Compiler) body(values []types.Value, defs map[string]*types.Quotation, active map[string]bool) ([]byte, error) {
+	var code []byte
+	var pending [][]byte // compiled quotations waiting for a combinator
+
+	for _, v := range values {
+		switch v := v.(type) {
+		case *types.Quotation:
+			q, err := c.body(v.Items, defs, active)
+			if err != nil {
+				return nil, err
+			}
+			pending = append(pending, q)
+			continue
+
+		case types.Symbol:
+			switch name := string(v); name {
+			case "if":
+				if len(pending) < 2 {
+					return nil, fmt.Errorf("if needs [cond] [then]")
+				}
+				cond, then := pending[len(pending)-2], pending[len(pending)-1]
+				pending = pending[:len(pending)-2]
+				if len(then) > 255 {
+					return nil, fmt.Errorf("if: branch too long (%d bytes)", len(then))
+				}
+				code = append(code, cond...)
+				code = append(code, OpJumpZ, byte(len(then)))
+				code = append(code, then...)
+				continue
+
+			case "ifte":
+				if len(pending) < 3 {
+					return nil, fmt.Errorf("ifte needs [cond] [then] [else]")
+				}
+				cond, then, els := pending[len(pending)-3], pending[len(pending)-2], pending[len(pending)-1]
+				pending = pending[:len(pending)-3]
+				if len(then)+2 > 255 || len(els) > 255 {
+					return nil, fmt.Errorf("ifte: branch too long (%d/%d bytes)", len(then), len(els))
+				}
+				code = append(code, cond...)
+				code = append(code, OpJumpZ, byte(len(then)+2))
+				code = append(code, then...)
+				code = append(code, OpJump, byte(len(els)))
+				code = append(code, els...)
+				continue
+			}
+		}
+
+		if len(pending) > 0 {
+			return nil, fmt.Errorf("quotation not consumed by if/ifte before %s", v)
+		}
+
+		switch v := v.(type) {
+		case types.Number:
+			n := float64(v)
+			if n != math.Trunc(n) || n < math.MinInt16 || n > math.MaxInt16 {
+				return nil, fmt.Errorf("number %v is not a 16-bit integer", n)
+			}
+			code = appendNumber(code, int(n))
+
+		case types.Boolean:
+			if v {
+				code = appendNumber(code, 1)
+			} else {
+				code = appendNumber(code, 0)
+			}
+
+		case types.Symbol:
+			name := string(v)
+			if def, ok := defs[name]; ok {
+				if active[name] {
+					return nil, fmt.Errorf("recursive definition %s", name)
+				}
+				active[name] = true
+				sub, err := c.body(def.Items, defs, active)
+				delete(active, name)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", name, err)
+				}
+				code = append(code, sub...)
+			} else if w, ok := c.Words[name]; ok {
+				code = append(code, w...)
+			} else if op, ok := compileOps[name]; ok {
+				code = append(code, op)
+			} else {
+				return nil, fmt.Errorf("unknown word: %s", name)
+			}
+
+		default:
+			return nil, fmt.Errorf("unsupported %s: %s", v.Type(), v)
+		}
+	}
+
+	if len(pending) > 0 {
+		return nil, fmt.Errorf("quotation not consumed by if/ifte")
+	}
+	return code, nil
+}
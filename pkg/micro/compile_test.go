@@ -0,0 +1,54 @@
+package micro
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompileOpsTakeNoOperand(t *testing.T) {
+	for name, op := range compileOps {
+		if Is2ByteOp(op) || Is3ByteOp(op) || IsVarLenOp(op) {
+			t.Errorf("%s (%02x) takes an operand", name, op)
+		}
+	}
+}
+
+// TestCompileRoundTrip checks that compiled code disassembles into source
+// that assembles back to the same bytes, so every word compiled to whole
+// instructions.
+func TestCompileRoundTrip(t *testing.T) {
+	c := Compiler{Words: map[string][]byte{"hunger": {OpRing0R, 4}}}
+	for _, src := range []string{
+		"1 2 + dup * 300 - .",
+		"DEFINE sq == [dup *]. 7 sq 3 sq swap / halt",
+		"[hunger 10 >] [1 yield] if 0",
+		"[hunger 5 <] [2] [-3 neg] ifte 64 ! 64 @ 1+ 2dup = not",
+		"true false and 1000 200 mod depth clear",
+	} {
+		code, err := c.Compile(src)
+		if err != nil {
+			t.Errorf("%q: %v", src, err)
+			continue
+		}
+		if err := Verify(code); err != nil {
+			t.Errorf("%q: % x does not verify: %v", src, code, err)
+			continue
+		}
+		text := Disassemble(code)
+		got, err := NewAssembler().Assemble(stripAddresses(text))
+		if err != nil {
+			t.Errorf("%q: disassembly does not assemble: %v\n%s", src, err, text)
+		} else if !bytes.Equal(got, code) {
+			t.Errorf("%q: % x round trips to % x\n%s", src, code, got, text)
+		}
+	}
+
+	for _, src := range []string{"exec", "1 loop", "push.b 5", "jmp 2"} {
+		if code, err := c.Compile(src); err == nil {
+			t.Errorf("%q: compiled to % x; want an error", src, code)
+		} else if !strings.Contains(err.Error(), "unknown word") {
+			t.Errorf("%q: %v", src, err)
+		}
+	}
+}
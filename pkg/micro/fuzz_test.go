@@ -0,0 +1,153 @@
+package micro
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// seedGenomes returns the code checked into the repo (the reference
+// genomes and the .mpsil programs), plus some random bytes, to seed the
+// fuzz corpora.
+func seedGenomes(t testing.TB) [][]byte {
+	var genomes [][]byte
+	paths, _ := filepath.Glob("../../testdata/genomes/*.hex")
+	for _, path := range paths {
+		text, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(string(text)), "\n")
+		if genome, err := hex.DecodeString(strings.TrimSpace(line)); err == nil {
+			genomes = append(genomes, genome)
+		}
+	}
+	for _, src := range seedSources(t) {
+		if code, err := NewAssembler().Assemble(src); err == nil {
+			genomes = append(genomes, code)
+		}
+	}
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 8; i++ {
+		genome := make([]byte, 16+8*i)
+		rng.Read(genome)
+		genomes = append(genomes, genome)
+	}
+	return genomes
+}
+
+// seedSources returns the .mpsil programs in the repo.
+func seedSources(t testing.TB) []string {
+	var srcs []string
+	for _, dir := range []string{"../../testdata/sandbox", "../../examples/micro"} {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.mpsil"))
+		for _, path := range paths {
+			src, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srcs = append(srcs, string(src))
+		}
+	}
+	return srcs
+}
+
+// FuzzVMRun runs arbitrary code as a brain is run, resuming after every
+// yield. It must neither panic nor outlive its gas.
+func FuzzVMRun(f *testing.F) {
+	for _, genome := range seedGenomes(f) {
+		f.Add(genome, false)
+	}
+	f.Add([]byte{OpLoopN}, false)
+	f.Add([]byte{OpCallFar, 0}, false)
+	f.Add([]byte{0x23, OpPoke, 0x21, OpJumpBack, 4}, true)
+	f.Fuzz(func(t *testing.T, code []byte, selfModify bool) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			vm := New()
+			vm.Output = io.Discard
+			vm.MaxGas, vm.Gas = 500, 500
+			vm.SelfModify = selfModify
+			vm.Load(code)
+			for {
+				vm.Run()
+				if !vm.Yielded || vm.Gas <= 0 {
+					break
+				}
+				vm.Yielded = false
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("% x still running after 5s", code)
+		}
+	})
+}
+
+// FuzzDisassemble checks that any code disassembles without panicking,
+// and that code Verify accepts assembles back from its disassembly byte
+// for byte.
+func FuzzDisassemble(f *testing.F) {
+	for _, genome := range seedGenomes(f) {
+		f.Add(genome)
+	}
+	f.Add([]byte{})
+	f.Add([]byte{OpQuotDef, 3, OpDup, OpJump, 0})
+	f.Fuzz(func(t *testing.T, code []byte) {
+		text := Disassemble(code)
+		if Verify(code) != nil {
+			return
+		}
+		got, err := NewAssembler().Assemble(stripAddresses(text))
+		if err != nil {
+			t.Fatalf("% x: disassembly does not assemble: %v\n%s", code, err, text)
+		}
+		if !bytes.Equal(got, code) {
+			t.Fatalf("% x: round trip gives % x\n%s", code, got, text)
+		}
+	})
+}
+
+// FuzzAssemble checks that any source assembles or fails without
+// panicking, and that what it assembles to (unless raw opcodes cut it
+// short) survives a round trip through the disassembler.
+func FuzzAssemble(f *testing.F) {
+	for _, src := range seedSources(f) {
+		f.Add(src)
+	}
+	f.Add("loop: r0@ 5 jz done 3 r1! 0 yield jmp loop\ndone: halt")
+	f.Add(`"hi" 1 qdef { dup + } push.w -300 ld 4000`)
+	f.Fuzz(func(t *testing.T, src string) {
+		code, err := NewAssembler().Assemble(src)
+		if err != nil || Verify(code) != nil {
+			return
+		}
+		text := Disassemble(code)
+		got, err := NewAssembler().Assemble(stripAddresses(text))
+		if err != nil {
+			t.Fatalf("%q: disassembly does not assemble: %v\n%s", src, err, text)
+		}
+		if !bytes.Equal(got, code) {
+			t.Fatalf("%q: % x round trips to % x\n%s", src, code, got, text)
+		}
+	})
+}
+
+// stripAddresses turns a disassembly back into assembler source.
+func stripAddresses(text string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if _, inst, ok := strings.Cut(line, ": "); ok {
+			sb.WriteString(inst + "\n")
+		}
+	}
+	return sb.String()
+}
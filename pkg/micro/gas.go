@@ -0,0 +1,85 @@
+package micro
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GasTable is the gas each opcode costs, indexed by opcode
+type GasTable [256]uint8
+
+// FlatGas charges 1 per instruction, as a VM with no GasCosts does
+var FlatGas = flatGas()
+
+// WeightedGas charges roughly in proportion to what the opcode costs the
+// Z80 VM: 1 for a plain stack op, more for software multiply and divide,
+// memory, quotation calls, output and code that writes code.
+var WeightedGas = weightedGas()
+
+func flatGas() GasTable {
+	var t GasTable
+	for op := range t {
+		t[op] = 1
+	}
+	return t
+}
+
+func weightedGas() GasTable {
+	t := flatGas()
+	for op, cost := range map[byte]uint8{
+		OpRot: 2, OpDup2: 2,
+		OpMul: 4, OpDiv: 6, OpMod: 6,
+		OpExec: 3, OpIfte: 3, OpDip: 3, OpLoop: 3,
+		OpLoad: 2, OpStore: 2, OpPrint: 4, OpPushWord: 2,
+		OpSymbol: 2, OpSymbol16: 2, OpLocal: 2, OpSetLocal: 2,
+		OpRing0R: 2, OpRing1R: 2, OpRing1W: 2,
+		OpCall: 4, OpLoopN: 3, OpCallFar: 3,
+		OpLoad16: 3, OpStore16: 3, OpLoadW: 3, OpStoreW: 3,
+		OpStringVar: 2, OpQuotVar: 3,
+		OpQuotDef: 8, OpQuotDefM: 8, OpPoke: 4,
+	} {
+		t[op] = cost
+	}
+	return t
+}
+
+// ParseGasCosts reads a gas table: "flat" or "weighted" (the default),
+// then any name=cost overrides, e.g. "weighted,exec=5,*=2". A name is what
+// OpName calls the opcodes to change, so num, sym and quot cover their
+// whole ranges.
+func ParseGasCosts(spec string) (*GasTable, error) {
+	t := WeightedGas
+	for n, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		switch {
+		case part == "":
+			continue
+		case n == 0 && part == "flat":
+			t = FlatGas
+			continue
+		case n == 0 && part == "weighted":
+			continue
+		}
+		name, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("gas costs: %q is not opcode=cost", part)
+		}
+		cost, err := strconv.ParseUint(strings.TrimSpace(val), 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("gas costs: %s: %w", name, err)
+		}
+		name = strings.TrimSpace(name)
+		found := false
+		for op := range t {
+			if OpName(byte(op)) == name {
+				t[op] = uint8(cost)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("gas costs: unknown opcode %q", name)
+		}
+	}
+	return &t, nil
+}
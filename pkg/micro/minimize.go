@@ -0,0 +1,131 @@
+package micro
+
+// opLen returns the length of the instruction at pc, or what is left of
+// the code if it runs past the end
+func opLen(code []byte, pc int) int {
+	op := code[pc]
+	n := 1
+	switch {
+	case Is2ByteOp(op):
+		n = 2
+	case Is3ByteOp(op):
+		n = 3
+	case IsVarLenOp(op):
+		n = len(code) - pc
+		if pc+1 < len(code) {
+			n = 2 + int(code[pc+1])
+		}
+	}
+	return min(n, len(code)-pc)
+}
+
+// Cut returns code with the bytes from..to removed. Jumps and far calls
+// are moved so they still land on the same instruction; one that landed
+// inside the cut lands where the cut was. from and to should be
+// instruction boundaries.
+func Cut(code []byte, from, to int) []byte {
+	at := func(p int) int {
+		switch {
+		case p < from:
+			return p
+		case p < to:
+			return from
+		}
+		return p - (to - from)
+	}
+	out := make([]byte, 0, len(code)-(to-from))
+	for pc := 0; pc < len(code); {
+		n := opLen(code, pc)
+		if pc >= from && pc < to {
+			pc += n
+			continue
+		}
+		in := append([]byte(nil), code[pc:pc+n]...)
+		after := pc + n
+		if n == 2 {
+			switch in[0] {
+			case OpJump, OpJumpZ, OpJumpNZ:
+				in[1] = byte(at(after+int(in[1])) - at(after))
+			case OpJumpBack:
+				in[1] = byte(at(after) - at(after-int(in[1])))
+			}
+		}
+		if n == 3 {
+			val := int(int16(in[2]) | int16(in[1])<<8)
+			switch in[0] {
+			case OpJumpFar, OpJumpZFar:
+				val = at(after+val) - at(after)
+			case OpCallFar:
+				val = at(int(uint16(val)))
+			}
+			in[1], in[2] = byte(val>>8), byte(val)
+		}
+		out = append(out, in...)
+		pc = after
+	}
+	return out
+}
+
+// Minimize shrinks code for as long as keep accepts the result, and
+// returns the smallest code it found (code itself if keep rejects every
+// attempt). It cuts runs of instructions, halving the run length down to
+// one, and turns conditional jumps into never-taken drops, until nothing
+// more can go. keep must accept code.
+func Minimize(code []byte, keep func([]byte) bool) []byte {
+	code = append([]byte(nil), code...)
+	for {
+		shrunk := false
+		for run := len(starts(code)) / 2; run >= 1; run /= 2 {
+			for i := 0; ; {
+				pcs := starts(code)
+				if i >= len(pcs) {
+					break
+				}
+				end := len(code)
+				if i+run < len(pcs) {
+					end = pcs[i+run]
+				}
+				if try := Cut(code, pcs[i], end); keep(try) {
+					code, shrunk = try, true
+					continue
+				}
+				i++
+			}
+		}
+		for _, pc := range starts(code) {
+			n := opLen(code, pc)
+			switch {
+			case code[pc] == OpJumpZ || code[pc] == OpJumpNZ:
+				if n < 2 {
+					continue
+				}
+			case code[pc] == OpJumpZFar:
+				if n < 3 {
+					continue
+				}
+			default:
+				continue
+			}
+			try := append([]byte(nil), code...)
+			try[pc] = OpDrop
+			for i := pc + 1; i < pc+n; i++ {
+				try[i] = OpNop
+			}
+			if keep(try) {
+				code, shrunk = try, true
+			}
+		}
+		if !shrunk {
+			return code
+		}
+	}
+}
+
+// starts returns where each instruction of code begins
+func starts(code []byte) []int {
+	var pcs []int
+	for pc := 0; pc < len(code); pc += opLen(code, pc) {
+		pcs = append(pcs, pc)
+	}
+	return pcs
+}
@@ -2,6 +2,8 @@
 // Designed for easy Z80/6502 implementation with UTF-8 style encoding.
 package micro
 
+import "fmt"
+
 // Bytecode encoding (UTF-8 style):
 //
 // 0x00-0x7F: 1 byte (hot path - 128 values)
@@ -170,8 +172,20 @@ const (
 	OpActShare     = 0x99 // [0] share energy with nearest adjacent NPC
 	OpActTrade     = 0x9A // [0] trade with nearest adjacent NPC
 	OpActCraft     = 0x9B // [0] craft held item
+	OpActBuild     = 0x9C // [kind] build structure: 0=wall, 1=shelter, 2=chest
+	OpActDeposit   = 0x9D // [0] store held item in own chest
+	OpActWithdraw  = 0x9E // [0] take item from own chest
+	OpActShoot     = 0x9F // [arg] shoot: 0=facing, 1-4=dir, 5=toward NPC
+	OpActGive      = 0xA0 // [0] give item or gold to nearest adjacent NPC
+	OpActFollow    = 0xA1 // [arg] follow: 0=nearest NPC, 1=nearby leader
+	OpActJoin      = 0xA2 // [0] join nearest adjacent NPC's clan
+	OpActMate      = 0xA3 // [0] offer to mate with nearest adjacent NPC
+	OpActSleep     = 0xA4 // [0] sleep in place this tick
+	OpActBuy       = 0xA5 // [item] buy at a market: 0=meal, else item type
+	OpActSell      = 0xA6 // [0] sell held item at a market
+	OpActPost      = 0xA7 // [ask] trading post: offer held item for item ask (0=anything), or collect
 
-	// 0x9C-0xBF reserved
+	// 0xA8-0xBF reserved
 )
 
 // Is2ByteOp returns true if opcode is a 2-byte operation
@@ -187,7 +201,9 @@ const (
 	OpJumpFar   = 0xC3 // [hi][lo] far jump
 	OpJumpZFar  = 0xC4 // [hi][lo] far jump if zero
 	OpCallFar   = 0xC5 // [hi][lo] call address
-	// 0xC6-0xDF reserved
+	OpStore16   = 0xC6 // [hi][lo] store to 16-bit address (st)
+	OpLoad16    = 0xC7 // [hi][lo] load from 16-bit address (ld)
+	// 0xC8-0xDF reserved
 )
 
 // Is3ByteOp returns true if opcode is a 3-byte operation
@@ -201,7 +217,8 @@ const (
 	OpBytesVar  = 0xE1 // [len][bytes...] raw bytes
 	OpVectorVar = 0xE2 // [len][items...] vector of values
 	OpQuotVar   = 0xE3 // [len][bytes...] inline quotation body
-	// 0xE4-0xEF reserved
+	OpQuotDef   = 0xE4 // [len][bytes...] idx -- (define quotation idx)
+	// 0xE5-0xEF reserved
 )
 
 // IsVarLenOp returns true if opcode is variable length
@@ -218,10 +235,39 @@ const (
 	OpError   = 0xF4 // set error flag
 	OpClearE  = 0xF5 // clear error
 	OpCheckE  = 0xF6 // check error flag
+	OpQuotDefM = 0xF7 // idx slot len -- (define quotation idx from memory)
+	OpPoke    = 0xF8 // byte addr -- (patch running code, if SelfModify)
+	OpLoadW   = 0xF9 // addr -- value (load, 16-bit address)
+	OpStoreW  = 0xFA // value addr -- (store, 16-bit address)
 	OpExtend  = 0xFE // [ext][...] extended opcode
 	OpEnd     = 0xFF // end marker
 )
 
+// Verify checks that code decodes into whole instructions: no operand or
+// length-prefixed body runs past the end
+func Verify(code []byte) error {
+	for pc := 0; pc < len(code); {
+		op := code[pc]
+		n := 1
+		switch {
+		case Is2ByteOp(op):
+			n = 2
+		case Is3ByteOp(op):
+			n = 3
+		case IsVarLenOp(op):
+			if pc+1 >= len(code) {
+				return fmt.Errorf("%04X: %s has no length", pc, OpName(op))
+			}
+			n = 2 + int(code[pc+1])
+		}
+		if pc+n > len(code) {
+			return fmt.Errorf("%04X: %s runs past the end", pc, OpName(op))
+		}
+		pc += n
+	}
+	return nil
+}
+
 // IsSpecialOp returns true if opcode is a special operation
 func IsSpecialOp(op byte) bool {
 	return op >= 0xF0
@@ -257,19 +303,38 @@ func OpName(op byte) string {
 			OpActMove: "act.move", OpActAttack: "act.attack", OpActHeal: "act.heal",
 			OpActEat: "act.eat", OpActHarvest: "act.harvest", OpActTerraform: "act.terra",
 			OpActShare: "act.share", OpActTrade: "act.trade", OpActCraft: "act.craft",
+			OpActBuild: "act.build", OpActDeposit: "act.deposit", OpActWithdraw: "act.withdraw",
+			OpActShoot: "act.shoot", OpActGive: "act.give",
+			OpActFollow: "act.follow", OpActJoin: "act.join", OpActMate: "act.mate",
+			OpActSleep: "act.sleep", OpActBuy: "act.buy", OpActSell: "act.sell",
+			OpActPost: "act.post",
 		}
 		if n, ok := names[op]; ok {
 			return n
 		}
 		return "2op"
+	case op == OpLoad16:
+		return "ld"
+	case op == OpStore16:
+		return "st"
 	case Is3ByteOp(op):
 		return "3op"
+	case op == OpQuotDef:
+		return "qdef"
 	case IsVarLenOp(op):
 		return "var"
 	case op == OpHalt:
 		return "halt"
 	case op == OpYield:
 		return "yield"
+	case op == OpQuotDefM:
+		return "qdef.m"
+	case op == OpPoke:
+		return "poke"
+	case op == OpLoadW:
+		return "@w"
+	case op == OpStoreW:
+		return "!w"
 	case op == OpEnd:
 		return "end"
 	default:
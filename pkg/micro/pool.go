@@ -0,0 +1,34 @@
+package micro
+
+import (
+	"os"
+	"sync"
+)
+
+// Pool recycles VMs, so hosts that start many short runs (the sandbox,
+// servers) reuse their stacks, tables and memory instead of allocating
+// new ones. The zero Pool is ready to use and safe for concurrent use.
+type Pool struct {
+	vms sync.Pool
+}
+
+// Get returns a VM as good as New's
+func (p *Pool) Get() *VM {
+	if vm, ok := p.vms.Get().(*VM); ok {
+		return vm
+	}
+	return New()
+}
+
+// Put wipes vm and keeps it for a later Get. vm must not be used after.
+func (p *Pool) Put(vm *VM) {
+	vm.Wipe()
+	vm.SetMemorySlots(DefaultMemorySlots)
+	vm.Output = os.Stdout
+	vm.Debug = false
+	vm.Trace = nil
+	vm.Gas, vm.MaxGas = 0, 0
+	vm.GasCosts = nil
+	vm.SelfModify = false
+	p.vms.Put(vm)
+}
@@ -0,0 +1,456 @@
+package micro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Vector is a test both VMs run: a program, the state it starts in and the
+// state it must leave behind. The Z80 port runs the same vectors from
+// EncodeVectors' binary form (z80/test_vectors.asm), so the two can be
+// shown to agree op by op.
+type Vector struct {
+	Name       string        `json:"name"`
+	Program    Hex           `json:"program"`
+	Quotations []Hex         `json:"quotations,omitempty"` // bodies, by index
+	Memory     map[int]int16 `json:"memory,omitempty"`     // initial, by slot
+	Gas        int           `json:"gas,omitempty"`        // 0 = unlimited
+	Expect     State         `json:"expect"`
+}
+
+// State is what a run leaves behind
+type State struct {
+	Stack   []int16       `json:"stack"`            // bottom first
+	Memory  map[int]int16 `json:"memory,omitempty"` // nonzero slots
+	Halted  bool          `json:"halted,omitempty"`
+	Yielded bool          `json:"yielded,omitempty"`
+	Error   bool          `json:"error,omitempty"` // CFlag
+	Code    byte          `json:"code,omitempty"`  // AReg
+	GasLeft int           `json:"gas_left,omitempty"`
+	Output  string        `json:"output,omitempty"`
+}
+
+// Hex is bytecode that reads and writes as a hex string
+type Hex []byte
+
+func (h Hex) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *Hex) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	*h = b
+	return err
+}
+
+// Run runs the vector on a fresh Go VM and returns the state it ends in
+func (v Vector) Run() State {
+	vm := New()
+	var out bytes.Buffer
+	vm.Output = &out
+	for slot, val := range v.Memory {
+		vm.MemWriteAt(slot, val)
+	}
+	for i, q := range v.Quotations {
+		vm.DefineQuot(i, q)
+	}
+	if v.Gas > 0 {
+		vm.MaxGas, vm.Gas = v.Gas, v.Gas
+	}
+	vm.Load(v.Program)
+	vm.Run()
+
+	s := State{
+		Stack:   stackValues(vm),
+		Halted:  vm.Halted,
+		Yielded: vm.Yielded,
+		Error:   vm.CFlag,
+		Code:    vm.AReg,
+		Output:  out.String(),
+	}
+	if v.Gas > 0 {
+		s.GasLeft = vm.Gas
+	}
+	for slot := 0; slot < vm.MemorySlots(); slot++ {
+		if val := vm.MemReadAt(slot); val != 0 {
+			if s.Memory == nil {
+				s.Memory = make(map[int]int16)
+			}
+			s.Memory[slot] = val
+		}
+	}
+	return s
+}
+
+// Check runs the vector and reports how the state differs from Expect
+func (v Vector) Check() error {
+	got := v.Run()
+	if reflect.DeepEqual(got, v.Expect) {
+		return nil
+	}
+	return fmt.Errorf("%s: got %+v, want %+v", v.Name, got, v.Expect)
+}
+
+// stackValues returns the values on the stack, bottom first, bytes and
+// words alike
+func stackValues(vm *VM) []int16 {
+	values := []int16{}
+	for pos := 0; pos < vm.SP; {
+		switch vm.Stack[pos] {
+		case 1:
+			values = append(values, int16(vm.Stack[pos+1]))
+			pos += 2
+		case 2:
+			values = append(values, int16(vm.Stack[pos+1])|int16(vm.Stack[pos+2])<<8)
+			pos += 3
+		default:
+			return values
+		}
+	}
+	return values
+}
+
+// vectorDef is a vector in source form
+type vectorDef struct {
+	name   string
+	src    string
+	quots  []string
+	memory map[int]int16
+	gas    int
+	expect State
+}
+
+// vectorDefs cover what the Go VM and the Z80 port both implement, op by
+// op. Left out: depth (Go counts bytes of tagged values), pick, the action
+// ops that set a target (or, on the Z80, a move too), the Go-only
+// extensions (qdef, far jumps, 16-bit addressing, strings, error ops) and
+// push.b values on top of a word, which Go's tagged stack can misread.
+// Quotations end in ret, as the Z80 runs on into the next body otherwise,
+// and programs that run out of gas end in halt, as running off the end
+// costs the Z80 an instruction. Expected states are written out rather
+// than taken from a run, so the Go VM is held to them as much as the Z80.
+var vectorDefs = []vectorDef{
+	// Literals
+	{name: "num", src: "0 1 31",
+		expect: State{Stack: []int16{0, 1, 31}, Halted: true}},
+	{name: "push.b", src: "32 push.b 200 255",
+		expect: State{Stack: []int16{32, 200, 255}, Halted: true}},
+	{name: "push.w", src: "push.w 4660 -300 push.w -32768 push.w 32767",
+		expect: State{Stack: []int16{4660, -300, -32768, 32767}, Halted: true}},
+	{name: "sym", src: "nil true x y",
+		expect: State{Stack: []int16{0, 1, 24, 25}, Halted: true}},
+	{name: "quot", src: "[0] [31] quot.x 40",
+		expect: State{Stack: []int16{-32768, -32737, -32728}, Halted: true}},
+
+	// Stack
+	{name: "nop", src: "1 nop nop",
+		expect: State{Stack: []int16{1}, Halted: true}},
+	{name: "dup", src: "5 dup",
+		expect: State{Stack: []int16{5, 5}, Halted: true}},
+	{name: "dup.b", src: "200 dup",
+		expect: State{Stack: []int16{200, 200}, Halted: true}},
+	{name: "drop", src: "1 2 drop",
+		expect: State{Stack: []int16{1}, Halted: true}},
+	{name: "swap", src: "1 2 swap",
+		expect: State{Stack: []int16{2, 1}, Halted: true}},
+	{name: "over", src: "1 2 over",
+		expect: State{Stack: []int16{1, 2, 1}, Halted: true}},
+	{name: "rot", src: "1 2 3 rot",
+		expect: State{Stack: []int16{2, 3, 1}, Halted: true}},
+	{name: "dup2", src: "1 2 dup2",
+		expect: State{Stack: []int16{1, 2, 1, 2}, Halted: true}},
+	{name: "clear", src: "1 2 3 clear 4",
+		expect: State{Stack: []int16{4}, Halted: true}},
+
+	// Arithmetic
+	{name: "add", src: "3 4 +",
+		expect: State{Stack: []int16{7}, Halted: true}},
+	{name: "add.wrap", src: "push.w 32767 1 +",
+		expect: State{Stack: []int16{-32768}, Halted: true}},
+	{name: "sub", src: "3 10 -",
+		expect: State{Stack: []int16{-7}, Halted: true}},
+	{name: "sub.wrap", src: "push.w -32768 1 -",
+		expect: State{Stack: []int16{32767}, Halted: true}},
+	{name: "mul", src: "-7 6 *",
+		expect: State{Stack: []int16{-42}, Halted: true}},
+	{name: "mul.wrap", src: "300 300 *",
+		expect: State{Stack: []int16{24464}, Halted: true}},
+	{name: "div", src: "17 5 /",
+		expect: State{Stack: []int16{3}, Halted: true}},
+	{name: "div.neg", src: "-17 5 / 17 -5 / -17 -5 /",
+		expect: State{Stack: []int16{-3, -3, 3}, Halted: true}},
+	{name: "div.min", src: "push.w -32768 -1 /",
+		expect: State{Stack: []int16{-32768}, Halted: true}},
+	{name: "mod", src: "17 5 mod",
+		expect: State{Stack: []int16{2}, Halted: true}},
+	{name: "mod.neg", src: "-17 5 mod 17 -5 mod -17 -5 mod",
+		expect: State{Stack: []int16{-2, 2, -2}, Halted: true}},
+	{name: "inc", src: "5 inc push.w 32767 inc",
+		expect: State{Stack: []int16{6, -32768}, Halted: true}},
+	{name: "dec", src: "0 dec push.w -32768 dec",
+		expect: State{Stack: []int16{-1, 32767}, Halted: true}},
+	{name: "neg", src: "5 neg -5 neg 0 neg push.w -32768 neg",
+		expect: State{Stack: []int16{-5, 5, 0, -32768}, Halted: true}},
+
+	// Comparison and logic
+	{name: "eq", src: "3 3 = 3 4 = -1 -1 =",
+		expect: State{Stack: []int16{1, 0, 1}, Halted: true}},
+	{name: "lt", src: "3 4 < 4 3 < 3 3 < -1 1 <",
+		expect: State{Stack: []int16{1, 0, 0, 1}, Halted: true}},
+	{name: "gt", src: "4 3 > 3 4 > 3 3 > 1 -1 >",
+		expect: State{Stack: []int16{1, 0, 0, 1}, Halted: true}},
+	{name: "and", src: "12 10 and -1 push.w 255 and",
+		expect: State{Stack: []int16{8, 255}, Halted: true}},
+	{name: "or", src: "12 10 or -256 push.w 255 or",
+		expect: State{Stack: []int16{14, -1}, Halted: true}},
+	{name: "not", src: "0 not 5 not -1 not",
+		expect: State{Stack: []int16{1, 0, 0}, Halted: true}},
+
+	// Memory
+	{name: "store", src: "42 x ! push.w -1234 push.w 63 !",
+		expect: State{Stack: []int16{}, Memory: map[int]int16{24: 42, 63: -1234}, Halted: true}},
+	{name: "load", src: "5 @ 6 @", memory: map[int]int16{5: 77, 6: -2},
+		expect: State{Stack: []int16{77, -2}, Memory: map[int]int16{5: 77, 6: -2}, Halted: true}},
+	{name: "store.load", src: "9 temp ! temp @ temp @ +",
+		expect: State{Stack: []int16{18}, Memory: map[int]int16{23: 9}, Halted: true}},
+	{name: "sym.x", src: "sym.x 7 sym.x 40", memory: map[int]int16{7: -2, 40: 1000},
+		expect: State{Stack: []int16{-2, 1000}, Memory: map[int]int16{7: -2, 40: 1000}, Halted: true}},
+	{name: "ld.st", src: "push.w 777 st 5 ld 5 ld 6", memory: map[int]int16{6: -3},
+		expect: State{Stack: []int16{777, -3}, Memory: map[int]int16{5: 777, 6: -3}, Halted: true}},
+	{name: "@w.!w", src: "push.w -9 7 !w 7 @w",
+		expect: State{Stack: []int16{-9}, Memory: map[int]int16{7: -9}, Halted: true}},
+	{name: "r0@", src: "r0@ 3 r0@ 53", memory: map[int]int16{3: 99, 53: -9},
+		expect: State{Stack: []int16{99, -9}, Memory: map[int]int16{3: 99, 53: -9}, Halted: true}},
+	{name: "r1@", src: "r1@ 1", memory: map[int]int16{65: 12},
+		expect: State{Stack: []int16{12}, Memory: map[int]int16{65: 12}, Halted: true}},
+	{name: "r1!", src: "7 r1! 2 -1 r1! 4",
+		expect: State{Stack: []int16{}, Memory: map[int]int16{66: 7, 68: -1}, Halted: true}},
+	{name: "local", src: "9 local! 3 local 3 local 3 +",
+		expect: State{Stack: []int16{18}, Halted: true}},
+
+	// Control
+	{name: "jmp", src: "1 jmp 1 2 3",
+		expect: State{Stack: []int16{1, 3}, Halted: true}},
+	{name: "jmp-", src: "5 dec dup jz 2 jmp -6",
+		expect: State{Stack: []int16{0}, Halted: true}},
+	{name: "jz", src: "0 jz 1 7 8 1 jz 1 7 8",
+		expect: State{Stack: []int16{8, 7, 8}, Halted: true}},
+	{name: "jnz", src: "1 jnz 1 7 8 0 jnz 1 7 8",
+		expect: State{Stack: []int16{8, 7, 8}, Halted: true}},
+	{name: "countdown", src: "0 4 dup rot + swap dec dup jz 2 jmp -10 drop",
+		expect: State{Stack: []int16{10}, Halted: true}},
+	{name: "halt", src: "1 halt 2",
+		expect: State{Stack: []int16{1}, Halted: true}},
+	{name: "end", src: "1 end 2",
+		expect: State{Stack: []int16{1}, Halted: true}},
+	{name: "yield", src: "1 yield 2",
+		expect: State{Stack: []int16{1}, Yielded: true}},
+	{name: "ret", src: "1 ret 2",
+		expect: State{Stack: []int16{1}, Halted: true}},
+
+	// Quotations
+	{name: "exec", src: "[0] exec", quots: []string{"7 ret"},
+		expect: State{Stack: []int16{7}, Halted: true}},
+	{name: "exec.args", src: "3 [0] exec", quots: []string{"dup * ret"},
+		expect: State{Stack: []int16{9}, Halted: true}},
+	{name: "exec.nested", src: "[0] exec", quots: []string{"[1] exec 1 + ret", "10 ret"},
+		expect: State{Stack: []int16{11}, Halted: true}},
+	{name: "exec.halt", src: "[0] exec 2", quots: []string{"1 halt"},
+		expect: State{Stack: []int16{1, 2}, Halted: true}},
+	{name: "ifte.then", src: "1 [0] [1] ifte", quots: []string{"10 ret", "20 ret"},
+		expect: State{Stack: []int16{10}, Halted: true}},
+	{name: "ifte.else", src: "0 [0] [1] ifte", quots: []string{"10 ret", "20 ret"},
+		expect: State{Stack: []int16{20}, Halted: true}},
+	{name: "dip", src: "1 2 [0] dip", quots: []string{"10 + ret"},
+		expect: State{Stack: []int16{11, 2}, Halted: true}},
+	{name: "loop", src: "0 5 [0] loop", quots: []string{"inc ret"},
+		expect: State{Stack: []int16{5}, Halted: true}},
+	{name: "loop.zero", src: "0 0 [0] loop", quots: []string{"inc ret"},
+		expect: State{Stack: []int16{0}, Halted: true}},
+
+	// Builtins and output
+	{name: "print", src: "42 print 0 print -7 print push.w -32768 print push.w 32767 print",
+		expect: State{Stack: []int16{}, Halted: true, Output: "420-7-3276832767"}},
+	{name: "call.nl", src: "1 print call 0 2 print",
+		expect: State{Stack: []int16{}, Halted: true, Output: "1\n2"}},
+	{name: "call.space", src: "1 print call 1 2 print",
+		expect: State{Stack: []int16{}, Halted: true, Output: "1 2"}},
+	{name: "call.char", src: "72 call 2 105 call 2",
+		expect: State{Stack: []int16{}, Halted: true, Output: "Hi"}},
+	{name: "call.abs", src: "-5 call 3 5 call 3",
+		expect: State{Stack: []int16{5, 5}, Halted: true}},
+	{name: "call.min", src: "3 -2 call 4 -2 3 call 4",
+		expect: State{Stack: []int16{-2, -2}, Halted: true}},
+	{name: "call.max", src: "3 -2 call 5 -2 3 call 5",
+		expect: State{Stack: []int16{3, 3}, Halted: true}},
+
+	// Actions
+	{name: "act.move", src: "7 act.move 3 8",
+		expect: State{Stack: []int16{7}, Memory: map[int]int16{64: 3}, Yielded: true}},
+	{name: "act.move.food", src: "act.move 5", memory: map[int]int16{13: 2},
+		expect: State{Stack: []int16{}, Memory: map[int]int16{13: 2, 64: 2}, Yielded: true}},
+	{name: "act.move.npc", src: "act.move 6", memory: map[int]int16{18: 4},
+		expect: State{Stack: []int16{}, Memory: map[int]int16{18: 4, 64: 4}, Yielded: true}},
+	{name: "act.move.item", src: "act.move 7", memory: map[int]int16{19: 1},
+		expect: State{Stack: []int16{}, Memory: map[int]int16{19: 1, 64: 1}, Yielded: true}},
+	{name: "act.harvest", src: "act.harvest 0",
+		expect: State{Stack: []int16{}, Memory: map[int]int16{65: 8}, Yielded: true}},
+	{name: "act.terra", src: "act.terra 0",
+		expect: State{Stack: []int16{}, Memory: map[int]int16{65: 9}, Yielded: true}},
+	{name: "act.craft", src: "act.craft 0",
+		expect: State{Stack: []int16{}, Memory: map[int]int16{65: 5}, Yielded: true}},
+
+	// Gas
+	{name: "gas.left", src: "1 2 + halt", gas: 20,
+		expect: State{Stack: []int16{3}, Halted: true, GasLeft: 16}},
+	{name: "gas.out", src: "1 2 3 4 5 6 7 8 9 10 11 12 halt", gas: 10,
+		expect: State{Stack: []int16{1, 2, 3, 4, 5, 6, 7, 8, 9}, Error: true, Code: 5}},
+	{name: "gas.op", src: "gas 10 1 halt", gas: 50,
+		expect: State{Stack: []int16{1}, Halted: true, GasLeft: 37}},
+	{name: "gas.quot", src: "[0] exec halt", quots: []string{"1 2 ret"}, gas: 30,
+		expect: State{Stack: []int16{1, 2}, Halted: true, GasLeft: 24}},
+}
+
+// Vectors returns the standard vectors, assembled
+func Vectors() ([]Vector, error) {
+	vectors := make([]Vector, 0, len(vectorDefs))
+	for _, d := range vectorDefs {
+		v := Vector{Name: d.name, Memory: d.memory, Gas: d.gas, Expect: d.expect}
+		var err error
+		if v.Program, err = NewAssembler().Assemble(d.src); err != nil {
+			return nil, fmt.Errorf("%s: %v", d.name, err)
+		}
+		for _, src := range d.quots {
+			q, err := NewAssembler().Assemble(src)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", d.name, err)
+			}
+			v.Quotations = append(v.Quotations, q)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+// VectorFiles returns the standard vectors as the files the Z80 build and
+// other ports read, by name: vectors.json and its binary form vectors.bin
+func VectorFiles() (map[string][]byte, error) {
+	vectors, err := Vectors()
+	if err != nil {
+		return nil, err
+	}
+	text, err := json.MarshalIndent(vectors, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	bin, err := EncodeVectors(vectors)
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{
+		"vectors.json": append(text, '\n'),
+		"vectors.bin":  bin,
+	}, nil
+}
+
+// EncodeVectors writes vectors in the binary form the Z80 harness reads.
+// Words are little-endian; a count byte (or word) precedes each list:
+//
+//	count:w, then per vector:
+//	  name:  len:b bytes
+//	  gas:w
+//	  code:  len:w bytes, then a halt the length leaves out
+//	  quots: len:w, then the quotation blob: count:b, len:w each, bodies
+//	  memory: count:b, slot:b value:w each
+//	  flags:b (1 halted, 2 yielded, 4 error), gas left:w
+//	  stack: count:b, value:w each, bottom first
+//	  memory: count:b, slot:b value:w each (every nonzero slot)
+//	  output: len:b bytes
+//
+// The halt stops a program that runs off its end, as the Go VM does.
+func EncodeVectors(vectors []Vector) ([]byte, error) {
+	var b bytes.Buffer
+	word := func(n int) {
+		binary.Write(&b, binary.LittleEndian, uint16(n))
+	}
+	memory := func(m map[int]int16) error {
+		slots := make([]int, 0, len(m))
+		for slot := range m {
+			if slot < 0 || slot > 255 {
+				return fmt.Errorf("slot %d out of reach", slot)
+			}
+			slots = append(slots, slot)
+		}
+		if len(slots) > 255 {
+			return fmt.Errorf("%d memory slots", len(slots))
+		}
+		sort.Ints(slots)
+		b.WriteByte(byte(len(slots)))
+		for _, slot := range slots {
+			b.WriteByte(byte(slot))
+			word(int(m[slot]))
+		}
+		return nil
+	}
+
+	word(len(vectors))
+	for _, v := range vectors {
+		e := v.Expect
+		switch {
+		case len(v.Name) > 255, len(e.Output) > 255, len(e.Stack) > 255:
+			return nil, fmt.Errorf("%s: too big to encode", v.Name)
+		case len(v.Quotations) > 32:
+			return nil, fmt.Errorf("%s: %d quotations", v.Name, len(v.Quotations))
+		}
+		b.WriteByte(byte(len(v.Name)))
+		b.WriteString(v.Name)
+		word(v.Gas)
+		word(len(v.Program))
+		b.Write(v.Program)
+		b.WriteByte(OpHalt)
+
+		var quots bytes.Buffer
+		if len(v.Quotations) > 0 {
+			quots.WriteByte(byte(len(v.Quotations)))
+			for _, q := range v.Quotations {
+				binary.Write(&quots, binary.LittleEndian, uint16(len(q)))
+			}
+			for _, q := range v.Quotations {
+				quots.Write(q)
+			}
+		}
+		word(quots.Len())
+		b.Write(quots.Bytes())
+		if err := memory(v.Memory); err != nil {
+			return nil, fmt.Errorf("%s: %v", v.Name, err)
+		}
+
+		var flags byte
+		if e.Halted {
+			flags |= 1
+		}
+		if e.Yielded {
+			flags |= 2
+		}
+		if e.Error {
+			flags |= 4
+		}
+		b.WriteByte(flags)
+		word(e.GasLeft)
+		b.WriteByte(byte(len(e.Stack)))
+		for _, val := range e.Stack {
+			word(int(val))
+		}
+		if err := memory(e.Memory); err != nil {
+			return nil, fmt.Errorf("%s: %v", v.Name, err)
+		}
+		b.WriteByte(byte(len(e.Output)))
+		b.WriteString(e.Output)
+	}
+	return b.Bytes(), nil
+}
@@ -21,8 +21,10 @@ type VM struct {
 	// Quotations table (indexed by quotation number)
 	Quotations [][]byte
 
-	// Memory/symbols (256 slots, 2 bytes each)
-	Memory [512]byte
+	// Memory/symbols, 2 bytes per slot: DefaultMemorySlots unless grown
+	// with SetMemorySlots. @ and ! reach the first 256 slots, @w, !w, ld
+	// and st all of them.
+	Memory []byte
 
 	// Flags (Z80 style)
 	ZFlag bool // Zero/comparison result
@@ -33,6 +35,10 @@ type VM struct {
 	Gas    int
 	MaxGas int
 
+	// GasCosts, if set, is what each instruction costs in gas (see
+	// WeightedGas); otherwise each costs 1
+	GasCosts *GasTable
+
 	// Call stack for quotation execution
 	CallStack []int
 	CallSP    int
@@ -46,18 +52,35 @@ type VM struct {
 	// Debug mode
 	Debug bool
 
+	// Trace, if set, is called before each instruction runs, with its
+	// address in Code
+	Trace func(vm *VM, pc int)
+
 	// Halted
 	Halted bool
 
 	// Yielded — set by OpYield, cleared by caller to resume
 	Yielded bool
+
+	// SelfModify lets OpPoke patch the running code. Off by default, when
+	// poke just drops its operands; on, Load runs a copy, so patches never
+	// reach the caller's bytes.
+	SelfModify bool
+	own        []byte // Load's copy of the code when SelfModify is set
 }
 
+// Memory sizes, in 16-bit slots
+const (
+	DefaultMemorySlots = 256   // what @ and ! can address
+	MaxMemorySlots     = 65536 // what a 16-bit address can
+)
+
 // New creates a new VM
 func New() *VM {
 	return &VM{
 		Stack:      make([]byte, 1024),
 		SP:         0,
+		Memory:     make([]byte, 2*DefaultMemorySlots),
 		Quotations: make([][]byte, 256),
 		CallStack:  make([]int, 64),
 		Output:     os.Stdout,
@@ -81,8 +104,23 @@ func (vm *VM) Reset() {
 	}
 }
 
+// Wipe clears memory, locals and quotations as well as execution state,
+// leaving the VM as good as new (Output and gas limits aside) without
+// reallocating anything.
+func (vm *VM) Wipe() {
+	vm.Reset()
+	clear(vm.Memory)
+	vm.Locals = [16]int16{}
+	clear(vm.Quotations)
+	vm.Code = nil
+}
+
 // Load loads bytecode into the VM
 func (vm *VM) Load(code []byte) {
+	if vm.SelfModify {
+		vm.own = append(vm.own[:0], code...)
+		code = vm.own
+	}
 	vm.Code = code
 	vm.PC = 0
 }
@@ -309,23 +347,83 @@ func (vm *VM) Over() {
 
 // === Memory operations ===
 
+// SetMemorySlots resizes memory to n slots (at least DefaultMemorySlots,
+// at most MaxMemorySlots), keeping what the slots that remain hold. New
+// slots are zero; it only allocates to grow past the largest size so far.
+func (vm *VM) SetMemorySlots(n int) {
+	n = min(max(n, DefaultMemorySlots), MaxMemorySlots)
+	if 2*n <= cap(vm.Memory) {
+		old := len(vm.Memory)
+		vm.Memory = vm.Memory[:2*n]
+		if 2*n > old {
+			clear(vm.Memory[old:])
+		}
+		return
+	}
+	mem := make([]byte, 2*n)
+	copy(mem, vm.Memory)
+	vm.Memory = mem
+}
+
+// MemorySlots returns how many slots memory has
+func (vm *VM) MemorySlots() int {
+	return len(vm.Memory) / 2
+}
+
 // MemRead reads a 16-bit value from memory slot
 func (vm *VM) MemRead(slot byte) int16 {
-	idx := int(slot) * 2
-	if idx+1 >= len(vm.Memory) {
+	return vm.MemReadAt(int(slot))
+}
+
+// MemWrite writes a 16-bit value to memory slot
+func (vm *VM) MemWrite(slot byte, v int16) {
+	vm.MemWriteAt(int(slot), v)
+}
+
+// MemReadAt reads a 16-bit value from any memory slot (0 past the end)
+func (vm *VM) MemReadAt(slot int) int16 {
+	idx := slot * 2
+	if slot < 0 || idx+1 >= len(vm.Memory) {
 		return 0
 	}
 	return int16(vm.Memory[idx]) | (int16(vm.Memory[idx+1]) << 8)
 }
 
-// MemWrite writes a 16-bit value to memory slot
-func (vm *VM) MemWrite(slot byte, v int16) {
-	idx := int(slot) * 2
-	if idx+1 >= len(vm.Memory) {
-		return
+// MemWriteAt writes a 16-bit value to any memory slot, reporting false
+// (and writing nothing) past the end
+func (vm *VM) MemWriteAt(slot int, v int16) bool {
+	idx := slot * 2
+	if slot < 0 || idx+1 >= len(vm.Memory) {
+		return false
 	}
 	vm.Memory[idx] = byte(v & 0xFF)
 	vm.Memory[idx+1] = byte((v >> 8) & 0xFF)
+	return true
+}
+
+// loadAt pushes memory slot addr, setting the error flag past the end
+func (vm *VM) loadAt(addr int) error {
+	if addr*2+1 >= len(vm.Memory) {
+		vm.CFlag = true
+		vm.AReg = 7 // memory range
+		return fmt.Errorf("memory address %d out of range", addr)
+	}
+	vm.PushWord(vm.MemReadAt(addr))
+	return nil
+}
+
+// storeAt pops into memory slot addr, setting the error flag past the end
+func (vm *VM) storeAt(addr int) error {
+	v := vm.PopWord()
+	if vm.CFlag {
+		return nil
+	}
+	if !vm.MemWriteAt(addr, v) {
+		vm.CFlag = true
+		vm.AReg = 7 // memory range
+		return fmt.Errorf("memory address %d out of range", addr)
+	}
+	return nil
 }
 
 // === Execution ===
@@ -343,7 +441,11 @@ func (vm *VM) Step() error {
 
 	// Gas check
 	if vm.MaxGas > 0 {
-		vm.Gas--
+		if vm.GasCosts != nil {
+			vm.Gas -= int(vm.GasCosts[vm.Code[vm.PC]])
+		} else {
+			vm.Gas--
+		}
 		if vm.Gas <= 0 {
 			vm.CFlag = true
 			vm.AReg = 5 // gas exhausted
@@ -351,6 +453,10 @@ func (vm *VM) Step() error {
 		}
 	}
 
+	if vm.Trace != nil {
+		vm.Trace(vm, vm.PC)
+	}
+
 	op := vm.Code[vm.PC]
 	vm.PC++
 
@@ -434,6 +540,41 @@ func (vm *VM) Step() error {
 		} else {
 			vm.PushInt(0)
 		}
+	case op == OpQuotDefM:
+		// idx slot len -> ; len bytes of memory starting at slot
+		n := vm.PopInt()
+		start := int(uint16(vm.PopWord())) * 2
+		idx := vm.PopInt()
+		if vm.CFlag {
+			return nil
+		}
+		if n < 0 || start+n > len(vm.Memory) {
+			vm.CFlag = true
+			vm.AReg = 7 // memory range
+			return fmt.Errorf("memory range %d+%d", start, n)
+		}
+		return vm.defineQuotation(idx, vm.Memory[start:start+n])
+	case op == OpLoadW:
+		// addr -> value
+		addr := int(uint16(vm.PopWord()))
+		if vm.CFlag {
+			return nil
+		}
+		return vm.loadAt(addr)
+	case op == OpStoreW:
+		// value addr ->
+		addr := int(uint16(vm.PopWord()))
+		if vm.CFlag {
+			return nil
+		}
+		return vm.storeAt(addr)
+	case op == OpPoke:
+		addr := vm.PopInt()
+		b := byte(vm.PopInt())
+		if vm.CFlag {
+			return nil
+		}
+		return vm.poke(addr, b)
 	}
 
 	return nil
@@ -803,6 +944,83 @@ func (vm *VM) exec2Byte(op, arg byte) error {
 		vm.MemWrite(64+1, 5) // Ring1Action = ActionCraft
 		vm.Yielded = true
 		return nil
+
+	case OpActBuild:
+		vm.MemWrite(64+1, 10)        // Ring1Action = ActionBuild
+		vm.MemWrite(64+2, int16(arg)) // Ring1Target = build kind
+		vm.Yielded = true
+		return nil
+
+	case OpActDeposit:
+		vm.MemWrite(64+1, 11) // Ring1Action = ActionDeposit
+		vm.Yielded = true
+		return nil
+
+	case OpActWithdraw:
+		vm.MemWrite(64+1, 12) // Ring1Action = ActionWithdraw
+		vm.Yielded = true
+		return nil
+
+	case OpActShoot:
+		// arg 0: facing, 1-4: literal direction, 5: toward nearest NPC
+		dir := int16(arg)
+		if arg == 5 {
+			dir = vm.MemRead(18) // Ring0NearDir
+		}
+		vm.MemWrite(64+1, 13)  // Ring1Action = ActionShoot
+		vm.MemWrite(64+2, dir) // Ring1Target = direction
+		vm.Yielded = true
+		return nil
+
+	case OpActGive:
+		vm.MemWrite(64+1, 14) // Ring1Action = ActionGive
+		vm.MemWrite(64+2, vm.MemRead(12)) // Ring1Target = Ring0NearID
+		vm.Yielded = true
+		return nil
+
+	case OpActFollow:
+		target := vm.MemRead(12) // Ring0NearID
+		if arg == 1 {
+			target = vm.MemRead(32) // Ring0LeaderID
+		}
+		vm.MemWrite(64+1, 15)     // Ring1Action = ActionFollow
+		vm.MemWrite(64+2, target) // Ring1Target = who to follow
+		vm.Yielded = true
+		return nil
+
+	case OpActJoin:
+		vm.MemWrite(64+1, 16) // Ring1Action = ActionJoin
+		vm.MemWrite(64+2, vm.MemRead(12)) // Ring1Target = Ring0NearID
+		vm.Yielded = true
+		return nil
+
+	case OpActMate:
+		vm.MemWrite(64+1, 17) // Ring1Action = ActionMate
+		vm.MemWrite(64+2, vm.MemRead(12)) // Ring1Target = Ring0NearID
+		vm.Yielded = true
+		return nil
+
+	case OpActSleep:
+		vm.MemWrite(64+1, 18) // Ring1Action = ActionSleep
+		vm.Yielded = true
+		return nil
+
+	case OpActBuy:
+		vm.MemWrite(64+1, 19)        // Ring1Action = ActionBuy
+		vm.MemWrite(64+2, int16(arg)) // Ring1Target = what to buy
+		vm.Yielded = true
+		return nil
+
+	case OpActSell:
+		vm.MemWrite(64+1, 20) // Ring1Action = ActionSell
+		vm.Yielded = true
+		return nil
+
+	case OpActPost:
+		vm.MemWrite(64+1, 21)        // Ring1Action = ActionPost
+		vm.MemWrite(64+2, int16(arg)) // Ring1Target = item asked for
+		vm.Yielded = true
+		return nil
 	}
 
 	return nil
@@ -820,6 +1038,12 @@ func (vm *VM) exec3Byte(op, hi, lo byte) error {
 		v := vm.MemRead(lo) // Use lo as slot, ignore hi for now
 		vm.PushWord(v)
 
+	case OpLoad16:
+		return vm.loadAt(int(uint16(val)))
+
+	case OpStore16:
+		return vm.storeAt(int(uint16(val)))
+
 	case OpQuot16:
 		vm.PushInt(int(val) | 0x8000)
 
@@ -834,6 +1058,11 @@ func (vm *VM) exec3Byte(op, hi, lo byte) error {
 
 	case OpCallFar:
 		// Save return address and jump
+		if vm.CallSP >= len(vm.CallStack) {
+			vm.CFlag = true
+			vm.AReg = 1 // stack overflow
+			return fmt.Errorf("call stack overflow")
+		}
 		vm.CallStack[vm.CallSP] = vm.PC
 		vm.CallSP++
 		vm.PC = int(val)
@@ -849,6 +1078,14 @@ func (vm *VM) execVarLen(op byte, data []byte) error {
 		// For now, just push the length
 		vm.PushInt(len(data))
 
+	case OpQuotDef:
+		// idx -> ; the bytes become quotation idx
+		idx := vm.PopInt()
+		if vm.CFlag {
+			return nil
+		}
+		return vm.defineQuotation(idx, data)
+
 	case OpQuotVar:
 		// Inline quotation - execute it
 		oldPC := vm.PC
@@ -870,6 +1107,48 @@ func (vm *VM) execVarLen(op byte, data []byte) error {
 	return nil
 }
 
+// defineQuotation installs a copy of code as quotation idx (marked or
+// not), for programs that write their own quotations
+func (vm *VM) defineQuotation(idx int, code []byte) error {
+	idx &= 0x7FFF
+	if idx >= len(vm.Quotations) {
+		vm.CFlag = true
+		vm.AReg = 6 // invalid quotation
+		return fmt.Errorf("invalid quotation %d", idx)
+	}
+	q := make([]byte, len(code)) // non-nil, so an empty body is defined
+	copy(q, code)
+	vm.Quotations[idx] = q
+	return nil
+}
+
+// poke writes b at addr in the running code (the main program or the
+// quotation being executed). Without SelfModify it does nothing; otherwise
+// the write is refused unless addr is in bounds and code that decoded
+// cleanly still does.
+func (vm *VM) poke(addr int, b byte) error {
+	if !vm.SelfModify {
+		return nil
+	}
+	if addr < 0 || addr >= len(vm.Code) {
+		vm.CFlag = true
+		vm.AReg = 8 // code write refused
+		return fmt.Errorf("code write at %d refused", addr)
+	}
+	old := vm.Code[addr]
+	clean := Verify(vm.Code) == nil
+	vm.Code[addr] = b
+	if clean {
+		if err := Verify(vm.Code); err != nil {
+			vm.Code[addr] = old
+			vm.CFlag = true
+			vm.AReg = 8
+			return fmt.Errorf("code write at %d refused: %v", addr, err)
+		}
+	}
+	return nil
+}
+
 // execQuotation executes a quotation by index
 func (vm *VM) execQuotation(idx int) error {
 	if idx < 0 || idx >= len(vm.Quotations) || vm.Quotations[idx] == nil {
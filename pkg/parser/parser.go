@@ -23,17 +23,37 @@ type Statement struct {
 
 // Definition: DEFINE name == quotation .
 type Definition struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+	Tokens []lexer.Token // as written, comments and whitespace included
+
 	Name string     `"DEFINE" @Ident "==" `
 	Body *Quotation `@@ "."`
 }
 
+// NamePos returns the position of the defined name
+func (d *Definition) NamePos() lexer.Position {
+	for _, t := range d.Tokens[min(1, len(d.Tokens)):] {
+		if t.Value == d.Name {
+			return t.Pos
+		}
+	}
+	return d.Pos
+}
+
 // Quotation: [ expr* ]
 type Quotation struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+
 	Items []*Expression `"[" @@* "]"`
 }
 
 // Expression: literal | symbol | quotation
 type Expression struct {
+	Pos    lexer.Position
+	EndPos lexer.Position
+
 	Number       *float64   `  @Number`
 	String       *string    `| @String`
 	Boolean      *string    `| @("true" | "false")`
@@ -79,6 +99,41 @@ func Parse(source string) (*Program, error) {
 	return Parser.ParseString("", source)
 }
 
+// Token is a lexed token: its kind (a lexer rule such as "Ident", "Number",
+// "Operator" or "Comment"), its text and where it starts
+type Token struct {
+	Kind  string
+	Value string
+	Pos   lexer.Position
+}
+
+// Tokenize lexes source into tokens, comments included and whitespace left
+// out. It needs no valid program, only valid tokens: on a lexing error it
+// returns the tokens before it along with the error.
+func Tokenize(source string) ([]Token, error) {
+	kinds := make(map[lexer.TokenType]string)
+	for name, t := range psilLexer.Symbols() {
+		kinds[t] = name
+	}
+	lex, err := psilLexer.LexString("", source)
+	if err != nil {
+		return nil, err
+	}
+	var tokens []Token
+	for {
+		t, err := lex.Next()
+		if err != nil {
+			return tokens, err
+		}
+		if t.EOF() {
+			return tokens, nil
+		}
+		if kind := kinds[t.Type]; kind != "Whitespace" {
+			tokens = append(tokens, Token{Kind: kind, Value: t.Value, Pos: t.Pos})
+		}
+	}
+}
+
 // ParseFile parses a PSIL source file
 func ParseFile(filename string) (*Program, error) {
 	return Parser.ParseString(filename, "")
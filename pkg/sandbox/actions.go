@@ -0,0 +1,118 @@
+package sandbox
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ActionCount is the number of action types (ActionIdle..ActionPost).
+const ActionCount = ActionPost + 1
+
+// ActionCost is the balance of one action.
+type ActionCost struct {
+	Energy   int // energy spent per use
+	Cooldown int // ticks before the same NPC may use it again (0 = none)
+	Range    int // max Manhattan distance to the target NPC
+}
+
+// ActionCosts is the per-action balance table, indexed by action type.
+// Range applies to the actions aimed at another NPC (attack, share, teach,
+// heal, give, join, mate); shots travel Scheduler.ShootRange.
+type ActionCosts [ActionCount]ActionCost
+
+// DefaultActionCosts is the classic sandbox balance.
+var DefaultActionCosts = ActionCosts{
+	ActionAttack:    {Energy: 10, Range: 1},
+	ActionShare:     {Energy: 10, Range: 1},
+	ActionCraft:     {Energy: 20}, // off a forge; crafting on one is free
+	ActionTeach:     {Energy: 10, Range: 1},
+	ActionHeal:      {Energy: 8, Range: 1},
+	ActionHarvest:   {Energy: 5},
+	ActionTerraform: {Energy: 30}, // before the tool discount
+	ActionBuild:     {Energy: buildCost},
+	ActionShoot:     {Energy: shootCost},
+	ActionGive:      {Range: 1},
+	ActionJoin:      {Range: 1},
+	ActionMate:      {Energy: mateCost, Range: 1},
+}
+
+// actionNames maps ParseActionCosts names to action types.
+var actionNames = map[string]int{
+	"idle": ActionIdle, "eat": ActionEat, "attack": ActionAttack,
+	"share": ActionShare, "trade": ActionTrade, "craft": ActionCraft,
+	"teach": ActionTeach, "heal": ActionHeal, "harvest": ActionHarvest,
+	"terraform": ActionTerraform, "build": ActionBuild, "deposit": ActionDeposit,
+	"withdraw": ActionWithdraw, "shoot": ActionShoot, "give": ActionGive,
+	"follow": ActionFollow, "join": ActionJoin, "mate": ActionMate,
+	"sleep": ActionSleep, "buy": ActionBuy, "sell": ActionSell,
+	"post": ActionPost,
+}
+
+// ParseActionCosts reads "action.field=value,..." overrides on top of
+// DefaultActionCosts, e.g. "attack.energy=15,shoot.cooldown=3,heal.range=2".
+// Fields: energy, cooldown, range.
+func ParseActionCosts(spec string) (ActionCosts, error) {
+	costs := DefaultActionCosts
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return costs, fmt.Errorf("actions: %q is not action.field=value", part)
+		}
+		name, field, ok := strings.Cut(strings.TrimSpace(key), ".")
+		if !ok {
+			return costs, fmt.Errorf("actions: %q is not action.field", key)
+		}
+		action, ok := actionNames[name]
+		if !ok {
+			names := make([]string, 0, len(actionNames))
+			for n := range actionNames {
+				names = append(names, n)
+			}
+			sort.Strings(names)
+			return costs, fmt.Errorf("actions: unknown action %q (want %s)", name, strings.Join(names, ", "))
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			return costs, fmt.Errorf("actions: %s: %w", key, err)
+		}
+		if n < 0 {
+			return costs, fmt.Errorf("actions: %s: negative value %d", key, n)
+		}
+		c := &costs[action]
+		switch field {
+		case "energy":
+			c.Energy = n
+		case "cooldown":
+			c.Cooldown = n
+		case "range":
+			c.Range = n
+		default:
+			return costs, fmt.Errorf("actions: unknown field %q (want energy, cooldown, range)", field)
+		}
+	}
+	return costs, nil
+}
+
+// ready reports whether npc's cooldown on action has run out.
+func (s *Scheduler) ready(npc *NPC, action int) bool {
+	return npc.nextUse[action] <= s.World.Tick
+}
+
+// inRange reports whether other is within action's range of npc.
+func (s *Scheduler) inRange(npc, other *NPC, action int) bool {
+	return abs(other.X-npc.X)+abs(other.Y-npc.Y) <= s.Actions[action].Range
+}
+
+// spend charges npc energy for action and starts its cooldown.
+func (s *Scheduler) spend(npc *NPC, action int, energy int) {
+	npc.Energy -= energy
+	if cd := s.Actions[action].Cooldown; cd > 0 {
+		npc.nextUse[action] = s.World.Tick + cd
+	}
+}
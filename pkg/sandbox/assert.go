@@ -0,0 +1,266 @@
+package sandbox
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AssertMetrics are the quantities an Assertion can check, read from the
+// scheduler after a tick.
+var AssertMetrics = map[string]func(s *Scheduler) int{
+	"tick":       func(s *Scheduler) int { return s.World.Tick },
+	"population": livingCount,
+	"alive":      livingCount,
+	"predators": func(s *Scheduler) int {
+		n := 0
+		for _, npc := range s.World.NPCs {
+			if npc.Alive() && npc.Predator {
+				n++
+			}
+		}
+		return n
+	},
+	"avg_fitness": func(s *Scheduler) int {
+		total, alive := 0, 0
+		for _, npc := range s.World.NPCs {
+			if npc.Alive() {
+				total += npc.Fitness
+				alive++
+			}
+		}
+		return total / max(alive, 1)
+	},
+	"best_fitness": func(s *Scheduler) int {
+		best := 0
+		for _, npc := range s.World.NPCs {
+			if npc.Alive() {
+				best = max(best, npc.Fitness)
+			}
+		}
+		return best
+	},
+	"gold": func(s *Scheduler) int {
+		total := 0
+		for _, npc := range s.World.NPCs {
+			if npc.Alive() {
+				total += npc.Gold
+			}
+		}
+		return total
+	},
+	"food":  func(s *Scheduler) int { return s.World.FoodCount() },
+	"items": func(s *Scheduler) int { return s.World.ItemCount() },
+	"deaths": func(s *Scheduler) int {
+		total := 0
+		for _, n := range s.Deaths {
+			total += n
+		}
+		return total
+	},
+	"trades":     func(s *Scheduler) int { return s.TradeCount },
+	"teaches":    func(s *Scheduler) int { return s.TeachCount },
+	"attacks":    func(s *Scheduler) int { return s.AttackCount },
+	"kills":      func(s *Scheduler) int { return s.KillCount },
+	"heals":      func(s *Scheduler) int { return s.HealCount },
+	"births":     func(s *Scheduler) int { return s.BirthCount },
+	"infections": func(s *Scheduler) int { return s.Infections },
+	"epochs":     func(s *Scheduler) int { return s.Epoch },
+}
+
+func livingCount(s *Scheduler) int {
+	n := 0
+	for _, npc := range s.World.NPCs {
+		if npc.Alive() {
+			n++
+		}
+	}
+	return n
+}
+
+// Assertion is a check on a run: a metric compared with a value, either at
+// the end of the run, at some tick up to a deadline, or after every tick.
+type Assertion struct {
+	Text   string // as written
+	Metric string // an AssertMetrics key
+	Op     string // >=, <=, >, <, == or !=
+	Value  int
+	By     int  // deadline tick: must hold after some tick up to it (0 = at the end)
+	Always bool // must hold after every tick
+
+	Done   bool // decided
+	Passed bool
+	Tick   int // when it was decided
+	Got    int // the metric then
+}
+
+// holds reports whether the assertion's comparison holds for got.
+func (a *Assertion) holds(got int) bool {
+	switch a.Op {
+	case ">=":
+		return got >= a.Value
+	case "<=":
+		return got <= a.Value
+	case ">":
+		return got > a.Value
+	case "<":
+		return got < a.Value
+	case "==":
+		return got == a.Value
+	default:
+		return got != a.Value
+	}
+}
+
+func (a *Assertion) decide(passed bool, tick, got int) {
+	a.Done, a.Passed, a.Tick, a.Got = true, passed, tick, got
+}
+
+// Assertions checks a run against a list of assertions, so a behavioural
+// regression fails the run. Install it before the first tick and Finish it
+// after the last.
+type Assertions struct {
+	Checks []Assertion
+}
+
+// Install registers the checks as a PostTick hook on s.
+func (as *Assertions) Install(s *Scheduler) {
+	s.PostTick(as.check)
+}
+
+// check decides the checks that this tick settles, emitting AssertionFailed
+// for each one that fails.
+func (as *Assertions) check(s *Scheduler) {
+	tick := s.World.Tick
+	for i := range as.Checks {
+		a := &as.Checks[i]
+		if a.Done || (!a.Always && a.By == 0) {
+			continue
+		}
+		got := AssertMetrics[a.Metric](s)
+		switch {
+		case a.Always && !a.holds(got):
+			a.decide(false, tick, got)
+		case !a.Always && a.holds(got):
+			a.decide(true, tick, got)
+		case !a.Always && tick >= a.By:
+			a.decide(false, tick, got)
+		default:
+			continue
+		}
+		if !a.Passed {
+			s.emit(AssertionFailed{Tick: tick, Check: i})
+		}
+	}
+}
+
+// Finish decides the checks still open when the run ends: end-of-run
+// checks are evaluated, unbroken "always" checks pass and unmet deadlines
+// fail. It returns how many checks failed in all.
+func (as *Assertions) Finish(s *Scheduler) int {
+	tick := s.World.Tick
+	failed := 0
+	for i := range as.Checks {
+		a := &as.Checks[i]
+		if !a.Done {
+			got := AssertMetrics[a.Metric](s)
+			a.decide(a.Always || (a.By == 0 && a.holds(got)), tick, got)
+			if !a.Passed {
+				s.emit(AssertionFailed{Tick: tick, Check: i})
+			}
+		}
+		if !a.Passed {
+			failed++
+		}
+	}
+	return failed
+}
+
+// ParseAssertions reads assertions separated by ";" or newlines, each one
+// of
+//
+//	METRIC OP N               at the end of the run
+//	METRIC OP N by [tick] T   after some tick up to T
+//	always METRIC OP N        after every tick
+//	METRIC never below N      after every tick (never above N likewise)
+//
+// where OP is >=, <=, >, <, == or !=, e.g. "trades >= 100 by tick 10000;
+// population never below 5". Metrics are the AssertMetrics keys. Lines
+// starting with # are comments.
+func ParseAssertions(spec string) (*Assertions, error) {
+	as := &Assertions{}
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		a, err := parseAssertion(line)
+		if err != nil {
+			return nil, fmt.Errorf("assert: %q: %w", line, err)
+		}
+		as.Checks = append(as.Checks, a)
+	}
+	return as, nil
+}
+
+func parseAssertion(line string) (Assertion, error) {
+	a := Assertion{Text: line}
+	words := strings.Fields(line)
+	if words[0] == "always" {
+		a.Always = true
+		words = words[1:]
+	}
+	if len(words) < 3 {
+		return a, fmt.Errorf("want METRIC OP VALUE")
+	}
+	a.Metric = words[0]
+	if AssertMetrics[a.Metric] == nil {
+		names := make([]string, 0, len(AssertMetrics))
+		for name := range AssertMetrics {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return a, fmt.Errorf("unknown metric %q (want %s)", a.Metric, strings.Join(names, ", "))
+	}
+
+	rest := words[2:]
+	switch op := words[1]; op {
+	case ">=", "<=", ">", "<", "==", "!=":
+		a.Op = op
+	case "never":
+		if a.Always || len(words) < 4 || (words[2] != "below" && words[2] != "above") {
+			return a, fmt.Errorf("want METRIC never below|above VALUE")
+		}
+		a.Always, a.Op = true, ">="
+		if words[2] == "above" {
+			a.Op = "<="
+		}
+		rest = words[3:]
+	default:
+		return a, fmt.Errorf("unknown comparison %q (want >=, <=, >, <, ==, !=, never)", op)
+	}
+
+	var err error
+	if a.Value, err = strconv.Atoi(rest[0]); err != nil {
+		return a, fmt.Errorf("bad value %q", rest[0])
+	}
+	rest = rest[1:]
+	if len(rest) == 0 {
+		return a, nil
+	}
+	if rest[0] != "by" || a.Always {
+		return a, fmt.Errorf("unexpected %q", strings.Join(rest, " "))
+	}
+	rest = rest[1:]
+	if len(rest) > 0 && rest[0] == "tick" {
+		rest = rest[1:]
+	}
+	if len(rest) != 1 {
+		return a, fmt.Errorf("want by [tick] T")
+	}
+	if a.By, err = strconv.Atoi(rest[0]); err != nil || a.By <= 0 {
+		return a, fmt.Errorf("bad deadline %q", rest[0])
+	}
+	return a, nil
+}
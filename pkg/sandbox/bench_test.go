@@ -0,0 +1,140 @@
+package sandbox
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+// benchSim is parallelSim with roughly 8 tiles per NPC.
+func benchSim(n, workers int) *Scheduler {
+	size := 32
+	for size*size < n*8 {
+		size *= 2
+	}
+	return parallelSim(n, size, workers)
+}
+
+func BenchmarkTick(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		for _, workers := range []int{0, 1, 4} {
+			b.Run(fmt.Sprintf("npcs=%d/workers=%d", n, workers), func(b *testing.B) {
+				s := benchSim(n, workers)
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					s.Tick()
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkTickPopulation(b *testing.B) {
+	for _, n := range []int{100, 1000, 10000} {
+		for _, fields := range []bool{false, true} {
+			b.Run(fmt.Sprintf("npcs=%d/fields=%v", n, fields), func(b *testing.B) {
+				s := benchSim(n, 0)
+				s.SensorFields = fields
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					s.Tick()
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkSense times one sensing pass over the population (no thinking
+// or acting). The fields variant includes building the fields.
+func BenchmarkSense(b *testing.B) {
+	for _, mode := range []string{"scan", "fields", "cone"} {
+		b.Run("npcs=1000/"+mode, func(b *testing.B) {
+			s := benchSim(1000, 0)
+			s.SensorFields = mode == "fields"
+			s.VisionCone = mode == "cone"
+			for i := 0; i < 10; i++ {
+				s.Tick() // let food and items spread
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if s.SensorFields {
+					s.fields.build(s.World)
+				}
+				for _, npc := range s.World.NPCs {
+					s.sense(npc)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkEvolve(b *testing.B) {
+	for _, n := range []int{100, 1000} {
+		b.Run(fmt.Sprintf("npcs=%d", n), func(b *testing.B) {
+			s := benchSim(n, 0)
+			for i := 0; i < 20; i++ {
+				s.Tick() // spread fitness
+			}
+			ga := NewGA(testRng())
+			npcs := s.World.NPCs
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				npcs = ga.Evolve(npcs)
+			}
+		})
+	}
+}
+
+func BenchmarkMemeticTransfer(b *testing.B) {
+	for _, size := range []int{32, 128} {
+		b.Run(fmt.Sprintf("genome=%d", size), func(b *testing.B) {
+			s := benchSim(2, 0)
+			ga := NewGA(testRng())
+			teacher, student := s.World.NPCs[0], s.World.NPCs[1]
+			teacher.Genome, student.Genome = ga.RandomGenome(size), ga.RandomGenome(size)
+			teacher.Fitness = 100
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.memeticTransfer(teacher, student)
+			}
+		})
+	}
+}
+
+// BenchmarkBrainVM times one short brain run on a new VM against one on a
+// VM from a micro.Pool, as NPCs are born and die.
+func BenchmarkBrainVM(b *testing.B) {
+	genome := NewGA(testRng()).RandomGenome(64)
+	run := func(vm *micro.VM) {
+		vm.MaxGas, vm.Gas = 200, 200
+		vm.Load(genome)
+		vm.Run()
+	}
+	b.Run("new", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			run(micro.New())
+		}
+	})
+	b.Run("pool", func(b *testing.B) {
+		var pool micro.Pool
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			vm := pool.Get()
+			run(vm)
+			pool.Put(vm)
+		}
+	})
+	b.Run("pool/parallel", func(b *testing.B) {
+		var pool micro.Pool
+		b.ReportAllocs()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				vm := pool.Get()
+				run(vm)
+				pool.Put(vm)
+			}
+		})
+	})
+}
@@ -0,0 +1,53 @@
+package sandbox
+
+// Causes of death, decided by whatever last took health away.
+const (
+	DeathUnknown    = 0
+	DeathStarvation = 1 // ran out of energy
+	DeathCombat     = 2 // melee, shots, fire
+	DeathPoison     = 3 // poison tiles, swamp, poison modifiers
+	DeathAge        = 4 // reached MaxAge
+	DeathCauses     = 5
+)
+
+// DeathCauseNames maps a death cause to its report label.
+var DeathCauseNames = [DeathCauses]string{"unknown", "starvation", "combat", "poison", "age"}
+
+// Biography is the life story of an NPC that died.
+type Biography struct {
+	ID        uint16
+	BirthTick int
+	DeathTick int
+	Parents   [2]uint16
+	ItemsHeld uint16 // bit per item type ever held
+	Trades    int
+	Kills     int
+	Fitness   int
+	Cause     byte
+}
+
+// recordDeath counts npc's cause of death, emits NPCDied and, with
+// KeepGraveyard, files its biography.
+func (s *Scheduler) recordDeath(npc *NPC) {
+	cause := npc.lastHarm
+	if npc.Age >= MaxAge {
+		cause = DeathAge
+	}
+	s.Deaths[cause]++
+	s.emit(NPCDied{Tick: s.World.Tick, ID: npc.ID, Cause: cause, Age: npc.Age, Fitness: npc.Fitness})
+	if !s.KeepGraveyard {
+		return
+	}
+	tick := s.World.Tick
+	s.Graveyard = append(s.Graveyard, Biography{
+		ID:        npc.ID,
+		BirthTick: tick + 1 - npc.Age, // Age already counts the death tick
+		DeathTick: tick,
+		Parents:   npc.Parents,
+		ItemsHeld: npc.ItemsHeld,
+		Trades:    npc.Trades,
+		Kills:     npc.Kills,
+		Fitness:   npc.Fitness,
+		Cause:     cause,
+	})
+}
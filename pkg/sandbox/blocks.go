@@ -0,0 +1,298 @@
+package sandbox
+
+import "github.com/psilLang/psil/pkg/micro"
+
+// Structural operators. Jumps are relative (jnz +8 skips the next 8 bytes),
+// so splicing bytes around them makes them land somewhere else, often mid-
+// instruction. With GA.Structural, crossover and mutation instead work on
+// decoded instructions: every jump keeps a pointer to the instruction it
+// lands on, genomes are cut only at basic-block boundaries, and offsets
+// are recomputed when the child is assembled.
+
+// instr is one decoded instruction.
+type instr struct {
+	code   []byte
+	jump   bool   // a relative jump; target is where it lands
+	target *instr // nil: the end of the genome
+	next   *instr // the following instruction of the genome it came from
+	leader bool   // starts a basic block
+}
+
+// isJump reports whether op is a relative jump.
+func isJump(op byte) bool {
+	switch op {
+	case micro.OpJump, micro.OpJumpBack, micro.OpJumpZ, micro.OpJumpNZ, micro.OpJumpFar, micro.OpJumpZFar:
+		return true
+	}
+	return false
+}
+
+// decode splits genome into instructions and resolves each jump to the
+// first instruction at or after the byte it lands on. A trailing partial
+// instruction is kept as raw bytes.
+func decode(genome []byte) []*instr {
+	points := OpcodeAlignedPoints(genome)
+	prog := make([]*instr, 0, len(points))
+	for i := 0; i+1 < len(points); i++ {
+		prog = append(prog, &instr{code: genome[points[i]:points[i+1]]})
+	}
+	if end := points[len(points)-1]; end < len(genome) {
+		prog = append(prog, &instr{code: genome[end:]})
+	}
+	for i, in := range prog {
+		if i+1 < len(prog) {
+			in.next = prog[i+1]
+		}
+	}
+
+	// at returns the first instruction starting at or after byte pos
+	at := func(pos int) *instr {
+		for i, p := range points[:len(prog)] {
+			if p >= pos {
+				return prog[i]
+			}
+		}
+		return nil
+	}
+	leaders := len(prog) > 0
+	for i, in := range prog {
+		if leaders {
+			in.leader = true
+		}
+		op := in.code[0]
+		leaders = op == micro.OpHalt || op == micro.OpYield
+		if !isJump(op) || len(in.code) != opcodeSize(op, in.code, 0) {
+			continue
+		}
+		end := points[i+1]
+		var off int
+		switch op {
+		case micro.OpJumpBack:
+			off = -int(in.code[1])
+		case micro.OpJumpFar, micro.OpJumpZFar:
+			off = int(int16(in.code[2]) | int16(in.code[1])<<8)
+		default:
+			off = int(in.code[1])
+		}
+		in.jump = true
+		in.target = at(max(end+off, 0))
+		if in.target != nil {
+			in.target.leader = true
+		}
+		leaders = true
+	}
+	return prog
+}
+
+// blockStarts returns the indexes of prog's basic blocks, plus len(prog).
+func blockStarts(prog []*instr) []int {
+	var starts []int
+	for i, in := range prog {
+		if in.leader {
+			starts = append(starts, i)
+		}
+	}
+	return append(starts, len(prog))
+}
+
+// assemble encodes prog, dropping trailing instructions past limit bytes and
+// re-fixing every jump offset. A jump whose target was left out lands on
+// the next instruction that was kept from the target's genome (or the end).
+// Conditional jumps cannot go backwards; one whose target moved behind it
+// falls through instead.
+func assemble(prog []*instr, limit int) []byte {
+	size := 0
+	for i, in := range prog {
+		if size+len(in.code) > limit {
+			prog = prog[:i]
+			break
+		}
+		size += len(in.code)
+	}
+	pos := make(map[*instr]int, len(prog))
+	size = 0
+	for _, in := range prog {
+		pos[in] = size
+		size += len(in.code)
+	}
+
+	out := make([]byte, 0, size)
+	for _, in := range prog {
+		start := len(out)
+		out = append(out, in.code...)
+		if !in.jump {
+			continue
+		}
+		to := size
+		for t := in.target; t != nil; t = t.next {
+			if p, ok := pos[t]; ok {
+				to = p
+				break
+			}
+		}
+		end := len(out)
+		off := to - end
+		switch op := in.code[0]; op {
+		case micro.OpJumpFar, micro.OpJumpZFar:
+			out[start+1], out[start+2] = byte(uint16(off)>>8), byte(off)
+		case micro.OpJump, micro.OpJumpBack:
+			if off >= 0 {
+				out[start], out[start+1] = micro.OpJump, byte(min(off, 255))
+			} else {
+				out[start], out[start+1] = micro.OpJumpBack, byte(min(-off, 255))
+			}
+		default:
+			out[start+1] = byte(min(max(off, 0), 255))
+		}
+	}
+	return out
+}
+
+// structuralCrossover replaces a run of a's basic blocks (possibly empty,
+// which inserts) with a run of b's.
+func (ga *GA) structuralCrossover(a, b []byte) []byte {
+	progA, progB := decode(a), decode(b)
+	startsA, startsB := blockStarts(progA), blockStarts(progB)
+	a1, a2 := ga.pointPair(startsA)
+	b1, b2 := ga.pointPair(startsB)
+	child := make([]*instr, 0, len(progA)-(a2-a1)+(b2-b1))
+	child = append(child, progA[:a1]...)
+	child = append(child, progB[b1:b2]...)
+	child = append(child, progA[a2:]...)
+	return ga.enforceBounds(assemble(child, ga.maxGenome()))
+}
+
+// randomInstr returns a random instruction; ring reads and writes get a
+// valid slot.
+func (ga *GA) randomInstr() []byte {
+	switch op := ga.randomOpcode(); op {
+	case micro.OpRing0R:
+		return []byte{op, byte(ga.Rng.Intn(Ring0ExtCount))}
+	case micro.OpRing1W:
+		return []byte{op, byte(ga.Rng.Intn(Ring1Count))}
+	default:
+		return []byte{op}
+	}
+}
+
+// structuralMutate applies one random mutation operator at instruction
+// granularity: replace, insert or delete an instruction, tweak a constant,
+// swap two basic blocks or duplicate one. decode gives it fresh
+// instructions, so they are changed in place.
+func (ga *GA) structuralMutate(genome []byte) []byte {
+	prog := decode(genome)
+	if len(prog) == 0 {
+		return genome
+	}
+	mx := ga.maxGenome()
+	switch ga.Rng.Intn(6) {
+	case 0: // Replace an instruction
+		in := prog[ga.Rng.Intn(len(prog))]
+		in.code, in.jump, in.target = ga.randomInstr(), false, nil
+
+	case 1: // Insert an instruction
+		i := ga.Rng.Intn(len(prog) + 1)
+		in := &instr{code: ga.randomInstr()}
+		prog = append(prog[:i], append([]*instr{in}, prog[i:]...)...)
+
+	case 2: // Delete an instruction
+		if len(genome) <= MinGenome {
+			return genome
+		}
+		i := ga.Rng.Intn(len(prog))
+		prog = append(prog[:i:i], prog[i+1:]...)
+
+	case 3: // Constant tweak (jump offsets are left to assemble)
+		var nums []int
+		for i, in := range prog {
+			if micro.IsSmallNum(in.code[0]) || (!in.jump && len(in.code) == 2 && micro.Is2ByteOp(in.code[0])) {
+				nums = append(nums, i)
+			}
+		}
+		if len(nums) == 0 {
+			return genome
+		}
+		in := prog[nums[ga.Rng.Intn(len(nums))]]
+		in.code = append([]byte(nil), in.code...) // don't write through to genome
+		b, lo, hi := &in.code[len(in.code)-1], byte(0), byte(0xFF)
+		if micro.IsSmallNum(in.code[0]) {
+			lo, hi = 0x20, 0x3F
+		}
+		if ga.Rng.Intn(2) == 0 && *b < hi {
+			*b++
+		} else if *b > lo {
+			*b--
+		}
+
+	case 4: // Swap two basic blocks
+		starts := blockStarts(prog)
+		if len(starts) < 3 {
+			return genome
+		}
+		i, j := ga.Rng.Intn(len(starts)-1), ga.Rng.Intn(len(starts)-1)
+		if i == j {
+			return genome
+		}
+		if i > j {
+			i, j = j, i
+		}
+		swapped := make([]*instr, 0, len(prog))
+		swapped = append(swapped, prog[:starts[i]]...)
+		swapped = append(swapped, prog[starts[j]:starts[j+1]]...)
+		swapped = append(swapped, prog[starts[i+1]:starts[j]]...)
+		swapped = append(swapped, prog[starts[i]:starts[i+1]]...)
+		swapped = append(swapped, prog[starts[j+1]:]...)
+		prog = swapped
+
+	case 5: // Duplicate a basic block at a block boundary
+		starts := blockStarts(prog)
+		k := ga.Rng.Intn(len(starts) - 1)
+		block := prog[starts[k]:starts[k+1]]
+		copies := make(map[*instr]*instr, len(block))
+		dup := make([]*instr, len(block))
+		for i, in := range block {
+			c := *in
+			dup[i], copies[in] = &c, &c
+		}
+		for _, c := range dup {
+			if t, ok := copies[c.target]; ok {
+				c.target = t // jumps inside the block stay inside the copy
+			}
+			if n, ok := copies[c.next]; ok {
+				c.next = n
+			}
+		}
+		at := starts[ga.Rng.Intn(len(starts))]
+		prog = append(prog[:at:at], append(dup, prog[at:]...)...)
+	}
+	return ga.enforceBounds(assemble(prog, mx))
+}
+
+// BrokenJumps counts the jumps in genome that land mid-instruction or
+// outside it: the damage naive splicing does to control flow.
+func BrokenJumps(genome []byte) int {
+	points := OpcodeAlignedPoints(genome)
+	boundary := make(map[int]bool, len(points))
+	for _, p := range points {
+		boundary[p] = true
+	}
+	broken := 0
+	for i := 0; i+1 < len(points); i++ {
+		pc, end := points[i], points[i+1]
+		var off int
+		switch genome[pc] {
+		case micro.OpJump, micro.OpJumpZ, micro.OpJumpNZ:
+			off = int(genome[pc+1])
+		case micro.OpJumpBack:
+			off = -int(genome[pc+1])
+		case micro.OpJumpFar, micro.OpJumpZFar:
+			off = int(int16(genome[pc+2]) | int16(genome[pc+1])<<8)
+		default:
+			continue
+		}
+		if !boundary[end+off] {
+			broken++
+		}
+	}
+	return broken
+}
@@ -0,0 +1,267 @@
+package sandbox
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+// brainSensors names the Ring0 slots for compiled brains.
+var brainSensors = map[string]byte{
+	"self": Ring0Self, "health": Ring0Health, "energy": Ring0Energy,
+	"hunger": Ring0Hunger, "fear": Ring0Fear, "food-dist": Ring0Food,
+	"danger": Ring0Danger, "near-dist": Ring0Near, "x": Ring0X, "y": Ring0Y,
+	"day": Ring0Day, "near-id": Ring0NearID, "food-dir": Ring0FoodDir,
+	"my-gold": Ring0MyGold, "my-item": Ring0MyItem, "item-dist": Ring0NearItem,
+	"near-trust": Ring0NearTrust, "near-dir": Ring0NearDir, "item-dir": Ring0ItemDir,
+	"rng": Ring0Rng, "stress": Ring0Stress, "my-gas": Ring0MyGas,
+	"on-forge": Ring0OnForge, "my-age": Ring0MyAge, "taught": Ring0Taught,
+	"biome": Ring0Biome, "tile-type": Ring0TileType, "similarity": Ring0Similarity,
+	"tile-ahead": Ring0TileAhead, "cooldown": Ring0Cooldown,
+	"incoming-fire": Ring0IncomingFire, "leader-id": Ring0LeaderID,
+	"leader-dir": Ring0LeaderDir, "my-followers": Ring0MyFollowers,
+	"near-kin": Ring0NearKin, "child-dir": Ring0ChildDir, "infected": Ring0Infected,
+	"near-emotion": Ring0NearEmotion, "behind": Ring0Behind, "facing": Ring0Facing,
+	"noise-dir": Ring0NoiseDir, "noise-level": Ring0NoiseLevel,
+	"predator-dist": Ring0PredatorDist, "predator-dir": Ring0PredatorDir,
+	"prey-dist": Ring0PreyDist, "prey-dir": Ring0PreyDir, "prey-id": Ring0PreyID,
+	"meal-price": Ring0MealPrice, "sell-price": Ring0SellPrice,
+	"contract-dir": Ring0ContractDir, "contract-reward": Ring0ContractReward,
+	"contract-item": Ring0ContractItem, "post-payout": Ring0PostPayout,
+}
+
+// brainOutputs names the Ring1 slots; each word pops a value into its slot.
+var brainOutputs = map[string]byte{
+	"set-move": Ring1Move, "set-action": Ring1Action, "set-target": Ring1Target,
+	"set-emotion": Ring1Emotion, "set-turn": Ring1Turn,
+}
+
+// brainConsts names the values written to Ring1.
+var brainConsts = map[string]int{
+	"north": 1, "east": 2, "south": 3, "west": 4,
+	"idle": ActionIdle, "eat": ActionEat, "attack": ActionAttack,
+	"share": ActionShare, "trade": ActionTrade, "craft": ActionCraft,
+	"teach": ActionTeach, "heal": ActionHeal, "harvest": ActionHarvest,
+	"terraform": ActionTerraform, "build": ActionBuild, "deposit": ActionDeposit,
+	"withdraw": ActionWithdraw, "shoot": ActionShoot, "give": ActionGive,
+	"follow": ActionFollow, "join": ActionJoin, "mate": ActionMate,
+	"sleep": ActionSleep, "buy": ActionBuy, "sell": ActionSell, "post": ActionPost,
+	"neutral": EmotionNeutral, "friendly": EmotionFriendly,
+	"aggressive": EmotionAggressive, "fearful": EmotionFearful,
+}
+
+// brainActs are the action-opcode shortcuts (each yields after setting Ring1).
+var brainActs = map[string][2]byte{
+	"move-to-food":  {micro.OpActMove, 5},
+	"move-to-npc":   {micro.OpActMove, 6},
+	"move-to-item":  {micro.OpActMove, 7},
+	"act-attack":    {micro.OpActAttack, 0},
+	"act-heal":      {micro.OpActHeal, 0},
+	"act-eat":       {micro.OpActEat, 0},
+	"act-harvest":   {micro.OpActHarvest, 0},
+	"act-terraform": {micro.OpActTerraform, 0},
+	"act-share":     {micro.OpActShare, 0},
+	"act-trade":     {micro.OpActTrade, 0},
+	"act-craft":     {micro.OpActCraft, 0},
+	"act-deposit":   {micro.OpActDeposit, 0},
+	"act-withdraw":  {micro.OpActWithdraw, 0},
+	"act-give":      {micro.OpActGive, 0},
+	"act-join":      {micro.OpActJoin, 0},
+	"act-mate":      {micro.OpActMate, 0},
+	"act-sleep":     {micro.OpActSleep, 0},
+	"act-buy-meal":  {micro.OpActBuy, 0},
+	"act-sell":      {micro.OpActSell, 0},
+	"act-post":      {micro.OpActPost, 0},
+}
+
+// BrainWords returns the sandbox vocabulary for compiled brains: sensors
+// by name (food-dir, near-dist, ...), set-move/set-action/set-target/
+// set-emotion/set-turn for outputs, action, direction and emotion
+// constants, and the act-* / move-to-* opcode shortcuts.
+func BrainWords() map[string][]byte {
+	words := make(map[string][]byte)
+	for name, slot := range brainSensors {
+		words[name] = []byte{micro.OpRing0R, slot}
+	}
+	for name, slot := range brainOutputs {
+		words[name] = []byte{micro.OpRing1W, slot}
+	}
+	for name, v := range brainConsts {
+		words[name] = []byte{micro.SmallNumOp(v)}
+	}
+	for name, op := range brainActs {
+		words[name] = []byte{op[0], op[1]}
+	}
+	return words
+}
+
+// CompileBrain compiles high-level PSIL source to a genome using the
+// sandbox vocabulary.
+func CompileBrain(source string) ([]byte, error) {
+	c := micro.Compiler{Words: BrainWords()}
+	return c.Compile(source)
+}
+
+// MemoryName names a VM memory slot in the brain vocabulary: a Ring0
+// sensor, or a Ring1 output (the set-* word writing it). Other slots have
+// no name.
+func MemoryName(slot byte) string {
+	for name, s := range brainSensors {
+		if s == slot {
+			return name
+		}
+	}
+	for name, s := range brainOutputs {
+		if 64+s == slot {
+			return name
+		}
+	}
+	return ""
+}
+
+// ExplainGenome disassembles genome like micro.Disassemble, commenting each
+// line in the brain vocabulary: the sensor a ring-0 read senses, the output
+// a ring-1 write sets (with the action, direction or emotion when the value
+// written is a literal), and where an act.move heads.
+func ExplainGenome(genome []byte) string {
+	names := func(vocab map[string]int, keep func(string) bool) map[int]string {
+		m := make(map[int]string)
+		for name, v := range vocab {
+			if keep(name) {
+				m[v] = name
+			}
+		}
+		return m
+	}
+	isDir := func(name string) bool {
+		return name == "north" || name == "east" || name == "south" || name == "west"
+	}
+	isEmotion := func(name string) bool {
+		return name == "neutral" || name == "friendly" || name == "aggressive" || name == "fearful"
+	}
+	dirs := names(brainConsts, isDir)
+	emotions := names(brainConsts, isEmotion)
+	actions := names(brainConsts, func(name string) bool { return !isDir(name) && !isEmotion(name) })
+	sensors := make(map[byte]string, len(brainSensors))
+	for name, slot := range brainSensors {
+		sensors[slot] = name
+	}
+	outputs := make(map[byte]string, len(brainOutputs))
+	for name, slot := range brainOutputs {
+		outputs[slot] = name
+	}
+	moves := make(map[byte]string)
+	for name, op := range brainActs {
+		if op[0] == micro.OpActMove {
+			moves[op[1]] = name
+		}
+	}
+
+	var sb strings.Builder
+	literal := -1 // value pushed by the previous instruction, if any
+	if len(genome) == 0 {
+		return ""
+	}
+	for _, line := range strings.Split(strings.TrimRight(micro.Disassemble(genome), "\n"), "\n") {
+		pc, err := strconv.ParseUint(line[:4], 16, 16)
+		if err != nil || int(pc) >= len(genome) {
+			sb.WriteString(line + "\n")
+			continue
+		}
+		op, arg := genome[pc], byte(0)
+		if int(pc)+1 < len(genome) {
+			arg = genome[pc+1]
+		}
+		var note string
+		switch {
+		case strings.HasSuffix(line, "(truncated)"):
+		case op == micro.OpRing0R:
+			note = sensors[arg]
+		case op == micro.OpRing1R:
+			note = strings.TrimPrefix(outputs[arg], "set-")
+		case op == micro.OpRing1W:
+			note = outputs[arg]
+			if v, ok := map[byte]map[int]string{
+				Ring1Move: dirs, Ring1Turn: dirs, Ring1Action: actions, Ring1Emotion: emotions,
+			}[arg][literal]; ok && literal >= 0 {
+				note += " " + v
+			}
+		case op == micro.OpActMove:
+			if note = moves[arg]; note == "" {
+				note = dirs[int(arg)]
+			}
+		}
+		if note != "" {
+			line = fmt.Sprintf("%-20s ; %s", line, note)
+		}
+		sb.WriteString(line + "\n")
+
+		switch {
+		case micro.IsSmallNum(op):
+			literal = micro.SmallNumValue(op)
+		case op == micro.OpPushByte:
+			literal = int(arg)
+		default:
+			literal = -1
+		}
+	}
+	return sb.String()
+}
+
+// RunBrain runs genome once on a fresh VM with the given Ring0 sensors and
+// gas, and returns the Ring1 outputs of each yield and then the final
+// ones: what the scheduler would act on, in order. Sensors are not
+// refreshed between yields, as when the scheduler runs brains on workers.
+func RunBrain(genome []byte, sensors map[byte]int16, gas int) [][Ring1Count]int16 {
+	vm := micro.New()
+	vm.Output = io.Discard
+	vm.MaxGas, vm.Gas = gas, gas
+	for slot, v := range sensors {
+		vm.MemWrite(slot, v)
+	}
+	vm.Load(genome)
+	var outs [][Ring1Count]int16
+	for {
+		vm.Run()
+		if !vm.Yielded {
+			break
+		}
+		outs = append(outs, readRing1(vm))
+		clearRing1(vm)
+		vm.Yielded = false
+		if vm.Gas <= 0 {
+			break
+		}
+	}
+	return append(outs, readRing1(vm))
+}
+
+// ParseSensors reads a sensor vector for RunBrain: space-separated
+// sensor=value pairs, each sensor named as in brains ("food-dist=3") or by
+// Ring0 slot ("5=3"). Sensors left out read 0.
+func ParseSensors(spec string) (map[byte]int16, error) {
+	sensors := make(map[byte]int16)
+	for _, pair := range strings.Fields(spec) {
+		name, val, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("sensors: %q is not sensor=value", pair)
+		}
+		slot, known := brainSensors[name]
+		if !known {
+			n, err := strconv.ParseUint(name, 10, 8)
+			if err != nil || n >= 64 {
+				return nil, fmt.Errorf("sensors: unknown sensor %q", name)
+			}
+			slot = byte(n)
+		}
+		v, err := strconv.ParseInt(val, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("sensors: %s: bad value %q", name, val)
+		}
+		sensors[slot] = int16(v)
+	}
+	return sensors, nil
+}
@@ -0,0 +1,345 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	randv2 "math/rand/v2"
+	"sort"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+// Source is the random source behind a run: a math/rand source backed by
+// the PCG generator of math/rand/v2, whose whole state a Checkpoint can
+// save and put back. Every path that runs a seed should draw from one, so
+// the same seed gives the same run however it is started.
+type Source struct {
+	pcg *randv2.PCG
+}
+
+// NewSource returns a Source seeded with seed.
+func NewSource(seed int64) *Source {
+	return &Source{pcg: randv2.NewPCG(uint64(seed), 0)}
+}
+
+func (s *Source) Int63() int64 {
+	return int64(s.pcg.Uint64() >> 1)
+}
+
+func (s *Source) Uint64() uint64 {
+	return s.pcg.Uint64()
+}
+
+func (s *Source) Seed(seed int64) {
+	s.pcg.Seed(uint64(seed), 0)
+}
+
+// MarshalBinary returns the state of the source.
+func (s *Source) MarshalBinary() ([]byte, error) {
+	return s.pcg.MarshalBinary()
+}
+
+// UnmarshalBinary puts back a state returned by MarshalBinary.
+func (s *Source) UnmarshalBinary(data []byte) error {
+	return s.pcg.UnmarshalBinary(data)
+}
+
+// checkpointVersion is the Checkpoint format written by SaveCheckpoint.
+const checkpointVersion = 3
+
+// Checkpoint is the complete state of a run between ticks: the world, the
+// scheduler's counters and carried-over state, the GAs' statistics and the
+// position of the random stream, plus whatever the caller adds as Extra. A
+// run restored from a checkpoint carries on exactly as the original did.
+//
+// Settings (scheduler and GA options, hooks, subscribers) are not saved:
+// the run restored into must be set up as the original was. Hook state,
+// such as how far a Curriculum has got, belongs in Extra.
+type Checkpoint struct {
+	Version   int             `json:"version"`
+	RNG       []byte          `json:"rng"` // the Source's state
+	World     worldState      `json:"world"`
+	Scheduler schedulerState  `json:"scheduler"`
+	GAs       []gaState       `json:"gas"`
+	Extra     json.RawMessage `json:"extra,omitempty"` // the caller's state, as JSON
+}
+
+type worldState struct {
+	Size        int
+	Tick        int
+	Grid        []Tile
+	OccGrid     []uint16
+	Cooldowns   []byte
+	BiomeGrid   []byte
+	Biomes      bool
+	NPCs        []npcState
+	NextID      uint16
+	FoodSpawned int
+	FoodRate    float64
+	MaxFood     int
+	ItemRate    float64
+	MaxItems    int
+	PoisonOdds  int
+	NightTicks  int
+	PoisonTTL   map[int]int
+	Chests      map[int]*Chest
+}
+
+// npcState is an NPC with its unexported state and brain memory.
+type npcState struct {
+	NPC
+	Visited    []uint64
+	FollowTick int
+	LastHarm   byte
+	NextUse    [ActionCount]int
+	Brain      *brainState `json:",omitempty"` // nil before the NPC first thinks
+}
+
+// brainState is what an NPC's VM keeps from one tick to the next.
+type brainState struct {
+	Memory     []byte
+	Locals     [16]int16
+	Quotations map[int][]byte `json:",omitempty"`
+}
+
+type schedulerState struct {
+	Gas            int
+	TradeCount     int
+	TeachCount     int
+	AttackCount    int
+	HealCount      int
+	HarvestCount   int
+	TerraformCount int
+	KillCount      int
+	PreyKills      int
+	BuildCount     int
+	DepositCount   int
+	RaidCount      int
+	ShotCount      int
+	ShotHits       int
+	GiftCount      int
+	GoldGifted     int
+	ClanJoins      int
+	SleepTicks     int
+	MarketBuys     int
+	MarketSells    int
+	GoldSpent      int
+	Treasury       int
+	TaxCollected   int
+	GoldMinted     int
+	GoldBurned     int
+	Contracts      []Contract
+	ContractsDone  int
+	ContractGold   int
+	Offers         []Offer
+	PostOffers     int
+	PostTrades     int
+	Lessons        map[int]int
+	GasUsed        int64
+	BirthCount     int
+	Epoch          int
+	CareEnergy     int
+	Infections     int
+	Cures          int
+	ClansFounded   int
+	RecipesLearned int
+	Deaths         [DeathCauses]int
+	Graveyard      []Biography
+	Noises         []noiseState // this tick's, heard again next tick
+}
+
+type noiseState struct {
+	X, Y, Loud int
+	Source     uint16
+}
+
+type gaState struct {
+	MutationRate      float64
+	Diversity         float64
+	Measured          bool
+	Species           int
+	Clones            int
+	Births            []Birth
+	MaxGenomeSize     int
+	MinedConstraints  [NumTokenTypes]uint16
+	MinedConstraints8 [8]byte
+}
+
+// SaveCheckpoint writes the state of s's run to out as a JSON Checkpoint:
+// s's World, src (the source behind every random number the run draws) and
+// gas, the run's GAs in a fixed order. extra, if not nil, is saved as JSON
+// alongside. Call it between ticks. The same state always saves the same.
+func SaveCheckpoint(out io.Writer, s *Scheduler, src *Source, gas []*GA, extra any) error {
+	cp := Checkpoint{Version: checkpointVersion}
+	var err error
+	if cp.RNG, err = src.MarshalBinary(); err != nil {
+		return err
+	}
+
+	w := s.World
+	cp.World = worldState{
+		Size: w.Size, Tick: w.Tick, Grid: w.Grid, OccGrid: w.OccGrid, Cooldowns: w.Cooldowns,
+		BiomeGrid: w.BiomeGrid, Biomes: w.Biomes, NextID: w.NextID, FoodSpawned: w.FoodSpawned,
+		FoodRate: w.FoodRate, MaxFood: w.MaxFood, ItemRate: w.ItemRate, MaxItems: w.MaxItems,
+		PoisonOdds: w.PoisonOdds, NightTicks: w.NightTicks, PoisonTTL: w.PoisonTTL, Chests: w.Chests,
+	}
+	for _, npc := range w.NPCs {
+		n := npcState{NPC: *npc, Visited: npc.visited, FollowTick: npc.followTick, LastHarm: npc.lastHarm, NextUse: npc.nextUse}
+		if vm := npc.vm; vm != nil {
+			n.Brain = &brainState{Memory: append([]byte(nil), vm.Memory...), Locals: vm.Locals}
+			for i, q := range vm.Quotations {
+				if q != nil {
+					if n.Brain.Quotations == nil {
+						n.Brain.Quotations = make(map[int][]byte)
+					}
+					n.Brain.Quotations[i] = append([]byte(nil), q...) // poke can patch it
+				}
+			}
+		}
+		cp.World.NPCs = append(cp.World.NPCs, n)
+	}
+
+	cp.Scheduler = schedulerState{
+		Gas: s.Gas, TradeCount: s.TradeCount, TeachCount: s.TeachCount, AttackCount: s.AttackCount,
+		HealCount: s.HealCount, HarvestCount: s.HarvestCount, TerraformCount: s.TerraformCount,
+		KillCount: s.KillCount, PreyKills: s.PreyKills, BuildCount: s.BuildCount, DepositCount: s.DepositCount,
+		RaidCount: s.RaidCount, ShotCount: s.ShotCount, ShotHits: s.ShotHits, GiftCount: s.GiftCount,
+		GoldGifted: s.GoldGifted, ClanJoins: s.ClanJoins, SleepTicks: s.SleepTicks, GasUsed: s.GasUsed,
+		MarketBuys: s.MarketBuys, MarketSells: s.MarketSells, GoldSpent: s.GoldSpent,
+		Treasury: s.Treasury, TaxCollected: s.TaxCollected, GoldMinted: s.GoldMinted, GoldBurned: s.GoldBurned,
+		Contracts: s.Contracts, ContractsDone: s.ContractsDone, ContractGold: s.ContractGold,
+		Offers: s.Offers, PostOffers: s.PostOffers, PostTrades: s.PostTrades, Lessons: s.Lessons,
+		BirthCount: s.BirthCount, Epoch: s.Epoch, CareEnergy: s.CareEnergy, Infections: s.Infections,
+		Cures: s.Cures, ClansFounded: s.clansFounded, RecipesLearned: s.RecipesLearned, Deaths: s.Deaths,
+		Graveyard: s.Graveyard,
+	}
+	buckets := make([][2]int, 0, len(s.noises.now))
+	for key := range s.noises.now {
+		buckets = append(buckets, key)
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		a, b := buckets[i], buckets[j]
+		return a[1] < b[1] || a[1] == b[1] && a[0] < b[0]
+	})
+	for _, key := range buckets {
+		for _, e := range s.noises.now[key] {
+			cp.Scheduler.Noises = append(cp.Scheduler.Noises, noiseState{X: e.x, Y: e.y, Loud: e.loud, Source: e.source})
+		}
+	}
+
+	for _, ga := range gas {
+		cp.GAs = append(cp.GAs, gaState{
+			MutationRate: ga.MutationRate, Diversity: ga.Diversity, Measured: ga.measured, Species: ga.Species,
+			Clones: ga.Clones, Births: ga.Births, MaxGenomeSize: ga.MaxGenomeSize,
+			MinedConstraints: ga.MinedConstraints, MinedConstraints8: ga.MinedConstraints8,
+		})
+	}
+
+	if extra != nil {
+		data, err := json.Marshal(extra)
+		if err != nil {
+			return err
+		}
+		cp.Extra = data
+	}
+	return json.NewEncoder(out).Encode(&cp)
+}
+
+// LoadCheckpoint reads a checkpoint written by SaveCheckpoint.
+func LoadCheckpoint(in io.Reader) (*Checkpoint, error) {
+	var cp Checkpoint
+	if err := json.NewDecoder(in).Decode(&cp); err != nil {
+		return nil, err
+	}
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint version %d, want %d", cp.Version, checkpointVersion)
+	}
+	return &cp, nil
+}
+
+// Restore puts the checkpointed state back into s (and its World), src and
+// gas, which must be set up as the checkpointed run was. The next tick s
+// runs is the one after the checkpoint.
+func (cp *Checkpoint) Restore(s *Scheduler, src *Source, gas []*GA) error {
+	w := s.World
+	if cp.World.Size != w.Size {
+		return fmt.Errorf("checkpoint world is %dx%d, not %dx%d", cp.World.Size, cp.World.Size, w.Size, w.Size)
+	}
+	if len(cp.GAs) != len(gas) {
+		return fmt.Errorf("checkpoint has %d GAs, not %d", len(cp.GAs), len(gas))
+	}
+	if err := src.UnmarshalBinary(cp.RNG); err != nil {
+		return fmt.Errorf("checkpoint random state: %w", err)
+	}
+
+	ws := cp.World
+	w.Tick, w.Grid, w.OccGrid, w.Cooldowns = ws.Tick, ws.Grid, ws.OccGrid, ws.Cooldowns
+	w.BiomeGrid, w.Biomes, w.NextID, w.FoodSpawned = ws.BiomeGrid, ws.Biomes, ws.NextID, ws.FoodSpawned
+	w.FoodRate, w.MaxFood, w.ItemRate, w.MaxItems = ws.FoodRate, ws.MaxFood, ws.ItemRate, ws.MaxItems
+	w.PoisonOdds, w.NightTicks = ws.PoisonOdds, ws.NightTicks
+	w.PoisonTTL, w.Chests = ws.PoisonTTL, ws.Chests
+	if w.PoisonTTL == nil {
+		w.PoisonTTL = make(map[int]int)
+	}
+	if w.Chests == nil {
+		w.Chests = make(map[int]*Chest)
+	}
+	w.foodCount, w.itemCount, w.marketCount = 0, 0, 0
+	for _, t := range w.Grid {
+		if isFood(t.Type()) {
+			w.foodCount++
+		}
+		if isItem(t.Type()) {
+			w.itemCount++
+		}
+		if t.Type() == TileMarket {
+			w.marketCount++
+		}
+	}
+	w.NPCs = make([]*NPC, 0, len(ws.NPCs))
+	w.npcByID = make(map[uint16]*NPC, len(ws.NPCs))
+	for _, n := range ws.NPCs {
+		npc := new(NPC)
+		*npc = n.NPC
+		npc.visited, npc.followTick, npc.lastHarm, npc.nextUse = n.Visited, n.FollowTick, n.LastHarm, n.NextUse
+		if b := n.Brain; b != nil {
+			npc.vm = micro.New()
+			npc.vm.SetMemorySlots(len(b.Memory) / 2)
+			copy(npc.vm.Memory, b.Memory)
+			npc.vm.Locals = b.Locals
+			for i, q := range b.Quotations {
+				npc.vm.DefineQuot(i, q)
+			}
+		}
+		w.NPCs = append(w.NPCs, npc)
+		w.npcByID[npc.ID] = npc
+	}
+
+	ss := cp.Scheduler
+	s.Gas, s.TradeCount, s.TeachCount, s.AttackCount = ss.Gas, ss.TradeCount, ss.TeachCount, ss.AttackCount
+	s.HealCount, s.HarvestCount, s.TerraformCount = ss.HealCount, ss.HarvestCount, ss.TerraformCount
+	s.KillCount, s.PreyKills, s.BuildCount, s.DepositCount = ss.KillCount, ss.PreyKills, ss.BuildCount, ss.DepositCount
+	s.RaidCount, s.ShotCount, s.ShotHits, s.GiftCount = ss.RaidCount, ss.ShotCount, ss.ShotHits, ss.GiftCount
+	s.GoldGifted, s.ClanJoins, s.SleepTicks, s.GasUsed = ss.GoldGifted, ss.ClanJoins, ss.SleepTicks, ss.GasUsed
+	s.MarketBuys, s.MarketSells, s.GoldSpent = ss.MarketBuys, ss.MarketSells, ss.GoldSpent
+	s.Treasury, s.TaxCollected, s.GoldMinted, s.GoldBurned = ss.Treasury, ss.TaxCollected, ss.GoldMinted, ss.GoldBurned
+	s.Contracts, s.ContractsDone, s.ContractGold = ss.Contracts, ss.ContractsDone, ss.ContractGold
+	s.Offers, s.PostOffers, s.PostTrades, s.Lessons = ss.Offers, ss.PostOffers, ss.PostTrades, ss.Lessons
+	s.BirthCount, s.Epoch, s.CareEnergy, s.Infections = ss.BirthCount, ss.Epoch, ss.CareEnergy, ss.Infections
+	s.Cures, s.clansFounded, s.RecipesLearned = ss.Cures, ss.ClansFounded, ss.RecipesLearned
+	s.Deaths, s.Graveyard = ss.Deaths, ss.Graveyard
+	s.noises = newNoiseLog()
+	for _, e := range ss.Noises {
+		key := [2]int{e.X / noiseBucket, e.Y / noiseBucket}
+		s.noises.now[key] = append(s.noises.now[key], noiseEvent{x: e.X, y: e.Y, loud: e.Loud, source: e.Source})
+	}
+
+	for i, ga := range gas {
+		gs := cp.GAs[i]
+		ga.MutationRate, ga.Diversity, ga.measured, ga.Species = gs.MutationRate, gs.Diversity, gs.Measured, gs.Species
+		ga.Clones, ga.Births, ga.MaxGenomeSize = gs.Clones, gs.Births, gs.MaxGenomeSize
+		ga.MinedConstraints, ga.MinedConstraints8 = gs.MinedConstraints, gs.MinedConstraints8
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package sandbox
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxClans caps the number of clans founded over a run (Clan IDs are bytes, 0 = none).
+const MaxClans = 255
+
+// clanSyllables builds pronounceable clan names from the clan ID.
+var clanSyllables = []string{
+	"ka", "ro", "mi", "tu", "sel", "var", "no", "dai",
+	"lo", "qen", "ash", "ur", "bel", "zi", "tor", "eth",
+}
+
+// ClanName returns the display name of a clan (empty for no clan).
+func ClanName(id byte) string {
+	if id == 0 {
+		return ""
+	}
+	a := clanSyllables[int(id)%len(clanSyllables)]
+	b := clanSyllables[(int(id)/len(clanSyllables)+int(id)*7)%len(clanSyllables)]
+	return strings.ToUpper(a[:1]) + a[1:] + b
+}
+
+// ClanStat aggregates one clan's living members.
+type ClanStat struct {
+	ID         byte
+	Name       string
+	Members    int
+	AvgFitness int
+	Gold       int
+	Items      int
+}
+
+// ClanStats returns per-clan aggregates for living NPCs, ordered by clan ID.
+func ClanStats(npcs []*NPC) []ClanStat {
+	byID := make(map[byte]*ClanStat)
+	for _, npc := range npcs {
+		if npc.Clan == 0 || !npc.Alive() {
+			continue
+		}
+		cs := byID[npc.Clan]
+		if cs == nil {
+			cs = &ClanStat{ID: npc.Clan, Name: ClanName(npc.Clan)}
+			byID[npc.Clan] = cs
+		}
+		cs.Members++
+		cs.AvgFitness += npc.Fitness
+		cs.Gold += npc.Gold
+		if npc.Item != ItemNone {
+			cs.Items++
+		}
+	}
+	stats := make([]ClanStat, 0, len(byID))
+	for _, cs := range byID {
+		cs.AvgFitness /= cs.Members
+		stats = append(stats, *cs)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
+
+// joinClan puts npc into other's clan, founding a new clan for the pair if
+// other has none. Returns false once MaxClans have been founded.
+func (s *Scheduler) joinClan(npc, other *NPC) bool {
+	if other.Clan == 0 {
+		if s.clansFounded >= MaxClans {
+			return false
+		}
+		s.clansFounded++
+		other.Clan = byte(s.clansFounded)
+	}
+	if npc.Clan == other.Clan {
+		return false
+	}
+	npc.Clan = other.Clan
+	s.ClanJoins++
+	return true
+}
+
+// shareClanFitness blends each clan member's fitness with its clan's
+// average: fitness = (1-ClanShare)*own + ClanShare*clanAvg.
+func (s *Scheduler) shareClanFitness() {
+	if s.ClanShare <= 0 {
+		return
+	}
+	sum := make(map[byte]int)
+	count := make(map[byte]int)
+	for _, npc := range s.World.NPCs {
+		if npc.Clan != 0 {
+			sum[npc.Clan] += npc.Fitness
+			count[npc.Clan]++
+		}
+	}
+	for _, npc := range s.World.NPCs {
+		if npc.Clan == 0 || count[npc.Clan] < 2 {
+			continue
+		}
+		avg := float64(sum[npc.Clan]) / float64(count[npc.Clan])
+		npc.Fitness = int((1-s.ClanShare)*float64(npc.Fitness) + s.ClanShare*avg)
+	}
+}
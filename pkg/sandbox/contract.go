@@ -0,0 +1,159 @@
+package sandbox
+
+// Contracts are jobs the world posts for gold: deliver an item to a tile,
+// or eliminate a wanted NPC. Any NPC may fulfil one; the reward counts as
+// earned gold (taxed like a trade, and scored by Fitness.Econ). With
+// Scheduler.ContractEvery set, a contract is posted every that many ticks,
+// up to maxContracts open at once, and lapses after contractTTL ticks.
+const (
+	maxContracts = 4
+	contractTTL  = 500
+)
+
+// Contract kinds
+const (
+	ContractDeliver   = 1 // bring Item to (X, Y)
+	ContractEliminate = 2 // kill Target
+)
+
+// Contract is one open job.
+type Contract struct {
+	Kind    byte
+	Item    byte   // ContractDeliver: the item wanted
+	X, Y    int    // ContractDeliver: where to bring it
+	Target  uint16 // ContractEliminate: the wanted NPC
+	Reward  int    // gold paid on completion
+	Expires int    // tick the contract lapses
+}
+
+// contractGoods are the items delivery contracts ask for.
+var contractGoods = [...]byte{ItemTool, ItemWeapon, ItemTreasure, ItemCrystal}
+
+// runContracts pays out deliveries, drops lapsed contracts and those whose
+// target died some other way, and posts new ones. Does nothing (and draws
+// no randomness) while ContractEvery is 0.
+func (s *Scheduler) runContracts() {
+	if s.ContractEvery <= 0 {
+		return
+	}
+	w := s.World
+	open := s.Contracts[:0]
+	for _, c := range s.Contracts {
+		switch {
+		case w.Tick >= c.Expires:
+			continue
+		case c.Kind == ContractEliminate:
+			if target := w.npcByID[c.Target]; target == nil || !target.Alive() {
+				continue
+			}
+		case c.Kind == ContractDeliver:
+			if npc := w.npcByID[w.OccAt(c.X, c.Y)]; npc != nil && npc.Alive() && npc.Item == c.Item {
+				removeItemModifier(npc, npc.Item)
+				npc.Item = ItemNone
+				s.payContract(npc, c)
+				continue
+			}
+		}
+		open = append(open, c)
+	}
+	s.Contracts = open
+
+	if w.Tick%s.ContractEvery == 0 && len(s.Contracts) < maxContracts {
+		if c, ok := s.newContract(); ok {
+			s.Contracts = append(s.Contracts, c)
+			s.emit(ContractPosted{Tick: w.Tick, Contract: c})
+		}
+	}
+}
+
+// newContract draws a contract: a bounty on the deadliest NPC (if anyone
+// has killed and is not already wanted) a third of the time, else a
+// delivery of a raw item to a random open tile, paying twice its value.
+func (s *Scheduler) newContract() (Contract, bool) {
+	w := s.World
+	c := Contract{Expires: w.Tick + contractTTL}
+	if w.Rng.Intn(3) == 0 {
+		var wanted *NPC
+		for _, npc := range w.NPCs {
+			if npc.Alive() && npc.Kills > 0 && (wanted == nil || npc.Kills > wanted.Kills) {
+				wanted = npc
+			}
+		}
+		if wanted != nil && !s.wanted(wanted.ID) {
+			c.Kind, c.Target = ContractEliminate, wanted.ID
+			c.Reward = 20 + 10*wanted.Kills
+			return c, true
+		}
+	}
+	c.Kind = ContractDeliver
+	c.Item = contractGoods[w.Rng.Intn(len(contractGoods))]
+	for tries := 0; tries < 50; tries++ {
+		x, y := w.Rng.Intn(w.Size), w.Rng.Intn(w.Size)
+		if w.TileAt(x, y).Type() != TileEmpty {
+			continue
+		}
+		if w.Biomes && w.BiomeGrid != nil && !BiomeTable[w.BiomeGrid[w.idx(x, y)]].Passable {
+			continue
+		}
+		c.X, c.Y = x, y
+		c.Reward = 2 * w.MarketValue(c.Item)
+		return c, true
+	}
+	return c, false
+}
+
+// wanted reports whether an open contract is out on id.
+func (s *Scheduler) wanted(id uint16) bool {
+	for _, c := range s.Contracts {
+		if c.Kind == ContractEliminate && c.Target == id {
+			return true
+		}
+	}
+	return false
+}
+
+// claimBounty pays killer for every contract out on victim, who has just
+// died; runContracts drops contracts on NPCs who die any other way.
+func (s *Scheduler) claimBounty(killer, victim *NPC) {
+	open := s.Contracts[:0]
+	for _, c := range s.Contracts {
+		if c.Kind == ContractEliminate && c.Target == victim.ID && killer != victim {
+			s.payContract(killer, c)
+			continue
+		}
+		open = append(open, c)
+	}
+	s.Contracts = open
+}
+
+// payContract rewards npc for completing c.
+func (s *Scheduler) payContract(npc *NPC, c Contract) {
+	npc.Gold += c.Reward
+	npc.GoldEarned += s.taxEarnings(npc, c.Reward)
+	s.ContractsDone++
+	s.ContractGold += c.Reward
+	s.emit(ContractFulfilled{Tick: s.World.Tick, NPC: npc.ID, Contract: c})
+}
+
+// nearestContract returns the direction toward, and the reward and wanted
+// item (0 for a bounty) of, the open contract whose objective is closest
+// to npc. A bounty on npc itself is not one it can take.
+func (s *Scheduler) nearestContract(npc *NPC) (dir, reward, item int) {
+	w := s.World
+	best := -1
+	for _, c := range s.Contracts {
+		x, y := c.X, c.Y
+		if c.Kind == ContractEliminate {
+			target := w.npcByID[c.Target]
+			if target == nil || target == npc {
+				continue
+			}
+			x, y = target.X, target.Y
+		}
+		if d := abs(x-npc.X) + abs(y-npc.Y); best < 0 || d < best {
+			best = d
+			dir, reward, item = directionToward(npc.X, npc.Y, x, y), c.Reward, int(c.Item)
+		}
+	}
+	return dir, reward, item
+}
@@ -0,0 +1,193 @@
+package sandbox
+
+import (
+	"sync"
+	"time"
+)
+
+// TickControl steers a running simulation from another goroutine, e.g. a
+// keyboard handler or a web dashboard. Scheduler implements it; the loop
+// driving Tick calls Scheduler.Wait before each tick to honour it.
+type TickControl interface {
+	Pause()
+	Resume()
+	Step(n int)
+	SetSpeed(ticksPerSec float64)
+	Stop()
+}
+
+// clock is the Scheduler's pause/step/speed state.
+type clock struct {
+	mu       sync.Mutex
+	wake     chan struct{} // nudged on every state change
+	paused   bool
+	steps    int           // ticks still allowed while paused
+	interval time.Duration // minimum time between ticks (0 = flat out)
+	last     time.Time     // when the previous tick was released
+	stopped  bool
+	calls    []func() // queued by Do, run by Wait or RunCalls
+}
+
+// nudge wakes a goroutine blocked in Wait. Called with mu held.
+func (c *clock) nudge() {
+	if c.wake == nil {
+		c.wake = make(chan struct{}, 1)
+	}
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Pause holds the simulation before its next tick.
+func (s *Scheduler) Pause() {
+	c := &s.clock
+	c.mu.Lock()
+	c.paused, c.steps = true, 0
+	c.nudge()
+	c.mu.Unlock()
+}
+
+// Resume lets a paused simulation run on.
+func (s *Scheduler) Resume() {
+	c := &s.clock
+	c.mu.Lock()
+	c.paused, c.steps = false, 0
+	c.nudge()
+	c.mu.Unlock()
+}
+
+// Step pauses the simulation after n more ticks.
+func (s *Scheduler) Step(n int) {
+	c := &s.clock
+	c.mu.Lock()
+	c.paused = true
+	c.steps += n
+	c.nudge()
+	c.mu.Unlock()
+}
+
+// SetSpeed caps the simulation at ticksPerSec (0 or less = no cap).
+func (s *Scheduler) SetSpeed(ticksPerSec float64) {
+	c := &s.clock
+	c.mu.Lock()
+	c.interval = 0
+	if ticksPerSec > 0 {
+		c.interval = time.Duration(float64(time.Second) / ticksPerSec)
+	}
+	c.nudge()
+	c.mu.Unlock()
+}
+
+// Stop ends the simulation: Wait returns false from now on.
+func (s *Scheduler) Stop() {
+	c := &s.clock
+	c.mu.Lock()
+	c.stopped = true
+	c.nudge()
+	c.mu.Unlock()
+}
+
+// Do runs fn on the goroutine driving the simulation, between ticks, and
+// returns once it has run: the safe way for another goroutine to read or
+// change the world. It returns false without running fn once Stop has been
+// called.
+func (s *Scheduler) Do(fn func()) bool {
+	c := &s.clock
+	done := make(chan struct{})
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return false
+	}
+	c.calls = append(c.calls, func() {
+		defer close(done)
+		fn()
+	})
+	c.nudge()
+	c.mu.Unlock()
+	<-done
+	return true
+}
+
+// RunCalls runs the calls queued by Do. Wait does this itself; a loop that
+// holds the simulation some other way (say, a paused UI) calls it instead.
+func (s *Scheduler) RunCalls() {
+	c := &s.clock
+	for {
+		c.mu.Lock()
+		calls := c.calls
+		c.calls = nil
+		c.mu.Unlock()
+		if len(calls) == 0 {
+			return
+		}
+		for _, fn := range calls {
+			fn()
+		}
+	}
+}
+
+// Paused reports whether the simulation is paused with no steps pending.
+func (s *Scheduler) Paused() bool {
+	c := &s.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused && c.steps == 0
+}
+
+// Wait blocks until the next tick may run: immediately when running flat
+// out, after the speed interval, or on Resume/Step while paused, running
+// any calls queued by Do meanwhile. It returns false once Stop has been
+// called.
+func (s *Scheduler) Wait() bool {
+	c := &s.clock
+	for {
+		s.RunCalls()
+		c.mu.Lock()
+		if c.wake == nil {
+			c.wake = make(chan struct{}, 1)
+		}
+		wake := c.wake
+		switch {
+		case c.stopped:
+			c.mu.Unlock()
+			return false
+		case c.paused && c.steps > 0:
+			c.steps--
+			c.last = time.Now()
+			c.mu.Unlock()
+			return true
+		case !c.paused:
+			delay := c.interval - time.Since(c.last)
+			if delay <= 0 {
+				c.last = time.Now()
+				c.mu.Unlock()
+				return true
+			}
+			c.mu.Unlock()
+			select {
+			case <-time.After(delay):
+			case <-wake:
+			}
+			continue
+		}
+		c.mu.Unlock()
+		<-wake
+	}
+}
+
+// Run ticks the simulation under the control state until ticks have run
+// (0 = until stopped). after, if set, is called following each tick and
+// may return false to end the run.
+func (s *Scheduler) Run(ticks int, after func() bool) {
+	for n := 0; ticks <= 0 || n < ticks; n++ {
+		if !s.Wait() {
+			return
+		}
+		s.Tick()
+		if after != nil && !after() {
+			return
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package sandbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Stage is one step of a Curriculum. It is reached once the world tick is
+// at least Tick and, if Fitness is set, the living NPCs' average fitness is
+// at least Fitness; its world settings then take effect. Zero settings are
+// left as they are.
+type Stage struct {
+	Tick    int // earliest tick
+	Fitness int // average fitness milestone (0 = none)
+
+	FoodRate   float64 // World.FoodRate (still decays from here)
+	MaxFood    int     // World.MaxFood
+	PoisonOdds int     // World.PoisonOdds
+	NightTicks int     // World.NightTicks
+}
+
+// Curriculum raises the difficulty of a run in stages, so behaviours that
+// work in an easy world are carried into harder ones. Stages are reached in
+// order: a stage waits for the one before it.
+type Curriculum struct {
+	Stages  []Stage
+	Reached int // stages applied so far
+}
+
+// Install registers the curriculum as a PreTick hook on s.
+func (c *Curriculum) Install(s *Scheduler) {
+	s.PreTick(c.advance)
+}
+
+// advance applies every stage that is due and emits StageReached for each.
+func (c *Curriculum) advance(s *Scheduler) {
+	w := s.World
+	for c.Reached < len(c.Stages) && c.Stages[c.Reached].due(w) {
+		c.Stages[c.Reached].apply(w)
+		s.emit(StageReached{Tick: w.Tick, Stage: c.Reached})
+		c.Reached++
+	}
+}
+
+// due reports whether w has met the stage's milestones.
+func (st *Stage) due(w *World) bool {
+	if w.Tick < st.Tick {
+		return false
+	}
+	if st.Fitness == 0 {
+		return true
+	}
+	total, alive := 0, 0
+	for _, npc := range w.NPCs {
+		if npc.Alive() {
+			total += npc.Fitness
+			alive++
+		}
+	}
+	return alive > 0 && total/alive >= st.Fitness
+}
+
+// apply sets the stage's world settings.
+func (st *Stage) apply(w *World) {
+	if st.FoodRate > 0 {
+		w.FoodRate = st.FoodRate
+	}
+	if st.MaxFood > 0 {
+		w.MaxFood = st.MaxFood
+	}
+	if st.PoisonOdds > 0 {
+		w.PoisonOdds = st.PoisonOdds
+	}
+	if st.NightTicks > 0 {
+		w.NightTicks = st.NightTicks
+	}
+}
+
+// ParseCurriculum reads stages separated by ";" or newlines, each a list of
+// "key=value" settings, e.g. "at=2000,food=0.1;fit=500,poison=4,night=96".
+// Keys: at (tick), fit (average fitness), food (spawn rate), maxfood,
+// poison (1-in-N poison odds) and night (winter ticks per day cycle, up to
+// DayCycle). Lines starting with # are comments.
+func ParseCurriculum(spec string) (*Curriculum, error) {
+	c := &Curriculum{}
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var st Stage
+		for _, part := range strings.Split(line, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			key, val, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, fmt.Errorf("curriculum: %q is not key=value", part)
+			}
+			key, val = strings.TrimSpace(key), strings.TrimSpace(val)
+			if key == "food" {
+				rate, err := strconv.ParseFloat(val, 64)
+				if err != nil || rate <= 0 || rate > 1 {
+					return nil, fmt.Errorf("curriculum: food: want a rate in (0,1], got %q", val)
+				}
+				st.FoodRate = rate
+				continue
+			}
+			n, err := strconv.Atoi(val)
+			if err != nil {
+				return nil, fmt.Errorf("curriculum: %s: %w", key, err)
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("curriculum: %s: negative value %d", key, n)
+			}
+			switch key {
+			case "at":
+				st.Tick = n
+			case "fit":
+				st.Fitness = n
+			case "maxfood":
+				st.MaxFood = n
+			case "poison":
+				st.PoisonOdds = n
+			case "night":
+				if n > DayCycle {
+					return nil, fmt.Errorf("curriculum: night: %d is longer than a day (%d)", n, DayCycle)
+				}
+				st.NightTicks = n
+			default:
+				return nil, fmt.Errorf("curriculum: unknown key %q (want at, fit, food, maxfood, poison, night)", key)
+			}
+		}
+		c.Stages = append(c.Stages, st)
+	}
+	return c, nil
+}
@@ -0,0 +1,103 @@
+package sandbox
+
+import "fmt"
+
+// Behaviours, as ClassifyBehavior names them
+const (
+	BehaviorIdle = iota
+	BehaviorForager
+	BehaviorGiver
+	BehaviorTrader
+	BehaviorTeacher
+	BehaviorCrafter
+	BehaviorFighter
+	BehaviorPredator
+	BehaviorCount
+)
+
+// BehaviorNames names the behaviours, indexed by BehaviorIdle..BehaviorPredator.
+var BehaviorNames = [BehaviorCount]string{
+	"idle", "forager", "giver", "trader", "teacher", "crafter", "fighter", "predator",
+}
+
+// ClassifyBehavior sorts npc by what it has done in its life, taking the
+// rarest deed it has to its name: a predator is always one, anyone who has
+// killed is a fighter, then crafter, teacher, trader, giver (gifts or
+// energy shares) and forager. An NPC that has done none of these is idle.
+func ClassifyBehavior(npc *NPC) int {
+	switch {
+	case npc.Predator:
+		return BehaviorPredator
+	case npc.Kills > 0:
+		return BehaviorFighter
+	case npc.CraftCount > 0:
+		return BehaviorCrafter
+	case npc.TeachCount > 0:
+		return BehaviorTeacher
+	case npc.Trades > 0:
+		return BehaviorTrader
+	case npc.GiftCount > 0 || npc.Shares > 0:
+		return BehaviorGiver
+	case npc.FoodEaten > 0:
+		return BehaviorForager
+	}
+	return BehaviorIdle
+}
+
+// AgeBands is how many equal bands of MaxAge Demographics.Ages has.
+const AgeBands = 5
+
+// GenomeBandLimits are the genome lengths below which each of
+// Demographics.Genomes' bands ends; one more band holds the longer genomes.
+var GenomeBandLimits = [...]int{16, 32, 64, 128}
+
+// Demographics is the structure of a population: how many living NPCs
+// fall in each age band, genome-length band and behaviour.
+type Demographics struct {
+	Ages      [AgeBands]int
+	Genomes   [len(GenomeBandLimits) + 1]int
+	Behaviors [BehaviorCount]int
+}
+
+// CensusDemographics takes the Demographics of the living NPCs in npcs.
+func CensusDemographics(npcs []*NPC) Demographics {
+	var d Demographics
+	for _, npc := range npcs {
+		if !npc.Alive() {
+			continue
+		}
+		d.Ages[min(max(npc.Age, 0)*AgeBands/MaxAge, AgeBands-1)]++
+		band := len(GenomeBandLimits)
+		for i, limit := range GenomeBandLimits {
+			if len(npc.Genome) < limit {
+				band = i
+				break
+			}
+		}
+		d.Genomes[band]++
+		d.Behaviors[ClassifyBehavior(npc)]++
+	}
+	return d
+}
+
+// Labels names the Demographics counts in the order Values lists them:
+// "age<1000".., "genome<16".., "genome128+", then the behaviour names.
+func (d Demographics) Labels() []string {
+	labels := make([]string, 0, len(d.Ages)+len(d.Genomes)+len(d.Behaviors))
+	for i := range d.Ages {
+		labels = append(labels, fmt.Sprintf("age<%d", (i+1)*MaxAge/AgeBands))
+	}
+	for _, limit := range GenomeBandLimits {
+		labels = append(labels, fmt.Sprintf("genome<%d", limit))
+	}
+	labels = append(labels, fmt.Sprintf("genome%d+", GenomeBandLimits[len(GenomeBandLimits)-1]))
+	return append(labels, BehaviorNames[:]...)
+}
+
+// Values lists the Demographics counts: ages, genome lengths, behaviours.
+func (d Demographics) Values() []int {
+	values := make([]int, 0, len(d.Ages)+len(d.Genomes)+len(d.Behaviors))
+	values = append(values, d.Ages[:]...)
+	values = append(values, d.Genomes[:]...)
+	return append(values, d.Behaviors[:]...)
+}
@@ -0,0 +1,80 @@
+package sandbox
+
+// Disease parameters. An infection lasts infectionTicks, draining
+// infectionDrain extra energy per tick, and leaves the NPC immune. With
+// Scheduler.Contagion enabled a random NPC falls ill every outbreakEvery ticks.
+const (
+	infectionTicks = 60
+	infectionDrain = 1
+	outbreakEvery  = 500
+)
+
+// infectionState returns the Ring0Infected sensor value.
+func (n *NPC) infectionState() int {
+	switch {
+	case n.Infection > 0:
+		return 1
+	case n.Immune:
+		return 2
+	}
+	return 0
+}
+
+// Infect makes npc ill unless it is already infected or immune.
+func (s *Scheduler) Infect(npc *NPC) bool {
+	if npc.Infection > 0 || npc.Immune || !npc.Alive() {
+		return false
+	}
+	npc.Infection = infectionTicks
+	s.Infections++
+	return true
+}
+
+// spreadDisease seeds periodic outbreaks, spreads infection to neighbours,
+// drains the sick, and cures those holding a remedy. Does nothing (and draws
+// no randomness) while Contagion is 0.
+func (s *Scheduler) spreadDisease() {
+	if s.Contagion <= 0 {
+		return
+	}
+	w := s.World
+	if len(w.NPCs) > 0 && w.Tick%outbreakEvery == 0 {
+		s.Infect(w.NPCs[w.Rng.Intn(len(w.NPCs))])
+	}
+
+	// Snapshot the sick first so infection spreads one step per tick
+	var sick []*NPC
+	for _, npc := range w.NPCs {
+		if npc.Infection > 0 {
+			sick = append(sick, npc)
+		}
+	}
+	for _, npc := range sick {
+		if npc.Item == ItemRemedy {
+			removeItemModifier(npc, npc.Item)
+			npc.Item = ItemNone
+			npc.Infection = 0
+			npc.Immune = true
+			s.Cures++
+			continue
+		}
+		for dir := byte(DirNorth); dir <= DirWest; dir++ {
+			dx, dy := dirDelta(dir)
+			if !w.InBounds(npc.X+dx, npc.Y+dy) {
+				continue
+			}
+			other := w.npcByID[w.OccAt(npc.X+dx, npc.Y+dy)]
+			if other != nil && other.Infection == 0 && !other.Immune && w.Rng.Intn(100) < s.Contagion {
+				s.Infect(other)
+			}
+		}
+		npc.Energy -= infectionDrain
+		if npc.Energy < 0 {
+			npc.Energy = 0
+		}
+		npc.Infection--
+		if npc.Infection == 0 {
+			npc.Immune = true
+		}
+	}
+}
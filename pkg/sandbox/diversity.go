@@ -0,0 +1,150 @@
+package sandbox
+
+import (
+	"math"
+	"sort"
+)
+
+// diversitySample caps how many genomes GenomeDiversity compares (all
+// pairs among an evenly spaced sample).
+const diversitySample = 48
+
+// maxMutationBoost is how far a total diversity collapse multiplies the
+// mutation rate: up to (1+maxMutationBoost)×MutationRate.
+const maxMutationBoost = 2
+
+// GenomeDiversity returns the mean pairwise distance between the NPCs'
+// genomes, from 0 (all clones) to 1 (no instruction in common). Distance
+// is the edit distance over opcode-aligned instructions, divided by the
+// longer genome's instruction count. Large populations are sampled.
+func GenomeDiversity(npcs []*NPC) float64 {
+	n := len(npcs)
+	if n < 2 {
+		return 0
+	}
+	k := min(n, diversitySample)
+	tokens := make([][]string, k)
+	for i := range tokens {
+		tokens[i] = genomeTokens(npcs[i*n/k].Genome)
+	}
+	total, pairs := 0.0, 0
+	for i := 0; i < k; i++ {
+		for j := i + 1; j < k; j++ {
+			total += tokenDistance(tokens[i], tokens[j])
+			pairs++
+		}
+	}
+	return total / float64(pairs)
+}
+
+// genomeTokens splits a genome into whole instructions.
+func genomeTokens(g []byte) []string {
+	points := OpcodeAlignedPoints(g)
+	tokens := make([]string, len(points)-1)
+	for i := range tokens {
+		tokens[i] = string(g[points[i]:points[i+1]])
+	}
+	return tokens
+}
+
+// tokenDistance is the normalized Levenshtein distance between two
+// instruction sequences.
+func tokenDistance(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(min(prev[j], cur[j-1])+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return float64(prev[len(b)]) / float64(max(len(a), len(b)))
+}
+
+// EffectiveMutation is the mutation rate breed applies: MutationRate, raised
+// by up to maxMutationBoost× while the last measured Diversity is below
+// DiversityTarget. Rates above 1 mean more than one mutation per child.
+func (ga *GA) EffectiveMutation() float64 {
+	if ga.DiversityTarget <= 0 || !ga.measured || ga.Diversity >= ga.DiversityTarget {
+		return ga.MutationRate
+	}
+	collapse := (ga.DiversityTarget - ga.Diversity) / ga.DiversityTarget
+	return ga.MutationRate * (1 + maxMutationBoost*collapse)
+}
+
+// GenomeCensus counts the distinct genomes in a population, so a large
+// population of clones shows up as what it is.
+type GenomeCensus struct {
+	Unique  int     // distinct genomes
+	Largest int     // NPCs sharing the most common genome
+	Shannon float64 // Shannon index of genome frequencies, in nats (0 = monoculture)
+	Simpson float64 // chance two NPCs drawn at random carry different genomes
+}
+
+// CensusGenomes takes a GenomeCensus of npcs (exact genome matches).
+func CensusGenomes(npcs []*NPC) GenomeCensus {
+	counts := make(map[string]int)
+	for _, npc := range npcs {
+		counts[string(npc.Genome)]++
+	}
+	c := GenomeCensus{Unique: len(counts)}
+	n := float64(len(npcs))
+	same := 0.0
+	for _, k := range counts {
+		c.Largest = max(c.Largest, k)
+		p := float64(k) / n
+		c.Shannon -= p * math.Log(p)
+		same += p * p
+	}
+	if len(npcs) > 0 {
+		c.Simpson = 1 - same
+	}
+	return c
+}
+
+// penalizeClones walks npcs best first and takes ClonePenalty of the
+// fitness of every NPC whose genome an earlier one already carries (or,
+// with CloneDistance, is within that distance of an earlier distinct
+// genome). It returns how many NPCs were penalized.
+func (ga *GA) penalizeClones(npcs []*NPC) int {
+	sorted := append([]*NPC(nil), npcs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Fitness > sorted[j].Fitness
+	})
+	seen := make(map[string]bool)
+	var originals [][]string
+	clones := 0
+	for _, npc := range sorted {
+		key := string(npc.Genome)
+		clone := seen[key]
+		if !clone && ga.CloneDistance > 0 {
+			tokens := genomeTokens(npc.Genome)
+			for _, o := range originals {
+				if tokenDistance(tokens, o) < ga.CloneDistance {
+					clone = true
+					break
+				}
+			}
+			if !clone {
+				originals = append(originals, tokens)
+			}
+		}
+		seen[key] = true
+		if clone {
+			npc.Fitness -= int(math.Abs(float64(npc.Fitness)) * ga.ClonePenalty)
+			clones++
+		}
+	}
+	return clones
+}
@@ -0,0 +1,94 @@
+package sandbox
+
+import "sort"
+
+// Economy is a policy experiment for the gold economy. The zero value
+// changes nothing.
+//
+// Tax is withheld from gold as it is earned by trading (bilateral trades
+// and market sales) and held in the Scheduler's Treasury. SettleEconomy,
+// run once per epoch, seizes gold above WealthCap into the treasury too,
+// then shares the treasury out equally (Redistribute) or destroys it, and
+// finally mints Faucet gold for every living NPC.
+type Economy struct {
+	TaxRate      float64 // fraction of earned gold withheld (0-1)
+	Redistribute bool    // share the treasury among the living each epoch instead of destroying it
+	Faucet       int     // gold minted per living NPC each epoch (inflation)
+	WealthCap    int     // gold an NPC may hold at the end of an epoch (0 = no cap)
+}
+
+// taxEarnings withholds the tax on gold npc has just earned and returns
+// what npc keeps.
+func (s *Scheduler) taxEarnings(npc *NPC, earned int) int {
+	tax := int(float64(earned) * s.Economy.TaxRate)
+	if tax <= 0 {
+		return earned
+	}
+	npc.Gold -= tax
+	s.Treasury += tax
+	s.TaxCollected += tax
+	return earned - tax
+}
+
+// SettleEconomy applies the Economy's end-of-epoch rules: wealth cap,
+// then treasury payout or destruction, then the faucet.
+func (s *Scheduler) SettleEconomy() {
+	e := s.Economy
+	var alive []*NPC
+	for _, npc := range s.World.NPCs {
+		if npc.Alive() {
+			alive = append(alive, npc)
+		}
+	}
+	if e.WealthCap > 0 {
+		for _, npc := range alive {
+			if excess := npc.Gold - e.WealthCap; excess > 0 {
+				npc.Gold -= excess
+				s.Treasury += excess
+				s.TaxCollected += excess
+			}
+		}
+	}
+	if s.Treasury > 0 {
+		if e.Redistribute && len(alive) > 0 {
+			share := s.Treasury / len(alive)
+			for _, npc := range alive {
+				npc.Gold += share
+			}
+			s.Treasury -= share * len(alive) // the remainder waits for next epoch
+		} else if !e.Redistribute {
+			s.GoldBurned += s.Treasury
+			s.Treasury = 0
+		}
+	}
+	if e.Faucet > 0 {
+		for _, npc := range alive {
+			npc.Gold += e.Faucet
+		}
+		s.GoldMinted += e.Faucet * len(alive)
+	}
+}
+
+// GoldGini returns the Gini coefficient of gold held by the living NPCs:
+// 0 when everyone holds the same, approaching 1 when one NPC holds it all.
+func GoldGini(npcs []*NPC) float64 {
+	var gold []int
+	total := 0
+	for _, npc := range npcs {
+		if npc.Alive() {
+			gold = append(gold, npc.Gold)
+			total += npc.Gold
+		}
+	}
+	n := len(gold)
+	if n == 0 || total <= 0 {
+		return 0
+	}
+	// With gold sorted ascending, G = 2·Σ i·x_i / (n·Σx) - (n+1)/n
+	sort.Ints(gold)
+	weighted := 0
+	for i, g := range gold {
+		weighted += (i + 1) * g
+	}
+	return 2*float64(weighted)/(float64(n)*float64(total)) - float64(n+1)/float64(n)
+}
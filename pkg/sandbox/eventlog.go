@@ -0,0 +1,174 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// EventLog writes one JSON line per NPC event, for offline analysis: births,
+// trades, teaching, crafting and deaths as they happen, plus a "moves" line
+// summing up how far each NPC walked in each epoch (GA generation). Every
+// line carries tick, event, npc and epoch; the rest depends on the event:
+//
+//	birth   parents, clone
+//	moves   ticks, steps, dist, x, y, fitness
+//	trade   with
+//	teach   student
+//	craft   input, output
+//	death   cause, age, fitness
+//	swap    frozen
+//
+// A moves line covers the ticks since the NPC's previous one and ends an
+// epoch, or comes just before the death or GA rebirth that ends the
+// individual. Attach the log before the first tick and Finish it after the
+// last.
+type EventLog struct {
+	w     *bufio.Writer
+	enc   *json.Encoder
+	s     *Scheduler
+	moves map[uint16]*moveTally
+}
+
+// moveTally is an NPC's movement since its last moves line.
+type moveTally struct {
+	epoch   int
+	ticks   int
+	steps   int // tiles walked
+	x0, y0  int // where the tally began
+	x, y    int
+	fitness int
+}
+
+// eventHead is the part every EventLog line shares.
+type eventHead struct {
+	Tick  int    `json:"tick"`
+	Event string `json:"event"`
+	NPC   uint16 `json:"npc"`
+	Epoch int    `json:"epoch"`
+}
+
+// NewEventLog returns an EventLog writing to out.
+func NewEventLog(out io.Writer) *EventLog {
+	w := bufio.NewWriter(out)
+	return &EventLog{w: w, enc: json.NewEncoder(w), moves: make(map[uint16]*moveTally)}
+}
+
+// Attach subscribes l to s and hooks it in to follow movement.
+func (l *EventLog) Attach(s *Scheduler) {
+	l.s = s
+	s.Subscribe(l)
+	s.PostTick(l.track)
+	s.OnEvolve(func(s *Scheduler, _ []*NPC) {
+		l.flushAll(s.World.Tick)
+	})
+}
+
+func (l *EventLog) head(tick int, event string, npc uint16) eventHead {
+	return eventHead{Tick: tick, Event: event, NPC: npc, Epoch: l.s.Epoch}
+}
+
+// OnEvent logs NPC events.
+func (l *EventLog) OnEvent(ev Event) {
+	switch e := ev.(type) {
+	case NPCBorn:
+		// A GA offspring replaces the individual living under its ID
+		l.flushMoves(e.ID, e.Tick)
+		l.enc.Encode(struct {
+			eventHead
+			Parents [2]uint16 `json:"parents"`
+			Clone   bool      `json:"clone"`
+		}{eventHead{Tick: e.Tick, Event: "birth", NPC: e.ID, Epoch: e.Epoch}, e.Parents, e.Clone})
+	case TradeCompleted:
+		l.enc.Encode(struct {
+			eventHead
+			With uint16 `json:"with"`
+		}{l.head(e.Tick, "trade", e.A), e.B})
+	case TeachSucceeded:
+		l.enc.Encode(struct {
+			eventHead
+			Student uint16 `json:"student"`
+		}{l.head(e.Tick, "teach", e.Teacher), e.Student})
+	case ItemCrafted:
+		l.enc.Encode(struct {
+			eventHead
+			Input  byte `json:"input"`
+			Output byte `json:"output"`
+		}{l.head(e.Tick, "craft", e.NPC), e.Input, e.Output})
+	case NPCDied:
+		l.flushMoves(e.ID, e.Tick)
+		l.enc.Encode(struct {
+			eventHead
+			Cause   string `json:"cause"`
+			Age     int    `json:"age"`
+			Fitness int    `json:"fitness"`
+		}{l.head(e.Tick, "death", e.ID), DeathCauseNames[e.Cause], e.Age, e.Fitness})
+	case GenomeSwapped:
+		l.enc.Encode(struct {
+			eventHead
+			Frozen bool `json:"frozen"`
+		}{l.head(e.Tick, "swap", e.NPC), e.Frozen})
+	case ContractFulfilled:
+		l.enc.Encode(struct {
+			eventHead
+			Kind   byte `json:"kind"`
+			Reward int  `json:"reward"`
+		}{l.head(e.Tick, "contract", e.NPC), e.Contract.Kind, e.Contract.Reward})
+	}
+}
+
+// track adds this tick's movement to every living NPC's tally.
+func (l *EventLog) track(s *Scheduler) {
+	for _, npc := range s.World.NPCs {
+		if !npc.Alive() {
+			continue
+		}
+		m := l.moves[npc.ID]
+		if m == nil {
+			m = &moveTally{epoch: s.Epoch, x0: npc.X, y0: npc.Y, x: npc.X, y: npc.Y}
+			l.moves[npc.ID] = m
+		}
+		m.ticks++
+		m.steps += abs(npc.X-m.x) + abs(npc.Y-m.y)
+		m.x, m.y, m.fitness = npc.X, npc.Y, npc.Fitness
+	}
+}
+
+// flushMoves writes and clears NPC id's movement tally, if it has one.
+func (l *EventLog) flushMoves(id uint16, tick int) {
+	m := l.moves[id]
+	if m == nil {
+		return
+	}
+	delete(l.moves, id)
+	l.enc.Encode(struct {
+		eventHead
+		Ticks   int `json:"ticks"`
+		Steps   int `json:"steps"`
+		Dist    int `json:"dist"` // from where the tally began
+		X       int `json:"x"`
+		Y       int `json:"y"`
+		Fitness int `json:"fitness"`
+	}{eventHead{Tick: tick, Event: "moves", NPC: id, Epoch: m.epoch},
+		m.ticks, m.steps, abs(m.x-m.x0) + abs(m.y-m.y0), m.x, m.y, m.fitness})
+}
+
+// Finish writes the open movement tallies and flushes the log. It returns
+// the first write error, if any.
+func (l *EventLog) Finish() error {
+	l.flushAll(l.s.World.Tick)
+	return l.w.Flush()
+}
+
+// flushAll writes every open movement tally, in NPC ID order.
+func (l *EventLog) flushAll(tick int) {
+	ids := make([]int, 0, len(l.moves))
+	for id := range l.moves {
+		ids = append(ids, int(id))
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		l.flushMoves(uint16(id), tick)
+	}
+}
@@ -0,0 +1,126 @@
+package sandbox
+
+// Event is something notable that happened during a tick. Subscribers type
+// switch on the concrete event (TradeCompleted, TeachSucceeded, NPCBorn,
+// NPCDied, ItemCrafted, Blight, StageReached, AssertionFailed,
+// ContractPosted, ContractFulfilled, GenomeSwapped).
+type Event interface {
+	EventTick() int
+}
+
+// TradeCompleted is emitted when two NPCs swap items.
+type TradeCompleted struct {
+	Tick int
+	A, B uint16 // the two traders
+}
+
+// TeachSucceeded is emitted when a teacher's genome fragment takes hold in
+// a student.
+type TeachSucceeded struct {
+	Tick             int
+	Teacher, Student uint16
+}
+
+// NPCBorn is emitted for every offspring: a child of in-world mating, or a
+// GA victim reborn with a bred (or, for an elite, cloned) genome. A GA
+// offspring keeps the victim's ID, so the individual that had it ends here.
+type NPCBorn struct {
+	Tick    int
+	ID      uint16
+	Parents [2]uint16
+	Epoch   int  // Scheduler.Epoch at birth
+	Clone   bool // an aged-out elite reborn with its own genome
+}
+
+// NPCDied is emitted as a dead NPC is removed from the world.
+type NPCDied struct {
+	Tick    int
+	ID      uint16
+	Cause   byte // DeathStarvation..DeathAge
+	Age     int
+	Fitness int
+}
+
+// ItemCrafted is emitted when an NPC turns its held item into a better one,
+// by the craft action or automatically on a forge.
+type ItemCrafted struct {
+	Tick          int
+	NPC           uint16
+	Input, Output byte
+}
+
+// Blight is emitted when a periodic blight wipes out food.
+type Blight struct {
+	Tick      int
+	Destroyed int // food tiles lost
+}
+
+// StageReached is emitted when a Curriculum stage takes effect.
+type StageReached struct {
+	Tick  int
+	Stage int // index into Curriculum.Stages
+}
+
+// AssertionFailed is emitted when one of an Assertions' checks fails.
+type AssertionFailed struct {
+	Tick  int
+	Check int // index into Assertions.Checks
+}
+
+// ContractPosted is emitted when the world posts a contract.
+type ContractPosted struct {
+	Tick     int
+	Contract Contract
+}
+
+// ContractFulfilled is emitted when an NPC completes a contract and is
+// paid.
+type ContractFulfilled struct {
+	Tick     int
+	NPC      uint16
+	Contract Contract
+}
+
+// GenomeSwapped is emitted when SwapGenome replaces a living NPC's genome.
+type GenomeSwapped struct {
+	Tick   int
+	NPC    uint16
+	Frozen bool // kept out of evolution from now on
+}
+
+func (e TradeCompleted) EventTick() int    { return e.Tick }
+func (e TeachSucceeded) EventTick() int    { return e.Tick }
+func (e NPCBorn) EventTick() int           { return e.Tick }
+func (e NPCDied) EventTick() int           { return e.Tick }
+func (e ItemCrafted) EventTick() int       { return e.Tick }
+func (e Blight) EventTick() int            { return e.Tick }
+func (e StageReached) EventTick() int      { return e.Tick }
+func (e AssertionFailed) EventTick() int   { return e.Tick }
+func (e ContractPosted) EventTick() int    { return e.Tick }
+func (e ContractFulfilled) EventTick() int { return e.Tick }
+func (e GenomeSwapped) EventTick() int     { return e.Tick }
+
+// Subscriber receives scheduler events. OnEvent is called synchronously
+// from Tick, in the order the events happen, and never from the worker
+// goroutines.
+type Subscriber interface {
+	OnEvent(ev Event)
+}
+
+// SubscriberFunc adapts a function to a Subscriber.
+type SubscriberFunc func(ev Event)
+
+// OnEvent calls f(ev).
+func (f SubscriberFunc) OnEvent(ev Event) { f(ev) }
+
+// Subscribe registers sub to receive every event from now on.
+func (s *Scheduler) Subscribe(sub Subscriber) {
+	s.subscribers = append(s.subscribers, sub)
+}
+
+// emit delivers ev to every subscriber.
+func (s *Scheduler) emit(ev Event) {
+	for _, sub := range s.subscribers {
+		sub.OnEvent(ev)
+	}
+}
@@ -0,0 +1,157 @@
+package sandbox
+
+// fieldCell is one cell of a nearest-source field: the distance to the
+// nearest source and where that source is (grid index).
+type fieldCell struct {
+	dist int16 // -1 = nothing within maxSearchRadius
+	src  int32
+}
+
+// npcCell is one of the two nearest NPCs recorded for a cell.
+type npcCell struct {
+	dist int16
+	id   uint16
+	src  int32
+}
+
+// sensorFields caches the Nearest* sensors for a whole tick: one
+// multi-source BFS per kind instead of a ring scan per NPC. Values reflect
+// the world at the start of the tick (see Scheduler.SensorFields).
+type sensorFields struct {
+	tick   int // w.Tick the fields were built for (-1 = never)
+	size   int
+	food   []fieldCell
+	item   []fieldCell
+	poison []fieldCell
+	npcs   [][2]npcCell // two nearest distinct NPCs, so one can be excluded
+	npcN   []uint8
+	queue  []int32
+}
+
+// build recomputes every field for the current tick.
+func (f *sensorFields) build(w *World) {
+	f.tick, f.size = w.Tick, w.Size
+	f.food = f.tileField(w, f.food, func(typ byte) bool { return typ == TileFood })
+	f.item = f.tileField(w, f.item, isItem)
+	f.poison = f.tileField(w, f.poison, func(typ byte) bool { return typ == TilePoison })
+	f.npcField(w)
+}
+
+// neighbours returns the in-bounds grid indices next to i.
+func (f *sensorFields) neighbours(i int32, out *[4]int32) []int32 {
+	n := 0
+	x, y := int(i)%f.size, int(i)/f.size
+	if y > 0 {
+		out[n] = i - int32(f.size)
+		n++
+	}
+	if x < f.size-1 {
+		out[n] = i + 1
+		n++
+	}
+	if y < f.size-1 {
+		out[n] = i + int32(f.size)
+		n++
+	}
+	if x > 0 {
+		out[n] = i - 1
+		n++
+	}
+	return out[:n]
+}
+
+// tileField runs a BFS from every tile matching want, up to maxSearchRadius.
+func (f *sensorFields) tileField(w *World, field []fieldCell, want func(byte) bool) []fieldCell {
+	if len(field) != len(w.Grid) {
+		field = make([]fieldCell, len(w.Grid))
+	}
+	q := f.queue[:0]
+	for i, t := range w.Grid {
+		if want(t.Type()) {
+			field[i] = fieldCell{0, int32(i)}
+			q = append(q, int32(i))
+		} else {
+			field[i] = fieldCell{-1, -1}
+		}
+	}
+	var nb [4]int32
+	for head := 0; head < len(q); head++ {
+		c := field[q[head]]
+		if c.dist >= maxSearchRadius {
+			continue
+		}
+		for _, n := range f.neighbours(q[head], &nb) {
+			if field[n].dist < 0 {
+				field[n] = fieldCell{c.dist + 1, c.src}
+				q = append(q, n)
+			}
+		}
+	}
+	f.queue = q
+	return field
+}
+
+// npcField runs a BFS from every living NPC, keeping the two nearest
+// distinct NPCs per cell.
+func (f *sensorFields) npcField(w *World) {
+	if len(f.npcs) != len(w.Grid) {
+		f.npcs = make([][2]npcCell, len(w.Grid))
+		f.npcN = make([]uint8, len(w.Grid))
+	}
+	clear(f.npcN)
+	// Queue entries pack the record slot into the low bit
+	q := f.queue[:0]
+	for _, npc := range w.NPCs {
+		if !npc.Alive() || !w.InBounds(npc.X, npc.Y) {
+			continue
+		}
+		i := int32(w.idx(npc.X, npc.Y))
+		if f.npcN[i] >= 2 {
+			continue
+		}
+		f.npcs[i][f.npcN[i]] = npcCell{0, npc.ID, i}
+		q = append(q, i<<1|int32(f.npcN[i]))
+		f.npcN[i]++
+	}
+	var nb [4]int32
+	for head := 0; head < len(q); head++ {
+		cell, slot := q[head]>>1, q[head]&1
+		r := f.npcs[cell][slot]
+		if r.dist >= maxSearchRadius {
+			continue
+		}
+		for _, n := range f.neighbours(cell, &nb) {
+			k := f.npcN[n]
+			if k >= 2 || (k == 1 && f.npcs[n][0].id == r.id) {
+				continue
+			}
+			f.npcs[n][k] = npcCell{r.dist + 1, r.id, r.src}
+			q = append(q, n<<1|int32(k))
+			f.npcN[n]++
+		}
+	}
+	f.queue = q
+}
+
+// nearest reads a tile field at (x, y) as (distance, direction).
+func (f *sensorFields) nearest(field []fieldCell, x, y int) (int, int) {
+	c := field[y*f.size+x]
+	if c.dist < 0 {
+		return maxSearchRadius, DirNone
+	}
+	return int(c.dist), directionToward(x, y, int(c.src)%f.size, int(c.src)/f.size)
+}
+
+// nearestNPC mirrors World.NearestNPCFull: (distance, ID, direction) of the
+// nearest NPC other than exclude.
+func (f *sensorFields) nearestNPC(x, y int, exclude uint16) (int, uint16, int) {
+	i := y*f.size + x
+	for k := 0; k < int(f.npcN[i]); k++ {
+		r := f.npcs[i][k]
+		if r.id == exclude || r.dist == 0 {
+			continue
+		}
+		return int(r.dist), r.id, directionToward(x, y, int(r.src)%f.size, int(r.src)/f.size)
+	}
+	return maxSearchRadius, 0, DirNone
+}
@@ -0,0 +1,136 @@
+package sandbox
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FitnessWeights scores an NPC as a weighted sum of its life stats, so
+// experiments can select for different behaviours. Gifts are weighted by
+// Scheduler.GiftFitness.
+//
+// Explore, Coop and Econ are behavioural terms, off by default: tiles are
+// only tracked for NPC.Explored while Explore is set.
+type FitnessWeights struct {
+	Age       int // per tick lived
+	Food      int // per food eaten
+	Health    int // per point of current health
+	Gold      int // per gold held
+	Craft     int // per item crafted
+	Teach     int // per successful teach
+	Trade     int // per trade completed
+	Kill      int // per NPC killed
+	StressDiv int // subtract stress/StressDiv (0 = stress ignored)
+	Explore   int // per distinct tile visited
+	Coop      int // per share or teach given
+	Econ      int // per trade completed or gold earned trading
+}
+
+// DefaultFitness is the classic sandbox formula.
+var DefaultFitness = FitnessWeights{
+	Age: 1, Food: 10, Health: 1, Gold: 20, Craft: 30, Teach: 15, StressDiv: 5,
+}
+
+// Score returns npc's fitness under these weights.
+func (fw FitnessWeights) Score(npc *NPC) int {
+	f := npc.Age*fw.Age + npc.FoodEaten*fw.Food + npc.Health*fw.Health +
+		npc.Gold*fw.Gold + npc.CraftCount*fw.Craft + npc.TeachCount*fw.Teach +
+		npc.Trades*fw.Trade + npc.Kills*fw.Kill
+	if fw.StressDiv != 0 {
+		f -= npc.Stress / fw.StressDiv
+	}
+	return f + npc.Explored*fw.Explore + (npc.Shares+npc.TeachCount)*fw.Coop +
+		(npc.Trades+npc.GoldEarned)*fw.Econ
+}
+
+// FitnessTerm is one weighted component of an NPC's fitness.
+type FitnessTerm struct {
+	Name  string // ParseFitness key
+	Value int
+}
+
+// Breakdown returns the terms of Score for npc, in ParseFitness key order
+// (stress is negative); they add up to Score.
+func (fw FitnessWeights) Breakdown(npc *NPC) []FitnessTerm {
+	stress := 0
+	if fw.StressDiv != 0 {
+		stress = -(npc.Stress / fw.StressDiv)
+	}
+	return []FitnessTerm{
+		{"age", npc.Age * fw.Age},
+		{"food", npc.FoodEaten * fw.Food},
+		{"health", npc.Health * fw.Health},
+		{"gold", npc.Gold * fw.Gold},
+		{"craft", npc.CraftCount * fw.Craft},
+		{"teach", npc.TeachCount * fw.Teach},
+		{"trade", npc.Trades * fw.Trade},
+		{"kill", npc.Kills * fw.Kill},
+		{"stress", stress},
+		{"explore", npc.Explored * fw.Explore},
+		{"coop", (npc.Shares + npc.TeachCount) * fw.Coop},
+		{"econ", (npc.Trades + npc.GoldEarned) * fw.Econ},
+	}
+}
+
+// visit marks npc's tile as explored.
+func (npc *NPC) visit(w *World) {
+	if npc.visited == nil {
+		npc.visited = make([]uint64, (w.Size*w.Size+63)/64)
+	}
+	i := w.idx(npc.X, npc.Y)
+	if npc.visited[i/64]&(1<<(i%64)) == 0 {
+		npc.visited[i/64] |= 1 << (i % 64)
+		npc.Explored++
+	}
+}
+
+// fitnessKeys maps ParseFitness names to weight fields.
+var fitnessKeys = map[string]func(*FitnessWeights) *int{
+	"age":     func(fw *FitnessWeights) *int { return &fw.Age },
+	"food":    func(fw *FitnessWeights) *int { return &fw.Food },
+	"health":  func(fw *FitnessWeights) *int { return &fw.Health },
+	"gold":    func(fw *FitnessWeights) *int { return &fw.Gold },
+	"craft":   func(fw *FitnessWeights) *int { return &fw.Craft },
+	"teach":   func(fw *FitnessWeights) *int { return &fw.Teach },
+	"trade":   func(fw *FitnessWeights) *int { return &fw.Trade },
+	"kill":    func(fw *FitnessWeights) *int { return &fw.Kill },
+	"stress":  func(fw *FitnessWeights) *int { return &fw.StressDiv },
+	"explore": func(fw *FitnessWeights) *int { return &fw.Explore },
+	"coop":    func(fw *FitnessWeights) *int { return &fw.Coop },
+	"econ":    func(fw *FitnessWeights) *int { return &fw.Econ },
+}
+
+// ParseFitness reads "key=weight,..." overrides on top of DefaultFitness,
+// e.g. "gold=0,kill=40". Keys: age, food, health, gold, craft, teach, trade,
+// kill, stress (the divisor; 0 ignores stress), and the behavioural explore,
+// coop and econ.
+func ParseFitness(spec string) (FitnessWeights, error) {
+	fw := DefaultFitness
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return fw, fmt.Errorf("fitness: %q is not key=weight", part)
+		}
+		field, ok := fitnessKeys[strings.TrimSpace(key)]
+		if !ok {
+			keys := make([]string, 0, len(fitnessKeys))
+			for k := range fitnessKeys {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			return fw, fmt.Errorf("fitness: unknown key %q (want %s)", key, strings.Join(keys, ", "))
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(val))
+		if err != nil {
+			return fw, fmt.Errorf("fitness: %s: %w", key, err)
+		}
+		*field(&fw) = n
+	}
+	return fw, nil
+}
@@ -20,14 +20,46 @@ type CrossoverMode int
 const (
 	CrossoverGrowth  CrossoverMode = iota // growth/exchange (default)
 	CrossoverClassic                      // classic single-point only
+	CrossoverTwoPoint                     // a splice of b replaces a slice of a
+	CrossoverUniform                      // each instruction from either parent
 )
 
+// SelectionMode selects how parents are drawn from the breeding pool (the
+// fitter half of the population).
+type SelectionMode int
+
+const (
+	SelectTournament SelectionMode = iota // best of TournamentSize random picks (default)
+	SelectRoulette                        // chance proportional to fitness
+	SelectRank                            // chance proportional to rank (linear)
+	SelectTruncation                      // uniform over the pool
+)
+
+// defaultTournamentSize is used when GA.TournamentSize is unset.
+const defaultTournamentSize = 3
+
 // GA is the genetic algorithm engine for evolving NPC genomes.
 type GA struct {
 	Rng              *rand.Rand
 	MutationRate     float64       // probability of mutation per offspring (0-1)
+	DiversityTarget  float64       // boost mutation while Diversity is below this (0 = fixed rate)
+	Diversity        float64       // genome diversity at the last Evolve (see GenomeDiversity)
+	measured         bool          // Diversity has been measured
 	ClassicRate      float64       // fraction using classic crossover (default 0.20)
 	Mode             CrossoverMode // growth or classic-only
+	Selection        SelectionMode // parent selection strategy
+	TournamentSize   int           // candidates per tournament (0 = 3)
+	Elitism          int           // top-N genomes kept unchanged; aged-out elites are cloned
+	SpeciesThreshold float64       // genome distance that splits species (0 = no speciation)
+	Species          int           // species found by the last Evolve (with speciation)
+	ClonePenalty     float64       // fitness fraction copies of a genome lose when ranked (0 = off)
+	CloneDistance    float64       // genomes closer than this count as copies (0 = exact only)
+	Clones           int           // NPCs penalized as copies by the last Evolve
+	Births           []Birth       // offspring of the last Evolve, in birth order
+	LengthPenalty    float64       // fitness deducted per genome byte when ranked (0 = off)
+	ShorterFirst     bool          // among equal fitness, the shorter genome ranks higher
+	Structural       bool          // jump-aware operators: recombine basic blocks, re-fix offsets (see blocks.go)
+	SelfModify       bool          // mutation may insert poke (see Scheduler.SelfModify)
 	MaxGenomeSize    int           // 0 = use DefaultMaxGenome (128)
 	WFCEnabled       bool
 	Archetypes       [][]byte                // handcrafted seed genomes
@@ -35,6 +67,22 @@ type GA struct {
 	MinedConstraints8 [8]byte                // latest mined constraints (8-type)
 }
 
+// ranksAbove orders the population for selection: by fitness, then (with
+// ShorterFirst) by genome length.
+func (ga *GA) ranksAbove(a, b *NPC) bool {
+	if a.Fitness != b.Fitness || !ga.ShorterFirst {
+		return a.Fitness > b.Fitness
+	}
+	return len(a.Genome) < len(b.Genome)
+}
+
+// Birth records one offspring of an Evolve.
+type Birth struct {
+	ID      uint16 // the victim reborn as the offspring
+	Parents [2]uint16
+	Clone   bool // an aged-out elite reborn with its own genome
+}
+
 // maxGenome returns the effective max genome size.
 func (ga *GA) maxGenome() int {
 	if ga.MaxGenomeSize > 0 {
@@ -53,17 +101,62 @@ func NewGA(rng *rand.Rand) *GA {
 }
 
 // Evolve replaces the bottom 25% and any aged-out NPCs with offspring from the top 50%.
+//
+// With SpeciesThreshold set, NPCs are first grouped into species, ranked by
+// fitness shared within their species, and every species gets at least one
+// offspring slot, bred from its own members.
+//
+// With ClonePenalty set, every copy of a genome but the fittest ranks with
+// reduced fitness, so monocultures breed less and are culled first.
+//
+// LengthPenalty and ShorterFirst add parsimony pressure against genome
+// bloat from the insert and duplicate mutations.
 func (ga *GA) Evolve(npcs []*NPC) []*NPC {
 	if len(npcs) < 4 {
 		return npcs
 	}
+	ga.Diversity, ga.measured = GenomeDiversity(npcs), true
+
+	// Clone penalties and sharing only rank; raw fitness is restored after breeding
+	var raw map[*NPC]int
+	if ga.ClonePenalty > 0 || ga.SpeciesThreshold > 0 || ga.LengthPenalty > 0 {
+		raw = make(map[*NPC]int, len(npcs))
+		for _, npc := range npcs {
+			raw[npc] = npc.Fitness
+		}
+	}
+	ga.Clones = 0
+	ga.Births = ga.Births[:0]
+	if ga.ClonePenalty > 0 {
+		ga.Clones = ga.penalizeClones(npcs)
+	}
+	if ga.LengthPenalty > 0 {
+		for _, npc := range npcs {
+			npc.Fitness -= int(float64(len(npc.Genome)) * ga.LengthPenalty)
+		}
+	}
 
 	// Sort by fitness descending
 	sorted := make([]*NPC, len(npcs))
 	copy(sorted, npcs)
 	sort.Slice(sorted, func(i, j int) bool {
-		return sorted[i].Fitness > sorted[j].Fitness
+		return ga.ranksAbove(sorted[i], sorted[j])
 	})
+	elites := make(map[*NPC]bool)
+	for _, npc := range sorted[:min(ga.Elitism, len(sorted))] {
+		elites[npc] = true
+	}
+
+	// Speciation: rank by shared fitness (restored once offspring are bred)
+	var species [][]*NPC
+	if ga.SpeciesThreshold > 0 {
+		species = ga.speciate(sorted)
+		ga.Species = len(species)
+		shareFitness(species)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return ga.ranksAbove(sorted[i], sorted[j])
+		})
+	}
 
 	// Top 50% are breeding pool
 	poolSize := len(sorted) / 2
@@ -102,49 +195,205 @@ func (ga *GA) Evolve(npcs []*NPC) []*NPC {
 			victims[npc] = true
 		}
 	}
+	// Frozen NPCs are left alone
+	for _, npc := range sorted {
+		if npc.Frozen {
+			delete(victims, npc)
+		}
+	}
 
-	// Generate offspring for all victims
-	for victim := range victims {
-		parentA := ga.tournamentSelect(pool)
-		parentB := ga.tournamentSelect(pool)
-
-		childGenome := ga.crossover(parentA.Genome, parentB.Genome)
-
-		if ga.Rng.Float64() < ga.MutationRate {
-			childGenome = ga.mutate(childGenome)
+	// Species breed from their own surviving members, up to their quota
+	var quotas []int
+	var speciesPools [][]*NPC
+	if species != nil {
+		bred := 0
+		for npc := range victims {
+			if !elites[npc] {
+				bred++
+			}
 		}
+		quotas = speciesQuotas(species, bred)
+		for _, sp := range species {
+			var survivors []*NPC
+			for _, npc := range sp {
+				if !victims[npc] {
+					survivors = append(survivors, npc)
+				}
+			}
+			if len(survivors) == 0 {
+				survivors = sp
+			}
+			speciesPools = append(speciesPools, survivors)
+		}
+	}
 
-		victim.Genome = childGenome
-		victim.Health = 100
-		victim.Energy = 100
-		victim.Age = 0
-		victim.Fitness = 0
-		victim.Hunger = 0
-		victim.FoodEaten = 0
+	// Generate offspring for all victims, in rank order so a seed replays exactly
+	nextSpecies := 0
+	for _, victim := range sorted {
+		if !victims[victim] {
+			continue
+		}
+		var parentA, parentB *NPC
+		clone := elites[victim]
+		if clone {
+			// Elites are never culled; one that aged out is reborn as a clone
+			if victim.Age < MaxAge {
+				continue
+			}
+			parentA, parentB = victim, victim
+			victim.Genome = append([]byte(nil), victim.Genome...)
+		} else {
+			parents := pool
+			if species != nil {
+				for quotas[nextSpecies] == 0 {
+					nextSpecies++
+				}
+				quotas[nextSpecies]--
+				parents = speciesPools[nextSpecies]
+			}
+			parentA = ga.selectParent(parents)
+			parentB = ga.selectParent(parents)
+			victim.Genome = ga.breed(parentA.Genome, parentB.Genome)
+		}
+		victim.newborn()
 		victim.Gold = (parentA.Gold + parentB.Gold) / 4 // economic memory persists (diminished)
-		victim.Item = ItemNone
-		victim.Mods = [4]Modifier{}
-		victim.Stress = 0
-		victim.CraftCount = 0
-		victim.Taught = 0
-		victim.TeachCount = 0
+		victim.Clan = parentA.Clan                      // offspring are raised in a parent's clan
+		if !clone { // a clone keeps its lineage
+			victim.Parents = [2]uint16{parentA.ID, parentB.ID}
+		}
+		victim.Recipes = ga.inheritRecipes(parentA.Recipes, parentB.Recipes)
+		ga.Births = append(ga.Births, Birth{ID: victim.ID, Parents: [2]uint16{parentA.ID, parentB.ID}, Clone: clone})
+		delete(raw, victim)
+	}
+	for npc, f := range raw {
+		npc.Fitness = f
 	}
 
 	return npcs
 }
 
-// tournamentSelect picks the best of 3 random candidates.
+// newborn resets npc to a fresh individual in its current body: full
+// health, no age, items, memory or relations. Genome, lineage, clan, gold
+// and recipes are left for the caller.
+func (npc *NPC) newborn() {
+	npc.Health = 100
+	npc.Energy = 100
+	npc.Age = 0
+	npc.Fitness = 0
+	npc.Hunger = 0
+	npc.FoodEaten = 0
+	npc.Item = ItemNone
+	npc.Mods = [4]Modifier{}
+	npc.Stress = 0
+	npc.CraftCount = 0
+	npc.Taught = 0
+	npc.TeachCount = 0
+	npc.GiftCount = 0
+	npc.Shares = 0
+	npc.GoldEarned = 0
+	npc.Explored = 0
+	npc.visited = nil
+	npc.Following = 0
+	npc.Infection = 0
+	npc.Immune = false
+	if npc.vm != nil {
+		npc.vm.Wipe() // a new individual remembers nothing
+	}
+	npc.Asleep = false
+	npc.Emotion = EmotionNeutral
+	npc.Trades = 0
+	npc.Kills = 0
+	npc.ItemsHeld = 0
+	npc.lastHarm = DeathUnknown
+	npc.nextUse = [ActionCount]int{}
+	npc.Relations = [RelationSlots]Relation{}
+}
+
+// breed produces a child genome by crossover of a and b plus optional mutation.
+func (ga *GA) breed(a, b []byte) []byte {
+	child := ga.crossover(a, b)
+	for rate := ga.EffectiveMutation(); rate > 0; rate-- {
+		if ga.Rng.Float64() < rate {
+			child = ga.mutate(child)
+		}
+	}
+	return child
+}
+
+// inheritRecipes passes on each recipe known by either parent with 50% probability.
+func (ga *GA) inheritRecipes(a, b byte) byte {
+	known := a | b
+	var r byte
+	for bit := byte(1); bit != 0; bit <<= 1 {
+		if known&bit != 0 && ga.Rng.Intn(2) == 0 {
+			r |= bit
+		}
+	}
+	return r
+}
+
+// selectParent draws one parent from pool, which is sorted by fitness,
+// best first.
+func (ga *GA) selectParent(pool []*NPC) *NPC {
+	switch ga.Selection {
+	case SelectRoulette:
+		return ga.rouletteSelect(pool)
+	case SelectRank:
+		return ga.rankSelect(pool)
+	case SelectTruncation:
+		return pool[ga.Rng.Intn(len(pool))]
+	default:
+		return ga.tournamentSelect(pool)
+	}
+}
+
+// tournamentSelect picks the best of TournamentSize random candidates.
 func (ga *GA) tournamentSelect(pool []*NPC) *NPC {
+	size := ga.TournamentSize
+	if size < 1 {
+		size = defaultTournamentSize
+	}
 	best := pool[ga.Rng.Intn(len(pool))]
-	for i := 0; i < 2; i++ {
+	for i := 1; i < size; i++ {
 		c := pool[ga.Rng.Intn(len(pool))]
-		if c.Fitness > best.Fitness {
+		if ga.ranksAbove(c, best) {
 			best = c
 		}
 	}
 	return best
 }
 
+// rouletteSelect picks with probability proportional to fitness, shifted
+// so the least fit candidate still has weight 1.
+func (ga *GA) rouletteSelect(pool []*NPC) *NPC {
+	low := pool[len(pool)-1].Fitness
+	total := 0
+	for _, npc := range pool {
+		total += npc.Fitness - low + 1
+	}
+	r := ga.Rng.Intn(total)
+	for _, npc := range pool {
+		r -= npc.Fitness - low + 1
+		if r < 0 {
+			return npc
+		}
+	}
+	return pool[len(pool)-1]
+}
+
+// rankSelect picks the i-th best of n with probability proportional to n-i.
+func (ga *GA) rankSelect(pool []*NPC) *NPC {
+	n := len(pool)
+	r := ga.Rng.Intn(n * (n + 1) / 2)
+	for i := range pool {
+		r -= n - i
+		if r < 0 {
+			return pool[i]
+		}
+	}
+	return pool[n-1]
+}
+
 // novelSegments returns instruction-aligned segments from b
 // that do not appear as contiguous byte subsequences in a.
 func novelSegments(a, b []byte) [][]byte {
@@ -181,6 +430,46 @@ func (ga *GA) classicCrossover(a, b []byte, pointsA, pointsB []int) []byte {
 	return ga.enforceBounds(child)
 }
 
+// twoPointCrossover replaces an instruction-aligned slice of a with an
+// instruction-aligned slice of b, so any region of either parent can
+// recombine, not just a's head with b's tail.
+func (ga *GA) twoPointCrossover(a, b []byte, pointsA, pointsB []int) []byte {
+	a1, a2 := ga.pointPair(pointsA)
+	b1, b2 := ga.pointPair(pointsB)
+	child := make([]byte, 0, len(a)-(a2-a1)+(b2-b1))
+	child = append(child, a[:a1]...)
+	child = append(child, b[b1:b2]...)
+	child = append(child, a[a2:]...)
+	return ga.enforceBounds(child)
+}
+
+// pointPair returns two ordered random points.
+func (ga *GA) pointPair(points []int) (int, int) {
+	p, q := points[ga.Rng.Intn(len(points))], points[ga.Rng.Intn(len(points))]
+	if p > q {
+		p, q = q, p
+	}
+	return p, q
+}
+
+// uniformCrossover takes the i-th instruction from either parent at random.
+// Past the end of the shorter parent, the longer one's instructions are
+// each kept with probability 1/2.
+func (ga *GA) uniformCrossover(a, b []byte, pointsA, pointsB []int) []byte {
+	nA, nB := len(pointsA)-1, len(pointsB)-1
+	child := make([]byte, 0, max(len(a), len(b)))
+	for i := 0; i < max(nA, nB); i++ {
+		if ga.Rng.Intn(2) == 0 {
+			if i < nA {
+				child = append(child, a[pointsA[i]:pointsA[i+1]]...)
+			}
+		} else if i < nB {
+			child = append(child, b[pointsB[i]:pointsB[i+1]]...)
+		}
+	}
+	return ga.enforceBounds(child)
+}
+
 // crossover performs growth/exchange crossover with classic fallback.
 func (ga *GA) crossover(a, b []byte) []byte {
 	pointsA := OpcodeAlignedPoints(a)
@@ -191,10 +480,17 @@ func (ga *GA) crossover(a, b []byte) []byte {
 		copy(r, a)
 		return r
 	}
+	if ga.Structural {
+		return ga.structuralCrossover(a, b)
+	}
 
-	// Classic-only mode: always use classic crossover
-	if ga.Mode == CrossoverClassic {
+	switch ga.Mode {
+	case CrossoverClassic:
 		return ga.classicCrossover(a, b, pointsA, pointsB)
+	case CrossoverTwoPoint:
+		return ga.twoPointCrossover(a, b, pointsA, pointsB)
+	case CrossoverUniform:
+		return ga.uniformCrossover(a, b, pointsA, pointsB)
 	}
 
 	// Classic crossover for diversity (tunable rate)
@@ -281,6 +577,9 @@ func (ga *GA) mutate(genome []byte) []byte {
 	if len(genome) == 0 {
 		return genome
 	}
+	if ga.Structural {
+		return ga.structuralMutate(genome)
+	}
 
 	mx := ga.maxGenome()
 	op := ga.Rng.Intn(6)
@@ -413,7 +712,7 @@ func (ga *GA) randomOpcode() byte {
 	// 15% ring ops (r0@, r1!) - sensor reads and action writes
 	// 10% inline symbols (0x40-0x5F) - sensor references
 	// 10% inline quotations (0x60-0x67) - first 8 quots
-	// 5% special (yield, halt)
+	// 5% special (yield, halt; poke too with SelfModify)
 	r := ga.Rng.Float64()
 	switch {
 	case r < 0.30:
@@ -431,6 +730,9 @@ func (ga *GA) randomOpcode() byte {
 	case r < 0.95:
 		return byte(0x60 + ga.Rng.Intn(8))
 	default:
+		if ga.SelfModify && ga.Rng.Intn(3) == 0 {
+			return micro.OpPoke
+		}
 		if ga.Rng.Intn(2) == 0 {
 			return micro.OpYield
 		}
@@ -0,0 +1,110 @@
+package sandbox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Gradient varies a spawn probability across the map, so some regions are
+// rich and others barren and NPCs have somewhere to migrate to and defend.
+// Stops are weights spread evenly from one edge to the other along Axis and
+// interpolated in between: "x:1,0.2" is rich in the west and poor in the
+// east, "x:0,1,0" a belt down the middle.
+type Gradient struct {
+	Axis  byte      // 'x' (west to east) or 'y' (north to south)
+	Stops []float64 // weights at evenly spaced points along Axis, edge to edge
+}
+
+// Gradients are the spawn gradients of a world; nil means uniform. Food and
+// item gradients shape where spawns land without changing how many there
+// are: a tile is accepted with its weight over the gradient's largest. The
+// poison gradient scales the chance that an item spawn is poison instead.
+type Gradients struct {
+	Food   *Gradient
+	Items  *Gradient
+	Poison *Gradient
+}
+
+// At returns the gradient's weight at (x,y) on a size×size map.
+func (g *Gradient) At(size, x, y int) float64 {
+	pos := x
+	if g.Axis == 'y' {
+		pos = y
+	}
+	if len(g.Stops) == 1 || size < 2 {
+		return g.Stops[0]
+	}
+	// Position along the axis in stop intervals
+	f := float64(pos) * float64(len(g.Stops)-1) / float64(size-1)
+	i := int(f)
+	if i >= len(g.Stops)-1 {
+		return g.Stops[len(g.Stops)-1]
+	}
+	frac := f - float64(i)
+	return g.Stops[i]*(1-frac) + g.Stops[i+1]*frac
+}
+
+// accept rolls whether a spawn may land on (x,y): weight over the
+// gradient's largest weight. A nil gradient accepts everywhere.
+func (g *Gradient) accept(w *World, x, y int) bool {
+	if g == nil {
+		return true
+	}
+	peak := 0.0
+	for _, s := range g.Stops {
+		peak = max(peak, s)
+	}
+	return peak > 0 && w.Rng.Float64()*peak < g.At(w.Size, x, y)
+}
+
+// poisonScale returns the poison gradient's weight at (x,y), 1 without one.
+func (w *World) poisonScale(x, y int) float64 {
+	if w.Gradients.Poison == nil {
+		return 1
+	}
+	return w.Gradients.Poison.At(w.Size, x, y)
+}
+
+// ParseGradients reads gradients separated by ";" or newlines, each
+// "resource=axis:w1,w2,...", e.g. "food=x:1,0.2;items=x:0.2,1;poison=x:0,4,0"
+// for food in the west, items in the east and a poison belt between them.
+// Resources: food, items, poison. Axes: x (west to east), y (north to
+// south). Weights are non-negative. Lines starting with # are comments.
+func ParseGradients(spec string) (Gradients, error) {
+	var gs Gradients
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, val, ok := strings.Cut(line, "=")
+		if !ok {
+			return gs, fmt.Errorf("gradients: %q is not resource=axis:weights", line)
+		}
+		key = strings.TrimSpace(key)
+		axis, stops, ok := strings.Cut(strings.TrimSpace(val), ":")
+		if !ok || (axis != "x" && axis != "y") {
+			return gs, fmt.Errorf("gradients: %s: want x:weights or y:weights, got %q", key, val)
+		}
+		g := &Gradient{Axis: axis[0]}
+		for _, s := range strings.Split(stops, ",") {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil || v < 0 {
+				return gs, fmt.Errorf("gradients: %s: bad weight %q", key, s)
+			}
+			g.Stops = append(g.Stops, v)
+		}
+		switch key {
+		case "food":
+			gs.Food = g
+		case "items":
+			gs.Items = g
+		case "poison":
+			gs.Poison = g
+		default:
+			return gs, fmt.Errorf("gradients: unknown resource %q (want food, items, poison)", key)
+		}
+	}
+	return gs, nil
+}
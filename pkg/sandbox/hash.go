@@ -0,0 +1,118 @@
+package sandbox
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// Hash fingerprints the simulation state: tick, tiles, occupancy,
+// cooldowns, chests, poison timers and every NPC's state, genome and the
+// brain VM state that carries over between ticks (memory, locals and
+// quotations) included. Two runs of the same seed should produce the
+// same hash after every tick; the first tick where they differ is where
+// determinism broke.
+func (w *World) Hash() uint64 {
+	return w.hash(true)
+}
+
+// StateHash is Hash without the genomes and the quotations they define, so
+// two runs whose genomes differ but act alike hash alike. Brain memory and
+// locals stay in: they carry over to the next tick, so genomes that leave
+// them different do not act alike for long.
+func (w *World) StateHash() uint64 {
+	return w.hash(false)
+}
+
+func (w *World) hash(genomes bool) uint64 {
+	h := fnv.New64a()
+	var buf []byte
+	put := func(vs ...int) {
+		for _, v := range vs {
+			buf = binary.AppendVarint(buf, int64(v))
+		}
+	}
+	flush := func() {
+		h.Write(buf)
+		buf = buf[:0]
+	}
+
+	put(w.Tick, w.Size, int(w.NextID), w.FoodSpawned)
+	for _, t := range w.Grid {
+		buf = append(buf, byte(t))
+	}
+	flush()
+	for _, id := range w.OccGrid {
+		buf = binary.LittleEndian.AppendUint16(buf, id)
+	}
+	flush()
+	h.Write(w.Cooldowns)
+
+	for _, i := range sortedKeys(w.Chests) {
+		c := w.Chests[i]
+		put(i, int(c.Owner), len(c.Items))
+		buf = append(buf, c.Items...)
+	}
+	for _, i := range sortedKeys(w.PoisonTTL) {
+		put(i, w.PoisonTTL[i])
+	}
+	flush()
+
+	put(len(w.NPCs))
+	for _, n := range w.NPCs {
+		put(int(n.ID), n.X, n.Y, n.Health, n.Energy, n.Age, n.Fitness,
+			n.Hunger, n.FoodEaten, n.Gold, int(n.Item), n.Stress, n.CraftCount,
+			n.Taught, n.TeachCount, n.GiftCount, int(n.Following), int(n.Clan),
+			int(n.Parents[0]), int(n.Parents[1]), n.Infection, int(n.Emotion),
+			n.Trades, n.Kills, int(n.ItemsHeld), int(n.LastDir), int(n.Recipes),
+			int(n.IncomingFire), n.Shares, n.GoldEarned, n.Explored)
+		for _, b := range []bool{n.Immune, n.Asleep, n.Predator, n.Frozen} {
+			if b {
+				put(1)
+			} else {
+				put(0)
+			}
+		}
+		put(n.nextUse[:]...)
+		buf = append(buf, n.RngState[:]...)
+		for _, m := range n.Mods {
+			put(int(m.Kind), int(m.Mag), int(m.Duration), int(m.Source))
+		}
+		for _, r := range n.Relations {
+			put(int(r.ID), int(r.Trust))
+		}
+		if genomes {
+			put(len(n.Genome))
+			buf = append(buf, n.Genome...)
+		}
+		if vm := n.vm; vm != nil {
+			put(len(vm.Memory))
+			buf = append(buf, vm.Memory...)
+			for _, v := range vm.Locals {
+				put(int(v))
+			}
+			if genomes {
+				for i, q := range vm.Quotations {
+					if q != nil {
+						put(i, len(q))
+						buf = append(buf, q...)
+					}
+				}
+			}
+		} else {
+			put(-1)
+		}
+		flush()
+	}
+	return h.Sum64()
+}
+
+// sortedKeys returns m's keys in ascending order.
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,47 @@
+package sandbox
+
+// TickHook runs at a fixed point of every tick. Hooks may change the world
+// freely (kill an NPC, drop items, rewrite a genome): they run on the tick
+// goroutine, never alongside the workers.
+type TickHook func(s *Scheduler)
+
+// EvolveHook runs after a GA generation, with the population it evolved.
+type EvolveHook func(s *Scheduler, npcs []*NPC)
+
+// PreTick registers fn to run at the start of every tick, before anything
+// senses the world.
+func (s *Scheduler) PreTick(fn TickHook) {
+	s.preTick = append(s.preTick, fn)
+}
+
+// PostTick registers fn to run at the end of every tick, once fitness is
+// scored and before World.Tick advances.
+func (s *Scheduler) PostTick(fn TickHook) {
+	s.postTick = append(s.postTick, fn)
+}
+
+// OnEvolve registers fn to run after each Scheduler.Evolve.
+func (s *Scheduler) OnEvolve(fn EvolveHook) {
+	s.onEvolve = append(s.onEvolve, fn)
+}
+
+// Evolve runs one GA generation over npcs, emits NPCBorn for each
+// offspring and then runs the OnEvolve hooks.
+func (s *Scheduler) Evolve(ga *GA, npcs []*NPC) []*NPC {
+	npcs = ga.Evolve(npcs)
+	s.Epoch++
+	for _, b := range ga.Births {
+		s.emit(NPCBorn{Tick: s.World.Tick, ID: b.ID, Parents: b.Parents, Epoch: s.Epoch, Clone: b.Clone})
+	}
+	for _, fn := range s.onEvolve {
+		fn(s, npcs)
+	}
+	return npcs
+}
+
+// runTickHooks calls each hook in registration order.
+func (s *Scheduler) runTickHooks(hooks []TickHook) {
+	for _, fn := range hooks {
+		fn(s)
+	}
+}
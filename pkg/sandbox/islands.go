@@ -0,0 +1,98 @@
+package sandbox
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Topology decides where each island sends its migrants.
+type Topology int
+
+const (
+	TopologyRing   Topology = iota // island i sends to island i+1 (default)
+	TopologyRandom                 // each island sends to a random other island
+)
+
+// Archipelago is the island model: separate worlds evolving on their own,
+// periodically trading their best genomes. Isolation lets each island
+// explore its own niche; migration spreads what works.
+type Archipelago struct {
+	Islands  []*World
+	Topology Topology
+	Migrants int        // genomes each island sends per migration
+	Rng      *rand.Rand // picks destinations (TopologyRandom)
+}
+
+// migrant is a genome in transit, copied before any island is changed.
+type migrant struct {
+	genome  []byte
+	recipes byte
+}
+
+// Migrate copies each island's Migrants fittest genomes to its destination
+// island, where they are born into the bodies of its least fit NPCs. All
+// emigrants are chosen before anyone arrives, so the order of islands does
+// not matter. It returns how many genomes moved.
+func (a *Archipelago) Migrate() int {
+	k := len(a.Islands)
+	if k < 2 || a.Migrants < 1 {
+		return 0
+	}
+
+	outgoing := make([][]migrant, k)
+	for i, w := range a.Islands {
+		for _, npc := range byFitness(w.NPCs)[:min(a.Migrants, len(w.NPCs))] {
+			outgoing[i] = append(outgoing[i], migrant{
+				genome:  append([]byte(nil), npc.Genome...),
+				recipes: npc.Recipes,
+			})
+		}
+	}
+
+	// Arrivals replace the least fit NPCs first, each at most once
+	hosts := make([][]*NPC, k)
+	moved := 0
+	for i, group := range outgoing {
+		dest := (i + 1) % k
+		if a.Topology == TopologyRandom {
+			dest = (i + 1 + a.Rng.Intn(k-1)) % k
+		}
+		if hosts[dest] == nil {
+			sorted := byFitness(a.Islands[dest].NPCs)
+			for l, r := 0, len(sorted)-1; l < r; l, r = l+1, r-1 {
+				sorted[l], sorted[r] = sorted[r], sorted[l]
+			}
+			for _, npc := range sorted {
+				if !npc.Frozen {
+					hosts[dest] = append(hosts[dest], npc)
+				}
+			}
+		}
+		for _, m := range group {
+			if len(hosts[dest]) == 0 {
+				break
+			}
+			host := hosts[dest][0]
+			hosts[dest] = hosts[dest][1:]
+			host.Genome = m.genome
+			host.newborn()
+			host.Gold = 0
+			host.Parents = [2]uint16{} // no lineage on this island
+			host.Recipes = m.recipes
+			moved++
+		}
+	}
+	return moved
+}
+
+// byFitness returns a copy of npcs sorted best first, ties by ID.
+func byFitness(npcs []*NPC) []*NPC {
+	sorted := append([]*NPC(nil), npcs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Fitness != sorted[j].Fitness {
+			return sorted[i].Fitness > sorted[j].Fitness
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
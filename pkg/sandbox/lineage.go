@@ -0,0 +1,155 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Lineage is a Subscriber that builds the family tree of a run from NPCBorn
+// and NPCDied events. The GA reuses a victim's NPC ID for its offspring, so
+// each individual gets its own node; NPCs never seen being born (the
+// initial population, refills) become founders the first time they appear.
+type Lineage struct {
+	Nodes   []LineageNode
+	current map[uint16]int // NPC ID → node of the individual living under it
+}
+
+// LineageNode is one individual in a Lineage.
+type LineageNode struct {
+	ID        int    `json:"id"`              // index in Lineage.Nodes
+	NPC       uint16 `json:"npc"`             // NPC ID it lived under
+	Parents   [2]int `json:"parents"`         // parent nodes, -1 for founders
+	BirthTick int    `json:"birth"`           // -1 if born before tracking began
+	Epoch     int    `json:"epoch"`           // Scheduler.Epoch at birth
+	Clone     bool   `json:"clone,omitempty"` // an elite reborn with its own genome
+	End       int    `json:"end"`             // tick it died or was replaced, -1 while alive
+	Cause     string `json:"cause,omitempty"` // death cause, or "replaced" by a GA offspring
+	Fitness   int    `json:"fitness"`         // at death, or at Finish for the living
+}
+
+// NewLineage returns a Lineage that starts tracking pop as founders.
+// Subscribe it to the scheduler to follow births and deaths.
+func NewLineage(pop []*NPC, tick int) *Lineage {
+	l := &Lineage{current: make(map[uint16]int)}
+	for _, npc := range pop {
+		l.founder(npc.ID).BirthTick = tick - npc.Age
+	}
+	return l
+}
+
+// founder adds a parentless node for NPC id.
+func (l *Lineage) founder(id uint16) *LineageNode {
+	l.current[id] = len(l.Nodes)
+	l.Nodes = append(l.Nodes, LineageNode{
+		ID: len(l.Nodes), NPC: id, Parents: [2]int{-1, -1}, BirthTick: -1, End: -1,
+	})
+	return &l.Nodes[len(l.Nodes)-1]
+}
+
+// node returns the individual living under NPC id.
+func (l *Lineage) node(id uint16) *LineageNode {
+	if i, ok := l.current[id]; ok {
+		return &l.Nodes[i]
+	}
+	return l.founder(id)
+}
+
+// OnEvent records births and deaths.
+func (l *Lineage) OnEvent(ev Event) {
+	switch e := ev.(type) {
+	case NPCBorn:
+		// Parents first: a clone's parent is the individual it replaces
+		parents := [2]int{l.node(e.Parents[0]).ID, l.node(e.Parents[1]).ID}
+		if i, ok := l.current[e.ID]; ok {
+			l.Nodes[i].End, l.Nodes[i].Cause = e.Tick, "replaced"
+		}
+		n := l.founder(e.ID)
+		n.Parents, n.BirthTick, n.Epoch, n.Clone = parents, e.Tick, e.Epoch, e.Clone
+	case NPCDied:
+		n := l.node(e.ID)
+		if n.BirthTick < 0 {
+			n.BirthTick = e.Tick + 1 - e.Age
+		}
+		n.End, n.Cause, n.Fitness = e.Tick, DeathCauseNames[e.Cause], e.Fitness
+		delete(l.current, e.ID)
+	}
+}
+
+// Finish records the fitness of the living (and the birth tick of any
+// founder first seen among them) at the end of a run.
+func (l *Lineage) Finish(pop []*NPC, tick int) {
+	for _, npc := range pop {
+		n := l.node(npc.ID)
+		if n.BirthTick < 0 {
+			n.BirthTick = tick - npc.Age
+		}
+		n.Fitness = npc.Fitness
+	}
+}
+
+// Ancestry marks every individual still alive and all of their ancestors:
+// the lineages that made it to the end of the run.
+func (l *Lineage) Ancestry() []bool {
+	keep := make([]bool, len(l.Nodes))
+	var stack []int
+	for _, i := range l.current {
+		stack = append(stack, i)
+	}
+	for len(stack) > 0 {
+		i := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if keep[i] {
+			continue
+		}
+		keep[i] = true
+		for _, p := range l.Nodes[i].Parents {
+			if p >= 0 {
+				stack = append(stack, p)
+			}
+		}
+	}
+	return keep
+}
+
+// WriteJSON writes the nodes kept by keep (all of them if keep is nil) as a
+// JSON array.
+func (l *Lineage) WriteJSON(out io.Writer, keep []bool) error {
+	nodes := make([]LineageNode, 0, len(l.Nodes))
+	for i, n := range l.Nodes {
+		if keep == nil || keep[i] {
+			nodes = append(nodes, n)
+		}
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", " ")
+	return enc.Encode(nodes)
+}
+
+// WriteDOT writes the nodes kept by keep (all of them if keep is nil) as a
+// Graphviz digraph, parents pointing to children. Living individuals are
+// drawn bold.
+func (l *Lineage) WriteDOT(out io.Writer, keep []bool) error {
+	bw := bufio.NewWriter(out)
+	fmt.Fprintln(bw, "digraph lineage {")
+	fmt.Fprintln(bw, "  node [shape=box fontsize=10];")
+	for i, n := range l.Nodes {
+		if keep != nil && !keep[i] {
+			continue
+		}
+		style := ""
+		if n.End < 0 {
+			style = " style=bold"
+		}
+		fmt.Fprintf(bw, "  n%d [label=\"#%d npc%d e%d\\nfit %d\"%s];\n", n.ID, n.ID, n.NPC, n.Epoch, n.Fitness, style)
+		for j, p := range n.Parents {
+			if p < 0 || (j == 1 && p == n.Parents[0]) {
+				continue
+			}
+			fmt.Fprintf(bw, "  n%d -> n%d;\n", p, n.ID)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
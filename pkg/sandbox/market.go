@@ -0,0 +1,130 @@
+package sandbox
+
+// Markets are tiles where NPCs deal with the world instead of each other:
+// a market sells meals and raw items for gold at scarcity prices, and buys
+// any held item back at half its MarketValue. The spread is gold leaving
+// the economy, so gold earned by trading has somewhere to go.
+
+// marketGoods are the items a market sells besides meals.
+var marketGoods = [...]byte{ItemTool, ItemWeapon, ItemCrystal}
+
+// marketPrices are one tick's prices, fixed at the start of the tick so
+// every NPC sees the same ones and MarketValue's scans run once.
+type marketPrices struct {
+	meal  int
+	value [ItemRemedy + 1]int // MarketValue by item type
+}
+
+// PlaceMarkets turns up to n random empty, passable tiles into markets.
+func (w *World) PlaceMarkets(n int) {
+	w.scatterTiles(TileMarket, n)
+}
+
+// scatterTiles turns up to n random empty, passable, unoccupied tiles into
+// typ.
+func (w *World) scatterTiles(typ byte, n int) {
+	for i := 0; i < n; i++ {
+		for tries := 0; tries < 50; tries++ {
+			x, y := w.Rng.Intn(w.Size), w.Rng.Intn(w.Size)
+			if w.TileAt(x, y).Type() != TileEmpty || w.OccAt(x, y) != 0 {
+				continue
+			}
+			if w.Biomes && w.BiomeGrid != nil && !BiomeTable[w.BiomeGrid[w.idx(x, y)]].Passable {
+				continue
+			}
+			w.SetTile(x, y, MakeTile(typ))
+			break
+		}
+	}
+}
+
+// Markets returns the number of market tiles.
+func (w *World) Markets() int {
+	return w.marketCount
+}
+
+// MealPrice returns what a market charges for a meal: MarketValue's
+// formula with living NPCs as the demand and food tiles as the supply, so
+// meals get dear when food runs short (at least 1 gold).
+func (w *World) MealPrice() int {
+	alive := 0
+	for _, npc := range w.NPCs {
+		if npc.Alive() {
+			alive++
+		}
+	}
+	return max(10*alive/max(w.FoodCount(), 1), 1)
+}
+
+// priceMarkets sets this tick's prices; worlds without markets skip the
+// scans and read 0 everywhere.
+func (s *Scheduler) priceMarkets() {
+	w := s.World
+	s.prices = marketPrices{}
+	if w.marketCount == 0 {
+		return
+	}
+	s.prices.meal = w.MealPrice()
+	for item := byte(ItemFoodPack); item <= ItemRemedy; item++ {
+		s.prices.value[item] = w.MarketValue(item)
+	}
+}
+
+// sellPrice returns what a market pays for item this tick.
+func (s *Scheduler) sellPrice(item byte) int {
+	if int(item) >= len(s.prices.value) {
+		return 0
+	}
+	return s.prices.value[item] / 2
+}
+
+// buy spends gold at a market on a meal (what = 0) or one of marketGoods.
+// Items need empty hands.
+func (s *Scheduler) buy(npc *NPC, what byte) {
+	cost := s.Actions[ActionBuy].Energy
+	if s.World.TileAt(npc.X, npc.Y).Type() != TileMarket || npc.Energy < cost {
+		return
+	}
+	price := 0
+	if what == 0 {
+		price = s.prices.meal
+		if npc.Predator || price == 0 || npc.Gold < price {
+			return
+		}
+		npc.Gold -= price
+		s.feed(npc)
+	} else {
+		for _, good := range marketGoods {
+			if good == what {
+				price = s.prices.value[what]
+			}
+		}
+		if price == 0 || npc.Item != ItemNone || npc.Gold < price {
+			return // not stocked, hands full or too poor
+		}
+		npc.Gold -= price
+		npc.Item = what
+		grantItemModifier(npc, what)
+	}
+	s.spend(npc, ActionBuy, cost)
+	s.MarketBuys++
+	s.GoldSpent += price
+}
+
+// sell trades the held item at a market for half its value.
+func (s *Scheduler) sell(npc *NPC) {
+	cost := s.Actions[ActionSell].Energy
+	if s.World.TileAt(npc.X, npc.Y).Type() != TileMarket || npc.Item == ItemNone || npc.Energy < cost {
+		return
+	}
+	price := s.sellPrice(npc.Item)
+	if price == 0 {
+		return
+	}
+	removeItemModifier(npc, npc.Item)
+	npc.Item = ItemNone
+	npc.Gold += price
+	npc.GoldEarned += s.taxEarnings(npc, price)
+	s.spend(npc, ActionSell, cost)
+	s.MarketSells++
+}
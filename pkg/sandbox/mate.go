@@ -0,0 +1,174 @@
+package sandbox
+
+// Mating thresholds: both partners need mateEnergy and each pays the mate
+// action's energy (mateCost by default).
+const (
+	mateEnergy = 80
+	mateCost   = 40
+)
+
+// Parental care: children born in-world start weak and depend on their
+// parents, who pass them careGift energy per tick while adjacent during the
+// first careTicks of the child's life, as long as they keep careReserve.
+const (
+	newbornEnergy = 30
+	careTicks     = 50
+	careGift      = 3
+	careReserve   = 40
+	childRadius   = 8 // range of the child-direction sensor
+)
+
+// resolveMating pairs NPCs whose act.mate intents name each other. Consenting
+// adjacent pairs with enough energy produce a child next to them, bred by
+// Scheduler.Mating. Pairs are visited in population order for determinism.
+func (s *Scheduler) resolveMating() {
+	if len(s.mateIntents) == 0 {
+		return
+	}
+	w := s.World
+	parents := w.NPCs // Spawn appends; only the current generation may mate
+	for _, a := range parents {
+		partner, ok := s.mateIntents[a.ID]
+		if !ok || s.mateIntents[partner] != a.ID || a.ID > partner {
+			continue // not mutual, or pair already visited from the other side
+		}
+		b := w.npcByID[partner]
+		if b == nil || !b.Alive() || !a.Alive() {
+			continue
+		}
+		if a.Predator != b.Predator || !s.inRange(a, b, ActionMate) || a.Energy < mateEnergy || b.Energy < mateEnergy {
+			continue
+		}
+		if s.MaxPopulation > 0 && len(w.NPCs) >= s.MaxPopulation {
+			break
+		}
+		x, y, ok := s.birthplace(a, b)
+		if !ok {
+			continue
+		}
+		ga := s.Mating
+		if a.Predator && s.PredatorMating != nil {
+			ga = s.PredatorMating
+		}
+		child := NewNPC(ga.breed(a.Genome, b.Genome))
+		child.X, child.Y = x, y
+		child.Clan = a.Clan
+		child.Predator = a.Predator
+		child.Recipes = ga.inheritRecipes(a.Recipes, b.Recipes)
+		child.Parents = [2]uint16{a.ID, b.ID}
+		child.Energy = newbornEnergy
+		if w.Spawn(child) {
+			s.emit(NPCBorn{Tick: w.Tick, ID: child.ID, Parents: child.Parents, Epoch: s.Epoch})
+		}
+		cost := s.Actions[ActionMate].Energy
+		s.spend(a, ActionMate, cost)
+		s.spend(b, ActionMate, cost)
+		s.BirthCount++
+	}
+	for k := range s.mateIntents {
+		delete(s.mateIntents, k)
+	}
+}
+
+// birthplace finds a free tile adjacent to either parent.
+func (s *Scheduler) birthplace(a, b *NPC) (int, int, bool) {
+	w := s.World
+	for _, p := range []*NPC{a, b} {
+		for dir := byte(DirNorth); dir <= DirWest; dir++ {
+			dx, dy := dirDelta(dir)
+			x, y := p.X+dx, p.Y+dy
+			if !w.InBounds(x, y) || w.OccAt(x, y) != 0 {
+				continue
+			}
+			if typ := w.TileAt(x, y).Type(); typ != TileEmpty && typ != TileForge {
+				continue
+			}
+			if w.Biomes && w.BiomeGrid != nil && !BiomeTable[w.BiomeGrid[w.idx(x, y)]].Passable {
+				continue
+			}
+			return x, y, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Kinship returns 2 if a and b are parent and child, 1 if they share a
+// parent, and 0 otherwise.
+func Kinship(a, b *NPC) int {
+	if a.isParentOf(b) || b.isParentOf(a) {
+		return 2
+	}
+	for _, pa := range a.Parents {
+		if pa != 0 && (pa == b.Parents[0] || pa == b.Parents[1]) {
+			return 1
+		}
+	}
+	return 0
+}
+
+func (n *NPC) isParentOf(child *NPC) bool {
+	return n.ID != 0 && (child.Parents[0] == n.ID || child.Parents[1] == n.ID)
+}
+
+// dependent reports whether child is still young enough to be cared for by n.
+func (n *NPC) dependent(child *NPC) bool {
+	return child.Age < careTicks && n.isParentOf(child)
+}
+
+// parentalCare transfers energy from npc to each adjacent dependent child.
+func (s *Scheduler) parentalCare(npc *NPC) {
+	w := s.World
+	for dir := byte(DirNorth); dir <= DirWest; dir++ {
+		if npc.Energy-careGift < careReserve {
+			return
+		}
+		dx, dy := dirDelta(dir)
+		if !w.InBounds(npc.X+dx, npc.Y+dy) {
+			continue
+		}
+		child := w.npcByID[w.OccAt(npc.X+dx, npc.Y+dy)]
+		if child == nil || !child.Alive() || !npc.dependent(child) {
+			continue
+		}
+		npc.Energy -= careGift
+		child.Energy += careGift
+		s.CareEnergy += careGift
+	}
+}
+
+// countCaregivers records which NPCs have a dependent child, so childDir
+// only scans around actual parents.
+func (s *Scheduler) countCaregivers() {
+	clear(s.caregivers)
+	for _, npc := range s.World.NPCs {
+		if npc.Age < careTicks {
+			for _, p := range npc.Parents {
+				if p != 0 {
+					s.caregivers[p] = true
+				}
+			}
+		}
+	}
+}
+
+// childDir returns the direction toward npc's nearest dependent child within
+// childRadius, or DirNone.
+func (s *Scheduler) childDir(w *World, npc *NPC) int {
+	if !s.caregivers[npc.ID] {
+		return DirNone
+	}
+	for d := 1; d <= childRadius; d++ {
+		bx, by := -1, -1
+		w.scanManhattanRing(npc.X, npc.Y, d, func(x, y int) bool {
+			if child := w.npcByID[w.OccAt(x, y)]; child != nil && child.Alive() && npc.dependent(child) {
+				bx, by = x, y
+				return true
+			}
+			return false
+		})
+		if bx >= 0 {
+			return directionToward(npc.X, npc.Y, bx, by)
+		}
+	}
+	return DirNone
+}
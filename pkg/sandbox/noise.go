@@ -0,0 +1,69 @@
+package sandbox
+
+// Noise loudness by event. A noise is heard at loudness minus Manhattan
+// distance, so the loudest event carries maxLoudness-1 tiles.
+const (
+	noiseMove   = 2
+	noiseBuild  = 4
+	noiseCraft  = 5
+	noiseShoot  = 6
+	noiseAttack = 8
+	maxLoudness = noiseAttack
+)
+
+// noiseBucket is the side of the square cells noises are filed under. It
+// must be at least maxLoudness so a listener only checks the 3x3 cells
+// around its own.
+const noiseBucket = 8
+
+// noiseEvent is one sound made in the world.
+type noiseEvent struct {
+	x, y   int
+	loud   int
+	source uint16
+}
+
+// noiseLog holds this tick's and last tick's noise events bucketed by
+// position, so NPCs early in the update order still hear what happened
+// after their turn.
+type noiseLog struct {
+	now, prev map[[2]int][]noiseEvent
+}
+
+func newNoiseLog() noiseLog {
+	return noiseLog{now: make(map[[2]int][]noiseEvent), prev: make(map[[2]int][]noiseEvent)}
+}
+
+// age forgets noises older than the previous tick.
+func (l *noiseLog) age() {
+	l.prev, l.now = l.now, l.prev
+	clear(l.now)
+}
+
+// noise records a sound of the given loudness at npc's position.
+func (s *Scheduler) noise(npc *NPC, loud int) {
+	key := [2]int{npc.X / noiseBucket, npc.Y / noiseBucket}
+	s.noises.now[key] = append(s.noises.now[key], noiseEvent{x: npc.X, y: npc.Y, loud: loud, source: npc.ID})
+}
+
+// loudest returns the direction toward and perceived loudness of the
+// loudest recent noise npc can hear (its own noises excluded).
+func (l *noiseLog) loudest(npc *NPC) (int, int) {
+	bx, by := npc.X/noiseBucket, npc.Y/noiseBucket
+	best, dir := 0, DirNone
+	for _, events := range []map[[2]int][]noiseEvent{l.now, l.prev} {
+		for ky := by - 1; ky <= by+1; ky++ {
+			for kx := bx - 1; kx <= bx+1; kx++ {
+				for _, e := range events[[2]int{kx, ky}] {
+					if e.source == npc.ID {
+						continue
+					}
+					if heard := e.loud - abs(e.x-npc.X) - abs(e.y-npc.Y); heard > best {
+						best, dir = heard, directionToward(npc.X, npc.Y, e.x, e.y)
+					}
+				}
+			}
+		}
+	}
+	return dir, best
+}
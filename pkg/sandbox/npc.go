@@ -1,5 +1,7 @@
 package sandbox
 
+import "github.com/psilLang/psil/pkg/micro"
+
 // MaxAge is the maximum age (in ticks) before an NPC dies of old age.
 const MaxAge = 5000 // ~50 GA cycles at evolve-every-100
 
@@ -22,7 +24,7 @@ const (
 	Ring0MyGold    = 14 // NPC's gold count
 	Ring0MyItem    = 15 // NPC's held item type
 	Ring0NearItem  = 16 // distance to nearest item tile
-	Ring0NearTrust = 17 // trust of nearest NPC (stub, Phase 3)
+	Ring0NearTrust = 17 // own trust toward nearest NPC (-100..100)
 	Ring0NearDir   = 18 // direction toward nearest NPC
 	Ring0ItemDir   = 19 // direction toward nearest item tile
 	Ring0Rng       = 20 // per-NPC random number (0-31)
@@ -36,7 +38,30 @@ const (
 	Ring0Similarity = 28 // genetic similarity to nearest NPC (0-100)
 	Ring0TileAhead  = 29 // tile type in move direction
 	Ring0Cooldown   = 30 // ticks remaining on current tile cooldown
-	Ring0ExtCount   = 31 // extended Ring0 slot count
+	Ring0IncomingFire = 31 // direction a shot came from this tick (0=none)
+	Ring0LeaderID     = 32 // ID of the most-followed NPC nearby (0=none)
+	Ring0LeaderDir    = 33 // direction toward that leader
+	Ring0MyFollowers  = 34 // number of NPCs following this one
+	Ring0NearKin      = 35 // kinship to nearest NPC (0=none, 1=sibling, 2=parent/child)
+	Ring0ChildDir     = 36 // direction toward nearest dependent child (0=none)
+	Ring0Infected     = 37 // infection state (0=healthy, 1=infected, 2=immune)
+	Ring0NearEmotion  = 38 // emotion shown by an adjacent nearest NPC (0=none/neutral)
+	Ring0Behind       = 39 // 1 if an NPC is close by outside the forward view cone
+	Ring0Facing       = 40 // facing direction (1=N,2=E,3=S,4=W)
+	Ring0NoiseDir     = 41 // direction toward the loudest recent noise heard (0=none/here)
+	Ring0NoiseLevel   = 42 // loudness of that noise after distance falloff (0=silence)
+	Ring0PredatorDist = 43 // distance to nearest predator (0 while no predators live)
+	Ring0PredatorDir  = 44 // direction toward that predator
+	Ring0PreyDist     = 45 // distance to nearest prey (non-predator), 0 while no predators live
+	Ring0PreyDir      = 46 // direction toward that prey
+	Ring0PreyID       = 47 // ID of that prey
+	Ring0MealPrice    = 48 // gold a market charges for a meal (0 while there are no markets)
+	Ring0SellPrice    = 49 // gold a market pays for the held item (0 with no item or no markets)
+	Ring0ContractDir  = 50 // direction toward the nearest open contract's objective (0=none/here)
+	Ring0ContractReward = 51 // gold that contract pays (0 = no contract)
+	Ring0ContractItem = 52 // item that contract wants delivered (0 = a bounty to kill)
+	Ring0PostPayout   = 53 // item waiting at the trading posts for this NPC to collect (0 = none)
+	Ring0ExtCount     = 54 // extended Ring0 slot count
 )
 
 // Ring1 action slots (writable by brain, read by scheduler)
@@ -44,8 +69,17 @@ const (
 	Ring1Move    = 0 // move direction (0=none, 1=N, 2=E, 3=S, 4=W)
 	Ring1Action  = 1 // action (0=idle, 1=eat, 2=attack, 3=share)
 	Ring1Target  = 2 // action target ID
-	Ring1Emotion = 3 // emotional state
-	Ring1Count   = 4 // number of Ring1 slots
+	Ring1Emotion = 3 // emotional state (EmotionNeutral..EmotionFearful)
+	Ring1Turn    = 4 // face a direction without moving (0=keep, 1-4=N/E/S/W)
+	Ring1Count   = 5 // number of Ring1 slots
+)
+
+// Emotions (Ring1Emotion), shown to adjacent NPCs
+const (
+	EmotionNeutral    = 0
+	EmotionFriendly   = 1 // sweetens trades, opens up to teaching
+	EmotionAggressive = 2 // refuses trades, resists teaching, frightens victims
+	EmotionFearful    = 3 // cowers: blunts attacks on it
 )
 
 // Move directions
@@ -69,6 +103,25 @@ const (
 	ActionHeal      = 7
 	ActionHarvest   = 8
 	ActionTerraform = 9
+	ActionBuild     = 10
+	ActionDeposit   = 11
+	ActionWithdraw  = 12
+	ActionShoot     = 13
+	ActionGive      = 14
+	ActionFollow    = 15
+	ActionJoin      = 16
+	ActionMate      = 17
+	ActionSleep     = 18
+	ActionBuy       = 19 // Ring1Target: item to buy, 0 = a meal
+	ActionSell      = 20
+	ActionPost      = 21 // Ring1Target: item asked for in return, 0 = anything
+)
+
+// Build kinds (Ring1Target when Ring1Action = ActionBuild)
+const (
+	BuildWall    = 0
+	BuildShelter = 1
+	BuildChest   = 2
 )
 
 // Item types
@@ -81,6 +134,15 @@ const (
 	ItemCrystal  = 5
 	ItemShield   = 6
 	ItemCompass  = 7
+	ItemCharm    = 8
+	ItemRemedy   = 9 // consumed automatically to cure an infection
+)
+
+// Damage types
+const (
+	DamagePhysical = 0 // melee and shots
+	DamagePoison   = 1 // poison tiles, swamp
+	DamageFire     = 2 // strikes made from a forge; no item resists fire
 )
 
 // Modifier kinds
@@ -95,8 +157,15 @@ const (
 	ModStealth = 7
 	ModTrade   = 8
 	ModStress  = 9
+	ModResistPoison = 10
 )
 
+// damageResist maps a damage type to the modifier that reduces it.
+var damageResist = map[byte]byte{
+	DamagePhysical: ModDefense,
+	DamagePoison:   ModResistPoison,
+}
+
 // Modifier represents a timed or permanent effect on an NPC.
 type Modifier struct {
 	Kind     byte  // ModGas, ModForage, etc.
@@ -112,6 +181,26 @@ var ItemModifiers = map[byte]Modifier{
 	ItemTreasure: {Kind: ModTrade, Mag: 3, Duration: -1, Source: ItemTreasure},
 	ItemShield:   {Kind: ModDefense, Mag: 5, Duration: -1, Source: ItemShield},
 	ItemCompass:  {Kind: ModForage, Mag: 2, Duration: -1, Source: ItemCompass},
+	ItemCharm:    {Kind: ModResistPoison, Mag: 10, Duration: -1, Source: ItemCharm},
+}
+
+// RelationSlots is the size of each NPC's relationship table.
+const RelationSlots = 8
+
+// Trust deltas applied by social interactions.
+const (
+	TrustTrade  = 10
+	TrustShare  = 5
+	TrustHeal   = 5
+	TrustTeach  = 3
+	TrustGift   = 8
+	TrustAttack = -20
+)
+
+// Relation records how much an NPC trusts another (0 ID = empty slot).
+type Relation struct {
+	ID    uint16
+	Trust int8 // -100..100
 }
 
 // NPC represents a creature in the sandbox world.
@@ -135,7 +224,49 @@ type NPC struct {
 	CraftCount int          // number of items crafted
 	Taught     int          // times this NPC's genome was externally modified
 	TeachCount int          // times this NPC successfully taught others
-	LastDir    byte         // last move direction (for tile-ahead sensor)
+	GiftCount  int          // items or gold given away with nothing in return
+	Shares     int          // energy shares given
+	GoldEarned int          // gold gained by trading
+	Explored   int          // distinct tiles visited (tracked while Fitness.Explore is set)
+	visited    []uint64     // bit per tile index, for Explored
+	Following  uint16       // ID of the NPC being followed (0 = none)
+	followTick int          // tick+1 of the last follow step (one per tick)
+	Clan       byte         // clan ID (0 = none), see ClanName
+	Parents    [2]uint16    // parent IDs (0 = unknown), for kinship sensors
+	Infection  int          // ticks of infection remaining (0 = healthy)
+	Immune     bool         // recovered from an infection; cannot catch it again
+	Asleep     bool         // chose to sleep this tick: rests, but resistances are ignored
+	Predator   bool         // hunts prey for energy; cannot eat food tiles or forage
+	Frozen     bool         // genome kept out of evolution: never culled, migrated over or taught (see SwapGenome)
+	Emotion    byte         // emotion shown this tick (EmotionNeutral..EmotionFearful)
+	Trades     int          // trades completed (biography)
+	Kills      int          // NPCs killed (biography)
+	ItemsHeld  uint16       // bit per item type ever held (1 << ItemTool, ...)
+	lastHarm   byte         // cause of the most recent health loss (DeathStarvation..)
+	LastDir    byte         // facing: last move or turn direction (0 = north)
+	Recipes    byte         // known forge recipes (RecipeCompass, RecipeShield bits)
+	IncomingFire byte       // direction toward the last shooter, cleared each tick
+	Relations  [RelationSlots]Relation // trust toward recently met NPCs (fixed-size, no heap)
+	vm         *micro.VM    // own brain VM, memory persists across ticks (see Scheduler.VM)
+	nextUse    [ActionCount]int // tick each action comes off cooldown
+}
+
+// TakeDamage applies dmg of the given type, reduced by the matching
+// resistance modifier (minimum 1; sleepers get no resistance), and returns
+// the health lost.
+func (n *NPC) TakeDamage(dmg int, kind byte) int {
+	if mod, ok := damageResist[kind]; ok && !n.Asleep {
+		dmg -= n.ModSum(mod)
+	}
+	if dmg < 1 {
+		dmg = 1
+	}
+	n.Health -= dmg
+	n.lastHarm = DeathCombat
+	if kind == DamagePoison {
+		n.lastHarm = DeathPoison
+	}
+	return dmg
 }
 
 // Alive returns true if NPC is still alive.
@@ -226,6 +357,56 @@ func (n *NPC) RemoveMod(source byte) {
 	}
 }
 
+// TrustOf returns this NPC's trust toward id (0 if unknown).
+func (n *NPC) TrustOf(id uint16) int {
+	if id == 0 {
+		return 0
+	}
+	for _, r := range n.Relations {
+		if r.ID == id {
+			return int(r.Trust)
+		}
+	}
+	return 0
+}
+
+// AdjustTrust shifts trust toward id by delta, clamped to -100..100. When the
+// table is full the weakest relationship (smallest |trust|) is forgotten.
+func (n *NPC) AdjustTrust(id uint16, delta int) {
+	if id == 0 || id == n.ID {
+		return
+	}
+	slot := -1
+	for i, r := range n.Relations {
+		if r.ID == id {
+			slot = i
+			break
+		}
+	}
+	if slot == -1 {
+		weakest := 101
+		for i, r := range n.Relations {
+			if r.ID == 0 {
+				slot = i
+				break
+			}
+			if t := abs(int(r.Trust)); t < weakest {
+				weakest = t
+				slot = i
+			}
+		}
+		n.Relations[slot] = Relation{ID: id}
+	}
+	t := int(n.Relations[slot].Trust) + delta
+	if t > 100 {
+		t = 100
+	}
+	if t < -100 {
+		t = -100
+	}
+	n.Relations[slot].Trust = int8(t)
+}
+
 // NewNPC creates an NPC with default stats and the given genome.
 func NewNPC(genome []byte) *NPC {
 	g := make([]byte, len(genome))
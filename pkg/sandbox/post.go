@@ -0,0 +1,105 @@
+package sandbox
+
+// Trading posts let NPCs trade without meeting. An NPC on a post leaves its
+// held item as an Offer, asking for an item type in return (0 = anything).
+// Whoever later posts the item asked for, at any post, while asking for
+// (or accepting anything like) the item on offer, takes it at once; the
+// offer's owner collects what they left in exchange by using a post again
+// with empty hands. Offers nobody takes within offerTTL ticks come back to
+// their owners the same way, and those of the dead are lost.
+const (
+	maxOffers = 16
+	offerTTL  = 1000
+)
+
+// Offer is an item left at the trading posts.
+type Offer struct {
+	Owner   uint16
+	Item    byte // on offer, or once Filled, the owner's to collect
+	Ask     byte // item wanted in return (0 = anything)
+	Filled  bool // taken (or lapsed): Item waits for the owner
+	Expires int  // tick an open offer lapses
+}
+
+// PlaceTradingPosts turns up to n random empty, passable tiles into trading
+// posts.
+func (w *World) PlaceTradingPosts(n int) {
+	w.scatterTiles(TilePost, n)
+}
+
+// post is ActionPost on a trading post: collect a payout with empty hands,
+// else trade the held item against a matching offer, or leave it as a new
+// one asking for ask.
+func (s *Scheduler) post(npc *NPC, ask byte) {
+	cost := s.Actions[ActionPost].Energy
+	if s.World.TileAt(npc.X, npc.Y).Type() != TilePost || npc.Energy < cost {
+		return
+	}
+	if npc.Item == ItemNone {
+		for i, o := range s.Offers {
+			if o.Owner == npc.ID && o.Filled {
+				npc.Item = o.Item
+				grantItemModifier(npc, npc.Item)
+				s.Offers = append(s.Offers[:i], s.Offers[i+1:]...)
+				s.spend(npc, ActionPost, cost)
+				return
+			}
+		}
+		return
+	}
+	for i := range s.Offers {
+		o := &s.Offers[i]
+		if o.Filled || o.Owner == npc.ID ||
+			ask != 0 && o.Item != ask || o.Ask != 0 && o.Ask != npc.Item {
+			continue
+		}
+		removeItemModifier(npc, npc.Item)
+		npc.Item, o.Item = o.Item, npc.Item
+		grantItemModifier(npc, npc.Item)
+		o.Filled = true
+		npc.Trades++
+		if owner := s.World.npcByID[o.Owner]; owner != nil {
+			owner.Trades++
+		}
+		s.TradeCount++
+		s.PostTrades++
+		s.emit(TradeCompleted{Tick: s.World.Tick, A: npc.ID, B: o.Owner})
+		s.spend(npc, ActionPost, cost)
+		return
+	}
+	if len(s.Offers) >= maxOffers {
+		return
+	}
+	removeItemModifier(npc, npc.Item)
+	s.Offers = append(s.Offers, Offer{Owner: npc.ID, Item: npc.Item, Ask: ask, Expires: s.World.Tick + offerTTL})
+	npc.Item = ItemNone
+	s.spend(npc, ActionPost, cost)
+	s.PostOffers++
+}
+
+// tendPosts returns lapsed offers to their owners and drops those of the
+// dead.
+func (s *Scheduler) tendPosts() {
+	w := s.World
+	open := s.Offers[:0]
+	for _, o := range s.Offers {
+		if owner := w.npcByID[o.Owner]; owner == nil || !owner.Alive() {
+			continue
+		}
+		if !o.Filled && w.Tick >= o.Expires {
+			o.Filled = true
+		}
+		open = append(open, o)
+	}
+	s.Offers = open
+}
+
+// payout returns the item waiting at the posts for id (0 = none).
+func (s *Scheduler) payout(id uint16) byte {
+	for _, o := range s.Offers {
+		if o.Owner == id && o.Filled {
+			return o.Item
+		}
+	}
+	return ItemNone
+}
@@ -0,0 +1,79 @@
+package sandbox
+
+import "github.com/psilLang/psil/pkg/micro"
+
+// Predators are a second population living in the same world (see
+// NPC.Predator). They cannot eat food tiles or forage; instead every melee
+// hit on prey (any non-predator) feeds them like a meal, and a kill feeds
+// them more. Prey sense the nearest predator and predators the nearest
+// prey, so each side can evolve against the other.
+const (
+	biteEnergy = 20 // energy a predator gains per hit on prey
+	killEnergy = 40 // extra energy for killing the prey
+)
+
+// countPredators records whether any predator is alive this tick.
+func (s *Scheduler) countPredators() {
+	s.hunting = false
+	for _, npc := range s.World.NPCs {
+		if npc.Predator && npc.Alive() {
+			s.hunting = true
+			return
+		}
+	}
+}
+
+// feedOnPrey feeds predator for a hit on prey.
+func (s *Scheduler) feedOnPrey(predator *NPC, killed bool) {
+	gain := biteEnergy
+	if killed {
+		gain += killEnergy
+		s.PreyKills++
+	}
+	predator.Energy += gain
+	if predator.Energy > 200 {
+		predator.Energy = 200
+	}
+	predator.FoodEaten++
+	predator.Hunger = 0
+}
+
+// senseHunt fills the predator and prey sensors. They stay 0 in worlds
+// without live predators, sparing the scans.
+func (s *Scheduler) senseHunt(w *World, vm *micro.VM, npc *NPC) {
+	var predDist, predDir, preyDist, preyDir int
+	var preyID uint16
+	if s.hunting {
+		predDist, _, predDir = w.NearestNPCOfKind(npc.X, npc.Y, npc.ID, true)
+		preyDist, preyID, preyDir = w.NearestNPCOfKind(npc.X, npc.Y, npc.ID, false)
+	}
+	vm.MemWrite(Ring0PredatorDist, int16(predDist))
+	vm.MemWrite(Ring0PredatorDir, int16(predDir))
+	vm.MemWrite(Ring0PreyDist, int16(preyDist))
+	vm.MemWrite(Ring0PreyDir, int16(preyDir))
+	vm.MemWrite(Ring0PreyID, int16(preyID))
+}
+
+// NearestNPCOfKind is NearestNPCFull restricted to predators (predator set)
+// or to prey.
+func (w *World) NearestNPCOfKind(x, y int, excludeID uint16, predator bool) (int, uint16, int) {
+	for d := 1; d <= maxSearchRadius; d++ {
+		bestID := uint16(0)
+		bx, by := -1, -1
+		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+			occ := w.OccAt(fx, fy)
+			if occ != 0 && occ != excludeID {
+				if npc := w.npcByID[occ]; npc != nil && npc.Alive() && npc.Predator == predator {
+					bestID = occ
+					bx, by = fx, fy
+					return true
+				}
+			}
+			return false
+		})
+		if bestID != 0 {
+			return d, bestID, directionToward(x, y, bx, by)
+		}
+	}
+	return maxSearchRadius, 0, DirNone
+}
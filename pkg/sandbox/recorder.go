@@ -31,6 +31,7 @@ type RecordNPC struct {
 	Fit    int    `json:"f"`
 	Stress int    `json:"s"`
 	GenLen int    `json:"gl"`
+	Clan   byte   `json:"cl,omitempty"`
 }
 
 // RecordFullNPC includes genome bytes (used in full frames).
@@ -48,6 +49,9 @@ type RecordStats struct {
 	Terraforms int `json:"ter"`
 	Trades     int `json:"trd"`
 	Teaches    int `json:"tch"`
+	Builds     int `json:"bld,omitempty"`
+	Gifts      int `json:"gft,omitempty"`
+	Births     int `json:"brn,omitempty"`
 }
 
 // RecordFrame is a tick snapshot (type="tick").
@@ -105,15 +109,7 @@ func (r *Recorder) RecordTick(tick int, w *World, s *Scheduler) error {
 		return nil
 	}
 
-	stats := RecordStats{
-		Attacks:    s.AttackCount,
-		Kills:      s.KillCount,
-		Heals:      s.HealCount,
-		Harvests:   s.HarvestCount,
-		Terraforms: s.TerraformCount,
-		Trades:     s.TradeCount,
-		Teaches:    s.TeachCount,
-	}
+	stats := NewRecordStats(s)
 
 	// Extract grid as raw bytes
 	grid := make([]byte, len(w.Grid))
@@ -131,7 +127,7 @@ func (r *Recorder) RecordTick(tick int, w *World, s *Scheduler) error {
 			gen := make([]byte, len(npc.Genome))
 			copy(gen, npc.Genome)
 			npcs = append(npcs, RecordFullNPC{
-				RecordNPC: makeRecordNPC(npc),
+				RecordNPC: NewRecordNPC(npc),
 				Genome:    gen,
 			})
 		}
@@ -151,7 +147,7 @@ func (r *Recorder) RecordTick(tick int, w *World, s *Scheduler) error {
 		if !npc.Alive() {
 			continue
 		}
-		npcs = append(npcs, makeRecordNPC(npc))
+		npcs = append(npcs, NewRecordNPC(npc))
 	}
 	return r.enc.Encode(RecordFrame{
 		Type:     "tick",
@@ -163,7 +159,8 @@ func (r *Recorder) RecordTick(tick int, w *World, s *Scheduler) error {
 	})
 }
 
-func makeRecordNPC(npc *NPC) RecordNPC {
+// NewRecordNPC captures npc's frame state.
+func NewRecordNPC(npc *NPC) RecordNPC {
 	return RecordNPC{
 		ID:     npc.ID,
 		X:      npc.X,
@@ -176,6 +173,23 @@ func makeRecordNPC(npc *NPC) RecordNPC {
 		Fit:    npc.Fitness,
 		Stress: npc.Stress,
 		GenLen: len(npc.Genome),
+		Clan:   npc.Clan,
+	}
+}
+
+// NewRecordStats captures s's cumulative counters.
+func NewRecordStats(s *Scheduler) RecordStats {
+	return RecordStats{
+		Attacks:    s.AttackCount,
+		Kills:      s.KillCount,
+		Heals:      s.HealCount,
+		Harvests:   s.HarvestCount,
+		Terraforms: s.TerraformCount,
+		Trades:     s.TradeCount,
+		Teaches:    s.TeachCount,
+		Builds:     s.BuildCount,
+		Gifts:      s.GiftCount,
+		Births:     s.BirthCount,
 	}
 }
 
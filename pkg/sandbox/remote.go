@@ -0,0 +1,69 @@
+package sandbox
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net"
+)
+
+// RemoteRequest is sent to an external brain for each decision.
+type RemoteRequest struct {
+	ID    uint16  `json:"id"`
+	Ring0 []int16 `json:"ring0"`
+}
+
+// RemoteReply carries an external brain's Ring1 outputs.
+type RemoteReply struct {
+	Move   int `json:"move"`
+	Action int `json:"action"`
+	Target int `json:"target"`
+}
+
+// RemoteBrain is a Controller backed by an external process speaking
+// newline-delimited JSON: one RemoteRequest out, one RemoteReply back per
+// decision. One connection may drive several NPCs (requests carry the NPC
+// ID). After the first I/O error the NPC idles and Err holds the error.
+type RemoteBrain struct {
+	rw  io.ReadWriter
+	enc *json.Encoder
+	dec *json.Decoder
+	Err error
+}
+
+// NewRemoteBrain speaks the brain protocol over rw.
+func NewRemoteBrain(rw io.ReadWriter) *RemoteBrain {
+	return &RemoteBrain{rw: rw, enc: json.NewEncoder(rw), dec: json.NewDecoder(bufio.NewReader(rw))}
+}
+
+// DialBrain connects to an external brain listening on a TCP address.
+func DialBrain(addr string) (*RemoteBrain, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewRemoteBrain(conn), nil
+}
+
+// Decide implements Controller.
+func (b *RemoteBrain) Decide(npc *NPC, ring0 []int16) (move, action, target int) {
+	if b.Err != nil {
+		return DirNone, ActionIdle, 0
+	}
+	if b.Err = b.enc.Encode(RemoteRequest{ID: npc.ID, Ring0: ring0}); b.Err != nil {
+		return DirNone, ActionIdle, 0
+	}
+	var r RemoteReply
+	if b.Err = b.dec.Decode(&r); b.Err != nil {
+		return DirNone, ActionIdle, 0
+	}
+	return r.Move, r.Action, r.Target
+}
+
+// Close closes the underlying connection if it can be closed.
+func (b *RemoteBrain) Close() error {
+	if c, ok := b.rw.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package sandbox
+
+import (
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// NPCColoring selects what an NPC's colour shows in a rendered frame.
+type NPCColoring int
+
+const (
+	ColorByRole    NPCColoring = iota // predators red, clan members their clan's colour, the rest white
+	ColorByFitness                    // dark blue (no fitness) to yellow (the fittest alive)
+)
+
+// Palette layout: tile types first, then the fitness ramp, the clan colours
+// and the fixed NPC colours. Every frame uses the same palette, so frames
+// can be stitched into a GIF as they are.
+const (
+	paletteFitness = numTileTypes                   // 16-step fitness ramp
+	paletteClans   = paletteFitness + fitnessShades // clanShades entries
+	paletteNPC     = paletteClans + clanShades      // unaffiliated NPC
+	palettePred    = paletteNPC + 1                 // predator
+	paletteItem    = palettePred + 1                // held-item marker
+	fitnessShades  = 16
+	clanShades     = 12
+)
+
+// tileColors are the tile types' colours, TileEmpty..TileSchool.
+var tileColors = []color.RGBA{
+	{24, 24, 24, 255},    // empty
+	{120, 120, 120, 255}, // wall
+	{40, 160, 40, 255},   // food
+	{30, 50, 120, 255},   // water
+	{170, 170, 170, 255}, // tool
+	{200, 200, 200, 255}, // weapon
+	{220, 200, 50, 255},  // treasure
+	{70, 220, 220, 255},  // crystal
+	{220, 70, 30, 255},   // forge
+	{200, 50, 200, 255},  // poison
+	{150, 100, 50, 255},  // shelter
+	{170, 120, 50, 255},  // chest
+	{240, 170, 40, 255},  // market
+	{0, 128, 128, 255},   // post
+	{150, 90, 220, 255},  // school
+}
+
+// clanColors tell clans apart in ColorByRole frames.
+var clanColors = [clanShades]color.RGBA{
+	{50, 150, 255, 255}, {255, 135, 0, 255}, {175, 50, 215, 255}, {50, 215, 50, 255},
+	{255, 50, 215, 255}, {50, 255, 255, 255}, {215, 120, 0, 255}, {135, 95, 255, 255},
+	{135, 230, 50, 255}, {255, 95, 175, 255}, {0, 120, 255, 255}, {255, 175, 0, 255},
+}
+
+// RenderPalette is the palette of every rendered frame.
+var RenderPalette = func() color.Palette {
+	p := make(color.Palette, 0, paletteItem+1)
+	for _, c := range tileColors {
+		p = append(p, c)
+	}
+	for i := 0; i < fitnessShades; i++ {
+		f := float64(i) / (fitnessShades - 1)
+		p = append(p, color.RGBA{uint8(30 + 225*f), uint8(40 + 200*f), uint8(160 * (1 - f)), 255})
+	}
+	for _, c := range clanColors {
+		p = append(p, c)
+	}
+	return append(p,
+		color.RGBA{255, 255, 255, 255}, // NPC
+		color.RGBA{255, 30, 30, 255},   // predator
+		color.RGBA{0, 0, 0, 255},       // item marker
+	)
+}()
+
+// Render draws w with scale×scale pixels per tile: tiles in their type's
+// colour, living NPCs as squares inset by a pixel (when scale > 2) coloured
+// by coloring, with a dark centre pixel when they hold an item.
+func Render(w *World, scale int, coloring NPCColoring) *image.Paletted {
+	if scale < 1 {
+		scale = 1
+	}
+	img := image.NewPaletted(image.Rect(0, 0, w.Size*scale, w.Size*scale), RenderPalette)
+	fill := func(x0, y0, x1, y1 int, idx uint8) {
+		for y := y0; y < y1; y++ {
+			row := img.Pix[y*img.Stride:]
+			for x := x0; x < x1; x++ {
+				row[x] = idx
+			}
+		}
+	}
+	for y := 0; y < w.Size; y++ {
+		for x := 0; x < w.Size; x++ {
+			idx := uint8(TileEmpty)
+			if typ := w.TileAt(x, y).Type(); int(typ) < len(tileColors) {
+				idx = typ
+			}
+			fill(x*scale, y*scale, (x+1)*scale, (y+1)*scale, idx)
+		}
+	}
+
+	best := 1
+	for _, npc := range w.NPCs {
+		if npc.Alive() && npc.Fitness > best {
+			best = npc.Fitness
+		}
+	}
+	inset := 0
+	if scale > 2 {
+		inset = 1
+	}
+	for _, npc := range w.NPCs {
+		if !npc.Alive() {
+			continue
+		}
+		idx := uint8(paletteNPC)
+		switch {
+		case coloring == ColorByFitness:
+			idx = uint8(paletteFitness + max(npc.Fitness, 0)*(fitnessShades-1)/best)
+		case npc.Predator:
+			idx = palettePred
+		case npc.Clan != 0:
+			idx = uint8(paletteClans + int(npc.Clan)%clanShades)
+		}
+		x0, y0 := npc.X*scale, npc.Y*scale
+		fill(x0+inset, y0+inset, x0+scale-inset, y0+scale-inset, idx)
+		if npc.Item != ItemNone && scale >= 4 {
+			img.Pix[(y0+scale/2)*img.Stride+x0+scale/2] = paletteItem
+		}
+	}
+	return img
+}
+
+// EncodeTimelapse writes frames as an endlessly looping animated GIF, each
+// frame shown for delay hundredths of a second.
+func EncodeTimelapse(out io.Writer, frames []*image.Paletted, delay int) error {
+	anim := &gif.GIF{Image: frames, Delay: make([]int, len(frames))}
+	for i := range anim.Delay {
+		anim.Delay[i] = delay
+	}
+	return gif.EncodeAll(out, anim)
+}
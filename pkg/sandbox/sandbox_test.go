@@ -2,9 +2,19 @@ package sandbox
 
 import (
 	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/gif"
 	"io"
 	"math/rand"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/psilLang/psil/pkg/micro"
 )
@@ -123,6 +133,457 @@ func TestGACrossoverValid(t *testing.T) {
 	}
 }
 
+func TestGATwoPointAndUniformCrossover(t *testing.T) {
+	// Ten 3-byte instructions per parent, tagged 100+i in a and 200+i in b
+	parent := func(base int) []byte {
+		var g []byte
+		for i := 0; i < 10; i++ {
+			g = append(g, micro.OpPushWord, 0, byte(base+i))
+		}
+		return g
+	}
+	a, b := parent(100), parent(200)
+	tags := func(child []byte) []int {
+		var out []int
+		for pc := 0; pc < len(child); pc++ {
+			if child[pc] == micro.OpNop {
+				continue // padding
+			}
+			if child[pc] != micro.OpPushWord || pc+2 >= len(child) {
+				t.Fatalf("child % x is not instruction-aligned at %d", child, pc)
+			}
+			out = append(out, int(child[pc+2]))
+			pc += 2
+		}
+		return out
+	}
+
+	ga := NewGA(testRng())
+	ga.MutationRate = 0
+	ga.Mode = CrossoverTwoPoint
+	for trial := 0; trial < 200; trial++ {
+		// a's head, then a run of b, then a's tail (picking up where it left off)
+		got := tags(ga.breed(a, b))
+		i := 0
+		for i < len(got) && got[i] == 100+i {
+			i++
+		}
+		j := i
+		for j < len(got) && got[j] >= 200 && (j == i || got[j] == got[j-1]+1) {
+			j++
+		}
+		for k := j; k < len(got); k++ {
+			if got[k] < 100 || got[k] >= 200 || (k > j && got[k] != got[k-1]+1) || got[len(got)-1] != 109 {
+				t.Fatalf("two-point child %v is not a[:i]+b[k:l]+a[j:]", got)
+			}
+		}
+	}
+
+	ga.Mode = CrossoverUniform
+	fromB := 0
+	for trial := 0; trial < 200; trial++ {
+		got := tags(ga.breed(a, b))
+		if len(got) != 10 {
+			t.Fatalf("uniform child %v should have 10 instructions", got)
+		}
+		for i, tag := range got {
+			if tag != 100+i && tag != 200+i {
+				t.Fatalf("uniform child %v: instruction %d not from position %d of a parent", got, i, i)
+			}
+			if tag >= 200 {
+				fromB++
+			}
+		}
+	}
+	if fromB < 800 || fromB > 1200 {
+		t.Errorf("uniform crossover took %d of 2000 instructions from b, want about half", fromB)
+	}
+}
+
+func TestGenomeDiversityAdaptsMutation(t *testing.T) {
+	clone := []byte{micro.SmallNumOp(1), micro.OpDup, micro.OpAdd, micro.OpHalt}
+	clones := make([]*NPC, 6)
+	for i := range clones {
+		clones[i] = NewNPC(append([]byte(nil), clone...))
+	}
+	if d := GenomeDiversity(clones); d != 0 {
+		t.Errorf("clones diversity = %v, want 0", d)
+	}
+	distinct := []*NPC{
+		NewNPC([]byte{micro.SmallNumOp(1), micro.SmallNumOp(2)}),
+		NewNPC([]byte{micro.OpDup, micro.OpAdd}),
+	}
+	if d := GenomeDiversity(distinct); d != 1 {
+		t.Errorf("disjoint diversity = %v, want 1", d)
+	}
+	if d := tokenDistance(genomeTokens(clone), genomeTokens(clone[:2])); d != 0.5 {
+		t.Errorf("prefix distance = %v, want 0.5", d)
+	}
+
+	ga := NewGA(testRng())
+	ga.DiversityTarget = 0.4
+	ga.Evolve(clones)
+	if ga.Diversity != 0 {
+		t.Fatalf("Evolve measured diversity %v, want 0", ga.Diversity)
+	}
+	if got, want := ga.EffectiveMutation(), ga.MutationRate*(1+maxMutationBoost); got != want {
+		t.Errorf("collapsed population mutation = %v, want %v", got, want)
+	}
+	ga.Diversity = 0.2
+	if got, want := ga.EffectiveMutation(), ga.MutationRate*(1+maxMutationBoost/2.0); got != want {
+		t.Errorf("half-collapsed mutation = %v, want %v", got, want)
+	}
+	ga.Diversity = 0.5
+	if got := ga.EffectiveMutation(); got != ga.MutationRate {
+		t.Errorf("diverse population mutation = %v, want base %v", got, ga.MutationRate)
+	}
+}
+
+func TestGenomeCensusAndClonePenalty(t *testing.T) {
+	clone := []byte{micro.SmallNumOp(1), micro.OpDup, micro.OpAdd, micro.OpHalt}
+	near := []byte{micro.SmallNumOp(1), micro.OpDup, micro.OpSub, micro.OpHalt}
+	population := func() []*NPC {
+		var npcs []*NPC
+		for i := 0; i < 6; i++ {
+			npc := NewNPC(append([]byte(nil), clone...))
+			npc.Fitness, npc.Age = 100-i, 5
+			npcs = append(npcs, npc)
+		}
+		for i, g := range [][]byte{near, {micro.OpPrint, micro.OpYield}} {
+			npc := NewNPC(g)
+			npc.Fitness, npc.Age = 50-10*i, 5
+			npcs = append(npcs, npc)
+		}
+		return npcs
+	}
+
+	c := CensusGenomes(population())
+	if c.Unique != 3 || c.Largest != 6 {
+		t.Errorf("census unique=%d largest=%d, want 3 and 6", c.Unique, c.Largest)
+	}
+	if c.Simpson != 1-38.0/64 || c.Shannon <= 0 {
+		t.Errorf("census simpson=%v shannon=%v, want %v and > 0", c.Simpson, c.Shannon, 1-38.0/64)
+	}
+	if c := CensusGenomes(population()[:6]); c.Unique != 1 || c.Shannon != 0 || c.Simpson != 0 {
+		t.Errorf("monoculture census = %+v", c)
+	}
+
+	survivors := func(npcs []*NPC) (clones, others int) {
+		for _, npc := range npcs {
+			if npc.Age == 0 {
+				continue
+			}
+			if bytes.Equal(npc.Genome, clone) {
+				clones++
+			} else {
+				others++
+				if npc.Fitness != 50 && npc.Fitness != 40 {
+					t.Errorf("survivor fitness %d not restored", npc.Fitness)
+				}
+			}
+		}
+		return clones, others
+	}
+	ga := NewGA(testRng())
+	ga.MutationRate = 0
+	if c, o := survivors(ga.Evolve(population())); c != 6 || o != 0 {
+		t.Errorf("without penalty %d clones and %d others survive, want 6 and 0", c, o)
+	}
+	ga.ClonePenalty = 0.9
+	if c, o := survivors(ga.Evolve(population())); c != 4 || o != 2 || ga.Clones != 5 {
+		t.Errorf("with penalty %d clones and %d others survive (%d penalized), want 4, 2 (5)", c, o, ga.Clones)
+	}
+	ga.CloneDistance = 0.3
+	ga.Evolve(population())
+	if ga.Clones != 6 {
+		t.Errorf("with clone distance %d penalized, want 6 (near copy included)", ga.Clones)
+	}
+}
+
+func TestDemographics(t *testing.T) {
+	npc := func(age, genome int, deed func(n *NPC)) *NPC {
+		n := NewNPC(make([]byte, genome))
+		n.Age = age
+		if deed != nil {
+			deed(n)
+		}
+		return n
+	}
+	dead := npc(10, 10, nil)
+	dead.Health = 0
+	npcs := []*NPC{
+		npc(0, 8, nil),
+		npc(999, 16, func(n *NPC) { n.FoodEaten = 3 }),
+		npc(1000, 40, func(n *NPC) { n.FoodEaten, n.Trades = 3, 1 }),
+		npc(MaxAge+10, 200, func(n *NPC) { n.Trades, n.Kills = 2, 1 }),
+		npc(2500, 64, func(n *NPC) { n.Predator = true }),
+		dead,
+	}
+	d := CensusDemographics(npcs)
+	if want := [AgeBands]int{2, 1, 1, 0, 1}; d.Ages != want {
+		t.Errorf("Ages = %v, want %v", d.Ages, want)
+	}
+	if want := [len(GenomeBandLimits) + 1]int{1, 1, 1, 1, 1}; d.Genomes != want {
+		t.Errorf("Genomes = %v, want %v", d.Genomes, want)
+	}
+	want := [BehaviorCount]int{}
+	want[BehaviorIdle], want[BehaviorForager], want[BehaviorTrader] = 1, 1, 1
+	want[BehaviorFighter], want[BehaviorPredator] = 1, 1
+	if d.Behaviors != want {
+		t.Errorf("Behaviors = %v, want %v", d.Behaviors, want)
+	}
+	if labels, values := d.Labels(), d.Values(); len(labels) != len(values) || labels[0] != "age<1000" || labels[len(labels)-1] != "predator" {
+		t.Errorf("Labels %v do not match Values %v", labels, values)
+	}
+}
+
+func TestGAParsimony(t *testing.T) {
+	short := []byte{micro.SmallNumOp(1), micro.OpPrint, micro.OpHalt}
+	long := bytes.Repeat([]byte{micro.OpDup, micro.OpDrop}, 20)
+	population := func(longBonus int) ([]*NPC, map[*NPC]bool) {
+		var npcs []*NPC
+		isLong := make(map[*NPC]bool)
+		for i := 0; i < 4; i++ {
+			s := NewNPC(append([]byte(nil), short...))
+			l := NewNPC(append([]byte(nil), long...))
+			s.Fitness, s.Age = 100, 5
+			l.Fitness, l.Age = 100+longBonus, 5
+			isLong[l] = true
+			npcs = append(npcs, s, l)
+		}
+		return npcs, isLong
+	}
+	culledLong := func(npcs []*NPC, isLong map[*NPC]bool) int {
+		n := 0
+		for _, npc := range npcs {
+			if npc.Age == 0 && isLong[npc] {
+				n++
+			}
+		}
+		return n
+	}
+
+	ga := NewGA(testRng())
+	ga.MutationRate = 0
+	npcs, isLong := population(20)
+	if n := culledLong(ga.Evolve(npcs), isLong); n != 0 {
+		t.Errorf("without parsimony %d fitter long genomes culled, want 0", n)
+	}
+	ga.ShorterFirst = true
+	npcs, isLong = population(0)
+	if n := culledLong(ga.Evolve(npcs), isLong); n != 2 {
+		t.Errorf("shorter-first tie-break culled %d long genomes, want 2", n)
+	}
+	ga.ShorterFirst = false
+	ga.LengthPenalty = 1
+	npcs, isLong = population(20)
+	if n := culledLong(ga.Evolve(npcs), isLong); n != 2 {
+		t.Errorf("length penalty culled %d long genomes, want 2", n)
+	}
+	for _, npc := range npcs {
+		if npc.Age != 0 && npc.Fitness != 100 && npc.Fitness != 120 {
+			t.Errorf("survivor fitness %d not restored after the length penalty", npc.Fitness)
+		}
+	}
+}
+
+func TestGAStructuralOperators(t *testing.T) {
+	a, err := CompileBrain("[food-dist 2 <] [eat set-action yield] [food-dir set-move yield] ifte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CompileBrain("[near-dist 1 =] [attack set-action near-id set-target yield] [[rng 2 <] [north set-move] [east set-move] ifte] ifte")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(assemble(decode(b), MaxGenome), b) || BrokenJumps(b) != 0 {
+		t.Fatal("decode/assemble should round-trip a compiled brain")
+	}
+
+	// Dropping an instruction the jz skips shortens the jump
+	prog := decode(a)
+	jz := -1
+	for i, in := range prog {
+		if in.jump && jz < 0 {
+			jz = i
+		}
+	}
+	if jz < 0 || jz+1 >= len(prog) {
+		t.Fatalf("no jump in % x", a)
+	}
+	dropped := len(prog[jz+1].code)
+	fixed := assemble(append(prog[:jz+1:jz+1], prog[jz+2:]...), MaxGenome)
+	if pc := OpcodeAlignedPoints(a)[jz]; int(fixed[pc+1]) != int(a[pc+1])-dropped || BrokenJumps(fixed) != 0 {
+		t.Errorf("jz offset %d after dropping %d bytes, was %d", fixed[pc+1], dropped, a[pc+1])
+	}
+
+	broken := func(structural bool) int {
+		ga := NewGA(testRng())
+		ga.Mode = CrossoverClassic
+		ga.Structural = structural
+		n := 0
+		for i := 0; i < 500; i++ {
+			n += BrokenJumps(ga.breed(a, b))
+		}
+		return n
+	}
+	if n := broken(true); n != 0 {
+		t.Errorf("structural operators broke %d jumps", n)
+	}
+	if n := broken(false); n == 0 {
+		t.Error("naive operators should break some jumps")
+	}
+}
+
+func TestGAElitism(t *testing.T) {
+	ga := NewGA(testRng())
+	ga.Elitism = 2
+	npcs := make([]*NPC, 8)
+	for i := range npcs {
+		npcs[i] = NewNPC(ga.RandomGenome(24))
+		npcs[i].ID = uint16(i + 1)
+		npcs[i].Fitness = 100 - i
+		npcs[i].Age = 50
+		npcs[i].Gold = 40
+	}
+	champ, runnerUp := npcs[0], npcs[1]
+	champ.Age = MaxAge
+	champGenome := append([]byte(nil), champ.Genome...)
+	runnerGenome := append([]byte(nil), runnerUp.Genome...)
+
+	ga.Evolve(npcs)
+	if !bytes.Equal(champ.Genome, champGenome) || champ.Age != 0 || champ.Gold != 20 {
+		t.Errorf("aged-out elite should be cloned into a fresh body: age=%d gold=%d genome changed=%v",
+			champ.Age, champ.Gold, !bytes.Equal(champ.Genome, champGenome))
+	}
+	if champ.Parents != [2]uint16{} {
+		t.Errorf("clone lineage = %v, want unchanged", champ.Parents)
+	}
+	if !bytes.Equal(runnerUp.Genome, runnerGenome) || runnerUp.Age != 50 {
+		t.Error("living elite should be untouched")
+	}
+	if npcs[7].Age != 0 {
+		t.Error("bottom NPC should still be replaced")
+	}
+
+	// Elites are protected even where they would rank among the victims
+	ga.Elitism = 4
+	small := npcs[:4]
+	for _, npc := range small {
+		npc.Age = 10
+	}
+	before := append([]byte(nil), small[3].Genome...)
+	ga.Evolve(small)
+	if !bytes.Equal(small[3].Genome, before) || small[3].Age != 10 {
+		t.Error("elite in the bottom quarter was replaced")
+	}
+}
+
+func TestGASpeciation(t *testing.T) {
+	lineA := []byte{micro.SmallNumOp(1), micro.OpDup, micro.OpAdd, micro.OpDup, micro.OpAdd, micro.OpHalt}
+	lineB := []byte{micro.SmallNumOp(3), micro.OpSub, micro.OpPrint, micro.OpSwap, micro.OpDrop, micro.OpYield}
+	population := func() []*NPC {
+		var npcs []*NPC
+		for i := 0; i < 10; i++ { // a dominant lineage
+			npc := NewNPC(append([]byte(nil), lineA...))
+			npc.Fitness, npc.Age = 100, 5
+			npcs = append(npcs, npc)
+		}
+		for i := 0; i < 4; i++ { // a weak minority
+			npc := NewNPC(append([]byte(nil), lineB...))
+			npc.Fitness, npc.Age = 10+i, 5
+			npcs = append(npcs, npc)
+		}
+		return npcs
+	}
+	countB := func(npcs []*NPC) int {
+		n := 0
+		for _, npc := range npcs {
+			if bytes.HasPrefix(npc.Genome, lineB) {
+				n++
+			}
+		}
+		return n
+	}
+
+	ga := NewGA(testRng())
+	ga.MutationRate = 0
+	ga.Mode = CrossoverUniform // a lineage bred with itself stays itself
+	if n := countB(ga.Evolve(population())); n != 1 {
+		t.Fatalf("without speciation %d minority genomes survive, want 1", n)
+	}
+
+	ga.SpeciesThreshold = 0.5
+	npcs := ga.Evolve(population())
+	if ga.Species != 2 {
+		t.Errorf("found %d species, want 2", ga.Species)
+	}
+	if n := countB(npcs); n < 2 {
+		t.Errorf("with speciation %d minority genomes, want the survivor plus offspring", n)
+	}
+	for _, npc := range npcs {
+		if npc.Age != 0 && npc.Fitness != 100 && npc.Fitness != 13 {
+			t.Errorf("survivor fitness %d not restored after sharing", npc.Fitness)
+		}
+	}
+}
+
+func TestArchipelagoMigrate(t *testing.T) {
+	islands := func() []*World {
+		var ws []*World
+		for i := 0; i < 3; i++ {
+			w := NewWorld(16, testRng())
+			for j := 0; j < 4; j++ {
+				npc := NewNPC([]byte{micro.SmallNumOp(i), micro.OpPrint, micro.OpHalt})
+				npc.X, npc.Y = j, 0
+				npc.Fitness, npc.Age, npc.Gold = 10*j, 50, 7
+				w.Spawn(npc)
+			}
+			ws = append(ws, w)
+		}
+		return ws
+	}
+	native := func(w *World, i int) int {
+		n := 0
+		for _, npc := range w.NPCs {
+			if npc.Genome[0] == micro.SmallNumOp(i) {
+				n++
+			}
+		}
+		return n
+	}
+
+	a := &Archipelago{Islands: islands(), Migrants: 2, Rng: testRng()}
+	if moved := a.Migrate(); moved != 6 {
+		t.Fatalf("moved %d genomes, want 6", moved)
+	}
+	for i, w := range a.Islands {
+		from := (i + 2) % 3
+		if native(w, i) != 2 || native(w, from) != 2 {
+			t.Errorf("island %d: %d native, %d from island %d; want 2 and 2", i, native(w, i), native(w, from), from)
+		}
+		for _, npc := range w.NPCs {
+			immigrant := npc.Genome[0] != micro.SmallNumOp(i)
+			if immigrant != (npc.Fitness < 20) && npc.Age != 0 {
+				t.Errorf("island %d: NPC with fitness %d replaced=%v", i, npc.Fitness, immigrant)
+			}
+			if immigrant && (npc.Age != 0 || npc.Gold != 0 || npc.Health != 100) {
+				t.Errorf("island %d: immigrant not newborn (age %d, gold %d, health %d)", i, npc.Age, npc.Gold, npc.Health)
+			}
+		}
+	}
+
+	a = &Archipelago{Islands: islands(), Migrants: 3, Topology: TopologyRandom, Rng: testRng()}
+	moved := a.Migrate()
+	arrived := 0
+	for i, w := range a.Islands {
+		arrived += len(w.NPCs) - native(w, i)
+	}
+	if moved == 0 || moved > 9 || arrived != moved {
+		t.Errorf("random topology moved %d genomes, %d arrived", moved, arrived)
+	}
+}
+
 func TestGAMutationPreservesSize(t *testing.T) {
 	ga := NewGA(testRng())
 
@@ -135,6 +596,55 @@ func TestGAMutationPreservesSize(t *testing.T) {
 	}
 }
 
+func TestGASelectionStrategies(t *testing.T) {
+	// Pool sorted best first: fitness 10, 9, ..., 1
+	pool := make([]*NPC, 10)
+	for i := range pool {
+		pool[i] = &NPC{Fitness: 10 - i}
+	}
+	const draws = 20000
+	share := func(ga *GA) (best, worst float64) {
+		counts := map[*NPC]int{}
+		for i := 0; i < draws; i++ {
+			counts[ga.selectParent(pool)]++
+		}
+		return float64(counts[pool[0]]) / draws, float64(counts[pool[9]]) / draws
+	}
+	near := func(name string, got, want float64) {
+		if got < want-0.02 || got > want+0.02 {
+			t.Errorf("%s: share %.3f, want about %.3f", name, got, want)
+		}
+	}
+
+	ga := NewGA(testRng())
+	best, worst := share(ga)
+	near("tournament(3) best", best, 1-0.9*0.9*0.9)
+	near("tournament(3) worst", worst, 0.001)
+
+	ga.TournamentSize = 1
+	best, _ = share(ga)
+	near("tournament(1) best", best, 0.1)
+
+	ga.TournamentSize = 0
+	ga.Selection = SelectRoulette
+	best, worst = share(ga) // weights fitness-1+1 = 10..1 of 55
+	near("roulette best", best, 10.0/55)
+	near("roulette worst", worst, 1.0/55)
+
+	for i := range pool {
+		pool[i].Fitness = 1000 - i // rank ignores the scale of fitness
+	}
+	ga.Selection = SelectRank
+	best, worst = share(ga)
+	near("rank best", best, 10.0/55)
+	near("rank worst", worst, 1.0/55)
+
+	ga.Selection = SelectTruncation
+	best, worst = share(ga)
+	near("truncation best", best, 0.1)
+	near("truncation worst", worst, 0.1)
+}
+
 func Test100TickSimulation(t *testing.T) {
 	rng := testRng()
 	w := NewWorld(16, rng)
@@ -1754,6 +2264,61 @@ func TestAgedNPCReplacedInEvolve(t *testing.T) {
 	}
 }
 
+func TestSwapGenomeFreezes(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	var swaps []GenomeSwapped
+	s.Subscribe(SubscriberFunc(func(ev Event) {
+		if e, ok := ev.(GenomeSwapped); ok {
+			swaps = append(swaps, e)
+		}
+	}))
+
+	ga := NewGA(testRng())
+	npcs := make([]*NPC, 8)
+	for i := range npcs {
+		npcs[i] = NewNPC(ga.RandomGenome(24))
+		spawnAt(w, npcs[i], i, 0)
+		npcs[i].Fitness = (i + 1) * 100
+	}
+	// The least fit NPC would be culled first
+	challenger := []byte{micro.OpActMove, DirEast, micro.OpHalt}
+	weakest := npcs[0]
+	s.VM(weakest).MemWrite(200, 7)
+	if err := s.SwapGenome(weakest.ID, challenger, true); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(weakest.Genome, challenger) || !weakest.Frozen || s.VM(weakest).MemRead(200) != 0 {
+		t.Fatalf("after swap: genome=%x frozen=%v mem=%d, want the challenger, frozen, fresh memory",
+			weakest.Genome, weakest.Frozen, s.VM(weakest).MemRead(200))
+	}
+	if len(swaps) != 1 || swaps[0].NPC != weakest.ID || !swaps[0].Frozen {
+		t.Errorf("events = %+v, want one frozen GenomeSwapped", swaps)
+	}
+
+	ga.Evolve(npcs)
+	if !bytes.Equal(weakest.Genome, challenger) || weakest.Fitness != 100 {
+		t.Errorf("frozen NPC was replaced by the GA: genome=%x fitness=%d", weakest.Genome, weakest.Fitness)
+	}
+	if npcs[1].Fitness != 0 {
+		t.Errorf("the other unfit NPC should still be culled, fitness=%d", npcs[1].Fitness)
+	}
+
+	// Lessons don't take on a frozen genome
+	teacher := npcs[7]
+	teacher.Fitness = 100000
+	for i := 0; i < 20; i++ {
+		s.memeticTransfer(teacher, weakest)
+	}
+	if weakest.Taught != 0 {
+		t.Errorf("frozen NPC was taught %d times", weakest.Taught)
+	}
+
+	if err := s.SwapGenome(999, challenger, false); err == nil {
+		t.Error("swapping an unknown NPC should fail")
+	}
+}
+
 func TestScaling100NPCs(t *testing.T) {
 	rng := rand.New(rand.NewSource(42))
 	ws := AutoWorldSize(100) // should be ~40
@@ -2141,3 +2706,2934 @@ func TestActionOpcodeBackwardCompat(t *testing.T) {
 	s.Tick()
 	t.Log("backward compat: old-style genome executed OK")
 }
+
+// === Recipe Meme Tests ===
+
+func TestRecipeMemesUnknownBlocksCraft(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.RecipeMemes = true
+
+	npc := NewNPC([]byte{micro.OpHalt})
+	npc.Item = ItemTool
+	if _, ok := s.recipeFor(npc, false); ok {
+		t.Error("unknown recipe should not be craftable off-forge")
+	}
+
+	npc.Recipes = RecipeCompass
+	out, ok := s.recipeFor(npc, false)
+	if !ok || out != ItemCompass {
+		t.Errorf("known recipe should craft compass: got %d ok=%v", out, ok)
+	}
+
+	s.RecipeMemes = false
+	npc.Recipes = 0
+	if _, ok := s.recipeFor(npc, false); !ok {
+		t.Error("with recipe memes off every recipe should be usable")
+	}
+}
+
+func TestRecipeMemesTaught(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.RecipeMemes = true
+
+	teacher := NewNPC([]byte{micro.OpHalt})
+	teacher.Recipes = RecipeCompass | RecipeShield
+	student := NewNPC([]byte{micro.OpHalt})
+
+	s.teachRecipe(teacher, student)
+	if student.Recipes == 0 || student.Recipes&^teacher.Recipes != 0 {
+		t.Errorf("student should learn one of the teacher's recipes: got %b", student.Recipes)
+	}
+	s.teachRecipe(teacher, student)
+	if student.Recipes != teacher.Recipes {
+		t.Errorf("second lesson should complete the set: got %b", student.Recipes)
+	}
+	if s.RecipesLearned != 2 {
+		t.Errorf("RecipesLearned = %d, want 2", s.RecipesLearned)
+	}
+}
+
+func TestRecipeMemesForgeDiscovery(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.RecipeMemes = true
+
+	npc := NewNPC([]byte{micro.OpHalt})
+	npc.Item = ItemWeapon
+	for i := 0; i < 200 && npc.Recipes == 0; i++ {
+		s.recipeFor(npc, true)
+	}
+	if npc.Recipes != RecipeShield {
+		t.Errorf("repeated forge attempts should discover the shield recipe: got %b", npc.Recipes)
+	}
+}
+
+func TestRecipeInheritance(t *testing.T) {
+	ga := NewGA(testRng())
+	if r := ga.inheritRecipes(0, 0); r != 0 {
+		t.Errorf("no parental knowledge should inherit nothing: got %b", r)
+	}
+	seen := byte(0)
+	for i := 0; i < 100; i++ {
+		r := ga.inheritRecipes(RecipeCompass, RecipeShield)
+		if r&^(RecipeCompass|RecipeShield) != 0 {
+			t.Fatalf("inherited unknown bits: %b", r)
+		}
+		seen |= r
+	}
+	if seen != RecipeCompass|RecipeShield {
+		t.Errorf("both parental recipes should be inheritable: got %b", seen)
+	}
+}
+
+// === Building Tests ===
+
+func TestBuildShelterConsumesItem(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	npc := NewNPC([]byte{micro.OpActBuild, BuildShelter, micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	w.SetTile(npc.X, npc.Y, MakeTile(TileEmpty))
+	npc.Item = ItemTool
+	npc.Energy = 100
+
+	s.Tick()
+
+	if got := w.TileAt(npc.X, npc.Y).Type(); got != TileShelter {
+		t.Fatalf("tile = %d, want TileShelter", got)
+	}
+	if npc.Item != ItemNone {
+		t.Errorf("building should consume the held item: got %d", npc.Item)
+	}
+	if npc.Energy > 100-buildCost {
+		t.Errorf("building should cost energy: got %d", npc.Energy)
+	}
+	if s.BuildCount != 1 {
+		t.Errorf("BuildCount = %d, want 1", s.BuildCount)
+	}
+}
+
+func TestBuildRequiresMaterial(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	npc := NewNPC([]byte{micro.OpActBuild, BuildWall, micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	w.SetTile(npc.X, npc.Y, MakeTile(TileEmpty))
+	npc.Energy = 100
+
+	s.Tick()
+
+	if got := w.TileAt(npc.X, npc.Y).Type(); got != TileEmpty {
+		t.Errorf("empty-handed NPC should not build: tile = %d", got)
+	}
+	if s.BuildCount != 0 {
+		t.Errorf("BuildCount = %d, want 0", s.BuildCount)
+	}
+}
+
+func TestBuiltWallBlocksMovement(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	for y := 2; y <= 5; y++ {
+		w.SetTile(5, y, MakeTile(TileEmpty))
+	}
+	builder := NewNPC([]byte{micro.OpActBuild, BuildWall, micro.OpHalt})
+	spawnAt(w, builder, 5, 4)
+	builder.Item = ItemWeapon
+	builder.Energy = 100
+
+	s.Tick()
+	if w.TileAt(5, 4).Type() != TileWall {
+		t.Fatal("builder should have placed a wall")
+	}
+	builder.Health = 0
+	s.Tick() // builder removed, wall stays
+
+	walker := NewNPC([]byte{micro.OpActMove, DirSouth, micro.OpHalt})
+	spawnAt(w, walker, 5, 3)
+	s.Tick()
+
+	if w.TileAt(5, 4).Type() != TileWall {
+		t.Fatal("wall should persist after builder leaves")
+	}
+	if walker.Y != 3 {
+		t.Errorf("walker should not enter a built wall: Y=%d", walker.Y)
+	}
+}
+
+func TestStructureSurvivesDeath(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	w.SetTile(npc.X, npc.Y, MakeTile(TileChest))
+	npc.Item = ItemTool
+	npc.Health = 0
+
+	s.Tick()
+
+	if got := w.TileAt(5, 5).Type(); got != TileChest {
+		t.Errorf("dead NPC should not erase a structure: tile = %d", got)
+	}
+}
+
+// === Chest Tests ===
+
+// placeChest builds a chest owned by owner at (x,y) holding items.
+func placeChest(w *World, x, y int, owner uint16, items ...byte) *Chest {
+	w.SetTile(x, y, MakeTile(TileChest))
+	c := &Chest{Owner: owner, Items: items}
+	w.Chests[w.idx(x, y)] = c
+	return c
+}
+
+func TestChestDepositWithdraw(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	npc := NewNPC([]byte{micro.OpActDeposit, 0x00, micro.OpHalt})
+	w.SetTile(5, 5, MakeTile(TileEmpty))
+	spawnAt(w, npc, 5, 5)
+	c := placeChest(w, npc.X, npc.Y, npc.ID)
+	npc.Item = ItemWeapon
+	grantItemModifier(npc, npc.Item)
+
+	s.Tick()
+	if npc.Item != ItemNone || len(c.Items) != 1 || c.Items[0] != ItemWeapon {
+		t.Fatalf("deposit failed: item=%d chest=%v", npc.Item, c.Items)
+	}
+	if npc.ModSum(ModAttack) != 0 {
+		t.Error("stored weapon should no longer grant its modifier")
+	}
+
+	npc.Genome = []byte{micro.OpActWithdraw, 0x00, micro.OpHalt}
+	s.Tick()
+	if npc.Item != ItemWeapon || len(c.Items) != 0 {
+		t.Errorf("withdraw failed: item=%d chest=%v", npc.Item, c.Items)
+	}
+}
+
+func TestChestOwnershipRespected(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	thief := NewNPC([]byte{micro.OpActWithdraw, 0x00, micro.OpHalt})
+	w.SetTile(5, 5, MakeTile(TileEmpty))
+	spawnAt(w, thief, 5, 5)
+	c := placeChest(w, thief.X, thief.Y, thief.ID+100, ItemTreasure)
+
+	s.Tick()
+	if thief.Item != ItemNone || len(c.Items) != 1 {
+		t.Errorf("non-owner should not withdraw: item=%d chest=%v", thief.Item, c.Items)
+	}
+}
+
+func TestChestRaidedByAttack(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	raider := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	w.SetTile(5, 5, MakeTile(TileEmpty))
+	spawnAt(w, raider, 5, 5)
+	raider.Energy = 100
+	c := placeChest(w, raider.X, raider.Y, raider.ID+100, ItemTool, ItemTreasure)
+
+	s.Tick()
+	if raider.Item != ItemTreasure || len(c.Items) != 1 {
+		t.Errorf("raid should steal the top item: item=%d chest=%v", raider.Item, c.Items)
+	}
+	if s.RaidCount != 1 {
+		t.Errorf("RaidCount = %d, want 1", s.RaidCount)
+	}
+}
+
+func TestChestDestroyedWithTile(t *testing.T) {
+	w := NewWorld(16, testRng())
+	placeChest(w, 3, 3, 1, ItemTool)
+	w.SetTile(3, 3, MakeTile(TileEmpty))
+	if w.ChestAt(3, 3) != nil {
+		t.Error("clearing the tile should remove the chest")
+	}
+}
+
+// === Ranged Attack Tests ===
+
+// clearColumn empties tiles (x, y0..y1) so shots and spawns are unobstructed.
+func clearColumn(w *World, x, y0, y1 int) {
+	for y := y0; y <= y1; y++ {
+		w.SetTile(x, y, MakeTile(TileEmpty))
+	}
+}
+
+func TestShootHitsAdjacent(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	clearColumn(w, 5, 2, 8)
+
+	shooter := NewNPC([]byte{micro.OpActShoot, DirSouth, micro.OpHalt})
+	spawnAt(w, shooter, 5, 5)
+	shooter.Item = ItemWeapon
+	shooter.Energy = 100
+
+	target := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, target, 5, 6)
+	hp := target.Health
+
+	s.Tick()
+
+	if target.Health >= hp {
+		t.Errorf("adjacent shot should always hit: before=%d after=%d", hp, target.Health)
+	}
+	if s.ShotCount != 1 || s.ShotHits != 1 {
+		t.Errorf("shots=%d hits=%d, want 1/1", s.ShotCount, s.ShotHits)
+	}
+}
+
+func TestShootRequiresWeapon(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	clearColumn(w, 5, 2, 8)
+
+	shooter := NewNPC([]byte{micro.OpActShoot, DirSouth, micro.OpHalt})
+	spawnAt(w, shooter, 5, 5)
+	shooter.Energy = 100
+
+	target := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, target, 5, 6)
+
+	s.Tick()
+
+	if s.ShotCount != 0 {
+		t.Errorf("unarmed NPC should not shoot: shots=%d", s.ShotCount)
+	}
+}
+
+func TestShootBlockedByWallAndRange(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.ShootRange = 3
+	clearColumn(w, 5, 1, 12)
+
+	shooter := NewNPC([]byte{micro.OpActShoot, DirSouth, micro.OpHalt})
+	spawnAt(w, shooter, 5, 5)
+	shooter.Item = ItemWeapon
+	shooter.Energy = 200
+
+	beyond := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, beyond, 5, 9) // distance 4, out of range
+
+	s.Tick()
+	if beyond.IncomingFire != 0 {
+		t.Error("target beyond range should not be fired upon")
+	}
+
+	w.SetTile(5, 6, MakeTile(TileWall))
+	behind := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, behind, 5, 7)
+	s.Tick()
+	if behind.IncomingFire != 0 {
+		t.Error("wall should stop the shot")
+	}
+}
+
+func TestIncomingFireSensor(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	clearColumn(w, 5, 2, 8)
+
+	target := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, target, 5, 6)
+
+	shooter := NewNPC([]byte{micro.OpActShoot, DirSouth, micro.OpHalt})
+	spawnAt(w, shooter, 5, 5)
+	shooter.Item = ItemWeapon
+	shooter.Energy = 100
+
+	s.Tick() // target acts first, then is shot
+	s.sense(target)
+	if got := s.VM(target).MemRead(Ring0IncomingFire); got != DirNorth {
+		t.Fatalf("incoming fire sensor = %d, want DirNorth", got)
+	}
+
+	shooter.Genome = []byte{micro.OpHalt}
+	s.Tick()
+	if target.IncomingFire != 0 {
+		t.Errorf("incoming fire should clear after a quiet tick: got %d", target.IncomingFire)
+	}
+}
+
+// === Damage Type Tests ===
+
+func TestDamageResistances(t *testing.T) {
+	npc := NewNPC([]byte{micro.OpHalt})
+	npc.Item = ItemShield
+	grantItemModifier(npc, npc.Item)
+
+	if got := npc.TakeDamage(15, DamagePhysical); got != 10 {
+		t.Errorf("shield should block 5 physical: took %d", got)
+	}
+	if got := npc.TakeDamage(15, DamagePoison); got != 15 {
+		t.Errorf("shield should not block poison: took %d", got)
+	}
+	if got := npc.TakeDamage(3, DamagePhysical); got != 1 {
+		t.Errorf("damage should never drop below 1: took %d", got)
+	}
+
+	removeItemModifier(npc, npc.Item)
+	npc.Item = ItemCharm
+	grantItemModifier(npc, npc.Item)
+	if got := npc.TakeDamage(15, DamagePoison); got != 5 {
+		t.Errorf("charm should resist 10 poison: took %d", got)
+	}
+	if got := npc.TakeDamage(15, DamageFire); got != 15 {
+		t.Errorf("nothing resists fire: took %d", got)
+	}
+}
+
+func TestSwampHarvestYieldsCharm(t *testing.T) {
+	w := NewWorldWithBiomes(32, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	swamp := -1
+	for i, b := range w.BiomeGrid {
+		if b == BiomeSwamp {
+			swamp = i
+			break
+		}
+	}
+	if swamp < 0 {
+		t.Skip("no swamp biome generated")
+	}
+	npc := NewNPC([]byte{micro.OpHalt})
+	npc.X, npc.Y = swamp%w.Size, swamp/w.Size
+
+	for i := 0; i < 200 && npc.Item == ItemNone; i++ {
+		npc.Energy, npc.Health = 100, 100
+		w.Cooldowns[swamp] = 0
+		s.harvest(npc)
+	}
+	if npc.Item != ItemCharm {
+		t.Fatalf("swamp harvesting should eventually yield a charm: item=%d", npc.Item)
+	}
+	if npc.ModSum(ModResistPoison) != 10 {
+		t.Errorf("charm should grant poison resistance: got %d", npc.ModSum(ModResistPoison))
+	}
+}
+
+func TestForgeStrikeIsFire(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	attacker := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	spawnAt(w, attacker, 5, 5)
+	w.SetTile(attacker.X, attacker.Y, MakeTile(TileForge))
+	attacker.Energy = 100
+
+	victim := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, victim, attacker.X, attacker.Y-1)
+	victim.Item = ItemShield
+	grantItemModifier(victim, victim.Item)
+	hp := victim.Health
+
+	s.Tick()
+
+	if lost := hp - victim.Health; lost != 5 {
+		t.Errorf("shield should not block forge fire: lost %d, want 5", lost)
+	}
+}
+
+// === Trust Tests ===
+
+func TestTrustTableBounded(t *testing.T) {
+	npc := NewNPC([]byte{micro.OpHalt})
+	npc.ID = 1
+	npc.AdjustTrust(2, 50)
+	npc.AdjustTrust(2, 80)
+	if got := npc.TrustOf(2); got != 100 {
+		t.Errorf("trust should clamp at 100: got %d", got)
+	}
+	for id := uint16(3); id < 3+RelationSlots; id++ {
+		npc.AdjustTrust(id, 1)
+	}
+	if got := npc.TrustOf(2); got != 100 {
+		t.Errorf("strong relationship should survive eviction: got %d", got)
+	}
+	if got := npc.TrustOf(3); got != 0 {
+		t.Errorf("weakest relationship should be forgotten: got %d", got)
+	}
+	npc.AdjustTrust(npc.ID, 10)
+	if got := npc.TrustOf(npc.ID); got != 0 {
+		t.Errorf("self-trust should not be recorded: got %d", got)
+	}
+}
+
+func TestAttackLowersTrust(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	attacker := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	spawnAt(w, attacker, 5, 5)
+	attacker.Energy = 100
+	victim := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, victim, 5, 4)
+
+	s.Tick()
+
+	if got := victim.TrustOf(attacker.ID); got != TrustAttack {
+		t.Errorf("victim trust toward attacker = %d, want %d", got, TrustAttack)
+	}
+	s.sense(victim)
+	if got := s.VM(victim).MemRead(Ring0NearTrust); int(got) != TrustAttack {
+		t.Errorf("near-trust sensor = %d, want %d", got, TrustAttack)
+	}
+}
+
+func TestTrustModulatesTradeGold(t *testing.T) {
+	trade := func(trust int) int {
+		w := NewWorld(16, testRng())
+		s := NewScheduler(w, 200, io.Discard)
+		a := NewNPC([]byte{micro.OpHalt})
+		spawnAt(w, a, 5, 5)
+		b := NewNPC([]byte{micro.OpHalt})
+		spawnAt(w, b, 5, 4)
+		a.Item, b.Item = ItemTool, ItemTool
+		a.AdjustTrust(b.ID, trust)
+		b.AdjustTrust(a.ID, trust)
+		s.tradeIntents[a.ID] = b.ID
+		s.tradeIntents[b.ID] = a.ID
+		s.resolveTrades()
+		if s.TradeCount != 1 {
+			t.Fatalf("trade did not resolve")
+		}
+		return a.Gold + b.Gold
+	}
+	neutral, trusted, hostile := trade(0), trade(100), trade(-100)
+	if trusted <= neutral || hostile >= neutral {
+		t.Errorf("gold should rise with trust: hostile=%d neutral=%d trusted=%d", hostile, neutral, trusted)
+	}
+}
+
+// === Gift Tests ===
+
+func TestGiveItem(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	giver := NewNPC([]byte{micro.OpActGive, 0x00, micro.OpHalt})
+	spawnAt(w, giver, 5, 5)
+	giver.Item = ItemWeapon
+	grantItemModifier(giver, giver.Item)
+	recipient := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, recipient, 5, 4)
+
+	s.Tick()
+
+	if giver.Item != ItemNone || recipient.Item != ItemWeapon {
+		t.Fatalf("item should change hands: giver=%d recipient=%d", giver.Item, recipient.Item)
+	}
+	if giver.ModSum(ModAttack) != 0 || recipient.ModSum(ModAttack) != 10 {
+		t.Error("weapon modifier should move with the item")
+	}
+	if s.GiftCount != 1 || giver.GiftCount != 1 {
+		t.Errorf("gift counters: sched=%d npc=%d, want 1/1", s.GiftCount, giver.GiftCount)
+	}
+	if recipient.TrustOf(giver.ID) != TrustGift {
+		t.Errorf("recipient should trust the giver: got %d", recipient.TrustOf(giver.ID))
+	}
+}
+
+func TestGiveGoldWhenHandsFull(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.GiftFitness = 100
+
+	giver := NewNPC([]byte{micro.OpActGive, 0x00, micro.OpHalt})
+	spawnAt(w, giver, 5, 5)
+	giver.Item = ItemTool
+	giver.Gold = 3
+	recipient := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, recipient, 5, 4)
+	recipient.Item = ItemTreasure
+
+	s.Tick()
+
+	if giver.Item != ItemTool || giver.Gold != 0 || recipient.Gold != 3 {
+		t.Errorf("gold should be given instead: giver item=%d gold=%d recipient gold=%d",
+			giver.Item, giver.Gold, recipient.Gold)
+	}
+	if s.GoldGifted != 3 {
+		t.Errorf("GoldGifted = %d, want 3", s.GoldGifted)
+	}
+	if giver.Fitness < 100 {
+		t.Errorf("gift fitness reward should apply: fitness=%d", giver.Fitness)
+	}
+}
+
+// === Follow Tests ===
+
+func TestFollowKeepsAdjacency(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			w.SetTile(x, y, MakeTile(TileEmpty))
+		}
+	}
+
+	leader := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, leader, 4, 8)
+	follower := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, follower, 4, 3)
+	follower.Following = leader.ID
+
+	s.Tick()
+	if follower.Y != 4 {
+		t.Fatalf("follower should take exactly one step per tick: Y=%d", follower.Y)
+	}
+	for i := 0; i < 5; i++ {
+		s.Tick()
+	}
+	if d := abs(follower.X-leader.X) + abs(follower.Y-leader.Y); d != 1 {
+		t.Errorf("follower should end adjacent to leader: distance %d", d)
+	}
+
+	leader.Health = 0
+	s.Tick()
+	s.Tick()
+	if follower.Following != 0 {
+		t.Error("following should end when the leader dies")
+	}
+}
+
+func TestFollowActionAndLeaderSensor(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	leader := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, leader, 8, 8)
+	a := NewNPC([]byte{micro.OpActFollow, 0x00, micro.OpHalt})
+	spawnAt(w, a, 8, 7)
+	b := NewNPC([]byte{micro.OpActFollow, 0x00, micro.OpHalt})
+	spawnAt(w, b, 8, 9)
+
+	s.Tick()
+	if a.Following != leader.ID || b.Following != leader.ID {
+		t.Fatalf("act.follow should latch onto nearest NPC: a=%d b=%d leader=%d", a.Following, b.Following, leader.ID)
+	}
+
+	s.countFollowers()
+	onlooker := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, onlooker, 11, 8)
+	s.sense(onlooker)
+	if got := s.VM(onlooker).MemRead(Ring0LeaderID); uint16(got) != leader.ID {
+		t.Errorf("leader sensor = %d, want %d", got, leader.ID)
+	}
+	if got := s.VM(onlooker).MemRead(Ring0LeaderDir); got != DirWest {
+		t.Errorf("leader dir = %d, want DirWest", got)
+	}
+	s.sense(leader)
+	if got := s.VM(leader).MemRead(Ring0MyFollowers); got != 2 {
+		t.Errorf("leader should sense 2 followers, got %d", got)
+	}
+}
+
+// === Clan Tests ===
+
+func TestJoinFoundsAndGrowsClan(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	founder := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, founder, 5, 5)
+	joiner := NewNPC([]byte{micro.OpActJoin, 0x00, micro.OpHalt})
+	spawnAt(w, joiner, 5, 4)
+
+	s.Tick()
+	if founder.Clan == 0 || joiner.Clan != founder.Clan {
+		t.Fatalf("act.join should found a shared clan: founder=%d joiner=%d", founder.Clan, joiner.Clan)
+	}
+
+	late := NewNPC([]byte{micro.OpActJoin, 0x00, micro.OpHalt})
+	spawnAt(w, late, 6, 5)
+	s.Tick()
+	if late.Clan != founder.Clan {
+		t.Errorf("joining a clan member should adopt its clan: got %d, want %d", late.Clan, founder.Clan)
+	}
+	if s.ClanJoins != 2 {
+		t.Errorf("ClanJoins = %d, want 2", s.ClanJoins)
+	}
+
+	stats := ClanStats(w.NPCs)
+	if len(stats) != 1 || stats[0].Members != 3 || stats[0].Name != ClanName(founder.Clan) {
+		t.Errorf("ClanStats = %+v, want one clan of 3", stats)
+	}
+}
+
+func TestClanNamesUnique(t *testing.T) {
+	if ClanName(0) != "" {
+		t.Error("clan 0 should have no name")
+	}
+	seen := make(map[string]byte)
+	for id := 1; id <= MaxClans; id++ {
+		name := ClanName(byte(id))
+		if name == "" {
+			t.Fatalf("clan %d has empty name", id)
+		}
+		if prev, ok := seen[name]; ok {
+			t.Fatalf("clans %d and %d share name %q", prev, id, name)
+		}
+		seen[name] = byte(id)
+	}
+}
+
+func TestClanShareBlendsFitness(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.ClanShare = 0.5
+
+	a := NewNPC([]byte{micro.OpHalt})
+	a.Clan, a.Fitness = 1, 100
+	b := NewNPC([]byte{micro.OpHalt})
+	b.Clan, b.Fitness = 1, 300
+	loner := NewNPC([]byte{micro.OpHalt})
+	loner.Clan, loner.Fitness = 2, 50
+	w.NPCs = append(w.NPCs, a, b, loner)
+
+	s.shareClanFitness()
+	if a.Fitness != 150 || b.Fitness != 250 {
+		t.Errorf("clan fitness should blend toward 200: a=%d b=%d", a.Fitness, b.Fitness)
+	}
+	if loner.Fitness != 50 {
+		t.Errorf("single-member clan should be unaffected: %d", loner.Fitness)
+	}
+}
+
+// === Mating Tests ===
+
+func TestMateProducesChild(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.Mating = NewGA(testRng())
+
+	a := NewNPC([]byte{micro.OpActMate, 0x00, micro.OpHalt})
+	spawnAt(w, a, 5, 5)
+	a.Clan = 3
+	b := NewNPC([]byte{micro.OpActMate, 0x00, micro.OpHalt})
+	spawnAt(w, b, 5, 4)
+
+	s.Tick()
+
+	if len(w.NPCs) != 3 || s.BirthCount != 1 {
+		t.Fatalf("mutual act.mate should produce one child: npcs=%d births=%d", len(w.NPCs), s.BirthCount)
+	}
+	child := w.NPCs[2]
+	if d := min(abs(child.X-a.X)+abs(child.Y-a.Y), abs(child.X-b.X)+abs(child.Y-b.Y)); d != 1 {
+		t.Errorf("child should be born next to a parent, distance %d", d)
+	}
+	if len(child.Genome) == 0 || child.Clan != a.Clan {
+		t.Errorf("child genome len=%d clan=%d", len(child.Genome), child.Clan)
+	}
+	if a.Energy >= 100-mateCost || b.Energy >= 100-mateCost {
+		t.Errorf("mating should cost both parents energy: a=%d b=%d", a.Energy, b.Energy)
+	}
+
+	// Parents are now below the energy threshold
+	s.Tick()
+	if s.BirthCount != 1 {
+		t.Errorf("exhausted parents should not mate again: births=%d", s.BirthCount)
+	}
+}
+
+func TestMateRequiresConsentAndFlag(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	a := NewNPC([]byte{micro.OpActMate, 0x00, micro.OpHalt})
+	spawnAt(w, a, 5, 5)
+	b := NewNPC([]byte{micro.OpActMate, 0x00, micro.OpHalt})
+	spawnAt(w, b, 5, 4)
+	s.Tick()
+	if s.BirthCount != 0 {
+		t.Fatal("mating should be disabled without Scheduler.Mating")
+	}
+
+	s.Mating = NewGA(testRng())
+	b.Genome = []byte{micro.OpHalt}
+	s.Tick()
+	if s.BirthCount != 0 || len(w.NPCs) != 2 {
+		t.Errorf("one-sided act.mate should not produce a child: births=%d", s.BirthCount)
+	}
+}
+
+func TestParentalCareAndKinship(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	parent := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, parent, 5, 5)
+	child := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, child, 5, 6)
+	child.Parents = [2]uint16{parent.ID, 0}
+	child.Energy = newbornEnergy
+	sibling := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, sibling, 9, 9)
+	sibling.Parents = [2]uint16{parent.ID, 0}
+
+	s.Tick()
+	if child.Energy != newbornEnergy-1+careGift {
+		t.Errorf("adjacent parent should feed the child: energy=%d", child.Energy)
+	}
+	if s.CareEnergy != careGift {
+		t.Errorf("CareEnergy = %d, want %d", s.CareEnergy, careGift)
+	}
+
+	if Kinship(parent, child) != 2 || Kinship(child, sibling) != 1 || Kinship(parent, NewNPC(nil)) != 0 {
+		t.Error("kinship: want parent/child=2, siblings=1, strangers=0")
+	}
+	s.sense(child)
+	if got := s.VM(child).MemRead(Ring0NearKin); got != 2 {
+		t.Errorf("near-kin sensor = %d, want 2", got)
+	}
+	s.sense(parent)
+	if got := s.VM(parent).MemRead(Ring0ChildDir); got != DirSouth {
+		t.Errorf("child-dir sensor = %d, want DirSouth", got)
+	}
+
+	child.Age = careTicks
+	before := child.Energy
+	s.Tick()
+	if child.Energy != before-1 {
+		t.Errorf("care should stop after careTicks: energy %d -> %d", before, child.Energy)
+	}
+}
+
+// === Disease Tests ===
+
+func TestDiseaseSpreadsAndConfersImmunity(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.Contagion = 100
+	w.Tick = 1 // skip the tick-0 outbreak
+
+	patient := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, patient, 5, 5)
+	neighbour := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, neighbour, 5, 6)
+	immune := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, immune, 5, 4)
+	immune.Immune = true
+	s.Infect(patient)
+
+	s.Tick()
+	if neighbour.Infection == 0 {
+		t.Fatal("contagion 100 should infect the adjacent NPC")
+	}
+	if immune.Infection != 0 {
+		t.Error("immune NPC should not be infected")
+	}
+	s.sense(neighbour)
+	if got := s.VM(neighbour).MemRead(Ring0Infected); got != 1 {
+		t.Errorf("infection sensor = %d, want 1", got)
+	}
+
+	for i := 0; i < infectionTicks; i++ {
+		s.Tick()
+	}
+	if patient.Infection != 0 || !patient.Immune {
+		t.Errorf("patient should recover immune: infection=%d immune=%v", patient.Infection, patient.Immune)
+	}
+	if s.Infections != 2 {
+		t.Errorf("Infections = %d, want 2", s.Infections)
+	}
+}
+
+func TestRemedyCuresInfection(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.Contagion = 50
+	w.Tick = 1
+
+	w.SetTile(5, 5, MakeTile(TileForge))
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	npc.Item = ItemCharm
+	s.Infect(npc)
+
+	s.Tick() // auto-crafts charm → remedy on the forge, then takes it
+	if npc.Infection != 0 || !npc.Immune || npc.Item != ItemNone {
+		t.Errorf("remedy should cure: infection=%d immune=%v item=%d", npc.Infection, npc.Immune, npc.Item)
+	}
+	if s.Cures != 1 {
+		t.Errorf("Cures = %d, want 1", s.Cures)
+	}
+}
+
+func TestDiseaseDisabledByDefault(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	for i := 0; i < outbreakEvery+1; i++ {
+		s.Tick()
+	}
+	if s.Infections != 0 {
+		t.Errorf("no outbreaks expected with Contagion 0, got %d", s.Infections)
+	}
+}
+
+// === Sleep Tests ===
+
+func TestSleepRestsAndLeavesDefenseless(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	leader := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, leader, 5, 1)
+	sleeper := NewNPC([]byte{micro.OpActSleep, 0x00, micro.OpHalt})
+	spawnAt(w, sleeper, 5, 5)
+	sleeper.Following = leader.ID
+	sleeper.Stress = 20
+	sleeper.Item = ItemShield
+	grantItemModifier(sleeper, ItemShield)
+
+	s.Tick()
+	if !sleeper.Asleep || sleeper.X != 5 || sleeper.Y != 5 {
+		t.Fatalf("sleeper should stay put: asleep=%v pos=(%d,%d)", sleeper.Asleep, sleeper.X, sleeper.Y)
+	}
+	if sleeper.Stress != 20-sleepStress || sleeper.Energy != 100-1+sleepEnergy {
+		t.Errorf("sleep should rest: stress=%d energy=%d", sleeper.Stress, sleeper.Energy)
+	}
+	if lost := sleeper.TakeDamage(10, DamagePhysical); lost != 10 {
+		t.Errorf("shield should not protect a sleeper: lost %d, want 10", lost)
+	}
+
+	w.Tick = DayCycle * 3 / 4 // night
+	stress := sleeper.Stress
+	s.Tick()
+	if sleeper.Stress != stress-2*sleepStress {
+		t.Errorf("night sleep should rest twice as much: stress %d -> %d", stress, sleeper.Stress)
+	}
+	if s.SleepTicks != 2 {
+		t.Errorf("SleepTicks = %d, want 2", s.SleepTicks)
+	}
+}
+
+// === Market Tests ===
+
+func TestMarketBuyAndSell(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	diner := NewNPC([]byte{micro.OpActBuy, 0, micro.OpHalt})
+	spawnAt(w, diner, 3, 3)
+	diner.Gold = 100
+	shopper := NewNPC([]byte{micro.OpActBuy, ItemTool, micro.OpHalt})
+	spawnAt(w, shopper, 8, 3)
+	shopper.Gold = 100
+	seller := NewNPC([]byte{micro.OpActSell, 0, micro.OpHalt})
+	spawnAt(w, seller, 3, 8)
+	seller.Item = ItemCrystal
+	pauper := NewNPC([]byte{micro.OpActBuy, 0, micro.OpHalt})
+	spawnAt(w, pauper, 8, 8)
+	for _, npc := range []*NPC{diner, shopper, seller, pauper} {
+		w.SetTile(npc.X, npc.Y, MakeTile(TileMarket))
+	}
+	offMarket := NewNPC([]byte{micro.OpActBuy, 0, micro.OpHalt})
+	spawnAt(w, offMarket, 12, 12)
+	offMarket.Gold = 100
+	if w.Markets() != 4 {
+		t.Fatalf("Markets() = %d, want 4", w.Markets())
+	}
+
+	// Prices are fixed at the start of the tick
+	meal, tool, crystal := w.MealPrice(), w.MarketValue(ItemTool), w.MarketValue(ItemCrystal)
+	s.Tick()
+	if diner.Gold != 100-meal || diner.FoodEaten != 1 {
+		t.Errorf("diner: gold=%d eaten=%d, want gold %d and one meal", diner.Gold, diner.FoodEaten, 100-meal)
+	}
+	if shopper.Item != ItemTool || shopper.Gold != 100-tool {
+		t.Errorf("shopper: item=%d gold=%d, want a tool for %d gold", shopper.Item, shopper.Gold, tool)
+	}
+	if seller.Item != ItemNone || seller.Gold != crystal/2 || seller.GoldEarned != crystal/2 {
+		t.Errorf("seller: item=%d gold=%d, want crystal sold for %d", seller.Item, seller.Gold, crystal/2)
+	}
+	if pauper.FoodEaten != 0 || offMarket.Gold != 100 {
+		t.Errorf("no gold or no market should mean no sale: pauper ate %d, off-market gold %d", pauper.FoodEaten, offMarket.Gold)
+	}
+	if s.MarketBuys != 2 || s.MarketSells != 1 || s.GoldSpent != meal+tool {
+		t.Errorf("buys=%d sells=%d spent=%d, want 2, 1, %d", s.MarketBuys, s.MarketSells, s.GoldSpent, meal+tool)
+	}
+
+	// Sensors show this tick's prices
+	s.priceMarkets()
+	s.sense(shopper)
+	vm := s.VM(shopper)
+	if got := vm.MemRead(Ring0MealPrice); int(got) != w.MealPrice() {
+		t.Errorf("meal-price sensor = %d, want %d", got, w.MealPrice())
+	}
+	if got := vm.MemRead(Ring0SellPrice); int(got) != w.MarketValue(ItemTool)/2 {
+		t.Errorf("sell-price sensor = %d, want %d", got, w.MarketValue(ItemTool)/2)
+	}
+
+	// Without markets every price reads 0
+	bare := NewScheduler(NewWorld(16, testRng()), 200, io.Discard)
+	bare.priceMarkets()
+	if bare.prices != (marketPrices{}) {
+		t.Errorf("prices without markets = %+v, want zero", bare.prices)
+	}
+}
+
+// === Economy Tests ===
+
+func TestEconomyPolicy(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	rich, poor := NewNPC([]byte{micro.OpHalt}), NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, rich, 2, 2)
+	spawnAt(w, poor, 9, 9)
+
+	// Without a policy nothing is withheld or settled
+	rich.Gold = 100
+	if kept := s.taxEarnings(rich, 10); kept != 10 || rich.Gold != 100 {
+		t.Errorf("untaxed: kept %d, gold %d", kept, rich.Gold)
+	}
+	s.SettleEconomy()
+	if rich.Gold != 100 || poor.Gold != 0 {
+		t.Errorf("zero Economy changed gold: %d, %d", rich.Gold, poor.Gold)
+	}
+
+	s.Economy = Economy{TaxRate: 0.25, Redistribute: true, WealthCap: 80, Faucet: 5}
+	if kept := s.taxEarnings(rich, 20); kept != 15 || rich.Gold != 95 || s.Treasury != 5 {
+		t.Errorf("taxed: kept %d, gold %d, treasury %d", kept, rich.Gold, s.Treasury)
+	}
+	// Cap seizes 15, the treasury of 20 is shared 10/10, then 5 each is minted
+	s.SettleEconomy()
+	if rich.Gold != 95 || poor.Gold != 15 || s.Treasury != 0 {
+		t.Errorf("settled: rich %d, poor %d, treasury %d; want 95, 15, 0", rich.Gold, poor.Gold, s.Treasury)
+	}
+	if s.TaxCollected != 20 || s.GoldMinted != 10 || s.GoldBurned != 0 {
+		t.Errorf("taxed=%d minted=%d burned=%d, want 20, 10, 0", s.TaxCollected, s.GoldMinted, s.GoldBurned)
+	}
+
+	// Without redistribution the treasury is destroyed
+	s.Economy = Economy{TaxRate: 0.5}
+	s.taxEarnings(poor, 10)
+	s.SettleEconomy()
+	if poor.Gold != 10 || s.Treasury != 0 || s.GoldBurned != 5 {
+		t.Errorf("burn: poor %d, treasury %d, burned %d", poor.Gold, s.Treasury, s.GoldBurned)
+	}
+
+	for _, tc := range []struct {
+		gold []int
+		want float64
+	}{
+		{[]int{10, 10, 10, 10}, 0},
+		{[]int{0, 0, 0, 40}, 0.75},
+		{[]int{0, 0}, 0},
+	} {
+		var npcs []*NPC
+		for _, g := range tc.gold {
+			npc := NewNPC(nil)
+			npc.Gold = g
+			npcs = append(npcs, npc)
+		}
+		if got := GoldGini(npcs); got != tc.want {
+			t.Errorf("GoldGini(%v) = %v, want %v", tc.gold, got, tc.want)
+		}
+	}
+}
+
+// === Contract Tests ===
+
+func TestContracts(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.ContractEvery = 1000 // no postings during the test
+	w.Tick = 1
+	var done []ContractFulfilled
+	s.Subscribe(SubscriberFunc(func(ev Event) {
+		if e, ok := ev.(ContractFulfilled); ok {
+			done = append(done, e)
+		}
+	}))
+
+	courier := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, courier, 4, 4)
+	courier.Item = ItemTool
+	attacker := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	spawnAt(w, attacker, 10, 10)
+	outlaw := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, outlaw, 11, 10)
+	outlaw.Health = 1
+	outlaw.Kills = 2
+	s.Contracts = []Contract{
+		{Kind: ContractDeliver, Item: ItemTool, X: 4, Y: 4, Reward: 30, Expires: 100},
+		{Kind: ContractEliminate, Target: outlaw.ID, Reward: 40, Expires: 100},
+		{Kind: ContractDeliver, Item: ItemWeapon, X: 1, Y: 1, Reward: 50, Expires: 1},
+	}
+
+	// The courier's direction sensor points at the nearer job, under its feet
+	s.sense(courier)
+	vm := s.VM(courier)
+	if dir, reward, item := vm.MemRead(Ring0ContractDir), vm.MemRead(Ring0ContractReward), vm.MemRead(Ring0ContractItem); dir != DirNone || reward != 30 || item != ItemTool {
+		t.Errorf("courier sensors: dir=%d reward=%d item=%d, want 0, 30, %d", dir, reward, item, ItemTool)
+	}
+
+	s.Tick()
+	if outlaw.Alive() {
+		t.Fatal("outlaw should have been killed")
+	}
+	if courier.Item != ItemNone || courier.Gold != 30 || attacker.Gold != 40 {
+		t.Errorf("courier item=%d gold=%d, attacker gold=%d; want delivery paid 30 and bounty 40",
+			courier.Item, courier.Gold, attacker.Gold)
+	}
+	if s.ContractsDone != 2 || s.ContractGold != 70 || len(done) != 2 {
+		t.Errorf("done=%d gold=%d events=%d, want 2, 70, 2", s.ContractsDone, s.ContractGold, len(done))
+	}
+	if len(s.Contracts) != 0 {
+		t.Errorf("open contracts = %+v, want the lapsed one dropped too", s.Contracts)
+	}
+
+	// Postings draw a job every ContractEvery ticks
+	s.ContractEvery = 1
+	s.Tick()
+	if len(s.Contracts) != 1 || s.Contracts[0].Reward <= 0 {
+		t.Errorf("posted contracts = %+v, want one paying job", s.Contracts)
+	}
+}
+
+// === Trading Post Tests ===
+
+func TestTradingPost(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	// A seller leaves a crystal asking for a tool; a buyer arrives at
+	// another post a tick later with one, never meeting the seller
+	seller := NewNPC([]byte{micro.OpActPost, ItemTool, micro.OpHalt})
+	spawnAt(w, seller, 2, 2)
+	seller.Item = ItemCrystal
+	w.SetTile(2, 2, MakeTile(TilePost))
+	s.Tick()
+	if seller.Item != ItemNone || len(s.Offers) != 1 || s.PostOffers != 1 {
+		t.Fatalf("after posting: item=%d offers=%d, want empty hands and one offer", seller.Item, len(s.Offers))
+	}
+
+	// The wrong item doesn't match the ask, so it becomes an offer too
+	wrong := NewNPC([]byte{micro.OpActPost, 0, micro.OpHalt})
+	spawnAt(w, wrong, 12, 2)
+	wrong.Item = ItemWeapon
+	w.SetTile(12, 2, MakeTile(TilePost))
+	buyer := NewNPC([]byte{micro.OpActPost, ItemCrystal, micro.OpHalt})
+	spawnAt(w, buyer, 12, 12)
+	buyer.Item = ItemTool
+	w.SetTile(12, 12, MakeTile(TilePost))
+	s.Tick()
+	if buyer.Item != ItemCrystal || s.PostTrades != 1 || s.TradeCount != 1 {
+		t.Errorf("buyer: item=%d post trades=%d, want the crystal in one trade", buyer.Item, s.PostTrades)
+	}
+	if wrong.Item != ItemNone || len(s.Offers) != 2 {
+		t.Errorf("wrong item: held=%d offers=%d, want it left as a second offer", wrong.Item, len(s.Offers))
+	}
+
+	// The seller sees the payout, then collects it with empty hands
+	w.SetTile(12, 12, MakeTile(TileEmpty)) // the buyer's post, so it keeps the crystal
+	s.sense(seller)
+	if got := s.VM(seller).MemRead(Ring0PostPayout); got != ItemTool {
+		t.Errorf("post-payout sensor = %d, want tool", got)
+	}
+	s.Tick()
+	if seller.Item != ItemTool || len(s.Offers) != 1 {
+		t.Errorf("seller collected %d, offers left %d; want the tool and one offer", seller.Item, len(s.Offers))
+	}
+
+	// Unclaimed offers lapse back to their owners; the dead lose theirs
+	w.Tick += offerTTL
+	s.tendPosts()
+	if got := s.payout(wrong.ID); got != ItemWeapon {
+		t.Errorf("lapsed offer payout = %d, want the weapon back", got)
+	}
+	wrong.Health = 0
+	s.tendPosts()
+	if len(s.Offers) != 0 {
+		t.Errorf("offers of the dead = %d, want dropped", len(s.Offers))
+	}
+}
+
+// === School Tests ===
+
+func TestSchoolAmplifiesTeaching(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	lesson := make([]byte, 24)
+	for i := range lesson {
+		lesson[i] = micro.SmallNumOp(1)
+	}
+	teacher := NewNPC(lesson)
+	spawnAt(w, teacher, 5, 5)
+	student := NewNPC(nil)
+	spawnAt(w, student, 5, 4)
+
+	// Equal fitness: heeded half the time outside, three quarters at school;
+	// at school up to 8 bytes come across instead of 4
+	trial := func() (successes, widest int) {
+		for i := 0; i < 400; i++ {
+			student.Genome = make([]byte, 16)
+			for j := range student.Genome {
+				student.Genome[j] = micro.OpNop
+			}
+			student.Taught, teacher.Fitness = 0, 0
+			s.memeticTransfer(teacher, student)
+			if student.Taught == 0 {
+				continue
+			}
+			successes++
+			copied := 0
+			for _, b := range student.Genome {
+				if b != micro.OpNop {
+					copied++
+				}
+			}
+			widest = max(widest, copied)
+		}
+		return successes, widest
+	}
+	plain, plainWidest := trial()
+	if len(s.Lessons) != 0 {
+		t.Fatalf("lessons outside a school = %v, want none", s.Lessons)
+	}
+	w.SetTile(5, 5, MakeTile(TileSchool))
+	school, schoolWidest := trial()
+	if school <= plain {
+		t.Errorf("successes: %d at school, %d outside; want more at school", school, plain)
+	}
+	if plainWidest > 4 || schoolWidest <= 4 {
+		t.Errorf("widest fragment: %d outside, %d at school; want <=4 and >4", plainWidest, schoolWidest)
+	}
+	if x, y, n := s.BusiestSchool(); x != 5 || y != 5 || n != school || s.SchoolLessons() != school {
+		t.Errorf("busiest school = (%d,%d) with %d, want (5,5) with %d", x, y, n, school)
+	}
+
+	// Teaching at school costs half the energy
+	teacher.Energy = 100
+	vm := s.VM(teacher)
+	vm.MemWrite(64+Ring1Action, ActionTeach)
+	vm.MemWrite(64+Ring1Target, int16(student.ID))
+	s.act(teacher)
+	if want := 100 - s.Actions[ActionTeach].Energy/2; teacher.Energy != want {
+		t.Errorf("teacher energy after a lesson at school = %d, want %d", teacher.Energy, want)
+	}
+}
+
+// === Emotion Tests ===
+
+// emoteTrade returns a genome that shows emotion and trades with target.
+func emoteTrade(emotion int, target uint16) []byte {
+	return []byte{
+		micro.SmallNumOp(emotion), micro.OpRing1W, Ring1Emotion,
+		micro.SmallNumOp(ActionTrade), micro.OpRing1W, Ring1Action,
+		micro.SmallNumOp(int(target)), micro.OpRing1W, Ring1Target,
+		micro.OpHalt,
+	}
+}
+
+func TestEmotionShownAndSensed(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	a := NewNPC(emoteTrade(EmotionFearful, 0))
+	spawnAt(w, a, 5, 5)
+	b := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, b, 5, 4)
+
+	s.Tick()
+	if a.Emotion != EmotionFearful {
+		t.Fatalf("emotion should persist after the brain halts: got %d", a.Emotion)
+	}
+	s.sense(b)
+	if got := s.VM(b).MemRead(Ring0NearEmotion); got != EmotionFearful {
+		t.Errorf("adjacent NPC should sense the emotion: got %d", got)
+	}
+	spawnAt(w, NewNPC([]byte{micro.OpHalt}), 12, 12)
+	b.X, b.Y = 9, 5
+	s.sense(b)
+	if got := s.VM(b).MemRead(Ring0NearEmotion); got != EmotionNeutral {
+		t.Errorf("emotion should not be readable at a distance: got %d", got)
+	}
+}
+
+func TestEmotionModulatesTrade(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		emotion  int
+		trades   int
+		goldEach int
+	}{
+		{"neutral", EmotionNeutral, 1, 3},
+		{"friendly", EmotionFriendly, 1, 4},
+		{"aggressive", EmotionAggressive, 0, 0},
+	} {
+		w := NewWorld(16, testRng())
+		s := NewScheduler(w, 200, io.Discard)
+		a := NewNPC(nil)
+		spawnAt(w, a, 5, 5)
+		b := NewNPC(nil)
+		spawnAt(w, b, 5, 4)
+		a.Genome = emoteTrade(tc.emotion, b.ID)
+		b.Genome = emoteTrade(tc.emotion, a.ID)
+		a.Item, b.Item = ItemTool, ItemTool
+
+		s.Tick()
+		if s.TradeCount != tc.trades || a.Gold != tc.goldEach {
+			t.Errorf("%s: trades=%d gold=%d, want %d/%d", tc.name, s.TradeCount, a.Gold, tc.trades, tc.goldEach)
+		}
+	}
+}
+
+func TestAggressiveAttackStress(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	victim := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, victim, 5, 4)
+	attacker := NewNPC([]byte{
+		micro.SmallNumOp(EmotionAggressive), micro.OpRing1W, Ring1Emotion,
+		micro.OpActAttack, 0x00, micro.OpHalt,
+	})
+	spawnAt(w, attacker, 5, 5)
+
+	s.Tick()
+	if victim.Stress != 25 {
+		t.Errorf("aggressive attack should add intimidation stress: got %d, want 25", victim.Stress)
+	}
+}
+
+func TestFearfulBluntsAttack(t *testing.T) {
+	hurt := func(emotion int) int {
+		w := NewWorld(16, testRng())
+		s := NewScheduler(w, 200, io.Discard)
+		victim := NewNPC([]byte{micro.SmallNumOp(emotion), micro.OpRing1W, Ring1Emotion, micro.OpHalt})
+		spawnAt(w, victim, 5, 4)
+		attacker := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+		spawnAt(w, attacker, 5, 5)
+		before := victim.Health
+		s.Tick()
+		return before - victim.Health
+	}
+	neutral, fearful := hurt(EmotionNeutral), hurt(EmotionFearful)
+	if neutral == 0 || fearful >= neutral {
+		t.Errorf("attack on a fearful NPC did %d damage, want less than %d", fearful, neutral)
+	}
+}
+
+// === Vision Cone Tests ===
+
+func TestVisionConeLimitsSensors(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.VisionCone = true
+
+	viewer := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, viewer, 8, 8)
+	viewer.LastDir = DirNorth
+	behind := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, behind, 8, 10)
+
+	s.sense(viewer)
+	if got := s.VM(viewer).MemRead(Ring0NearID); got != 0 {
+		t.Errorf("NPC behind should be invisible in the cone, sensed ID %d", got)
+	}
+	if got := s.VM(viewer).MemRead(Ring0Behind); got != 1 {
+		t.Errorf("behind sensor = %d, want 1", got)
+	}
+
+	viewer.LastDir = DirSouth
+	s.sense(viewer)
+	if got := s.VM(viewer).MemRead(Ring0NearID); uint16(got) != behind.ID {
+		t.Errorf("facing south should see the NPC: got %d", got)
+	}
+	if got := s.VM(viewer).MemRead(Ring0Behind); got != 0 {
+		t.Errorf("behind sensor = %d, want 0 when the NPC is in view", got)
+	}
+}
+
+func TestTurnOutputSetsFacing(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	npc := NewNPC([]byte{micro.SmallNumOp(DirWest), micro.OpRing1W, Ring1Turn, micro.OpHalt})
+	spawnAt(w, npc, 8, 8)
+	s.Tick()
+	if npc.X != 8 || npc.Y != 8 || npc.facing() != DirWest {
+		t.Errorf("turn should face west in place: pos=(%d,%d) facing=%d", npc.X, npc.Y, npc.facing())
+	}
+	s.sense(npc)
+	if got := s.VM(npc).MemRead(Ring0Facing); got != DirWest {
+		t.Errorf("facing sensor = %d, want DirWest", got)
+	}
+}
+
+// === Hearing Tests ===
+
+func TestHearingLoudestNoise(t *testing.T) {
+	w := NewWorld(32, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	listener := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, listener, 8, 8)
+	fighter := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	spawnAt(w, fighter, 8, 13)
+	victim := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, victim, 8, 14)
+
+	s.Tick() // listener senses before the attack happens
+	s.sense(listener)
+	if got := s.VM(listener).MemRead(Ring0NoiseDir); got != DirSouth {
+		t.Errorf("noise dir = %d, want DirSouth", got)
+	}
+	if got := s.VM(listener).MemRead(Ring0NoiseLevel); got != noiseAttack-5 {
+		t.Errorf("noise level = %d, want %d", got, noiseAttack-5)
+	}
+
+	// Noises fade after the following tick
+	fighter.Genome = []byte{micro.OpHalt}
+	s.Tick()
+	s.Tick()
+	s.sense(listener)
+	if got := s.VM(listener).MemRead(Ring0NoiseLevel); got != 0 {
+		t.Errorf("old noise should be forgotten, level %d", got)
+	}
+}
+
+func TestHearingIgnoresOwnNoise(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	s.noise(npc, noiseAttack)
+	s.sense(npc)
+	if got := s.VM(npc).MemRead(Ring0NoiseLevel); got != 0 {
+		t.Errorf("NPC should not hear itself, level %d", got)
+	}
+}
+
+// === Predator Tests ===
+
+func TestPredatorPrey(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	prey := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, prey, 5, 5)
+
+	// No predators: the hunt sensors stay quiet
+	s.Tick()
+	s.sense(prey)
+	if got := s.VM(prey).MemRead(Ring0PreyDist); got != 0 {
+		t.Errorf("prey-dist without predators = %d, want 0", got)
+	}
+
+	hunter := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	hunter.Predator = true
+	spawnAt(w, hunter, 5, 8)
+	w.SetTile(6, 8, MakeTile(TileFood))
+	if s.tryEat(hunter, 6, 8) || w.TileAt(6, 8).Type() != TileFood {
+		t.Error("predators should not eat food tiles")
+	}
+
+	s.Tick()
+	s.sense(prey)
+	s.sense(hunter)
+	if got := s.VM(prey).MemRead(Ring0PredatorDist); got != 3 {
+		t.Errorf("predator-dist = %d, want 3", got)
+	}
+	if got := s.VM(prey).MemRead(Ring0PredatorDir); got != DirSouth {
+		t.Errorf("predator-dir = %d, want DirSouth", got)
+	}
+	if got := s.VM(hunter).MemRead(Ring0PreyID); got != int16(prey.ID) {
+		t.Errorf("prey-id = %d, want %d", got, prey.ID)
+	}
+	if got := s.VM(hunter).MemRead(Ring0PreyDir); got != DirNorth {
+		t.Errorf("prey-dir = %d, want DirNorth", got)
+	}
+
+	// Biting prey feeds the predator; a kill counts
+	w.SetOcc(hunter.X, hunter.Y, 0)
+	hunter.X, hunter.Y = 5, 6
+	w.SetOcc(hunter.X, hunter.Y, hunter.ID)
+	hunter.Energy = 100
+	s.Tick()
+	if hunter.FoodEaten != 1 || prey.Health >= 100 {
+		t.Fatalf("predator should have bitten the prey: food_eaten=%d prey_hp=%d", hunter.FoodEaten, prey.Health)
+	}
+	prey.Health = 1
+	s.Tick()
+	if prey.Alive() || s.PreyKills != 1 {
+		t.Errorf("predator should have killed the prey: alive=%v prey_kills=%d", prey.Alive(), s.PreyKills)
+	}
+}
+
+// === Biography Tests ===
+
+func TestDeathCausesAndBiography(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.KeepGraveyard = true
+
+	starving := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, starving, 2, 2)
+	starving.Energy, starving.Health = 0, 5
+	old := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, old, 8, 8)
+	old.Age = MaxAge - 1
+	victim := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, victim, 12, 11)
+	victim.Health = 1
+	killer := NewNPC([]byte{micro.OpActAttack, 0x00, micro.OpHalt})
+	spawnAt(w, killer, 12, 12)
+	killer.Item = ItemWeapon
+	grantItemModifier(killer, ItemWeapon)
+
+	s.Tick()
+
+	want := map[int]int{DeathStarvation: 1, DeathAge: 1, DeathCombat: 1}
+	for cause, n := range want {
+		if s.Deaths[cause] != n {
+			t.Errorf("%s deaths = %d, want %d", DeathCauseNames[cause], s.Deaths[cause], n)
+		}
+	}
+	if killer.Kills != 1 || killer.ItemsHeld&(1<<ItemWeapon) == 0 {
+		t.Errorf("killer biography: kills=%d items=%b", killer.Kills, killer.ItemsHeld)
+	}
+	if len(s.Graveyard) != 3 {
+		t.Fatalf("graveyard has %d entries, want 3", len(s.Graveyard))
+	}
+	for _, b := range s.Graveyard {
+		if b.ID == old.ID && (b.BirthTick != 1-MaxAge || b.Cause != DeathAge) {
+			t.Errorf("old NPC biography = %+v", b)
+		}
+	}
+}
+
+// === Event Tests ===
+
+func TestEventBus(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	var events []Event
+	s.Subscribe(SubscriberFunc(func(ev Event) { events = append(events, ev) }))
+
+	// Two adjacent traders
+	a := NewNPC([]byte{micro.SmallNumOp(ActionTrade), micro.OpRing1W, Ring1Action, micro.SmallNumOp(2), micro.OpRing1W, Ring1Target, micro.OpHalt})
+	spawnAt(w, a, 5, 5)
+	a.Item = ItemTool
+	b := NewNPC([]byte{micro.SmallNumOp(ActionTrade), micro.OpRing1W, Ring1Action, micro.SmallNumOp(1), micro.OpRing1W, Ring1Target, micro.OpHalt})
+	spawnAt(w, b, 5, 4)
+	b.Item = ItemWeapon
+	// A crafter standing on a forge, and a starving NPC
+	w.SetTile(10, 10, MakeTile(TileForge))
+	crafter := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, crafter, 10, 10)
+	crafter.Item = ItemCharm
+	starving := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, starving, 2, 12)
+	starving.Energy, starving.Health = 0, 1
+	// Food for the blight that falls on tick 1024
+	for x := 0; x < 16; x++ {
+		w.SetTile(x, 15, MakeTile(TileFood))
+	}
+	w.Tick = 1024
+
+	s.Tick()
+	teacher, student := a, b
+	teacher.Fitness = 1000
+	for i := 0; i < 20; i++ {
+		s.memeticTransfer(teacher, student)
+	}
+
+	var trades, teaches, crafts, deaths, blights int
+	for _, ev := range events {
+		switch ev := ev.(type) {
+		case TradeCompleted:
+			trades++
+		case TeachSucceeded:
+			teaches++
+			if ev.Teacher != teacher.ID || ev.Student != student.ID {
+				t.Errorf("teach event %+v", ev)
+			}
+		case ItemCrafted:
+			crafts++
+			if ev.NPC != crafter.ID || ev.Input != ItemCharm || ev.Output != ItemRemedy {
+				t.Errorf("craft event %+v", ev)
+			}
+		case NPCDied:
+			deaths++
+			if ev.ID != starving.ID || ev.Cause != DeathStarvation {
+				t.Errorf("death event %+v", ev)
+			}
+		case Blight:
+			blights++
+			if ev.Destroyed == 0 || ev.Tick != 1024 {
+				t.Errorf("blight event %+v", ev)
+			}
+		}
+	}
+	if trades != 1 || s.TradeCount != 1 {
+		t.Errorf("trade events = %d (TradeCount %d), want 1", trades, s.TradeCount)
+	}
+	if teaches == 0 || teaches != s.TeachCount {
+		t.Errorf("teach events = %d, TeachCount = %d", teaches, s.TeachCount)
+	}
+	if crafts != 1 || deaths != 1 || blights != 1 {
+		t.Errorf("crafts=%d deaths=%d blights=%d, want 1 each", crafts, deaths, blights)
+	}
+}
+
+func TestLineage(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	for i := 0; i < 8; i++ {
+		npc := NewNPC([]byte{micro.OpHalt})
+		spawnAt(w, npc, i, 3)
+		npc.Fitness, npc.Age = 100-10*i, 5
+	}
+	l := NewLineage(w.NPCs, w.Tick)
+	s.Subscribe(l)
+
+	ga := NewGA(testRng())
+	w.NPCs = s.Evolve(ga, w.NPCs)
+	if len(ga.Births) != 2 || s.Epoch != 1 {
+		t.Fatalf("%d GA births in epoch %d, want 2 in epoch 1", len(ga.Births), s.Epoch)
+	}
+	if len(l.Nodes) != 10 {
+		t.Fatalf("lineage has %d nodes, want 8 founders + 2 offspring", len(l.Nodes))
+	}
+	for i, b := range ga.Births {
+		child := l.Nodes[8+i]
+		if child.NPC != b.ID || child.Epoch != 1 || child.End != -1 {
+			t.Errorf("offspring node %+v for birth %+v", child, b)
+		}
+		for _, p := range child.Parents {
+			if p < 0 || p >= 8 || l.Nodes[p].NPC != b.Parents[0] && l.Nodes[p].NPC != b.Parents[1] {
+				t.Errorf("offspring parent node %d, want a founder of %v", p, b.Parents)
+			}
+		}
+		if old := l.Nodes[b.ID-1]; old.End != w.Tick || old.Cause != "replaced" {
+			t.Errorf("replaced founder %+v not ended", old)
+		}
+	}
+
+	// A death ends the individual with its cause and fitness
+	var dying *NPC
+	for _, npc := range w.NPCs {
+		related := false
+		for _, b := range ga.Births {
+			related = related || npc.ID == b.ID || npc.ID == b.Parents[0] || npc.ID == b.Parents[1]
+		}
+		if !related {
+			dying = npc
+		}
+	}
+	dying.Energy, dying.Health = 0, 1
+	s.Tick()
+	if n := l.Nodes[dying.ID-1]; n.End != 0 || n.Cause != "starvation" || n.Fitness != dying.Fitness {
+		t.Errorf("dead founder %+v", n)
+	}
+
+	l.Finish(w.NPCs, w.Tick)
+	keep := l.Ancestry()
+	for _, i := range []int{8, 9, l.Nodes[8].Parents[0], l.Nodes[9].Parents[1]} {
+		if !keep[i] {
+			t.Errorf("node %d not in the survivors' ancestry", i)
+		}
+	}
+	if keep[dying.ID-1] {
+		t.Error("childless dead founder kept in ancestry")
+	}
+	var dot, js bytes.Buffer
+	if err := l.WriteDOT(&dot, keep); err != nil || !strings.Contains(dot.String(), fmt.Sprintf("n%d -> n8;", l.Nodes[8].Parents[0])) {
+		t.Errorf("DOT export (err %v):\n%s", err, dot.String())
+	}
+	var nodes []LineageNode
+	if err := l.WriteJSON(&js, nil); err != nil || json.Unmarshal(js.Bytes(), &nodes) != nil || len(nodes) != 10 {
+		t.Errorf("JSON export: %d nodes, err %v", len(nodes), err)
+	}
+}
+
+func TestEventLog(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	for i := 0; i < 8; i++ {
+		w.SetTile(i, 3, MakeTile(TileEmpty))
+		w.SetTile(i, 4, MakeTile(TileEmpty))
+		npc := NewNPC([]byte{micro.OpHalt})
+		spawnAt(w, npc, i, 3)
+		npc.Fitness = 100 - 10*i
+	}
+	var buf bytes.Buffer
+	l := NewEventLog(&buf)
+	l.Attach(s)
+
+	walker, dying := w.NPCs[0], w.NPCs[7]
+	s.Tick()
+	w.ClearOcc(walker.X, walker.Y)
+	walker.Y = 4
+	w.SetOcc(walker.X, walker.Y, walker.ID)
+	s.Tick()
+	dying.Energy, dying.Health = 0, 1
+	s.Tick()
+	w.NPCs = s.Evolve(NewGA(testRng()), w.NPCs)
+	if err := l.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	type line struct {
+		Tick    int    `json:"tick"`
+		Event   string `json:"event"`
+		NPC     uint16 `json:"npc"`
+		Epoch   int    `json:"epoch"`
+		Ticks   int    `json:"ticks"`
+		Steps   int    `json:"steps"`
+		Dist    int    `json:"dist"`
+		Cause   string `json:"cause"`
+		Fitness int    `json:"fitness"`
+	}
+	var lines []line
+	moved := map[uint16]line{}
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var ln line
+		if err := json.Unmarshal([]byte(raw), &ln); err != nil {
+			t.Fatalf("bad line %q: %v", raw, err)
+		}
+		lines = append(lines, ln)
+		if ln.Event == "moves" {
+			if _, dup := moved[ln.NPC]; dup {
+				t.Errorf("NPC %d has two moves lines in one epoch", ln.NPC)
+			}
+			moved[ln.NPC] = ln
+		}
+	}
+	if len(moved) != 8 {
+		t.Fatalf("%d moves lines, want one per NPC:\n%s", len(moved), buf.String())
+	}
+	if m := moved[walker.ID]; m.Steps != 1 || m.Dist != 1 || m.Ticks != 3 || m.Epoch != 0 {
+		t.Errorf("walker moves %+v, want 1 step over 3 ticks in epoch 0", m)
+	}
+	if m := moved[w.NPCs[1].ID]; m.Steps != 0 || m.Fitness != w.NPCs[1].Fitness {
+		t.Errorf("idle NPC moves %+v", m)
+	}
+
+	births := 0
+	for i, ln := range lines {
+		switch ln.Event {
+		case "death":
+			if ln.NPC != dying.ID || ln.Cause != "starvation" {
+				t.Errorf("death line %+v", ln)
+			}
+			if prev := lines[i-1]; prev.Event != "moves" || prev.NPC != dying.ID || prev.Ticks != 2 {
+				t.Errorf("death not preceded by its moves: %+v", prev)
+			}
+		case "birth":
+			births++
+			if ln.Epoch != 1 {
+				t.Errorf("birth in epoch %d, want 1", ln.Epoch)
+			}
+		}
+	}
+	if births == 0 {
+		t.Error("no GA births logged")
+	}
+}
+
+func TestBrainTrace(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	genome := []byte{
+		micro.OpRing0R, Ring0Health,
+		micro.SmallNumOp(DirEast), micro.OpRing1W, Ring1Move,
+		micro.OpYield,
+		micro.SmallNumOp(ActionEat), micro.OpRing1W, Ring1Action,
+		micro.OpHalt,
+	}
+	traced, other := NewNPC(genome), NewNPC(genome)
+	spawnAt(w, traced, 3, 3)
+	spawnAt(w, other, 8, 8)
+	var buf bytes.Buffer
+	s.Trace = NewBrainTrace(traced.ID, &buf)
+	s.Tick()
+	s.Tick()
+	if err := s.Trace.Finish(); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs []traceThink
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var run traceThink
+		if err := json.Unmarshal([]byte(raw), &run); err != nil {
+			t.Fatalf("bad line %q: %v", raw, err)
+		}
+		runs = append(runs, run)
+	}
+	if len(runs) != 2 || runs[0].NPC != traced.ID || runs[1].Tick != 1 {
+		t.Fatalf("want a line per tick for NPC %d:\n%s", traced.ID, buf.String())
+	}
+	if runs[0].Genome == "" || runs[1].Genome != "" {
+		t.Error("genome should be logged once, when first seen")
+	}
+	run := runs[0]
+	if len(run.Segments) != 2 || run.End != "halt" || run.GasUsed != 7 {
+		t.Fatalf("run: %+v", run)
+	}
+	first, second := run.Segments[0], run.Segments[1]
+	if first.Ring0["health"] != 100 || len(first.Steps) != 4 || first.Ring1["move"] != DirEast {
+		t.Errorf("first segment: %+v", first)
+	}
+	if st := first.Steps[2]; st.PC != 3 || st.Op != "r1! 0 ; set-move east" || st.Stack != "[ 100 2 ]" {
+		t.Errorf("step %+v", st)
+	}
+	if len(second.Steps) != 3 || second.Ring1["action"] != ActionEat || second.Ring1["move"] != 0 {
+		t.Errorf("second segment: %+v", second)
+	}
+}
+
+func TestSchedulerHooks(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	for i := 0; i < 8; i++ {
+		spawnAt(w, NewNPC([]byte{micro.OpHalt}), i, 3)
+	}
+	doomed := w.NPCs[0]
+
+	var order []string
+	s.PreTick(func(s *Scheduler) {
+		order = append(order, fmt.Sprintf("pre %d", s.World.Tick))
+		if s.World.Tick == 1 {
+			doomed.Health = 0 // perturbation: dies this tick
+		}
+	})
+	s.PostTick(func(s *Scheduler) {
+		order = append(order, fmt.Sprintf("post %d n=%d", s.World.Tick, len(s.World.NPCs)))
+	})
+	var evolved int
+	s.OnEvolve(func(s *Scheduler, npcs []*NPC) {
+		evolved = len(npcs)
+		npcs[0].Genome = []byte{micro.OpNop, micro.OpHalt}
+	})
+
+	s.Tick()
+	s.Tick()
+	want := []string{"pre 0", "post 0 n=8", "pre 1", "post 1 n=7"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("hook order = %q, want %q", order, want)
+	}
+	if doomed.Alive() || w.NPCByID(doomed.ID) != nil {
+		t.Error("NPC killed in PreTick was not removed that tick")
+	}
+
+	npcs := s.Evolve(NewGA(testRng()), w.NPCs)
+	if evolved != 7 || len(npcs[0].Genome) != 2 {
+		t.Errorf("OnEvolve saw %d NPCs, genome %x", evolved, npcs[0].Genome)
+	}
+}
+
+func TestTickControl(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	spawnAt(w, NewNPC([]byte{micro.OpHalt}), 3, 3)
+
+	var ticks atomic.Int64
+	s.PostTick(func(*Scheduler) { ticks.Add(1) })
+	waitFor := func(want int64) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for ticks.Load() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("ticks = %d, want %d", ticks.Load(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	s.Pause()
+	done := make(chan struct{})
+	go func() {
+		s.Run(0, nil)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	waitFor(0)
+
+	s.Step(3)
+	waitFor(3)
+	time.Sleep(20 * time.Millisecond)
+	if ticks.Load() != 3 || !s.Paused() {
+		t.Fatalf("after Step(3): ticks=%d paused=%v", ticks.Load(), s.Paused())
+	}
+
+	s.SetSpeed(200)
+	s.Resume()
+	time.Sleep(100 * time.Millisecond)
+	if n := ticks.Load(); n < 5 || n > 60 {
+		t.Errorf("at 200 ticks/s, 100ms ran %d ticks", n-3)
+	}
+
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+}
+
+func TestSchedulerDo(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 3, 3)
+	var blights []Blight
+	s.Subscribe(SubscriberFunc(func(ev Event) {
+		if b, ok := ev.(Blight); ok {
+			blights = append(blights, b)
+		}
+	}))
+
+	// Calls run on the tick goroutine while it waits paused
+	s.Pause()
+	done := make(chan struct{})
+	go func() {
+		s.Run(0, nil)
+		close(done)
+	}()
+	for i := 0; i < 4; i++ {
+		w.SetTile(i, 0, MakeTile(TileFood))
+	}
+	destroyed := -1
+	if !s.Do(func() {
+		npc.SetItem(ItemWeapon)
+		destroyed = s.Blight()
+	}) {
+		t.Fatal("Do refused while running")
+	}
+	if npc.ModSum(ModAttack) != 10 {
+		t.Errorf("SetItem(weapon): attack = %d, want 10", npc.ModSum(ModAttack))
+	}
+	if len(blights) != 1 || blights[0].Destroyed != destroyed {
+		t.Errorf("Blight destroyed %d, events %+v", destroyed, blights)
+	}
+	s.Do(func() { npc.SetItem(ItemNone) })
+	if npc.ModSum(ModAttack) != 0 {
+		t.Errorf("SetItem(none): attack = %d, want 0", npc.ModSum(ModAttack))
+	}
+
+	s.Stop()
+	<-done
+	if s.Do(func() { t.Error("call ran after Stop") }) {
+		t.Error("Do after Stop returned true")
+	}
+
+	if got := MemoryName(Ring0Health); got != "health" {
+		t.Errorf("MemoryName(Ring0Health) = %q", got)
+	}
+	if got := MemoryName(64 + Ring1Action); got != "set-action" {
+		t.Errorf("MemoryName(64+Ring1Action) = %q", got)
+	}
+}
+
+// === Controller Tests ===
+
+type scriptedController struct {
+	moves []int
+	calls int
+}
+
+func (c *scriptedController) Decide(npc *NPC, ring0 []int16) (int, int, int) {
+	c.calls++
+	if len(c.moves) == 0 {
+		return DirNone, ActionIdle, 0
+	}
+	m := c.moves[0]
+	c.moves = c.moves[1:]
+	return m, ActionIdle, 0
+}
+
+func TestControllerOverridesGenome(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			w.SetTile(x, y, MakeTile(TileEmpty))
+		}
+	}
+
+	npc := NewNPC([]byte{micro.OpActMove, DirWest, micro.OpHalt})
+	spawnAt(w, npc, 8, 8)
+	ctl := &scriptedController{moves: []int{DirEast, DirSouth}}
+	s.Controllers = map[uint16]Controller{npc.ID: ctl}
+
+	s.Tick()
+	s.Tick()
+	if npc.X != 9 || npc.Y != 9 {
+		t.Errorf("controller moves should replace the genome: pos=(%d,%d), want (9,9)", npc.X, npc.Y)
+	}
+	if ctl.calls != 2 {
+		t.Errorf("Decide should run once per tick, ran %d times", ctl.calls)
+	}
+}
+
+func TestRemoteBrainProtocol(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 8, 8)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	brain := NewRemoteBrain(client)
+	s.Controllers = map[uint16]Controller{npc.ID: brain}
+
+	got := make(chan RemoteRequest, 1)
+	go func() {
+		dec := json.NewDecoder(server)
+		var req RemoteRequest
+		if err := dec.Decode(&req); err != nil {
+			close(got)
+			return
+		}
+		got <- req
+		go io.Copy(io.Discard, server) // the pipe is unbuffered: drain the request's tail
+		json.NewEncoder(server).Encode(RemoteReply{Move: DirSouth})
+		server.Close() // further decisions fail and idle
+	}()
+
+	s.Tick()
+	req, ok := <-got
+	if !ok || req.ID != npc.ID || len(req.Ring0) != Ring0ExtCount || req.Ring0[Ring0X] != 8 {
+		t.Fatalf("bad request: %+v", req)
+	}
+	if npc.Y != 9 {
+		t.Errorf("reply move should apply: Y=%d, want 9", npc.Y)
+	}
+
+	s.Tick()
+	if brain.Err == nil {
+		t.Error("closed connection should surface in Err")
+	}
+	if npc.Y != 9 {
+		t.Errorf("broken brain should idle: Y=%d", npc.Y)
+	}
+}
+
+func TestCompileBrain(t *testing.T) {
+	genome, err := CompileBrain(`
+		% walk west while healthy, east otherwise
+		DEFINE step == [ west set-move yield ].
+		[health 0 >] [step] [east set-move] ifte
+	`)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 16; x++ {
+			w.SetTile(x, y, MakeTile(TileEmpty))
+		}
+	}
+	npc := NewNPC(genome)
+	spawnAt(w, npc, 8, 8)
+	s.Tick()
+	if npc.X != 7 || npc.Y != 8 {
+		t.Errorf("compiled brain should step west: pos=(%d,%d), want (7,8)", npc.X, npc.Y)
+	}
+
+	for _, src := range []string{
+		"DEFINE loop-forever == [ loop-forever ]. loop-forever",
+		"food-dir set-move frobnicate",
+		"[health 0 >] [eat set-action]",
+		`"hello" set-move`,
+	} {
+		if _, err := CompileBrain(src); err == nil {
+			t.Errorf("CompileBrain(%q) should fail", src)
+		}
+	}
+}
+
+func TestExplainGenome(t *testing.T) {
+	genome, err := CompileBrain("food-dir set-move eat set-action move-to-food friendly set-emotion")
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(ExplainGenome(genome), "\n"), "\n")
+	want := []string{"; food-dir", "; set-move", "", "; set-action eat", "; move-to-food", "", "; set-emotion friendly"}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(lines), len(want), strings.Join(lines, "\n"))
+	}
+	for i, line := range lines {
+		if want[i] == "" && strings.Contains(line, ";") || !strings.HasSuffix(line, want[i]) {
+			t.Errorf("line %d = %q, want it to end in %q", i, line, want[i])
+		}
+	}
+	if got := ExplainGenome([]byte{micro.OpRing0R}); strings.Contains(got, ";") {
+		t.Errorf("truncated read annotated: %q", got)
+	}
+}
+
+// parallelSim builds a seeded world of n NPCs with random genomes.
+func parallelSim(n, size int, workers int) *Scheduler {
+	rng := rand.New(rand.NewSource(7))
+	w := NewWorld(size, rng)
+	w.MaxFood = size * size / 16
+	w.FoodRate = 0.5
+	ga := NewGA(rng)
+	s := NewScheduler(w, 200, io.Discard)
+	s.Workers = workers
+	for i := 0; i < n; i++ {
+		npc := NewNPC(ga.RandomGenome(24 + rng.Intn(16)))
+		npc.X = rng.Intn(size)
+		npc.Y = rng.Intn(size)
+		w.Spawn(npc)
+	}
+	return s
+}
+
+// parallelHashes runs s for 150 ticks with vision cones and returns the
+// world hash after each.
+func parallelHashes(s *Scheduler) []uint64 {
+	s.VisionCone = true
+	hashes := make([]uint64, 0, 150)
+	for i := 0; i < 150; i++ {
+		s.Tick()
+		hashes = append(hashes, s.World.Hash())
+	}
+	return hashes
+}
+
+func TestParallelTickDeterministic(t *testing.T) {
+	one := parallelSim(100, 32, 1)
+	want := parallelHashes(one)
+	if len(one.World.NPCs) == 0 {
+		t.Fatal("everyone died; nothing to compare")
+	}
+	for _, workers := range []int{2, 7} {
+		got := parallelHashes(parallelSim(100, 32, workers))
+		for tick := range want {
+			if got[tick] != want[tick] {
+				t.Errorf("workers=%d diverged from workers=1 at tick %d", workers, tick)
+				break
+			}
+		}
+	}
+}
+
+// With workers, NPCs act in ID order whatever order the population is in.
+func TestParallelActsInIDOrder(t *testing.T) {
+	state := func(reverse bool) map[uint16]NPC {
+		s := parallelSim(100, 32, 3)
+		if reverse {
+			slices.Reverse(s.World.NPCs)
+		}
+		for i := 0; i < 50; i++ {
+			s.Tick()
+		}
+		out := make(map[uint16]NPC, len(s.World.NPCs))
+		for _, npc := range s.World.NPCs {
+			out[npc.ID] = NPC{ID: npc.ID, X: npc.X, Y: npc.Y, Health: npc.Health,
+				Energy: npc.Energy, Gold: npc.Gold, Item: npc.Item, Fitness: npc.Fitness}
+		}
+		return out
+	}
+	want := state(false)
+	if len(want) == 0 {
+		t.Fatal("everyone died; nothing to compare")
+	}
+	if got := state(true); !reflect.DeepEqual(got, want) {
+		t.Error("reversing the population changed the run")
+	}
+}
+
+func TestNPCVMsIsolatedAndPersistent(t *testing.T) {
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+
+	// counter: slot 100 += 1 each tick
+	counter := []byte{micro.OpPushWord, 0, 100, micro.OpLoad, micro.OpInc, micro.OpPushWord, 0, 100, micro.OpStore, micro.OpHalt}
+	a := NewNPC(counter)
+	b := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, a, 2, 2)
+	spawnAt(w, b, 12, 12)
+
+	s.Tick()
+	s.Tick()
+	if got := s.VM(a).MemRead(100); got != 2 {
+		t.Errorf("a's memory should persist across ticks: slot 100 = %d, want 2", got)
+	}
+	if got := s.VM(b).MemRead(100); got != 0 {
+		t.Errorf("b should not see a's memory: slot 100 = %d", got)
+	}
+
+	old := s.VM(a)
+	a.Health = 0
+	s.Tick()
+	c := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, c, 6, 6)
+	if s.VM(c) != old {
+		t.Error("a dead NPC's VM should be reused from the pool")
+	}
+	if got := s.VM(c).MemRead(100); got != 0 {
+		t.Errorf("recycled VM should be wiped: slot 100 = %d", got)
+	}
+}
+
+func TestConfigurableFitness(t *testing.T) {
+	fw, err := ParseFitness("gold=0, kill=40,stress=0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DefaultFitness
+	want.Gold, want.Kill, want.StressDiv = 0, 40, 0
+	if fw != want {
+		t.Errorf("ParseFitness = %+v, want %+v", fw, want)
+	}
+	for _, bad := range []string{"gold", "charisma=3", "food=lots"} {
+		if _, err := ParseFitness(bad); err == nil {
+			t.Errorf("ParseFitness(%q) should fail", bad)
+		}
+	}
+
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 8, 8)
+	npc.Kills = 2
+	npc.Gold = 100
+	s.Fitness = FitnessWeights{Kill: 40}
+	s.Tick()
+	if npc.Fitness != 80 {
+		t.Errorf("fitness with only kills weighted = %d, want 80", npc.Fitness)
+	}
+}
+
+func TestBehavioralFitness(t *testing.T) {
+	fw, err := ParseFitness("explore=3,coop=10,econ=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.Fitness = FitnessWeights{Explore: fw.Explore, Coop: fw.Coop, Econ: fw.Econ}
+	walker := NewNPC([]byte{micro.OpActMove, DirEast, micro.OpHalt})
+	spawnAt(w, walker, 2, 8)
+	giver := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, giver, 12, 12)
+	giver.Shares, giver.TeachCount, giver.Trades, giver.GoldEarned = 2, 1, 3, 4
+
+	for i := 0; i < 3; i++ {
+		s.Tick()
+	}
+	walker.Genome = []byte{micro.OpHalt}
+	s.Tick() // standing still explores nothing new
+	if walker.Explored != 3 || walker.Fitness != 9 {
+		t.Errorf("walker explored %d tiles, fitness %d; want 3 and 9", walker.Explored, walker.Fitness)
+	}
+	if want := 3 + (2+1)*10 + (3+4)*2; giver.Fitness != want {
+		t.Errorf("giver fitness = %d, want %d", giver.Fitness, want)
+	}
+
+	sum := 0
+	for _, term := range DefaultFitness.Breakdown(giver) {
+		sum += term.Value
+	}
+	if sum != DefaultFitness.Score(giver) {
+		t.Errorf("breakdown adds up to %d, Score is %d", sum, DefaultFitness.Score(giver))
+	}
+}
+
+func TestActionCostsConfig(t *testing.T) {
+	costs, err := ParseActionCosts("attack.energy=4, attack.cooldown=3, heal.range=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if costs[ActionAttack] != (ActionCost{Energy: 4, Cooldown: 3, Range: 1}) || costs[ActionHeal].Range != 2 {
+		t.Errorf("parsed costs: attack %+v heal %+v", costs[ActionAttack], costs[ActionHeal])
+	}
+	if costs[ActionShoot] != DefaultActionCosts[ActionShoot] {
+		t.Errorf("unmentioned action changed: %+v", costs[ActionShoot])
+	}
+	for _, bad := range []string{"attack=3", "fly.energy=1", "attack.speed=1", "heal.range=-1"} {
+		if _, err := ParseActionCosts(bad); err == nil {
+			t.Errorf("ParseActionCosts(%q) should fail", bad)
+		}
+	}
+
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	s.Actions = costs
+	attacker := NewNPC([]byte{micro.SmallNumOp(ActionAttack), micro.OpRing1W, Ring1Action,
+		micro.SmallNumOp(2), micro.OpRing1W, Ring1Target, micro.OpHalt})
+	spawnAt(w, attacker, 5, 5)
+	victim := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, victim, 5, 6)
+
+	// The attack lands, then the cooldown idles the next two tries
+	var hits []int
+	for i := 0; i < 6; i++ {
+		before := s.AttackCount
+		s.Tick()
+		hits = append(hits, s.AttackCount-before)
+	}
+	if want := []int{1, 0, 0, 1, 0, 0}; !reflect.DeepEqual(hits, want) {
+		t.Errorf("attacks per tick = %v, want %v", hits, want)
+	}
+	if got := 100 - attacker.Energy; got > 2*4+6 {
+		t.Errorf("attacker spent %d energy, want two 4-energy attacks plus upkeep", got)
+	}
+
+	// A healer reaches two tiles with heal.range=2
+	healer := NewNPC([]byte{micro.SmallNumOp(ActionHeal), micro.OpRing1W, Ring1Action,
+		micro.SmallNumOp(int(victim.ID)), micro.OpRing1W, Ring1Target, micro.OpHalt})
+	spawnAt(w, healer, 5, 8)
+	before := s.HealCount
+	s.Tick()
+	if s.HealCount != before+1 {
+		t.Errorf("heal at range 2 did not land")
+	}
+}
+
+func TestWorldHash(t *testing.T) {
+	a, b := parallelSim(60, 24, 1), parallelSim(60, 24, 3)
+	for tick := 0; tick < 100; tick++ {
+		a.Tick()
+		b.Tick()
+		if ha, hb := a.World.Hash(), b.World.Hash(); ha != hb {
+			t.Fatalf("tick %d: hashes differ %016x vs %016x", tick, ha, hb)
+		}
+	}
+
+	w := a.World
+	h := w.Hash()
+	if w.Hash() != h {
+		t.Fatal("Hash is not stable")
+	}
+	w.NPCs[0].Gold++
+	if w.Hash() == h {
+		t.Error("NPC gold change did not change the hash")
+	}
+	w.NPCs[0].Gold--
+	if w.Hash() != h {
+		t.Fatal("restoring gold did not restore the hash")
+	}
+	vm, sh := a.VM(w.NPCs[0]), w.StateHash()
+	vm.MemWrite(200, vm.MemRead(200)+1)
+	if w.StateHash() == sh {
+		t.Error("brain memory change did not change the state hash")
+	}
+	vm.MemWrite(200, vm.MemRead(200)-1)
+	tile := MakeTile(TileWall)
+	if w.TileAt(0, 0) == tile {
+		tile = MakeTile(TileEmpty)
+	}
+	w.SetTile(0, 0, tile)
+	if w.Hash() == h {
+		t.Error("tile change did not change the hash")
+	}
+}
+
+func TestSourceState(t *testing.T) {
+	a := NewSource(3)
+	for i := 0; i < 1000; i++ {
+		a.Uint64()
+	}
+	state, err := a.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewSource(0)
+	if err := b.UnmarshalBinary(state); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if x, y := a.Int63(), b.Int63(); x != y {
+			t.Fatalf("draw %d after restoring: %d, want %d", i, y, x)
+		}
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	build := func(seed int64) (*Scheduler, *Source, *GA) {
+		src := NewSource(seed)
+		rng := rand.New(src)
+		w := NewWorld(24, rng)
+		w.MaxFood, w.FoodRate = 36, 0.5
+		ga := NewGA(rng)
+		s := NewScheduler(w, 200, io.Discard)
+		s.Mating, s.MaxPopulation = ga, 80
+		s.KeepGraveyard = true
+		s.Fitness.Explore = 1
+		for i := 0; i < 40; i++ {
+			npc := NewNPC(ga.RandomGenome(24 + rng.Intn(16)))
+			npc.X, npc.Y = rng.Intn(24), rng.Intn(24)
+			w.Spawn(npc)
+		}
+		return s, src, ga
+	}
+	run := func(s *Scheduler, ga *GA, ticks int) []uint64 {
+		var hashes []uint64
+		for i := 0; i < ticks; i++ {
+			s.Tick()
+			if s.World.Tick%50 == 0 {
+				s.World.NPCs = s.Evolve(ga, s.World.NPCs)
+			}
+			hashes = append(hashes, s.World.Hash())
+		}
+		return hashes
+	}
+
+	a, srcA, gaA := build(11)
+	run(a, gaA, 120)
+	var buf bytes.Buffer
+	if err := SaveCheckpoint(&buf, a, srcA, []*GA{gaA}, map[string]int{"stage": 2}); err != nil {
+		t.Fatal(err)
+	}
+	want := run(a, gaA, 150)
+	if len(a.World.NPCs) == 0 {
+		t.Fatal("everyone died; nothing to compare")
+	}
+
+	cp, err := LoadCheckpoint(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, srcB, gaB := build(99) // a different start, overwritten by the restore
+	if err := cp.Restore(b, srcB, []*GA{gaB}); err != nil {
+		t.Fatal(err)
+	}
+	if b.World.Tick != 120 {
+		t.Fatalf("restored tick %d, want 120", b.World.Tick)
+	}
+	var again bytes.Buffer
+	if err := SaveCheckpoint(&again, b, srcB, []*GA{gaB}, map[string]int{"stage": 2}); err != nil {
+		t.Fatal(err)
+	}
+	if saved, _ := json.Marshal(cp); !bytes.Equal(bytes.TrimSpace(again.Bytes()), saved) {
+		t.Error("saving the restored run gives a different checkpoint")
+	}
+	got := run(b, gaB, 150)
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tick %d after the checkpoint: hash %016x, want %016x", i, got[i], want[i])
+		}
+	}
+	if b.GasUsed != a.GasUsed || b.TradeCount != a.TradeCount || b.Deaths != a.Deaths || b.Epoch != a.Epoch ||
+		len(b.Graveyard) != len(a.Graveyard) {
+		t.Errorf("counters diverged: gas %d/%d trades %d/%d deaths %v/%v epochs %d/%d graves %d/%d",
+			b.GasUsed, a.GasUsed, b.TradeCount, a.TradeCount, b.Deaths, a.Deaths, b.Epoch, a.Epoch, len(b.Graveyard), len(a.Graveyard))
+	}
+	for i, npc := range b.World.NPCs {
+		if va, vb := a.World.NPCs[i].vm, npc.vm; (va == nil) != (vb == nil) || vb != nil && !bytes.Equal(vb.Memory, va.Memory) {
+			t.Fatalf("NPC %d's brain memory diverged", npc.ID)
+		}
+	}
+	if sa, sb := srcA.Uint64(), srcB.Uint64(); sa != sb {
+		t.Errorf("random streams diverged: next draw %d, want %d", sb, sa)
+	}
+
+	var extra map[string]int
+	if err := json.Unmarshal(cp.Extra, &extra); err != nil || extra["stage"] != 2 {
+		t.Errorf("extra = %s (%v), want stage 2", cp.Extra, err)
+	}
+	if err := cp.Restore(NewScheduler(NewWorld(16, testRng()), 200, io.Discard), NewSource(1), []*GA{gaB}); err == nil {
+		t.Error("restored into a world of another size")
+	}
+}
+
+func TestTileColors(t *testing.T) {
+	if len(tileColors) != numTileTypes {
+		t.Fatalf("%d tile colours, want one per tile type, %d", len(tileColors), numTileTypes)
+	}
+	w := NewWorld(4, testRng())
+	for typ := byte(TileEmpty); typ < numTileTypes; typ++ {
+		w.SetTile(1, 1, MakeTile(typ))
+		if got := Render(w, 1, ColorByRole).ColorIndexAt(1, 1); got != typ {
+			t.Errorf("tile type %d drawn in colour %d", typ, got)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	w := NewWorld(16, testRng())
+	for _, pos := range [][2]int{{2, 2}, {4, 2}, {6, 2}, {8, 2}} {
+		w.SetTile(pos[0], pos[1], MakeTile(TileEmpty))
+	}
+	w.SetTile(0, 0, MakeTile(TileFood))
+	spawn := func(x, y, fitness int) *NPC {
+		npc := NewNPC([]byte{micro.OpHalt})
+		npc.X, npc.Y, npc.Fitness = x, y, fitness
+		w.Spawn(npc)
+		return npc
+	}
+	spawn(2, 2, 0)
+	pred := spawn(4, 2, 10)
+	pred.Predator = true
+	clan := spawn(6, 2, 40)
+	clan.Clan = 3
+	clan.Item = ItemTool
+	dead := spawn(8, 2, 40)
+	dead.Health = 0
+
+	const scale = 4
+	at := func(img *image.Paletted, x, y int) uint8 { return img.ColorIndexAt(x*scale+1, y*scale+1) }
+	img := Render(w, scale, ColorByRole)
+	if b := img.Bounds(); b.Dx() != 16*scale || b.Dy() != 16*scale {
+		t.Fatalf("bounds %v, want %dx%d", b, 16*scale, 16*scale)
+	}
+	if got := img.ColorIndexAt(0, 0); got != TileFood {
+		t.Errorf("food tile index %d, want %d", got, TileFood)
+	}
+	if got := at(img, 2, 2); got != paletteNPC {
+		t.Errorf("plain NPC index %d, want %d", got, paletteNPC)
+	}
+	if got := at(img, 4, 2); got != palettePred {
+		t.Errorf("predator index %d, want %d", got, palettePred)
+	}
+	if got := at(img, 6, 2); got != paletteClans+3 {
+		t.Errorf("clan NPC index %d, want %d", got, paletteClans+3)
+	}
+	if got := img.ColorIndexAt(6*scale+scale/2, 2*scale+scale/2); got != paletteItem {
+		t.Errorf("item marker index %d, want %d", got, paletteItem)
+	}
+	if got := at(img, 8, 2); got != TileEmpty {
+		t.Errorf("dead NPC drawn: index %d", got)
+	}
+
+	img = Render(w, scale, ColorByFitness)
+	if lo, hi := at(img, 2, 2), at(img, 6, 2); lo != paletteFitness || hi != paletteFitness+fitnessShades-1 {
+		t.Errorf("fitness shades %d..%d, want %d..%d", lo, hi, paletteFitness, paletteFitness+fitnessShades-1)
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeTimelapse(&buf, []*image.Paletted{img, Render(w, 1, ColorByRole)}, 10); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != 2 || anim.Delay[1] != 10 {
+		t.Errorf("timelapse: %d frames, delays %v", len(anim.Image), anim.Delay)
+	}
+}
+
+func TestSensorFieldsMatchScans(t *testing.T) {
+	s := parallelSim(150, 40, 0)
+	w := s.World
+	for i := 0; i < 30; i++ {
+		s.Tick()
+	}
+	s.fields.build(w)
+	f := &s.fields
+
+	for y := 0; y < w.Size; y++ {
+		for x := 0; x < w.Size; x++ {
+			if d, _ := f.nearest(f.food, x, y); d != w.NearestFood(x, y) {
+				t.Fatalf("food distance at (%d,%d) = %d, scan says %d", x, y, d, w.NearestFood(x, y))
+			}
+			if c := f.food[y*w.Size+x]; c.dist > 0 {
+				if sx, sy := int(c.src)%w.Size, int(c.src)/w.Size; w.TileAt(sx, sy).Type() != TileFood || abs(sx-x)+abs(sy-y) != int(c.dist) {
+					t.Fatalf("food field at (%d,%d) points at a non-food or farther tile", x, y)
+				}
+			}
+			if d, _ := f.nearest(f.item, x, y); d != func() int { d, _ := w.NearestItem(x, y); return d }() {
+				t.Fatalf("item distance mismatch at (%d,%d)", x, y)
+			}
+			if d, _ := f.nearest(f.poison, x, y); d != w.NearestPoison(x, y) {
+				t.Fatalf("poison distance mismatch at (%d,%d)", x, y)
+			}
+		}
+	}
+	for _, npc := range w.NPCs {
+		d, id, _ := f.nearestNPC(npc.X, npc.Y, npc.ID)
+		wd, _, _ := w.NearestNPCFull(npc.X, npc.Y, npc.ID)
+		if d != wd {
+			t.Fatalf("NPC %d: nearest-NPC distance %d, scan says %d", npc.ID, d, wd)
+		}
+		if other := w.NPCByID(id); id != 0 && (other == nil || abs(other.X-npc.X)+abs(other.Y-npc.Y) != d) {
+			t.Fatalf("NPC %d: field names NPC %d, which is not at distance %d", npc.ID, id, d)
+		}
+	}
+}
+
+
+// === Curriculum Tests ===
+
+func TestCurriculumStages(t *testing.T) {
+	c, err := ParseCurriculum("at=10,food=0.1,maxfood=5\n# harder\nfit=5000,poison=3;night=128")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Stages) != 3 || c.Stages[0].Tick != 10 || c.Stages[1].Fitness != 5000 || c.Stages[2].NightTicks != 128 {
+		t.Fatalf("parsed stages: %+v", c.Stages)
+	}
+	for _, bad := range []string{"at=x", "food=2", "night=300", "speed=1", "at"} {
+		if _, err := ParseCurriculum(bad); err == nil {
+			t.Errorf("ParseCurriculum(%q) should fail", bad)
+		}
+	}
+
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 5, 5)
+	var reached []int
+	s.Subscribe(SubscriberFunc(func(ev Event) {
+		if e, ok := ev.(StageReached); ok {
+			reached = append(reached, e.Stage)
+		}
+	}))
+	c.Install(s)
+
+	w.Tick = 9
+	s.Tick()
+	if c.Reached != 0 || w.MaxFood == 5 {
+		t.Fatal("stage 0 applied before its tick")
+	}
+	s.Tick()
+	if c.Reached != 1 || w.FoodRate != 0.1 || w.MaxFood != 5 {
+		t.Fatalf("stage 0 at tick 10: reached=%d food_rate=%v max_food=%d", c.Reached, w.FoodRate, w.MaxFood)
+	}
+
+	// The fitness milestone holds back the stage after it too
+	s.Tick()
+	if c.Reached != 1 || w.PoisonOdds != 10 || w.NightTicks != DayCycle/4 {
+		t.Fatalf("fitness stage applied below its milestone: reached=%d", c.Reached)
+	}
+	c.Stages[1].Fitness = npc.Fitness
+	s.Tick()
+	if c.Reached != 3 || w.PoisonOdds != 3 || w.NightTicks != 128 {
+		t.Fatalf("fitness milestone should release the last stages: reached=%d poison=%d night=%d", c.Reached, w.PoisonOdds, w.NightTicks)
+	}
+	if len(reached) != 3 || reached[2] != 2 {
+		t.Errorf("StageReached events: %v", reached)
+	}
+
+	w.Tick = DayCycle / 2
+	if !w.Night() {
+		t.Error("a 128-tick winter should start mid-cycle")
+	}
+}
+
+func TestResourceGradients(t *testing.T) {
+	gs, err := ParseGradients("food=x:1,0\n# belt\npoison=y:0,2,0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gs.Food == nil || gs.Items != nil || gs.Poison == nil || gs.Poison.Axis != 'y' {
+		t.Fatalf("parsed gradients: %+v", gs)
+	}
+	if got := gs.Poison.At(33, 0, 16); got != 2 {
+		t.Errorf("belt middle weight = %v, want 2", got)
+	}
+	if got := gs.Food.At(33, 16, 0); got != 0.5 {
+		t.Errorf("food halfway weight = %v, want 0.5", got)
+	}
+	for _, bad := range []string{"food", "food=z:1", "food=x:", "food=x:-1", "gold=x:1"} {
+		if _, err := ParseGradients(bad); err == nil {
+			t.Errorf("ParseGradients(%q) should fail", bad)
+		}
+	}
+
+	// Food rich in the west lands mostly in the western half
+	w := NewWorld(32, testRng())
+	w.FoodRate, w.MaxFood, w.NightTicks = 1, 200, 0
+	w.Gradients = gs
+	for i := 0; i < 100; i++ {
+		w.RespawnFood()
+	}
+	west, east := 0, 0
+	for y := 0; y < w.Size; y++ {
+		for x := 0; x < w.Size; x++ {
+			if w.TileAt(x, y).Type() == TileFood {
+				if x < w.Size/2 {
+					west++
+				} else {
+					east++
+				}
+			}
+		}
+	}
+	if west < 2*east || west == 0 {
+		t.Errorf("food west=%d east=%d, want the west much richer", west, east)
+	}
+}
+
+func TestSensorNoise(t *testing.T) {
+	if n, err := ParseSensorNoise("# none", 1); n != nil || err != nil {
+		t.Fatalf("empty spec = %v, %v; want nil", n, err)
+	}
+	for _, bad := range []string{"food-dist", "smell=gauss:1", "food-dist=gauss:x", "food-dist=drop:2", "food-dist=blur:1"} {
+		if _, err := ParseSensorNoise(bad, 1); err == nil {
+			t.Errorf("ParseSensorNoise(%q) should fail", bad)
+		}
+	}
+	n, err := ParseSensorNoise("x=gauss:3\ny=drop:1;*=uniform:0", 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Rules[Ring0X].Kind != NoiseGauss || n.Rules[Ring0Y].Dropout != 1 || n.Rules[Ring0Health].Kind != NoiseUniform {
+		t.Fatalf("rules = %+v", n.Rules)
+	}
+
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	npc := NewNPC([]byte{micro.OpHalt})
+	spawnAt(w, npc, 8, 8)
+	s.SensorNoise = n
+
+	// x wanders around the truth, y always drops out, uniform:0 is exact
+	var xs []int16
+	blurred := false
+	for tick := 0; tick < 50; tick++ {
+		w.Tick = tick
+		s.sense(npc)
+		vm := s.VM(npc)
+		x := vm.MemRead(Ring0X)
+		xs = append(xs, x)
+		blurred = blurred || x != 8
+		if vm.MemRead(Ring0Y) != 0 || vm.MemRead(Ring0Health) != int16(npc.Health) {
+			t.Fatalf("tick %d: y=%d health=%d, want 0 and exact", tick, vm.MemRead(Ring0Y), vm.MemRead(Ring0Health))
+		}
+		if x < 8-15 || x > 8+15 {
+			t.Errorf("tick %d: x=%d, more than 5 SD off", tick, x)
+		}
+	}
+	if !blurred {
+		t.Error("gauss:3 never changed x")
+	}
+
+	// The same seed, NPC and tick blur the same way
+	for tick, want := range xs[:5] {
+		w.Tick = tick
+		s.sense(npc)
+		if got := s.VM(npc).MemRead(Ring0X); got != want {
+			t.Errorf("tick %d replayed x=%d, want %d", tick, got, want)
+		}
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	as, err := ParseAssertions("trades >= 1 by tick 12\n# survival\npopulation never below 1;always tick < 11;teaches == 0;kills > 0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(as.Checks) != 5 || as.Checks[0].By != 12 || !as.Checks[1].Always || as.Checks[1].Op != ">=" || !as.Checks[2].Always {
+		t.Fatalf("parsed checks: %+v", as.Checks)
+	}
+	for _, bad := range []string{"trades", "speed > 1", "trades ~ 1", "trades > x", "trades > 1 by", "trades > 1 by 0",
+		"always trades > 1 by 5", "trades never 5", "trades never below"} {
+		if _, err := ParseAssertions(bad); err == nil {
+			t.Errorf("ParseAssertions(%q) should fail", bad)
+		}
+	}
+
+	w := NewWorld(16, testRng())
+	s := NewScheduler(w, 200, io.Discard)
+	spawnAt(w, NewNPC([]byte{micro.OpHalt}), 5, 5)
+	var failures []int
+	s.Subscribe(SubscriberFunc(func(ev Event) {
+		if e, ok := ev.(AssertionFailed); ok {
+			failures = append(failures, e.Check)
+		}
+	}))
+	as.Install(s)
+
+	w.Tick = 9
+	s.Tick()
+	s.TradeCount = 1
+	s.Tick()
+	if !as.Checks[0].Done || !as.Checks[0].Passed || as.Checks[0].Tick != 10 {
+		t.Fatalf("deadline check should pass at tick 10: %+v", as.Checks[0])
+	}
+	s.Tick()
+	if c := as.Checks[2]; !c.Done || c.Passed || c.Tick != 11 || len(failures) != 1 || failures[0] != 2 {
+		t.Fatalf("always check should fail at tick 11: %+v, failures %v", c, failures)
+	}
+
+	if failed := as.Finish(s); failed != 2 {
+		t.Errorf("Finish: %d failed, want 2", failed)
+	}
+	if !as.Checks[1].Passed || !as.Checks[3].Passed || as.Checks[4].Passed {
+		t.Errorf("end-of-run verdicts: %+v", as.Checks)
+	}
+	if len(failures) != 2 || failures[1] != 4 {
+		t.Errorf("AssertionFailed events: %v", failures)
+	}
+}
+
+func TestSelfModify(t *testing.T) {
+	// Patch the west push at 4 into an east push, then move
+	genome := []byte{
+		micro.OpPushByte, micro.SmallNumOp(DirEast), micro.SmallNumOp(4), micro.OpPoke,
+		micro.SmallNumOp(DirWest), micro.OpRing1W, Ring1Move,
+		micro.OpHalt,
+	}
+	for _, on := range []bool{false, true} {
+		w := NewWorld(16, testRng())
+		s := NewScheduler(w, 200, io.Discard)
+		s.SelfModify = on
+		npc := NewNPC(append([]byte(nil), genome...))
+		spawnAt(w, npc, 8, 8)
+		s.Tick()
+
+		wantX := 7 // without SelfModify the poke does nothing
+		if on {
+			wantX = 9
+		}
+		if npc.X != wantX || npc.Y != 8 {
+			t.Errorf("SelfModify=%v: NPC at (%d,%d), want (%d,8)", on, npc.X, npc.Y, wantX)
+		}
+		if !bytes.Equal(npc.Genome, genome) {
+			t.Errorf("SelfModify=%v: the patch reached the genome: % x", on, npc.Genome)
+		}
+	}
+
+	// A patch that would cut an instruction short is refused
+	vm := micro.New()
+	vm.SelfModify = true
+	vm.Load([]byte{micro.OpPushByte, micro.OpRing1W, micro.SmallNumOp(4), micro.OpPoke, micro.OpNop})
+	if err := vm.Run(); err == nil || !strings.Contains(err.Error(), "past the end") || vm.Code[4] != micro.OpNop {
+		t.Errorf("unverifiable patch: err %v, code % x", err, vm.Code)
+	}
+}
+
+func TestGasCosts(t *testing.T) {
+	genome := []byte{micro.SmallNumOp(2), micro.SmallNumOp(3), micro.OpMul, micro.OpDrop, micro.OpHalt}
+	weighted := micro.WeightedGas
+	free, err := micro.ParseGasCosts("weighted,num=0,*=3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		costs *micro.GasTable
+		want  int64
+	}{
+		{nil, 5},       // 1 per instruction
+		{&weighted, 8}, // * costs 4
+		{free, 5},      // numbers are free, * costs 3
+	} {
+		w := NewWorld(16, testRng())
+		s := NewScheduler(w, 200, io.Discard)
+		s.GasCosts = tc.costs
+		spawnAt(w, NewNPC(genome), 8, 8)
+		s.Tick()
+		if s.GasUsed != tc.want {
+			t.Errorf("GasUsed = %d, want %d", s.GasUsed, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"weighted,exec", "exec=x", "exec=300", "nosuch=1", "exec=1,flat"} {
+		if _, err := micro.ParseGasCosts(bad); err == nil {
+			t.Errorf("ParseGasCosts(%q) should fail", bad)
+		}
+	}
+}
+
+func TestVMPool(t *testing.T) {
+	var pool micro.Pool
+	vm := pool.Get()
+	vm.SetMemorySlots(4096)
+	vm.MemWriteAt(4000, 7)
+	vm.MemWrite(Ring1Move, 3)
+	vm.DefineQuot(5, []byte{micro.OpDup})
+	vm.Locals[2] = 9
+	vm.MaxGas, vm.Gas, vm.SelfModify, vm.GasCosts = 50, 10, true, &micro.WeightedGas
+	vm.Output = io.Discard
+	vm.PushInt(1)
+	pool.Put(vm)
+
+	// sync.Pool may drop vm; whichever VM comes back must look new
+	got, fresh := pool.Get(), micro.New()
+	if got.MemorySlots() != micro.DefaultMemorySlots || !bytes.Equal(got.Memory, fresh.Memory) {
+		t.Errorf("memory not reset: %d slots", got.MemorySlots())
+	}
+	if got.Quotations[5] != nil || got.Locals != fresh.Locals || got.SP != 0 {
+		t.Error("quotations, locals or stack survived Put")
+	}
+	if got.MaxGas != 0 || got.Gas != 0 || got.SelfModify || got.GasCosts != nil || got.Output != fresh.Output {
+		t.Error("settings survived Put")
+	}
+	got.SetMemorySlots(4096)
+	if v := got.MemReadAt(4000); v != 0 {
+		t.Errorf("regrown memory kept slot 4000 = %d", v)
+	}
+}
+
+func TestMinimizeGenome(t *testing.T) {
+	// r0@ hunger drop (dead), r0@ food-dist, jz over "3 set-move", 1 set-action, yield
+	genome := []byte{
+		micro.OpRing0R, Ring0Hunger, micro.OpDrop,
+		micro.OpRing0R, Ring0Food, micro.OpJumpZ, 3,
+		0x23, micro.OpRing1W, Ring1Move,
+		0x21, micro.OpRing1W, Ring1Action, micro.OpYield,
+	}
+	cases := []map[byte]int16{{Ring0Food: 0}, {Ring0Food: 4, Ring0Hunger: 9}}
+	keep := func(code []byte) bool {
+		for _, sensors := range cases {
+			if !reflect.DeepEqual(RunBrain(code, sensors, 100), RunBrain(genome, sensors, 100)) {
+				return false
+			}
+		}
+		return true
+	}
+	got := micro.Minimize(genome, keep)
+	want := genome[3:]
+	if !bytes.Equal(got, want) {
+		t.Errorf("Minimize = % x, want % x", got, want)
+	}
+	if outs := RunBrain(got, cases[1], 100); outs[0][Ring1Move] != 3 || outs[0][Ring1Action] != 1 {
+		t.Errorf("minimal genome outputs %v", outs)
+	}
+
+	// Cutting before a jump's target moves the jump; far calls follow too
+	code := []byte{micro.OpJump, 2, micro.OpNop, micro.OpNop, micro.OpCallFar, 0, 8, micro.OpNop, micro.OpHalt}
+	if cut := micro.Cut(code, 2, 3); !bytes.Equal(cut, []byte{micro.OpJump, 1, micro.OpNop, micro.OpCallFar, 0, 7, micro.OpNop, micro.OpHalt}) {
+		t.Errorf("Cut = % x", cut)
+	}
+}
+
+func TestParseSensors(t *testing.T) {
+	got, err := ParseSensors("food-dist=3 hunger=-2 20=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[byte]int16{Ring0Food: 3, Ring0Hunger: -2, 20: 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSensors = %v, want %v", got, want)
+	}
+	for _, bad := range []string{"food-dist", "smell=1", "64=1", "hunger=x", "hunger=40000"} {
+		if _, err := ParseSensors(bad); err == nil {
+			t.Errorf("ParseSensors(%q) should fail", bad)
+		}
+	}
+}
+
+func TestStateHashIgnoresGenomes(t *testing.T) {
+	w := NewWorld(16, testRng())
+	npc := NewNPC([]byte{micro.OpYield})
+	spawnAt(w, npc, 4, 4)
+	h, state := w.Hash(), w.StateHash()
+	npc.Genome = append(npc.Genome, micro.OpNop)
+	if w.Hash() == h {
+		t.Error("genome change did not change Hash")
+	}
+	if w.StateHash() != state {
+		t.Error("genome change changed StateHash")
+	}
+	npc.Gold++
+	if w.StateHash() == state {
+		t.Error("gold change did not change StateHash")
+	}
+}
@@ -1,7 +1,11 @@
 package sandbox
 
 import (
+	"cmp"
 	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
 
 	"github.com/psilLang/psil/pkg/micro"
 )
@@ -13,6 +17,33 @@ const DayCycle = 256
 var forgeRecipes = map[byte]byte{
 	ItemTool:   ItemCompass,
 	ItemWeapon: ItemShield,
+	ItemCharm:  ItemRemedy,
+}
+
+// Recipe knowledge bits (NPC.Recipes). All forge recipes are advanced: with
+// Scheduler.RecipeMemes enabled an NPC must know a recipe before using it.
+const (
+	RecipeCompass byte = 1 << iota // tool → compass
+	RecipeShield                   // weapon → shield
+	RecipeRemedy                   // charm → remedy
+)
+
+// recipeBits maps a recipe's input item to its knowledge bit.
+var recipeBits = map[byte]byte{
+	ItemTool:   RecipeCompass,
+	ItemWeapon: RecipeShield,
+	ItemCharm:  RecipeRemedy,
+}
+
+// discoverChance is the 1-in-N chance per attempt of discovering an
+// unknown recipe by experimenting on a forge.
+const discoverChance = 8
+
+// Controller decides an NPC's Ring1 outputs in place of its genome, e.g. a
+// human player or an external agent (see RemoteBrain). Decide is called once
+// per tick, after sensing, with the NPC's Ring0 slots.
+type Controller interface {
+	Decide(npc *NPC, ring0 []int16) (move, action, target int)
 }
 
 // Scheduler runs the sandbox tick loop.
@@ -21,8 +52,22 @@ type Scheduler struct {
 	Gas    int // gas limit per NPC brain execution
 	Output io.Writer
 
-	vm           *micro.VM        // reusable VM instance
+	vmPool       micro.Pool        // VMs recycled from dead NPCs
 	tradeIntents map[uint16]uint16 // NPC ID -> target NPC ID
+	followers    map[uint16]int    // leader ID -> follower count (refreshed each tick)
+	mateIntents  map[uint16]uint16 // NPC ID -> chosen partner ID
+	caregivers   map[uint16]bool   // parents with a dependent child (refreshed each tick)
+	noises       noiseLog          // this and last tick's noise events
+	subscribers  []Subscriber      // event listeners (see Subscribe)
+	preTick      []TickHook        // see PreTick
+	postTick     []TickHook        // see PostTick
+	onEvolve     []EvolveHook      // see OnEvolve
+	clock        clock             // pause/step/speed state (see Wait)
+	plans        [][]ring1Out      // per-NPC Ring1 outputs planned by the workers
+	order        []int             // NPC indexes in the order Tick acts for them
+	fields       sensorFields      // per-tick nearest-X fields (SensorFields)
+	hunting      bool              // a predator is alive (refreshed each tick)
+	prices       marketPrices      // this tick's market prices (see priceMarkets)
 	TradeCount     int               // total bilateral trades completed
 	TeachCount     int               // total successful teach events
 	AttackCount    int               // total attack actions executed
@@ -30,39 +75,136 @@ type Scheduler struct {
 	HarvestCount   int               // total harvest actions executed
 	TerraformCount int               // total terraform actions executed
 	KillCount      int               // total NPCs killed by attacks
+	PreyKills      int               // prey killed by predators
+	BuildCount     int               // total structures built
+	DepositCount   int               // total items stored in chests
+	RaidCount      int               // total items stolen from others' chests
+	ShotCount      int               // total shots fired
+	ShotHits       int               // total shots that hit
+	GiftCount      int               // total items given away
+	GoldGifted     int               // total gold given away
+	ClanJoins      int               // total clan joins (including founding)
+	SleepTicks     int               // total NPC-ticks spent asleep
+	MarketBuys     int               // total meals and items bought at markets
+	MarketSells    int               // total items sold at markets
+	GoldSpent      int               // total gold spent at markets
+	Treasury       int               // gold taxed or seized, awaiting SettleEconomy
+	TaxCollected   int               // total gold taxed or seized above the wealth cap
+	GoldMinted     int               // total gold created by the Economy faucet
+	GoldBurned     int               // total treasury gold destroyed
+	Contracts      []Contract        // open contracts (see ContractEvery)
+	ContractsDone  int               // total contracts fulfilled
+	ContractGold   int               // total gold paid for contracts
+	Offers         []Offer           // items left at the trading posts
+	PostOffers     int               // total offers left at trading posts
+	PostTrades     int               // total trades completed through trading posts
+	Lessons        map[int]int       // successful teaches per school tile index
+	GasUsed        int64             // total VM gas spent running genomes (updated atomically by the workers)
+	BirthCount     int               // total children born by in-world mating
+	Epoch          int               // GA generations run by Evolve
+	CareEnergy     int               // total energy fed by parents to offspring
+	Infections     int               // total NPCs infected (outbreaks and contagion)
+	Cures          int               // total infections cured by a remedy
+	clansFounded   int               // clan IDs handed out so far
+	RecipesLearned int               // recipes acquired by teaching or forge discovery
+	Deaths         [DeathCauses]int  // deaths by cause (DeathStarvation..DeathAge)
+	Graveyard      []Biography       // life stories of the dead (only with KeepGraveyard)
+
+	RecipeMemes bool // advanced recipes are unknown until taught or discovered
+	ShootRange  int  // max tiles a shot travels (0 disables ranged attacks)
+	Actions     ActionCosts // per-action energy, cooldown and range (DefaultActionCosts)
+	GiftFitness int  // fitness per gift given (0 = altruism unrewarded)
+	Fitness     FitnessWeights // per-stat fitness weights (DefaultFitness)
+	ClanShare   float64 // fraction of fitness taken from the clan average (0-1)
+	Mating      *GA     // breeds children for act.mate (nil disables in-world mating)
+	PredatorMating *GA  // breeds predator children (nil = Mating)
+	Contagion   int     // % chance per tick an infected NPC infects each neighbour (0 disables disease)
+	VisionCone  bool    // Nearest*/direction sensors only see the 90° cone the NPC faces
+	KeepGraveyard bool  // record a Biography for every NPC that dies
+	Controllers map[uint16]Controller // NPCs driven by a Controller instead of their genome
+	MaxPopulation int   // no births while this many NPCs are alive (0 = no cap)
+	Workers     int     // plan brains on this many goroutines, then act in NPC-ID order (0 = interleaved; see planAll)
+	SensorFields bool   // nearest food/item/poison/NPC sensors from per-tick BFS fields (start-of-tick values)
+	SensorNoise  *SensorNoise // blur and drop sensor readings (nil = exact senses; see ParseSensorNoise)
+	Trace       *BrainTrace // records one NPC's genome runs (nil = none)
+	SelfModify  bool        // genomes may poke their own code; patches last for the turn, not the genome
+	GasCosts    *micro.GasTable // per-opcode gas for brains (nil = 1 per instruction, as on the Z80)
+	Economy     Economy         // tax, faucet and wealth-cap experiment (zero = none; see SettleEconomy)
+	ContractEvery int           // ticks between contract postings (0 disables contracts)
+}
+
+// Blight wipes out about half the food on the map now, emits Blight and
+// returns how many food tiles were lost.
+func (s *Scheduler) Blight() int {
+	n := s.World.Blight()
+	s.emit(Blight{Tick: s.World.Tick, Destroyed: n})
+	return n
 }
 
+// ring1Out is one set of Ring1 outputs: a yield's worth of intent.
+type ring1Out [Ring1Count]int16
+
 // NewScheduler creates a scheduler for the given world.
 func NewScheduler(w *World, gas int, output io.Writer) *Scheduler {
 	return &Scheduler{
 		World:        w,
 		Gas:          gas,
 		Output:       output,
-		vm:           micro.New(),
 		tradeIntents: make(map[uint16]uint16),
+		followers:    make(map[uint16]int),
+		mateIntents:  make(map[uint16]uint16),
+		caregivers:   make(map[uint16]bool),
+		noises:       newNoiseLog(),
+		ShootRange:   4,
+		Actions:      DefaultActionCosts,
+		Fitness:      DefaultFitness,
 	}
 }
 
 // Tick runs one simulation step.
 func (s *Scheduler) Tick() {
 	w := s.World
+	s.runTickHooks(s.preTick)
+	s.countFollowers()
+	s.countCaregivers()
+	s.countPredators()
+	s.priceMarkets()
+	s.noises.age()
+	if s.SensorFields {
+		s.fields.build(w)
+	}
 
-	for _, npc := range w.NPCs {
+	// With workers, every brain runs first against the start-of-tick world
+	if s.Workers > 0 {
+		s.planAll()
+	}
+
+	for _, i := range s.tickOrder() {
+		npc := w.NPCs[i]
 		if !npc.Alive() {
 			continue
 		}
 
-		// 1. Sense: fill Ring0
-		s.sense(npc)
+		if s.Workers > 0 && s.Controllers[npc.ID] == nil {
+			// 1-3. Carry out the planned outputs
+			s.actPlan(npc, s.plans[i])
+		} else {
+			// 1. Sense: fill Ring0
+			s.sense(npc)
 
-		// 2. Think: run genome
-		s.think(npc)
+			// 2. Think: run genome
+			s.think(npc)
 
-		// 3. Act: read Ring1, apply to world
-		s.act(npc)
+			// 3. Act: read Ring1, apply to world
+			s.act(npc)
+		}
 
 		// 4. Auto-actions: eat food (extended radius), auto-craft on forge
-		s.autoActions(npc)
+		if npc.Asleep {
+			s.sleep(npc)
+		} else {
+			s.autoActions(npc)
+		}
 
 		// 4b. Apply and decay modifiers
 		applyModifiers(npc)
@@ -73,13 +215,16 @@ func (s *Scheduler) Tick() {
 		if npc.Energy <= 0 {
 			npc.Health -= 5
 			npc.Energy = 0
+			npc.lastHarm = DeathStarvation
 		}
 		npc.Age++
 		npc.Hunger++
+		npc.IncomingFire = 0
 
 		// Natural death: max age reached
 		if npc.Age >= MaxAge {
 			npc.Health = 0
+			npc.lastHarm = DeathAge
 		}
 
 		// 5b. Stress events
@@ -89,6 +234,9 @@ func (s *Scheduler) Tick() {
 		if npc.Energy > 150 {
 			npc.Stress-- // resting decay
 		}
+		if w.TileAt(npc.X, npc.Y).Type() == TileShelter {
+			npc.Stress -= 2 // sheltered rest
+		}
 		if npc.Stress > 100 {
 			npc.Stress = 100
 		}
@@ -103,13 +251,15 @@ func (s *Scheduler) Tick() {
 		if npc.Alive() {
 			alive = append(alive, npc)
 		} else {
-			// Determine underlying tile to preserve (forge)
+			s.recordDeath(npc)
+			s.releaseVM(npc)
+			// Determine underlying tile to preserve (forge, structures)
 			baseTile := byte(TileEmpty)
-			if w.TileAt(npc.X, npc.Y).Type() == TileForge {
-				baseTile = TileForge
+			if typ := w.TileAt(npc.X, npc.Y).Type(); typ == TileForge || typ == TileMarket || typ == TilePost || typ == TileSchool || typ == TileWall || isStructure(typ) {
+				baseTile = typ
 			}
 			// Drop held item as a tile (only standard items get dropped)
-			if npc.Item >= ItemTool && npc.Item <= ItemTreasure && baseTile != TileForge {
+			if npc.Item >= ItemTool && npc.Item <= ItemTreasure && baseTile == TileEmpty {
 				tileType := byte(TileTool) + npc.Item - ItemTool
 				w.SetTile(npc.X, npc.Y, MakeTile(tileType))
 			} else {
@@ -121,8 +271,9 @@ func (s *Scheduler) Tick() {
 	}
 	w.NPCs = alive
 
-	// 5. Resolve bilateral trades
+	// 5. Resolve bilateral trades and mating
 	s.resolveTrades()
+	s.resolveMating()
 
 	// 6. Respawn food and items
 	w.RespawnFood()
@@ -139,9 +290,18 @@ func (s *Scheduler) Tick() {
 	// 6b. Decay poison tiles and trigger periodic blights
 	w.DecayPoison()
 	if w.Tick > 0 && w.Tick%1024 == 0 {
-		w.Blight()
+		s.Blight()
 	}
 
+	// 6b'. Disease: outbreaks, contagion, recovery
+	s.spreadDisease()
+
+	// 6b''. Contracts: deliveries, lapses, new postings
+	s.runContracts()
+
+	// 6b'''. Trading posts: lapsed offers go back to their owners
+	s.tendPosts()
+
 	// 6c. Decay tile cooldowns
 	for i := range w.Cooldowns {
 		if w.Cooldowns[i] > 0 {
@@ -151,42 +311,87 @@ func (s *Scheduler) Tick() {
 
 	// 7. Score fitness (stress penalty, crafting bonus, teaching bonus)
 	for _, npc := range w.NPCs {
-		npc.Fitness = npc.Age + npc.FoodEaten*10 + npc.Health + npc.Gold*20 + npc.CraftCount*30 + npc.TeachCount*15 - npc.Stress/5
+		if s.Fitness.Explore != 0 {
+			npc.visit(w)
+		}
+		npc.Fitness = s.Fitness.Score(npc) + npc.GiftCount*s.GiftFitness
 	}
+	s.shareClanFitness()
+	s.runTickHooks(s.postTick)
 
 	w.Tick++
 }
 
-// sense fills Ring0 slots from world state.
+// VM returns npc's own brain VM, taking one from the pool on first use.
+// Memory outside Ring0/Ring1 (and locals) persists across the NPC's ticks.
+func (s *Scheduler) VM(npc *NPC) *micro.VM {
+	if npc.vm == nil {
+		npc.vm = s.vmPool.Get()
+	}
+	return npc.vm
+}
+
+// releaseVM wipes a dead NPC's VM and returns it to the pool.
+func (s *Scheduler) releaseVM(npc *NPC) {
+	if npc.vm == nil {
+		return
+	}
+	s.vmPool.Put(npc.vm)
+	npc.vm = nil
+}
+
+// sense fills Ring0 slots from world state. Once npc has a VM it only
+// reads shared state (its own RNG aside), so workers may call it
+// concurrently.
 func (s *Scheduler) sense(npc *NPC) {
-	vm := s.vm
+	vm := s.VM(npc)
 	w := s.World
 
-	// Compute NPC-related sensors once (avoids duplicate scans)
-	nearNPCDist, nearNPCID, nearNPCDir := w.NearestNPCFull(npc.X, npc.Y, npc.ID)
+	// Vision cone: restrict world scans to what the NPC is facing
+	cone := byte(DirNone)
+	if s.VisionCone {
+		cone = npc.facing()
+	}
+
+	// Nearest food/item/poison/NPC: from this tick's fields when built
+	// (the fields are all-round, so not under a vision cone), else by scanning
+	var foodDist, foodDir, itemDist, itemDir, poisonDist, nearNPCDist, nearNPCDir int
+	var nearNPCID uint16
+	if f := &s.fields; s.SensorFields && !s.VisionCone && f.tick == w.Tick && f.size == w.Size {
+		nearNPCDist, nearNPCID, nearNPCDir = f.nearestNPC(npc.X, npc.Y, npc.ID)
+		foodDist, foodDir = f.nearest(f.food, npc.X, npc.Y)
+		itemDist, itemDir = f.nearest(f.item, npc.X, npc.Y)
+		poisonDist, _ = f.nearest(f.poison, npc.X, npc.Y)
+	} else {
+		// Compute NPC-related sensors once (avoids duplicate scans)
+		nearNPCDist, nearNPCID, nearNPCDir = w.nearestNPCFull(npc.X, npc.Y, npc.ID, cone)
+		foodDist, foodDir = w.nearestFood(npc.X, npc.Y, cone), w.nearestFoodDir(npc.X, npc.Y, cone)
+		itemDist, _ = w.nearestItem(npc.X, npc.Y, cone)
+		itemDir = w.nearestItemDir(npc.X, npc.Y, cone)
+		poisonDist = w.nearestPoison(npc.X, npc.Y, cone)
+	}
 
 	vm.MemWrite(Ring0Self, int16(npc.ID))
 	vm.MemWrite(Ring0Health, int16(npc.Health))
 	vm.MemWrite(Ring0Energy, int16(npc.Energy))
 	vm.MemWrite(Ring0Hunger, int16(npc.Hunger))
 	vm.MemWrite(Ring0Fear, int16(nearNPCDist))
-	vm.MemWrite(Ring0Food, int16(w.NearestFood(npc.X, npc.Y)))
-	vm.MemWrite(Ring0Danger, int16(w.NearestPoison(npc.X, npc.Y)))
+	vm.MemWrite(Ring0Food, int16(foodDist))
+	vm.MemWrite(Ring0Danger, int16(poisonDist))
 	vm.MemWrite(Ring0Near, int16(nearNPCDist))
 	vm.MemWrite(Ring0X, int16(npc.X))
 	vm.MemWrite(Ring0Y, int16(npc.Y))
 	vm.MemWrite(Ring0Day, int16(w.Tick%DayCycle))
 	vm.MemWrite(Ring0NearID, int16(nearNPCID))
-	vm.MemWrite(Ring0FoodDir, int16(w.NearestFoodDir(npc.X, npc.Y)))
+	vm.MemWrite(Ring0FoodDir, int16(foodDir))
 
 	// Extended Ring0 slots
 	vm.MemWrite(Ring0MyGold, int16(npc.Gold))
 	vm.MemWrite(Ring0MyItem, int16(npc.Item))
-	dist, _ := w.NearestItem(npc.X, npc.Y)
-	vm.MemWrite(Ring0NearItem, int16(dist))
-	vm.MemWrite(Ring0NearTrust, 0) // stub for Phase 3
+	vm.MemWrite(Ring0NearItem, int16(itemDist))
+	vm.MemWrite(Ring0NearTrust, int16(npc.TrustOf(uint16(nearNPCID))))
 	vm.MemWrite(Ring0NearDir, int16(nearNPCDir))
-	vm.MemWrite(Ring0ItemDir, int16(w.NearestItemDir(npc.X, npc.Y)))
+	vm.MemWrite(Ring0ItemDir, int16(itemDir))
 	vm.MemWrite(Ring0Rng, int16(npc.Rand()))
 	vm.MemWrite(Ring0Stress, int16(npc.Stress))
 
@@ -231,6 +436,53 @@ func (s *Scheduler) sense(npc *NPC) {
 	} else {
 		vm.MemWrite(Ring0Cooldown, 0)
 	}
+	vm.MemWrite(Ring0IncomingFire, int16(npc.IncomingFire))
+
+	// Leadership: most-followed NPC nearby, and own following
+	leaderID, leaderDir := s.nearestLeader(npc)
+	vm.MemWrite(Ring0LeaderID, int16(leaderID))
+	vm.MemWrite(Ring0LeaderDir, int16(leaderDir))
+	vm.MemWrite(Ring0MyFollowers, int16(s.followers[npc.ID]))
+
+	// Kinship
+	kin := 0
+	if near := w.NPCByID(nearNPCID); near != nil {
+		kin = Kinship(npc, near)
+	}
+	vm.MemWrite(Ring0NearKin, int16(kin))
+	vm.MemWrite(Ring0ChildDir, int16(s.childDir(w, npc)))
+	vm.MemWrite(Ring0Infected, int16(npc.infectionState()))
+
+	// Emotion is only readable face to face
+	nearEmotion := int16(EmotionNeutral)
+	if near := w.NPCByID(nearNPCID); near != nil && nearNPCDist <= 1 {
+		nearEmotion = int16(near.Emotion)
+	}
+	vm.MemWrite(Ring0NearEmotion, nearEmotion)
+
+	// Facing and peripheral awareness
+	vm.MemWrite(Ring0Facing, int16(npc.facing()))
+	vm.MemWrite(Ring0Behind, int16(s.somethingBehind(npc)))
+
+	// Hearing works all round, even outside the vision cone
+	noiseDir, noiseLevel := s.noises.loudest(npc)
+	vm.MemWrite(Ring0NoiseDir, int16(noiseDir))
+	vm.MemWrite(Ring0NoiseLevel, int16(noiseLevel))
+
+	// Market prices (0 without markets)
+	vm.MemWrite(Ring0MealPrice, int16(s.prices.meal))
+	vm.MemWrite(Ring0SellPrice, int16(s.sellPrice(npc.Item)))
+
+	// Nearest open contract (all 0 without contracts)
+	contractDir, contractReward, contractItem := s.nearestContract(npc)
+	vm.MemWrite(Ring0ContractDir, int16(contractDir))
+	vm.MemWrite(Ring0ContractReward, int16(contractReward))
+	vm.MemWrite(Ring0ContractItem, int16(contractItem))
+
+	// Item waiting at the trading posts
+	vm.MemWrite(Ring0PostPayout, int16(s.payout(npc.ID)))
+
+	s.senseHunt(w, vm, npc)
 
 	// Effective gas: base + modifier bonus with diminishing returns
 	gasBonus := 0
@@ -250,11 +502,42 @@ func (s *Scheduler) sense(npc *NPC) {
 		effectiveGas = 500
 	}
 	vm.MemWrite(Ring0MyGas, int16(effectiveGas))
+
+	if s.SensorNoise != nil {
+		s.SensorNoise.apply(vm, npc, s.World.Tick)
+	}
 }
 
 // think runs the NPC's genome on the VM.
 func (s *Scheduler) think(npc *NPC) {
-	vm := s.vm
+	vm := s.VM(npc)
+	npc.Asleep = false // wakes up to think
+	npc.Emotion = EmotionNeutral
+	s.prepareVM(vm, npc)
+	vm.Output = s.Output
+
+	// Externally controlled NPCs skip their genome
+	if c := s.Controllers[npc.ID]; c != nil {
+		ring0 := make([]int16, Ring0ExtCount)
+		for i := range ring0 {
+			ring0[i] = vm.MemRead(byte(i))
+		}
+		move, action, target := c.Decide(npc, ring0)
+		vm.MemWrite(64+Ring1Move, int16(move))
+		vm.MemWrite(64+Ring1Action, int16(action))
+		vm.MemWrite(64+Ring1Target, int16(target))
+		return
+	}
+
+	// Yield: execute Ring1 actions, refresh sensors, resume
+	s.runGenome(vm, npc, func() {
+		s.act(npc)
+		s.sense(npc)
+	})
+}
+
+// prepareVM resets vm for npc's turn: effective gas and cleared Ring1.
+func (s *Scheduler) prepareVM(vm *micro.VM, npc *NPC) {
 	vm.Reset()
 
 	// Compute effective gas with modifier bonus and diminishing returns
@@ -276,38 +559,142 @@ func (s *Scheduler) think(npc *NPC) {
 	}
 	vm.MaxGas = effectiveGas
 	vm.Gas = effectiveGas
-	vm.Output = s.Output
-
-	// Clear Ring1 slots
-	vm.MemWrite(64+Ring1Move, 0)
-	vm.MemWrite(64+Ring1Action, 0)
-	vm.MemWrite(64+Ring1Target, 0)
-	vm.MemWrite(64+Ring1Emotion, 0)
+	vm.GasCosts = s.GasCosts
+	clearRing1(vm)
+}
 
-	// Load genome and run as coroutine
+// runGenome loads npc's genome and runs it as a coroutine, calling onYield
+// with the Ring1 outputs of each yield before clearing them and resuming.
+func (s *Scheduler) runGenome(vm *micro.VM, npc *NPC, onYield func()) {
+	vm.SelfModify = s.SelfModify
 	vm.Load(npc.Genome)
+	if t := s.Trace; t != nil && t.NPC == npc.ID {
+		t.begin(s.World.Tick, vm, npc)
+		defer t.end(vm)
+		yield := onYield
+		onYield = func() {
+			t.yield(vm)
+			yield()
+			t.resume(vm)
+		}
+	}
 	for {
 		vm.Run() // ignores error (gas exhaustion is normal)
 		if !vm.Yielded {
 			break // halted, error, or gas exhaustion
 		}
-		// Yield: execute Ring1 actions, refresh sensors, resume
-		s.act(npc)
-		s.sense(npc)
-		vm.MemWrite(64+Ring1Move, 0)
-		vm.MemWrite(64+Ring1Action, 0)
-		vm.MemWrite(64+Ring1Target, 0)
-		vm.MemWrite(64+Ring1Emotion, 0)
+		onYield()
+		clearRing1(vm)
 		vm.Yielded = false
 		if vm.Gas <= 0 {
 			break
 		}
 	}
+	atomic.AddInt64(&s.GasUsed, int64(vm.MaxGas-max(vm.Gas, 0)))
+}
+
+func clearRing1(vm *micro.VM) {
+	for i := 0; i < Ring1Count; i++ {
+		vm.MemWrite(byte(64+i), 0)
+	}
+}
+
+func readRing1(vm *micro.VM) ring1Out {
+	var out ring1Out
+	for i := range out {
+		out[i] = vm.MemRead(byte(64 + i))
+	}
+	return out
+}
+
+// tickOrder returns the indexes of the NPCs in the order Tick acts for
+// them: population order, or NPC-ID order with workers.
+func (s *Scheduler) tickOrder() []int {
+	npcs := s.World.NPCs
+	s.order = s.order[:0]
+	for i := range npcs {
+		s.order = append(s.order, i)
+	}
+	if s.Workers > 0 {
+		slices.SortFunc(s.order, func(a, b int) int {
+			return cmp.Compare(npcs[a].ID, npcs[b].ID)
+		})
+	}
+	return s.order
+}
+
+// planAll senses and thinks for every genome-driven NPC on s.Workers
+// goroutines. Brains only read the start-of-tick world and Tick carries
+// out the plans in NPC-ID order, so the tick does not depend on the
+// worker count or on population order. Genome output (print) is
+// discarded.
+//
+// The interleaved tick (no workers) differs: there each NPC senses after
+// the NPCs before it have moved and re-senses after every yield, so the
+// same seed gives a different run.
+func (s *Scheduler) planAll() {
+	npcs := s.World.NPCs
+	workers := s.Workers
+	for len(s.plans) < len(npcs) {
+		s.plans = append(s.plans, nil)
+	}
+	// Hand out VMs up front, so the workers only read npc.vm
+	for _, npc := range npcs {
+		if npc.Alive() {
+			s.VM(npc)
+		}
+	}
+
+	chunk := (len(npcs) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(npcs); lo += chunk {
+		hi := min(lo+chunk, len(npcs))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := lo; i < hi; i++ {
+				npc := npcs[i]
+				s.plans[i] = s.plans[i][:0]
+				if !npc.Alive() || s.Controllers[npc.ID] != nil {
+					continue
+				}
+				s.sense(npc)
+				s.plans[i] = s.plan(npc, s.plans[i])
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// plan runs npc's genome without touching the world, appending the Ring1
+// outputs of each yield and then the final ones. Sensors are not refreshed
+// between yields.
+func (s *Scheduler) plan(npc *NPC, out []ring1Out) []ring1Out {
+	vm := npc.vm
+	s.prepareVM(vm, npc)
+	vm.Output = io.Discard
+	s.runGenome(vm, npc, func() {
+		out = append(out, readRing1(vm))
+	})
+	return append(out, readRing1(vm))
+}
+
+// actPlan carries out planned Ring1 outputs in order, as think and act
+// would have done on each yield.
+func (s *Scheduler) actPlan(npc *NPC, plan []ring1Out) {
+	npc.Asleep = false
+	npc.Emotion = EmotionNeutral
+	for _, out := range plan {
+		for i, v := range out {
+			npc.vm.MemWrite(byte(64+i), v)
+		}
+		s.act(npc)
+	}
 }
 
 // act reads Ring1 outputs and applies movement/action.
 func (s *Scheduler) act(npc *NPC) {
-	vm := s.vm
+	vm := s.VM(npc)
 	w := s.World
 
 	// Read Ring1 outputs
@@ -324,6 +711,34 @@ func (s *Scheduler) act(npc *NPC) {
 		}
 	}
 
+	// Actions still cooling down do nothing
+	if action >= 0 && action < ActionCount && !s.ready(npc, action) {
+		action = ActionIdle
+	}
+
+	// Emotion stays on display for the rest of the tick once shown
+	if e := vm.MemRead(64 + Ring1Emotion); e > EmotionNeutral && e <= EmotionFearful {
+		npc.Emotion = byte(e)
+	}
+
+	// Sleepers stay put for the rest of the tick
+	if action == ActionSleep {
+		npc.Asleep = true
+	}
+	if npc.Asleep {
+		moveDir = DirNone
+	}
+
+	// Turning changes facing without moving
+	if turn := vm.MemRead(64 + Ring1Turn); turn >= DirNorth && turn <= DirWest {
+		npc.LastDir = byte(turn)
+	}
+
+	// Followers without an explicit move step toward their target
+	if moveDir == DirNone && npc.Following != 0 && !npc.Asleep {
+		moveDir = s.followDir(npc)
+	}
+
 	// Apply movement
 	if moveDir >= DirNorth && moveDir <= DirWest {
 		npc.LastDir = byte(moveDir)
@@ -356,13 +771,14 @@ func (s *Scheduler) act(npc *NPC) {
 			npc.X = nx
 			npc.Y = ny
 			w.SetOcc(npc.X, npc.Y, npc.ID)
+			s.noise(npc, noiseMove)
 		}
 	}
 
 	// Swamp hazard: 5% chance per tick of -5 health, +3 stress
 	if w.Biomes && w.BiomeGrid != nil && w.BiomeGrid[w.idx(npc.X, npc.Y)] == BiomeSwamp {
 		if w.Rng.Intn(20) == 0 {
-			npc.Health -= 5
+			npc.TakeDamage(5, DamagePoison)
 			npc.Stress += 3
 			if npc.Stress > 100 {
 				npc.Stress = 100
@@ -373,7 +789,7 @@ func (s *Scheduler) act(npc *NPC) {
 	// Handle poison tile
 	destType := w.TileAt(npc.X, npc.Y).Type()
 	if destType == TilePoison {
-		npc.Health -= 15
+		npc.TakeDamage(15, DamagePoison)
 		npc.Stress += 10
 		if npc.Stress > 100 {
 			npc.Stress = 100
@@ -409,23 +825,37 @@ func (s *Scheduler) act(npc *NPC) {
 		}
 	case ActionAttack:
 		targetID := uint16(vm.MemRead(64 + Ring1Target))
+		hit := false
 		if other := w.npcByID[targetID]; other != nil && other.Alive() {
-			d := abs(other.X-npc.X) + abs(other.Y-npc.Y)
-			if d <= 1 && npc.Energy >= 10 {
-				dmg := 5 + npc.ModSum(ModAttack) - other.ModSum(ModDefense)
-				if dmg < 1 {
-					dmg = 1
+			if cost := s.Actions[ActionAttack].Energy; s.inRange(npc, other, ActionAttack) && npc.Energy >= cost {
+				hit = true
+				damage := 5 + npc.ModSum(ModAttack)
+				if other.Emotion == EmotionFearful {
+					damage /= 2 // cowering
 				}
-				other.Health -= dmg
-				npc.Energy -= 10
+				other.TakeDamage(damage, s.strikeType(npc))
+				other.AdjustTrust(npc.ID, TrustAttack)
+				s.spend(npc, ActionAttack, cost)
 				s.AttackCount++
+				s.noise(npc, noiseAttack)
 				other.Stress += 15
+				if npc.Emotion == EmotionAggressive {
+					other.Stress += 10 // intimidated
+				}
+				if npc.Emotion == EmotionFriendly {
+					npc.Stress += 5 // turning on someone you smiled at
+				}
 				if other.Stress > 100 {
 					other.Stress = 100
 				}
+				if npc.Predator && !other.Predator {
+					s.feedOnPrey(npc, !other.Alive())
+				}
 				// Steal item if target dies
 				if !other.Alive() {
 					s.KillCount++
+					npc.Kills++
+					s.claimBounty(npc, other)
 				}
 				if !other.Alive() && other.Item != ItemNone && npc.Item == ItemNone {
 					npc.Item = other.Item
@@ -434,13 +864,18 @@ func (s *Scheduler) act(npc *NPC) {
 				}
 			}
 		}
+		if !hit {
+			s.raidChest(npc) // nobody to fight: break into a chest instead
+		}
 	case ActionShare:
 		targetID := uint16(vm.MemRead(64 + Ring1Target))
 		if other := w.npcByID[targetID]; other != nil && other.Alive() {
-			d := abs(other.X-npc.X) + abs(other.Y-npc.Y)
-			if d <= 1 && npc.Energy > 20 {
-				npc.Energy -= 10
-				other.Energy += 10
+			// Sharers keep at least as much as they give
+			if gift := s.Actions[ActionShare].Energy; s.inRange(npc, other, ActionShare) && npc.Energy > 2*gift {
+				s.spend(npc, ActionShare, gift)
+				other.Energy += gift
+				npc.Shares++
+				other.AdjustTrust(npc.ID, TrustShare)
 			}
 		}
 	case ActionTrade:
@@ -449,43 +884,50 @@ func (s *Scheduler) act(npc *NPC) {
 			s.tradeIntents[npc.ID] = targetID
 		}
 	case ActionCraft:
-		// Craft anywhere: free on forge, costs 20 energy off forge
+		// Craft anywhere: free on forge, costs energy off forge
 		if npc.Item != ItemNone {
-			if output, ok := forgeRecipes[npc.Item]; ok {
-				onForge := w.TileAt(npc.X, npc.Y).Type() == TileForge
-				if onForge || npc.Energy >= 20 {
-					if !onForge {
-						npc.Energy -= 20
-					}
+			onForge := w.TileAt(npc.X, npc.Y).Type() == TileForge
+			if output, ok := s.recipeFor(npc, onForge); ok {
+				cost := s.Actions[ActionCraft].Energy
+				if onForge {
+					cost = 0
+				}
+				if npc.Energy >= cost {
+					s.spend(npc, ActionCraft, cost)
+					s.emit(ItemCrafted{Tick: w.Tick, NPC: npc.ID, Input: npc.Item, Output: output})
 					removeItemModifier(npc, npc.Item)
 					npc.Item = output
 					grantItemModifier(npc, npc.Item)
 					npc.Fitness += 50
 					npc.CraftCount++
+					s.noise(npc, noiseCraft)
 				}
 			}
 		}
 	case ActionTeach:
 		targetID := uint16(vm.MemRead(64 + Ring1Target))
 		if other := w.npcByID[targetID]; other != nil && other.Alive() {
-			d := abs(other.X-npc.X) + abs(other.Y-npc.Y)
-			if d <= 1 && npc.Energy >= 10 {
+			cost := s.Actions[ActionTeach].Energy
+			if s.atSchool(npc) {
+				cost /= 2
+			}
+			if s.inRange(npc, other, ActionTeach) && npc.Energy >= cost {
 				s.memeticTransfer(npc, other)
-				npc.Energy -= 10
+				s.spend(npc, ActionTeach, cost)
 			}
 		}
 	case ActionHeal:
 		targetID := uint16(vm.MemRead(64 + Ring1Target))
 		if other := w.npcByID[targetID]; other != nil && other.Alive() {
-			d := abs(other.X-npc.X) + abs(other.Y-npc.Y)
-			if d <= 1 && npc.Energy >= 8 {
+			if cost := s.Actions[ActionHeal].Energy; s.inRange(npc, other, ActionHeal) && npc.Energy >= cost {
 				heal := 5 + npc.ModSum(ModForage) // tool bonus
 				other.Health += heal
 				if other.Health > 100 {
 					other.Health = 100
 				}
-				npc.Energy -= 8
+				s.spend(npc, ActionHeal, cost)
 				s.HealCount++
+				other.AdjustTrust(npc.ID, TrustHeal)
 				// Healing relieves stress for both
 				npc.Stress -= 3
 				if npc.Stress < 0 {
@@ -501,6 +943,53 @@ func (s *Scheduler) act(npc *NPC) {
 		s.harvest(npc)
 	case ActionTerraform:
 		s.terraform(npc)
+	case ActionBuild:
+		s.build(npc, int(vm.MemRead(64+Ring1Target)))
+	case ActionDeposit:
+		if c := w.ChestAt(npc.X, npc.Y); c != nil && c.Owner == npc.ID &&
+			npc.Item != ItemNone && len(c.Items) < ChestCapacity {
+			removeItemModifier(npc, npc.Item)
+			c.Items = append(c.Items, npc.Item)
+			npc.Item = ItemNone
+			s.DepositCount++
+		}
+	case ActionWithdraw:
+		if c := w.ChestAt(npc.X, npc.Y); c != nil && c.Owner == npc.ID {
+			takeFromChest(npc, c)
+		}
+	case ActionShoot:
+		s.shoot(npc, byte(vm.MemRead(64+Ring1Target)))
+	case ActionFollow:
+		targetID := uint16(vm.MemRead(64 + Ring1Target))
+		if other := w.npcByID[targetID]; other != nil && other.Alive() && other != npc {
+			npc.Following = targetID
+		} else {
+			npc.Following = 0
+		}
+	case ActionJoin:
+		targetID := uint16(vm.MemRead(64 + Ring1Target))
+		if other := w.npcByID[targetID]; other != nil && other.Alive() && other != npc {
+			if s.inRange(npc, other, ActionJoin) && s.joinClan(npc, other) {
+				s.spend(npc, ActionJoin, s.Actions[ActionJoin].Energy)
+			}
+		}
+	case ActionMate:
+		if s.Mating != nil {
+			s.mateIntents[npc.ID] = uint16(vm.MemRead(64 + Ring1Target))
+		}
+	case ActionGive:
+		targetID := uint16(vm.MemRead(64 + Ring1Target))
+		if other := w.npcByID[targetID]; other != nil && other.Alive() {
+			if s.inRange(npc, other, ActionGive) {
+				s.give(npc, other)
+			}
+		}
+	case ActionBuy:
+		s.buy(npc, byte(vm.MemRead(64+Ring1Target)))
+	case ActionSell:
+		s.sell(npc)
+	case ActionPost:
+		s.post(npc, byte(vm.MemRead(64+Ring1Target)))
 	}
 }
 
@@ -519,6 +1008,9 @@ func (s *Scheduler) resolveTrades() {
 		if abs(npcA.X-npcB.X)+abs(npcA.Y-npcB.Y) > 1 {
 			continue // must be adjacent
 		}
+		if npcA.Emotion == EmotionAggressive || npcB.Emotion == EmotionAggressive {
+			continue // nobody deals with a snarling partner
+		}
 		// Remove old item modifiers, swap items, grant new modifiers
 		removeItemModifier(npcA, npcA.Item)
 		removeItemModifier(npcB, npcB.Item)
@@ -528,8 +1020,16 @@ func (s *Scheduler) resolveTrades() {
 		// Scarcity-based gold transfer: value difference flows as gold, plus base reward
 		valA := s.World.MarketValue(npcA.Item) // A now holds what B had
 		valB := s.World.MarketValue(npcB.Item) // B now holds what A had
-		baseGold := 3
+		// Mutual trust sweetens the deal; distrust eats into it
+		baseGold := 3 + (npcA.TrustOf(npcB.ID)+npcB.TrustOf(npcA.ID))/40
+		if npcA.Emotion == EmotionFriendly && npcB.Emotion == EmotionFriendly {
+			baseGold++
+		}
+		if baseGold < 0 {
+			baseGold = 0
+		}
 		diff := (valA - valB) / 2
+		goldA, goldB := npcA.Gold, npcB.Gold
 		npcA.Gold += baseGold - diff
 		npcB.Gold += baseGold + diff
 		if npcA.Gold < 0 {
@@ -538,6 +1038,8 @@ func (s *Scheduler) resolveTrades() {
 		if npcB.Gold < 0 {
 			npcB.Gold = 0
 		}
+		npcA.GoldEarned += s.taxEarnings(npcA, max(npcA.Gold-goldA, 0))
+		npcB.GoldEarned += s.taxEarnings(npcB, max(npcB.Gold-goldB, 0))
 		// Trading relieves stress
 		npcA.Stress -= 5
 		if npcA.Stress < 0 {
@@ -547,7 +1049,12 @@ func (s *Scheduler) resolveTrades() {
 		if npcB.Stress < 0 {
 			npcB.Stress = 0
 		}
+		npcA.AdjustTrust(npcB.ID, TrustTrade)
+		npcB.AdjustTrust(npcA.ID, TrustTrade)
+		npcA.Trades++
+		npcB.Trades++
 		s.TradeCount++
+		s.emit(TradeCompleted{Tick: s.World.Tick, A: npcA.ID, B: npcB.ID})
 		delete(s.tradeIntents, idA)
 		delete(s.tradeIntents, targetA)
 	}
@@ -564,7 +1071,11 @@ func (s *Scheduler) findNPC(id uint16) *NPC {
 
 // memeticTransfer copies a genome fragment from teacher to student.
 func (s *Scheduler) memeticTransfer(teacher, student *NPC) {
-	// Pick instruction-aligned fragment from teacher (4 bytes)
+	if student.Frozen {
+		return
+	}
+	// Pick instruction-aligned fragment from teacher (4 bytes, 8 at school)
+	school := s.atSchool(teacher)
 	points := OpcodeAlignedPoints(teacher.Genome)
 	if len(points) < 2 {
 		return
@@ -572,6 +1083,9 @@ func (s *Scheduler) memeticTransfer(teacher, student *NPC) {
 	srcIdx := s.World.Rng.Intn(len(points) - 1)
 	srcStart := points[srcIdx]
 	srcEnd := srcStart + 4
+	if school {
+		srcEnd = srcStart + schoolFragment
+	}
 	if srcEnd > len(teacher.Genome) {
 		srcEnd = len(teacher.Genome)
 	}
@@ -579,6 +1093,15 @@ func (s *Scheduler) memeticTransfer(teacher, student *NPC) {
 
 	// Success probability: teacher.Fitness / (teacher.Fitness + student.Fitness + 1)
 	prob := float64(teacher.Fitness+1) / float64(teacher.Fitness+student.Fitness+2)
+	switch student.Emotion {
+	case EmotionFriendly:
+		prob = (prob + 1) / 2 // receptive
+	case EmotionAggressive:
+		prob /= 2 // defiant
+	}
+	if school {
+		prob = (prob + 1) / 2 // the classroom lends authority
+	}
 	if s.World.Rng.Float64() > prob {
 		return // student resisted
 	}
@@ -611,6 +1134,58 @@ func (s *Scheduler) memeticTransfer(teacher, student *NPC) {
 		teacher.Stress = 0
 	}
 	s.TeachCount++
+	if school {
+		if s.Lessons == nil {
+			s.Lessons = make(map[int]int)
+		}
+		s.Lessons[s.World.idx(teacher.X, teacher.Y)]++
+	}
+	student.AdjustTrust(teacher.ID, TrustTeach)
+	s.emit(TeachSucceeded{Tick: s.World.Tick, Teacher: teacher.ID, Student: student.ID})
+
+	s.teachRecipe(teacher, student)
+}
+
+// recipeFor returns the crafted output for the NPC's held item, or false if
+// no recipe applies. With RecipeMemes on, an unknown recipe can only be
+// discovered by experimenting on a forge.
+func (s *Scheduler) recipeFor(npc *NPC, onForge bool) (byte, bool) {
+	output, ok := forgeRecipes[npc.Item]
+	if !ok {
+		return 0, false
+	}
+	if !s.RecipeMemes {
+		return output, true
+	}
+	bit := recipeBits[npc.Item]
+	if npc.Recipes&bit != 0 {
+		return output, true
+	}
+	if onForge && s.World.Rng.Intn(discoverChance) == 0 {
+		npc.Recipes |= bit
+		s.RecipesLearned++
+		return output, true
+	}
+	return 0, false
+}
+
+// teachRecipe passes one random recipe the teacher knows and the student lacks.
+func (s *Scheduler) teachRecipe(teacher, student *NPC) {
+	if !s.RecipeMemes {
+		return
+	}
+	missing := teacher.Recipes &^ student.Recipes
+	if missing == 0 {
+		return
+	}
+	var bits []byte
+	for bit := byte(1); bit != 0; bit <<= 1 {
+		if missing&bit != 0 {
+			bits = append(bits, bit)
+		}
+	}
+	student.Recipes |= bits[s.World.Rng.Intn(len(bits))]
+	s.RecipesLearned++
 }
 
 // autoActions makes NPC passively eat food (extended radius with ModForage)
@@ -639,49 +1214,60 @@ func (s *Scheduler) autoActions(npc *NPC) {
 
 	// Auto-craft on forge: if on forge tile with a craftable item, craft for free
 	if w.TileAt(npc.X, npc.Y).Type() == TileForge && npc.Item != ItemNone {
-		if output, ok := forgeRecipes[npc.Item]; ok {
+		if output, ok := s.recipeFor(npc, true); ok {
+			s.emit(ItemCrafted{Tick: w.Tick, NPC: npc.ID, Input: npc.Item, Output: output})
 			removeItemModifier(npc, npc.Item)
 			npc.Item = output
 			grantItemModifier(npc, npc.Item)
 			npc.Fitness += 50
 			npc.CraftCount++
+			s.noise(npc, noiseCraft)
 		}
 	}
+
+	// Feed dependent offspring standing next to us
+	s.parentalCare(npc)
 }
 
 func (s *Scheduler) tryEat(npc *NPC, x, y int) bool {
 	w := s.World
-	if !w.InBounds(x, y) {
+	if npc.Predator || !w.InBounds(x, y) {
 		return false
 	}
 	t := w.TileAt(x, y)
 	if t.Type() == TileFood {
 		w.SetTile(x, y, MakeTile(TileEmpty))
-		npc.Energy += 30
-		if npc.Energy > 200 {
-			npc.Energy = 200
-		}
-		npc.Health += 5
-		if npc.Health > 100 {
-			npc.Health = 100
-		}
-		npc.FoodEaten++
-		npc.Hunger = 0
-		// Eating relieves stress
-		npc.Stress -= 2
-		if npc.Stress < 0 {
-			npc.Stress = 0
-		}
+		s.feed(npc)
 		return true
 	}
 	return false
 }
 
+// feed gives npc one meal's worth of energy and health.
+func (s *Scheduler) feed(npc *NPC) {
+	npc.Energy += 30
+	if npc.Energy > 200 {
+		npc.Energy = 200
+	}
+	npc.Health += 5
+	if npc.Health > 100 {
+		npc.Health = 100
+	}
+	npc.FoodEaten++
+	npc.Hunger = 0
+	// Eating relieves stress
+	npc.Stress -= 2
+	if npc.Stress < 0 {
+		npc.Stress = 0
+	}
+}
+
 // harvest extracts a resource from the tile the NPC stands on.
 // Tile stays but goes on cooldown. Result depends on biome.
 func (s *Scheduler) harvest(npc *NPC) {
 	w := s.World
-	if npc.Energy < 5 {
+	cost := s.Actions[ActionHarvest].Energy
+	if npc.Predator || npc.Energy < cost {
 		return
 	}
 	idx := w.idx(npc.X, npc.Y)
@@ -695,7 +1281,7 @@ func (s *Scheduler) harvest(npc *NPC) {
 		biome = w.BiomeGrid[idx]
 	}
 
-	npc.Energy -= 5
+	s.spend(npc, ActionHarvest, cost)
 	s.HarvestCount++
 	roll := w.Rng.Intn(100)
 
@@ -754,15 +1340,18 @@ func (s *Scheduler) harvest(npc *NPC) {
 		}
 		w.Cooldowns[idx] = 30
 	case BiomeSwamp:
-		// 50% food, 50% poison damage
+		// 50% food, 10% charm (if empty-handed), otherwise poison damage
 		if roll < 50 {
 			npc.Energy += 30
 			if npc.Energy > 200 {
 				npc.Energy = 200
 			}
 			npc.FoodEaten++
+		} else if roll >= 90 && npc.Item == ItemNone {
+			npc.Item = ItemCharm
+			grantItemModifier(npc, ItemCharm)
 		} else {
-			npc.Health -= 10
+			npc.TakeDamage(10, DamagePoison)
 			npc.Stress += 5
 			if npc.Stress > 100 {
 				npc.Stress = 100
@@ -792,9 +1381,10 @@ func (s *Scheduler) harvest(npc *NPC) {
 // terraform modifies the tile the NPC stands on.
 func (s *Scheduler) terraform(npc *NPC) {
 	w := s.World
-	cost := 30 - npc.ModSum(ModForage)*5 // tool reduces cost
-	if cost < 10 {
-		cost = 10
+	base := s.Actions[ActionTerraform].Energy
+	cost := base - npc.ModSum(ModForage)*5 // tool reduces cost, down to a third
+	if cost < base/3 {
+		cost = base / 3
 	}
 	if npc.Energy < cost {
 		return
@@ -805,20 +1395,276 @@ func (s *Scheduler) terraform(npc *NPC) {
 	case TileEmpty:
 		// Plant food
 		w.SetTile(npc.X, npc.Y, MakeTile(TileFood))
-		npc.Energy -= cost
+		s.spend(npc, ActionTerraform, cost)
 		s.TerraformCount++
 	case TileFood:
 		// Already food — no-op
-	case TileForge:
-		// Can't terraform forge
+	case TileForge, TileMarket, TilePost, TileSchool:
+		// Can't terraform forges, markets, trading posts or schools
 	default:
 		// Clear any other tile to empty (forest→empty, etc.)
 		w.SetTile(npc.X, npc.Y, MakeTile(TileEmpty))
-		npc.Energy -= cost
+		s.spend(npc, ActionTerraform, cost)
 		s.TerraformCount++
 	}
 }
 
+// leaderRadius is how far (Manhattan) an NPC can see a leader.
+const leaderRadius = 8
+
+// countFollowers refreshes follower counts and drops follows whose
+// target has died.
+func (s *Scheduler) countFollowers() {
+	clear(s.followers)
+	for _, npc := range s.World.NPCs {
+		if npc.Following == 0 {
+			continue
+		}
+		if t := s.World.npcByID[npc.Following]; t == nil || !t.Alive() {
+			npc.Following = 0
+			continue
+		}
+		s.followers[npc.Following]++
+	}
+}
+
+// nearestLeader returns the ID of and direction toward the most-followed
+// NPC within leaderRadius (ties go to the closer one).
+func (s *Scheduler) nearestLeader(npc *NPC) (uint16, int) {
+	var best *NPC
+	bestCount, bestDist := 0, 0
+	for id, count := range s.followers {
+		l := s.World.npcByID[id]
+		if l == nil || l == npc {
+			continue
+		}
+		d := abs(l.X-npc.X) + abs(l.Y-npc.Y)
+		if d > leaderRadius {
+			continue
+		}
+		if count > bestCount || (count == bestCount && (d < bestDist || d == bestDist && l.ID < best.ID)) {
+			best, bestCount, bestDist = l, count, d
+		}
+	}
+	if best == nil {
+		return 0, DirNone
+	}
+	return best.ID, directionToward(npc.X, npc.Y, best.X, best.Y)
+}
+
+// followDir returns one step toward the followed NPC, or DirNone if it is
+// already adjacent, gone, or this NPC has taken its follow step this tick.
+func (s *Scheduler) followDir(npc *NPC) int {
+	t := s.World.npcByID[npc.Following]
+	if t == nil || !t.Alive() {
+		npc.Following = 0
+		return DirNone
+	}
+	if npc.followTick == s.World.Tick+1 || abs(t.X-npc.X)+abs(t.Y-npc.Y) <= 1 {
+		return DirNone
+	}
+	npc.followTick = s.World.Tick + 1
+	return directionToward(npc.X, npc.Y, t.X, t.Y)
+}
+
+// behindRadius is how far peripheral awareness (Ring0Behind) reaches.
+const behindRadius = 3
+
+// facing returns the direction the NPC faces (north until it first moves or turns).
+func (n *NPC) facing() byte {
+	if n.LastDir == DirNone {
+		return DirNorth
+	}
+	return n.LastDir
+}
+
+// somethingBehind returns 1 if a living NPC within behindRadius is outside
+// npc's forward cone, else 0.
+func (s *Scheduler) somethingBehind(npc *NPC) int {
+	w := s.World
+	dir := npc.facing()
+	for dy := -behindRadius; dy <= behindRadius; dy++ {
+		for dx := -behindRadius; dx <= behindRadius; dx++ {
+			x, y := npc.X+dx, npc.Y+dy
+			if (dx == 0 && dy == 0) || abs(dx)+abs(dy) > behindRadius || !w.InBounds(x, y) {
+				continue
+			}
+			if other := w.npcByID[w.OccAt(x, y)]; other != nil && other.Alive() && !inCone(npc.X, npc.Y, x, y, dir) {
+				return 1
+			}
+		}
+	}
+	return 0
+}
+
+// Sleep restores sleepStress stress and sleepEnergy energy per tick, doubled
+// at night.
+const (
+	sleepStress = 4
+	sleepEnergy = 1
+)
+
+// sleep rests npc for one tick instead of its auto-actions.
+func (s *Scheduler) sleep(npc *NPC) {
+	rest := 1
+	if s.World.Night() {
+		rest = 2
+	}
+	npc.Stress -= sleepStress * rest
+	if npc.Stress < 0 {
+		npc.Stress = 0
+	}
+	npc.Energy += sleepEnergy * rest
+	s.SleepTicks++
+}
+
+// giftGold is the most gold handed over by one empty-handed gift.
+const giftGold = 5
+
+// give hands the held item to other if their hands are free, otherwise
+// up to giftGold gold. Nothing comes back; the recipient remembers it.
+func (s *Scheduler) give(npc, other *NPC) {
+	switch {
+	case npc.Item != ItemNone && other.Item == ItemNone:
+		removeItemModifier(npc, npc.Item)
+		other.Item = npc.Item
+		npc.Item = ItemNone
+		grantItemModifier(other, other.Item)
+		s.GiftCount++
+	case npc.Gold > 0:
+		amount := min(npc.Gold, giftGold)
+		npc.Gold -= amount
+		other.Gold += amount
+		s.GoldGifted += amount
+	default:
+		return
+	}
+	npc.GiftCount++
+	other.AdjustTrust(npc.ID, TrustGift)
+}
+
+// buildCost is the default energy spent constructing any structure.
+const buildCost = 20
+
+// buildTiles maps a build kind to the tile it produces.
+var buildTiles = map[int]byte{
+	BuildWall:    TileWall,
+	BuildShelter: TileShelter,
+	BuildChest:   TileChest,
+}
+
+// build consumes the held item and energy to place a structure on the
+// NPC's current (empty) tile. Walls block movement once the builder leaves.
+func (s *Scheduler) build(npc *NPC, kind int) {
+	w := s.World
+	tile, ok := buildTiles[kind]
+	cost := s.Actions[ActionBuild].Energy
+	if !ok || npc.Item == ItemNone || npc.Energy < cost {
+		return
+	}
+	if w.TileAt(npc.X, npc.Y).Type() != TileEmpty {
+		return
+	}
+	removeItemModifier(npc, npc.Item)
+	npc.Item = ItemNone
+	s.spend(npc, ActionBuild, cost)
+	w.SetTile(npc.X, npc.Y, MakeTile(tile))
+	if tile == TileChest {
+		w.Chests[w.idx(npc.X, npc.Y)] = &Chest{Owner: npc.ID}
+	}
+	s.BuildCount++
+	s.noise(npc, noiseBuild)
+}
+
+// takeFromChest moves the most recently stored item into the NPC's hands.
+func takeFromChest(npc *NPC, c *Chest) bool {
+	if npc.Item != ItemNone || len(c.Items) == 0 {
+		return false
+	}
+	npc.Item = c.Items[len(c.Items)-1]
+	c.Items = c.Items[:len(c.Items)-1]
+	grantItemModifier(npc, npc.Item)
+	return true
+}
+
+// raidChest lets an attacking NPC standing on someone else's chest steal
+// from it. Forcing the lock costs the same energy as an attack.
+func (s *Scheduler) raidChest(npc *NPC) {
+	c := s.World.ChestAt(npc.X, npc.Y)
+	cost := s.Actions[ActionAttack].Energy
+	if c == nil || c.Owner == npc.ID || npc.Energy < cost {
+		return
+	}
+	if takeFromChest(npc, c) {
+		s.spend(npc, ActionAttack, cost)
+		s.RaidCount++
+	}
+}
+
+// strikeType returns the damage type of an NPC's attacks: forge heat turns
+// strikes made from a forge tile into fire damage.
+func (s *Scheduler) strikeType(npc *NPC) byte {
+	if s.World.TileAt(npc.X, npc.Y).Type() == TileForge {
+		return DamageFire
+	}
+	return DamagePhysical
+}
+
+// shootCost is the default energy spent per shot.
+const shootCost = 8
+
+// shoot fires the held weapon along a cardinal direction (the last move
+// direction, or north, if dir is unset) at the first NPC within ShootRange. Walls stop
+// the shot, and the miss chance grows with distance: adjacent targets are
+// always hit, targets at the edge of range are hit 1 time in ShootRange.
+func (s *Scheduler) shoot(npc *NPC, dir byte) {
+	w := s.World
+	cost := s.Actions[ActionShoot].Energy // spent per shot, hit or miss
+	if s.ShootRange <= 0 || npc.Item != ItemWeapon || npc.Energy < cost {
+		return
+	}
+	if dir < DirNorth || dir > DirWest {
+		dir = npc.LastDir
+	}
+	if dir < DirNorth || dir > DirWest {
+		dir = DirNorth
+	}
+	s.spend(npc, ActionShoot, cost)
+	s.ShotCount++
+	s.noise(npc, noiseShoot)
+
+	dx, dy := dirDelta(dir)
+	x, y := npc.X, npc.Y
+	for d := 1; d <= s.ShootRange; d++ {
+		x += dx
+		y += dy
+		if w.TileAt(x, y).Type() == TileWall {
+			return
+		}
+		other := w.npcByID[w.OccAt(x, y)]
+		if other == nil || !other.Alive() {
+			continue
+		}
+		other.IncomingFire = oppositeDir(dir)
+		if w.Rng.Intn(s.ShootRange) < d-1 {
+			return // missed
+		}
+		other.TakeDamage(3+npc.ModSum(ModAttack), s.strikeType(npc))
+		other.AdjustTrust(npc.ID, TrustAttack)
+		other.Stress += 10
+		if other.Stress > 100 {
+			other.Stress = 100
+		}
+		s.ShotHits++
+		if !other.Alive() {
+			s.KillCount++
+			npc.Kills++
+			s.claimBounty(npc, other)
+		}
+		return
+	}
+}
+
 // applyModifiers applies per-tick effects from active modifiers.
 func applyModifiers(npc *NPC) {
 	for _, m := range npc.Mods {
@@ -836,6 +1682,9 @@ func applyModifiers(npc *NPC) {
 			}
 		case ModHealth:
 			npc.Health += int(m.Mag)
+			if m.Mag < 0 {
+				npc.lastHarm = DeathPoison
+			}
 			if npc.Health > 100 {
 				npc.Health = 100
 			}
@@ -864,6 +1713,9 @@ func decayModifiers(npc *NPC) {
 
 // grantItemModifier adds the item's modifier to the NPC.
 func grantItemModifier(npc *NPC, item byte) {
+	if item != ItemNone {
+		npc.ItemsHeld |= 1 << item
+	}
 	if m, ok := ItemModifiers[item]; ok {
 		npc.AddMod(m)
 	}
@@ -876,6 +1728,13 @@ func removeItemModifier(npc *NPC, item byte) {
 	}
 }
 
+// SetItem swaps what n holds for item, moving the item modifiers with it.
+func (n *NPC) SetItem(item byte) {
+	removeItemModifier(n, n.Item)
+	n.Item = item
+	grantItemModifier(n, item)
+}
+
 // computeGasBonus calculates the gas bonus with diminishing returns.
 func computeGasBonus(modSum int) int {
 	bonus := 0
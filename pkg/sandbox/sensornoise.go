@@ -0,0 +1,141 @@
+package sandbox
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+// Sensor noise kinds.
+const (
+	NoiseNone    = 0
+	NoiseGauss   = 1 // Gaussian, Amount = standard deviation
+	NoiseUniform = 2 // uniform in [-Amount, Amount]
+)
+
+// NoiseRule blurs one sensor: each tick its reading is lost (reads 0) with
+// probability Dropout, else Kind noise of size Amount is added to it and
+// the result rounded.
+type NoiseRule struct {
+	Kind    byte
+	Amount  float64
+	Dropout float64
+}
+
+// SensorNoise makes NPCs sense the world imperfectly, so evolved behaviours
+// have to be robust rather than tuned to exact readings. Rules are keyed by
+// Ring0 slot. The noise is a pure function of Seed, NPC, tick and slot: runs
+// replay exactly, and workers sensing in parallel share no random state.
+type SensorNoise struct {
+	Seed  int64
+	Rules map[byte]NoiseRule
+}
+
+// apply blurs the NPC's freshly sensed Ring0 slots.
+func (n *SensorNoise) apply(vm *micro.VM, npc *NPC, tick int) {
+	for slot, r := range n.Rules {
+		h := noiseHash(uint64(n.Seed), uint64(npc.ID), uint64(tick), uint64(slot))
+		if r.Dropout > 0 && unitFloat(h) < r.Dropout {
+			vm.MemWrite(slot, 0)
+			continue
+		}
+		var d float64
+		switch r.Kind {
+		case NoiseGauss:
+			// Box-Muller from two more draws
+			u1 := 1 - unitFloat(noiseHash(h, 1, 0, 0)) // (0,1]
+			u2 := unitFloat(noiseHash(h, 2, 0, 0))
+			d = r.Amount * math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+		case NoiseUniform:
+			d = r.Amount * (2*unitFloat(noiseHash(h, 1, 0, 0)) - 1)
+		default:
+			continue
+		}
+		v := math.Round(float64(vm.MemRead(slot)) + d)
+		vm.MemWrite(slot, int16(math.Max(math.MinInt16, math.Min(math.MaxInt16, v))))
+	}
+}
+
+// noiseHash mixes its inputs into 64 well-scrambled bits (splitmix64 steps).
+func noiseHash(vs ...uint64) uint64 {
+	h := uint64(0x9E3779B97F4A7C15)
+	for _, v := range vs {
+		h ^= v
+		h += 0x9E3779B97F4A7C15
+		h = (h ^ h>>30) * 0xBF58476D1CE4E5B9
+		h = (h ^ h>>27) * 0x94D049BB133111EB
+		h ^= h >> 31
+	}
+	return h
+}
+
+// unitFloat maps h to [0,1).
+func unitFloat(h uint64) float64 {
+	return float64(h>>11) / (1 << 53)
+}
+
+// ParseSensorNoise reads rules separated by ";" or newlines, each
+// "sensor=part,part..." where a part is gauss:SD, uniform:W or drop:P, e.g.
+// "food-dist=gauss:2;near-dist=uniform:3,drop:0.1". Sensors are the brain
+// sensor names (see BrainWords); "*" sets the rule for every sensor not
+// given one of its own. Lines starting with # are comments. An empty spec
+// returns nil: perfect senses.
+func ParseSensorNoise(spec string, seed int64) (*SensorNoise, error) {
+	named := make(map[string]NoiseRule)
+	var all *NoiseRule
+	for _, line := range strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == '\n' }) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, parts, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("sensor noise: %q is not sensor=rule", line)
+		}
+		name = strings.TrimSpace(name)
+		if _, known := brainSensors[name]; !known && name != "*" {
+			return nil, fmt.Errorf("sensor noise: unknown sensor %q", name)
+		}
+		var r NoiseRule
+		for _, part := range strings.Split(parts, ",") {
+			kind, val, _ := strings.Cut(strings.TrimSpace(part), ":")
+			v, err := strconv.ParseFloat(strings.TrimSpace(val), 64)
+			if err != nil || v < 0 {
+				return nil, fmt.Errorf("sensor noise: %s: bad %s amount %q", name, kind, val)
+			}
+			switch kind {
+			case "gauss":
+				r.Kind, r.Amount = NoiseGauss, v
+			case "uniform":
+				r.Kind, r.Amount = NoiseUniform, v
+			case "drop":
+				if v > 1 {
+					return nil, fmt.Errorf("sensor noise: %s: drop %v is not a probability", name, v)
+				}
+				r.Dropout = v
+			default:
+				return nil, fmt.Errorf("sensor noise: %s: unknown part %q (want gauss:SD, uniform:W or drop:P)", name, kind)
+			}
+		}
+		if name == "*" {
+			all = &r
+		} else {
+			named[name] = r
+		}
+	}
+	if all == nil && len(named) == 0 {
+		return nil, nil
+	}
+	n := &SensorNoise{Seed: seed, Rules: make(map[byte]NoiseRule)}
+	for name, slot := range brainSensors {
+		if r, ok := named[name]; ok {
+			n.Rules[slot] = r
+		} else if all != nil {
+			n.Rules[slot] = *all
+		}
+	}
+	return n, nil
+}
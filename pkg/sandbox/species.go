@@ -0,0 +1,77 @@
+package sandbox
+
+// speciate groups NPCs (sorted best first) into species: each NPC joins the
+// first species whose founder is within SpeciesThreshold genome distance
+// (see tokenDistance), or founds a new one. Species come out ordered by
+// their best member.
+func (ga *GA) speciate(sorted []*NPC) [][]*NPC {
+	var species [][]*NPC
+	var founders [][]string
+next:
+	for _, npc := range sorted {
+		tokens := genomeTokens(npc.Genome)
+		for i, f := range founders {
+			if tokenDistance(tokens, f) < ga.SpeciesThreshold {
+				species[i] = append(species[i], npc)
+				continue next
+			}
+		}
+		species = append(species, []*NPC{npc})
+		founders = append(founders, tokens)
+	}
+	return species
+}
+
+// shareFitness divides each NPC's fitness by the size of its species, so a
+// crowded lineage competes as one.
+func shareFitness(species [][]*NPC) {
+	for _, sp := range species {
+		for _, npc := range sp {
+			npc.Fitness /= len(sp)
+		}
+	}
+}
+
+// speciesQuotas splits slots offspring between species: one each, best
+// species first, then the rest in proportion to each species' total shared
+// fitness.
+func speciesQuotas(species [][]*NPC, slots int) []int {
+	quotas := make([]int, len(species))
+	for i := range quotas {
+		if slots == 0 {
+			return quotas
+		}
+		quotas[i] = 1
+		slots--
+	}
+	if slots == 0 {
+		return quotas
+	}
+
+	// Shift fitness so the weakest NPC still weighs 1
+	low := 0
+	for _, sp := range species {
+		for _, npc := range sp {
+			low = min(low, npc.Fitness)
+		}
+	}
+	weights := make([]int, len(species))
+	total := 0
+	for i, sp := range species {
+		for _, npc := range sp {
+			weights[i] += npc.Fitness - low + 1
+		}
+		total += weights[i]
+	}
+	given := 0
+	for i, w := range weights {
+		q := slots * w / total
+		quotas[i] += q
+		given += q
+	}
+	for i := 0; given < slots; i = (i + 1) % len(quotas) {
+		quotas[i]++ // rounding leftovers go to the best species
+		given++
+	}
+	return quotas
+}
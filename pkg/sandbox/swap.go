@@ -0,0 +1,25 @@
+package sandbox
+
+import "fmt"
+
+// SwapGenome replaces the genome of the living NPC id mid-run, so a
+// hand-written challenger can be dropped into an evolved population. The
+// NPC keeps its body, age and belongings but starts thinking afresh with a
+// wiped VM. With freeze set it is also taken out of evolution (see
+// NPC.Frozen), so the challenger survives the next epoch; clear it to hand
+// the NPC back. Call it between ticks (Scheduler.Do from another
+// goroutine).
+func (s *Scheduler) SwapGenome(id uint16, genome []byte, freeze bool) error {
+	npc := s.World.npcByID[id]
+	if npc == nil || !npc.Alive() {
+		return fmt.Errorf("no living NPC %d", id)
+	}
+	if len(genome) == 0 {
+		return fmt.Errorf("empty genome")
+	}
+	npc.Genome = append([]byte(nil), genome...)
+	npc.Frozen = freeze
+	s.releaseVM(npc)
+	s.emit(GenomeSwapped{Tick: s.World.Tick, NPC: id, Frozen: freeze})
+	return nil
+}
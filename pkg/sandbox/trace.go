@@ -0,0 +1,130 @@
+package sandbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/micro"
+)
+
+// BrainTrace records every genome run of one NPC, one JSON line per tick
+// it thinks, for picking apart a single agent's decisions. A line holds
+// the NPC's state, then a segment per stretch of execution up to a yield
+// (and one for the rest of the run): the Ring0 sensors as the segment
+// began, each instruction executed with the stack before it, and the Ring1
+// outputs it ended with. The genome is included whenever it changes.
+// Set Scheduler.Trace before the first tick and Finish the trace after
+// the last.
+type BrainTrace struct {
+	NPC uint16 // the NPC traced
+
+	w      *bufio.Writer
+	enc    *json.Encoder
+	genome []byte         // the genome lines annotate
+	lines  map[int]string // its annotated instructions by address
+	cur    traceThink
+}
+
+// traceThink is one BrainTrace line.
+type traceThink struct {
+	Tick     int            `json:"tick"`
+	NPC      uint16         `json:"npc"`
+	X        int            `json:"x"`
+	Y        int            `json:"y"`
+	Health   int            `json:"health"`
+	Energy   int            `json:"energy"`
+	Genome   string         `json:"genome,omitempty"` // hex, when it changed
+	Gas      int            `json:"gas"`              // budget
+	Segments []traceSegment `json:"segments"`
+	End      string         `json:"end"` // halt, gas or error
+	GasUsed  int            `json:"gas_used"`
+}
+
+type traceSegment struct {
+	Ring0 map[string]int16 `json:"ring0"` // sensors by name
+	Steps []traceStep      `json:"steps"`
+	Ring1 map[string]int16 `json:"ring1"` // outputs by name, at the yield or end
+}
+
+type traceStep struct {
+	PC    int    `json:"pc"`
+	Op    string `json:"op"`    // annotated, see ExplainGenome
+	Stack string `json:"stack"` // before the instruction
+}
+
+// NewBrainTrace returns a BrainTrace of NPC id writing to out.
+func NewBrainTrace(id uint16, out io.Writer) *BrainTrace {
+	w := bufio.NewWriter(out)
+	return &BrainTrace{NPC: id, w: w, enc: json.NewEncoder(w)}
+}
+
+// begin starts a line for npc's run on vm.
+func (t *BrainTrace) begin(tick int, vm *micro.VM, npc *NPC) {
+	t.cur = traceThink{Tick: tick, NPC: npc.ID, X: npc.X, Y: npc.Y, Health: npc.Health, Energy: npc.Energy, Gas: vm.Gas}
+	if !bytes.Equal(npc.Genome, t.genome) {
+		t.genome = append(t.genome[:0], npc.Genome...)
+		t.lines = make(map[int]string)
+		for _, line := range strings.Split(strings.TrimRight(ExplainGenome(t.genome), "\n"), "\n") {
+			if pc, err := strconv.ParseUint(line[:4], 16, 16); err == nil && len(line) > 6 {
+				t.lines[int(pc)] = strings.Join(strings.Fields(line[6:]), " ")
+			}
+		}
+		t.cur.Genome = hex.EncodeToString(t.genome)
+	}
+	t.resume(vm)
+	vm.Trace = t.step
+}
+
+// step records the instruction at pc, about to run.
+func (t *BrainTrace) step(vm *micro.VM, pc int) {
+	op, ok := t.lines[pc]
+	if !ok || !bytes.Equal(vm.Code, t.genome) {
+		op = micro.OpName(vm.Code[pc]) // not the genome: a quotation
+	}
+	seg := &t.cur.Segments[len(t.cur.Segments)-1]
+	seg.Steps = append(seg.Steps, traceStep{PC: pc, Op: op, Stack: vm.StackDump()})
+}
+
+// yield ends the current segment with vm's Ring1 outputs.
+func (t *BrainTrace) yield(vm *micro.VM) {
+	seg := &t.cur.Segments[len(t.cur.Segments)-1]
+	seg.Ring1 = make(map[string]int16, len(brainOutputs))
+	for name, slot := range brainOutputs {
+		seg.Ring1[strings.TrimPrefix(name, "set-")] = vm.MemRead(64 + slot)
+	}
+}
+
+// resume starts a segment with vm's Ring0 sensors.
+func (t *BrainTrace) resume(vm *micro.VM) {
+	seg := traceSegment{Ring0: make(map[string]int16, len(brainSensors)), Steps: []traceStep{}}
+	for name, slot := range brainSensors {
+		seg.Ring0[name] = vm.MemRead(slot)
+	}
+	t.cur.Segments = append(t.cur.Segments, seg)
+}
+
+// end closes the run and writes its line.
+func (t *BrainTrace) end(vm *micro.VM) {
+	vm.Trace = nil
+	t.yield(vm)
+	switch {
+	case vm.Gas <= 0:
+		t.cur.End = "gas"
+	case vm.CFlag:
+		t.cur.End = "error"
+	default:
+		t.cur.End = "halt"
+	}
+	t.cur.GasUsed = vm.MaxGas - max(vm.Gas, 0)
+	t.enc.Encode(&t.cur)
+}
+
+// Finish flushes the trace. It returns the first write error, if any.
+func (t *BrainTrace) Finish() error {
+	return t.w.Flush()
+}
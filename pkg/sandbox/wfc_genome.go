@@ -65,7 +65,7 @@ func ClassifyOpcode(op byte, code []byte, pc int) TokenType {
 		return TokMath
 
 	// Action opcodes → TokAction (they auto-yield, so they act as action+yield)
-	case op >= micro.OpActMove && op <= micro.OpActCraft:
+	case op >= micro.OpActMove && op <= micro.OpActPost:
 		return TokAction
 
 	// Yield / halt
@@ -218,7 +218,7 @@ func TestRiverBlocks(t *testing.T) {
 
 	// Run a tick - NPC genome halts immediately, so override Ring1 manually
 	sched.sense(npc)
-	sched.vm.MemWrite(64+Ring1Move, int16(moveDir))
+	sched.VM(npc).MemWrite(64+Ring1Move, int16(moveDir))
 	sched.act(npc)
 
 	// NPC should not have moved into river
@@ -255,7 +255,7 @@ func TestBiomeSensor(t *testing.T) {
 			w.Spawn(npc)
 
 			sched.sense(npc)
-			sensorVal := sched.vm.MemRead(Ring0Biome)
+			sensorVal := sched.VM(npc).MemRead(Ring0Biome)
 
 			if byte(sensorVal) != biome {
 				t.Errorf("Ring0Biome at (%d,%d): got %d, expected %d (biome=%d)",
@@ -17,6 +17,13 @@ const (
 	TileCrystal // 7
 	TileForge   // 8
 	TilePoison  // 9 — deals damage when walked on
+	TileShelter // 10 — built by NPCs, relieves stress while occupied
+	TileChest   // 11 — built by NPCs, storage
+	TileMarket  // 12 — buy and sell for gold (see PlaceMarkets)
+	TilePost    // 13 — trading post: leave offers for other NPCs (see PlaceTradingPosts)
+	TileSchool  // 14 — teaching here passes on more, more reliably (see PlaceSchools)
+
+	numTileTypes // new tile types go above, and need a colour in tileColors
 )
 
 // Tile is pure terrain — occupancy is tracked separately in OccGrid.
@@ -36,6 +43,18 @@ func isItem(typ byte) bool {
 	return (typ >= TileTool && typ <= TileTreasure) || typ == TileCrystal
 }
 
+// isStructure returns true if the tile type was built by an NPC.
+func isStructure(typ byte) bool { return typ == TileShelter || typ == TileChest }
+
+// ChestCapacity is the number of items a chest can hold.
+const ChestCapacity = 4
+
+// Chest is the storage behind a TileChest tile.
+type Chest struct {
+	Owner uint16 // ID of the NPC that built it
+	Items []byte // stored item types, last in first out
+}
+
 // World is a 2D tile grid with NPCs.
 type World struct {
 	Size int // width and height (square)
@@ -49,14 +68,18 @@ type World struct {
 	npcByID map[uint16]*NPC
 
 	// Cached tile counts (maintained by SetTile)
-	foodCount int
-	itemCount int
+	foodCount   int
+	itemCount   int
+	marketCount int
 
 	// Config
-	FoodRate    float64 // probability of food spawn per tick
-	MaxFood     int     // max food tiles on map
-	ItemRate    float64 // probability of item spawn per tick
-	MaxItems    int     // cap for item tiles on map
+	FoodRate    float64   // probability of food spawn per tick
+	MaxFood     int       // max food tiles on map
+	ItemRate    float64   // probability of item spawn per tick
+	MaxItems    int       // cap for item tiles on map
+	PoisonOdds  int       // 1-in-N chance an item spawn is poison (outside biomes)
+	NightTicks  int       // ticks of winter/night at the end of each day cycle
+	Gradients   Gradients // where food, items and poison spawn richest (zero = uniform)
 	Rng         *rand.Rand
 	NextID      uint16
 	FoodSpawned int
@@ -64,30 +87,37 @@ type World struct {
 	// Poison tile lifetimes: grid index → tick when placed
 	PoisonTTL map[int]int
 
+	// Chest contents: grid index → chest (dropped when the tile changes)
+	Chests map[int]*Chest
+
 	// Tile cooldowns for harvest (parallel to Grid, 0 = available)
 	Cooldowns []byte
 
 	// Biome system (WFC-generated)
 	BiomeGrid []byte // parallel to Grid, BiomeClearing..BiomeBridge per cell
 	Biomes    bool   // true if WFC biomes are active
+
 }
 
 // NewWorld creates a Size×Size world.
 func NewWorld(size int, rng *rand.Rand) *World {
 	w := &World{
-		Size:      size,
-		Grid:      make([]Tile, size*size),
-		OccGrid:   make([]uint16, size*size),
-		NPCs:      make([]*NPC, 0, 32),
-		npcByID:   make(map[uint16]*NPC),
-		FoodRate:  0.25,
-		MaxFood:   size * 3 / 4,
-		ItemRate:  0.05,
-		MaxItems:  size / 4,
-		Rng:       rng,
-		NextID:    1,
-		PoisonTTL: make(map[int]int),
-		Cooldowns: make([]byte, size*size),
+		Size:       size,
+		Grid:       make([]Tile, size*size),
+		OccGrid:    make([]uint16, size*size),
+		NPCs:       make([]*NPC, 0, 32),
+		npcByID:    make(map[uint16]*NPC),
+		FoodRate:   0.25,
+		MaxFood:    size * 3 / 4,
+		ItemRate:   0.05,
+		MaxItems:   size / 4,
+		PoisonOdds: 10,
+		NightTicks: DayCycle / 4,
+		Rng:        rng,
+		NextID:     1,
+		PoisonTTL:  make(map[int]int),
+		Chests:     make(map[int]*Chest),
+		Cooldowns:  make([]byte, size*size),
 	}
 
 	// Place forges: max(3, size/8)
@@ -113,20 +143,23 @@ func NewWorld(size int, rng *rand.Rand) *World {
 // WFC runs at half resolution (each biome cell = 2x2 world tiles).
 func NewWorldWithBiomes(size int, rng *rand.Rand) *World {
 	w := &World{
-		Size:      size,
-		Grid:      make([]Tile, size*size),
-		OccGrid:   make([]uint16, size*size),
-		NPCs:      make([]*NPC, 0, 32),
-		npcByID:   make(map[uint16]*NPC),
-		FoodRate:  0.25,
-		MaxFood:   size * 3 / 4,
-		ItemRate:  0.05,
-		MaxItems:  size / 4,
-		Rng:       rng,
-		NextID:    1,
-		PoisonTTL: make(map[int]int),
-		Cooldowns: make([]byte, size*size),
-		Biomes:    true,
+		Size:       size,
+		Grid:       make([]Tile, size*size),
+		OccGrid:    make([]uint16, size*size),
+		NPCs:       make([]*NPC, 0, 32),
+		npcByID:    make(map[uint16]*NPC),
+		FoodRate:   0.25,
+		MaxFood:    size * 3 / 4,
+		ItemRate:   0.05,
+		MaxItems:   size / 4,
+		PoisonOdds: 10,
+		NightTicks: DayCycle / 4,
+		Rng:        rng,
+		NextID:     1,
+		PoisonTTL:  make(map[int]int),
+		Chests:     make(map[int]*Chest),
+		Cooldowns:  make([]byte, size*size),
+		Biomes:     true,
 	}
 
 	// WFC at half resolution
@@ -256,20 +289,27 @@ func (w *World) TileAt(x, y int) Tile {
 
 // TileAhead returns the tile type one step in the given direction from (x,y).
 func (w *World) TileAhead(x, y int, dir byte) byte {
-	dx, dy := 0, 0
+	dx, dy := dirDelta(dir)
+	return w.TileAt(x+dx, y+dy).Type()
+}
+
+// dirDelta returns the unit step for a direction (north if unset).
+func dirDelta(dir byte) (int, int) {
 	switch dir {
-	case DirNorth:
-		dy = -1
 	case DirEast:
-		dx = 1
+		return 1, 0
 	case DirSouth:
-		dy = 1
+		return 0, 1
 	case DirWest:
-		dx = -1
+		return -1, 0
 	default:
-		dy = -1 // default: look north
+		return 0, -1
 	}
-	return w.TileAt(x+dx, y+dy).Type()
+}
+
+// oppositeDir returns the reverse of a cardinal direction.
+func oppositeDir(dir byte) byte {
+	return (dir+1)%4 + 1
 }
 
 func (w *World) SetTile(x, y int, t Tile) {
@@ -293,10 +333,27 @@ func (w *World) SetTile(x, y int, t Tile) {
 	if isItem(newTyp) {
 		w.itemCount++
 	}
+	if old == TileMarket {
+		w.marketCount--
+	}
+	if newTyp == TileMarket {
+		w.marketCount++
+	}
+	if old == TileChest && newTyp != TileChest {
+		delete(w.Chests, i) // contents are lost with the chest
+	}
 
 	w.Grid[i] = t
 }
 
+// ChestAt returns the chest at (x,y), or nil if there is none.
+func (w *World) ChestAt(x, y int) *Chest {
+	if !w.InBounds(x, y) {
+		return nil
+	}
+	return w.Chests[w.idx(x, y)]
+}
+
 // OccAt returns the NPC ID occupying (x,y), or 0 if empty.
 func (w *World) OccAt(x, y int) uint16 {
 	if !w.InBounds(x, y) {
@@ -388,9 +445,16 @@ func (w *World) FoodCount() int {
 	return w.foodCount
 }
 
+// Night reports whether the tick falls in the last NightTicks of the day
+// cycle (by default ticks 192-255): the winter/night phase when no food
+// spawns.
+func (w *World) Night() bool {
+	return w.Tick%DayCycle >= DayCycle-w.NightTicks
+}
+
 func (w *World) RespawnFood() {
-	// Winter: last quarter of day cycle (ticks 192-255), no food spawns
-	if w.Tick%DayCycle >= DayCycle*3/4 {
+	// Winter: end of the day cycle (see Night), no food spawns
+	if w.Night() {
 		return
 	}
 	if w.FoodCount() >= w.MaxFood {
@@ -408,6 +472,9 @@ func (w *World) RespawnFood() {
 			if w.TileAt(x, y).Type() != TileEmpty || w.OccAt(x, y) != 0 {
 				continue
 			}
+			if !w.Gradients.Food.accept(w, x, y) {
+				continue
+			}
 			// Biome-aware: use per-biome food rate
 			if w.Biomes && w.BiomeGrid != nil {
 				biome := w.BiomeGrid[w.idx(x, y)]
@@ -422,15 +489,36 @@ func (w *World) RespawnFood() {
 	}
 }
 
+// inCone reports whether (x,y) lies in the 90° forward cone of a viewer at
+// (cx,cy) facing dir: at least as far ahead as it is to the side.
+func inCone(cx, cy, x, y int, dir byte) bool {
+	fx, fy := dirDelta(dir)
+	ahead := (x-cx)*fx + (y-cy)*fy
+	side := (x-cx)*fy - (y-cy)*fx
+	return ahead >= abs(side)
+}
+
 // scanManhattanRing calls fn for each cell at exactly Manhattan distance d from (cx,cy).
 // fn returns true to stop scanning (found). Returns true if fn stopped early.
 func (w *World) scanManhattanRing(cx, cy, d int, fn func(x, y int) bool) bool {
+	return w.scanCone(cx, cy, d, DirNone, fn)
+}
+
+// scanCone is scanManhattanRing limited to the forward cone facing dir
+// (DirNone = all-round).
+func (w *World) scanCone(cx, cy, d int, dir byte, fn func(x, y int) bool) bool {
 	if d == 0 {
 		if w.InBounds(cx, cy) {
 			return fn(cx, cy)
 		}
 		return false
 	}
+	if dir != DirNone {
+		inner := fn
+		fn = func(x, y int) bool {
+			return inCone(cx, cy, x, y, dir) && inner(x, y)
+		}
+	}
 	// Walk the diamond perimeter: 4 edges, d cells each
 	for i := 0; i < d; i++ {
 		// Top-right edge: (cx+i, cy-d+i)
@@ -465,9 +553,14 @@ const maxSearchRadius = 31
 
 // NearestFood returns Manhattan distance to nearest food tile, or 31 if none.
 func (w *World) NearestFood(x, y int) int {
+	return w.nearestFood(x, y, DirNone)
+}
+
+// nearestFood is NearestFood limited to the forward cone facing dir.
+func (w *World) nearestFood(x, y int, dir byte) int {
 	for d := 0; d <= maxSearchRadius; d++ {
 		found := false
-		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+		w.scanCone(x, y, d, dir, func(fx, fy int) bool {
 			if w.TileAt(fx, fy).Type() == TileFood {
 				found = true
 				return true
@@ -483,9 +576,14 @@ func (w *World) NearestFood(x, y int) int {
 
 // NearestFoodDir returns the direction (1=N,2=E,3=S,4=W) toward nearest food, or 0.
 func (w *World) NearestFoodDir(x, y int) int {
+	return w.nearestFoodDir(x, y, DirNone)
+}
+
+// nearestFoodDir is NearestFoodDir limited to the forward cone facing dir.
+func (w *World) nearestFoodDir(x, y int, dir byte) int {
 	for d := 0; d <= maxSearchRadius; d++ {
 		bx, by := -1, -1
-		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+		w.scanCone(x, y, d, dir, func(fx, fy int) bool {
 			if w.TileAt(fx, fy).Type() == TileFood {
 				bx, by = fx, fy
 				return true
@@ -564,10 +662,15 @@ func directionToward(fx, fy, tx, ty int) int {
 
 // NearestNPCFull returns (distance, ID, direction) to nearest other NPC in a single scan.
 func (w *World) NearestNPCFull(x, y int, excludeID uint16) (int, uint16, int) {
+	return w.nearestNPCFull(x, y, excludeID, DirNone)
+}
+
+// nearestNPCFull is NearestNPCFull limited to the forward cone facing dir.
+func (w *World) nearestNPCFull(x, y int, excludeID uint16, dir byte) (int, uint16, int) {
 	for d := 1; d <= maxSearchRadius; d++ {
 		bestID := uint16(0)
 		bx, by := -1, -1
-		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+		w.scanCone(x, y, d, dir, func(fx, fy int) bool {
 			occ := w.OccAt(fx, fy)
 			if occ != 0 && occ != excludeID {
 				if npc := w.npcByID[occ]; npc != nil && npc.Alive() {
@@ -608,9 +711,14 @@ func (w *World) NearestNPCDir(x, y int, excludeID uint16) int {
 
 // NearestItemDir returns the direction toward the nearest item tile, or 0.
 func (w *World) NearestItemDir(x, y int) int {
+	return w.nearestItemDir(x, y, DirNone)
+}
+
+// nearestItemDir is NearestItemDir limited to the forward cone facing dir.
+func (w *World) nearestItemDir(x, y int, dir byte) int {
 	for d := 0; d <= maxSearchRadius; d++ {
 		bx, by := -1, -1
-		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+		w.scanCone(x, y, d, dir, func(fx, fy int) bool {
 			if isItem(w.TileAt(fx, fy).Type()) {
 				bx, by = fx, fy
 				return true
@@ -637,7 +745,7 @@ func (w *World) RespawnItems() {
 	if w.Rng.Float64() > w.ItemRate {
 		return
 	}
-	// Place 1 item (1-in-10 chance it's poison instead)
+	// Place 1 item (1-in-PoisonOdds chance it's poison instead)
 	for tries := 0; tries < 50; tries++ {
 		x := w.Rng.Intn(w.Size)
 		y := w.Rng.Intn(w.Size)
@@ -656,14 +764,14 @@ func (w *World) RespawnItems() {
 			}
 
 			// Biome poison chance
-			if props.Poison > 0 && w.Rng.Float64() < props.Poison {
+			if props.Poison > 0 && w.Rng.Float64() < props.Poison*w.poisonScale(x, y) {
 				w.SetTile(x, y, MakeTile(TilePoison))
 				w.PoisonTTL[w.idx(x, y)] = w.Tick
 				return
 			}
 
 			// No items in this biome
-			if len(props.ItemTypes) == 0 {
+			if len(props.ItemTypes) == 0 || !w.Gradients.Items.accept(w, x, y) {
 				continue
 			}
 
@@ -674,7 +782,16 @@ func (w *World) RespawnItems() {
 		}
 
 		// Non-biome (original) logic
-		if w.Rng.Intn(10) == 0 {
+		var poison bool
+		if w.Gradients.Poison == nil {
+			poison = w.Rng.Intn(w.PoisonOdds) == 0
+		} else {
+			poison = w.Rng.Float64()*float64(w.PoisonOdds) < w.poisonScale(x, y)
+		}
+		if !poison && !w.Gradients.Items.accept(w, x, y) {
+			continue
+		}
+		if poison {
 			w.SetTile(x, y, MakeTile(TilePoison))
 			w.PoisonTTL[w.idx(x, y)] = w.Tick
 		} else {
@@ -692,9 +809,14 @@ func (w *World) RespawnItems() {
 
 // NearestItem returns (Manhattan distance, tile type) of nearest item tile, or (31, 0) if none.
 func (w *World) NearestItem(x, y int) (int, byte) {
+	return w.nearestItem(x, y, DirNone)
+}
+
+// nearestItem is NearestItem limited to the forward cone facing dir.
+func (w *World) nearestItem(x, y int, dir byte) (int, byte) {
 	for d := 0; d <= maxSearchRadius; d++ {
 		bestType := byte(0)
-		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+		w.scanCone(x, y, d, dir, func(fx, fy int) bool {
 			typ := w.TileAt(fx, fy).Type()
 			if isItem(typ) {
 				bestType = typ
@@ -762,9 +884,14 @@ func (w *World) MarketValue(item byte) int {
 
 // NearestPoison returns Manhattan distance to nearest poison tile, or 31 if none.
 func (w *World) NearestPoison(x, y int) int {
+	return w.nearestPoison(x, y, DirNone)
+}
+
+// nearestPoison is NearestPoison limited to the forward cone facing dir.
+func (w *World) nearestPoison(x, y int, dir byte) int {
 	for d := 0; d <= maxSearchRadius; d++ {
 		found := false
-		w.scanManhattanRing(x, y, d, func(fx, fy int) bool {
+		w.scanCone(x, y, d, dir, func(fx, fy int) bool {
 			if w.TileAt(fx, fy).Type() == TilePoison {
 				found = true
 				return true
@@ -792,17 +919,20 @@ func (w *World) DecayPoison() {
 	}
 }
 
-// Blight destroys ~50% of food tiles on the map.
-func (w *World) Blight() {
+// Blight destroys ~50% of food tiles on the map and returns how many.
+func (w *World) Blight() int {
+	destroyed := 0
 	for y := 0; y < w.Size; y++ {
 		for x := 0; x < w.Size; x++ {
 			if w.TileAt(x, y).Type() == TileFood {
 				if w.Rng.Intn(2) == 0 {
 					w.SetTile(x, y, MakeTile(TileEmpty))
+					destroyed++
 				}
 			}
 		}
 	}
+	return destroyed
 }
 
 // AutoWorldSize returns an appropriate world size for the given number of NPCs.
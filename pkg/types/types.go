@@ -3,10 +3,13 @@
 package types
 
 import (
+	"bufio"
 	"fmt"
 	"image"
 	"image/color"
+	"net"
 	"strings"
+	"sync/atomic"
 )
 
 // Value is the interface all PSIL values implement.
@@ -103,6 +106,78 @@ func (q *QuotedSymbol) Equal(other Value) bool {
 // This is the key type - quotations are first-class values.
 type Quotation struct {
 	Items []Value
+
+	// Items may be a window on a listBuf (see Cons and Concat), starting
+	// at index off
+	buf *listBuf
+	off int
+}
+
+// listBuf is a backing array shared by the quotations Cons and Concat
+// build on one another. Slots below head and from tail up are free; each is
+// claimed once, by the first quotation to grow into it, so no quotation
+// ever sees its items change.
+type listBuf struct {
+	vals       []Value
+	head, tail atomic.Int64
+}
+
+// shared returns q's buffer if q.Items is still the window q.off names
+func (q *Quotation) shared() *listBuf {
+	b := q.buf
+	if b == nil || len(q.Items) == 0 || &q.Items[0] != &b.vals[q.off] {
+		return nil
+	}
+	return b
+}
+
+// window makes a quotation of n items, for the caller to fill, on a new
+// buffer with about as many free slots again: before the items if front is
+// set, after them if not
+func window(n int, front bool) *Quotation {
+	room := n + 8
+	b := &listBuf{vals: make([]Value, n+room)}
+	off := 0
+	if front {
+		off = room
+	}
+	b.head.Store(int64(off))
+	b.tail.Store(int64(off + n))
+	return &Quotation{Items: b.vals[off : off+n : off+n], buf: b, off: off}
+}
+
+// Cons is [v q...]. Consing onto a quotation Cons made takes amortized
+// constant time: the new one shares its items.
+func Cons(v Value, q *Quotation) *Quotation {
+	if b := q.shared(); b != nil && q.off > 0 && b.head.CompareAndSwap(int64(q.off), int64(q.off-1)) {
+		off, end := q.off-1, q.off+len(q.Items)
+		b.vals[off] = v
+		return &Quotation{Items: b.vals[off:end:end], buf: b, off: off}
+	}
+	c := window(len(q.Items)+1, true)
+	c.Items[0] = v
+	copy(c.Items[1:], q.Items)
+	return c
+}
+
+// Concat is [a... b...]. Appending to a quotation Concat made takes time
+// in proportion to what is appended: the new one shares a's items.
+func Concat(a, b *Quotation) *Quotation {
+	if len(b.Items) == 0 {
+		return &Quotation{Items: a.Items, buf: a.buf, off: a.off}
+	}
+	if buf := a.shared(); buf != nil {
+		end := a.off + len(a.Items)
+		grown := end + len(b.Items)
+		if grown <= len(buf.vals) && buf.tail.CompareAndSwap(int64(end), int64(grown)) {
+			copy(buf.vals[end:], b.Items)
+			return &Quotation{Items: buf.vals[a.off:grown:grown], buf: buf, off: a.off}
+		}
+	}
+	c := window(len(a.Items)+len(b.Items), false)
+	copy(c.Items, a.Items)
+	copy(c.Items[len(a.Items):], b.Items)
+	return c
 }
 
 func (q *Quotation) String() string {
@@ -233,6 +308,109 @@ func (t *Turtle) Equal(other Value) bool {
 	return false
 }
 
+// Socket is an open network connection, written and read a line at a time
+type Socket struct {
+	Conn   net.Conn
+	Reader *bufio.Reader
+	Addr   string // as given to socket-connect
+}
+
+// NewSocket wraps a connection made to addr
+func NewSocket(conn net.Conn, addr string) *Socket {
+	return &Socket{Conn: conn, Reader: bufio.NewReader(conn), Addr: addr}
+}
+
+func (s *Socket) String() string { return "<socket:" + s.Addr + ">" }
+func (s *Socket) Type() string   { return "socket" }
+
+func (s *Socket) Equal(other Value) bool {
+	if o, ok := other.(*Socket); ok {
+		return s == o
+	}
+	return false
+}
+
+// Vector is a vec2, a vec3 or a mat3. A matrix keeps its elements row by
+// row. Vectors are values: the vector words always make new ones.
+type Vector struct {
+	Elems []float64
+	Side  int // rows (and columns) of a matrix, 0 for a vector
+}
+
+// NewVector makes a vector of the given elements
+func NewVector(elems ...float64) *Vector {
+	return &Vector{Elems: elems}
+}
+
+// Kind is the vector's shape: vec2, vec3 or mat3
+func (v *Vector) Kind() string {
+	if v.Side > 0 {
+		return fmt.Sprintf("mat%d", v.Side)
+	}
+	return fmt.Sprintf("vec%d", len(v.Elems))
+}
+
+func (v *Vector) String() string {
+	parts := make([]string, len(v.Elems))
+	for n, x := range v.Elems {
+		parts[n] = Number(x).String()
+	}
+	return "<" + v.Kind() + ":" + strings.Join(parts, ",") + ">"
+}
+
+func (v *Vector) Type() string { return "vector" }
+
+func (v *Vector) Equal(other Value) bool {
+	o, ok := other.(*Vector)
+	if !ok || v.Side != o.Side || len(v.Elems) != len(o.Elems) {
+		return false
+	}
+	for n, x := range v.Elems {
+		if x != o.Elems[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// StringBuffer accumulates text without copying it on every addition.
+// Like an image it is changed in place: copies of it share the text.
+type StringBuffer struct {
+	Text strings.Builder
+}
+
+func (b *StringBuffer) String() string { return fmt.Sprintf("<sbuf:%d bytes>", b.Text.Len()) }
+func (b *StringBuffer) Type() string   { return "sbuf" }
+
+func (b *StringBuffer) Equal(other Value) bool {
+	if o, ok := other.(*StringBuffer); ok {
+		return b == o
+	}
+	return false
+}
+
+// Stream is a lazy, possibly endless sequence. It holds how to make its
+// elements rather than the elements: every use starts again from the
+// first, so a dup'd stream is not used up by its copy.
+type Stream struct {
+	Desc string // the words that built it, e.g. "0 lazy-range 10 take"
+	Open func() StreamNext
+}
+
+// StreamNext yields a stream's next element, or false at its end. It gets
+// the interpreter using the stream, as a Builtin's Fn does.
+type StreamNext func(interp interface{}) (Value, bool, error)
+
+func (s *Stream) String() string { return "<stream:" + s.Desc + ">" }
+func (s *Stream) Type() string   { return "stream" }
+
+func (s *Stream) Equal(other Value) bool {
+	if o, ok := other.(*Stream); ok {
+		return s == o
+	}
+	return false
+}
+
 // Error codes (stored in A register when C flag is set)
 const (
 	ErrNone             = 0
@@ -244,6 +422,11 @@ const (
 	ErrInvalidQuotation = 6
 	ErrImageError       = 7
 	ErrFileError        = 8
+	ErrCanceled         = 9
+	ErrDenied           = 10
+	ErrOutputLimit      = 11
+	ErrNetwork          = 12
+	ErrExec             = 13
 )
 
 // ErrorMessage returns a human-readable error message for an error code
@@ -267,6 +450,16 @@ func ErrorMessage(code int) string {
 		return "image error"
 	case ErrFileError:
 		return "file error"
+	case ErrCanceled:
+		return "canceled"
+	case ErrDenied:
+		return "capability denied"
+	case ErrOutputLimit:
+		return "output limit exceeded"
+	case ErrNetwork:
+		return "network error"
+	case ErrExec:
+		return "exec error"
 	default:
 		return fmt.Sprintf("unknown error %d", code)
 	}
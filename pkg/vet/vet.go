@@ -0,0 +1,381 @@
+// Package vet reports likely mistakes in PSIL programs: if-then-else
+// branches that leave different stack depths, DEFINEs that nothing uses or
+// that shadow a builtin, loops and recursions only gas can stop, and number
+// literals that do not read the way they look.
+//
+// Stack effects come from the builtins' documented effects (see
+// interpreter.WordEffect) and are inferred for DEFINEs and quotations; a
+// check whose effects cannot be worked out statically stays quiet.
+package vet
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/alecthomas/participle/v2/lexer"
+	"github.com/psilLang/psil/pkg/interpreter"
+	"github.com/psilLang/psil/pkg/parser"
+)
+
+// Finding is one likely mistake
+type Finding struct {
+	Pos     lexer.Position
+	Check   string // branches, unused, shadow, endless or number
+	Message string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%d:%d: %s", f.Pos.Line, f.Pos.Column, f.Message)
+}
+
+// vetter holds what one source defines and what it has found so far
+type vetter struct {
+	defs     map[string]*parser.Definition
+	effects  map[string]*effect // inferred DEFINE effects; nil if unknown
+	findings []Finding
+}
+
+// Source vets PSIL source, returning its findings in source order. Source
+// that does not parse is an error.
+func Source(src []byte) ([]Finding, error) {
+	text := string(src)
+	prog, err := parser.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := parser.Tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &vetter{defs: make(map[string]*parser.Definition), effects: make(map[string]*effect)}
+	var top []*parser.Expression
+	for _, stmt := range prog.Statements {
+		if d := stmt.Definition; d != nil {
+			v.defs[d.Name] = d
+		} else {
+			top = append(top, stmt.Expression)
+		}
+	}
+
+	v.walk(top)
+	for _, stmt := range prog.Statements {
+		if d := stmt.Definition; d != nil {
+			v.walk(d.Body.Items)
+			v.definition(d)
+		}
+	}
+	if len(top) > 0 {
+		v.unused(prog)
+	}
+	v.numbers(tokens)
+
+	sort.SliceStable(v.findings, func(a, b int) bool {
+		return v.findings[a].Pos.Offset < v.findings[b].Pos.Offset
+	})
+	return v.findings, nil
+}
+
+func (v *vetter) report(pos lexer.Position, check, format string, args ...any) {
+	v.findings = append(v.findings, Finding{Pos: pos, Check: check, Message: fmt.Sprintf(format, args...)})
+}
+
+// walk checks each word of a sequence and of the quotations in it
+func (v *vetter) walk(items []*parser.Expression) {
+	for j, x := range items {
+		if x.Quotation != nil {
+			v.walk(x.Quotation.Items)
+			continue
+		}
+		name := word(x)
+		if name == "" {
+			continue
+		}
+		if _, ok := v.defs[name]; ok {
+			continue // not the builtin
+		}
+		v.branches(items[:j], x, name)
+		v.endless(items[:j], x, name)
+		if j > 0 && items[j-1].Number != nil && *items[j-1].Number == 0 {
+			switch name {
+			case "/", "div", "mod", "%":
+				v.report(x.Pos, "number", "division by zero")
+			}
+		}
+	}
+}
+
+// branches checks that both ways through a conditional leave the stack
+// equally deep
+func (v *vetter) branches(prev []*parser.Expression, x *parser.Expression, name string) {
+	switch name {
+	case "ifte", "ifelse", "branch":
+		q := trailing(prev, 3)
+		if q == nil {
+			return
+		}
+		t, okT := v.effectOf(q[1].Items)
+		e, okE := v.effectOf(q[2].Items)
+		if okT && okE && t.net() != e.net() {
+			v.report(x.Pos, "branches", "%s branches leave different stack depths: then %+d, else %+d", name, t.net(), e.net())
+		}
+	case "if":
+		q := trailing(prev, 2)
+		if q == nil {
+			return
+		}
+		if t, ok := v.effectOf(q[1].Items); ok && t.net() != 0 {
+			v.report(x.Pos, "branches", "if branch changes the stack depth by %+d, but skipping it does not", t.net())
+		}
+	}
+}
+
+// endless finds loops and recursions that only gas or a timeout can stop
+func (v *vetter) endless(prev []*parser.Expression, x *parser.Expression, name string) {
+	switch name {
+	case "loop":
+		if q := trailing(prev, 1); q != nil && cannotFail(q[0].Items) {
+			v.report(x.Pos, "endless", "loop body can never fail, so only gas or a timeout stops the loop")
+		}
+	case "while":
+		if q := trailing(prev, 2); q != nil && always(q[0].Items, "true") {
+			v.report(x.Pos, "endless", "while condition is always true, so only gas or a timeout stops the loop")
+		}
+	case "tailrec", "linrec", "binrec", "genrec":
+		n := 4
+		if name == "tailrec" {
+			n = 3
+		}
+		if q := trailing(prev, n); q != nil && always(q[0].Items, "false") {
+			v.report(x.Pos, "endless", "%s condition is always false, so the recursion never ends", name)
+		}
+	}
+}
+
+// definition checks a DEFINE itself
+func (v *vetter) definition(d *parser.Definition) {
+	if _, ok := interpreter.WordDoc(d.Name); ok {
+		v.report(d.NamePos(), "shadow", "DEFINE %s shadows the builtin %s", d.Name, d.Name)
+	}
+	for _, x := range d.Body.Items {
+		if word(x) == d.Name {
+			v.report(x.Pos, "endless", "%s calls itself unconditionally, so it never returns", d.Name)
+			break
+		}
+	}
+}
+
+// unused reports DEFINEs that are referred to only from their own body.
+// It runs for programs only: a file of DEFINEs alone is a library.
+func (v *vetter) unused(prog *parser.Program) {
+	used := make(map[string]bool)
+	var mark func(items []*parser.Expression, self string)
+	mark = func(items []*parser.Expression, self string) {
+		for _, x := range items {
+			switch {
+			case x.Quotation != nil:
+				mark(x.Quotation.Items, self)
+			case x.QuotedSymbol != nil:
+				used[*x.QuotedSymbol] = true
+			case x.String != nil:
+				used[strings.Trim(*x.String, `"`)] = true // "name" undefine
+			case word(x) != self:
+				used[word(x)] = true
+			}
+		}
+	}
+	for _, stmt := range prog.Statements {
+		if d := stmt.Definition; d != nil {
+			mark(d.Body.Items, d.Name)
+		} else {
+			mark([]*parser.Expression{stmt.Expression}, "")
+		}
+	}
+	for _, stmt := range prog.Statements {
+		if d := stmt.Definition; d != nil && !used[d.Name] {
+			v.report(d.NamePos(), "unused", "%s is defined but never used", d.Name)
+		}
+	}
+}
+
+// numbers checks number literals as they were lexed
+func (v *vetter) numbers(tokens []parser.Token) {
+	for j, t := range tokens {
+		var next parser.Token
+		if j+1 < len(tokens) && tokens[j+1].Pos.Offset == t.Pos.Offset+len(t.Value) {
+			next = tokens[j+1] // no space between
+		}
+		switch t.Kind {
+		case "Number":
+			digits := strings.TrimPrefix(t.Value, "-")
+			if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+				v.report(t.Pos, "number", "%s has a leading zero, but numbers are always decimal", t.Value)
+			}
+			if n, ok := new(big.Int).SetString(t.Value, 10); ok {
+				f, _ := strconv.ParseFloat(t.Value, 64)
+				if exact, _ := big.NewFloat(f).Int(nil); exact.Cmp(n) != 0 {
+					v.report(t.Pos, "number", "%s cannot be held exactly and reads as %s", t.Value, exact)
+				}
+			}
+			if next.Kind == "Ident" || next.Kind == "Operator" && strings.HasPrefix(next.Value, ".") {
+				v.report(t.Pos, "number", "%s%s reads as the number %s followed by the word %s", t.Value, next.Value, t.Value, next.Value)
+			}
+		case "Operator":
+			switch {
+			case next.Kind == "Number" && strings.HasSuffix(t.Value, "."):
+				v.report(t.Pos, "number", "%s%s reads as the word %s followed by the number %s; write 0%s", t.Value, next.Value, t.Value, next.Value, t.Value[len(t.Value)-1:]+next.Value)
+			case next.Kind == "Ident" && t.Value == "-":
+				v.report(t.Pos, "number", "-%s reads as the word - followed by the word %s; negate with neg", next.Value, next.Value)
+			}
+		}
+	}
+}
+
+// effect is a stack effect: how many values are taken and how many left
+type effect struct{ in, out int }
+
+// then is a followed by b
+func (a effect) then(b effect) effect {
+	if b.in > a.out {
+		return effect{a.in + b.in - a.out, b.out}
+	}
+	return effect{a.in, a.out - b.in + b.out}
+}
+
+// net is the change in stack depth
+func (a effect) net() int {
+	return a.out - a.in
+}
+
+// effectOf infers the effect of a sequence of expressions
+func (v *vetter) effectOf(items []*parser.Expression) (effect, bool) {
+	var e effect
+	for j, x := range items {
+		w, ok := v.wordEffect(items[:j], x)
+		if !ok {
+			return effect{}, false
+		}
+		e = e.then(w)
+	}
+	return e, true
+}
+
+// wordEffect is the effect of one expression after prev. The combinators
+// that run a literal quotation a fixed way get the quotation's effect.
+func (v *vetter) wordEffect(prev []*parser.Expression, x *parser.Expression) (effect, bool) {
+	name := word(x)
+	if name == "" {
+		return effect{0, 1}, true // a literal or quotation
+	}
+	if d, ok := v.defs[name]; ok {
+		return v.defEffect(d)
+	}
+	switch name {
+	case "i", "call":
+		if q := trailing(prev, 1); q != nil {
+			if b, ok := v.effectOf(q[0].Items); ok {
+				return effect{1 + b.in, b.out}, true
+			}
+		}
+	case "dip":
+		if q := trailing(prev, 1); q != nil {
+			if b, ok := v.effectOf(q[0].Items); ok {
+				return effect{2 + b.in, b.out + 1}, true
+			}
+		}
+	case "ifte", "ifelse", "branch":
+		if q := trailing(prev, 3); q != nil {
+			t, okT := v.effectOf(q[1].Items)
+			e, okE := v.effectOf(q[2].Items)
+			if okT && okE && t.net() == e.net() {
+				in := max(t.in, e.in)
+				return effect{3 + in, in + t.net()}, true
+			}
+		}
+	case "if":
+		if q := trailing(prev, 2); q != nil {
+			if t, ok := v.effectOf(q[1].Items); ok && t.net() == 0 {
+				return effect{2 + t.in, t.in}, true
+			}
+		}
+	}
+	in, out, ok := interpreter.WordEffect(name)
+	return effect{in, out}, ok
+}
+
+// defEffect infers a DEFINE's effect once; recursive ones stay unknown
+func (v *vetter) defEffect(d *parser.Definition) (effect, bool) {
+	if e, done := v.effects[d.Name]; done {
+		if e == nil {
+			return effect{}, false
+		}
+		return *e, true
+	}
+	v.effects[d.Name] = nil
+	e, ok := v.effectOf(d.Body.Items)
+	if ok {
+		v.effects[d.Name] = &e
+	}
+	return e, ok
+}
+
+// word returns the name an expression calls, or "" for a value
+func word(x *parser.Expression) string {
+	switch {
+	case x.Symbol != nil:
+		return *x.Symbol
+	case x.Operator != nil:
+		return *x.Operator
+	}
+	return ""
+}
+
+// trailing returns the quotations written last in prev, if its last n
+// expressions are all literal quotations
+func trailing(prev []*parser.Expression, n int) []*parser.Quotation {
+	if len(prev) < n {
+		return nil
+	}
+	var q []*parser.Quotation
+	for _, x := range prev[len(prev)-n:] {
+		if x.Quotation == nil {
+			return nil
+		}
+		q = append(q, x.Quotation)
+	}
+	return q
+}
+
+// always reports whether a condition is the boolean literal b alone
+func always(items []*parser.Expression, b string) bool {
+	return len(items) == 1 && items[0].Boolean != nil && *items[0].Boolean == b
+}
+
+// safeWords cannot fail once the stack holds what they take
+var safeWords = map[string]bool{
+	"dup": true, "drop": true, "pop": true, "swap": true, "over": true,
+	"rot": true, "nip": true, "tuck": true, "dup2": true, "drop2": true,
+	"setz": true, "clrz": true, "clearerr": true,
+}
+
+// cannotFail reports whether a loop body can never set the error flag: it
+// only pushes literals and shuffles what it pushed
+func cannotFail(items []*parser.Expression) bool {
+	depth := 0
+	for _, x := range items {
+		name := word(x)
+		if name == "" {
+			depth++
+			continue
+		}
+		in, out, _ := interpreter.WordEffect(name)
+		if !safeWords[name] || in > depth {
+			return false
+		}
+		depth += out - in
+	}
+	return true
+}
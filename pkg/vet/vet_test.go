@@ -0,0 +1,52 @@
+package vet
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name, in string
+		want     []string // "line:col: check" of each finding
+	}{
+		{"clean", "DEFINE sq == [dup *].\n5 sq [0 >] [\"pos\"] [\"neg\"] ifte .", nil},
+		{"unbalanced ifte", "1 [0 >] [dup] [] ifte", []string{"1:18: branches"}},
+		{"balanced through defines", "DEFINE two == [1 2].\n[true] [two +] [3] ifte", nil},
+		{"unbalanced through defines", "DEFINE two == [1 2].\n[true] [two] [3] ifte", []string{"2:18: branches"}},
+		{"unbalanced if", "1 [true] [drop] if", []string{"1:17: branches"}},
+		{"unknown effect is quiet", "[true] [[1] i] [clear] ifte", nil},
+		{"nested quotations", "[[true] [1] [] branch] i", []string{"1:16: branches"}},
+		{"unused", "DEFINE a == [1].\nDEFINE b == [a].\nDEFINE c == [c].\nb", []string{"3:8: unused", "3:14: endless"}},
+		{"library", "DEFINE a == [1].", nil},
+		{"quoted name is a use", "DEFINE a == [1].\n'a undefine", nil},
+		{"shadow", "DEFINE dup == [1].\ndup", []string{"1:8: shadow"}},
+		{"loop", "[1 dup drop2] loop", []string{"1:15: endless"}},
+		{"loop that can fail", "[1 print] loop [drop] loop", nil},
+		{"while", "[true] [1 .] while", []string{"1:14: endless"}},
+		{"tailrec", "5 [false] [] [dec] tailrec", []string{"1:20: endless"}},
+		{"numbers", "007 9007199254740993 1e6 .5 5. 4 -roll 0.5 -3", []string{
+			"1:1: number", "1:5: number", "1:22: number", "1:26: number", "1:29: number", "1:34: number"}},
+		{"division by zero", "1 0 / 1 0.0 mod 1 2 /", []string{"1:5: number", "1:13: number"}},
+	}
+	for _, tt := range tests {
+		findings, err := Source([]byte(tt.in))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		var got []string
+		for _, f := range findings {
+			got = append(got, f.String()[:strings.Index(f.String(), ": ")]+": "+f.Check)
+		}
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSourceParseError(t *testing.T) {
+	if _, err := Source([]byte("[1 2")); err == nil {
+		t.Error("Expected a parse error")
+	}
+}
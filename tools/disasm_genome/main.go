@@ -25,6 +25,12 @@ var moveArgs = map[byte]string{
 	1: "N", 2: "E", 3: "S", 4: "W", 5: "→food", 6: "→npc", 7: "→item",
 }
 
+var buildArgs = map[byte]string{0: "wall", 1: "shelter", 2: "chest"}
+
+var shootArgs = map[byte]string{
+	0: "facing", 1: "N", 2: "E", 3: "S", 4: "W", 5: "→npc",
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		fmt.Fprintln(os.Stderr, "usage: disasm_genome <hex>")
@@ -120,6 +126,54 @@ func main() {
 		case op == micro.OpActCraft && pc+1 < len(code):
 			fmt.Printf("%s  act.craft\n", addr)
 			pc += 2
+		case op == micro.OpActBuild && pc+1 < len(code):
+			kind := buildArgs[code[pc+1]]
+			if kind == "" {
+				kind = fmt.Sprintf("%d", code[pc+1])
+			}
+			fmt.Printf("%s  act.build %s\n", addr, kind)
+			pc += 2
+		case op == micro.OpActDeposit && pc+1 < len(code):
+			fmt.Printf("%s  act.deposit\n", addr)
+			pc += 2
+		case op == micro.OpActWithdraw && pc+1 < len(code):
+			fmt.Printf("%s  act.withdraw\n", addr)
+			pc += 2
+		case op == micro.OpActShoot && pc+1 < len(code):
+			dir := shootArgs[code[pc+1]]
+			if dir == "" {
+				dir = fmt.Sprintf("%d", code[pc+1])
+			}
+			fmt.Printf("%s  act.shoot %s\n", addr, dir)
+			pc += 2
+		case op == micro.OpActGive && pc+1 < len(code):
+			fmt.Printf("%s  act.give\n", addr)
+			pc += 2
+		case op == micro.OpActFollow && pc+1 < len(code):
+			who := "nearest"
+			if code[pc+1] == 1 {
+				who = "leader"
+			}
+			fmt.Printf("%s  act.follow %s\n", addr, who)
+			pc += 2
+		case op == micro.OpActJoin && pc+1 < len(code):
+			fmt.Printf("%s  act.join\n", addr)
+			pc += 2
+		case op == micro.OpActMate && pc+1 < len(code):
+			fmt.Printf("%s  act.mate\n", addr)
+			pc += 2
+		case op == micro.OpActSleep && pc+1 < len(code):
+			fmt.Printf("%s  act.sleep\n", addr)
+			pc += 2
+		case op == micro.OpActBuy && pc+1 < len(code):
+			fmt.Printf("%s  act.buy %d\n", addr, code[pc+1])
+			pc += 2
+		case op == micro.OpActSell && pc+1 < len(code):
+			fmt.Printf("%s  act.sell\n", addr)
+			pc += 2
+		case op == micro.OpActPost && pc+1 < len(code):
+			fmt.Printf("%s  act.post %d\n", addr, code[pc+1])
+			pc += 2
 		case op == micro.OpPushByte && pc+1 < len(code):
 			fmt.Printf("%s  push.b %d\n", addr, code[pc+1])
 			pc += 2
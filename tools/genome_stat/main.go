@@ -0,0 +1,527 @@
+// genome_stat — Static analysis of sandbox genomes.
+//
+// Usage: go run tools/genome_stat/main.go [-gas flat|weighted|SPEC] [-json] PATH|HEX...
+//
+// Each argument is a genome file (hex on its first non-empty line, a .psil
+// brain or .mpsil assembly), a directory of them (walked recursively), or a hex genome.
+// For every genome it reports the opcode histogram, the Ring0 sensors read
+// and Ring1 outputs read or written, reachable versus dead code, the most
+// gas any path from the entry can burn and how many yields (yield or act.*)
+// a path makes. With several genomes it ends with totals: the opcode
+// histogram, how many genomes use each sensor and output, and how many
+// loop or carry dead code.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/micro"
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// unbounded marks a gas or yield count that a loop can push without limit
+// (in the sandbox, until the VM runs out of gas).
+const unbounded = -1
+
+// stats is what genome_stat reports about one genome.
+type stats struct {
+	Name         string         `json:"name"`
+	Bytes        int            `json:"bytes"`
+	Instructions int            `json:"instructions"` // reachable instructions
+	DeadBytes    int            `json:"dead_bytes"`   // bytes no path from the entry executes
+	Loops        bool           `json:"loops"`
+	MaxGas       int            `json:"max_gas"`    // -1 = unbounded
+	MinYields    int            `json:"min_yields"` // fewest yields on a path to an exit
+	MaxYields    int            `json:"max_yields"` // -1 = unbounded
+	Opcodes      map[string]int `json:"opcodes"`    // reachable instructions by name
+	Ring0Reads   map[string]int `json:"ring0_reads"`
+	Ring1Reads   map[string]int `json:"ring1_reads"`
+	Ring1Writes  map[string]int `json:"ring1_writes"` // act.* count as writing action
+}
+
+// inst is one decoded instruction.
+type inst struct {
+	pc, n int
+	op    byte
+	arg   int // 2-byte operand, or the 3-byte operand as int16
+	bad   bool
+}
+
+// decode reads the instruction at pc. One that runs past the end is bad:
+// the VM stops on it with an error.
+func decode(code []byte, pc int) inst {
+	in := inst{pc: pc, n: 1, op: code[pc]}
+	switch {
+	case micro.Is2ByteOp(in.op):
+		in.n = 2
+	case micro.Is3ByteOp(in.op):
+		in.n = 3
+	case micro.IsVarLenOp(in.op):
+		if pc+1 >= len(code) {
+			in.bad = true
+			return in
+		}
+		in.n = 2 + int(code[pc+1])
+	}
+	if pc+in.n > len(code) {
+		in.bad = true
+		return in
+	}
+	switch in.n {
+	case 2:
+		in.arg = int(code[pc+1])
+	case 3:
+		in.arg = int(int16(code[pc+2]) | int16(code[pc+1])<<8)
+	}
+	return in
+}
+
+// next returns where control can go after in, and whether it can leave
+// the code instead: by halting, an error, or running or jumping off it.
+func (in inst) next(size int) (to []int, exits bool) {
+	if in.bad {
+		return nil, true
+	}
+	after := in.pc + in.n
+	switch in.op {
+	case micro.OpHalt, micro.OpEnd, micro.OpError, micro.OpRet:
+		return nil, true
+	case micro.OpJump, micro.OpJumpFar:
+		to = []int{after + in.arg}
+	case micro.OpJumpBack:
+		to = []int{after - in.arg}
+	case micro.OpJumpZ, micro.OpJumpNZ, micro.OpJumpZFar:
+		to = []int{after, after + in.arg}
+	case micro.OpCallFar:
+		to = []int{int(uint16(in.arg))} // nothing returns from it
+	default:
+		to = []int{after}
+	}
+	kept := to[:0]
+	for _, pc := range to {
+		if pc >= 0 && pc < size {
+			kept = append(kept, pc)
+		} else {
+			exits = true
+		}
+	}
+	return kept, exits
+}
+
+// succ is next without the exit flag.
+func (in inst) succ(size int) []int {
+	to, _ := in.next(size)
+	return to
+}
+
+// yields reports whether in hands control back to the scheduler.
+func yields(in inst) bool {
+	return in.op == micro.OpYield || in.op >= micro.OpActMove && in.op <= micro.OpActPost
+}
+
+// analyze works out the stats of one genome, charging gas from table.
+func analyze(name string, code []byte, table *micro.GasTable) stats {
+	st := stats{
+		Name: name, Bytes: len(code), MinYields: unbounded,
+		Opcodes: map[string]int{}, Ring0Reads: map[string]int{}, Ring1Reads: map[string]int{}, Ring1Writes: map[string]int{},
+	}
+	if len(code) == 0 {
+		st.MinYields = 0
+		return st
+	}
+
+	// Reachable instructions, decoded from every pc control can reach (a
+	// jump into an operand decodes it as an opcode, as the VM would)
+	insts := map[int]inst{}
+	work := []int{0}
+	for len(work) > 0 {
+		pc := work[len(work)-1]
+		work = work[:len(work)-1]
+		if _, seen := insts[pc]; seen {
+			continue
+		}
+		in := decode(code, pc)
+		insts[pc] = in
+		work = append(work, in.succ(len(code))...)
+	}
+	live := make([]bool, len(code))
+	for _, in := range insts {
+		for i := in.pc; i < in.pc+in.n && i < len(code); i++ {
+			live[i] = true
+		}
+	}
+	for _, l := range live {
+		if !l {
+			st.DeadBytes++
+		}
+	}
+
+	st.Instructions = len(insts)
+	for _, in := range insts {
+		st.Opcodes[micro.OpName(in.op)]++
+		switch {
+		case in.bad:
+		case in.op == micro.OpRing0R:
+			st.Ring0Reads[slotName(byte(in.arg), "r0")]++
+		case in.op == micro.OpRing1R:
+			st.Ring1Reads[slotName(64+byte(in.arg), "r1")]++
+		case in.op == micro.OpRing1W:
+			st.Ring1Writes[slotName(64+byte(in.arg), "r1")]++
+		case in.op >= micro.OpActMove && in.op <= micro.OpActPost:
+			st.Ring1Writes[slotName(64+sandbox.Ring1Action, "r1")]++
+		}
+	}
+
+	st.Loops, st.MaxGas, st.MaxYields = longestPaths(insts, len(code), table)
+	st.MinYields = fewestYields(insts, len(code))
+	return st
+}
+
+// slotName names a memory slot in the brain vocabulary, else by ring and
+// number.
+func slotName(slot byte, ring string) string {
+	if name := sandbox.MemoryName(slot); name != "" {
+		return name
+	}
+	if ring == "r1" {
+		slot -= 64
+	}
+	return fmt.Sprintf("%s[%d]", ring, slot)
+}
+
+// longestPaths finds the most gas and yields any path from the entry to an
+// exit can take, condensing loops (strongly connected components) first:
+// a path through a loop can burn unbounded gas, and unbounded yields if
+// the loop yields.
+func longestPaths(insts map[int]inst, size int, table *micro.GasTable) (loops bool, gas, yieldCount int) {
+	// Tarjan's strongly connected components
+	index, low, comp := map[int]int{}, map[int]int{}, map[int]int{}
+	onStack := map[int]bool{}
+	var stack []int
+	var comps [][]int
+	var visit func(pc int)
+	visit = func(pc int) {
+		index[pc], low[pc] = len(index), len(index)
+		stack = append(stack, pc)
+		onStack[pc] = true
+		for _, to := range insts[pc].succ(size) {
+			if _, seen := index[to]; !seen {
+				visit(to)
+				low[pc] = min(low[pc], low[to])
+			} else if onStack[to] {
+				low[pc] = min(low[pc], index[to])
+			}
+		}
+		if low[pc] == index[pc] {
+			var c []int
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				comp[top] = len(comps)
+				c = append(c, top)
+				if top == pc {
+					break
+				}
+			}
+			comps = append(comps, c)
+		}
+	}
+	visit(0)
+
+	// Tarjan emits components in reverse topological order, so successors
+	// are done first
+	bestGas := make([]int, len(comps))
+	bestYields := make([]int, len(comps))
+	for ci, c := range comps {
+		cyclic := len(c) > 1
+		cGas, cYields := 0, 0
+		for _, pc := range c {
+			in := insts[pc]
+			for _, to := range in.succ(size) {
+				if to == pc {
+					cyclic = true
+				}
+			}
+			cost := 1
+			if table != nil {
+				cost = int(table[in.op])
+			}
+			cGas += cost
+			if yields(in) {
+				cYields++
+			}
+		}
+		outGas, outYields := 0, 0
+		for _, pc := range c {
+			for _, to := range insts[pc].succ(size) {
+				if d := comp[to]; d != ci {
+					outGas, outYields = maxCount(outGas, bestGas[d]), maxCount(outYields, bestYields[d])
+				}
+			}
+		}
+		if cyclic {
+			loops = true
+			bestGas[ci] = unbounded
+			if cYields > 0 {
+				bestYields[ci] = unbounded
+			} else {
+				bestYields[ci] = outYields
+			}
+			continue
+		}
+		bestGas[ci] = addCount(cGas, outGas)
+		bestYields[ci] = addCount(cYields, outYields)
+	}
+	root := comp[0]
+	return loops, bestGas[root], bestYields[root]
+}
+
+// maxCount is max with unbounded above everything.
+func maxCount(a, b int) int {
+	if a == unbounded || b == unbounded {
+		return unbounded
+	}
+	return max(a, b)
+}
+
+// addCount is + with unbounded absorbing.
+func addCount(a, b int) int {
+	if a == unbounded || b == unbounded {
+		return unbounded
+	}
+	return a + b
+}
+
+// fewestYields finds the fewest yields on any path from the entry to an
+// exit (0-1 breadth-first search); unbounded if no path exits.
+func fewestYields(insts map[int]inst, size int) int {
+	dist := map[int]int{0: 0}
+	if yields(insts[0]) {
+		dist[0] = 1
+	}
+	deque := []int{0}
+	best := unbounded
+	for len(deque) > 0 {
+		pc := deque[0]
+		deque = deque[1:]
+		in := insts[pc]
+		if _, exits := in.next(size); exits && (best == unbounded || dist[pc] < best) {
+			best = dist[pc]
+		}
+		for _, to := range in.succ(size) {
+			d := dist[pc]
+			if yields(insts[to]) {
+				d++
+			}
+			if old, seen := dist[to]; !seen || d < old {
+				dist[to] = d
+				if d == dist[pc] {
+					deque = append([]int{to}, deque...)
+				} else {
+					deque = append(deque, to)
+				}
+			}
+		}
+	}
+	return best
+}
+
+// readGenome loads a genome file: a .psil file is compiled as a brain, a
+// .mpsil file assembled, anything else holds hex bytes on its first
+// non-empty line.
+func readGenome(path string) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".psil":
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return sandbox.CompileBrain(string(src))
+	case ".mpsil":
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return micro.NewAssembler().Assemble(string(src))
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" {
+			return hex.DecodeString(line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("no genome found")
+}
+
+// collect gathers the genomes an argument names, in path order.
+func collect(arg string, table *micro.GasTable) ([]stats, error) {
+	info, err := os.Stat(arg)
+	if err != nil {
+		code, hexErr := hex.DecodeString(arg)
+		if hexErr != nil {
+			return nil, err
+		}
+		return []stats{analyze("hex", code, table)}, nil
+	}
+	if !info.IsDir() {
+		code, err := readGenome(arg)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", arg, err)
+		}
+		return []stats{analyze(arg, code, table)}, nil
+	}
+	var all []stats
+	err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		code, err := readGenome(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "genome_stat: skipping %s: %v\n", path, err)
+			return nil
+		}
+		all = append(all, analyze(path, code, table))
+		return nil
+	})
+	return all, err
+}
+
+// count formats a gas or yield count.
+func count(n int) string {
+	if n == unbounded {
+		return "unbounded"
+	}
+	return fmt.Sprint(n)
+}
+
+// histogram formats counts as "name×n" pairs, most frequent first.
+func histogram(h map[string]int) string {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if h[names[i]] != h[names[j]] {
+			return h[names[i]] > h[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s×%d", name, h[name])
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+func printStats(st stats) {
+	fmt.Printf("%s: %d bytes, %d reachable instructions, %d dead bytes\n",
+		st.Name, st.Bytes, st.Instructions, st.DeadBytes)
+	if st.MinYields == unbounded {
+		fmt.Printf("  max gas %s, never exits (runs until out of gas)", count(st.MaxGas))
+	} else {
+		fmt.Printf("  max gas %s, yields per path %d..%s", count(st.MaxGas), st.MinYields, count(st.MaxYields))
+	}
+	if st.Loops {
+		fmt.Print(" (loops)")
+	}
+	fmt.Println()
+	fmt.Printf("  ring0 reads:  %s\n", histogram(st.Ring0Reads))
+	fmt.Printf("  ring1 reads:  %s\n", histogram(st.Ring1Reads))
+	fmt.Printf("  ring1 writes: %s\n", histogram(st.Ring1Writes))
+	fmt.Printf("  opcodes:      %s\n", histogram(st.Opcodes))
+}
+
+// printTotals sums up a corpus: opcodes over every genome, and how many
+// genomes use each slot.
+func printTotals(all []stats) {
+	ops, r0, r1w := map[string]int{}, map[string]int{}, map[string]int{}
+	loops, dead, bytes := 0, 0, 0
+	for _, st := range all {
+		for name, n := range st.Opcodes {
+			ops[name] += n
+		}
+		for name := range st.Ring0Reads {
+			r0[name]++
+		}
+		for name := range st.Ring1Writes {
+			r1w[name]++
+		}
+		if st.Loops {
+			loops++
+		}
+		if st.DeadBytes > 0 {
+			dead++
+		}
+		bytes += st.Bytes
+	}
+	fmt.Printf("\n%d genomes, %.1f bytes on average, %d loop, %d carry dead code\n",
+		len(all), float64(bytes)/float64(len(all)), loops, dead)
+	fmt.Printf("  genomes reading each sensor:  %s\n", histogram(r0))
+	fmt.Printf("  genomes writing each output:  %s\n", histogram(r1w))
+	fmt.Printf("  opcodes:                      %s\n", histogram(ops))
+}
+
+func main() {
+	gasSpec := flag.String("gas", "flat", "gas table for max gas: flat, weighted, or a -gas-costs spec such as weighted,exec=5")
+	asJSON := flag.Bool("json", false, "print the stats as JSON, one object per genome")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "usage: genome_stat [-gas flat|weighted|SPEC] [-json] PATH|HEX...")
+		os.Exit(1)
+	}
+	table := &micro.FlatGas
+	if *gasSpec != "flat" {
+		var err error
+		if table, err = micro.ParseGasCosts(*gasSpec); err != nil {
+			fmt.Fprintln(os.Stderr, "genome_stat:", err)
+			os.Exit(1)
+		}
+	}
+
+	var all []stats
+	for _, arg := range flag.Args() {
+		sts, err := collect(arg, table)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "genome_stat:", err)
+			os.Exit(1)
+		}
+		all = append(all, sts...)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, st := range all {
+			enc.Encode(st)
+		}
+		return
+	}
+	for i, st := range all {
+		if i > 0 {
+			fmt.Println()
+		}
+		printStats(st)
+	}
+	if len(all) > 1 {
+		printTotals(all)
+	}
+}
@@ -0,0 +1,173 @@
+// minimize_genome — Shrink a genome to the instructions its behaviour needs.
+//
+// Usage: go run tools/minimize_genome/main.go [flags] GENOME
+//
+// GENOME is a genome file (hex on its first non-empty line, a .psil brain
+// or .mpsil assembly) or a hex genome. The genome is run on a set of sensor
+// vectors (from -cases, one per line as in "food-dist=3 hunger=40", plus
+// -random ones) and, with -ticks, in a small deterministic world. Its
+// instructions are then cut and its conditional jumps neutralized for as
+// long as every vector still gives the same Ring1 outputs and the world
+// ends in the same state, and the minimal genome is printed with its
+// disassembly.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/psilLang/psil/pkg/micro"
+	"github.com/psilLang/psil/pkg/sandbox"
+)
+
+// readGenome loads a genome file like genome_stat, or decodes arg as hex.
+func readGenome(arg string) ([]byte, error) {
+	src, err := os.ReadFile(arg)
+	if err != nil {
+		if code, hexErr := hex.DecodeString(arg); hexErr == nil {
+			return code, nil
+		}
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(arg)) {
+	case ".psil":
+		return sandbox.CompileBrain(string(src))
+	case ".mpsil":
+		return micro.NewAssembler().Assemble(string(src))
+	}
+	for _, line := range strings.Split(string(src), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return hex.DecodeString(line)
+		}
+	}
+	return nil, fmt.Errorf("%s: no genome found", arg)
+}
+
+// readCases reads sensor vectors, one per line; blank lines and # comments
+// are skipped.
+func readCases(path string) ([]map[byte]int16, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var cases []map[byte]int16
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		sensors, err := sandbox.ParseSensors(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		cases = append(cases, sensors)
+	}
+	return cases, sc.Err()
+}
+
+// randomCases returns n sensor vectors over every Ring0 slot, mostly small
+// values (directions, flags, distances) and some large ones.
+func randomCases(n int, rng *rand.Rand) []map[byte]int16 {
+	cases := make([]map[byte]int16, n)
+	for i := range cases {
+		sensors := make(map[byte]int16, sandbox.Ring0ExtCount)
+		for slot := 0; slot < sandbox.Ring0ExtCount; slot++ {
+			if rng.Intn(2) == 0 {
+				sensors[byte(slot)] = int16(rng.Intn(8))
+			} else {
+				sensors[byte(slot)] = int16(rng.Intn(256))
+			}
+		}
+		cases[i] = sensors
+	}
+	return cases
+}
+
+// world runs npcs copies of genome for ticks ticks in a size×size world
+// grown from seed and returns its final state, genomes aside.
+func world(genome []byte, seed int64, size, npcs, ticks, gas int) uint64 {
+	rng := rand.New(rand.NewSource(seed))
+	w := sandbox.NewWorld(size, rng)
+	s := sandbox.NewScheduler(w, gas, io.Discard)
+	for i := 0; i < npcs; i++ {
+		npc := sandbox.NewNPC(genome)
+		npc.X, npc.Y = rng.Intn(size), rng.Intn(size)
+		w.Spawn(npc)
+	}
+	for i := 0; i < ticks; i++ {
+		s.Tick()
+	}
+	return w.StateHash()
+}
+
+func main() {
+	casesPath := flag.String("cases", "", "file of sensor vectors, one per line (sensor=value ...)")
+	random := flag.Int("random", 64, "random sensor vectors to add")
+	seed := flag.Int64("seed", 1, "seed for the random vectors and the world")
+	gas := flag.Int("gas", 200, "gas per run")
+	ticks := flag.Int("ticks", 0, "also keep the outcome of a world run this many ticks")
+	npcs := flag.Int("npcs", 12, "NPCs in the world, all running the genome")
+	size := flag.Int("size", 16, "world size")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: minimize_genome [-cases FILE] [-random N] [-ticks N] [flags] GENOME")
+		os.Exit(1)
+	}
+	genome, err := readGenome(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "minimize_genome:", err)
+		os.Exit(1)
+	}
+
+	var cases []map[byte]int16
+	if *casesPath != "" {
+		if cases, err = readCases(*casesPath); err != nil {
+			fmt.Fprintln(os.Stderr, "minimize_genome:", err)
+			os.Exit(1)
+		}
+	}
+	cases = append(cases, randomCases(*random, rand.New(rand.NewSource(*seed)))...)
+	if len(cases) == 0 && *ticks == 0 {
+		fmt.Fprintln(os.Stderr, "minimize_genome: nothing to preserve (no cases, -random 0 and no -ticks)")
+		os.Exit(1)
+	}
+
+	// The behaviour to keep is whatever the original genome does
+	want := make([][][sandbox.Ring1Count]int16, len(cases))
+	for i, sensors := range cases {
+		want[i] = sandbox.RunBrain(genome, sensors, *gas)
+	}
+	var wantWorld uint64
+	if *ticks > 0 {
+		wantWorld = world(genome, *seed, *size, *npcs, *ticks, *gas)
+	}
+	tries := 0
+	keep := func(code []byte) bool {
+		tries++
+		for i, sensors := range cases {
+			if !reflect.DeepEqual(sandbox.RunBrain(code, sensors, *gas), want[i]) {
+				return false
+			}
+		}
+		return *ticks == 0 || world(code, *seed, *size, *npcs, *ticks, *gas) == wantWorld
+	}
+
+	small := micro.Minimize(genome, keep)
+	fmt.Printf("; %d -> %d bytes (%d candidates tried, %d sensor vectors", len(genome), len(small), tries, len(cases))
+	if *ticks > 0 {
+		fmt.Printf(", %d-tick world", *ticks)
+	}
+	fmt.Println(")")
+	fmt.Println(hex.EncodeToString(small))
+	fmt.Print(sandbox.ExplainGenome(small))
+}
@@ -36,6 +36,7 @@ type recordNPC struct {
 	Fit    int    `json:"f"`
 	Stress int    `json:"s"`
 	GenLen int    `json:"gl"`
+	Clan   byte   `json:"cl,omitempty"`
 }
 
 type recordStats struct {
@@ -328,8 +329,8 @@ func render(hdr recordHeader, fr frame, idx, total, fps int, paused bool) {
 			{fmt.Sprintf("%s│%s %s$%s treasure", reset, reset, fgYellow + "$" + reset, reset)},
 			{fmt.Sprintf("%s│%s %s*%s crystal   CONTROLS", reset, reset, bold + fgMagenta + "*" + reset, reset)},
 			{fmt.Sprintf("%s│%s %sF%s forge     Space  pause", reset, reset, bold + fgBrightCyan + "F" + reset, reset)},
-			{fmt.Sprintf("%s│%s             ←/→    step", reset, reset)},
-			{fmt.Sprintf("%s│%s             +/-    speed", reset, reset)},
+			{fmt.Sprintf("%s│%s %sh%s shelter   ←/→    step", reset, reset, fgYellow + "h" + reset, reset)},
+			{fmt.Sprintf("%s│%s %sC%s chest     +/-    speed", reset, reset, bold + fgYellow + "C" + reset, reset)},
 			{fmt.Sprintf("%s│%s             q      quit", reset, reset)},
 		}
 	}
@@ -351,6 +352,8 @@ func render(hdr recordHeader, fr frame, idx, total, fps int, paused bool) {
 				sb.WriteString(bold)
 				if npc.HP < 30 {
 					sb.WriteString(fgBrightRed) // dying = red
+				} else if npc.Clan != 0 {
+					sb.WriteString(clanColor(npc.Clan)) // faction colour
 				} else if npc.Item != 0 {
 					sb.WriteString(fgBrightYellow) // has item = yellow
 				} else {
@@ -395,7 +398,9 @@ func render(hdr recordHeader, fr frame, idx, total, fps int, paused bool) {
 			fgRed + "w" + reset + "=weapon " +
 			fgYellow + "$" + reset + "=treasure " +
 			fgMagenta + "*" + reset + "=crystal " +
-			fgBrightCyan + "F" + reset + "=forge\033[K\r\n")
+			fgBrightCyan + "F" + reset + "=forge " +
+			fgYellow + "h" + reset + "=shelter " +
+			bold + fgYellow + "C" + reset + "=chest\033[K\r\n")
 		sb.WriteString("[Space]=pause [\u2190\u2192]=step [+/-]=speed [q]=quit\033[K\r\n")
 	}
 
@@ -419,6 +424,10 @@ func npcChar(npc recordNPC) byte {
 			return 's'
 		case 7: // compass
 			return 'c'
+		case 8: // charm
+			return '&'
+		case 9: // remedy
+			return '+'
 		default:
 			return 'o'
 		}
@@ -426,6 +435,14 @@ func npcChar(npc recordNPC) byte {
 	return '@'
 }
 
+// clanPalette holds distinct 256-colour foregrounds for clans (avoiding the
+// red/yellow/white used for NPC state).
+var clanPalette = []int{39, 208, 129, 46, 201, 51, 172, 99, 118, 205, 33, 214}
+
+func clanColor(clan byte) string {
+	return fmt.Sprintf("\033[38;5;%dm", clanPalette[int(clan)%len(clanPalette)])
+}
+
 func tileColor(t byte) string {
 	switch t {
 	case 2: // food
@@ -442,6 +459,10 @@ func tileColor(t byte) string {
 		return bold + fgBrightCyan
 	case 9: // poison
 		return bold + fgBrightRed
+	case 10: // shelter
+		return fgYellow
+	case 11: // chest
+		return bold + fgYellow
 	case 1: // wall
 		return fgBlue
 	default:
@@ -465,6 +486,16 @@ func tileChar(t byte) string {
 		return "F"
 	case 9: // TilePoison
 		return "!"
+	case 10: // TileShelter
+		return "h"
+	case 11: // TileChest
+		return "C"
+	case 12: // TileMarket
+		return "M"
+	case 13: // TilePost
+		return "P"
+	case 14: // TileSchool
+		return "S"
 	case 1: // TileWall
 		return "#"
 	default:
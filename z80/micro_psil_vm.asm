@@ -15,7 +15,8 @@
 ;       --load z80/build/factorial_quots.bin@9200 \
 ;       --console-io --frames DI:HALT
 ;
-; I/O: Uses OUT ($23),A via --console-io (no ROM needed).
+; I/O: Uses OUT ($23),A via --console-io (no ROM needed). Setting vm_outp
+;      captures the output in memory instead (see test_vectors.asm).
 ;
 ; Memory map:
 ;   $8000-$8FFF  VM code (this file)
@@ -174,10 +175,31 @@ vm_run:
     JP C, do_2byte
     CP $E0
     JR C, .op3
+    CP $F9
+    JR Z, .loadw
+    CP $FA
+    JR Z, .storew
 
     ; Skip variable-length and special
     JP vm_run
 
+.loadw:
+    ; @w: addr -- value
+    CALL pop_w
+    CALL mem_slot
+    RET C                  ; past the end: halt
+    CALL mem_rd
+    CALL push_w
+    JP vm_run
+
+.storew:
+    ; !w: value addr --
+    CALL pop_w
+    CALL mem_slot
+    RET C                  ; past the end: halt
+    CALL pop_w             ; value (keeps A)
+    JP mem_wr_run
+
 .num:
     SUB $20
     LD E, A
@@ -200,23 +222,48 @@ vm_run:
     JP vm_run
 
 .op3:
-    CP $C0
-    JR NZ, .skip3
-    ; push.w [hi][lo]
+    ; Fetch [hi][lo] into DE; ops not handled here just skip it
     LD HL, (bc_pc)
     LD D, (HL)
     INC HL
     LD E, (HL)
     INC HL
     LD (bc_pc), HL
+    CP $C0
+    JR Z, .pushw
+    CP $C1
+    JR Z, .sym16
+    CP $C6
+    JR Z, .st16
+    CP $C7
+    JR Z, .ld16
+    JP vm_run
+
+.pushw:
+    ; push.w [hi][lo]
     CALL push_w
     JP vm_run
-.skip3:
-    ; Skip 2 argument bytes
-    LD HL, (bc_pc)
-    INC HL
-    INC HL
-    LD (bc_pc), HL
+
+.sym16:
+    ; extended symbol: slot lo
+    LD A, E
+    CALL mem_rd
+    CALL push_w
+    JP vm_run
+
+.st16:
+    ; st [hi][lo]: value --
+    CALL mem_slot
+    RET C                  ; past the end: halt
+    CALL pop_w             ; value (keeps A)
+    JP mem_wr_run
+
+.ld16:
+    ; ld [hi][lo]: -- value
+    CALL mem_slot
+    RET C                  ; past the end: halt
+    CALL mem_rd
+    CALL push_w
     JP vm_run
 
 ; ============================================================================
@@ -1008,6 +1055,18 @@ mem_rd:
     LD D, (HL)
     RET
 
+; mem_slot: DE = 16-bit address → A = slot, carry set if it is past the
+; 128 slots mem_rd and mem_wr reach
+mem_slot:
+    LD A, D
+    OR A
+    SCF
+    RET NZ
+    LD A, E
+    CP 128
+    CCF
+    RET
+
 ; mem_wr_run: A = slot, DE = value, then continue VM loop
 mem_wr_run:
     CALL mem_wr
@@ -1053,16 +1112,16 @@ do_builtin:
     RET
 .bi_nl:
     LD A, 10
-    OUT ($23), A
+    CALL vm_putc
     RET
 .bi_sp:
     LD A, ' '
-    OUT ($23), A
+    CALL vm_putc
     RET
 .bi_ch:
     CALL pop_w
     LD A, E
-    OUT ($23), A
+    CALL vm_putc
     RET
 .bi_abs:
     CALL pop_w
@@ -1090,13 +1149,13 @@ do_builtin:
     JP push_w
 
 ; ============================================================================
-; pr_s16: Print signed 16-bit DE via OUT ($23)
+; pr_s16: Print signed 16-bit DE via vm_putc
 ; ============================================================================
 pr_s16:
     BIT 7, D
     JR Z, .ps_p
     LD A, '-'
-    OUT ($23), A
+    CALL vm_putc
     CALL neg_de
 .ps_p:
     LD (pr_v), DE
@@ -1112,7 +1171,7 @@ pr_s16:
     LD HL, (pr_v)
     LD A, L
     ADD A, '0'
-    OUT ($23), A
+    CALL vm_putc
     RET
 
 .ps_d:
@@ -1142,12 +1201,35 @@ pr_s16:
     LD A, 1
     LD (pr_lz), A
     POP AF
-    OUT ($23), A
+    CALL vm_putc
     RET
 
 pr_v:  DW 0
 pr_lz: DB 0
 
+; ============================================================================
+; vm_putc: Output A via OUT ($23), or append it at (vm_outp) if that is set.
+; Preserves all registers.
+; ============================================================================
+vm_putc:
+    PUSH HL
+    PUSH AF
+    LD HL, (vm_outp)
+    LD A, H
+    OR L
+    JR Z, .vp_port
+    POP AF
+    LD (HL), A
+    INC HL
+    LD (vm_outp), HL
+    POP HL
+    RET
+.vp_port:
+    POP AF
+    OUT ($23), A
+    POP HL
+    RET
+
 ; ============================================================================
 ; Math routines
 ; ============================================================================
@@ -1255,6 +1337,7 @@ vm_sp:   DW VM_STACK        ; VM value stack pointer
 vm_retf: DB 0               ; Return flag
 vm_gas:  DW 0               ; Gas counter (0 = unlimited)
 vm_mute: DB 0               ; Mute print output (for sandbox)
+vm_outp: DW 0               ; Output capture pointer (0 = print via port)
 
 ; Temporaries
 t1: DW 0
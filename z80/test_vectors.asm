@@ -0,0 +1,383 @@
+; ============================================================================
+; Test harness for the Go/Z80 test vectors
+; Runs every vector in vectors/vectors.bin on this VM and checks the stack,
+; memory, output and gas left against the expected state (the literals in
+; pkg/micro/vectors.go, which the Go VM is held to as well). Prints one line
+; per vector ("ok NAME" or "FAIL NAME: WHAT") and a count.
+;
+; Build:  go run ./cmd/micro-psil -gen-vectors z80/vectors
+;         sjasmplus z80/test_vectors.asm --raw=z80/build/test_vectors.bin
+; Run:    mzx --run z80/build/test_vectors.bin@8000 --console-io --frames DI:HALT
+;
+; WHAT is the first thing that differed: s = stack, m = memory, o = output,
+; g = gas left. The VM keeps no error flag, so the flags are Go's to check.
+; Memory is checked slot by slot for the slots Go left nonzero, and slots
+; 0-63 must hold nothing else (64 and up hold locals and the quotation
+; table here).
+;
+; Vector layout: see EncodeVectors in pkg/micro/vectors.go.
+; ============================================================================
+
+    ORG $8000
+    JP test_entry
+
+    DEFINE VM_LIB_MODE
+    INCLUDE "micro_psil_vm.asm"
+
+VECTORS     EQU $9800       ; above the quotation blob, below the VM stack
+
+test_entry:
+    LD SP, $BFFE
+    DI
+
+    LD HL, str_header
+    CALL print_str
+
+    LD HL, VECTORS
+    LD E, (HL)
+    INC HL
+    LD D, (HL)
+    INC HL
+    LD (tv_ptr), HL
+    LD (tv_left), DE
+    LD (tv_total), DE
+
+.next:
+    LD HL, (tv_left)
+    LD A, H
+    OR L
+    JR Z, .done
+    DEC HL
+    LD (tv_left), HL
+    CALL run_vector
+    JR .next
+
+.done:
+    LD DE, (tv_pass)
+    CALL pr_s16
+    LD A, '/'
+    OUT ($23), A
+    LD DE, (tv_total)
+    CALL pr_s16
+    LD HL, str_passed
+    CALL print_str
+
+    DI
+    HALT
+
+; ============================================================================
+; run_vector: Run the vector at (tv_ptr), check it, print the result and
+; leave (tv_ptr) at the next one
+; ============================================================================
+run_vector:
+    ; Fresh VM: empty stack, memory and quotation table
+    LD HL, VM_STACK
+    LD (vm_sp), HL
+    XOR A
+    LD (vm_retf), A
+    LD (vm_mute), A
+    LD (tv_fail), A
+    LD HL, VM_MEM
+    LD DE, VM_MEM + 1
+    LD BC, 191              ; memory slots and QUOT_TBL
+    LD (HL), 0
+    LDIR
+
+    ; Name
+    LD HL, (tv_ptr)
+    LD (tv_name), HL
+    LD C, (HL)
+    LD B, 0
+    INC HL
+    ADD HL, BC
+
+    ; Gas
+    LD E, (HL)
+    INC HL
+    LD D, (HL)
+    INC HL
+    LD (vm_gas), DE
+
+    ; Code, run in place; a halt follows it
+    LD C, (HL)
+    INC HL
+    LD B, (HL)
+    INC HL
+    LD (bc_pc), HL
+    ADD HL, BC
+    INC HL
+
+    ; Quotations, copied to QUOT_BLOB
+    LD C, (HL)
+    INC HL
+    LD B, (HL)
+    INC HL
+    LD A, B
+    OR C
+    JR Z, .rv_mem
+    LD DE, QUOT_BLOB
+    LDIR
+    LD (tv_ptr), HL
+    CALL parse_quots
+    LD HL, (tv_ptr)
+
+    ; Initial memory
+.rv_mem:
+    LD B, (HL)
+    INC HL
+.rv_mem_lp:
+    LD A, B
+    OR A
+    JR Z, .rv_run
+    PUSH BC
+    LD A, (HL)
+    INC HL
+    LD E, (HL)
+    INC HL
+    LD D, (HL)
+    INC HL
+    PUSH HL
+    CALL mem_wr
+    POP HL
+    POP BC
+    DEC B
+    JR .rv_mem_lp
+
+.rv_run:
+    LD (tv_ptr), HL
+    LD HL, tv_out
+    LD (vm_outp), HL
+    CALL vm_run
+    LD HL, (vm_outp)
+    LD (tv_oend), HL
+    LD HL, 0
+    LD (vm_outp), HL
+    XOR A
+    LD (vm_retf), A
+
+    ; Flags: not kept here
+    LD HL, (tv_ptr)
+    INC HL
+
+    ; Gas left
+    LD E, (HL)
+    INC HL
+    LD D, (HL)
+    INC HL
+    PUSH HL
+    LD HL, (vm_gas)
+    OR A
+    SBC HL, DE
+    POP HL
+    CALL NZ, fail_g
+
+    ; Stack: depth, then each value
+    LD B, (HL)
+    INC HL
+    PUSH HL
+    LD HL, (vm_sp)
+    LD DE, VM_STACK
+    OR A
+    SBC HL, DE
+    SRL H
+    RR L
+    LD A, H
+    OR A
+    CALL NZ, fail_s
+    LD A, L
+    CP B
+    CALL NZ, fail_s
+    POP HL
+    LD DE, VM_STACK
+.rv_stack_lp:
+    LD A, B
+    OR A
+    JR Z, .rv_memory
+    LD A, (DE)
+    CP (HL)
+    CALL NZ, fail_s
+    INC DE
+    INC HL
+    LD A, (DE)
+    CP (HL)
+    CALL NZ, fail_s
+    INC DE
+    INC HL
+    DEC B
+    JR .rv_stack_lp
+
+    ; Memory: each nonzero slot, cleared below 64 once checked so the
+    ; sweep after finds only stray writes
+.rv_memory:
+    LD B, (HL)
+    INC HL
+.rv_memory_lp:
+    LD A, B
+    OR A
+    JR Z, .rv_sweep
+    PUSH BC
+    LD A, (HL)
+    INC HL
+    LD (tv_slot), A
+    LD E, (HL)
+    INC HL
+    LD D, (HL)
+    INC HL
+    PUSH HL
+    PUSH DE
+    CALL mem_rd
+    POP HL
+    OR A
+    SBC HL, DE
+    CALL NZ, fail_m
+    LD A, (tv_slot)
+    CP 64
+    JR NC, .rv_memory_kept
+    LD DE, 0
+    CALL mem_wr
+.rv_memory_kept:
+    POP HL
+    POP BC
+    DEC B
+    JR .rv_memory_lp
+
+.rv_sweep:
+    PUSH HL
+    LD HL, VM_MEM
+    LD B, 128
+.rv_sweep_lp:
+    LD A, (HL)
+    OR A
+    CALL NZ, fail_m
+    INC HL
+    DJNZ .rv_sweep_lp
+    POP HL
+
+    ; Output: length, then each byte
+    LD B, (HL)
+    INC HL
+    PUSH HL
+    LD HL, (tv_oend)
+    LD DE, tv_out
+    OR A
+    SBC HL, DE
+    LD A, H
+    OR A
+    CALL NZ, fail_o
+    LD A, L
+    CP B
+    CALL NZ, fail_o
+    POP HL
+    LD DE, tv_out
+.rv_out_lp:
+    LD A, B
+    OR A
+    JR Z, .rv_report
+    LD A, (DE)
+    CP (HL)
+    CALL NZ, fail_o
+    INC DE
+    INC HL
+    DEC B
+    JR .rv_out_lp
+
+.rv_report:
+    LD (tv_ptr), HL
+    LD A, (tv_fail)
+    OR A
+    JR NZ, .rv_failed
+    LD HL, str_ok
+    CALL print_str
+    CALL print_name
+    LD HL, (tv_pass)
+    INC HL
+    LD (tv_pass), HL
+    JR .rv_nl
+.rv_failed:
+    LD HL, str_fail
+    CALL print_str
+    CALL print_name
+    LD A, ':'
+    OUT ($23), A
+    LD A, ' '
+    OUT ($23), A
+    LD A, (tv_fail)
+    OUT ($23), A
+.rv_nl:
+    LD A, 10
+    OUT ($23), A
+    RET
+
+; ============================================================================
+; Failures: record the first thing that differed. Only A and F change.
+; ============================================================================
+fail_s:
+    LD A, 's'
+    JR fail
+fail_m:
+    LD A, 'm'
+    JR fail
+fail_o:
+    LD A, 'o'
+    JR fail
+fail_g:
+    LD A, 'g'
+fail:
+    PUSH BC
+    LD B, A
+    LD A, (tv_fail)
+    OR A
+    JR NZ, .fl_keep
+    LD A, B
+    LD (tv_fail), A
+.fl_keep:
+    POP BC
+    RET
+
+; ============================================================================
+; Print helpers
+; ============================================================================
+
+; print_name: Print the current vector's name
+print_name:
+    LD HL, (tv_name)
+    LD B, (HL)
+.pn_loop:
+    INC HL
+    LD A, (HL)
+    OUT ($23), A
+    DJNZ .pn_loop
+    RET
+
+print_str:
+    LD A, (HL)
+    OR A
+    RET Z
+    OUT ($23), A
+    INC HL
+    JR print_str
+
+str_header: DB "micro-PSIL test vectors", 10, 0
+str_ok:     DB "ok   ", 0
+str_fail:   DB "FAIL ", 0
+str_passed: DB " passed", 10, 0
+
+; ============================================================================
+; State
+; ============================================================================
+tv_ptr:   DW 0              ; Next vector field
+tv_left:  DW 0              ; Vectors still to run
+tv_total: DW 0
+tv_pass:  DW 0
+tv_name:  DW 0              ; Current vector's name (length first)
+tv_fail:  DB 0              ; First failure, 0 if none
+tv_slot:  DB 0
+tv_oend:  DW 0              ; End of the captured output
+tv_out:   DS 256            ; Captured output
+
+    ASSERT $ <= QUOT_BLOB
+
+    ORG VECTORS
+    INCBIN "vectors/vectors.bin"
+
+    ASSERT $ <= VM_STACK